@@ -1,14 +1,22 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/angelfreak/net/pkg/config"
+	"github.com/angelfreak/net/pkg/oui"
+	"github.com/angelfreak/net/pkg/secrets"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/wifi"
 )
 
 // App encapsulates all dependencies for testable CLI operations.
@@ -26,6 +34,9 @@ type App struct {
 	DHCPMgr    types.DHCPManager    // DHCP server management
 	PortalDet  types.PortalDetector // Captive portal / connectivity probing
 	RouteMgr   types.RouteManager   // Route inspection for multi-home signaling (nil-safe)
+	// FirewallMgr installs the kill switch and inbound filter of trust
+	// policies (nil-safe: nil when iptables is unavailable)
+	FirewallMgr types.FirewallManager
 
 	// Runtime configuration
 	Interface string // Primary network interface to use
@@ -37,6 +48,35 @@ type App struct {
 	// default; tests set 1ms.
 	PortalRetryDelay time.Duration
 
+	// ReadPassword asks for a WiFi password on the terminal without echo.
+	// Nil disables prompting (tests).
+	ReadPassword func(prompt string) (string, error)
+
+	// Save records a successful plain-SSID connection in the config file
+	// at ConfigPath (--save), as network SaveName or, when empty, a name
+	// derived from the SSID.
+	Save       bool
+	SaveName   string
+	ConfigPath string
+
+	// Hidden connects a plain SSID as a hidden network (--hidden): it
+	// isn't looked up in the scan, and --save records hidden: true.
+	Hidden bool
+
+	// BSSID pins a plain SSID connection to one access point (--bssid);
+	// --save records it as ap-addr.
+	BSSID string
+
+	// ProfileStateDir records the active network profile of each interface
+	// for `net status`, a file per interface. Empty disables recording
+	// (tests).
+	ProfileStateDir string
+
+	// alongside is the interface of the connection that keeps the
+	// system-wide settings while this command connects another radio, ""
+	// when the new connection takes them over. Set by leaveNetwork.
+	alongside string
+
 	// Output streams for testability
 	Stdout io.Writer // Standard output (default: os.Stdout)
 	Stderr io.Writer // Standard error (default: os.Stderr)
@@ -78,21 +118,23 @@ func maskSecret(s string) string {
 // attemptVPNConnect tries to connect to the specified VPN.
 // On success, prints a confirmation message to stdout.
 // On failure, logs the error and prints a warning to stderr.
-func (a *App) attemptVPNConnect(vpnName string) {
+// Returns whether the VPN came up.
+func (a *App) attemptVPNConnect(vpnName string) bool {
 	a.Logger.Info("Connecting to VPN", "vpn", vpnName)
 	a.progress("Connecting to VPN '%s'...\n", vpnName)
 	if err := a.VPNMgr.Connect(vpnName); err != nil {
 		a.Logger.Error("Failed to connect to VPN", "error", err)
 		a.errorf("Warning: VPN connection failed: %v\n", err)
-	} else {
-		a.printf("VPN connected!\n")
+		return false
 	}
+	a.printf("VPN connected!\n")
+	return true
 }
 
 // resolveVPNName resolves the VPN name for a network, handling inheritance:
 //   - vpn: some-vpn → uses that VPN
 //   - vpn: (empty)  → disables VPN (won't inherit from common)
-//   - no vpn key    → inherits from common.vpn
+//   - no vpn key    → inherits from the trust level's policy, or common.vpn
 //
 // Returns "" when no VPN is configured or the config is nil. Replaces the
 // former connectVPN (RunConnect was its only production caller and now calls
@@ -106,9 +148,12 @@ func (a *App) resolveVPNName(networkName string) string {
 	if config == nil {
 		return ""
 	}
-	if netConfig, ok := config.Networks[networkName]; ok {
+	if netConfig, ok := config.Network(networkName); ok {
 		return a.ConfigMgr.MergeWithCommon(networkName, &netConfig).VPN
 	}
+	if _, ok := a.trustPolicy(a.trustLevel(networkName)); ok {
+		return a.settingsFor(networkName).VPN
+	}
 	return config.Common.VPN
 }
 
@@ -231,9 +276,15 @@ func (a *App) RunList() error {
 
 	for _, conn := range connections {
 		a.printf("Interface: %s\n", conn.Interface)
+		if conn.Radio != "" {
+			a.printf("Radio: %s\n", conn.Radio)
+		}
 		if conn.SSID != "" {
 			a.printf("SSID: %s\n", conn.SSID)
 		}
+		if conn.BSSID != "" {
+			a.printf("BSSID: %s\n", conn.BSSID)
+		}
 		a.printf("State: %s\n", conn.State)
 		if conn.IP != nil {
 			a.printf("IP: %s\n", conn.IP.String())
@@ -249,9 +300,15 @@ func (a *App) RunList() error {
 	return nil
 }
 
-// RunScan scans for available WiFi networks and displays them.
-// If showOpen is true, only open (unprotected) networks are shown.
-func (a *App) RunScan(showOpen bool) error {
+// RunScan scans for available WiFi networks and displays the access points
+// that pass filter, strongest first (one line per network when grouped by
+// SSID).
+func (a *App) RunScan(filter scanFilter) error {
+	if err := filter.validate(); err != nil {
+		a.errorf("Error: %v\n", err)
+		return err
+	}
+
 	a.progress("Scanning for networks...\n")
 
 	networks, err := a.WiFiMgr.Scan()
@@ -261,24 +318,26 @@ func (a *App) RunScan(showOpen bool) error {
 		return err
 	}
 
-	// Count networks to display (respecting showOpen filter)
-	displayCount := 0
-	for _, network := range networks {
-		if showOpen && network.Security != "Open" {
-			continue
-		}
-		displayCount++
-	}
-	a.progress("Found %d networks\n", displayCount)
+	entries := filter.apply(networks)
+	a.progress("Found %d networks\n", len(entries))
 
-	for _, network := range networks {
-		if showOpen && network.Security != "Open" {
-			continue
-		}
+	for _, entry := range entries {
 		// Scanned SSIDs are attacker-controlled over the air; sanitize before
 		// printing to prevent terminal-escape injection.
-		a.printf("%s (%s) - Signal: %d dBm - Security: %s\n",
-			system.SanitizeForTerminal(network.SSID), network.BSSID, network.Signal, network.Security)
+		ssid := system.SanitizeForTerminal(entry.SSID)
+		if entry.Hidden {
+			ssid = "<hidden>"
+		}
+		bssid := entry.BSSID
+		if vendor := oui.Lookup(bssid); vendor != "" {
+			bssid += ", " + vendor
+		}
+		line := fmt.Sprintf("%s (%s) - Signal: %d dBm - Security: %s%s",
+			ssid, bssid, entry.Signal, entry.Security, scanDetails(entry.WiFiNetwork))
+		if entry.APs > 1 {
+			line += fmt.Sprintf(" - %d access points", entry.APs)
+		}
+		a.printf("%s\n", line)
 	}
 	return nil
 }
@@ -289,21 +348,16 @@ func (a *App) RunScan(showOpen bool) error {
 func (a *App) RunConnect(name, password string) error {
 	a.Logger.Debug("Connect command called", "name", name)
 
-	// Disconnect any active VPN before connecting to new network
-	// This prevents stale VPN routes/interfaces from interfering.
-	// Skip if --no-vpn is set — user wants to keep their VPN alive.
-	if !a.NoVPN {
-		a.Logger.Debug("Disconnecting any active VPN before connecting")
-		if err := a.VPNMgr.Disconnect(""); err != nil {
-			a.Logger.Debug("No active VPN to disconnect", "error", err)
-		}
-	}
-
 	// Check if it's a configured network
 	a.Logger.Debug("Looking up network config", "name", name)
 	networkConfig, err := a.ConfigMgr.GetNetworkConfig(name)
 	configName := name
 	var connectedIface string
+	// siteCandidates are the configured networks sharing this SSID when at
+	// least one of them has match: rules: which one applies is decided from
+	// the environment after link-up.
+	var siteCandidates []string
+	plainSSID := false
 	if err != nil {
 		// A config that failed to load (parse/validation error) is different
 		// from a name that simply isn't configured: don't silently degrade to
@@ -318,73 +372,519 @@ func (a *App) RunConnect(name, password string) error {
 		// DNS, VPN) instead of degrading to a plain SSID connection.
 		cfg := a.ConfigMgr.GetConfig()
 		var matches []string
+		hasRules := false
 		for netName, nc := range cfg.Networks {
 			if nc.SSID == name {
 				matches = append(matches, netName)
+				hasRules = hasRules || !nc.Match.IsEmpty()
 			}
 		}
-		if len(matches) == 1 {
+		if hasRules {
+			sort.Strings(matches)
+			siteCandidates = matches
+			a.Logger.Info("SSID is shared by site profiles, choosing after link-up", "ssid", name, "networks", strings.Join(matches, ", "))
+			if password == "" {
+				password = a.sharedPSK(matches)
+			}
+		} else if len(matches) == 1 {
 			configName = matches[0]
 			nc := cfg.Networks[configName] // copy: map values are not addressable
 			networkConfig = &nc
 			err = nil
+			if secrets.IsReference(nc.PSK) {
+				// The config map holds the raw reference; go through the
+				// manager so it gets resolved.
+				networkConfig, err = a.ConfigMgr.GetNetworkConfig(configName)
+				if err != nil {
+					a.errorf("Error: %v\n", err)
+					return err
+				}
+			}
 			a.Logger.Info("SSID matches configured network, using its configuration", "ssid", name, "network", configName)
 		} else if len(matches) > 1 {
 			a.Logger.Warn("Multiple configured networks share this SSID, connecting as plain SSID", "ssid", name, "networks", strings.Join(matches, ", "))
 		}
 	}
-	if err != nil {
+	// A WiFi network connects on its interface: or the default radio; a
+	// wired one takes over from the WiFi, whichever interface it gets.
+	radio := a.WiFiMgr.GetInterface()
+	if err == nil && networkConfig != nil {
+		if networkConfig.Interface != "" {
+			radio = networkConfig.Interface
+		}
+		if networkConfig.SSID == "" {
+			radio = ""
+		}
+	}
+	a.leaveNetwork(radio)
+
+	var matchedRule string
+	if siteCandidates != nil {
+		// Associate with the common settings (so the common MAC policy
+		// applies from the first frame), then pick the site's profile.
+		// Until a profile matches, the site is unknown: public.
+		base := a.ConfigMgr.MergeWithCommon("", &types.NetworkConfig{SSID: name, Trust: types.TrustPublic})
+		a.progress("Connecting to WiFi...\n")
+		if err := a.connectNetwork(base, password); err != nil {
+			a.Logger.Error("Failed to connect to WiFi", "error", err)
+			a.errorf("Error: %v\n", err)
+			return err
+		}
+		connectedIface = base.Interface
+		profile, rule := a.matchProfile(connectedIface, siteCandidates)
+		if profile == "" {
+			a.errorf("Warning: no profile for '%s' matches this site; connected with common settings.\n", name)
+		} else {
+			iface, err := a.connectProfile(profile, password)
+			if err != nil {
+				return err
+			}
+			configName, connectedIface, matchedRule = profile, iface, rule
+		}
+	} else if err != nil {
 		// Not configured, treat as SSID
 		a.Logger.Debug("Network config not found, treating as direct SSID", "name", name, "error", err)
 		a.Logger.Info("Connecting to SSID", "ssid", name)
 
-		// Flush stale DNS before DHCP so external tools (netbird) don't retain their DNS
-		a.NetworkMgr.ClearDNS()
-
-		a.progress("Connecting to WiFi...\n")
-		err = a.WiFiMgr.Connect(name, password, "")
+		plainSSID = true
+		if password == "" {
+			if password, err = a.promptPassword(name); err != nil {
+				a.errorf("Error: %v\n", err)
+				return err
+			}
+		}
+		connectedIface, err = a.connectSSID(name, password)
+		if errors.Is(err, wifi.ErrWrongKey) && a.ReadPassword != nil {
+			// Give a mistyped password a second chance.
+			a.errorf("Wrong password for %s.\n", name)
+			if retry, perr := a.ReadPassword(fmt.Sprintf("Password for %s: ", name)); perr == nil && retry != "" {
+				password = retry
+				connectedIface, err = a.connectSSID(name, password)
+			}
+		}
 		if err != nil {
 			a.Logger.Error("Failed to connect to WiFi", "error", err)
 			a.errorf("Error: %v\n", err)
 			return err
 		}
-		connectedIface = a.WiFiMgr.GetInterface()
-
-		// Lock resolv.conf after DHCP writes DNS to prevent external tools
-		// (like netbird) from overwriting with their own DNS servers
-		a.NetworkMgr.LockDNS()
+		if a.Save {
+			if err := a.saveNetwork(name, password, strings.ToLower(a.BSSID)); err != nil {
+				a.errorf("Error: failed to save network: %v\n", err)
+				return err
+			}
+		}
 	} else {
-		// Use configured network - merge with common settings first
-		networkConfig = a.ConfigMgr.MergeWithCommon(configName, networkConfig)
-		a.Logger.Debug("Found network config", "name", configName, "ssid", networkConfig.SSID, "mac", networkConfig.MAC)
-		a.Logger.Info("Connecting to configured network", "name", configName)
+		connectedIface, err = a.applyProfile(configName, networkConfig, password)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.Save && !plainSSID {
+		a.errorf("Note: '%s' is already configured, not saving it.\n", configName)
+	}
+	return a.finishConnect(configName, connectedIface, matchedRule)
+}
+
+// leaveNetwork undoes what belongs to the previous connection of iface
+// before a new connect: "" for a wired link, which replaces the connection
+// with the system-wide settings. When another radio's connection has the
+// system-wide settings (DNS, trust policy, hostname), they stay with it and
+// only the VPN of iface's previous connection is disconnected.
+func (a *App) leaveNetwork(iface string) {
+	profiles := a.activeProfiles()
+	var previous *activeProfile
+	for _, p := range profiles {
+		if p.Interface == iface || iface == "" && p.Alongside == "" {
+			previous = p
+		}
+	}
+	a.alongside = ""
+	others := false
+	for _, p := range profiles {
+		if p == previous {
+			continue
+		}
+		others = true
+		if p.Alongside == "" {
+			a.alongside = p.Interface
+		}
+	}
+
+	// Disconnect any active VPN before connecting to new network
+	// This prevents stale VPN routes/interfaces from interfering.
+	// Skip if --no-vpn is set — user wants to keep their VPN alive.
+	// With other connections up, only the previous one's VPN goes.
+	if !a.NoVPN {
+		vpnName := ""
+		if others && previous != nil {
+			vpnName = previous.VPN
+		}
+		if !others || vpnName != "" {
+			a.Logger.Debug("Disconnecting VPN before connecting", "vpn", vpnName)
+			if err := a.VPNMgr.Disconnect(vpnName); err != nil {
+				a.Logger.Debug("No active VPN to disconnect", "error", err)
+			}
+		}
+	}
+	if a.alongside == "" {
+		// The previous network's kill switch, inbound filter and hostname
+		// go with it.
+		a.resetTrustPolicy()
+		a.resetHostnameMode()
+	}
+	if previous != nil {
+		a.clearActiveProfile(previous.Interface)
+	}
+}
+
+// connectNetwork brings up the link of config, leaving the system's DNS to
+// the connection alongside, if any.
+func (a *App) connectNetwork(config *types.NetworkConfig, password string) error {
+	config.KeepDNS = a.alongside != ""
+	return a.NetworkMgr.ConnectToConfiguredNetwork(config, password, a.WiFiMgr)
+}
+
+// promptPassword asks for the password of ssid when the scan shows it takes
+// one. It returns "" for open networks, networks that aren't heard and when
+// prompting is disabled.
+func (a *App) promptPassword(ssid string) (string, error) {
+	if a.ReadPassword == nil {
+		return "", nil
+	}
+	if a.Hidden {
+		// A hidden network isn't in the scan to tell whether it's open.
+		password, err := a.ReadPassword(fmt.Sprintf("Password for %s (empty if open): ", ssid))
+		if errors.Is(err, system.ErrNoTerminal) {
+			return "", nil
+		}
+		return password, err
+	}
+	network := a.WiFiMgr.DetectNetwork(ssid)
+	if network == nil || !network.NeedsPassphrase() {
+		return "", nil
+	}
+	password, err := a.ReadPassword(fmt.Sprintf("Password for %s: ", ssid))
+	if errors.Is(err, system.ErrNoTerminal) {
+		return "", fmt.Errorf("%s needs a password: give it after the SSID (net connect %q <password>)", ssid, ssid)
+	}
+	return password, err
+}
+
+// connectSSID joins ssid, which isn't a configured network, and returns the
+// interface used.
+func (a *App) connectSSID(ssid, password string) (string, error) {
+	if _, ok := a.trustPolicy(types.TrustPublic); ok {
+		// Unknown networks are public: join with that policy's MAC and
+		// hostname settings applied from the first frame.
+		base := a.ConfigMgr.MergeWithCommon("", &types.NetworkConfig{SSID: ssid, ApAddr: a.BSSID, Trust: types.TrustPublic, Hidden: a.Hidden})
+		a.progress("Connecting to WiFi...\n")
+		if err := a.connectNetwork(base, password); err != nil {
+			return "", err
+		}
+		return base.Interface, nil
+	}
+
+	// Flush stale DNS before DHCP so external tools (netbird) don't retain their DNS
+	a.NetworkMgr.ClearDNS()
+
+	a.progress("Connecting to WiFi...\n")
+	var err error
+	switch {
+	case a.Hidden:
+		err = a.WiFiMgr.ConnectHidden(ssid, password, a.BSSID, "")
+	case a.BSSID != "":
+		err = a.WiFiMgr.ConnectWithBSSID(ssid, password, a.BSSID, "")
+	default:
+		err = a.WiFiMgr.Connect(ssid, password, "")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Lock resolv.conf after DHCP writes DNS to prevent external tools
+	// (like netbird) from overwriting with their own DNS servers
+	a.NetworkMgr.LockDNS()
+	return a.WiFiMgr.GetInterface(), nil
+}
+
+// saveNetwork records ssid as a new network of the config file (--save),
+// pinned to access point bssid when it isn't empty. The password goes to
+// secrets.yaml as a secret:<name> reference when that file exists next to a
+// plaintext config, inline otherwise. The secret is removed again when the
+// config can't be written, so it never outlives its reference.
+func (a *App) saveNetwork(ssid, password, bssid string) error {
+	name := a.SaveName
+	if name == "" {
+		name = config.NetworkName(ssid)
+	}
+	secretsAdded := ""
+	path, err := updateConfigFile(a.ConfigPath, func(doc *config.Document, path string, encrypted bool) error {
+		if err := doc.CheckNetworkName(name); err != nil {
+			return err
+		}
+		if err := doc.SetString(ssid, name, "ssid"); err != nil {
+			return err
+		}
+		if bssid != "" {
+			if err := doc.SetString(bssid, name, "ap-addr"); err != nil {
+				return err
+			}
+		}
+		if a.Hidden {
+			if err := doc.Set("true", name, "hidden"); err != nil {
+				return err
+			}
+		}
 		if password == "" {
-			password = networkConfig.PSK
+			return nil
 		}
-		a.Logger.Debug("Using network config", "configSSID", networkConfig.SSID)
-		if networkConfig.SSID != "" {
-			a.progress("Connecting to WiFi...\n")
-		} else {
-			// Switching to wired — disconnect WiFi first so its stale default
-			// route doesn't prevent DHCP from setting the correct gateway.
-			a.Logger.Debug("Disconnecting WiFi before wired connection")
-			if err := a.WiFiMgr.Disconnect(); err != nil {
-				a.Logger.Debug("No active WiFi to disconnect", "error", err)
+		psk := password
+		secretsPath := filepath.Join(filepath.Dir(path), "secrets.yaml")
+		if _, err := os.Stat(secretsPath); err == nil && !encrypted {
+			if err := setSecret(secretsPath, name, password); err != nil {
+				return err
 			}
-			a.progress("Connecting to wired network...\n")
+			secretsAdded = secretsPath
+			psk = secrets.PrefixSecret + name
+		}
+		return doc.SetString(psk, name, "psk")
+	})
+	if err != nil {
+		if secretsAdded != "" {
+			if rerr := removeSecret(secretsAdded, name); rerr != nil {
+				a.errorf("Warning: secret '%s' left in %s: %v\n", name, secretsAdded, rerr)
+			}
+		}
+		return err
+	}
+	a.printf("Saved network '%s' to %s\n", name, path)
+	return nil
+}
+
+// RunConnectWPS joins a network with WPS (net connect --wps): push-button,
+// or with pin entered on the access point when withPIN is set (a random
+// one when pin is empty). bssid limits WPS to that access point. The
+// credentials the access point provisions are saved as a new network.
+func (a *App) RunConnectWPS(bssid, pin string, withPIN bool) error {
+	a.Logger.Debug("WPS connect command called", "bssid", bssid, "pin", withPIN)
+	if withPIN {
+		var err error
+		if pin == "" {
+			pin, err = wifi.GenerateWPSPIN()
+		} else {
+			err = wifi.ValidateWPSPIN(pin)
 		}
-		err = a.NetworkMgr.ConnectToConfiguredNetwork(networkConfig, password, a.WiFiMgr)
 		if err != nil {
-			a.Logger.Error("Failed to connect to configured network", "error", err)
 			a.errorf("Error: %v\n", err)
 			return err
 		}
-		// ConnectToConfiguredNetwork sets networkConfig.Interface via auto-detection
-		connectedIface = networkConfig.Interface
+	}
+	a.leaveNetwork(a.WiFiMgr.GetInterface())
+
+	if withPIN {
+		a.printf("Enter PIN %s in the WPS settings of the access point within 2 minutes...\n", pin)
+	} else {
+		a.printf("Press the WPS button on the access point within 2 minutes...\n")
+	}
+	if a.alongside == "" {
+		a.NetworkMgr.ClearDNS()
+	}
+	cred, err := a.WiFiMgr.ConnectWPS(bssid, pin, "")
+	if err != nil {
+		a.Logger.Error("WPS connection failed", "error", err)
+		a.errorf("Error: %v\n", err)
+		return err
+	}
+	if a.alongside == "" {
+		a.NetworkMgr.LockDNS()
+	}
+	a.printf("WPS provisioned network %s\n", cred.SSID)
+
+	// The access point that provisioned the credentials is the one they
+	// are known to work with: pin it.
+	pinned := cred.BSSID
+	if pinned == "" {
+		pinned = bssid
+	}
+	if name := a.configuredSSID(cred.SSID); name != "" {
+		a.errorf("Note: %s is already configured as '%s', not saving it.\n", cred.SSID, name)
+	} else if err := a.saveNetwork(cred.SSID, cred.PSK, strings.ToLower(pinned)); err != nil {
+		a.errorf("Error: failed to save network: %v\n", err)
+		return err
+	}
+	return a.finishConnect(cred.SSID, a.WiFiMgr.GetInterface(), "")
+}
+
+// configuredSSID returns the first (by name) configured network of ssid, or
+// "".
+func (a *App) configuredSSID(ssid string) string {
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil {
+		return ""
+	}
+	var names []string
+	for name, nc := range cfg.Networks {
+		if nc.SSID == ssid {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// RunAuto brings up a wired link without a named profile, then applies the
+// profile whose match: rules fit what is seen on it (gateway MAC, DHCP
+// domain, LLDP switch name). Without a match, the link stays up with the
+// common settings. ifaceName may be empty to auto-detect the wired interface.
+func (a *App) RunAuto(ifaceName string) error {
+	a.leaveNetwork("")
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil {
+		a.errorf("Error: configuration failed to load. Fix the config file and retry.\n")
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	base := a.ConfigMgr.MergeWithCommon("", &types.NetworkConfig{Interface: ifaceName})
+	a.progress("Connecting to wired network...\n")
+	if err := a.connectNetwork(base, ""); err != nil {
+		a.Logger.Error("Failed to bring up wired network", "error", err)
+		a.errorf("Error: %v\n", err)
+		return err
+	}
+	connectedIface := base.Interface
+
+	configName, matchedRule := "", ""
+	if profile, rule := a.matchProfile(connectedIface, nil); profile != "" {
+		iface, err := a.connectProfile(profile, "")
+		if err != nil {
+			return err
+		}
+		configName, connectedIface, matchedRule = profile, iface, rule
+	} else {
+		a.println("No profile matches this network; using common settings.")
+	}
+
+	return a.finishConnect(configName, connectedIface, matchedRule)
+}
+
+// sharedPSK returns the PSK of the given networks when they all use the same
+// one, so the initial association for site matching needs no password on
+// the command line. Returns "" when they differ or can't be resolved.
+func (a *App) sharedPSK(networks []string) string {
+	psk := ""
+	for i, name := range networks {
+		nc, err := a.ConfigMgr.GetNetworkConfig(name)
+		if err != nil {
+			return ""
+		}
+		if i > 0 && nc.PSK != psk {
+			return ""
+		}
+		psk = nc.PSK
+	}
+	return psk
+}
+
+// matchProfile detects the environment on iface and returns the profile
+// among candidates (nil: all networks) whose match: rules fit, with the rule
+// that matched. Returns "" when detection fails or nothing matches.
+func (a *App) matchProfile(iface string, candidates []string) (string, string) {
+	env, err := a.NetworkMgr.DetectEnvironment(iface)
+	if err != nil {
+		a.Logger.Warn("Failed to inspect link for profile matching", "iface", iface, "error", err)
+		return "", ""
+	}
+	profile, rule, err := config.MatchProfile(a.ConfigMgr.GetConfig().Networks, env, candidates)
+	if err != nil {
+		a.errorf("Warning: %v\n", err)
+		return "", ""
+	}
+	if profile != "" {
+		a.Logger.Info("Matched network profile", "network", profile, "rule", rule)
+		a.printf("Matched profile '%s' (%s)\n", profile, rule)
+	}
+	return profile, rule
+}
+
+// connectProfile looks up a matched profile and applies it. The link is
+// brought up again because the profile's MAC address must be set before
+// association / DHCP.
+func (a *App) connectProfile(name, password string) (string, error) {
+	networkConfig, err := a.ConfigMgr.GetNetworkConfig(name)
+	if err != nil {
+		a.errorf("Error: %v\n", err)
+		return "", err
+	}
+	return a.applyProfile(name, networkConfig, password)
+}
+
+// applyProfile merges a network profile with the common settings and
+// connects with it, returning the interface it came up on.
+func (a *App) applyProfile(configName string, networkConfig *types.NetworkConfig, password string) (string, error) {
+	networkConfig = a.ConfigMgr.MergeWithCommon(configName, networkConfig)
+	networkConfig.Name = configName
+	a.Logger.Debug("Found network config", "name", configName, "ssid", networkConfig.SSID, "mac", networkConfig.MAC)
+	a.Logger.Info("Connecting to configured network", "name", configName)
+	if password == "" {
+		password = networkConfig.PSK
+	}
+	a.Logger.Debug("Using network config", "configSSID", networkConfig.SSID)
+	if networkConfig.SSID != "" {
+		a.progress("Connecting to WiFi...\n")
+	} else {
+		// Switching to wired — disconnect WiFi first so its stale default
+		// route doesn't prevent DHCP from setting the correct gateway.
+		a.Logger.Debug("Disconnecting WiFi before wired connection")
+		if err := a.WiFiMgr.Disconnect(); err != nil {
+			a.Logger.Debug("No active WiFi to disconnect", "error", err)
+		}
+		a.progress("Connecting to wired network...\n")
+	}
+	err := a.connectNetwork(networkConfig, password)
+	if err != nil {
+		a.Logger.Error("Failed to connect to configured network", "error", err)
+		a.errorf("Error: %v\n", err)
+		return "", err
+	}
+	// ConnectToConfiguredNetwork sets networkConfig.Interface via auto-detection
+	return networkConfig.Interface, nil
+}
+
+// finishConnect runs the steps common to every successful connect: it
+// applies the connection's trust policy, prints the connection, checks for a
+// captive portal, brings up the VPN and records the active profile
+// (configName is "" or the SSID for plain connects). The only error is a
+// kill switch the trust policy asks for that can't be put up, which takes
+// the link down again.
+func (a *App) finishConnect(configName, connectedIface, matchedRule string) error {
+	active := &activeProfile{Interface: connectedIface, Trust: a.trustLevel(configName), Alongside: a.alongside}
+	if cfg := a.ConfigMgr.GetConfig(); cfg != nil && configName != "" {
+		if _, ok := cfg.Network(configName); ok {
+			active.Network, active.Rule = configName, matchedRule
+		}
+	}
+	policy, hasPolicy := a.trustPolicy(active.Trust)
+	if a.alongside != "" {
+		// Another radio's connection keeps the system-wide settings.
+		a.errorf("Note: the DNS servers, trust policy and hostname of the connection on %s stay in effect.\n", a.alongside)
+		hasPolicy = false
+	} else {
+		if hasPolicy {
+			a.Logger.Info("Applying trust policy", "trust", active.Trust, "iface", connectedIface)
+			active.Protections = a.applyInboundFilter(policy, connectedIface)
+		}
+		active.HostnameMode = a.applyHostnameMode(configName, connectedIface)
 	}
 
 	// Display connection information (includes "Connected!" message)
 	a.printConnectionInfo(connectedIface)
+	if active.Trust != "" {
+		a.printf("  Trust:   %s\n", active.Trust)
+	}
 
 	// Resolve the VPN name once, before the portal check, so the hint, the
 	// offline-warning suppression, and the attempt can never disagree.
@@ -395,12 +895,32 @@ func (a *App) RunConnect(name, password string) error {
 
 	portalDetected := a.checkPortalAfterConnect(connectedIface, vpnName != "")
 
+	// The kill switch goes up before the VPN, so nothing leaks while the
+	// tunnel comes up or when it doesn't.
+	if hasPolicy && policy.KillSwitch {
+		if err := a.raiseKillSwitch(connectedIface, vpnName); err != nil {
+			return a.dropLink(active.Trust, connectedIface, err)
+		}
+	}
 	if vpnName != "" {
 		if portalDetected {
 			a.errorf("Note: the VPN may not come up until the portal login is complete.\n")
 		}
-		a.attemptVPNConnect(vpnName)
+		if a.attemptVPNConnect(vpnName) {
+			active.VPN = vpnName
+		}
+	}
+
+	if hasPolicy {
+		var dns []string
+		if settings := a.settingsFor(configName); settings != nil {
+			dns = settings.DNS
+		}
+		active.Protections = append(active.Protections,
+			a.applyTunnelPolicy(active.Trust, policy, connectedIface, vpnName, dns, portalDetected)...)
 	}
+
+	a.saveActiveProfile(active)
 	return nil
 }
 
@@ -474,6 +994,15 @@ func (a *App) RunStop(interfaces []string) error {
 		if err := a.WiFiMgr.Disconnect(); err != nil {
 			a.Logger.Debug("No active WiFi to disconnect", "error", err)
 		}
+		// Other radios run their own wpa_supplicant.
+		for name, station := range a.stations() {
+			if name == a.WiFiMgr.GetInterface() || station.SSID == "" {
+				continue
+			}
+			if err := a.WiFiMgr.ForInterface(name).Disconnect(); err != nil {
+				a.Logger.Debug("Failed to disconnect WiFi", "interface", name, "error", err)
+			}
+		}
 		torn := a.NetworkMgr.DisconnectAll()
 		if len(torn) > 0 {
 			stoppedServices = append(stoppedServices, "Network ("+strings.Join(torn, ", ")+")")
@@ -492,6 +1021,14 @@ func (a *App) RunStop(interfaces []string) error {
 			stoppedServices = append(stoppedServices, "DNS")
 		}
 
+		if a.resetTrustPolicy() {
+			stoppedServices = append(stoppedServices, "Trust policy")
+		}
+		if a.resetHostnameMode() {
+			stoppedServices = append(stoppedServices, "Hostname")
+		}
+		a.clearActiveProfile("")
+
 		// Print summary
 		if len(stoppedServices) > 0 {
 			a.println("✓ Stopped services:")
@@ -506,14 +1043,28 @@ func (a *App) RunStop(interfaces []string) error {
 		// are killed, addresses/routes flushed, and the link brought down —
 		// otherwise a zombie udhcpc keeps renewing on a down interface.
 		var lastErr error
+		stations := a.stations()
 		for _, iface := range interfaces {
 			a.Logger.Debug("Stopping interface", "interface", iface)
+			if _, ok := stations[iface]; ok {
+				// Stop its wpa_supplicant too, or it keeps reassociating.
+				if err := a.WiFiMgr.ForInterface(iface).Disconnect(); err != nil {
+					a.Logger.Debug("Failed to disconnect WiFi", "interface", iface, "error", err)
+				}
+			}
 			if err := a.NetworkMgr.Disconnect(iface); err != nil {
 				a.Logger.Error("Failed to stop interface", "interface", iface, "error", err)
 				a.errorf("✗ Failed to stop %s\n", iface)
 				lastErr = err
 			} else {
 				a.printf("✓ Stopped interface %s\n", iface)
+				if p := a.loadActiveProfile(iface); p != nil {
+					if p.Alongside == "" {
+						a.resetTrustPolicy()
+						a.resetHostnameMode()
+					}
+					a.clearActiveProfile(iface)
+				}
 			}
 		}
 		if lastErr != nil {
@@ -523,6 +1074,23 @@ func (a *App) RunStop(interfaces []string) error {
 	return nil
 }
 
+// stations returns the wireless station interfaces of all radios by name,
+// with their associations.
+func (a *App) stations() map[string]types.Connection {
+	connections, err := a.WiFiMgr.ListConnections()
+	if err != nil {
+		a.Logger.Debug("Failed to list radios", "error", err)
+		return nil
+	}
+	stations := make(map[string]types.Connection)
+	for _, conn := range connections {
+		if conn.Radio != "" && conn.State != "access point" {
+			stations[conn.Interface] = conn
+		}
+	}
+	return stations
+}
+
 // RunDNS sets DNS servers or restores DHCP-provided DNS.
 // If servers is empty or contains only "dhcp", performs DHCP renewal to restore DNS.
 // Otherwise sets the specified DNS servers.
@@ -565,6 +1133,22 @@ func (a *App) RunMAC(mac string) error {
 	return nil
 }
 
+// RunOUI prints the vendor of a MAC address or prefix from the embedded OUI
+// database.
+func (a *App) RunOUI(mac string) error {
+	vendor := oui.Lookup(mac)
+	if vendor != "" {
+		a.printf("%s  %s\n", mac, vendor)
+		return nil
+	}
+	if oui.IsLocal(mac) {
+		a.printf("%s  locally administered (randomized or virtual), no vendor\n", mac)
+		return nil
+	}
+	a.errorf("No vendor known for %s\n", mac)
+	return fmt.Errorf("unknown OUI: %s", mac)
+}
+
 // RunVPN manages VPN connections.
 // If arg is empty, lists all configured VPNs with their status.
 // If arg is "stop", disconnects all active VPNs.
@@ -630,7 +1214,8 @@ func (a *App) RunGenkey() error {
 // RunShow displays configuration.
 // If networkName is empty, shows all configuration (common settings, networks, VPNs).
 // If networkName is specified, shows that network's config merged with common settings.
-// Sensitive values like PSK are masked in the output.
+// Sensitive values like PSK are masked in the output; secret references are
+// shown as written and never resolved.
 func (a *App) RunShow(networkName string) error {
 	if networkName == "" {
 		// Show all configurations
@@ -678,24 +1263,43 @@ func (a *App) RunShow(networkName string) error {
 			a.printf("  %s\n", iface)
 		}
 	} else {
-		// Show specific network
-		config, err := a.ConfigMgr.GetNetworkConfig(networkName)
-		if err != nil {
-			a.Logger.Error("Failed to get network config", "name", networkName, "error", err)
-			a.errorf("Error: %v\n", err)
-			return err
+		// Show specific network. A PSK written as a secret reference is shown
+		// as written from the raw config, without resolving it (which could
+		// run a password manager just to mask its output).
+		var config *types.NetworkConfig
+		if raw := a.rawNetworkConfig(networkName); raw != nil && secrets.IsReference(raw.PSK) {
+			config = raw
+		} else {
+			var err error
+			config, err = a.ConfigMgr.GetNetworkConfig(networkName)
+			if err != nil {
+				a.Logger.Error("Failed to get network config", "name", networkName, "error", err)
+				a.errorf("Error: %v\n", err)
+				return err
+			}
+			// Aliases are only cached after the lookup above.
+			if raw := a.rawNetworkConfig(networkName); raw != nil && secrets.IsReference(raw.PSK) {
+				resolved := *config
+				resolved.PSK = raw.PSK
+				config = &resolved
+			}
 		}
 
 		merged := a.ConfigMgr.MergeWithCommon(networkName, config)
 
 		a.printf("Network: %s\n", networkName)
+		if cfg := a.ConfigMgr.GetConfig(); cfg != nil && cfg.Sources[strings.ToLower(networkName)] != "" {
+			a.printf("Defined in: %s\n", cfg.Sources[strings.ToLower(networkName)])
+		}
 		if merged.Interface != "" {
 			a.printf("Interface: %s\n", merged.Interface)
 		}
 		if merged.SSID != "" {
 			a.printf("SSID: %s\n", merged.SSID)
 		}
-		if merged.PSK != "" {
+		if secrets.IsReference(merged.PSK) {
+			a.printf("PSK: %s (secret reference)\n", merged.PSK)
+		} else if merged.PSK != "" {
 			a.printf("PSK: %s\n", maskSecret(merged.PSK))
 		}
 		if len(merged.DNS) > 0 {
@@ -707,13 +1311,49 @@ func (a *App) RunShow(networkName string) error {
 		if merged.Hostname != "" {
 			a.printf("Hostname: %s\n", merged.Hostname)
 		}
+		if merged.HostnameMode != "" {
+			a.printf("Hostname mode: %s\n", merged.HostnameMode)
+		}
+		if merged.DHCPAnonymous {
+			a.printf("DHCP: anonymous (RFC 7844)\n")
+		}
 		if merged.VPN != "" {
 			a.printf("VPN: %s\n", merged.VPN)
 		}
+		if merged.Trust != "" {
+			a.printf("Trust: %s\n", merged.Trust)
+		}
+		for _, rule := range []struct {
+			kind   string
+			values []string
+		}{
+			{"bssid", merged.Match.BSSID},
+			{"gateway-mac", merged.Match.GatewayMAC},
+			{"domain", merged.Match.Domain},
+			{"switch", merged.Match.Switch},
+		} {
+			if len(rule.values) > 0 {
+				a.printf("Match %s: %s\n", rule.kind, strings.Join(rule.values, ", "))
+			}
+		}
 	}
 	return nil
 }
 
+// rawNetworkConfig returns a copy of the network's entry in the loaded config
+// as written (secret references unresolved), or nil if it isn't there.
+func (a *App) rawNetworkConfig(name string) *types.NetworkConfig {
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+	raw, ok := cfg.Network(name)
+	if !ok {
+		return nil
+	}
+	return &raw
+}
+
 // RunPortal probes for internet connectivity and captive portals, printing
 // the portal login URL when one is detected. Returns the detected status so
 // the CLI can map it to scripting-friendly exit codes; the status is only
@@ -811,7 +1451,11 @@ func (a *App) RunStatus() error {
 	if err != nil {
 		a.Logger.Debug("Failed to get hostname", "error", err)
 	} else {
-		a.printf("\nHostname:  %s\n", strings.TrimSpace(hostname))
+		line := strings.TrimSpace(hostname)
+		if p := a.primaryProfile(); p != nil && p.HostnameMode != "" {
+			line += " (hostname_mode: " + p.HostnameMode + ")"
+		}
+		a.printf("\nHostname:  %s\n", line)
 	}
 
 	// Interface info
@@ -824,7 +1468,9 @@ func (a *App) RunStatus() error {
 	} else {
 		macInfo := mac
 		config := a.ConfigMgr.GetConfig()
-		if config != nil {
+		if policy := a.NetworkMgr.MACPolicy(a.Interface); policy != "" {
+			macInfo = mac + " (" + policy + ")"
+		} else if config != nil {
 			commonMAC := config.Common.MAC
 			if commonMAC == "random" {
 				macInfo = mac + " (random)"
@@ -841,6 +1487,29 @@ func (a *App) RunStatus() error {
 		if conn.SSID != "" {
 			a.printf("SSID:      %s\n", conn.SSID)
 		}
+		p := a.loadActiveProfile(a.Interface)
+		if p == nil {
+			p = a.primaryProfile()
+		}
+		if p != nil {
+			if p.Network != "" {
+				line := p.Network
+				if p.Rule != "" {
+					line += " (matched " + p.Rule + ")"
+				}
+				if p.Interface != "" && p.Interface != a.Interface {
+					line += " on " + p.Interface
+				}
+				a.printf("Profile:   %s\n", line)
+			}
+			if p.Trust != "" {
+				line := p.Trust
+				if len(p.Protections) > 0 {
+					line += " (" + strings.Join(p.Protections, ", ") + ")"
+				}
+				a.printf("Trust:     %s\n", line)
+			}
+		}
 
 		a.printf("State:     %s\n", conn.State)
 
@@ -975,6 +1644,14 @@ func (a *App) RunHotspot(action string, config *types.HotspotConfig) error {
 			a.errorf("Configuration required for start action\n")
 			return fmt.Errorf("configuration required")
 		}
+		if config.Interface == "" {
+			config.Interface = a.Interface
+		}
+		var common types.CommonConfig
+		if cfg := a.ConfigMgr.GetConfig(); cfg != nil {
+			common = cfg.Common
+		}
+		applyHotspotDefaults(config, &common)
 		a.progress("Starting hotspot...\n")
 		err := a.HotspotMgr.Start(config)
 		if err != nil {
@@ -984,12 +1661,13 @@ func (a *App) RunHotspot(action string, config *types.HotspotConfig) error {
 		}
 		a.printf("Hotspot '%s' started!\n", config.SSID)
 		a.printf("  SSID:     %s\n", config.SSID)
-		if config.Password != "" {
-			a.printf("  Security: WPA2 (password protected)\n")
-		} else {
-			a.printf("  Security: Open\n")
-		}
+		a.printf("  Security: %s\n", hotspotSecurityLabel(config))
 		a.printf("  Gateway:  %s\n", config.Gateway)
+		if status, err := a.HotspotMgr.GetStatus(); err == nil && status.Station != "" {
+			a.printf("  Channel:  %d (on %s, alongside %s's connection)\n", status.Channel, status.Interface, status.Station)
+		} else if err == nil && status.Channel != 0 {
+			a.printf("  Channel:  %d\n", status.Channel)
+		}
 
 	case "stop":
 		err := a.HotspotMgr.Stop()
@@ -1015,10 +1693,16 @@ func (a *App) RunHotspot(action string, config *types.HotspotConfig) error {
 		a.println("Hotspot Status:")
 		a.printf("  SSID:      %s\n", status.SSID)
 		a.printf("  Interface: %s\n", status.Interface)
+		if status.Station != "" {
+			a.printf("  Channel:   %d (shared with %s)\n", status.Channel, status.Station)
+		} else if status.Channel != 0 {
+			a.printf("  Channel:   %d\n", status.Channel)
+		}
 		if status.Gateway != nil {
 			a.printf("  Gateway:   %s\n", status.Gateway.String())
 		}
 		a.printf("  Clients:   %d\n", status.Clients)
+		a.warnHotspotChannel(status)
 
 	default:
 		a.errorf("Unknown action: %s\n", action)
@@ -1027,6 +1711,148 @@ func (a *App) RunHotspot(action string, config *types.HotspotConfig) error {
 	return nil
 }
 
+// warnHotspotChannel warns when the connection a running hotspot shares its
+// channel with has moved to another channel: the access point can't follow
+// it there, so clients lose it until the hotspot is restarted.
+func (a *App) warnHotspotChannel(status *types.HotspotStatus) {
+	if !status.Running || status.Station == "" || status.StationChannel == 0 || status.StationChannel == status.Channel {
+		return
+	}
+	a.errorf("Warning: %s moved to channel %d, the hotspot on %s stays on channel %d; restart it with `net hotspot stop` and `net hotspot start`.\n",
+		status.Station, status.StationChannel, status.Interface, status.Channel)
+}
+
+// HotspotProfile returns the named hotspot profile from the config, with its
+// password resolved, for `net hotspot start <profile>`. An empty name gives
+// an empty config that flags and defaults fill in.
+func (a *App) HotspotProfile(name string) (*types.HotspotConfig, error) {
+	if name == "" {
+		return &types.HotspotConfig{}, nil
+	}
+	config, err := a.ConfigMgr.GetHotspotConfig(name)
+	if err != nil {
+		a.errorf("Failed to load hotspot profile: %v\n", err)
+		return nil, err
+	}
+	return config, nil
+}
+
+// applyHotspotDefaults fills in what neither the profile nor the flags set:
+// the common settings, then the built-in defaults. A band without a channel
+// means any channel of the band.
+func applyHotspotDefaults(config *types.HotspotConfig, common *types.CommonConfig) {
+	if config.SSID == "" {
+		config.SSID = "net-hotspot"
+	}
+	if config.Channel == 0 && !config.AutoChannel {
+		if config.Band != "" {
+			config.AutoChannel = true
+		} else {
+			config.Channel = 6
+		}
+	}
+	if config.Gateway == "" {
+		config.Gateway = "192.168.50.1"
+	}
+	if config.IPRange == "" {
+		config.IPRange = "192.168.50.50,192.168.50.150"
+	}
+	if len(config.DNS) == 0 {
+		// Clients need server addresses: "dhcp" and other non-IP entries
+		// of common.dns mean nothing to them.
+		for _, server := range common.DNS {
+			if server = strings.TrimSpace(server); net.ParseIP(server) != nil {
+				config.DNS = append(config.DNS, server)
+			}
+		}
+	}
+	if len(config.DNS) == 0 {
+		config.DNS = []string{"8.8.8.8", "8.8.4.4"}
+	}
+}
+
+// RunHotspotClients lists the stations connected to the hotspot.
+func (a *App) RunHotspotClients() error {
+	clients, err := a.HotspotMgr.Clients()
+	if err != nil {
+		a.Logger.Error("Failed to list hotspot clients", "error", err)
+		a.errorf("Failed to list hotspot clients: %v\n", err)
+		return err
+	}
+	if len(clients) == 0 {
+		a.println("(no clients connected)")
+		return nil
+	}
+
+	a.printf("%-17s  %-15s  %-20s  %-12s  %-6s  %-9s  %-9s  %s\n", "MAC", "IP", "HOSTNAME", "VENDOR", "SIGNAL", "CONNECTED", "RX", "TX")
+	for _, c := range clients {
+		// Hostnames come from the clients' DHCP requests; sanitize before
+		// printing to prevent terminal-escape injection.
+		hostname := system.SanitizeForTerminal(c.Hostname)
+		if hostname == "" {
+			hostname = "-"
+		}
+		ip, vendor, signal := c.IP, c.Vendor, "-"
+		if ip == "" {
+			ip = "-"
+		}
+		if vendor == "" {
+			vendor = "-"
+		}
+		if c.Signal != 0 {
+			signal = fmt.Sprintf("%d", c.Signal)
+		}
+		a.printf("%-17s  %-15s  %-20s  %-12s  %-6s  %-9s  %-9s  %s\n", c.MAC, ip, hostname, vendor, signal,
+			c.ConnectedTime.Round(time.Second).String(), formatBytes(c.RxBytes), formatBytes(c.TxBytes))
+	}
+	return nil
+}
+
+// RunHotspotKick disconnects a station from the hotspot and denies it.
+func (a *App) RunHotspotKick(mac string) error {
+	if err := a.HotspotMgr.Kick(mac); err != nil {
+		a.Logger.Error("Failed to disconnect hotspot client", "error", err)
+		a.errorf("Failed to disconnect %s: %v\n", mac, err)
+		return err
+	}
+	a.printf("✓ Disconnected %s (denied until the hotspot stops; add it to deny: to keep it out for good)\n", mac)
+	return nil
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.5 MiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// hotspotSecurityLabel describes the security mode of a hotspot config, which
+// defaults to WPA2 with a password and open without.
+func hotspotSecurityLabel(config *types.HotspotConfig) string {
+	mode := config.Security
+	if mode == "" && config.Password != "" {
+		mode = types.HotspotSecurityWPA2
+	}
+	switch mode {
+	case types.HotspotSecurityWPA2:
+		return "WPA2 (password protected)"
+	case types.HotspotSecurityWPA3:
+		return "WPA3 (password protected)"
+	case types.HotspotSecurityWPA2WPA3:
+		return "WPA2/WPA3 transition (password protected)"
+	case types.HotspotSecurityOWE:
+		return "Enhanced Open (encrypted, no password)"
+	}
+	return "Open"
+}
+
 // RunDHCPServer manages the DHCP server for hotspot mode.
 // Actions: "start" (requires config), "stop", "status".
 func (a *App) RunDHCPServer(action string, config *types.DHCPServerConfig) error {
@@ -1075,7 +1901,7 @@ func (a *App) RunDHCPServer(action string, config *types.DHCPServerConfig) error
 		if len(leases) == 0 {
 			a.println("\n(no active leases)")
 		} else {
-			a.printf("\n%-17s  %-15s  %-20s  %s\n", "MAC", "IP", "HOSTNAME", "EXPIRES")
+			a.printf("\n%-17s  %-15s  %-20s  %-12s  %s\n", "MAC", "IP", "HOSTNAME", "VENDOR", "EXPIRES")
 			for _, l := range leases {
 				// Lease hostnames come from LAN DHCP clients; sanitize before
 				// printing to prevent terminal-escape injection.
@@ -1083,7 +1909,11 @@ func (a *App) RunDHCPServer(action string, config *types.DHCPServerConfig) error
 				if hostname == "" {
 					hostname = "-"
 				}
-				a.printf("%-17s  %-15s  %-20s  %s\n", l.MAC, l.IP, hostname, l.Expiry.Format("2006-01-02 15:04"))
+				vendor := l.Vendor
+				if vendor == "" {
+					vendor = "-"
+				}
+				a.printf("%-17s  %-15s  %-20s  %-12s  %s\n", l.MAC, l.IP, hostname, vendor, l.Expiry.Format("2006-01-02 15:04"))
 			}
 		}
 
@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/angelfreak/net/pkg/config"
+	fakefirewall "github.com/angelfreak/net/pkg/firewall/fake"
 	fakenetlink "github.com/angelfreak/net/pkg/netlink/fake"
+	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/wifi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // testLogger implements types.Logger for testing
@@ -74,6 +82,13 @@ func (c *testConfigManager) GetConfig() *types.Config {
 
 func (c *testConfigManager) GetNetworkConfig(name string) (*types.NetworkConfig, error) {
 	if c.networkErr != nil {
+		// Profiles picked by SSID or match: rules are looked up by their
+		// config name.
+		if c.config != nil {
+			if nc, ok := c.config.Networks[name]; ok {
+				return &nc, nil
+			}
+		}
 		return nil, c.networkErr
 	}
 	return c.networkConfig, nil
@@ -88,12 +103,25 @@ func (c *testConfigManager) MergeWithCommon(name string, config *types.NetworkCo
 		if len(merged.DNS) == 0 && len(c.config.Common.DNS) > 0 {
 			merged.DNS = c.config.Common.DNS
 		}
+		policy := c.config.Common.Trust[merged.Trust]
+		if merged.MAC == "" && policy.MAC != "" {
+			merged.MAC = policy.MAC
+		}
 		if merged.MAC == "" && c.config.Common.MAC != "" {
 			merged.MAC = c.config.Common.MAC
 		}
+		if merged.VPN == "" && policy.VPN != "" {
+			merged.VPN = policy.VPN
+		}
+		if merged.HostnameMode == "" && policy.HideHostname && merged.Hostname == "" {
+			merged.HostnameMode = types.HostnameModeAnonymous
+		}
 		if merged.Hostname == "" && c.config.Common.Hostname != "" {
 			merged.Hostname = c.config.Common.Hostname
 		}
+		if merged.HostnameMode == "" {
+			merged.HostnameMode = c.config.Common.HostnameMode
+		}
 		// Only inherit VPN from common if not explicitly disabled for this network
 		if merged.VPN == "" && c.config.Common.VPN != "" {
 			if c.vpnExplicitlyDisabled == nil || !c.vpnExplicitlyDisabled[name] {
@@ -114,6 +142,15 @@ func (c *testConfigManager) GetVPNConfig(name string) (*types.VPNConfig, error)
 	return nil, errors.New("vpn not found")
 }
 
+func (c *testConfigManager) GetHotspotConfig(name string) (*types.HotspotConfig, error) {
+	if c.config != nil && c.config.Hotspot != nil {
+		if hotspot, ok := c.config.Hotspot[name]; ok {
+			return &hotspot, nil
+		}
+	}
+	return nil, fmt.Errorf("hotspot profile '%s' not found", name)
+}
+
 // testWiFiManager implements types.WiFiManager for testing
 type testWiFiManager struct {
 	connections []types.Connection
@@ -121,6 +158,27 @@ type testWiFiManager struct {
 	scanErr     error
 	connectErr  error
 	listErr     error
+	// roams are returned by successive Roam calls (nil entries: no roam);
+	// roamCalls counts the calls.
+	roams     []*types.RoamEvent
+	roamCalls int
+	// connectErrs fail successive Connect calls (then connectErr applies);
+	// passwords records the password of each call.
+	connectErrs []error
+	passwords   []string
+	// hidden records the SSIDs of ConnectHidden calls, pinned the bssid of
+	// ConnectWithBSSID calls; wpsCalls the bssid and pin of ConnectWPS
+	// calls, which return wpsCred or wpsErr.
+	hidden   []string
+	pinned   []string
+	wpsCalls [][2]string
+	wpsCred  *types.WPSCredential
+	wpsErr   error
+	// radios are the managers ForInterface returns for other interfaces
+	// (created on first use); disconnects counts Disconnect calls.
+	iface       string
+	radios      map[string]*testWiFiManager
+	disconnects int
 }
 
 func (w *testWiFiManager) Scan() ([]types.WiFiNetwork, error) {
@@ -131,14 +189,53 @@ func (w *testWiFiManager) Scan() ([]types.WiFiNetwork, error) {
 }
 
 func (w *testWiFiManager) Connect(ssid, password, hostname string) error {
+	w.passwords = append(w.passwords, password)
+	if n := len(w.passwords) - 1; n < len(w.connectErrs) {
+		return w.connectErrs[n]
+	}
 	return w.connectErr
 }
 
 func (w *testWiFiManager) ConnectWithBSSID(ssid, password, bssid, hostname string) error {
+	w.pinned = append(w.pinned, bssid)
+	w.passwords = append(w.passwords, password)
 	return w.connectErr
 }
 
+func (w *testWiFiManager) ConnectHidden(ssid, password, bssid, hostname string) error {
+	w.hidden = append(w.hidden, ssid)
+	w.passwords = append(w.passwords, password)
+	return w.connectErr
+}
+
+func (w *testWiFiManager) ConnectWPS(bssid, pin, hostname string) (*types.WPSCredential, error) {
+	w.wpsCalls = append(w.wpsCalls, [2]string{bssid, pin})
+	return w.wpsCred, w.wpsErr
+}
+
+func (w *testWiFiManager) DetectNetwork(ssid string) *types.WiFiNetwork {
+	for i := range w.networks {
+		if w.networks[i].SSID == ssid {
+			return &w.networks[i]
+		}
+	}
+	return nil
+}
+
+func (w *testWiFiManager) BestBSSID(ssid string, policy types.RoamConfig) (string, error) {
+	return "", nil
+}
+
+func (w *testWiFiManager) Roam(ssid string, policy types.RoamConfig) (*types.RoamEvent, error) {
+	w.roamCalls++
+	if w.roamCalls <= len(w.roams) {
+		return w.roams[w.roamCalls-1], nil
+	}
+	return nil, nil
+}
+
 func (w *testWiFiManager) Disconnect() error {
+	w.disconnects++
 	return nil
 }
 
@@ -150,9 +247,25 @@ func (w *testWiFiManager) ListConnections() ([]types.Connection, error) {
 }
 
 func (w *testWiFiManager) GetInterface() string {
+	if w.iface != "" {
+		return w.iface
+	}
 	return "wlan0"
 }
 
+func (w *testWiFiManager) ForInterface(iface string) types.WiFiManager {
+	if iface == "" || iface == w.GetInterface() {
+		return w
+	}
+	if w.radios == nil {
+		w.radios = make(map[string]*testWiFiManager)
+	}
+	if w.radios[iface] == nil {
+		w.radios[iface] = &testWiFiManager{iface: iface}
+	}
+	return w.radios[iface]
+}
+
 // testVPNManager implements types.VPNManager for testing
 type testVPNManager struct {
 	vpns       []types.VPNStatus
@@ -192,6 +305,15 @@ type testNetworkManager struct {
 	connectErr     error
 	connectionInfo *types.Connection
 	connectionErr  error
+	environment    *types.Environment    // returned by DetectEnvironment
+	connected      []types.NetworkConfig // configs passed to ConnectToConfiguredNetwork
+	encryptedDNS   []string              // servers passed to SetEncryptedDNS
+	encryptedDNSOn bool
+	macPolicy      string // returned by MACPolicy
+	savedHostname  bool   // RestoreHostname reports a hostname to restore
+	restoreCalls   int
+	dnsSet         chan []string // when set, receives the servers of each SetDNS
+	disconnected   []string      // interfaces passed to Disconnect
 }
 
 func (n *testNetworkManager) SetMAC(iface, mac string) error {
@@ -202,10 +324,29 @@ func (n *testNetworkManager) GetMAC(iface string) (string, error) {
 	return n.mac, nil
 }
 
+func (n *testNetworkManager) MACPolicy(iface string) string {
+	return n.macPolicy
+}
+
+func (n *testNetworkManager) RestoreHostname() (bool, error) {
+	n.restoreCalls++
+	restored := n.savedHostname
+	n.savedHostname = false
+	return restored, nil
+}
+
 func (n *testNetworkManager) SetDNS(servers []string) error {
+	if n.dnsSet != nil {
+		n.dnsSet <- servers
+	}
 	return n.setDNSErr
 }
 
+func (n *testNetworkManager) SetEncryptedDNS(servers []string) error {
+	n.encryptedDNS, n.encryptedDNSOn = servers, true
+	return nil
+}
+
 func (n *testNetworkManager) ClearDNS() error {
 	return nil
 }
@@ -230,9 +371,17 @@ func (n *testNetworkManager) ConnectToConfiguredNetwork(config *types.NetworkCon
 			config.Interface = "eth0"
 		}
 	}
+	n.connected = append(n.connected, *config)
 	return n.connectErr
 }
 
+func (n *testNetworkManager) DetectEnvironment(iface string) (*types.Environment, error) {
+	if n.environment != nil {
+		return n.environment, nil
+	}
+	return &types.Environment{Interface: iface}, nil
+}
+
 func (n *testNetworkManager) AddRoute(iface, destination, gateway string) error {
 	return nil
 }
@@ -260,6 +409,7 @@ func (n *testNetworkManager) GetConnectionInfo(iface string) (*types.Connection,
 }
 
 func (n *testNetworkManager) Disconnect(iface string) error {
+	n.disconnected = append(n.disconnected, iface)
 	return nil
 }
 
@@ -269,10 +419,14 @@ func (n *testNetworkManager) DisconnectAll() []string {
 
 // testHotspotManager implements types.HotspotManager for testing
 type testHotspotManager struct {
-	status    *types.HotspotStatus
-	startErr  error
-	stopErr   error
-	statusErr error
+	status     *types.HotspotStatus
+	clients    []types.HotspotClient
+	kicked     []string
+	startErr   error
+	stopErr    error
+	statusErr  error
+	clientsErr error
+	kickErr    error
 }
 
 func (h *testHotspotManager) Start(config *types.HotspotConfig) error {
@@ -293,6 +447,18 @@ func (h *testHotspotManager) GetStatus() (*types.HotspotStatus, error) {
 	return h.status, nil
 }
 
+func (h *testHotspotManager) Clients() ([]types.HotspotClient, error) {
+	return h.clients, h.clientsErr
+}
+
+func (h *testHotspotManager) Kick(mac string) error {
+	if h.kickErr != nil {
+		return h.kickErr
+	}
+	h.kicked = append(h.kicked, mac)
+	return nil
+}
+
 // testDHCPManager implements types.DHCPManager for testing
 type testDHCPManager struct {
 	running       bool
@@ -363,6 +529,20 @@ func TestApp_RunList_Success(t *testing.T) {
 	assert.Contains(t, stdout.String(), "192.168.1.100")
 }
 
+func TestApp_RunList_Radios(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.WiFiMgr = &testWiFiManager{
+		connections: []types.Connection{
+			{Interface: "wlan0", Radio: "phy#0", SSID: "Site", BSSID: "aa:00:00:00:00:01", State: "connected"},
+			{Interface: "wlan1", Radio: "phy#1", State: "disconnected"},
+		},
+	}
+
+	require.NoError(t, app.RunList())
+	assert.Equal(t, "Interface: wlan0\nRadio: phy#0\nSSID: Site\nBSSID: aa:00:00:00:00:01\nState: connected\n\n"+
+		"Interface: wlan1\nRadio: phy#1\nState: disconnected\n\n", stdout.String())
+}
+
 func TestApp_RunList_NoConnections(t *testing.T) {
 	app, stdout, _ := newTestApp()
 
@@ -389,7 +569,7 @@ func TestApp_RunScan_Success(t *testing.T) {
 		},
 	}
 
-	err := app.RunScan(false)
+	err := app.RunScan(scanFilter{})
 	assert.NoError(t, err)
 	assert.Contains(t, stdout.String(), "Scanning for networks...")
 	assert.Contains(t, stdout.String(), "Found 2 networks")
@@ -397,6 +577,21 @@ func TestApp_RunScan_Success(t *testing.T) {
 	assert.Contains(t, stdout.String(), "OpenNet")
 }
 
+func TestApp_RunScan_ShowsVendor(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.WiFiMgr = &testWiFiManager{
+		networks: []types.WiFiNetwork{
+			{SSID: "Office", BSSID: "00:00:0c:12:34:56", Signal: -50, Security: "WPA2"},
+			{SSID: "Phone", BSSID: "02:11:22:33:44:55", Signal: -60, Security: "WPA2"},
+		},
+	}
+
+	err := app.RunScan(scanFilter{})
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Office (00:00:0c:12:34:56, Cisco)")
+	assert.Contains(t, stdout.String(), "Phone (02:11:22:33:44:55)")
+}
+
 func TestApp_RunScan_ProgressSuppressedInDebugMode(t *testing.T) {
 	app, stdout, _ := newTestApp()
 	app.Debug = true
@@ -406,7 +601,7 @@ func TestApp_RunScan_ProgressSuppressedInDebugMode(t *testing.T) {
 		},
 	}
 
-	err := app.RunScan(false)
+	err := app.RunScan(scanFilter{})
 	assert.NoError(t, err)
 	// Progress messages should NOT appear in debug mode
 	assert.NotContains(t, stdout.String(), "Scanning for networks...")
@@ -424,7 +619,7 @@ func TestApp_RunScan_OpenOnly(t *testing.T) {
 		},
 	}
 
-	err := app.RunScan(true)
+	err := app.RunScan(scanFilter{Security: "open"})
 	assert.NoError(t, err)
 	assert.NotContains(t, stdout.String(), "Network1")
 	assert.Contains(t, stdout.String(), "OpenNet")
@@ -434,11 +629,58 @@ func TestApp_RunScan_Error(t *testing.T) {
 	app, _, stderr := newTestApp()
 	app.WiFiMgr = &testWiFiManager{scanErr: errors.New("scan failed")}
 
-	err := app.RunScan(false)
+	err := app.RunScan(scanFilter{})
 	assert.Error(t, err)
 	assert.Contains(t, stderr.String(), "scan failed")
 }
 
+func TestApp_RunScan_Filters(t *testing.T) {
+	networks := []types.WiFiNetwork{
+		{SSID: "Office", BSSID: "02:00:00:00:00:01", Signal: -45, Frequency: 5180, Channel: 36, Width: 80, Generation: "ax",
+			Security: "WPA2/WPA3", AKMs: []string{"WPA-PSK", "SAE"}, PMFCapable: true},
+		{SSID: "Office", BSSID: "02:00:00:00:00:02", Signal: -62, Frequency: 2412, Channel: 1, Width: 20, Generation: "n",
+			Security: "WPA2/WPA3", AKMs: []string{"WPA-PSK", "SAE"}, PMFCapable: true},
+		{BSSID: "02:00:00:00:00:03", Signal: -66, Frequency: 5745, Channel: 149, Width: 20, Security: "WPA2", AKMs: []string{"WPA-PSK"}, Hidden: true},
+		{SSID: "Printer", BSSID: "02:00:00:00:00:04", Signal: -75, Frequency: 2437, Channel: 6, Width: 20, Security: "WPA2", AKMs: []string{"WPA-PSK"}, WPS: true},
+	}
+
+	t.Run("details and hidden networks", func(t *testing.T) {
+		app, stdout, _ := newTestApp()
+		app.WiFiMgr = &testWiFiManager{networks: networks}
+		assert.NoError(t, app.RunScan(scanFilter{}))
+		assert.Contains(t, stdout.String(), "Found 4 networks")
+		assert.Contains(t, stdout.String(), "Office (02:00:00:00:00:01) - Signal: -45 dBm - Security: WPA2/WPA3 (WPA-PSK SAE, PMF capable) - Channel: 36 (5 GHz, 80 MHz) - 802.11ax\n")
+		assert.Contains(t, stdout.String(), "<hidden> (02:00:00:00:00:03)")
+		assert.Contains(t, stdout.String(), "Channel: 6 (2.4 GHz, 20 MHz) - WPS\n")
+	})
+
+	t.Run("band, security and minimum signal", func(t *testing.T) {
+		app, stdout, _ := newTestApp()
+		app.WiFiMgr = &testWiFiManager{networks: networks}
+		assert.NoError(t, app.RunScan(scanFilter{Band: "5", Security: "wpa3", MinSignal: -70}))
+		assert.Contains(t, stdout.String(), "Found 1 networks")
+		assert.Contains(t, stdout.String(), "02:00:00:00:00:01")
+		assert.NotContains(t, stdout.String(), "02:00:00:00:00:03")
+	})
+
+	t.Run("group by SSID keeps the strongest access point", func(t *testing.T) {
+		app, stdout, _ := newTestApp()
+		app.WiFiMgr = &testWiFiManager{networks: networks}
+		assert.NoError(t, app.RunScan(scanFilter{GroupBy: "ssid"}))
+		assert.Contains(t, stdout.String(), "Found 3 networks")
+		assert.Contains(t, stdout.String(), "802.11ax - 2 access points\n")
+		assert.NotContains(t, stdout.String(), "02:00:00:00:00:02")
+	})
+
+	t.Run("invalid filter is rejected before scanning", func(t *testing.T) {
+		app, _, stderr := newTestApp()
+		app.WiFiMgr = &testWiFiManager{scanErr: errors.New("should not scan")}
+		err := app.RunScan(scanFilter{Band: "3"})
+		assert.Error(t, err)
+		assert.Contains(t, stderr.String(), "invalid band")
+	})
+}
+
 func TestApp_RunConnect_DirectSSID(t *testing.T) {
 	app, stdout, _ := newTestApp()
 	// Config loaded fine (non-nil) but the name isn't a configured network
@@ -455,6 +697,302 @@ func TestApp_RunConnect_DirectSSID(t *testing.T) {
 	assert.Contains(t, stdout.String(), "Connected!")
 }
 
+// passwords returns a ReadPassword answering with answers in turn and
+// recording the prompts.
+func passwords(prompts *[]string, answers ...string) func(string) (string, error) {
+	return func(prompt string) (string, error) {
+		*prompts = append(*prompts, prompt)
+		if len(answers) == 0 {
+			return "", system.ErrNoTerminal
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+}
+
+func TestApp_RunConnect_PromptsForProtectedSSID(t *testing.T) {
+	app, _, _ := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+	wifiMgr := &testWiFiManager{networks: []types.WiFiNetwork{
+		{SSID: "Cafe", AKMs: []string{"SAE"}},
+		{SSID: "Library"},
+	}}
+	app.WiFiMgr = wifiMgr
+	var prompts []string
+	app.ReadPassword = passwords(&prompts, "hunter22")
+
+	require.NoError(t, app.RunConnect("Cafe", ""))
+	assert.Equal(t, []string{"Password for Cafe: "}, prompts)
+	assert.Equal(t, []string{"hunter22"}, wifiMgr.passwords)
+
+	// Open networks, and passwords on the command line, don't prompt.
+	require.NoError(t, app.RunConnect("Library", ""))
+	require.NoError(t, app.RunConnect("Cafe", "given"))
+	assert.Len(t, prompts, 1)
+	assert.Equal(t, []string{"hunter22", "", "given"}, wifiMgr.passwords)
+}
+
+func TestApp_RunConnect_ProtectedSSIDWithoutTerminal(t *testing.T) {
+	app, _, stderr := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+	wifiMgr := &testWiFiManager{networks: []types.WiFiNetwork{{SSID: "Cafe", AKMs: []string{"WPA-PSK"}}}}
+	app.WiFiMgr = wifiMgr
+	var prompts []string
+	app.ReadPassword = passwords(&prompts)
+
+	err := app.RunConnect("Cafe", "")
+	assert.ErrorContains(t, err, "Cafe needs a password")
+	assert.Contains(t, stderr.String(), `net connect "Cafe" <password>`)
+	assert.Empty(t, wifiMgr.passwords, "no connection attempt without a password")
+}
+
+func TestApp_RunConnect_RetriesWrongPasswordOnce(t *testing.T) {
+	wrongKey := fmt.Errorf("failed to associate with access point: %q: %w", "Cafe", wifi.ErrWrongKey)
+
+	t.Run("second try succeeds", func(t *testing.T) {
+		app, stdout, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		wifiMgr := &testWiFiManager{connectErrs: []error{wrongKey}}
+		app.WiFiMgr = wifiMgr
+		var prompts []string
+		app.ReadPassword = passwords(&prompts, "right")
+
+		require.NoError(t, app.RunConnect("Cafe", "wrong"))
+		assert.Equal(t, []string{"wrong", "right"}, wifiMgr.passwords)
+		assert.Contains(t, stderr.String(), "Wrong password for Cafe.")
+		assert.Contains(t, stdout.String(), "Connected!")
+	})
+
+	t.Run("only once", func(t *testing.T) {
+		app, _, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		wifiMgr := &testWiFiManager{connectErr: wrongKey}
+		app.WiFiMgr = wifiMgr
+		var prompts []string
+		app.ReadPassword = passwords(&prompts, "still wrong", "never asked")
+
+		err := app.RunConnect("Cafe", "wrong")
+		assert.True(t, errors.Is(err, wifi.ErrWrongKey))
+		assert.Len(t, prompts, 1)
+		assert.Contains(t, stderr.String(), "Error: failed to associate with access point: \"Cafe\": wrong password")
+	})
+
+	t.Run("other errors aren't retried", func(t *testing.T) {
+		app, _, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		wifiMgr := &testWiFiManager{connectErr: fmt.Errorf("%q: %w", "Cafe", wifi.ErrNetworkNotFound)}
+		app.WiFiMgr = wifiMgr
+		var prompts []string
+		app.ReadPassword = passwords(&prompts, "x")
+
+		assert.Error(t, app.RunConnect("Cafe", "pw"))
+		assert.Empty(t, prompts)
+	})
+}
+
+func TestApp_RunConnect_Save(t *testing.T) {
+	const existing = "# my networks\nhome:\n  ssid: Home # upstairs\n"
+
+	t.Run("inline password", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(existing), 0600))
+		app, stdout, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		app.WiFiMgr = &testWiFiManager{}
+		app.ConfigPath, app.Save = path, true
+
+		require.NoError(t, app.RunConnect("Cafe Central", "12345678"))
+		assert.Contains(t, stdout.String(), "Saved network 'cafe-central' to "+path)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, existing+"cafe-central:\n  ssid: Cafe Central\n  psk: \"12345678\"\n", string(data))
+	})
+
+	t.Run("password to secrets.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(existing), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets.yaml"), []byte("home: pw\n"), 0600))
+		app, _, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		app.WiFiMgr = &testWiFiManager{}
+		app.ConfigPath, app.Save, app.SaveName = path, true, "cafe"
+
+		require.NoError(t, app.RunConnect("Cafe", "secret pw"))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "cafe:\n  ssid: Cafe\n  psk: secret:cafe\n")
+		data, err = os.ReadFile(filepath.Join(dir, "secrets.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "home: pw\ncafe: secret pw\n", string(data))
+	})
+
+	t.Run("secret removed when the config is not saved", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		invalid := existing + "  sssid: typo\n"
+		require.NoError(t, os.WriteFile(path, []byte(invalid), 0600))
+		secretsPath := filepath.Join(dir, "secrets.yaml")
+		require.NoError(t, os.WriteFile(secretsPath, []byte("home: pw\n"), 0600))
+		app, _, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		app.WiFiMgr = &testWiFiManager{}
+		app.ConfigPath, app.Save, app.SaveName = path, true, "cafe"
+
+		assert.ErrorContains(t, app.RunConnect("Cafe", "secret pw"), "not saving an invalid config")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, invalid, string(data))
+		data, err = os.ReadFile(secretsPath)
+		require.NoError(t, err)
+		assert.Equal(t, "home: pw\n", string(data))
+	})
+
+	t.Run("name in use", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(existing), 0600))
+		app, _, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		app.WiFiMgr = &testWiFiManager{}
+		app.ConfigPath, app.Save, app.SaveName = path, true, "home"
+
+		assert.Error(t, app.RunConnect("Cafe", ""))
+		assert.Contains(t, stderr.String(), "'home' already exists in the config")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, existing, string(data))
+	})
+
+	t.Run("new config file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "net", "config.yaml")
+		app, _, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+		app.WiFiMgr = &testWiFiManager{}
+		app.ConfigPath, app.Save = path, true
+
+		require.NoError(t, app.RunConnect("Library", ""))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "library:\n  ssid: Library\n", string(data))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+}
+
+func TestApp_RunConnect_Hidden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	app, _, _ := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+	wifiMgr := &testWiFiManager{}
+	app.WiFiMgr = wifiMgr
+	var prompts []string
+	app.ReadPassword = passwords(&prompts, "12345678")
+	app.ConfigPath, app.Save, app.Hidden = path, true, true
+
+	require.NoError(t, app.RunConnect("Gateway", ""))
+	assert.Equal(t, []string{"Gateway"}, wifiMgr.hidden)
+	assert.Equal(t, []string{"Password for Gateway (empty if open): "}, prompts, "prompted without a scan")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "gateway:\n  ssid: Gateway\n  hidden: true\n  psk: \"12345678\"\n", string(data))
+}
+
+func TestApp_RunConnect_SavePinnedBSSID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	app, _, _ := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{}, networkErr: errors.New("not found")}
+	wifiMgr := &testWiFiManager{}
+	app.WiFiMgr = wifiMgr
+	app.ConfigPath, app.Save, app.BSSID = path, true, "AA:BB:CC:00:11:22"
+
+	require.NoError(t, app.RunConnect("Cafe", "12345678"))
+	assert.Equal(t, []string{"AA:BB:CC:00:11:22"}, wifiMgr.pinned)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "cafe:\n  ssid: Cafe\n  ap-addr: aa:bb:cc:00:11:22\n  psk: \"12345678\"\n", string(data))
+}
+
+func TestApp_RunConnectWPS(t *testing.T) {
+	const existing = "home:\n  ssid: Home\n"
+
+	t.Run("push-button saves the network", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(existing), 0600))
+		app, stdout, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{Networks: map[string]types.NetworkConfig{"home": {SSID: "Home"}}}}
+		wifiMgr := &testWiFiManager{wpsCred: &types.WPSCredential{SSID: "Printer Net", PSK: "s3cret-key"}}
+		app.WiFiMgr = wifiMgr
+		app.ConfigPath = path
+
+		require.NoError(t, app.RunConnectWPS("aa:bb:cc:00:00:01", "", false))
+		assert.Equal(t, [][2]string{{"aa:bb:cc:00:00:01", ""}}, wifiMgr.wpsCalls)
+		assert.Contains(t, stdout.String(), "Press the WPS button on the access point")
+		assert.Contains(t, stdout.String(), "Saved network 'printer-net' to "+path)
+		assert.Contains(t, stdout.String(), "Connected!")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, existing+"printer-net:\n  ssid: Printer Net\n  ap-addr: aa:bb:cc:00:00:01\n  psk: s3cret-key\n", string(data))
+	})
+
+	t.Run("generated PIN", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		app, stdout, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}}
+		wifiMgr := &testWiFiManager{wpsCred: &types.WPSCredential{SSID: "Gateway", BSSID: "AA:BB:CC:00:00:02"}}
+		app.WiFiMgr = wifiMgr
+		app.ConfigPath, app.SaveName = path, "iot"
+
+		require.NoError(t, app.RunConnectWPS("", "", true))
+		require.Len(t, wifiMgr.wpsCalls, 1)
+		pin := wifiMgr.wpsCalls[0][1]
+		assert.NoError(t, wifi.ValidateWPSPIN(pin))
+		assert.Contains(t, stdout.String(), "Enter PIN "+pin+" in the WPS settings of the access point")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "iot:\n  ssid: Gateway\n  ap-addr: aa:bb:cc:00:00:02\n", string(data))
+	})
+
+	t.Run("already configured", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(existing), 0600))
+		app, _, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{Networks: map[string]types.NetworkConfig{"home": {SSID: "Home"}}}}
+		app.WiFiMgr = &testWiFiManager{wpsCred: &types.WPSCredential{SSID: "Home", PSK: "12345678"}}
+		app.ConfigPath = path
+
+		require.NoError(t, app.RunConnectWPS("", "12345670", true))
+		assert.Contains(t, stderr.String(), "Note: Home is already configured as 'home', not saving it.")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, existing, string(data))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		app, _, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: &types.Config{}}
+		app.WiFiMgr = &testWiFiManager{wpsErr: wifi.ErrWPSTimeout}
+		app.ConfigPath = filepath.Join(t.TempDir(), "config.yaml")
+
+		assert.ErrorIs(t, app.RunConnectWPS("", "", false), wifi.ErrWPSTimeout)
+		assert.Contains(t, stderr.String(), "Error: no access point answered WPS within 2 minutes")
+		assert.NoFileExists(t, app.ConfigPath)
+	})
+
+	t.Run("bad PIN", func(t *testing.T) {
+		app, _, stderr := newTestApp()
+		wifiMgr := &testWiFiManager{}
+		app.WiFiMgr = wifiMgr
+
+		assert.Error(t, app.RunConnectWPS("", "12345678", true))
+		assert.Contains(t, stderr.String(), "wrong checksum digit")
+		assert.Empty(t, wifiMgr.wpsCalls)
+	})
+}
+
 func TestApp_RunConnect_SSIDMatchesConfiguredNetwork(t *testing.T) {
 	app, stdout, _ := newTestApp()
 	// GetNetworkConfig fails for the given name, but its SSID uniquely matches
@@ -513,6 +1051,342 @@ func TestApp_RunConnect_AmbiguousSSIDFallsBackToDirect(t *testing.T) {
 	assert.False(t, cfgMgr.mergeWithCommonCalled, "ambiguous SSID must not use configured path")
 }
 
+func TestApp_RunConnect_SharedSSIDPicksProfileByMatchRules(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.ProfileStateDir = t.TempDir()
+	tracker := &trackingVPNManager{}
+	app.VPNMgr = tracker
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{MAC: "random"},
+			Networks: map[string]types.NetworkConfig{
+				"guest-berlin": {SSID: "Guest", DNS: []string{"10.1.0.53"}, Match: types.MatchConfig{BSSID: []string{"00:11:22"}}},
+				"guest-paris":  {SSID: "Guest", VPN: "paris", Match: types.MatchConfig{BSSID: []string{"00:11:33"}}},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{environment: &types.Environment{Interface: "wlan0", SSID: "Guest", BSSID: "00:11:33:aa:bb:cc"}}
+	app.NetworkMgr = netMgr
+
+	err := app.RunConnect("Guest", "pw")
+	require.NoError(t, err)
+	// First association with the common settings, then the matched profile.
+	require.Len(t, netMgr.connected, 2)
+	assert.Equal(t, "random", netMgr.connected[0].MAC)
+	assert.Equal(t, "paris", netMgr.connected[1].VPN)
+	assert.Contains(t, stdout.String(), "Matched profile 'guest-paris' (bssid 00:11:33)")
+	assert.Equal(t, "paris", tracker.lastConnectName)
+
+	app.NetworkMgr = &testNetworkManager{connectionInfo: &types.Connection{Interface: "wlan0", SSID: "Guest", State: "connected"}}
+	stdout.Reset()
+	require.NoError(t, app.RunStatus())
+	assert.Contains(t, stdout.String(), "Profile:   guest-paris (matched bssid 00:11:33)")
+
+	require.NoError(t, app.RunStop(nil))
+	stdout.Reset()
+	require.NoError(t, app.RunStatus())
+	assert.NotContains(t, stdout.String(), "Profile:")
+}
+
+func TestApp_RunConnect_SharedSSIDWithoutMatchKeepsCommonSettings(t *testing.T) {
+	app, _, stderr := newTestApp()
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Networks: map[string]types.NetworkConfig{
+				"guest-berlin": {SSID: "Guest", Match: types.MatchConfig{BSSID: []string{"00:11:22"}}},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{environment: &types.Environment{SSID: "Guest", BSSID: "00:99:99:00:00:01"}}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("Guest", "pw"))
+	assert.Len(t, netMgr.connected, 1)
+	assert.Contains(t, stderr.String(), "no profile for 'Guest' matches this site")
+}
+
+func TestApp_RunConnect_UnknownSSIDIsPublic(t *testing.T) {
+	app, stdout, stderr := newTestApp()
+	app.ProfileStateDir = t.TempDir()
+	tracker := &trackingVPNManager{}
+	app.VPNMgr = tracker
+	fw := &fakefirewall.Manager{}
+	app.FirewallMgr = fw
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{
+				VPN: "home",
+				DNS: []string{"9.9.9.9"},
+				Trust: map[string]types.TrustPolicy{
+					"public": {VPN: "travel", KillSwitch: true, Firewall: true, MAC: "random", EncryptedDNS: true},
+				},
+			},
+			VPN: map[string]types.VPNConfig{
+				"travel": {Type: "wireguard", Config: "[Peer]\nEndpoint = 198.51.100.1:51820\n"},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("Hotel WiFi", ""))
+	require.Len(t, netMgr.connected, 1)
+	assert.Equal(t, types.TrustPublic, netMgr.connected[0].Trust)
+	assert.Equal(t, "random", netMgr.connected[0].MAC)
+	assert.Equal(t, "travel", tracker.lastConnectName)
+	assert.Equal(t, "wlan0", fw.Inbound)
+	assert.Equal(t, "wlan0", fw.KillSwitch)
+	assert.Equal(t, []string{"198.51.100.1"}, fw.KillSwitchAllowed)
+	assert.True(t, netMgr.encryptedDNSOn)
+	assert.Equal(t, []string{"9.9.9.9"}, netMgr.encryptedDNS)
+	assert.Contains(t, stdout.String(), "Trust:   public")
+	assert.Empty(t, stderr.String())
+
+	app.NetworkMgr = &testNetworkManager{connectionInfo: &types.Connection{Interface: "wlan0", SSID: "Hotel WiFi", State: "connected"}}
+	stdout.Reset()
+	require.NoError(t, app.RunStatus())
+	assert.Contains(t, stdout.String(), "Trust:     public (firewall, encrypted DNS, kill switch)")
+
+	require.NoError(t, app.RunStop(nil))
+	assert.Equal(t, "", fw.KillSwitch)
+	assert.Equal(t, "", fw.Inbound)
+	assert.Contains(t, stdout.String(), "Trust policy")
+}
+
+func TestApp_RunConnect_TrustedNetworkLiftsKillSwitch(t *testing.T) {
+	app, _, _ := newTestApp()
+	fw := &fakefirewall.Manager{KillSwitch: "wlan0", Inbound: "wlan0"}
+	app.FirewallMgr = fw
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{
+				Trust: map[string]types.TrustPolicy{
+					"trusted": {MAC: "permanent"},
+					"public":  {KillSwitch: true, Firewall: true},
+				},
+			},
+			Networks: map[string]types.NetworkConfig{
+				"home": {SSID: "Home", Trust: "trusted"},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("home", ""))
+	require.Len(t, netMgr.connected, 1)
+	assert.Equal(t, "permanent", netMgr.connected[0].MAC)
+	assert.Equal(t, "", fw.KillSwitch)
+	assert.Equal(t, "", fw.Inbound)
+	assert.False(t, netMgr.encryptedDNSOn)
+}
+
+func TestApp_RunConnect_KillSwitchBeforeVPN(t *testing.T) {
+	app, _, _ := newTestApp()
+	fw := &fakefirewall.Manager{}
+	app.FirewallMgr = fw
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{
+				Trust: map[string]types.TrustPolicy{"public": {VPN: "travel", KillSwitch: true}},
+			},
+			VPN: map[string]types.VPNConfig{
+				"travel": {Type: "wireguard", Config: "[Peer]\nEndpoint = 198.51.100.1:51820\n"},
+			},
+		},
+	}
+	app.NetworkMgr = &testNetworkManager{connectionInfo: &types.Connection{
+		Interface: "wlan0", State: "connected", DNS: []net.IP{net.ParseIP("192.0.2.53")},
+	}}
+	var atConnect []string
+	tracker := &trackingVPNManager{onConnect: func() {
+		atConnect = append([]string{fw.KillSwitch}, fw.KillSwitchAllowed...)
+	}}
+	app.VPNMgr = tracker
+
+	require.NoError(t, app.RunConnect("Hotel WiFi", ""))
+	// Up while the tunnel comes up, with the network's DNS server reachable
+	// for the VPN client, then tightened to the VPN server.
+	assert.Equal(t, []string{"wlan0", "198.51.100.1", "192.0.2.53"}, atConnect)
+	assert.Equal(t, "wlan0", fw.KillSwitch)
+	assert.Equal(t, []string{"198.51.100.1"}, fw.KillSwitchAllowed)
+
+	t.Run("stays up when the VPN fails", func(t *testing.T) {
+		fw.KillSwitch, fw.KillSwitchAllowed = "", nil
+		app.VPNMgr = &testVPNManager{connectErr: errors.New("handshake timeout")}
+		require.NoError(t, app.RunConnect("Hotel WiFi", ""))
+		assert.Equal(t, "wlan0", fw.KillSwitch)
+		assert.Equal(t, []string{"198.51.100.1"}, fw.KillSwitchAllowed)
+	})
+}
+
+// A kill switch the policy asks for but that can't go up fails the connect
+// and takes the link down, rather than leaving it unprotected.
+func TestApp_RunConnect_KillSwitchUnavailable(t *testing.T) {
+	newApp := func(vpnType string) (*App, *fakefirewall.Manager, *testNetworkManager, *trackingVPNManager, *bytes.Buffer) {
+		app, _, stderr := newTestApp()
+		fw := &fakefirewall.Manager{}
+		app.FirewallMgr = fw
+		app.ConfigMgr = &testConfigManager{
+			networkErr: errors.New("not found"),
+			config: &types.Config{
+				Common: types.CommonConfig{
+					Trust: map[string]types.TrustPolicy{"public": {VPN: "travel", KillSwitch: true, Firewall: true}},
+				},
+				VPN: map[string]types.VPNConfig{"travel": {Type: vpnType}},
+			},
+		}
+		netMgr := &testNetworkManager{}
+		app.NetworkMgr = netMgr
+		tracker := &trackingVPNManager{}
+		app.VPNMgr = tracker
+		return app, fw, netMgr, tracker, stderr
+	}
+
+	for _, vpnType := range []string{"tailscale", "netbird"} {
+		t.Run(vpnType, func(t *testing.T) {
+			app, fw, netMgr, tracker, stderr := newApp(vpnType)
+			err := app.RunConnect("Hotel WiFi", "")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "kill switch of the public policy can't be enabled")
+			assert.Contains(t, stderr.String(), vpnType+" VPNs have no fixed server addresses")
+			assert.Equal(t, []string{"wlan0"}, netMgr.disconnected)
+			assert.Empty(t, tracker.lastConnectName, "the VPN is not started")
+			assert.Equal(t, "", fw.Inbound)
+			assert.Equal(t, "", fw.KillSwitch)
+		})
+	}
+
+	t.Run("without iptables", func(t *testing.T) {
+		app, _, netMgr, _, stderr := newApp("wireguard")
+		app.FirewallMgr = nil
+		require.Error(t, app.RunConnect("Hotel WiFi", ""))
+		assert.Contains(t, stderr.String(), "iptables is unavailable")
+		assert.Equal(t, []string{"wlan0"}, netMgr.disconnected)
+	})
+
+	t.Run("not with --no-vpn", func(t *testing.T) {
+		app, _, netMgr, _, stderr := newApp("tailscale")
+		app.NoVPN = true
+		require.NoError(t, app.RunConnect("Hotel WiFi", ""))
+		assert.Empty(t, netMgr.disconnected)
+		assert.Contains(t, stderr.String(), "kill switch of the public policy not enabled with --no-vpn")
+	})
+}
+
+func TestApp_RunConnect_PortalKeepsKillSwitch(t *testing.T) {
+	app, _, stderr := newTestApp()
+	fw := &fakefirewall.Manager{}
+	app.FirewallMgr = fw
+	app.PortalDet = &testPortalDetector{results: []types.PortalResult{{Status: types.PortalStatusPortal}}}
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{
+				Trust: map[string]types.TrustPolicy{"public": {KillSwitch: true, Firewall: true, EncryptedDNS: true}},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("Airport", ""))
+	assert.Equal(t, "wlan0", fw.Inbound)
+	assert.Equal(t, "wlan0", fw.KillSwitch)
+	assert.False(t, netMgr.encryptedDNSOn)
+	assert.Contains(t, stderr.String(), "encrypted DNS of the public policy is off until the portal login is done")
+	assert.Contains(t, stderr.String(), "kill switch blocks the portal login page")
+}
+
+func TestApp_RunConnect_HostnameMode(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.ProfileStateDir = t.TempDir()
+	fw := &fakefirewall.Manager{}
+	app.FirewallMgr = fw
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{HostnameMode: types.HostnameModeSystem, Hostname: "laptop"},
+			Networks: map[string]types.NetworkConfig{
+				"cafe": {SSID: "Cafe", HostnameMode: types.HostnameModeAnonymous},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{connectionInfo: &types.Connection{Interface: "wlan0", SSID: "Cafe", State: "connected"}}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("cafe", ""))
+	require.Len(t, netMgr.connected, 1)
+	assert.Equal(t, types.HostnameModeAnonymous, netMgr.connected[0].HostnameMode)
+	assert.Equal(t, "wlan0", fw.Names)
+	assert.Equal(t, 1, netMgr.restoreCalls, "the previous connection's hostname is restored first")
+
+	stdout.Reset()
+	require.NoError(t, app.RunStatus())
+	assert.Contains(t, stdout.String(), "(hostname_mode: anonymous)")
+
+	netMgr.savedHostname = true
+	require.NoError(t, app.RunStop(nil))
+	assert.Equal(t, "", fw.Names)
+	assert.Contains(t, stdout.String(), "• Hostname")
+}
+
+func TestApp_RunConnect_HideHostnameIsAnonymous(t *testing.T) {
+	app, _, _ := newTestApp()
+	fw := &fakefirewall.Manager{}
+	app.FirewallMgr = fw
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{
+				Hostname: "laptop",
+				Trust:    map[string]types.TrustPolicy{"public": {HideHostname: true}},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("Airport", ""))
+	require.Len(t, netMgr.connected, 1)
+	assert.Equal(t, types.HostnameModeAnonymous, netMgr.connected[0].HostnameMode)
+	assert.Equal(t, "wlan0", fw.Names, "name broadcasts are blocked too")
+}
+
+func TestApp_RunAuto(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Networks: map[string]types.NetworkConfig{
+				"office": {Routes: []string{"10.0.0.0/8 -> 10.1.0.1"}, Match: types.MatchConfig{GatewayMAC: []string{"aa:bb:cc:dd:ee:ff"}}},
+				"lab":    {Match: types.MatchConfig{Switch: []string{"sw-lab"}}},
+			},
+		},
+	}
+	netMgr := &testNetworkManager{environment: &types.Environment{Interface: "eth0", GatewayMAC: "AA:BB:CC:DD:EE:FF"}}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunAuto(""))
+	require.Len(t, netMgr.connected, 2)
+	assert.Equal(t, []string{"10.0.0.0/8 -> 10.1.0.1"}, netMgr.connected[1].Routes)
+	assert.Contains(t, stdout.String(), "Matched profile 'office' (gateway-mac aa:bb:cc:dd:ee:ff)")
+
+	// Nothing matches: stays up with the common settings.
+	netMgr.connected = nil
+	netMgr.environment = &types.Environment{Interface: "eth0", GatewayMAC: "00:00:5e:00:53:01"}
+	stdout.Reset()
+	require.NoError(t, app.RunAuto("eth1"))
+	require.Len(t, netMgr.connected, 1)
+	assert.Equal(t, "eth1", netMgr.connected[0].Interface)
+	assert.Contains(t, stdout.String(), "No profile matches this network")
+}
+
 func TestApp_RunConnect_FailsWhenConfigNotLoaded(t *testing.T) {
 	app, _, stderr := newTestApp()
 	// GetConfig() returns nil — the config file failed to load. Connect must
@@ -586,6 +1460,38 @@ func TestApp_RunStop_AllServices(t *testing.T) {
 	assert.Contains(t, stdout.String(), "Stopped services")
 }
 
+func TestApp_RunStop_AllRadios(t *testing.T) {
+	radios := []types.Connection{
+		{Interface: "wlan0", Radio: "phy#0", SSID: "Site", State: "connected"},
+		{Interface: "wlan1", Radio: "phy#1", SSID: "Mgmt", State: "connected"},
+		{Interface: "wlan2", Radio: "phy#2", State: "disconnected"},
+		{Interface: "wlan0_ap", Radio: "phy#0", SSID: "Hotspot", State: "access point"},
+	}
+
+	t.Run("stop everything", func(t *testing.T) {
+		app, _, _ := newTestApp()
+		wifi := &testWiFiManager{connections: radios}
+		app.WiFiMgr = wifi
+
+		require.NoError(t, app.RunStop(nil))
+		assert.Equal(t, 1, wifi.disconnects)
+		assert.Equal(t, 1, wifi.radios["wlan1"].disconnects)
+		assert.NotContains(t, wifi.radios, "wlan2", "idle radios are left alone")
+		assert.NotContains(t, wifi.radios, "wlan0_ap")
+	})
+
+	t.Run("stop one radio", func(t *testing.T) {
+		app, stdout, _ := newTestApp()
+		wifi := &testWiFiManager{connections: radios}
+		app.WiFiMgr = wifi
+
+		require.NoError(t, app.RunStop([]string{"wlan1", "eth0"}))
+		assert.Equal(t, 1, wifi.radios["wlan1"].disconnects)
+		assert.Zero(t, wifi.disconnects)
+		assert.Contains(t, stdout.String(), "Stopped interface wlan1")
+	})
+}
+
 func TestApp_RunStop_SpecificInterface(t *testing.T) {
 	app, stdout, _ := newTestApp()
 
@@ -767,6 +1673,44 @@ func TestApp_RunHotspot_Start(t *testing.T) {
 	assert.Contains(t, stdout.String(), "TestHotspot")
 }
 
+func TestHotspotSecurityLabel(t *testing.T) {
+	assert.Equal(t, "Open", hotspotSecurityLabel(&types.HotspotConfig{}))
+	assert.Equal(t, "WPA2 (password protected)", hotspotSecurityLabel(&types.HotspotConfig{Password: "password123"}))
+	assert.Equal(t, "WPA3 (password protected)", hotspotSecurityLabel(&types.HotspotConfig{Password: "password123", Security: "wpa3"}))
+	assert.Equal(t, "WPA2/WPA3 transition (password protected)", hotspotSecurityLabel(&types.HotspotConfig{Password: "password123", Security: "wpa2-wpa3"}))
+	assert.Equal(t, "Enhanced Open (encrypted, no password)", hotspotSecurityLabel(&types.HotspotConfig{Security: "owe"}))
+}
+
+func TestApp_RunHotspot_StartAlongsideConnection(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.HotspotMgr = &testHotspotManager{
+		status: &types.HotspotStatus{Running: true, Interface: "wlan0_ap", Station: "wlan0", Channel: 44},
+	}
+
+	err := app.RunHotspot("start", &types.HotspotConfig{Interface: "wlan0", SSID: "TestHotspot", Gateway: "192.168.50.1"})
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "  Channel:  44 (on wlan0_ap, alongside wlan0's connection)\n")
+
+	stdout.Reset()
+	assert.NoError(t, app.RunHotspot("status", nil))
+	assert.Contains(t, stdout.String(), "  Channel:   44 (shared with wlan0)\n")
+}
+
+func TestApp_RunHotspot_StatusAfterStationRoamed(t *testing.T) {
+	app, _, stderr := newTestApp()
+	app.HotspotMgr = &testHotspotManager{
+		status: &types.HotspotStatus{Running: true, Interface: "wlan0_ap", Station: "wlan0", Channel: 44, StationChannel: 44},
+	}
+	assert.NoError(t, app.RunHotspot("status", nil))
+	assert.Empty(t, stderr.String())
+
+	app.HotspotMgr = &testHotspotManager{
+		status: &types.HotspotStatus{Running: true, Interface: "wlan0_ap", Station: "wlan0", Channel: 44, StationChannel: 149},
+	}
+	assert.NoError(t, app.RunHotspot("status", nil))
+	assert.Contains(t, stderr.String(), "Warning: wlan0 moved to channel 149, the hotspot on wlan0_ap stays on channel 44")
+}
+
 func TestApp_RunHotspot_Stop(t *testing.T) {
 	app, stdout, _ := newTestApp()
 
@@ -784,6 +1728,7 @@ func TestApp_RunHotspot_Status(t *testing.T) {
 			Interface: "wlan0",
 			Gateway:   net.ParseIP("192.168.50.1"),
 			Clients:   2,
+			Channel:   36,
 		},
 	}
 
@@ -791,6 +1736,7 @@ func TestApp_RunHotspot_Status(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, stdout.String(), "Hotspot Status")
 	assert.Contains(t, stdout.String(), "MyHotspot")
+	assert.Contains(t, stdout.String(), "  Channel:   36\n")
 }
 
 func TestApp_RunHotspot_StartError(t *testing.T) {
@@ -803,6 +1749,109 @@ func TestApp_RunHotspot_StartError(t *testing.T) {
 	assert.Contains(t, stderr.String(), "Failed to start hotspot")
 }
 
+func TestApp_HotspotProfile(t *testing.T) {
+	app, _, stderr := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{Hotspot: map[string]types.HotspotConfig{
+		"car": {SSID: "Car", Password: "car-password", AutoChannel: true, Band: "5"},
+	}}}
+
+	config, err := app.HotspotProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, &types.HotspotConfig{}, config)
+
+	config, err = app.HotspotProfile("car")
+	require.NoError(t, err)
+	assert.Equal(t, "Car", config.SSID)
+	assert.True(t, config.AutoChannel)
+
+	_, err = app.HotspotProfile("boat")
+	assert.Error(t, err)
+	assert.Contains(t, stderr.String(), "Failed to load hotspot profile: hotspot profile 'boat' not found")
+}
+
+func TestApplyHotspotDefaults(t *testing.T) {
+	config := &types.HotspotConfig{}
+	applyHotspotDefaults(config, &types.CommonConfig{})
+	assert.Equal(t, &types.HotspotConfig{
+		SSID: "net-hotspot", Channel: 6, Gateway: "192.168.50.1",
+		IPRange: "192.168.50.50,192.168.50.150", DNS: []string{"8.8.8.8", "8.8.4.4"},
+	}, config)
+
+	// A band without a channel picks one; profile values are kept
+	config = &types.HotspotConfig{SSID: "Car", Band: "5", Gateway: "10.0.0.1"}
+	applyHotspotDefaults(config, &types.CommonConfig{})
+	assert.True(t, config.AutoChannel)
+	assert.Zero(t, config.Channel)
+	assert.Equal(t, "Car", config.SSID)
+	assert.Equal(t, "10.0.0.1", config.Gateway)
+
+	// common.dns comes before the built-in servers, profile DNS before both
+	config = &types.HotspotConfig{}
+	applyHotspotDefaults(config, &types.CommonConfig{DNS: []string{"1.1.1.1", " 9.9.9.9", "dhcp"}})
+	assert.Equal(t, []string{"1.1.1.1", "9.9.9.9"}, config.DNS)
+
+	config = &types.HotspotConfig{}
+	applyHotspotDefaults(config, &types.CommonConfig{DNS: []string{"dhcp"}})
+	assert.Equal(t, []string{"8.8.8.8", "8.8.4.4"}, config.DNS)
+
+	config = &types.HotspotConfig{DNS: []string{"10.0.0.53"}}
+	applyHotspotDefaults(config, &types.CommonConfig{DNS: []string{"1.1.1.1"}})
+	assert.Equal(t, []string{"10.0.0.53"}, config.DNS)
+}
+
+func TestApp_RunHotspot_StartWithCommonDNS(t *testing.T) {
+	app, _, _ := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{Common: types.CommonConfig{DNS: []string{"1.1.1.1"}}}}
+
+	config := &types.HotspotConfig{SSID: "Test"}
+	assert.NoError(t, app.RunHotspot("start", config))
+	assert.Equal(t, []string{"1.1.1.1"}, config.DNS)
+}
+
+func TestApp_RunHotspotClients(t *testing.T) {
+	app, stdout, stderr := newTestApp()
+	app.HotspotMgr = &testHotspotManager{}
+	assert.NoError(t, app.RunHotspotClients())
+	assert.Equal(t, "(no clients connected)\n", stdout.String())
+
+	stdout.Reset()
+	app.HotspotMgr = &testHotspotManager{clients: []types.HotspotClient{
+		{MAC: "00:1b:63:aa:bb:cc", IP: "192.168.50.23", Hostname: "mac\x1b[2Jbook", Vendor: "Apple",
+			Signal: -52, ConnectedTime: 10*time.Minute + 300*time.Millisecond, RxBytes: 2048, TxBytes: 3 << 20},
+		{MAC: "02:11:22:33:44:55", ConnectedTime: 2 * time.Second, RxBytes: 12},
+	}}
+	assert.NoError(t, app.RunHotspotClients())
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, strings.Fields("MAC IP HOSTNAME VENDOR SIGNAL CONNECTED RX TX"), strings.Fields(lines[0]))
+	assert.Equal(t, strings.Fields("00:1b:63:aa:bb:cc 192.168.50.23 mac?[2Jbook Apple -52 10m0s 2.0 KiB 3.0 MiB"), strings.Fields(lines[1]))
+	assert.Equal(t, strings.Fields("02:11:22:33:44:55 - - - - 2s 12 B 0 B"), strings.Fields(lines[2]))
+
+	app.HotspotMgr = &testHotspotManager{clientsErr: errors.New("hotspot is not running")}
+	assert.Error(t, app.RunHotspotClients())
+	assert.Contains(t, stderr.String(), "Failed to list hotspot clients: hotspot is not running")
+}
+
+func TestApp_RunHotspotKick(t *testing.T) {
+	app, stdout, stderr := newTestApp()
+	mgr := &testHotspotManager{}
+	app.HotspotMgr = mgr
+	assert.NoError(t, app.RunHotspotKick("02:11:22:33:44:55"))
+	assert.Equal(t, []string{"02:11:22:33:44:55"}, mgr.kicked)
+	assert.Contains(t, stdout.String(), "✓ Disconnected 02:11:22:33:44:55")
+
+	mgr.kickErr = errors.New("02:11:22:33:44:55 is not connected to the hotspot")
+	assert.Error(t, app.RunHotspotKick("02:11:22:33:44:55"))
+	assert.Contains(t, stderr.String(), "Failed to disconnect 02:11:22:33:44:55: 02:11:22:33:44:55 is not connected to the hotspot")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "0 B", formatBytes(0))
+	assert.Equal(t, "1023 B", formatBytes(1023))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "1.0 GiB", formatBytes(1<<30))
+}
+
 func TestApp_RunDHCPServer_Start(t *testing.T) {
 	app, stdout, _ := newTestApp()
 	config := &types.DHCPServerConfig{
@@ -855,7 +1904,7 @@ func TestApp_RunDHCPServer_StatusWithLeases(t *testing.T) {
 			IPRange:   "192.168.100.50,192.168.100.150",
 		},
 		leases: []types.DHCPLease{
-			{MAC: "aa:bb:cc:dd:ee:ff", IP: "192.168.100.51", Hostname: "laptop"},
+			{MAC: "aa:bb:cc:dd:ee:ff", IP: "192.168.100.51", Hostname: "laptop", Vendor: "Apple"},
 		},
 	}
 
@@ -865,6 +1914,19 @@ func TestApp_RunDHCPServer_StatusWithLeases(t *testing.T) {
 	assert.Contains(t, output, "aa:bb:cc:dd:ee:ff")
 	assert.Contains(t, output, "192.168.100.51")
 	assert.Contains(t, output, "laptop")
+	assert.Contains(t, output, "Apple")
+}
+
+func TestApp_RunOUI(t *testing.T) {
+	app, stdout, stderr := newTestApp()
+	assert.NoError(t, app.RunOUI("B8-27-EB-01-02-03"))
+	assert.Contains(t, stdout.String(), "Raspberry Pi")
+
+	assert.NoError(t, app.RunOUI("02:00:00:00:00:01"))
+	assert.Contains(t, stdout.String(), "locally administered")
+
+	assert.Error(t, app.RunOUI("00:00:01"))
+	assert.Contains(t, stderr.String(), "No vendor known")
 }
 
 func TestApp_RunDHCPServer_StatusNotRunning(t *testing.T) {
@@ -1281,18 +2343,24 @@ func TestApp_RunShow_MergesWithCommon(t *testing.T) {
 type trackingVPNManager struct {
 	testVPNManager
 	disconnectCalled bool
+	disconnected     []string // names passed to Disconnect ("" for all)
 	connectCalled    bool
 	lastConnectName  string
+	onConnect        func() // called from Connect, to observe state at that point
 }
 
 func (v *trackingVPNManager) Disconnect(name string) error {
 	v.disconnectCalled = true
+	v.disconnected = append(v.disconnected, name)
 	return nil
 }
 
 func (v *trackingVPNManager) Connect(name string) error {
 	v.connectCalled = true
 	v.lastConnectName = name
+	if v.onConnect != nil {
+		v.onConnect()
+	}
 	return nil
 }
 
@@ -1308,6 +2376,56 @@ func TestApp_RunConnect_DisconnectsActiveVPNFirst(t *testing.T) {
 	assert.True(t, vpnMgr.disconnectCalled, "should disconnect active VPN before connecting")
 }
 
+func TestApp_RunConnect_SecondRadioKeepsFirstConnection(t *testing.T) {
+	app, _, stderr := newTestApp()
+	app.ProfileStateDir = t.TempDir()
+	tracker := &trackingVPNManager{}
+	app.VPNMgr = tracker
+	app.ConfigMgr = &testConfigManager{
+		networkErr: errors.New("not found"),
+		config: &types.Config{
+			Common: types.CommonConfig{Trust: map[string]types.TrustPolicy{"public": {Firewall: true}}},
+			VPN:    map[string]types.VPNConfig{"work": {Type: "wireguard"}},
+			Networks: map[string]types.NetworkConfig{
+				"office": {SSID: "Office", VPN: "work", DNS: []string{"10.0.0.53"}},
+				"mgmt":   {SSID: "Mgmt", Interface: "wlan1", Trust: "public"},
+			},
+		},
+	}
+	firewall := &fakefirewall.Manager{}
+	app.FirewallMgr = firewall
+	netMgr := &testNetworkManager{}
+	app.NetworkMgr = netMgr
+
+	require.NoError(t, app.RunConnect("office", ""))
+	require.Equal(t, "work", tracker.lastConnectName)
+	tracker.disconnected = nil
+
+	require.NoError(t, app.RunConnect("mgmt", ""))
+	assert.Empty(t, tracker.disconnected, "the VPN of wlan0 must stay up")
+	require.Len(t, netMgr.connected, 2)
+	assert.False(t, netMgr.connected[0].KeepDNS)
+	assert.True(t, netMgr.connected[1].KeepDNS, "the DNS of wlan0 must stay in place")
+	assert.Empty(t, firewall.Inbound, "the trust policy is wlan0's to set")
+	assert.Contains(t, stderr.String(), "of the connection on wlan0 stay in effect")
+
+	office := app.loadActiveProfile("wlan0")
+	require.NotNil(t, office)
+	assert.Equal(t, "office", office.Network)
+	assert.Equal(t, "work", office.VPN)
+	assert.Empty(t, office.Alongside)
+	mgmt := app.loadActiveProfile("wlan1")
+	require.NotNil(t, mgmt)
+	assert.Equal(t, "mgmt", mgmt.Network)
+	assert.Equal(t, "wlan0", mgmt.Alongside)
+
+	// Reconnecting wlan0 leaves wlan1 alone too.
+	require.NoError(t, app.RunConnect("office", ""))
+	assert.Equal(t, []string{"work"}, tracker.disconnected)
+	assert.False(t, netMgr.connected[2].KeepDNS)
+	assert.NotNil(t, app.loadActiveProfile("wlan1"))
+}
+
 func TestMaskSecret(t *testing.T) {
 	// Short secrets are fully masked
 	assert.Equal(t, "****", maskSecret("abc"))
@@ -1339,6 +2457,38 @@ func TestApp_RunShow_MasksPSK(t *testing.T) {
 	assert.Contains(t, stdout.String(), "su***************rd") // masked version
 }
 
+func TestApp_RunShow_SecretReferenceNotResolved(t *testing.T) {
+	cfgMgr := &testConfigManager{
+		config: &types.Config{
+			Networks: map[string]types.NetworkConfig{
+				"work": {SSID: "WorkWiFi", PSK: "cmd:pass show wifi/work"},
+			},
+		},
+		networkErr: errors.New("must not resolve"),
+	}
+	app, stdout, _ := newTestApp()
+	app.ConfigMgr = cfgMgr
+
+	err := app.RunShow("work")
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "PSK: cmd:pass show wifi/work (secret reference)")
+}
+
+func TestApp_RunShow_Provenance(t *testing.T) {
+	cfgMgr := &testConfigManager{
+		config: &types.Config{
+			Networks: map[string]types.NetworkConfig{"office": {SSID: "OfficeWiFi"}},
+			Sources:  map[string]string{"office": "/etc/net/config.d/10-office.yaml"},
+		},
+		networkConfig: &types.NetworkConfig{SSID: "OfficeWiFi"},
+	}
+	app, stdout, _ := newTestApp()
+	app.ConfigMgr = cfgMgr
+
+	require.NoError(t, app.RunShow("Office"))
+	assert.Contains(t, stdout.String(), "Defined in: /etc/net/config.d/10-office.yaml")
+}
+
 // --- Task 4: net portal command tests ---
 
 // testPortalDetector returns results in sequence, repeating the last one.
@@ -1737,6 +2887,16 @@ func TestApp_RunStatus_ProbeErrorLine(t *testing.T) {
 	assert.Contains(t, stdout.String(), "Internet:  probe error")
 }
 
+func TestApp_RunStatus_LabelsMACPolicy(t *testing.T) {
+	app, stdout, _ := newTestApp()
+	app.ConfigMgr = &testConfigManager{config: &types.Config{Common: types.CommonConfig{MAC: "random"}}}
+	app.NetworkMgr = &testNetworkManager{mac: "02:11:22:33:44:55", macPolicy: "stable for Home"}
+
+	err := app.RunStatus()
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "MAC:       02:11:22:33:44:55 (stable for Home)")
+}
+
 func TestApp_RunStatus_ShowsInternetLine(t *testing.T) {
 	app, stdout, _ := newTestApp()
 	app.ConfigMgr = &testConfigManager{config: &types.Config{}} // loaded config: auto-probe allowed
@@ -1911,3 +3071,89 @@ func TestApp_RunStatus_PortalCheckOffSkipsProbe(t *testing.T) {
 	assert.Equal(t, 0, det.calls)
 	assert.NotContains(t, stdout.String(), "Internet:")
 }
+
+func TestApp_RunRoam(t *testing.T) {
+	cfg := &types.Config{Networks: map[string]types.NetworkConfig{
+		"office": {SSID: "Office", Roam: &types.RoamConfig{Threshold: -72}},
+		"home":   {SSID: "Home"},
+		"mgmt":   {SSID: "Mgmt", Interface: "wlan1", Roam: &types.RoamConfig{}},
+	}}
+	// A cancelled context stops the loop after the first check.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("prints roams", func(t *testing.T) {
+		app, stdout, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: cfg}
+		wifi := &testWiFiManager{roams: []*types.RoamEvent{
+			{SSID: "Office", From: "02:00:00:00:00:01", FromSignal: -78, To: "02:00:00:00:00:02", ToSignal: -55},
+		}}
+		app.WiFiMgr = wifi
+		assert.NoError(t, app.RunRoam(ctx, "office"))
+		assert.Equal(t, 1, wifi.roamCalls)
+		assert.Contains(t, stdout.String(), "Roaming on Office below -72 dBm, checking every 10s\n")
+		assert.Contains(t, stdout.String(), "Roamed from 02:00:00:00:00:01 (-78 dBm) to 02:00:00:00:00:02 (-55 dBm)\n")
+	})
+
+	t.Run("on the network's radio", func(t *testing.T) {
+		app, _, _ := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: cfg}
+		wifi := &testWiFiManager{}
+		app.WiFiMgr = wifi
+		assert.NoError(t, app.RunRoam(ctx, "mgmt"))
+		assert.Zero(t, wifi.roamCalls)
+		assert.Equal(t, 1, wifi.radios["wlan1"].roamCalls)
+	})
+
+	t.Run("network without roam policy", func(t *testing.T) {
+		app, _, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: cfg}
+		assert.Error(t, app.RunRoam(ctx, "home"))
+		assert.Contains(t, stderr.String(), "network home has no ssid and roam: policy")
+	})
+
+	t.Run("no network and no active profile", func(t *testing.T) {
+		app, _, stderr := newTestApp()
+		app.ConfigMgr = &testConfigManager{config: cfg}
+		assert.Error(t, app.RunRoam(ctx, ""))
+		assert.Contains(t, stderr.String(), "no active network profile")
+	})
+}
+
+func TestApp_RunRoam_ReappliesChangedConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	write := func(dns string) {
+		data := "office:\n  ssid: Office\n  dns: [" + dns + "]\n  roam:\n    threshold: -72\n"
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	}
+	write("1.1.1.1")
+	mgr := config.NewManager(&testLogger{})
+	_, err := mgr.LoadConfig(path)
+	require.NoError(t, err)
+
+	app, _, _ := newTestApp()
+	app.ConfigMgr = mgr
+	app.ProfileStateDir = filepath.Join(dir, "profiles")
+	app.saveActiveProfile(&activeProfile{Network: "office", Interface: "wlan0"})
+	network := &testNetworkManager{dnsSet: make(chan []string, 64)}
+	app.NetworkMgr = network
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.RunRoam(ctx, "") }()
+	// The watcher starts asynchronously: keep editing, slower than the
+	// reload debounce, until it sees one.
+	var servers []string
+	for i := 0; servers == nil && i < 10; i++ {
+		write(fmt.Sprintf("9.9.9.%d", i+1))
+		select {
+		case servers = <-network.dnsSet:
+		case <-time.After(time.Second):
+		}
+	}
+	cancel()
+	require.NoError(t, <-done)
+	require.NotNil(t, servers, "new DNS servers were never applied")
+	assert.Regexp(t, `^9\.9\.9\.\d+$`, servers[0])
+}
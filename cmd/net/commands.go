@@ -5,61 +5,27 @@ import (
 	"os"
 	"strings"
 
+	"github.com/angelfreak/net/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 // getNetworkNames returns a list of all network names from the config for completion
 func getNetworkNames() []string {
-	// Determine config file path
-	configFile := configPath
-	if configFile == "" {
-		// Use default path
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil
-		}
-		// Handle SUDO_USER for sudo execution (must match pkg/config/config.go logic)
-		if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
-			if sudoUser == "root" {
-				home = "/root"
-			} else {
-				home = "/home/" + sudoUser
-			}
-		}
-		configFile = home + "/.net/config.yaml"
-	}
-
-	// Read the config file to get all top-level keys
-	file, err := os.ReadFile(configFile)
+	path, err := config.ResolvePath(configPath)
 	if err != nil {
 		return nil
 	}
-
-	var networks []string
-	allKeys := make(map[string]bool)
-
-	lines := strings.Split(string(file), "\n")
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// Skip comments and empty lines
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-		// Check if this is a top-level key (no leading whitespace in original line)
-		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' && strings.Contains(line, ":") {
-			key := strings.TrimSpace(strings.Split(line, ":")[0])
-			// Skip special sections
-			if key != "common" && key != "ignored" && key != "vpn" {
-				allKeys[key] = true
-			}
-		}
+	// An encrypted config isn't decrypted (a passphrase prompt would break
+	// completion): it completes nothing.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
 	}
-
-	for key := range allKeys {
-		networks = append(networks, key)
+	doc, err := config.ParseDocument(data)
+	if err != nil {
+		return nil
 	}
-
-	return networks
+	return doc.Networks()
 }
 
 // installCompletion installs the completion script to the appropriate system location
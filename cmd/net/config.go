@@ -0,0 +1,621 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/angelfreak/net/pkg/config"
+	"github.com/angelfreak/net/pkg/system"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration file",
+	// Config subcommands work on the file itself; skip initializeManagers so
+	// an encrypted config isn't decrypted (and its passphrase asked for) twice.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		logger = system.NewLogger(debug)
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Args:  cobra.NoArgs,
+	Short: "Edit the configuration file (decrypting and re-encrypting if needed)",
+	Long: `Open the configuration file in $VISUAL or $EDITOR (default: vi).
+
+An encrypted config (config.yaml.age) is decrypted to a private temporary
+file, which is removed afterwards, and re-encrypted for the same key when
+saved. The edited file is validated before it replaces the original; on
+validation errors you can edit again or abort without saving.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := editConfig(configPath, os.Stdin, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Check the configuration for unknown fields and syntax errors",
+	Long: `Validate a config file. With no argument, the full configuration is
+loaded (main file, includes and drop-ins) and checked, including conflicts
+between files. With a file argument, only that file is checked.
+
+Exit codes: 0 = valid, 1 = validation errors, 2 = file could not be read.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		path := configPath
+		single := len(args) == 1
+		if single {
+			path = args[0]
+		}
+		os.Exit(validateConfig(path, single, asJSON, os.Stdout))
+	},
+}
+
+var configDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Args:  cobra.NoArgs,
+	Short: "Find problems in the configuration beyond unknown fields",
+	Long: `Load the configuration and check it for problems that only show up at
+connect time: dangling VPN references, alias cycles, duplicate SSIDs,
+invalid MAC templates, static gateways outside the addr subnet, overlapping
+routes, WireGuard configs that don't parse, unresolvable secret references,
+and missing binaries for the VPN types in use.
+
+Exit codes: 0 = no errors (warnings allowed), 1 = errors found,
+2 = config could not be loaded.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		executor := system.NewExecutor(logger, debug)
+		os.Exit(doctorConfig(configPath, executor.HasCommand, asJSON, os.Stdout))
+	},
+}
+
+var configWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Args:  cobra.NoArgs,
+	Short: "Reload the configuration whenever it changes and show what changed",
+	Long: `Watch the configuration files (main file, includes and drop-ins) and
+reload them on every change, printing the networks and VPNs affected.
+
+This is the reload loop long-running modes use: an edit only takes effect
+once the whole configuration loads and validates again; otherwise the error
+is reported and the previous configuration stays in effect. Runs until
+interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := watchConfig(context.Background(), configPath, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().Bool("json", false, "Print results as JSON")
+	configDoctorCmd.Flags().Bool("json", false, "Print results as JSON")
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configDoctorCmd)
+	configCmd.AddCommand(configWatchCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// Exit codes for config validate/doctor.
+const (
+	exitConfigOK      = 0
+	exitConfigInvalid = 1
+	exitConfigUnread  = 2
+)
+
+// validationResult is the JSON form of `net config validate`.
+type validationResult struct {
+	File   string            `json:"file"`
+	Valid  bool              `json:"valid"`
+	Error  string            `json:"error,omitempty"`
+	Issues []validationIssue `json:"issues"`
+}
+
+type validationIssue struct {
+	File       string `json:"file,omitempty"`
+	Section    string `json:"section,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+	Message    string `json:"message"`
+}
+
+// validateConfig implements `net config validate` and returns the exit code.
+// single checks only path; otherwise the full merged config is loaded.
+func validateConfig(path string, single, asJSON bool, out io.Writer) int {
+	resolved, err := config.ResolvePath(path)
+	result := validationResult{File: resolved, Issues: []validationIssue{}}
+	var errs config.ValidationErrors
+	if err == nil {
+		if single {
+			errs, err = config.CheckFile(resolved)
+		} else {
+			_, err = config.NewManager(logger).LoadConfig(resolved)
+			if ve, ok := err.(config.ValidationErrors); ok {
+				errs, err = ve, nil
+			}
+		}
+	}
+
+	code := exitConfigOK
+	switch {
+	case err != nil:
+		code = exitConfigUnread
+		result.Error = err.Error()
+	case len(errs) > 0:
+		code = exitConfigInvalid
+	}
+	result.Valid = code == exitConfigOK
+	for _, e := range errs {
+		result.Issues = append(result.Issues, validationIssue{
+			File: e.File, Section: e.Section, Field: e.Field, Suggestion: e.Suggestion, Message: e.Error(),
+		})
+	}
+
+	if asJSON {
+		writeJSON(out, result)
+		return code
+	}
+	switch code {
+	case exitConfigUnread:
+		fmt.Fprintf(out, "%s: %s\n", resolved, result.Error)
+	case exitConfigInvalid:
+		for _, issue := range result.Issues {
+			if issue.File != "" {
+				fmt.Fprintf(out, "error: %s\n", issue.Message)
+			} else {
+				fmt.Fprintf(out, "error: %s: %s\n", resolved, issue.Message)
+			}
+		}
+	default:
+		fmt.Fprintf(out, "%s: OK\n", resolved)
+	}
+	return code
+}
+
+// doctorResult is the JSON form of `net config doctor`.
+type doctorResult struct {
+	File     string           `json:"file"`
+	Error    string           `json:"error,omitempty"`
+	Findings []config.Finding `json:"findings"`
+}
+
+// doctorConfig implements `net config doctor` and returns the exit code.
+func doctorConfig(path string, hasCommand func(string) bool, asJSON bool, out io.Writer) int {
+	resolved, err := config.ResolvePath(path)
+	result := doctorResult{File: resolved, Findings: []config.Finding{}}
+	code := exitConfigOK
+	if err == nil {
+		mgr := config.NewManager(logger)
+		if _, err = mgr.LoadConfig(resolved); err == nil {
+			result.Findings = append(result.Findings, mgr.Doctor(hasCommand)...)
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		code = exitConfigUnread
+		if _, ok := err.(config.ValidationErrors); ok {
+			code = exitConfigInvalid
+		}
+	}
+	for _, f := range result.Findings {
+		if f.Severity == config.SeverityError {
+			code = exitConfigInvalid
+		}
+	}
+
+	if asJSON {
+		writeJSON(out, result)
+		return code
+	}
+	if result.Error != "" {
+		fmt.Fprintf(out, "%s: %s\n", resolved, result.Error)
+		return code
+	}
+	if len(result.Findings) == 0 {
+		fmt.Fprintf(out, "%s: no problems found\n", resolved)
+		return code
+	}
+	for _, f := range result.Findings {
+		fmt.Fprintf(out, "%s: %s: %s [%s]\n", f.Severity, f.Subject, f.Message, f.Check)
+	}
+	return code
+}
+
+// watchConfig implements `net config watch`: it loads the config at path and
+// prints a line for every reload that changed something until ctx is done.
+func watchConfig(ctx context.Context, path string, out io.Writer) error {
+	mgr := config.NewManager(logger)
+	if _, err := mgr.LoadConfig(path); err != nil {
+		return err
+	}
+	files := mgr.SourceFiles()
+	if len(files) == 0 {
+		return fmt.Errorf("no config file to watch")
+	}
+	fmt.Fprintf(out, "Watching %s\n", strings.Join(files, ", "))
+	return mgr.Watch(ctx, func(change config.Change) {
+		fmt.Fprintf(out, "Reloaded: %s\n", change)
+	})
+}
+
+func writeJSON(out io.Writer, v interface{}) {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// runEditor opens path in the user's editor. Overridable in tests.
+var runEditor = func(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run through the shell so EDITOR may carry arguments ("code --wait").
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// configPassphrase prompts for an encrypted config's passphrase. Overridable
+// in tests.
+var configPassphrase config.PassphraseFunc = system.ReadPassword
+
+// editConfig implements `net config edit`. in answers the "edit again?"
+// question after a validation failure; messages go to out.
+func editConfig(path string, in io.Reader, out io.Writer) error {
+	path, err := config.ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	plaintext := original
+	var key *config.ConfigKey
+	if config.IsEncrypted(original) {
+		plaintext, key, err = config.DecryptConfig(path, original, configPassphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Private directory so the plaintext is never readable by other users.
+	dir, err := os.MkdirTemp("", "net-config-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(tmp, plaintext, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	answers := bufio.NewReader(in)
+	var edited []byte
+	for {
+		if err := runEditor(tmp); err != nil {
+			return fmt.Errorf("editor failed: %w", err)
+		}
+		edited, err = os.ReadFile(tmp)
+		if err != nil {
+			return fmt.Errorf("failed to read edited config: %w", err)
+		}
+		if bytes.Equal(edited, plaintext) {
+			fmt.Fprintln(out, "No changes.")
+			return nil
+		}
+		problems := validateEditedConfig(path, edited)
+		if len(problems) == 0 {
+			break
+		}
+		fmt.Fprintln(out, "Config has errors:")
+		for _, p := range problems {
+			fmt.Fprintf(out, "  %s\n", p)
+		}
+		fmt.Fprint(out, "Edit again? [Y/n] ")
+		answer, _ := answers.ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "" && a != "y" && a != "yes" {
+			return fmt.Errorf("config not saved")
+		}
+	}
+
+	data := edited
+	if key != nil {
+		if data, err = config.EncryptConfig(edited, key); err != nil {
+			return err
+		}
+	}
+	if err := checkUnchanged(path, original, true); err != nil {
+		return err
+	}
+	if err := replaceFile(path, data, info); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Saved %s\n", path)
+	return nil
+}
+
+// validateEditedConfig returns human-readable problems with data as the new
+// content of the config at path: YAML syntax errors first, then unknown or
+// invalid fields and conflicts with the files it includes. data is loaded
+// from memory in place of path, so relative include: paths, config.d
+// drop-ins and secrets.yaml resolve as they will once it is saved, and the
+// plaintext of an encrypted config is never written next to it.
+func validateEditedConfig(path string, data []byte) []string {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return []string{err.Error()}
+	}
+
+	_, err := config.NewManager(nil).LoadConfigData(path, data)
+	var problems []string
+	if errs, ok := err.(config.ValidationErrors); ok {
+		for _, e := range errs {
+			problems = append(problems, e.Error())
+		}
+	} else if err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// updateConfigFile applies edit to the config file at path ("" for the
+// default) and writes it back: decrypted and re-encrypted when encrypted,
+// validated, and replaced atomically. Comments and untouched entries are
+// kept. A missing config file is created. edit gets the resolved path and
+// whether the file is encrypted; updateConfigFile returns the path. An entry
+// the edit adds must not be defined already by an include or drop-in.
+func updateConfigFile(path string, edit func(doc *config.Document, path string, encrypted bool) error) (string, error) {
+	return updateConfigEntry(path, "", edit)
+}
+
+// updateConfigEntry is updateConfigFile for an edit of an existing entry,
+// given by its types.Config.Sources key ("<network>", "vpn.<name>" or
+// "hotspot.<name>"): the file defining it is edited, which is an include or
+// drop-in rather than the main file when the entry comes from one.
+func updateConfigEntry(path, entry string, edit func(doc *config.Document, path string, encrypted bool) error) (string, error) {
+	path, err := config.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	prompt := cachedPassphrase(configPassphrase)
+	sources := configSources(path, prompt)
+	if file := sources[strings.ToLower(entry)]; entry != "" && file != "" {
+		path = file
+	}
+	original, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read config: %w", err)
+	}
+	var info os.FileInfo
+	if err == nil {
+		if info, err = os.Stat(path); err != nil {
+			return "", fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	plaintext := original
+	var key *config.ConfigKey
+	if config.IsEncrypted(original) {
+		plaintext, key, err = config.DecryptConfig(path, original, prompt)
+		if err != nil {
+			return "", err
+		}
+	}
+	doc, err := config.ParseDocument(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if err := edit(doc, path, key != nil); err != nil {
+		return "", err
+	}
+	if err := checkDefinedElsewhere(doc, path, sources); err != nil {
+		return "", err
+	}
+	data, err := doc.Bytes()
+	if err != nil {
+		return "", err
+	}
+	if problems := config.ValidateConfigData(data); len(problems) > 0 {
+		return "", fmt.Errorf("not saving an invalid config: %w", problems)
+	}
+	if key != nil {
+		if data, err = config.EncryptConfig(data, key); err != nil {
+			return "", err
+		}
+	}
+	if err := checkUnchanged(path, original, info != nil); err != nil {
+		return "", err
+	}
+	if info == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return "", fmt.Errorf("failed to create config directory: %w", err)
+		}
+	}
+	return path, replaceFile(path, data, info)
+}
+
+// configSources returns the file defining each entry of the config at path
+// (types.Config.Sources), or nil when the config doesn't load: the edit then
+// only sees the file at path, and may be what fixes it.
+func configSources(path string, prompt config.PassphraseFunc) map[string]string {
+	mgr := config.NewManager(nil)
+	mgr.SetPassphrasePrompt(prompt)
+	cfg, err := mgr.LoadConfig(path)
+	if err != nil {
+		return nil
+	}
+	return cfg.Sources
+}
+
+// checkDefinedElsewhere fails when the edited document of file has an entry
+// that sources says another file of the config defines: the config would no
+// longer load.
+func checkDefinedElsewhere(doc *config.Document, file string, sources map[string]string) error {
+	keys := doc.Networks()
+	for _, section := range []string{"vpn", "hotspot"} {
+		for _, name := range doc.Keys(section) {
+			keys = append(keys, section+"."+name)
+		}
+	}
+	for _, key := range keys {
+		if other := sources[strings.ToLower(key)]; other != "" && other != file {
+			return fmt.Errorf("'%s' is already defined in %s", key, other)
+		}
+	}
+	return nil
+}
+
+// cachedPassphrase wraps prompt to ask once per file when a config is read
+// more than once.
+func cachedPassphrase(prompt config.PassphraseFunc) config.PassphraseFunc {
+	if prompt == nil {
+		return nil
+	}
+	answers := make(map[string]string)
+	return func(question string) (string, error) {
+		if answer, ok := answers[question]; ok {
+			return answer, nil
+		}
+		answer, err := prompt(question)
+		if err == nil {
+			answers[question] = answer
+		}
+		return answer, err
+	}
+}
+
+// checkUnchanged fails when the file at path no longer holds original (or
+// exists when it didn't), so a write doesn't lose changes made meanwhile by
+// an editor or another net.
+func checkUnchanged(path string, original []byte, existed bool) error {
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if (err == nil) != existed || !bytes.Equal(current, original) {
+		return fmt.Errorf("%s was changed by something else meanwhile, not saving", path)
+	}
+	return nil
+}
+
+// setSecret adds name to the flat name: value secrets file at path. An
+// existing entry is not overwritten: it may belong to something else.
+func setSecret(path, name, value string) error {
+	return editSecrets(path, func(doc *config.Document) error {
+		if doc.Has(name) {
+			return fmt.Errorf("secret '%s' already exists in %s", name, path)
+		}
+		return doc.SetString(value, name)
+	})
+}
+
+// removeSecret deletes name from the secrets file at path, undoing a
+// setSecret whose reference could not be saved.
+func removeSecret(path, name string) error {
+	return editSecrets(path, func(doc *config.Document) error {
+		if !doc.Has(name) {
+			return nil
+		}
+		return doc.Delete(name)
+	})
+}
+
+// editSecrets applies edit to the secrets file at path and writes it back
+// unless it was changed meanwhile.
+func editSecrets(path string, edit func(doc *config.Document) error) error {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	doc, err := config.ParseDocument(original)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := edit(doc); err != nil {
+		return err
+	}
+	data, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := checkUnchanged(path, original, true); err != nil {
+		return err
+	}
+	return replaceFile(path, data, info)
+}
+
+// replaceFile atomically replaces path with data, keeping the original file's
+// mode and (when running as root) ownership. A nil info (new file) gives a
+// private 0600 file.
+func replaceFile(path string, data []byte, info os.FileInfo) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op after a successful rename
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	mode := os.FileMode(0600)
+	if info != nil {
+		mode = info.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if info != nil {
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && os.Geteuid() == 0 {
+			_ = f.Chown(int(st.Uid), int(st.Gid))
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
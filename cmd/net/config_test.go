@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/angelfreak/net/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEditor replaces runEditor with one that applies each edit in turn.
+func stubEditor(t *testing.T, edits ...func(string) string) *int {
+	t.Helper()
+	calls := 0
+	orig := runEditor
+	runEditor = func(path string) error {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "plaintext temp file must be private")
+		edit := edits[len(edits)-1]
+		if calls < len(edits) {
+			edit = edits[calls]
+		}
+		calls++
+		return os.WriteFile(path, []byte(edit(string(data))), 0600)
+	}
+	t.Cleanup(func() { runEditor = orig })
+	return &calls
+}
+
+func TestEditConfig_EncryptedRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.txt"), []byte(identity.String()+"\n"), 0600))
+	t.Setenv(config.KeyFileEnv, "")
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, identity.Recipient())
+	require.NoError(t, err)
+	_, _ = w.Write([]byte("home:\n  ssid: Old\n"))
+	require.NoError(t, w.Close())
+	path := filepath.Join(dir, "config.yaml.age")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+
+	stubEditor(t, func(s string) string { return strings.Replace(s, "Old", "New", 1) })
+	var out bytes.Buffer
+	require.NoError(t, editConfig(path, strings.NewReader(""), &out))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, config.IsEncrypted(data), "config must be re-encrypted")
+	plaintext, _, err := config.DecryptConfig(path, data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "home:\n  ssid: New\n", string(plaintext))
+}
+
+func TestEditConfig_InvalidThenAbort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssid: Home\n"), 0644))
+
+	calls := stubEditor(t, func(s string) string { return s + "  ssd: typo\n" })
+	var out bytes.Buffer
+	err := editConfig(path, strings.NewReader("n\n"), &out)
+	assert.ErrorContains(t, err, "not saved")
+	assert.Equal(t, 1, *calls)
+	assert.Contains(t, out.String(), "ssd")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "home:\n  ssid: Home\n", string(data), "original must be untouched")
+}
+
+func TestEditConfig_InvalidThenFixed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssid: Home\n"), 0640))
+
+	calls := stubEditor(t,
+		func(s string) string { return s + "  psk: [unclosed\n" },
+		func(string) string { return "home:\n  ssid: Home\n  psk: hunter2hunter2\n" },
+	)
+	var out bytes.Buffer
+	require.NoError(t, editConfig(path, strings.NewReader("\n"), &out))
+	assert.Equal(t, 2, *calls)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "psk: hunter2hunter2")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm(), "mode must be preserved")
+}
+
+func TestEditConfig_ResolvesIncludesNextToOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("include:\n  - extra.yaml\nhome:\n  ssid: Home\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "extra.yaml"), []byte("work:\n  ssid: Work\n"), 0644))
+
+	stubEditor(t, func(s string) string { return strings.Replace(s, "Home", "House", 1) })
+	var out bytes.Buffer
+	require.NoError(t, editConfig(path, strings.NewReader(""), &out))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "ssid: House")
+
+	// A network that clashes with the drop-in is only visible with the
+	// include resolved.
+	stubEditor(t, func(s string) string { return s + "work:\n  ssid: Other\n" })
+	out.Reset()
+	err = editConfig(path, strings.NewReader("n\n"), &out)
+	assert.ErrorContains(t, err, "not saved")
+	assert.Contains(t, out.String(), "work")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "nothing may be written next to the config")
+}
+
+// The edited buffer is validated in memory: nothing is written next to the
+// config, not even where its directory can't be written to.
+func TestValidateEditedConfig_InMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "config.yaml.age")
+	assert.Empty(t, validateEditedConfig(path, []byte("home:\n  ssid: Home\n")))
+	problems := validateEditedConfig(path, []byte("home:\n  ssd: Home\n"))
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "ssd")
+	assert.NoDirExists(t, filepath.Dir(path))
+}
+
+func TestEditConfig_NoChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssid: Home\n"), 0600))
+
+	stubEditor(t, func(s string) string { return s })
+	var out bytes.Buffer
+	require.NoError(t, editConfig(path, strings.NewReader(""), &out))
+	assert.Contains(t, out.String(), "No changes")
+}
+
+func TestValidateConfig_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte("home:\n  ssid: Home\n"), 0600))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("home:\n  ssd: Home\n"), 0600))
+
+	var out bytes.Buffer
+	assert.Equal(t, exitConfigOK, validateConfig(valid, true, false, &out))
+	assert.Contains(t, out.String(), "OK")
+
+	out.Reset()
+	assert.Equal(t, exitConfigInvalid, validateConfig(invalid, true, false, &out))
+	assert.Contains(t, out.String(), "did you mean 'ssid'")
+
+	out.Reset()
+	assert.Equal(t, exitConfigUnread, validateConfig(filepath.Join(dir, "missing.yaml"), true, false, &out))
+}
+
+func TestValidateConfig_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssd: Home\n"), 0600))
+
+	var out bytes.Buffer
+	require.Equal(t, exitConfigInvalid, validateConfig(path, false, true, &out))
+
+	var result validationResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.False(t, result.Valid)
+	require.Len(t, result.Issues, 1)
+	assert.Equal(t, "ssd", result.Issues[0].Field)
+	assert.Equal(t, "ssid", result.Issues[0].Suggestion)
+}
+
+func TestDoctorConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssid: Home\n  vpn: nope\n"), 0600))
+
+	var out bytes.Buffer
+	assert.Equal(t, exitConfigInvalid, doctorConfig(path, func(string) bool { return true }, false, &out))
+	assert.Contains(t, out.String(), "error: network 'home': vpn 'nope' is not defined")
+
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssid: Home\n"), 0600))
+	out.Reset()
+	assert.Equal(t, exitConfigOK, doctorConfig(path, func(string) bool { return true }, true, &out))
+	var result doctorResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Empty(t, result.Findings)
+}
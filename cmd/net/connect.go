@@ -1,26 +1,66 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var connectCmd = &cobra.Command{
-	Use:   "connect <name|ssid> [password]",
+	Use:   "connect <name|ssid> [password] | --wps[=bssid] [--wps-pin[=pin]]",
 	Short: "Connect to a configured network (WiFi or wired) or WiFi SSID",
 	Long: `Connect to a network by config name or WiFi SSID.
 
 If <name> matches a network in ~/.net/config.yaml, uses that config.
 Profiles without an SSID are treated as wired connections.
-Otherwise, treats it as a WiFi SSID and connects directly.
+Otherwise, treats it as a WiFi SSID and connects directly. When several
+configured networks share that SSID and have match: rules, netop connects
+with the common settings, then applies the profile whose rules fit the site
+(BSSID, gateway MAC, DHCP domain, LLDP switch name).
+
+Without a password, a protected SSID prompts for one (not echoed), and a
+wrong password gets one more try. --save records a successful SSID
+connection as a new network in the config file, named after the SSID or
+--save=<name>; its password goes to secrets.yaml when that file exists.
+--hidden joins an SSID that isn't broadcast by probing for it instead of
+looking it up in the scan (configured networks use hidden: true).
+--bssid=<bssid> joins an SSID only through that access point; --save
+records it as ap-addr.
+
+A trust policy with kill_switch: true never leaves the link up without its
+kill switch. The kill switch only lets the VPN servers through, so it needs
+iptables and a WireGuard or OpenVPN VPN: Tailscale and NetBird pick relays
+and peers at run time. Otherwise the connect fails and the link is taken
+down again; --no-vpn connects without the VPN and the kill switch.
+
+--wps joins with WPS push-button: press the button on the access point
+within 2 minutes. --wps=<bssid> only talks to that access point, needed
+when several are in WPS mode. --wps-pin shows a PIN to enter on the access
+point instead (--wps-pin=<pin> to choose it). The network WPS provisions is
+saved to the config file, named after its SSID or --save=<name>.
 
 Examples:
   net connect home              Use "home" from config (WiFi)
   net connect wired             Use "wired" from config (Ethernet)
   net connect CoffeeShop        Connect to SSID "CoffeeShop" (open)
-  net connect CoffeeShop pass   Connect with password`,
-	Args: cobra.RangeArgs(1, 2),
+  net connect CoffeeShop pass   Connect with password
+  net connect CoffeeShop --save Prompt for the password, then save it
+  net connect Gateway --hidden  Connect to a hidden SSID
+  net connect Cafe --bssid aa:bb:cc:00:11:22 --save
+                                Pin one access point and save it
+  net connect --wps             WPS push-button with any access point
+  net connect --wps-pin         WPS with a PIN entered on the access point`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("wps") || cmd.Flags().Changed("wps-pin") {
+			if len(args) > 0 {
+				return fmt.Errorf("--wps learns the network from the access point, don't give a name")
+			}
+			return nil
+		}
+		return cobra.RangeArgs(1, 2)(cmd, args)
+	},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) != 0 {
 			return nil, cobra.ShellCompDirectiveNoFileComp
@@ -28,17 +68,66 @@ Examples:
 		return getNetworkNames(), cobra.ShellCompDirectiveNoFileComp
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		name := args[0]
-		password := ""
-		if len(args) > 1 {
-			password = args[1]
+		app := createApp()
+		if cmd.Flags().Changed("save") {
+			saveName, _ := cmd.Flags().GetString("save")
+			app.Save, app.SaveName = true, strings.TrimSpace(saveName)
+		}
+		app.Hidden, _ = cmd.Flags().GetBool("hidden")
+		bssid, _ := cmd.Flags().GetString("bssid")
+		app.BSSID = strings.TrimSpace(bssid)
+
+		var err error
+		if withPIN := cmd.Flags().Changed("wps-pin"); withPIN || cmd.Flags().Changed("wps") {
+			bssid, _ := cmd.Flags().GetString("wps")
+			pin, _ := cmd.Flags().GetString("wps-pin")
+			err = app.RunConnectWPS(strings.TrimSpace(bssid), strings.TrimSpace(pin), withPIN)
+		} else {
+			password := ""
+			if len(args) > 1 {
+				password = args[1]
+			}
+			err = app.RunConnect(args[0], password)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+var autoCmd = &cobra.Command{
+	Use:   "auto [interface]",
+	Short: "Bring up a wired network and apply the profile its match: rules select",
+	Long: `Bring up a wired interface with DHCP and the common settings, then
+look at the link (gateway MAC address, DHCP domain, LLDP switch name) and
+apply the configured profile whose match: rules fit. Without a match the
+link stays up with the common settings.
+
+Examples:
+  net auto                      Auto-detect the wired interface
+  net auto enp3s0               Use enp3s0`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
 		}
-		if err := createApp().RunConnect(name, password); err != nil {
+		if err := createApp().RunAuto(name); err != nil {
 			os.Exit(1)
 		}
 	},
 }
 
 func init() {
+	connectCmd.Flags().String("save", "", "Save a successful SSID connection to the config as network `name` (default: from the SSID)")
+	// --save alone takes the name from the SSID.
+	connectCmd.Flags().Lookup("save").NoOptDefVal = " "
+	connectCmd.Flags().Bool("hidden", false, "The SSID is hidden: probe for it instead of looking it up in the scan")
+	connectCmd.Flags().String("bssid", "", "Join an SSID only through the access point `bssid` (saved as ap-addr with --save)")
+	connectCmd.Flags().String("wps", "", "Join with WPS push-button, with the access point `bssid` if given")
+	connectCmd.Flags().Lookup("wps").NoOptDefVal = " "
+	connectCmd.Flags().String("wps-pin", "", "Join with WPS using `pin` entered on the access point (default: a random one)")
+	connectCmd.Flags().Lookup("wps-pin").NoOptDefVal = " "
 	rootCmd.AddCommand(connectCmd)
+	rootCmd.AddCommand(autoCmd)
 }
@@ -19,6 +19,7 @@ Examples:
   net dhcp                                          Show status
   net dhcp start --interface eth0                   Start on eth0 with defaults
   net dhcp start --interface eth0 --gateway 10.0.0.1  Custom gateway
+  net dhcp start --interface eth0 --uplink vpn      Share only through the VPN
   net dhcp stop                                     Stop the server`,
 	Run: func(cmd *cobra.Command, args []string) {
 		action := "status"
@@ -40,6 +41,7 @@ Examples:
 			ipRange, _ := cmd.Flags().GetString("ip-range")
 			dnsServers, _ := cmd.Flags().GetStringSlice("dns")
 			leaseTime, _ := cmd.Flags().GetString("lease-time")
+			uplink, _ := cmd.Flags().GetString("uplink")
 
 			// Set defaults if not provided
 			if gateway == "" {
@@ -61,6 +63,7 @@ Examples:
 				IPRange:   ipRange,
 				DNS:       dnsServers,
 				LeaseTime: leaseTime,
+				Uplink:    uplink,
 			}
 		}
 
@@ -76,6 +79,7 @@ func init() {
 	dhcpServerCmd.Flags().String("ip-range", "192.168.100.50,192.168.100.150", "DHCP IP range")
 	dhcpServerCmd.Flags().StringSlice("dns", []string{"8.8.8.8", "8.8.4.4"}, "DNS servers")
 	dhcpServerCmd.Flags().String("lease-time", "12h", "DHCP lease time (e.g., 12h, 24h)")
+	dhcpServerCmd.Flags().String("uplink", "", "Share out of: auto (the default route's interface), vpn (only the VPN tunnel) or an interface name (default: auto)")
 
 	rootCmd.AddCommand(dhcpServerCmd)
 }
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/config"
+	"github.com/angelfreak/net/pkg/system"
+	"github.com/angelfreak/net/pkg/wgconfig"
+	"github.com/spf13/cobra"
+)
+
+// The editing commands work on the config file itself, like `net config`:
+// they skip initializeManagers and don't need root.
+func editPreRun(cmd *cobra.Command, args []string) {
+	logger = system.NewLogger(debug)
+}
+
+// runEdit runs an editing command, exiting on failure.
+func runEdit(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Add a network to the configuration file",
+	Long: `Add a network to the configuration file: a WiFi network with --ssid,
+a wired one with --interface. Further fields are set with net set.
+
+The file is edited in place: comments and the order of the other entries
+are kept, the result is validated before it replaces the file, and an
+encrypted config stays encrypted.
+
+Examples:
+  net add cafe --ssid "Cafe Central" --psk 12345678
+  net add home --ssid Home --psk secret:home
+  net add office --interface enp3s0 --vpn work`,
+	PersistentPreRun: editPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		var fields []configField
+		for _, flag := range []string{"ssid", "psk", "interface", "vpn"} {
+			if cmd.Flags().Changed(flag) {
+				value, _ := cmd.Flags().GetString(flag)
+				fields = append(fields, configField{flag, value})
+			}
+		}
+		runEdit(addNetwork(configPath, args[0], fields, os.Stdout))
+	},
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <name|name.field|vpn.name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Remove a network, a VPN or a field from the configuration file",
+	Long: `Remove a network, a VPN (vpn.<name>) or a single field (<name>.<field>)
+from the configuration file. A network still used by an alias and a VPN
+still used by a vpn: key are not removed.
+
+Examples:
+  net rm cafe                   Remove network "cafe"
+  net rm office.dns             Remove the dns: of network "office"
+  net rm vpn.work               Remove VPN "work"`,
+	PersistentPreRun: editPreRun,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getNetworkNames(), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		runEdit(removeEntry(configPath, args[0], os.Stdout))
+	},
+}
+
+var setCmd = &cobra.Command{
+	Use:   "set <name.field> <value>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Set a field in the configuration file",
+	Long: `Set a field of a network, a VPN (vpn.<name>.<field>) or the common
+settings (common.<field>). Nested fields are separated by dots too. List
+fields (dns, routes, match rules) take comma-separated values. A plain
+<name> makes it an alias of another network.
+
+Examples:
+  net set office.dns 1.1.1.1,9.9.9.9
+  net set office.roam.threshold -72
+  net set vpn.work.gateway true
+  net set common.hostname_mode dhcp-only
+  net set work office           "work" is an alias of "office"`,
+	PersistentPreRun: editPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		runEdit(setField(configPath, args[0], args[1], os.Stdout))
+	},
+}
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <name> <new-name>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Rename a network or a VPN in the configuration file",
+	Long: `Rename a network, or a VPN with vpn.<name>. Aliases of the network and
+vpn: keys using the VPN are updated too.
+
+Examples:
+  net rename cafe cafe-central
+  net rename vpn.work vpn.office`,
+	PersistentPreRun: editPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		runEdit(renameEntry(configPath, args[0], args[1], os.Stdout))
+	},
+}
+
+var vpnAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Add a VPN to the configuration file",
+	Long: `Add a VPN to the configuration file. --from reads the OpenVPN or
+WireGuard config to embed; for WireGuard, the address: defaults to the
+[Interface] Address of that file.
+
+Examples:
+  net vpn add work --type wireguard --from wg0.conf --gateway
+  net vpn add office --type openvpn --from office.ovpn
+  net vpn add tail --type tailscale --auth-key secret:tailscale`,
+	PersistentPreRun: editPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := vpnOptions{}
+		opts.Type, _ = cmd.Flags().GetString("type")
+		opts.From, _ = cmd.Flags().GetString("from")
+		opts.Gateway, _ = cmd.Flags().GetBool("gateway")
+		for _, flag := range []string{"address", "interface", "auth-key", "setup-key"} {
+			if cmd.Flags().Changed(flag) {
+				value, _ := cmd.Flags().GetString(flag)
+				opts.Fields = append(opts.Fields, configField{strings.ReplaceAll(flag, "-", "_"), value})
+			}
+		}
+		runEdit(addVPN(configPath, args[0], opts, os.Stdout))
+	},
+}
+
+func init() {
+	addCmd.Flags().String("ssid", "", "WiFi network name")
+	addCmd.Flags().String("psk", "", "WiFi password, or a secret reference (secret:<name>, cmd:..., ...)")
+	addCmd.Flags().String("interface", "", "Interface to use (required for wired networks)")
+	addCmd.Flags().String("vpn", "", "VPN to connect after the network is up")
+
+	vpnAddCmd.Flags().String("type", "", "VPN type: wireguard, openvpn, tailscale or netbird (required)")
+	vpnAddCmd.Flags().String("from", "", "OpenVPN or WireGuard config file to embed")
+	vpnAddCmd.Flags().String("address", "", "WireGuard address (default: from the config file)")
+	vpnAddCmd.Flags().String("interface", "", "WireGuard interface name")
+	vpnAddCmd.Flags().Bool("gateway", false, "Route all traffic through the VPN (WireGuard)")
+	vpnAddCmd.Flags().String("auth-key", "", "Tailscale auth key")
+	vpnAddCmd.Flags().String("setup-key", "", "NetBird setup key")
+	_ = vpnAddCmd.MarkFlagRequired("type")
+
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(rmCmd)
+	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(renameCmd)
+	vpnCmd.AddCommand(vpnAddCmd)
+}
+
+// configField is a field given on the command line, in the order given.
+type configField struct {
+	key, value string
+}
+
+// vpnTypes are the VPN types `net vpn add` accepts.
+var vpnTypes = []string{"wireguard", "openvpn", "tailscale", "netbird"}
+
+// vpnOptions are the settings of `net vpn add`.
+type vpnOptions struct {
+	Type    string
+	From    string // OpenVPN/WireGuard config file to embed as config:
+	Gateway bool
+	Fields  []configField
+}
+
+// addNetwork implements `net add`.
+func addNetwork(path, name string, fields []configField, out io.Writer) error {
+	wifi, wired := false, false
+	for _, f := range fields {
+		wifi = wifi || f.key == "ssid" && f.value != ""
+		wired = wired || f.key == "interface" && f.value != ""
+	}
+	if !wifi && !wired {
+		return fmt.Errorf("give --ssid for a WiFi network or --interface for a wired one")
+	}
+	path, err := updateConfigFile(path, func(doc *config.Document, _ string, _ bool) error {
+		if err := doc.CheckNetworkName(name); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := doc.SetField(f.value, name, f.key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added network '%s' to %s\n", name, path)
+	return nil
+}
+
+// addVPN implements `net vpn add`.
+func addVPN(path, name string, opts vpnOptions, out io.Writer) error {
+	if !containsString(vpnTypes, opts.Type) {
+		return fmt.Errorf("unknown VPN type '%s' (use %s)", opts.Type, strings.Join(vpnTypes, ", "))
+	}
+	embedded := opts.Type == "wireguard" || opts.Type == "openvpn"
+	var inline string
+	switch {
+	case embedded && opts.From == "":
+		return fmt.Errorf("%s VPNs need --from <config file>", opts.Type)
+	case !embedded && opts.From != "":
+		return fmt.Errorf("%s VPNs have no config file", opts.Type)
+	case opts.From != "":
+		data, err := os.ReadFile(opts.From)
+		if err != nil {
+			return fmt.Errorf("failed to read VPN config: %w", err)
+		}
+		inline = strings.TrimRight(string(data), "\n") + "\n"
+	}
+
+	path, err := updateConfigFile(path, func(doc *config.Document, _ string, _ bool) error {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("VPN name is empty")
+		}
+		if doc.Has("vpn", name) {
+			return fmt.Errorf("VPN '%s' already exists in the config", name)
+		}
+		set := func(key, value string) error {
+			return doc.SetField(value, "vpn", name, key)
+		}
+		if err := set("type", opts.Type); err != nil {
+			return err
+		}
+		if inline != "" {
+			if err := set("config", inline); err != nil {
+				return err
+			}
+		}
+		address := ""
+		for _, f := range opts.Fields {
+			if f.key == "address" {
+				address = f.value
+			}
+		}
+		if address == "" && opts.Type == "wireguard" {
+			if address = wgconfig.Address(inline); address != "" {
+				opts.Fields = append(opts.Fields, configField{"address", address})
+			}
+		}
+		for _, f := range opts.Fields {
+			if err := set(f.key, f.value); err != nil {
+				return err
+			}
+		}
+		if opts.Gateway {
+			return set("gateway", "true")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added VPN '%s' to %s\n", name, path)
+	return nil
+}
+
+// removeEntry implements `net rm`.
+func removeEntry(path, target string, out io.Writer) error {
+	keys := strings.Split(target, ".")
+	path, err := updateConfigEntry(path, entryKey(keys), func(doc *config.Document, file string, _ bool) error {
+		switch {
+		case len(keys) == 1 && (keys[0] == "vpn" || doc.Has(keys[0]) && !containsString(doc.Networks(), keys[0])):
+			return fmt.Errorf("'%s' is a section: remove its entries", target)
+		case len(keys) == 1:
+			if aliases := doc.AliasesOf(keys[0]); len(aliases) > 0 {
+				return fmt.Errorf("network '%s' is used by alias %s", keys[0], strings.Join(aliases, ", "))
+			}
+		case len(keys) == 2 && keys[0] == "vpn":
+			if users := doc.VPNReferences(keys[1]); len(users) > 0 {
+				var names []string
+				for _, u := range users {
+					names = append(names, strings.Join(u, "."))
+				}
+				return fmt.Errorf("VPN '%s' is used by %s", keys[1], strings.Join(names, ", "))
+			}
+		}
+		if !doc.Has(keys...) {
+			return fmt.Errorf("'%s' not found in %s", target, file)
+		}
+		return doc.Delete(keys...)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed '%s' from %s\n", target, path)
+	return nil
+}
+
+// setField implements `net set`.
+func setField(path, target, value string, out io.Writer) error {
+	keys := strings.Split(target, ".")
+	path, err := updateConfigEntry(path, entryKey(keys), func(doc *config.Document, file string, _ bool) error {
+		// Only fields of existing entries, so a typo in a name doesn't
+		// create a network.
+		switch {
+		case len(keys) == 1:
+			if !containsString(doc.Networks(), value) {
+				return fmt.Errorf("network '%s' not found in %s", value, file)
+			}
+			if doc.Has(keys[0]) {
+				if _, alias := doc.Get(keys[0]); !alias {
+					return fmt.Errorf("'%s' is a network, not an alias", keys[0])
+				}
+			}
+		case keys[0] == "vpn":
+			if len(keys) < 3 {
+				return fmt.Errorf("give a VPN field: vpn.<name>.<field>")
+			}
+			if !doc.Has("vpn", keys[1]) {
+				return fmt.Errorf("VPN '%s' not found in %s", keys[1], file)
+			}
+		case keys[0] == "hotspot":
+			if len(keys) < 3 {
+				return fmt.Errorf("give a hotspot field: hotspot.<profile>.<field>")
+			}
+			if !doc.Has("hotspot", keys[1]) {
+				return fmt.Errorf("hotspot profile '%s' not found in %s", keys[1], file)
+			}
+		case keys[0] != "common" && keys[0] != "ignored" && !doc.Has(keys[0]):
+			return fmt.Errorf("network '%s' not found in %s", keys[0], file)
+		}
+		return doc.SetField(value, keys...)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Set %s in %s\n", target, path)
+	return nil
+}
+
+// renameEntry implements `net rename`. An entry of an include or drop-in is
+// not renamed: its users may be in other files.
+func renameEntry(path, old, name string, out io.Writer) error {
+	main, err := config.ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	path, err = updateConfigEntry(main, entryKey(strings.SplitN(old, ".", 2)), func(doc *config.Document, file string, _ bool) error {
+		if file != main {
+			return fmt.Errorf("'%s' is defined in %s, rename it there", old, file)
+		}
+		if vpn, ok := strings.CutPrefix(old, "vpn."); ok {
+			return doc.RenameVPN(vpn, strings.TrimPrefix(name, "vpn."))
+		}
+		return doc.RenameNetwork(old, name)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Renamed '%s' to '%s' in %s\n", old, name, path)
+	return nil
+}
+
+// entryKey returns the types.Config.Sources key of the entry holding the
+// config keys, or "" for the common and ignored sections.
+func entryKey(keys []string) string {
+	switch keys[0] {
+	case "common", "ignored":
+		return ""
+	case "vpn", "hotspot":
+		if len(keys) < 2 {
+			return ""
+		}
+		return keys[0] + "." + keys[1]
+	}
+	return keys[0]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
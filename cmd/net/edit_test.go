@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/angelfreak/net/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const editTestConfig = `# netop config
+common:
+  dns: [1.1.1.1]
+
+vpn:
+  work: # the office tunnel
+    type: wireguard
+    config: |
+      [Interface]
+      PrivateKey = x
+
+# home network
+home:
+  ssid: Home
+  vpn: work
+house: home
+`
+
+func writeEditTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(editTestConfig), 0600))
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestAddNetwork(t *testing.T) {
+	path := writeEditTestConfig(t)
+	var out bytes.Buffer
+
+	require.NoError(t, addNetwork(path, "cafe", []configField{{"ssid", "Cafe"}, {"psk", "12345678"}}, &out))
+	assert.Equal(t, "Added network 'cafe' to "+path+"\n", out.String())
+	assert.Equal(t, editTestConfig+"cafe:\n  ssid: Cafe\n  psk: \"12345678\"\n", readFile(t, path))
+
+	assert.EqualError(t, addNetwork(path, "home", []configField{{"ssid", "x"}}, &out), "'home' already exists in the config")
+	assert.ErrorContains(t, addNetwork(path, "desk", nil, &out), "give --ssid")
+}
+
+func TestSetField(t *testing.T) {
+	path := writeEditTestConfig(t)
+	var out bytes.Buffer
+
+	require.NoError(t, setField(path, "home.dns", "9.9.9.9, 1.1.1.1", &out))
+	require.NoError(t, setField(path, "home.ssid", "1234", &out))
+	require.NoError(t, setField(path, "home.roam.threshold", "-72", &out))
+	require.NoError(t, setField(path, "vpn.work.gateway", "true", &out))
+	require.NoError(t, setField(path, "common.hostname_mode", "dhcp-only", &out))
+	assert.Contains(t, out.String(), "Set home.dns in "+path)
+
+	data := readFile(t, path)
+	assert.Contains(t, data, "home:\n  ssid: \"1234\"\n  vpn: work\n  dns: [9.9.9.9, 1.1.1.1]\n  roam:\n    threshold: -72\n")
+	assert.Contains(t, data, "    gateway: true\n")
+	assert.Contains(t, data, "  hostname_mode: dhcp-only\n")
+	assert.Contains(t, data, "# home network\n", "comments are kept")
+
+	cfg, err := config.ParseDocument([]byte(data))
+	require.NoError(t, err)
+	assert.True(t, cfg.Has("common", "dns"))
+
+	assert.EqualError(t, setField(path, "hom.dns", "1.1.1.1", &out), "network 'hom' not found in "+path)
+	assert.EqualError(t, setField(path, "vpn.play.type", "openvpn", &out), "VPN 'play' not found in "+path)
+	assert.EqualError(t, setField(path, "home.roam", "x", &out), "'home.roam' is a section, set one of its fields")
+	assert.ErrorContains(t, setField(path, "home.dnss", "1.1.1.1", &out), "not saving an invalid config")
+	assert.ErrorContains(t, setField(path, "home.dnss", "1.1.1.1", &out), "dns")
+	assert.EqualError(t, setField(path, "home", "house", &out), "'home' is a network, not an alias")
+	assert.EqualError(t, setField(path, "office", "flat", &out), "network 'flat' not found in "+path)
+
+	require.NoError(t, setField(path, "office", "home", &out))
+	assert.Contains(t, readFile(t, path), "office: home\n")
+}
+
+func TestSetField_Hotspot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("hotspot:\n  car:\n    ssid: Car\n"), 0600))
+	var out bytes.Buffer
+
+	require.NoError(t, setField(path, "hotspot.car.max_clients", "4", &out))
+	require.NoError(t, setField(path, "hotspot.car.deny", "aa:bb:cc:dd:ee:ff", &out))
+	assert.Equal(t, "hotspot:\n  car:\n    ssid: Car\n    max_clients: 4\n    deny: ['aa:bb:cc:dd:ee:ff']\n", readFile(t, path))
+
+	assert.EqualError(t, setField(path, "hotspot.boat.ssid", "Boat", &out), "hotspot profile 'boat' not found in "+path)
+	assert.EqualError(t, setField(path, "hotspot.car", "x", &out), "give a hotspot field: hotspot.<profile>.<field>")
+}
+
+func TestRemoveEntry(t *testing.T) {
+	path := writeEditTestConfig(t)
+	var out bytes.Buffer
+
+	assert.EqualError(t, removeEntry(path, "home", &out), "network 'home' is used by alias house")
+	assert.EqualError(t, removeEntry(path, "vpn.work", &out), "VPN 'work' is used by home.vpn")
+	assert.EqualError(t, removeEntry(path, "common", &out), "'common' is a section: remove its entries")
+	assert.EqualError(t, removeEntry(path, "cafe", &out), "'cafe' not found in "+path)
+	assert.Equal(t, editTestConfig, readFile(t, path))
+
+	require.NoError(t, removeEntry(path, "home.vpn", &out))
+	require.NoError(t, removeEntry(path, "vpn.work", &out))
+	require.NoError(t, removeEntry(path, "house", &out))
+	assert.Contains(t, out.String(), "Removed 'house' from "+path)
+	assert.Equal(t, `# netop config
+common:
+  dns: [1.1.1.1]
+vpn: {}
+
+# home network
+home:
+  ssid: Home
+`, readFile(t, path))
+}
+
+func TestRenameEntry(t *testing.T) {
+	path := writeEditTestConfig(t)
+	var out bytes.Buffer
+
+	require.NoError(t, renameEntry(path, "home", "flat", &out))
+	require.NoError(t, renameEntry(path, "vpn.work", "vpn.office", &out))
+	assert.Contains(t, out.String(), "Renamed 'home' to 'flat' in "+path)
+	data := readFile(t, path)
+	assert.Contains(t, data, "  office: # the office tunnel\n")
+	assert.Contains(t, data, "# home network\nflat:\n  ssid: Home\n  vpn: office\nhouse: flat\n")
+
+	assert.EqualError(t, renameEntry(path, "flat", "house", &out), "'house' already exists in the config")
+	assert.EqualError(t, renameEntry(path, "vpn.play", "vpn.x", &out), "'vpn.play' not found")
+	assert.EqualError(t, renameEntry(path, "common", "x", &out), "'common' is not a network")
+}
+
+func TestAddVPN(t *testing.T) {
+	path := writeEditTestConfig(t)
+	wg := filepath.Join(t.TempDir(), "wg0.conf")
+	require.NoError(t, os.WriteFile(wg, []byte("[Interface]\nPrivateKey = abc\nAddress = 10.0.0.2/32, fd00::2/128\n\n[Peer]\nPublicKey = def\n"), 0600))
+	var out bytes.Buffer
+
+	require.NoError(t, addVPN(path, "play", vpnOptions{Type: "wireguard", From: wg, Gateway: true}, &out))
+	assert.Equal(t, "Added VPN 'play' to "+path+"\n", out.String())
+	assert.Contains(t, readFile(t, path), `  play:
+    type: wireguard
+    config: |
+      [Interface]
+      PrivateKey = abc
+      Address = 10.0.0.2/32, fd00::2/128
+
+      [Peer]
+      PublicKey = def
+    address: 10.0.0.2/32
+    gateway: true
+`)
+
+	require.NoError(t, addVPN(path, "tail", vpnOptions{Type: "tailscale", Fields: []configField{{"auth_key", "secret:ts"}}}, &out))
+	assert.Contains(t, readFile(t, path), "  tail:\n    type: tailscale\n    auth_key: secret:ts\n")
+
+	assert.EqualError(t, addVPN(path, "work", vpnOptions{Type: "wireguard", From: wg}, &out), "VPN 'work' already exists in the config")
+	assert.EqualError(t, addVPN(path, "x", vpnOptions{Type: "ipsec"}, &out), "unknown VPN type 'ipsec' (use wireguard, openvpn, tailscale, netbird)")
+	assert.EqualError(t, addVPN(path, "x", vpnOptions{Type: "openvpn"}, &out), "openvpn VPNs need --from <config file>")
+}
+
+func TestEdit_EntryOfInclude(t *testing.T) {
+	path := writeEditTestConfig(t)
+	require.NoError(t, os.WriteFile(path, []byte("include: team.yaml\n"+editTestConfig), 0600))
+	team := filepath.Join(filepath.Dir(path), "team.yaml")
+	require.NoError(t, os.WriteFile(team, []byte("office:\n  ssid: Office\nvpn:\n  lab:\n    type: tailscale\n"), 0600))
+	var out bytes.Buffer
+
+	require.NoError(t, setField(path, "office.psk", "12345678", &out))
+	assert.Equal(t, "Set office.psk in "+team+"\n", out.String())
+	assert.Contains(t, readFile(t, team), "psk: \"12345678\"")
+	assert.NotContains(t, readFile(t, path), "office:")
+
+	out.Reset()
+	require.NoError(t, removeEntry(path, "vpn.lab", &out))
+	assert.Equal(t, "Removed 'vpn.lab' from "+team+"\n", out.String())
+	assert.NotContains(t, readFile(t, team), "lab")
+
+	assert.EqualError(t, renameEntry(path, "office", "work", &out), "'office' is defined in "+team+", rename it there")
+	assert.EqualError(t, addNetwork(path, "office", []configField{{"ssid", "x"}}, &out), "'office' is already defined in "+team)
+	assert.EqualError(t, renameEntry(path, "home", "Office", &out), "'Office' is already defined in "+team)
+	assert.NotContains(t, readFile(t, path), "office:")
+}
+
+func TestUpdateConfigFile_RefusesConcurrentChange(t *testing.T) {
+	path := writeEditTestConfig(t)
+	_, err := updateConfigFile(path, func(doc *config.Document, _ string, _ bool) error {
+		// Another writer gets in between the read and the write.
+		require.NoError(t, os.WriteFile(path, []byte(editTestConfig+"desk:\n  interface: eth0\n"), 0600))
+		return doc.SetString("Cafe", "cafe", "ssid")
+	})
+	assert.ErrorContains(t, err, "was changed by something else meanwhile, not saving")
+	assert.NotContains(t, readFile(t, path), "cafe")
+}
+
+func TestEditConfig_RefusesConcurrentChange(t *testing.T) {
+	path := writeEditTestConfig(t)
+	stubEditor(t, func(s string) string {
+		require.NoError(t, os.WriteFile(path, []byte(editTestConfig+"desk:\n  interface: eth0\n"), 0600))
+		return s + "cafe:\n  ssid: Cafe\n"
+	})
+	err := editConfig(path, nil, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "was changed by something else meanwhile")
+	assert.NotContains(t, readFile(t, path), "cafe")
+}
+
+func TestGetNetworkNames(t *testing.T) {
+	orig := configPath
+	t.Cleanup(func() { configPath = orig })
+	configPath = writeEditTestConfig(t)
+	assert.Equal(t, []string{"home", "house"}, getNetworkNames())
+}
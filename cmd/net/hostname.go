@@ -0,0 +1,49 @@
+package main
+
+// applyHostnameMode stops iface from announcing the machine's name over
+// mDNS, LLMNR and NetBIOS when the connection has a hostname_mode. The
+// hostname itself (DHCP option 12, the kernel hostname) was already taken
+// care of by the network manager when the link came up. Returns the mode
+// applied, or "" for none.
+func (a *App) applyHostnameMode(configName, iface string) string {
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil {
+		return ""
+	}
+	// Only merge when a mode is set somewhere: most configs have none.
+	policy, _ := a.trustPolicy(a.trustLevel(configName))
+	network, _ := cfg.Network(configName)
+	if cfg.Common.HostnameMode == "" && network.HostnameMode == "" && !policy.HideHostname {
+		return ""
+	}
+	settings := a.settingsFor(configName)
+	if settings == nil || settings.HostnameMode == "" {
+		return ""
+	}
+	mode := settings.HostnameMode
+	if a.FirewallMgr == nil {
+		a.errorf("Warning: name broadcasts not blocked: iptables is unavailable\n")
+		return mode
+	}
+	if err := a.FirewallMgr.BlockNameBroadcasts(iface); err != nil {
+		a.Logger.Error("Failed to block name broadcasts", "iface", iface, "error", err)
+		a.errorf("Warning: name broadcasts not blocked: %v\n", err)
+	}
+	return mode
+}
+
+// resetHostnameMode undoes the previous connection's hostname_mode: it
+// restores the hostname replaced by hostname_mode: system and lifts the
+// name broadcast block. Returns whether a hostname was restored.
+func (a *App) resetHostnameMode() bool {
+	restored, err := a.NetworkMgr.RestoreHostname()
+	if err != nil {
+		a.Logger.Warn("Failed to restore hostname", "error", err)
+	}
+	if a.FirewallMgr != nil {
+		if err := a.FirewallMgr.UnblockNameBroadcasts(); err != nil {
+			a.Logger.Warn("Failed to remove name broadcast block", "error", err)
+		}
+	}
+	return restored
+}
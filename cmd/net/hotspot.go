@@ -1,30 +1,65 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/angelfreak/net/pkg/types"
 	"github.com/spf13/cobra"
 )
 
 var hotspotCmd = &cobra.Command{
-	Use:   "hotspot [start|stop|status]",
+	Use:   "hotspot [start [profile]|stop|status|clients|kick <mac>]",
 	Short: "Create a WiFi hotspot to share your connection",
 	Long: `Create and manage a WiFi access point.
 
 Without arguments: Shows hotspot status.
 
+"start <profile>" starts a profile from the hotspot: section of the config;
+flags override its settings:
+
+  hotspot:
+    car:
+      ssid: Car
+      password: !secret car-hotspot
+      channel: auto
+      band: "5"
+      max_clients: 4
+
 Features:
-  - Supports 2.4GHz (channels 1-14) and 5GHz (channels 36-165)
-  - Automatic NAT/IP forwarding for internet sharing
-  - WPA2 encryption with password protection
+  - Supports 2.4GHz (channels 1-14) and 5GHz (channels 36-165), or picks
+    the least congested channel of a band with --channel auto
+  - 802.11n/ac/ax with 40, 80 or 160 MHz channels
+  - Automatic NAT/IP forwarding for internet sharing, out of the default
+    route's interface, a given one, or only through the VPN (--uplink vpn:
+    clients are cut off rather than leaked while the tunnel is down)
+  - WPA2, WPA3 (SAE), WPA2/WPA3 transition, Enhanced Open (OWE) or open
+  - Lists connected clients with their IP, hostname, signal and traffic,
+    disconnects them, and keeps MACs out (--deny) or only lets some in
+    (--allow)
+  - Stays connected: on a connected interface, the access point runs on a
+    virtual <interface>_ap next to the connection, on its channel (when the
+    driver supports a station and an AP at once)
 
 Examples:
   net hotspot                           Show status
   net hotspot start                     Start with defaults (SSID: net-hotspot)
   net hotspot start --ssid MyHotspot    Start with custom SSID
   net hotspot start --password secret   Start with WPA2 password
+  net hotspot start --password secret --security wpa2-wpa3
+                                        WPA3 for clients that support it
   net hotspot start --channel 36        Start on 5GHz channel 36
+  net hotspot start --band 5 --ieee80211ac --width 80 --country DE
+                                        Pick a free 80 MHz 5GHz channel
+  net hotspot start car                 Start the car profile
+  net hotspot start car --channel 11    ...on channel 11
+  net hotspot start --deny aa:bb:cc:dd:ee:ff --max-clients 4
+                                        Keep a device out, allow 4 clients
+  net hotspot start --uplink vpn        Share only through the VPN
+  net hotspot clients                   List connected clients
+  net hotspot kick aa:bb:cc:dd:ee:ff    Disconnect a client and keep it out
+                                        until the hotspot stops
   net hotspot stop                      Stop the hotspot`,
 	Run: func(cmd *cobra.Command, args []string) {
 		action := "status"
@@ -32,57 +67,132 @@ Examples:
 			action = args[0]
 		}
 
-		var config *types.HotspotConfig
-		if action == "start" {
-			// Get configuration from flags or use defaults
-			ssid, _ := cmd.Flags().GetString("ssid")
-			password, _ := cmd.Flags().GetString("password")
-			channel, _ := cmd.Flags().GetInt("channel")
-			gateway, _ := cmd.Flags().GetString("gateway")
-			ipRange, _ := cmd.Flags().GetString("ip-range")
-			dnsServers, _ := cmd.Flags().GetStringSlice("dns")
-
-			// Set defaults if not provided
-			if ssid == "" {
-				ssid = "net-hotspot"
+		switch action {
+		case "clients":
+			if err := createApp().RunHotspotClients(); err != nil {
+				os.Exit(1)
 			}
-			if channel == 0 {
-				channel = 6
+			return
+		case "kick":
+			if len(args) != 2 {
+				fmt.Fprintln(os.Stderr, "Error: usage: net hotspot kick <mac>")
+				os.Exit(1)
 			}
-			if gateway == "" {
-				gateway = "192.168.50.1"
+			if err := createApp().RunHotspotKick(args[1]); err != nil {
+				os.Exit(1)
 			}
-			if ipRange == "" {
-				ipRange = "192.168.50.50,192.168.50.150"
+			return
+		}
+
+		app := createApp()
+		var config *types.HotspotConfig
+		if action == "start" {
+			// Profile values, then flags, then defaults
+			profile := ""
+			if len(args) > 1 {
+				profile = args[1]
 			}
-			if len(dnsServers) == 0 {
-				dnsServers = []string{"8.8.8.8", "8.8.4.4"}
+			var err error
+			if config, err = app.HotspotProfile(profile); err != nil {
+				os.Exit(1)
 			}
-
-			config = &types.HotspotConfig{
-				Interface: iface,
-				SSID:      ssid,
-				Password:  password,
-				Channel:   channel,
-				Gateway:   gateway,
-				IPRange:   ipRange,
-				DNS:       dnsServers,
+			if err := applyHotspotFlags(cmd, config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 		}
 
-		if err := createApp().RunHotspot(action, config); err != nil {
+		if err := app.RunHotspot(action, config); err != nil {
 			os.Exit(1)
 		}
 	},
 }
 
-func init() {
-	hotspotCmd.Flags().String("ssid", "", "Hotspot SSID (default: net-hotspot)")
-	hotspotCmd.Flags().String("password", "", "Hotspot password (min 8 chars, empty for open network)")
-	hotspotCmd.Flags().Int("channel", 6, "WiFi channel (2.4GHz: 1-14, 5GHz: 36,40,44,48,149,153,157,161,165)")
-	hotspotCmd.Flags().String("gateway", "192.168.50.1", "Gateway IP address")
-	hotspotCmd.Flags().String("ip-range", "192.168.50.50,192.168.50.150", "DHCP IP range")
-	hotspotCmd.Flags().StringSlice("dns", []string{"8.8.8.8", "8.8.4.4"}, "DNS servers")
+// applyHotspotFlags overrides config with the flags given on the command
+// line, so a profile's settings only change where asked to.
+func applyHotspotFlags(cmd *cobra.Command, config *types.HotspotConfig) error {
+	flags := cmd.Flags()
+	if f := cmd.Flag("iface"); f != nil && f.Changed {
+		config.Interface = iface
+	}
+	for name, field := range map[string]*string{
+		"ssid":     &config.SSID,
+		"password": &config.Password,
+		"security": &config.Security,
+		"band":     &config.Band,
+		"country":  &config.Country,
+		"gateway":  &config.Gateway,
+		"ip-range": &config.IPRange,
+		"uplink":   &config.Uplink,
+	} {
+		if flags.Changed(name) {
+			*field, _ = flags.GetString(name)
+		}
+	}
+	for name, field := range map[string]*bool{
+		"ieee80211n":  &config.IEEE80211N,
+		"ieee80211ac": &config.IEEE80211AC,
+		"ieee80211ax": &config.IEEE80211AX,
+	} {
+		if flags.Changed(name) {
+			*field, _ = flags.GetBool(name)
+		}
+	}
+	for name, field := range map[string]*int{
+		"width":       &config.Width,
+		"max-clients": &config.MaxClients,
+	} {
+		if flags.Changed(name) {
+			*field, _ = flags.GetInt(name)
+		}
+	}
+	for name, field := range map[string]*[]string{
+		"dns":   &config.DNS,
+		"deny":  &config.Deny,
+		"allow": &config.Allow,
+	} {
+		if flags.Changed(name) {
+			*field, _ = flags.GetStringSlice(name)
+		}
+	}
 
+	if flags.Changed("channel") {
+		channel, _ := flags.GetString("channel")
+		if channel == "auto" {
+			config.Channel, config.AutoChannel = 0, true
+		} else {
+			n, err := strconv.Atoi(channel)
+			if err != nil {
+				return fmt.Errorf("invalid channel %q (a number or auto)", channel)
+			}
+			config.Channel, config.AutoChannel = n, false
+		}
+	}
+	return nil
+}
+
+func init() {
+	addHotspotFlags(hotspotCmd)
 	rootCmd.AddCommand(hotspotCmd)
 }
+
+// addHotspotFlags registers the hotspot settings flags on cmd.
+func addHotspotFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ssid", "", "Hotspot SSID (default: net-hotspot)")
+	cmd.Flags().String("password", "", "Hotspot password (min 8 chars, empty for open network; a profile keeps it out of shell history)")
+	cmd.Flags().String("security", "", "Security: wpa2, wpa3, wpa2-wpa3, open or owe (default: wpa2 with a password, open without)")
+	cmd.Flags().String("channel", "", "WiFi channel (2.4GHz: 1-14, 5GHz: 36,40,44,48,149,153,157,161,165) or auto (default: 6, or auto with --band)")
+	cmd.Flags().String("band", "", "Band for --channel auto: 2.4 or 5")
+	cmd.Flags().Int("width", 0, "Channel width in MHz: 20, 40 (needs 802.11n/ac/ax), 80 or 160 (need 802.11ac/ax)")
+	cmd.Flags().String("country", "", "Regulatory domain, e.g. DE (needed for most 5GHz channels)")
+	cmd.Flags().Bool("ieee80211n", false, "Enable 802.11n (WiFi 4)")
+	cmd.Flags().Bool("ieee80211ac", false, "Enable 802.11ac (WiFi 5, 5GHz only)")
+	cmd.Flags().Bool("ieee80211ax", false, "Enable 802.11ax (WiFi 6)")
+	cmd.Flags().String("gateway", "", "Gateway IP address (default: 192.168.50.1)")
+	cmd.Flags().String("ip-range", "", "DHCP IP range (default: 192.168.50.50,192.168.50.150)")
+	cmd.Flags().StringSlice("dns", nil, "DNS servers (default: common.dns, else 8.8.8.8,8.8.4.4)")
+	cmd.Flags().StringSlice("deny", nil, "MAC addresses that may not connect")
+	cmd.Flags().StringSlice("allow", nil, "Only let these MAC addresses connect (deny wins over allow)")
+	cmd.Flags().Int("max-clients", 0, "Maximum number of connected clients (default: no limit)")
+	cmd.Flags().String("uplink", "", "Share out of: auto (the default route's interface), vpn (only the VPN tunnel) or an interface name (default: auto)")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyHotspotFlags(t *testing.T) {
+	parse := func(t *testing.T, args ...string) *cobra.Command {
+		t.Helper()
+		cmd := &cobra.Command{}
+		addHotspotFlags(cmd)
+		require.NoError(t, cmd.Flags().Parse(args))
+		return cmd
+	}
+	profile := func() *types.HotspotConfig {
+		return &types.HotspotConfig{
+			SSID: "Car", Password: "car-password", AutoChannel: true, Band: "5",
+			IEEE80211AC: true, Deny: []string{"aa:bb:cc:dd:ee:ff"}, MaxClients: 4,
+		}
+	}
+
+	// No flags: the profile is untouched
+	config := profile()
+	require.NoError(t, applyHotspotFlags(parse(t), config))
+	assert.Equal(t, profile(), config)
+
+	// Flags override just their own fields
+	config = profile()
+	require.NoError(t, applyHotspotFlags(parse(t, "--channel", "36", "--ssid", "Van", "--ieee80211ac=false",
+		"--max-clients", "2", "--allow", "02:11:22:33:44:55", "--uplink", "vpn"), config))
+	assert.Equal(t, &types.HotspotConfig{
+		SSID: "Van", Password: "car-password", Channel: 36, Band: "5",
+		Deny: []string{"aa:bb:cc:dd:ee:ff"}, Allow: []string{"02:11:22:33:44:55"}, MaxClients: 2,
+		Uplink: "vpn",
+	}, config)
+
+	config = &types.HotspotConfig{Channel: 6}
+	require.NoError(t, applyHotspotFlags(parse(t, "--channel", "auto"), config))
+	assert.True(t, config.AutoChannel)
+	assert.Zero(t, config.Channel)
+
+	assert.EqualError(t, applyHotspotFlags(parse(t, "--channel", "six"), &types.HotspotConfig{}),
+		`invalid channel "six" (a number or auto)`)
+}
@@ -18,7 +18,11 @@ Examples:
   net mac                         Random MAC
   net mac random                  Random MAC (explicit)
   net mac AA:BB:CC:DD:EE:FF       Set specific MAC
-  net mac default                 Randomize with Apple OUI prefix`,
+  net mac default                 Randomize with Apple OUI prefix
+  net mac permanent               Restore the factory MAC
+  net mac stable                  Stable per-interface MAC derived from a secret
+  net mac rotate:24h              Random MAC kept for 24 hours
+  net mac vendor:Intel            Random MAC with a real Intel prefix`,
 	Run: func(cmd *cobra.Command, args []string) {
 		mac := ""
 		if len(args) > 0 {
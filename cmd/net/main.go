@@ -13,6 +13,7 @@ import (
 	"github.com/angelfreak/net/pkg/config"
 	"github.com/angelfreak/net/pkg/dhcp"
 	"github.com/angelfreak/net/pkg/dhcpclient"
+	"github.com/angelfreak/net/pkg/firewall"
 	"github.com/angelfreak/net/pkg/hotspot"
 	"github.com/angelfreak/net/pkg/netlink"
 	"github.com/angelfreak/net/pkg/network"
@@ -21,6 +22,7 @@ import (
 	"github.com/angelfreak/net/pkg/types"
 	"github.com/angelfreak/net/pkg/vpn"
 	"github.com/angelfreak/net/pkg/wifi"
+	"github.com/angelfreak/net/pkg/wpa"
 	"github.com/spf13/cobra"
 )
 
@@ -129,8 +131,21 @@ func commandNeedsRootArgs(args []string) bool {
 		}
 		// First positional arg is the subcommand — check if it's root-exempt
 		switch arg {
-		case "help", "completion", "status", "show", "list", "portal":
+		case "help", "completion", "status", "show", "list", "portal", "config", "oui",
+			"add", "rm", "set", "rename":
 			return false
+		case "vpn":
+			// `net vpn add` only edits the config file.
+			for j := i + 1; j < len(args); j++ {
+				if strings.HasPrefix(args[j], "-") {
+					if valueFlags[args[j]] {
+						j++
+					}
+					continue
+				}
+				return args[j] != "add"
+			}
+			return true
 		default:
 			// First positional arg is not exempt, needs root
 			return true
@@ -211,7 +226,7 @@ func main() {
 			_ = system.SetImmutable("/etc/resolv.conf", false)
 			// Kill any wpa_supplicant/dhclient we may have started
 			if iface != "" {
-				sysExecutor.ExecuteWithTimeout(1*time.Second, "wpa_cli", "-i", iface, "terminate")
+				_ = wpa.NewClient().Terminate(iface)
 				sysExecutor.ExecuteWithTimeout(500*time.Millisecond, "pkill", "-f", "dhclient.*"+iface)
 				sysExecutor.ExecuteWithTimeout(500*time.Millisecond, "pkill", "-f", "udhcpc.*"+iface)
 			}
@@ -287,6 +302,11 @@ func createApp() *App {
 		Debug:      debug,
 		Stdout:     os.Stdout,
 		Stderr:     os.Stderr,
+
+		ReadPassword:    system.ReadPassword,
+		ConfigPath:      configPath,
+		FirewallMgr:     createFirewallManager(),
+		ProfileStateDir: types.RuntimeDir + "/profiles",
 	}
 }
 
@@ -303,6 +323,18 @@ func createPortalDetector() types.PortalDetector {
 	return portal.New(probeURL, timeout, logger)
 }
 
+// createFirewallManager returns the iptables-backed firewall for trust
+// policies, or nil when iptables is unavailable (policies needing it then
+// report that they couldn't be applied).
+func createFirewallManager() types.FirewallManager {
+	fw, err := firewall.New()
+	if err != nil {
+		logger.Debug("Firewall unavailable", "error", err)
+		return nil
+	}
+	return fw
+}
+
 // findDefaultInterface picks the primary network interface by reading sysfs
 // directly (/sys/class/net), which needs no external binary. This matters
 // because root-exempt commands like `net status` run without /sbin in PATH,
@@ -32,6 +32,12 @@ func TestCommandNeedsRootArgs(t *testing.T) {
 		{"debug flag then status stays exempt", []string{"--debug", "status"}, false},
 		{"portal is exempt", []string{"portal"}, false},
 		{"portal with iface flag is exempt", []string{"--iface", "wlan0", "portal"}, false},
+		{"config is exempt", []string{"config", "edit"}, false},
+		{"oui is exempt", []string{"oui", "ac:bc:32"}, false},
+		{"set is exempt", []string{"set", "office.dns", "1.1.1.1"}, false},
+		{"rm is exempt", []string{"rm", "cafe"}, false},
+		{"vpn add is exempt", []string{"vpn", "--config", "/tmp/c.yaml", "add", "work"}, false},
+		{"vpn named add-on needs root", []string{"vpn", "add-on"}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
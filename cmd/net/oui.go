@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var ouiCmd = &cobra.Command{
+	Use:   "oui <mac>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Look up the vendor of a MAC address",
+	Long: `Look up the vendor of a MAC address or prefix in the OUI database built
+into net. Separators may be ':', '-' or '.', or left out.
+
+Examples:
+  net oui ac:bc:32:01:02:03
+  net oui B8-27-EB`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := createApp().RunOUI(args[0]); err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ouiCmd)
+}
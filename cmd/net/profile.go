@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// activeProfile records which network profile is applied to an interface and
+// why, and the trust policy in effect, so `net status` can show them and
+// later commands can undo them after the connecting process has exited.
+// Each connected interface has its own record.
+type activeProfile struct {
+	// Network is empty for an SSID without a network config of its own.
+	Network   string `json:"network,omitempty"`
+	Interface string `json:"interface"`
+	// Rule is the match: rule that selected the profile, e.g. "bssid
+	// 00:11:22". Empty when the profile was chosen by name or SSID.
+	Rule string `json:"rule,omitempty"`
+	// Trust is the connection's trust level, and Protections what its
+	// policy turned on ("firewall", "encrypted DNS", "kill switch").
+	Trust       string   `json:"trust,omitempty"`
+	Protections []string `json:"protections,omitempty"`
+	// HostnameMode is the connection's hostname_mode, if any.
+	HostnameMode string `json:"hostname_mode,omitempty"`
+	// VPN is the VPN brought up with the connection, if any.
+	VPN string `json:"vpn,omitempty"`
+	// Alongside is the interface of the connection that keeps the
+	// system-wide settings (DNS, trust policy, hostname) while this one is
+	// up. Empty when this connection has them.
+	Alongside string `json:"alongside,omitempty"`
+}
+
+// saveActiveProfile records p for its interface. A no-op when the App has no
+// ProfileStateDir (tests).
+func (a *App) saveActiveProfile(p *activeProfile) {
+	if a.ProfileStateDir == "" || p.Interface == "" {
+		return
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	_ = os.MkdirAll(a.ProfileStateDir, 0755)
+	if err := os.WriteFile(filepath.Join(a.ProfileStateDir, p.Interface), data, 0644); err != nil {
+		a.Logger.Debug("Failed to record active profile", "error", err)
+	}
+}
+
+// clearActiveProfile removes the record of iface, or of every interface when
+// iface is "".
+func (a *App) clearActiveProfile(iface string) {
+	if a.ProfileStateDir == "" {
+		return
+	}
+	if iface == "" {
+		_ = os.RemoveAll(a.ProfileStateDir)
+		return
+	}
+	_ = os.Remove(filepath.Join(a.ProfileStateDir, iface))
+}
+
+// loadActiveProfile returns the profile recorded for iface, or nil if none.
+func (a *App) loadActiveProfile(iface string) *activeProfile {
+	if a.ProfileStateDir == "" || iface == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(a.ProfileStateDir, iface))
+	if err != nil {
+		return nil
+	}
+	var p activeProfile
+	if json.Unmarshal(data, &p) != nil || p.Interface != iface {
+		return nil
+	}
+	return &p
+}
+
+// activeProfiles returns the recorded profiles of all interfaces, ordered by
+// interface name.
+func (a *App) activeProfiles() []*activeProfile {
+	if a.ProfileStateDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(a.ProfileStateDir)
+	if err != nil {
+		return nil
+	}
+	var profiles []*activeProfile
+	for _, entry := range entries {
+		if p := a.loadActiveProfile(entry.Name()); p != nil {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// primaryProfile returns the profile of the connection that has the
+// system-wide settings, or nil if none.
+func (a *App) primaryProfile() *activeProfile {
+	for _, p := range a.activeProfiles() {
+		if p.Alongside == "" {
+			return p
+		}
+	}
+	return nil
+}
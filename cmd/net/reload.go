@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+
+	"github.com/angelfreak/net/pkg/config"
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// configWatcher is implemented by a ConfigMgr that can reload its files when
+// they change (*config.Manager).
+type configWatcher interface {
+	Watch(ctx context.Context, onChange func(config.Change)) error
+}
+
+// configChanges starts watching the config for a long-running command and
+// returns the changes of each successful reload, or nil (a channel that never
+// delivers) when the ConfigMgr can't be watched. Changes are handed to the
+// caller's loop rather than applied from the watcher, so re-applying never
+// races the command's own work.
+func (a *App) configChanges(ctx context.Context) <-chan config.Change {
+	w, ok := a.ConfigMgr.(configWatcher)
+	if !ok {
+		return nil
+	}
+	changes := make(chan config.Change)
+	go func() {
+		err := w.Watch(ctx, func(change config.Change) {
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			a.Logger.Warn("Not reloading config on change", "error", err)
+		}
+	}()
+	return changes
+}
+
+// reapplyConfig re-applies the DNS servers, VPN and trust policy of the
+// connection on iface after a config reload, when change affects its network
+// or VPN; only the VPN when it's up alongside another radio's connection.
+// The link itself is left up: settings that only take effect on association
+// or DHCP (MAC, hostname, static IP) wait for the next `net connect`.
+func (a *App) reapplyConfig(iface string, change config.Change) {
+	p := a.loadActiveProfile(iface)
+	if p == nil || !change.AffectsNetwork(p.Network, p.VPN) {
+		return
+	}
+
+	// A plain SSID connection has no network of its own: its settings are
+	// the common ones under the trust level it was connected with.
+	level := p.Trust
+	var settings *types.NetworkConfig
+	if p.Network != "" {
+		level = a.trustLevel(p.Network)
+		settings = a.settingsFor(p.Network)
+	} else {
+		settings = a.ConfigMgr.MergeWithCommon("", &types.NetworkConfig{Trust: level})
+	}
+	if settings == nil {
+		return
+	}
+	name := p.Network
+	if name == "" {
+		name = p.Interface
+	}
+	a.Logger.Info("Re-applying settings after config change", "network", name, "changed", change.String())
+
+	p.Trust, p.Protections = level, nil
+	policy, hasPolicy := a.trustPolicy(level)
+	if p.Alongside != "" {
+		hasPolicy = false
+	} else {
+		a.resetTrustPolicy()
+	}
+	if hasPolicy {
+		p.Protections = a.applyInboundFilter(policy, p.Interface)
+	}
+
+	vpnName := ""
+	if !a.NoVPN {
+		vpnName = settings.VPN
+		if p.Network != "" {
+			vpnName = a.resolveVPNName(p.Network)
+		}
+	}
+	if hasPolicy && policy.KillSwitch {
+		if err := a.raiseKillSwitch(p.Interface, vpnName); err != nil {
+			_ = a.dropLink(level, p.Interface, err)
+			return
+		}
+	}
+	if vpnName != p.VPN || (vpnName != "" && change.AffectsVPN(vpnName)) {
+		if p.VPN != "" {
+			if err := a.VPNMgr.Disconnect(p.VPN); err != nil {
+				a.Logger.Warn("Failed to disconnect VPN", "vpn", p.VPN, "error", err)
+			}
+		}
+		p.VPN = ""
+		if vpnName != "" && a.attemptVPNConnect(vpnName) {
+			p.VPN = vpnName
+		}
+	}
+
+	// Encrypted DNS replaces the plain servers; otherwise an empty list
+	// hands DNS back to DHCP.
+	if p.Alongside == "" && (!hasPolicy || !policy.EncryptedDNS) {
+		if err := a.NetworkMgr.SetDNS(settings.DNS); err != nil {
+			a.Logger.Error("Failed to set DNS", "error", err)
+			a.errorf("Warning: DNS not updated: %v\n", err)
+		}
+	}
+	if hasPolicy {
+		p.Protections = append(p.Protections,
+			a.applyTunnelPolicy(level, policy, p.Interface, vpnName, settings.DNS, false)...)
+	}
+
+	a.saveActiveProfile(p)
+	a.printf("Config changed: re-applied settings of %s\n", name)
+}
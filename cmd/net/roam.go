@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var roamCmd = &cobra.Command{
+	Use:   "roam [network]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Move to a better access point of the current network when the signal drops",
+	Long: `Watch the signal of the current WiFi connection and roam to a better
+access point of the same network, following the network's roam: policy.
+
+Every interval the signal is checked; once it falls below the threshold, a
+scan looks for an access point at least min_gain dB stronger (preferring
+prefer_band within band_margin dB) and wpa_supplicant reassociates to it.
+
+The network defaults to the one connected with 'net connect'. Runs until
+interrupted. When the config changes meanwhile, the active connection's DNS
+servers, VPN and trust policy are re-applied and the new roam: policy used.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if err := createApp().RunRoam(context.Background(), name); err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(roamCmd)
+}
+
+// RunRoam checks the connection to the named network (or the active profile)
+// every roam interval and roams when its policy says so, until ctx is done.
+// Config reloads re-apply the active connection's settings and the policy.
+func (a *App) RunRoam(ctx context.Context, name string) error {
+	if name == "" {
+		if p := a.primaryProfile(); p != nil {
+			name = p.Network
+		}
+	}
+	if name == "" {
+		err := fmt.Errorf("no network given and no active network profile")
+		a.errorf("Error: %v\n", err)
+		return err
+	}
+	settings := a.settingsFor(name)
+	if settings == nil || settings.SSID == "" || settings.Roam == nil {
+		err := fmt.Errorf("network %s has no ssid and roam: policy", name)
+		a.errorf("Error: %v\n", err)
+		return err
+	}
+	policy := *settings.Roam
+	wifiMgr := a.WiFiMgr.ForInterface(settings.Interface)
+
+	a.printf("Roaming on %s below %d dBm, checking every %s\n", settings.SSID, policy.GetThreshold(), policy.GetInterval())
+	ticker := time.NewTicker(policy.GetInterval())
+	defer ticker.Stop()
+	changes := a.configChanges(ctx)
+	for {
+		event, err := wifiMgr.Roam(settings.SSID, policy)
+		if err != nil {
+			// Not fatal: the link may be reconnecting.
+			a.Logger.Warn("Roam check failed", "ssid", settings.SSID, "error", err)
+		} else if event != nil {
+			a.printf("Roamed from %s (%d dBm) to %s (%d dBm)\n", event.From, event.FromSignal, event.To, event.ToSignal)
+			if status, err := a.HotspotMgr.GetStatus(); err == nil && status.Station == wifiMgr.GetInterface() {
+				a.warnHotspotChannel(status)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case change := <-changes:
+			a.reapplyConfig(wifiMgr.GetInterface(), change)
+			if s := a.settingsFor(name); s != nil && s.Roam != nil && change.AffectsNetwork(name, "") {
+				policy = *s.Roam
+				ticker.Reset(policy.GetInterval())
+			}
+		}
+	}
+}
@@ -1,22 +1,161 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/angelfreak/net/pkg/types"
 	"github.com/spf13/cobra"
 )
 
 var scanCmd = &cobra.Command{
 	Use:   "scan [open]",
 	Short: "Scan for WiFi networks (use 'scan open' to show only unprotected)",
+	Long: `Scan for WiFi networks and list every access point heard, strongest first,
+with its security (AKMs and PMF), channel, width, 802.11 generation and WPS.
+Hidden networks are listed as <hidden>.
+
+Examples:
+  net scan                         List every access point
+  net scan open                    Only unprotected networks
+  net scan --band 5                Only 5 GHz access points
+  net scan --security wpa3         Only networks offering WPA3
+  net scan --min-signal -70        Only access points at -70 dBm or better
+  net scan --group-by ssid         One line per network (its strongest AP)`,
 	Run: func(cmd *cobra.Command, args []string) {
-		showOpen := len(args) > 0 && args[0] == "open"
-		if err := createApp().RunScan(showOpen); err != nil {
+		filter := scanFilter{}
+		filter.Band, _ = cmd.Flags().GetString("band")
+		filter.Security, _ = cmd.Flags().GetString("security")
+		filter.MinSignal, _ = cmd.Flags().GetInt("min-signal")
+		filter.GroupBy, _ = cmd.Flags().GetString("group-by")
+		if len(args) > 0 && args[0] == "open" && filter.Security == "" {
+			filter.Security = "open"
+		}
+		if err := createApp().RunScan(filter); err != nil {
 			os.Exit(1)
 		}
 	},
 }
 
 func init() {
+	scanCmd.Flags().String("band", "", "Only show access points on this band: 2.4, 5 or 6 (GHz)")
+	scanCmd.Flags().String("security", "", "Only show networks with this security: open, owe, wep, wpa, wpa2, wpa3 or enterprise")
+	scanCmd.Flags().Int("min-signal", 0, "Only show access points at or above this signal (dBm, e.g. -70)")
+	scanCmd.Flags().String("group-by", "", "Group access points: ssid shows one line per network")
+
 	rootCmd.AddCommand(scanCmd)
 }
+
+// scanFilter narrows and groups the output of `net scan`.
+type scanFilter struct {
+	Band      string // "2.4", "5" or "6" (GHz); "" for all bands
+	Security  string // "open", "owe", "wep", "wpa", "wpa2", "wpa3" or "enterprise"; "" for all
+	MinSignal int    // dBm; 0 for no minimum
+	GroupBy   string // "ssid" for one line per network; "" for one per access point
+}
+
+// validate rejects unknown filter values.
+func (f scanFilter) validate() error {
+	switch f.Band {
+	case "", "2.4", "5", "6":
+	default:
+		return fmt.Errorf("invalid band %q: use 2.4, 5 or 6", f.Band)
+	}
+	switch f.Security {
+	case "", "open", "owe", "wep", "wpa", "wpa2", "wpa3", "enterprise":
+	default:
+		return fmt.Errorf("invalid security %q: use open, owe, wep, wpa, wpa2, wpa3 or enterprise", f.Security)
+	}
+	if f.MinSignal > 0 {
+		return fmt.Errorf("invalid minimum signal %d: signal is in dBm, e.g. -70", f.MinSignal)
+	}
+	switch f.GroupBy {
+	case "", "ssid":
+	default:
+		return fmt.Errorf("invalid grouping %q: use ssid", f.GroupBy)
+	}
+	return nil
+}
+
+// matches reports whether a scanned access point passes the filter.
+func (f scanFilter) matches(n types.WiFiNetwork) bool {
+	if f.Band != "" && n.Band() != f.Band {
+		return false
+	}
+	if f.MinSignal != 0 && n.Signal < f.MinSignal {
+		return false
+	}
+	switch f.Security {
+	case "open":
+		return n.Security == "Open"
+	case "owe":
+		return n.HasAKM("OWE")
+	case "wep":
+		return n.Security == "WEP"
+	case "wpa":
+		return n.Security == "WPA"
+	case "wpa2":
+		return n.Security == "WPA2" || n.Security == "WPA2/WPA3"
+	case "wpa3":
+		return n.Security == "WPA3" || n.Security == "WPA2/WPA3" || n.Security == "WPA3-Enterprise"
+	case "enterprise":
+		return n.Security == "WPA2-Enterprise" || n.Security == "WPA3-Enterprise"
+	}
+	return true
+}
+
+// scanEntry is one line of `net scan` output: an access point, or with
+// --group-by ssid the strongest access point of a network.
+type scanEntry struct {
+	types.WiFiNetwork
+	APs int
+}
+
+// apply filters networks (strongest first) and groups them as requested.
+// Hidden networks are never grouped: their access points can't be told apart
+// by name.
+func (f scanFilter) apply(networks []types.WiFiNetwork) []scanEntry {
+	var entries []scanEntry
+	bySSID := make(map[string]int)
+	for _, n := range networks {
+		if !f.matches(n) {
+			continue
+		}
+		if f.GroupBy == "ssid" && !n.Hidden {
+			if i, ok := bySSID[n.SSID]; ok {
+				entries[i].APs++
+				continue
+			}
+			bySSID[n.SSID] = len(entries)
+		}
+		entries = append(entries, scanEntry{WiFiNetwork: n, APs: 1})
+	}
+	return entries
+}
+
+// scanDetails formats what the scan parsed beyond signal and security
+// summary: AKMs and PMF, channel, width, generation and WPS.
+func scanDetails(n types.WiFiNetwork) string {
+	details := ""
+	if len(n.AKMs) > 0 {
+		pmf := ""
+		switch {
+		case n.PMFRequired:
+			pmf = ", PMF required"
+		case n.PMFCapable:
+			pmf = ", PMF capable"
+		}
+		details += fmt.Sprintf(" (%s%s)", strings.Join(n.AKMs, " "), pmf)
+	}
+	if n.Channel > 0 {
+		details += fmt.Sprintf(" - Channel: %d (%s GHz, %d MHz)", n.Channel, n.Band(), n.Width)
+	}
+	if n.Generation != "" {
+		details += " - 802.11" + n.Generation
+	}
+	if n.WPS {
+		details += " - WPS"
+	}
+	return details
+}
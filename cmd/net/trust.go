@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/vpn"
+)
+
+// trustLevel returns the trust level of a connection: the network's trust:
+// key, or public for an SSID that has no network config of its own.
+// configName is "" for a wired link no profile matched, which has none.
+func (a *App) trustLevel(configName string) string {
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil || configName == "" {
+		return ""
+	}
+	if nc, ok := cfg.Network(configName); ok {
+		return strings.ToLower(strings.TrimSpace(nc.Trust))
+	}
+	return types.TrustPublic
+}
+
+// trustPolicy returns the common.trust policy for level, if one is defined.
+func (a *App) trustPolicy(level string) (types.TrustPolicy, bool) {
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil || level == "" {
+		return types.TrustPolicy{}, false
+	}
+	policy, ok := cfg.Common.Trust[level]
+	return policy, ok
+}
+
+// settingsFor returns the merged settings a connection was made with: the
+// network's config merged with its trust policy and the common settings, or
+// for an unknown SSID the common settings under its trust level.
+func (a *App) settingsFor(configName string) *types.NetworkConfig {
+	cfg := a.ConfigMgr.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+	if nc, ok := cfg.Network(configName); ok {
+		return a.ConfigMgr.MergeWithCommon(configName, &nc)
+	}
+	return a.ConfigMgr.MergeWithCommon("", &types.NetworkConfig{Trust: a.trustLevel(configName)})
+}
+
+// resetTrustPolicy removes the kill switch and inbound filter of the
+// previous connection's trust policy. Returns whether any was in place.
+func (a *App) resetTrustPolicy() bool {
+	p := a.primaryProfile()
+	removed := p != nil && len(p.Protections) > 0
+	if a.FirewallMgr == nil {
+		return removed
+	}
+	if err := a.FirewallMgr.DisableKillSwitch(); err != nil {
+		a.Logger.Warn("Failed to remove kill switch", "error", err)
+	}
+	if err := a.FirewallMgr.UnblockInbound(); err != nil {
+		a.Logger.Warn("Failed to remove inbound filter", "error", err)
+	}
+	return removed
+}
+
+// applyInboundFilter firewalls iface when the policy asks for it. It runs
+// right after link-up, before anything else talks on the network. Returns
+// the protection applied, if any.
+func (a *App) applyInboundFilter(policy types.TrustPolicy, iface string) []string {
+	if !policy.Firewall {
+		return nil
+	}
+	if a.FirewallMgr == nil {
+		a.errorf("Warning: inbound firewall not enabled: iptables is unavailable\n")
+		return nil
+	}
+	if err := a.FirewallMgr.BlockInbound(iface); err != nil {
+		a.Logger.Error("Failed to block inbound connections", "iface", iface, "error", err)
+		a.errorf("Warning: inbound firewall not enabled: %v\n", err)
+		return nil
+	}
+	return []string{"firewall"}
+}
+
+// applyTunnelPolicy turns on the encrypted DNS and kill switch of a trust
+// policy once the VPN (vpnName, "" for none) has been brought up. Encrypted
+// DNS is held back behind a captive portal, whose login page needs plain DNS;
+// the kill switch is not, so a tunnel that fails behind a portal leaks
+// nothing either. Returns the protections applied.
+func (a *App) applyTunnelPolicy(level string, policy types.TrustPolicy, iface, vpnName string, dns []string, portal bool) []string {
+	if !policy.EncryptedDNS && !policy.KillSwitch {
+		return nil
+	}
+
+	var applied []string
+	if policy.EncryptedDNS && portal {
+		a.errorf("Note: encrypted DNS of the %s policy is off until the portal login is done; run `net connect` again afterwards.\n", level)
+	} else if policy.EncryptedDNS {
+		if err := a.NetworkMgr.SetEncryptedDNS(dns); err != nil {
+			a.Logger.Error("Failed to set up encrypted DNS", "error", err)
+			a.errorf("Warning: encrypted DNS not enabled: %v\n", err)
+		} else {
+			applied = append(applied, "encrypted DNS")
+		}
+	}
+
+	if policy.KillSwitch {
+		if ok := a.enableKillSwitch(level, iface, vpnName); ok {
+			applied = append(applied, "kill switch")
+			if portal {
+				a.errorf("Note: the kill switch blocks the portal login page: log in after `net connect --no-vpn`, then run `net connect` again.\n")
+			}
+		}
+	}
+	return applied
+}
+
+// raiseKillSwitch puts up the kill switch of a trust policy on iface before
+// the VPN (vpnName, "" for none) is started. Until enableKillSwitch tightens
+// it afterwards, the network's DNS servers stay reachable too, since VPN
+// clients look up their servers' names themselves. A kill switch that can't
+// go up is an error: without iptables, or for a Tailscale or NetBird VPN,
+// whose relays and peers aren't known in advance.
+func (a *App) raiseKillSwitch(iface, vpnName string) error {
+	if a.NoVPN {
+		return nil
+	}
+	if a.FirewallMgr == nil {
+		return fmt.Errorf("iptables is unavailable")
+	}
+	allowed, err := a.vpnServers(vpnName)
+	if err != nil {
+		return err
+	}
+	if info, err := a.NetworkMgr.GetConnectionInfo(iface); err == nil {
+		for _, ip := range info.DNS {
+			allowed = append(allowed, ip.String())
+		}
+	}
+	return a.FirewallMgr.EnableKillSwitch(iface, allowed)
+}
+
+// dropLink takes iface down again after its trust policy's kill switch
+// couldn't be put up, undoing what the connect applied so far: a policy
+// that asks for a kill switch never leaves the link up without one.
+func (a *App) dropLink(level, iface string, cause error) error {
+	a.errorf("Error: kill switch of the %s policy can't be enabled: %v\n", level, cause)
+	if _, ok := a.stations()[iface]; ok {
+		if err := a.WiFiMgr.ForInterface(iface).Disconnect(); err != nil {
+			a.Logger.Debug("Failed to disconnect WiFi", "interface", iface, "error", err)
+		}
+	}
+	if err := a.NetworkMgr.Disconnect(iface); err != nil {
+		a.Logger.Error("Failed to take the link down", "interface", iface, "error", err)
+	}
+	a.resetTrustPolicy()
+	a.resetHostnameMode()
+	a.clearActiveProfile(iface)
+	a.errorf("Disconnected %s, so nothing leaves it unprotected.\n", iface)
+	return fmt.Errorf("kill switch of the %s policy can't be enabled: %w", level, cause)
+}
+
+// vpnServers returns the addresses the kill switch lets through for VPN
+// vpnName: none without a VPN.
+func (a *App) vpnServers(vpnName string) ([]string, error) {
+	if vpnName == "" {
+		return nil, nil
+	}
+	vpnConfig, err := a.ConfigMgr.GetVPNConfig(vpnName)
+	if err != nil {
+		return nil, err
+	}
+	return vpn.ServerAddresses(vpnConfig)
+}
+
+// enableKillSwitch blocks traffic on iface that doesn't go to the VPN
+// servers. Without a VPN that blocks everything, which is what a kill
+// switch promises, so it only stays off when asked to with --no-vpn.
+func (a *App) enableKillSwitch(level, iface, vpnName string) bool {
+	if a.NoVPN {
+		a.errorf("Note: kill switch of the %s policy not enabled with --no-vpn.\n", level)
+		return false
+	}
+	if a.FirewallMgr == nil {
+		a.errorf("Warning: kill switch not enabled: iptables is unavailable\n")
+		return false
+	}
+	allowed, err := a.vpnServers(vpnName)
+	if err != nil {
+		a.errorf("Warning: kill switch not enabled: %v\n", err)
+		return false
+	}
+	if err := a.FirewallMgr.EnableKillSwitch(iface, allowed); err != nil {
+		a.Logger.Error("Failed to enable kill switch", "iface", iface, "error", err)
+		a.errorf("Warning: kill switch not enabled: %v\n", err)
+		return false
+	}
+	if vpnName == "" {
+		a.errorf("Kill switch on with no VPN: all traffic on %s is blocked.\n", iface)
+	} else {
+		a.printf("Kill switch on: traffic on %s only leaves through VPN '%s'.\n", iface, vpnName)
+	}
+	return true
+}
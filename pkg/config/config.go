@@ -1,13 +1,17 @@
 package config
 
 import (
+	"bytes"
 	"crypto/rand"
 	"fmt"
 	"math/big"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/angelfreak/net/pkg/secrets"
 	"github.com/angelfreak/net/pkg/types"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
@@ -20,16 +24,27 @@ var (
 		"common":  true,
 		"ignored": true,
 		"vpn":     true,
+		"include": true,
+		"hotspot": true,
+	}
+
+	// VPN names that `net vpn <name>` can't connect: its "stop" argument and
+	// its "add" subcommand
+	reservedVPNNames = map[string]bool{
+		"add":  true,
+		"stop": true,
 	}
 
 	// Valid fields for CommonConfig
 	validCommonFields = map[string]bool{
-		"mac":      true,
-		"dns":      true,
-		"hostname": true,
-		"vpn":      true,
-		"timeouts": true,
-		"portal":   true,
+		"mac":           true,
+		"dns":           true,
+		"hostname":      true,
+		"vpn":           true,
+		"timeouts":      true,
+		"portal":        true,
+		"trust":         true,
+		"hostname_mode": true,
 	}
 
 	// Valid fields for PortalConfig
@@ -56,23 +71,61 @@ var (
 		"setup_key":      true, // NetBird setup key
 		"management_url": true, // NetBird management URL
 		"profile":        true, // Tailscale/NetBird profile for account switching
+		"private_key":    true, // WireGuard private key (usually a secret reference)
+	}
+
+	// Valid fields for a hotspot profile (HotspotConfig)
+	validHotspotFields = map[string]bool{
+		"interface":   true,
+		"ssid":        true,
+		"password":    true,
+		"security":    true,
+		"channel":     true,
+		"band":        true,
+		"width":       true,
+		"country":     true,
+		"ieee80211n":  true,
+		"ieee80211ac": true,
+		"ieee80211ax": true,
+		"ip_range":    true,
+		"gateway":     true,
+		"netmask":     true,
+		"dns":         true,
+		"deny":        true,
+		"allow":       true,
+		"max_clients": true,
+		"uplink":      true,
 	}
 
 	// Valid fields for NetworkConfig
 	validNetworkFields = map[string]bool{
-		"interface": true,
-		"ssid":      true,
-		"psk":       true,
-		"wpa":       true,
-		"ap-addr":   true,
-		"addr":      true,
-		"gateway":   true,
-		"routes":    true,
-		"dns":       true,
-		"mac":       true,
-		"hostname":  true,
-		"vpn":       true,
-		"metric":    true,
+		"interface":      true,
+		"ssid":           true,
+		"psk":            true,
+		"wpa":            true,
+		"ap-addr":        true,
+		"addr":           true,
+		"gateway":        true,
+		"routes":         true,
+		"dns":            true,
+		"mac":            true,
+		"hostname":       true,
+		"vpn":            true,
+		"metric":         true,
+		"match":          true,
+		"trust":          true,
+		"hostname_mode":  true,
+		"dhcp_anonymous": true,
+		"roam":           true,
+		"hidden":         true,
+	}
+
+	// Valid fields for a network's match: rules
+	validMatchFields = map[string]bool{
+		"bssid":       true,
+		"gateway-mac": true,
+		"domain":      true,
+		"switch":      true,
 	}
 )
 
@@ -84,16 +137,24 @@ type ValidationError struct {
 	// Message, when set, is used verbatim by Error() — for value-level errors
 	// (e.g. a bad common.portal.check) that are not "unknown field" reports.
 	Message string
+	// File, when set, names the included or drop-in file the error is in.
+	File string
 }
 
 func (e ValidationError) Error() string {
-	if e.Message != "" {
-		return e.Message
-	}
-	if e.Suggestion != "" {
-		return fmt.Sprintf("unknown field '%s' in %s (did you mean '%s'?)", e.Field, e.Section, e.Suggestion)
-	}
-	return fmt.Sprintf("unknown field '%s' in %s", e.Field, e.Section)
+	var msg string
+	switch {
+	case e.Message != "":
+		msg = e.Message
+	case e.Suggestion != "":
+		msg = fmt.Sprintf("unknown field '%s' in %s (did you mean '%s'?)", e.Field, e.Section, e.Suggestion)
+	default:
+		msg = fmt.Sprintf("unknown field '%s' in %s", e.Field, e.Section)
+	}
+	if e.File != "" && !strings.Contains(msg, e.File) {
+		return e.File + ": " + msg
+	}
+	return msg
 }
 
 // ValidationErrors is a collection of validation errors
@@ -182,13 +243,63 @@ func validateFields(section string, data map[string]interface{}, validFields map
 	return errors
 }
 
+// validateHotspotChannel checks that a hotspot profile's channel: is a
+// number or "auto". The number itself is checked when the hotspot starts.
+func validateHotspotChannel(section string, value interface{}) []ValidationError {
+	switch v := value.(type) {
+	case nil, int:
+		return nil
+	case string:
+		if _, err := strconv.Atoi(v); err == nil || strings.EqualFold(v, "auto") {
+			return nil
+		}
+	}
+	return []ValidationError{{Section: section, Field: "channel",
+		Message: section + `.channel must be a channel number or "auto"`}}
+}
+
+// validateUplink checks that a sharing uplink: is auto, vpn or an interface
+// name.
+func validateUplink(section string, value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	if uplink, ok := value.(string); ok && types.ValidateUplink(uplink) == nil {
+		return nil
+	}
+	return []ValidationError{{Section: section, Field: "uplink",
+		Message: section + `.uplink must be "auto", "vpn" or an interface name`}}
+}
+
+// validateHidden checks that hidden: is a boolean on a network with an SSID.
+func validateHidden(section string, network map[string]interface{}) []ValidationError {
+	value, ok := network["hidden"]
+	if !ok {
+		return nil
+	}
+	msg := ""
+	if hidden, isBool := value.(bool); !isBool {
+		msg = section + ".hidden must be true or false"
+	} else if hidden && network["ssid"] == nil {
+		msg = section + ".hidden needs an ssid to probe for"
+	}
+	if msg == "" {
+		return nil
+	}
+	return []ValidationError{{Section: section, Field: "hidden", Message: msg}}
+}
+
 // ValidateConfigFile validates a config file for unknown/misspelled fields
 func ValidateConfigFile(path string) ValidationErrors {
-	data, err := os.ReadFile(path)
+	data, err := readConfigData(path, promptPassphrase)
 	if err != nil {
 		return nil // File read errors handled elsewhere
 	}
+	return validateConfigData(data)
+}
 
+// validateConfigData validates an already-read config document
+func validateConfigData(data []byte) ValidationErrors {
 	var raw map[string]interface{}
 	if err := yaml.Unmarshal(data, &raw); err != nil {
 		return nil // Parse errors handled elsewhere
@@ -206,6 +317,8 @@ func validateRawConfig(raw map[string]interface{}) ValidationErrors {
 		case "common":
 			if commonMap, ok := value.(map[string]interface{}); ok {
 				errors = append(errors, validateFields("common", commonMap, validCommonFields)...)
+				errors = append(errors, validateTrustPolicies(commonMap["trust"])...)
+				errors = append(errors, validateHostnameMode("common", commonMap["hostname_mode"])...)
 				// common.portal: absent or null → defaults; map → validate; else reject.
 				if portalVal, exists := commonMap["portal"]; exists && portalVal != nil {
 					portalMap, ok := portalVal.(map[string]interface{})
@@ -245,6 +358,10 @@ func validateRawConfig(raw map[string]interface{}) ValidationErrors {
 					}
 				}
 			}
+		case "include":
+			if _, err := includePatterns(value); err != nil {
+				errors = append(errors, ValidationError{Section: "include", Field: "include", Message: err.Error()})
+			}
 		case "ignored":
 			if ignoredMap, ok := value.(map[string]interface{}); ok {
 				errors = append(errors, validateFields("ignored", ignoredMap, validIgnoredFields)...)
@@ -252,17 +369,42 @@ func validateRawConfig(raw map[string]interface{}) ValidationErrors {
 		case "vpn":
 			if vpnMap, ok := value.(map[string]interface{}); ok {
 				for vpnName, vpnValue := range vpnMap {
+					if reservedVPNNames[strings.ToLower(vpnName)] {
+						errors = append(errors, ValidationError{
+							Section: "vpn", Field: vpnName,
+							Message: fmt.Sprintf("VPN name '%s' is reserved by `net vpn %s`, choose another name", vpnName, strings.ToLower(vpnName)),
+						})
+					}
 					if vpnConfig, ok := vpnValue.(map[string]interface{}); ok {
-						errs := validateFields(fmt.Sprintf("vpn.%s", vpnName), vpnConfig, validVPNFields)
-						errors = append(errors, errs...)
+						section := fmt.Sprintf("vpn.%s", vpnName)
+						errors = append(errors, validateFields(section, vpnConfig, validVPNFields)...)
+						errors = append(errors, validateSecretRefs(section, vpnConfig, vpnSecretFields)...)
+					}
+				}
+			}
+		case "hotspot":
+			if hotspotMap, ok := value.(map[string]interface{}); ok {
+				for name, profileValue := range hotspotMap {
+					if profile, ok := profileValue.(map[string]interface{}); ok {
+						section := fmt.Sprintf("hotspot.%s", name)
+						errors = append(errors, validateFields(section, profile, validHotspotFields)...)
+						errors = append(errors, validateSecretRefs(section, profile, hotspotSecretFields)...)
+						errors = append(errors, validateHotspotChannel(section, profile["channel"])...)
+						errors = append(errors, validateUplink(section, profile["uplink"])...)
 					}
 				}
 			}
 		default:
 			// It's either a network config or an alias (string value)
 			if netMap, ok := value.(map[string]interface{}); ok {
-				errs := validateFields(fmt.Sprintf("network '%s'", key), netMap, validNetworkFields)
-				errors = append(errors, errs...)
+				section := fmt.Sprintf("network '%s'", key)
+				errors = append(errors, validateFields(section, netMap, validNetworkFields)...)
+				errors = append(errors, validateSecretRefs(section, netMap, networkSecretFields)...)
+				errors = append(errors, validateMatch(section, netMap["match"])...)
+				errors = append(errors, validateNetworkTrust(section, netMap["trust"])...)
+				errors = append(errors, validateHostnameMode(section, netMap["hostname_mode"])...)
+				errors = append(errors, validateRoam(section, netMap["roam"])...)
+				errors = append(errors, validateHidden(section, netMap)...)
 			}
 			// String values are aliases, no validation needed
 		}
@@ -317,10 +459,16 @@ func randomFirstName() string {
 
 // Manager implements the ConfigManager interface
 type Manager struct {
-	config     *types.Config
-	logger     types.Logger
-	viper      *viper.Viper
-	configPath string
+	mu          sync.RWMutex // guards the loaded state against Reload swapping it
+	config      *types.Config
+	logger      types.Logger
+	viper       *viper.Viper
+	configPath  string
+	secrets     *secrets.Resolver // resolves secret references in credential fields
+	passphrase  PassphraseFunc    // prompts for an encrypted config's passphrase
+	noPrompt    bool              // never prompt (SetPassphrasePrompt(nil))
+	sourceFiles []string          // every file merged into config, in merge order
+	systemDirs  bool              // whether system drop-ins take part (only for the default config)
 }
 
 // NewManager creates a new config manager
@@ -330,31 +478,38 @@ func NewManager(logger types.Logger) *Manager {
 	}
 }
 
-// LoadConfig loads configuration from the specified path
-func (m *Manager) LoadConfig(path string) (*types.Config, error) {
-	if m.logger != nil {
-		m.logger.Debug("LoadConfig called", "path", path)
-	}
+// SetPassphrasePrompt overrides how the passphrase of an encrypted config is
+// requested (default: a no-echo terminal prompt). A nil prompt disables
+// prompting, so only the key file and keyring are tried.
+func (m *Manager) SetPassphrasePrompt(prompt PassphraseFunc) {
+	m.passphrase = prompt
+	m.noPrompt = prompt == nil
+}
 
-	if path == "-" {
-		// No config file
-		if m.logger != nil {
-			m.logger.Debug("Using no config file (path='-')")
-		}
-		m.config = &types.Config{
-			Common:   types.CommonConfig{},
-			Ignored:  types.IgnoredConfig{},
-			VPN:      make(map[string]types.VPNConfig),
-			Networks: make(map[string]types.NetworkConfig),
-		}
-		return m.config, nil
+// passphrasePrompt returns the prompt to use for encrypted configs.
+func (m *Manager) passphrasePrompt() PassphraseFunc {
+	if m.noPrompt {
+		return nil
+	}
+	if m.passphrase != nil {
+		return m.passphrase
 	}
+	return promptPassphrase
+}
+
+// ResolvePath returns the config file LoadConfig would read for path: ~ is
+// expanded, "" means ~/.net/config.yaml (the invoking user's under sudo), and
+// a missing file falls back to its encrypted variant (<path>.age).
+func ResolvePath(path string) (string, error) {
+	return (&Manager{}).resolvePath(path)
+}
 
+func (m *Manager) resolvePath(path string) (string, error) {
 	// Expand ~ to home directory
 	if strings.HasPrefix(path, "~") {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+			return "", fmt.Errorf("failed to get home directory: %w", err)
 		}
 		path = filepath.Join(home, path[1:])
 		if m.logger != nil {
@@ -386,7 +541,7 @@ func (m *Manager) LoadConfig(path string) (*types.Config, error) {
 			// Fallback to os.UserHomeDir()
 			home, err = os.UserHomeDir()
 			if err != nil {
-				return nil, fmt.Errorf("failed to get home directory: %w", err)
+				return "", fmt.Errorf("failed to get home directory: %w", err)
 			}
 		}
 		path = filepath.Join(home, ".net", "config.yaml")
@@ -399,11 +554,47 @@ func (m *Manager) LoadConfig(path string) (*types.Config, error) {
 		m.logger.Debug("Final config path determined", "path", path)
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// File doesn't exist, return empty config
+	// Fall back to the encrypted variant (config.yaml.age) when the plain
+	// file doesn't exist.
+	if _, err := os.Stat(path); os.IsNotExist(err) && !strings.HasSuffix(path, EncryptedSuffix) {
+		if _, err := os.Stat(path + EncryptedSuffix); err == nil {
+			path += EncryptedSuffix
+			if m.logger != nil {
+				m.logger.Debug("Using encrypted config", "path", path)
+			}
+		}
+	}
+
+	return path, nil
+}
+
+// LoadConfig loads configuration from the specified path
+func (m *Manager) LoadConfig(path string) (*types.Config, error) {
+	return m.loadConfig(path, nil)
+}
+
+// LoadConfigData loads data as the content of the config at path, without
+// reading or writing that file: includes, drop-ins and secrets.yaml resolve
+// as they will once data is saved there. `net config edit` validates the
+// plaintext of an encrypted config this way, so it never touches the disk.
+func (m *Manager) LoadConfigData(path string, data []byte) (*types.Config, error) {
+	if data == nil {
+		data = []byte{}
+	}
+	return m.loadConfig(path, data)
+}
+
+// loadConfig loads the config at path, with data as the main config's
+// content unless it is nil.
+func (m *Manager) loadConfig(path string, data []byte) (*types.Config, error) {
+	if m.logger != nil {
+		m.logger.Debug("LoadConfig called", "path", path)
+	}
+
+	if path == "-" {
+		// No config file
 		if m.logger != nil {
-			m.logger.Debug("Config file does not exist, returning empty config", "path", path)
+			m.logger.Debug("Using no config file (path='-')")
 		}
 		m.config = &types.Config{
 			Common:   types.CommonConfig{},
@@ -414,31 +605,107 @@ func (m *Manager) LoadConfig(path string) (*types.Config, error) {
 		return m.config, nil
 	}
 
-	if m.logger != nil {
-		m.logger.Debug("Config file exists and is readable", "path", path)
+	path, err := m.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// System drop-ins belong to the default config: a config named
+	// explicitly (--config, a test or a scratch file) stands on its own.
+	systemDirs := false
+	if def, err := m.resolvePath(""); err == nil {
+		systemDirs = samePath(path, def)
+	}
+
+	// Read the main config first: its include: directive decides which
+	// other files take part. Reads go through readConfigData so an encrypted
+	// config is decrypted in memory and `!secret` tags survive as references.
+	// The config is always YAML, whatever the file extension.
+	var mainSource *configSource
+	var validationErrors ValidationErrors
+	if data != nil {
+		src, errs, err := parseSource(path, data, true)
+		if err != nil {
+			return nil, err
+		}
+		mainSource = &src
+		validationErrors = append(validationErrors, errs...)
+	} else if _, err := os.Stat(path); err == nil {
+		if m.logger != nil {
+			m.logger.Debug("Config file exists and is readable", "path", path)
+		}
+		src, errs, err := m.readSource(path, true)
+		if err != nil {
+			return nil, err
+		}
+		mainSource = &src
+		validationErrors = append(validationErrors, errs...)
 	}
 
-	v := viper.New()
-	v.SetConfigFile(path)
-
-	// Set config type to yaml for files that might not have standard extensions
-	if filepath.Ext(path) == ".example" || filepath.Ext(path) == "" {
-		v.SetConfigType("yaml")
+	var mainRaw map[string]interface{}
+	if mainSource != nil {
+		mainRaw = mainSource.raw
+		if mainRaw == nil {
+			mainRaw = map[string]interface{}{}
+		}
 	}
-
-	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	paths, err := sourcePaths(path, mainRaw, systemDirs)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		// Neither the file nor any drop-ins exist, return empty config
+		if m.logger != nil {
+			m.logger.Debug("Config file does not exist, returning empty config", "path", path)
+		}
+		m.config = &types.Config{
+			Common:   types.CommonConfig{},
+			Ignored:  types.IgnoredConfig{},
+			VPN:      make(map[string]types.VPNConfig),
+			Networks: make(map[string]types.NetworkConfig),
+		}
+		return m.config, nil
 	}
 
-	if m.logger != nil {
-		m.logger.Debug("Config file loaded")
+	sources := make([]configSource, 0, len(paths))
+	for _, p := range paths {
+		if mainSource != nil && p == path {
+			sources = append(sources, *mainSource)
+			continue
+		}
+		src, errs, err := m.readSource(p, false)
+		if err != nil {
+			return nil, err
+		}
+		if m.logger != nil {
+			m.logger.Debug("Loaded additional config file", "path", p)
+		}
+		sources = append(sources, src)
+		validationErrors = append(validationErrors, errs...)
 	}
 
 	// Validate config for unknown/misspelled fields
-	if validationErrors := ValidateConfigFile(path); len(validationErrors) > 0 {
+	if len(validationErrors) > 0 {
 		return nil, validationErrors
 	}
 
+	merged, provenance, conflicts := mergeSources(sources)
+	if len(conflicts) > 0 {
+		return nil, conflicts
+	}
+	mergedData, err := marshalMerged(merged)
+	if err != nil {
+		return nil, err
+	}
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(mergedData)); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if m.logger != nil {
+		m.logger.Debug("Config file loaded", "files", len(sources))
+	}
+
 	var config types.Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -451,11 +718,31 @@ func (m *Manager) LoadConfig(path string) (*types.Config, error) {
 	if config.Networks == nil {
 		config.Networks = make(map[string]types.NetworkConfig)
 	}
+	// A trust policy holding only a null "vpn:" decodes to nothing; keep the
+	// level so it still counts as defined.
+	for level := range v.GetStringMap("common.trust") {
+		if _, ok := config.Common.Trust[level]; !ok {
+			if config.Common.Trust == nil {
+				config.Common.Trust = make(map[string]types.TrustPolicy)
+			}
+			config.Common.Trust[level] = types.TrustPolicy{}
+		}
+	}
 
 	// Store viper and path for lazy loading networks
 	m.viper = v
 	m.configPath = path
 	m.config = &config
+	m.sourceFiles = paths
+	m.systemDirs = systemDirs
+	config.Sources = provenance
+	m.secrets = secrets.NewResolver(filepath.Join(filepath.Dir(path), "secrets.yaml"))
+
+	hotspots, err := decodeHotspotProfiles(v)
+	if err != nil {
+		return nil, err
+	}
+	config.Hotspot = hotspots
 
 	// Load all network configs upfront (mapstructure ,inline doesn't work with viper)
 	// Networks are all top-level keys that aren't reserved (common, ignored, vpn)
@@ -489,6 +776,15 @@ func (m *Manager) LoadConfig(path string) (*types.Config, error) {
 		}
 	}
 
+	// Resolve aliases now, so lookups never write to the loaded config and
+	// a reload can tell when an alias was pointed elsewhere
+	config.Aliases = make(map[string]types.NetworkConfig)
+	for alias, target := range m.aliases() {
+		if resolved, err := m.resolveAlias(target, 5); err == nil {
+			config.Aliases[alias] = *resolved
+		}
+	}
+
 	// Warn about plain text credentials after successful load
 	m.WarnAboutPlainTextCredentials()
 
@@ -519,13 +815,32 @@ func (m *Manager) warnAboutDanglingVPNRefs() {
 		m.logger.Warn("Config references a VPN that is not defined", "section", section, "vpn", ref)
 	}
 	check("common", m.config.Common.VPN)
+	for level, policy := range m.config.Common.Trust {
+		check("common.trust."+level, policy.VPN)
+	}
 	for name, network := range m.config.Networks {
 		check("network '"+name+"'", network.VPN)
 	}
 }
 
-// GetNetworkConfig returns the configuration for a specific network
+// GetNetworkConfig returns the configuration for a specific network, with
+// secret references in its credential fields resolved. Secrets resolve
+// outside the lock: a cmd: reference can run for a while, and a reload must
+// not wait on it.
 func (m *Manager) GetNetworkConfig(name string) (*types.NetworkConfig, error) {
+	m.mu.RLock()
+	config, err := m.lookupNetworkConfig(name)
+	resolver := m.resolver()
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return resolveNetworkSecrets(resolver, name, config)
+}
+
+// lookupNetworkConfig returns the raw (unresolved) configuration for a
+// network, following aliases. It only reads the loaded config.
+func (m *Manager) lookupNetworkConfig(name string) (*types.NetworkConfig, error) {
 	if m.config == nil || m.viper == nil {
 		return nil, fmt.Errorf("config not loaded")
 	}
@@ -539,15 +854,15 @@ func (m *Manager) GetNetworkConfig(name string) (*types.NetworkConfig, error) {
 		name = strings.ReplaceAll(name, "$(hostname)", hostname)
 	}
 
-	// Check if already loaded in cache
-	if config, exists := m.config.Networks[name]; exists {
+	// Networks and aliases are decoded at load
+	if config, exists := m.config.Network(name); exists {
 		if m.logger != nil {
-			m.logger.Debug("Network config from cache", "network", name, "ssid", config.SSID)
+			m.logger.Debug("Network config found", "network", name, "ssid", config.SSID)
 		}
 		return &config, nil
 	}
 
-	// Lazy load the specific network from viper
+	// Fall back to viper, which matches keys case-insensitively
 	if !m.viper.IsSet(name) {
 		return nil, fmt.Errorf("network configuration '%s' not found", name)
 	}
@@ -561,15 +876,7 @@ func (m *Manager) GetNetworkConfig(name string) (*types.NetworkConfig, error) {
 			if m.logger != nil {
 				m.logger.Debug("Network alias detected", "alias", name, "target", aliasTarget)
 			}
-			resolved, err := m.resolveAlias(aliasTarget, 5)
-			if err != nil {
-				return nil, err
-			}
-			// Cache the resolved config under the ALIAS name too, so later
-			// lookups by alias (notably resolveVPNName, which indexes Networks
-			// by the name the user connected with) find the target's VPN/settings.
-			m.config.Networks[name] = *resolved
-			return resolved, nil
+			return m.resolveAlias(aliasTarget, 5)
 		}
 		return nil, fmt.Errorf("failed to read network configuration '%s'", name)
 	}
@@ -580,8 +887,6 @@ func (m *Manager) GetNetworkConfig(name string) (*types.NetworkConfig, error) {
 		return nil, fmt.Errorf("failed to unmarshal network config '%s': %w", name, err)
 	}
 
-	// Cache it for next time
-	m.config.Networks[name] = netConfig
 	if m.logger != nil {
 		m.logger.Debug("Loaded network config", "network", name, "ssid", netConfig.SSID)
 	}
@@ -633,8 +938,21 @@ func (m *Manager) resolveAlias(name string, maxDepth int) (*types.NetworkConfig,
 	return &netConfig, nil
 }
 
-// GetVPNConfig returns the configuration for a specific VPN
+// GetVPNConfig returns the configuration for a specific VPN, with secret
+// references in its credential fields resolved.
 func (m *Manager) GetVPNConfig(name string) (*types.VPNConfig, error) {
+	m.mu.RLock()
+	config, err := m.lookupVPNConfig(name)
+	resolver := m.resolver()
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return resolveVPNSecrets(resolver, name, config)
+}
+
+// lookupVPNConfig returns the raw (unresolved) configuration of a VPN.
+func (m *Manager) lookupVPNConfig(name string) (*types.VPNConfig, error) {
 	if m.config == nil {
 		return nil, fmt.Errorf("config not loaded")
 	}
@@ -653,13 +971,73 @@ func (m *Manager) GetVPNConfig(name string) (*types.VPNConfig, error) {
 	return &config, nil
 }
 
+// decodeHotspotProfiles decodes the hotspot: section. channel: auto can't
+// go through the int Channel field, so it is mapped to AutoChannel here.
+func decodeHotspotProfiles(v *viper.Viper) (map[string]types.HotspotConfig, error) {
+	profiles := make(map[string]types.HotspotConfig)
+	for name := range v.GetStringMap("hotspot") {
+		sub := v.Sub("hotspot." + name)
+		if sub == nil {
+			continue
+		}
+		auto := false
+		if channel, ok := sub.Get("channel").(string); ok && strings.EqualFold(channel, "auto") {
+			auto = true
+			sub.Set("channel", 0)
+		}
+		var profile types.HotspotConfig
+		if err := sub.Unmarshal(&profile); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal hotspot profile '%s': %w", name, err)
+		}
+		profile.AutoChannel = auto
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// GetHotspotConfig returns the named hotspot profile, with a secret
+// reference in its password resolved.
+func (m *Manager) GetHotspotConfig(name string) (*types.HotspotConfig, error) {
+	m.mu.RLock()
+	config, err := m.lookupHotspotConfig(name)
+	resolver := m.resolver()
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return resolveHotspotSecrets(resolver, name, config)
+}
+
+// lookupHotspotConfig returns the raw (unresolved) configuration of a hotspot profile.
+func (m *Manager) lookupHotspotConfig(name string) (*types.HotspotConfig, error) {
+	if m.config == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+
+	// Viper lowercases map keys, as for VPNs
+	config, exists := m.config.Hotspot[name]
+	if !exists {
+		config, exists = m.config.Hotspot[strings.ToLower(name)]
+	}
+	if !exists {
+		return nil, fmt.Errorf("hotspot profile '%s' not found", name)
+	}
+
+	return &config, nil
+}
+
 // MergeWithCommon merges network config with common settings
 func (m *Manager) MergeWithCommon(networkName string, config *types.NetworkConfig) *types.NetworkConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if m.config == nil {
 		return config
 	}
 
 	merged := *config // Copy
+	merged.Trust = normalizeTrust(merged.Trust)
+	// The trust level's policy sits between the network and common settings.
+	policy, policyVPNSet := m.trustPolicy(merged.Trust)
 
 	// Interface is not in common config, it's per-network
 	if merged.DNS == nil && m.config.Common.DNS != nil {
@@ -667,13 +1045,25 @@ func (m *Manager) MergeWithCommon(networkName string, config *types.NetworkConfi
 		copy(merged.DNS, m.config.Common.DNS)
 	}
 	if merged.MAC == "" {
-		if m.config.Common.MAC != "" {
+		if policy.MAC != "" {
+			merged.MAC = policy.MAC
+		} else if m.config.Common.MAC != "" {
 			merged.MAC = m.config.Common.MAC
 		} else {
 			// Default to random MAC if nothing specified
 			merged.MAC = "random"
 		}
 	}
+	if merged.HostnameMode == "" {
+		// hide_hostname is anonymous mode, unless the network names
+		// itself.
+		if policy.HideHostname && merged.Hostname == "" {
+			merged.HostnameMode = types.HostnameModeAnonymous
+		} else {
+			merged.HostnameMode = m.config.Common.HostnameMode
+		}
+	}
+	merged.HostnameMode = normalizeHostnameMode(merged.HostnameMode)
 	if merged.Hostname == "" {
 		merged.Hostname = m.config.Common.Hostname
 	}
@@ -683,12 +1073,16 @@ func (m *Manager) MergeWithCommon(networkName string, config *types.NetworkConfi
 		// Check if vpn key exists in the network config (even if nil/empty)
 		// viper.IsSet() returns false for nil values, so we check the raw map
 		vpnExplicitlySet := false
-		if m.viper != nil {
+		if m.viper != nil && networkName != "" {
 			networkMap := m.viper.GetStringMap(networkName)
 			_, vpnExplicitlySet = networkMap["vpn"]
 		}
 		if !vpnExplicitlySet {
-			merged.VPN = m.config.Common.VPN
+			if policyVPNSet {
+				merged.VPN = policy.VPN
+			} else {
+				merged.VPN = m.config.Common.VPN
+			}
 		}
 	}
 
@@ -704,14 +1098,26 @@ func (m *Manager) MergeWithCommon(networkName string, config *types.NetworkConfi
 
 // GetIgnoredInterfaces returns the list of ignored interfaces
 func (m *Manager) GetIgnoredInterfaces() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if m.config == nil {
 		return nil
 	}
 	return m.config.Ignored.Interfaces
 }
 
+// SourceFiles returns every file merged into the loaded config (drop-ins,
+// includes and the main file), in merge order.
+func (m *Manager) SourceFiles() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sourceFiles
+}
+
 // GetConfig returns the loaded configuration
 func (m *Manager) GetConfig() *types.Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.config
 }
 
@@ -721,9 +1127,10 @@ func (m *Manager) GetConfig() *types.Config {
 // Security note: Storing passwords and private keys in plain text config files
 // poses security risks. Consider:
 //   - Using file permissions (chmod 600) to restrict access to config files
-//   - Storing sensitive credentials in separate files referenced by path
-//   - Using environment variables for sensitive values
-//   - For VPNs, use separate key files rather than inline config
+//   - Replacing credentials with secret references (!secret, cmd:, file:,
+//     env:, creds:, keyring:), which are resolved only when needed
+//
+// Fields that already hold a secret reference are not reported.
 func (m *Manager) WarnAboutPlainTextCredentials() {
 	if m.config == nil || m.logger == nil {
 		return
@@ -732,19 +1139,39 @@ func (m *Manager) WarnAboutPlainTextCredentials() {
 	// Check for plain text WiFi passwords (PSK fields)
 	// Use Debug level to avoid noise on every invocation - visible with --debug flag
 	for name, network := range m.config.Networks {
-		if network.PSK != "" {
+		if network.PSK != "" && !secrets.IsReference(network.PSK) {
 			m.logger.Debug("WiFi password for network is stored in plain text",
 				"network", name,
-				"suggestion", "Consider using file permissions (chmod 600) to protect your config file")
+				"suggestion", "Use a secret reference, e.g. psk: !secret "+name+" or psk: \"cmd:pass show wifi/"+name+"\"")
 		}
 	}
 
-	// Check for plain text VPN private keys in inline config
+	// Check for plain text hotspot passwords
+	for name, hotspot := range m.config.Hotspot {
+		if hotspot.Password != "" && !secrets.IsReference(hotspot.Password) {
+			m.logger.Debug("Hotspot password is stored in plain text",
+				"hotspot", name,
+				"suggestion", "Use a secret reference, e.g. password: !secret hotspot-"+name)
+		}
+	}
+
+	// Check for plain text VPN keys, inline or in dedicated fields
 	for name, vpn := range m.config.VPN {
 		if containsPrivateKey(vpn.Config) {
 			m.logger.Debug("VPN contains private key in plain text config",
 				"vpn", name,
-				"suggestion", "Consider storing keys in separate files with restricted permissions")
+				"suggestion", "Move the key to private_key: with a secret reference, or use config: file:/path")
+		}
+		for field, value := range map[string]string{
+			"auth_key":    vpn.AuthKey,
+			"setup_key":   vpn.SetupKey,
+			"private_key": vpn.PrivateKey,
+		} {
+			if value != "" && !secrets.IsReference(value) {
+				m.logger.Debug("VPN credential is stored in plain text",
+					"vpn", name, "field", field,
+					"suggestion", "Use a secret reference, e.g. "+field+": !secret "+name)
+			}
 		}
 	}
 }
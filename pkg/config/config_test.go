@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,17 +14,22 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// mockLogger for testing
+// mockLogger for testing; safe for the concurrent reload tests
 type mockLogger struct {
+	mu            sync.Mutex
 	debugMessages []string
 	warnMessages  []string
 }
 
 func (m *mockLogger) Debug(msg string, fields ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.debugMessages = append(m.debugMessages, msg)
 }
 func (m *mockLogger) Info(msg string, fields ...interface{}) {}
 func (m *mockLogger) Warn(msg string, fields ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.warnMessages = append(m.warnMessages, msg)
 }
 func (m *mockLogger) Error(msg string, fields ...interface{}) {}
@@ -967,9 +973,9 @@ static-%s:
 	assert.Equal(t, "HostWifi", nc.SSID)
 }
 
-// Resolving a network via an alias must make the target's config (incl. VPN)
-// findable under the ALIAS name, so connectVPN(aliasName) doesn't skip the VPN.
-func TestGetNetworkConfig_AliasCachedUnderAliasName(t *testing.T) {
+// An alias's resolved config (incl. VPN) is findable under the ALIAS name,
+// so connectVPN(aliasName) doesn't skip the VPN.
+func TestGetNetworkConfig_AliasFindableUnderAliasName(t *testing.T) {
 	tmpDir := t.TempDir()
 	configFile := filepath.Join(tmpDir, "config.yaml")
 	content := `
@@ -985,15 +991,16 @@ home:
 	cfg, err := manager.LoadConfig(configFile)
 	require.NoError(t, err)
 
-	// Connecting via the alias resolves it...
 	_, err = manager.GetNetworkConfig("work")
 	require.NoError(t, err)
 
-	// ...and the resolved config (with the VPN) is now indexable by the alias
-	// name, which is what connectVPN(name) relies on.
-	resolved, ok := cfg.Networks["work"]
-	assert.True(t, ok, "alias must be cached under its own name for connectVPN")
+	// The resolved config (with the VPN) is indexable by the alias name,
+	// which is what connectVPN(name) relies on, without the alias turning
+	// into a network of its own.
+	resolved, ok := cfg.Network("work")
+	assert.True(t, ok, "alias must be findable under its own name for connectVPN")
 	assert.Equal(t, "office-vpn", resolved.VPN)
+	assert.NotContains(t, cfg.Networks, "work")
 }
 
 // loadPortalConfig writes content to a temp config file and loads it through
@@ -1100,3 +1107,215 @@ func TestPortalConfigEmptyURLAllowed(t *testing.T) {
 		assert.NoError(t, err, "portal url form %s must be accepted", name)
 	}
 }
+
+func TestLoadConfig_SecretTagResolvedFromSecretsFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+home:
+  ssid: HomeWiFi
+  psk: !secret home-wifi
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets.yaml"), []byte("home-wifi: hunter2hunter2\n"), 0600))
+
+	manager := NewManager(&mockLogger{})
+	cfg, err := manager.LoadConfig(configPath)
+	require.NoError(t, err)
+	// The loaded config keeps the reference, never the secret.
+	assert.Equal(t, "secret:home-wifi", cfg.Networks["home"].PSK)
+
+	network, err := manager.GetNetworkConfig("home")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2hunter2", network.PSK)
+	assert.Equal(t, "secret:home-wifi", manager.GetConfig().Networks["home"].PSK, "resolved value must not be cached")
+}
+
+func TestGetNetworkConfig_ResolvesEnvReference(t *testing.T) {
+	t.Setenv("NET_TEST_PSK", "from-the-environment")
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, "\ncafe:\n  ssid: Cafe\n  psk: env:NET_TEST_PSK\n")
+	require.NoError(t, err)
+
+	network, err := manager.GetNetworkConfig("cafe")
+	require.NoError(t, err)
+	assert.Equal(t, "from-the-environment", network.PSK)
+}
+
+func TestGetNetworkConfig_UnresolvableReference(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, "\ncafe:\n  ssid: Cafe\n  psk: env:NET_TEST_UNSET_VARIABLE\n")
+	require.NoError(t, err)
+
+	_, err = manager.GetNetworkConfig("cafe")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "network 'cafe' psk")
+	assert.Contains(t, err.Error(), "NET_TEST_UNSET_VARIABLE")
+}
+
+func TestGetVPNConfig_ResolvesSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "wg.key")
+	require.NoError(t, os.WriteFile(keyPath, []byte("private-key-material\n"), 0600))
+	t.Setenv("NET_TEST_AUTH_KEY", "tskey-auth-123")
+
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+vpn:
+  wg:
+    type: wireguard
+    private_key: file:`+keyPath+`
+    config: |
+      [Peer]
+      PublicKey = xyz
+  ts:
+    type: tailscale
+    auth_key: env:NET_TEST_AUTH_KEY
+`)
+	require.NoError(t, err)
+
+	wg, err := manager.GetVPNConfig("wg")
+	require.NoError(t, err)
+	assert.Equal(t, "private-key-material", wg.PrivateKey)
+	assert.Contains(t, wg.Config, "[Peer]", "inline configs are never treated as references")
+
+	ts, err := manager.GetVPNConfig("ts")
+	require.NoError(t, err)
+	assert.Equal(t, "tskey-auth-123", ts.AuthKey)
+	assert.Equal(t, "env:NET_TEST_AUTH_KEY", manager.GetConfig().VPN["ts"].AuthKey)
+}
+
+func TestValidateConfigFile_EmptySecretReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("\nhome:\n  ssid: Home\n  psk: \"cmd:\"\n"), 0600))
+
+	errors := ValidateConfigFile(path)
+	require.Len(t, errors, 1)
+	assert.Equal(t, "psk", errors[0].Field)
+	assert.Contains(t, errors[0].Message, "empty cmd target")
+}
+
+func TestLoadConfig_Hidden(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, "gateway:\n  ssid: Gateway\n  hidden: true\n")
+	require.NoError(t, err)
+	gateway, err := manager.GetNetworkConfig("gateway")
+	require.NoError(t, err)
+	assert.True(t, gateway.Hidden)
+
+	_, err = loadConfigInto(t, NewManager(&mockLogger{}), "gateway:\n  ssid: Gateway\n  hidden: yes\n")
+	assert.ErrorContains(t, err, "'gateway'.hidden must be true or false")
+	_, err = loadConfigInto(t, NewManager(&mockLogger{}), "desk:\n  interface: eth0\n  hidden: true\n")
+	assert.ErrorContains(t, err, "'desk'.hidden needs an ssid to probe for")
+}
+
+func TestWarnAboutPlainTextCredentials_SkipsReferences(t *testing.T) {
+	logger := &mockLogger{}
+	manager := NewManager(logger)
+	manager.config = &types.Config{
+		Networks: map[string]types.NetworkConfig{
+			"home": {SSID: "HomeWiFi", PSK: "secret:home"},
+		},
+		VPN: map[string]types.VPNConfig{
+			"ts": {Type: "tailscale", AuthKey: "cmd:pass show tailscale"},
+		},
+	}
+
+	manager.WarnAboutPlainTextCredentials()
+
+	assert.Empty(t, logger.debugMessages)
+}
+
+func TestLoadConfig_HotspotProfiles(t *testing.T) {
+	t.Setenv("NET_TEST_HOTSPOT_PASSWORD", "car-password")
+	logger := &mockLogger{}
+	manager := NewManager(logger)
+	cfg, err := loadConfigInto(t, manager, `
+hotspot:
+  car:
+    ssid: Car
+    password: env:NET_TEST_HOTSPOT_PASSWORD
+    channel: auto
+    band: "5"
+    deny: [aa:bb:cc:dd:ee:ff]
+    max_clients: 4
+  Demo:
+    ssid: Demo
+    password: demo-password
+    channel: 11
+    security: wpa2-wpa3
+home:
+  ssid: HomeWiFi
+`)
+	require.NoError(t, err)
+	assert.Len(t, cfg.Hotspot, 2)
+	assert.NotContains(t, cfg.Networks, "hotspot", "hotspot: is not a network")
+	assert.Contains(t, cfg.Networks, "home")
+
+	car, err := manager.GetHotspotConfig("car")
+	require.NoError(t, err)
+	assert.Equal(t, types.HotspotConfig{
+		SSID: "Car", Password: "car-password", AutoChannel: true, Band: "5",
+		Deny: []string{"aa:bb:cc:dd:ee:ff"}, MaxClients: 4,
+	}, *car)
+	assert.Equal(t, "env:NET_TEST_HOTSPOT_PASSWORD", manager.GetConfig().Hotspot["car"].Password, "resolved value must not be cached")
+
+	demo, err := manager.GetHotspotConfig("Demo")
+	require.NoError(t, err)
+	assert.Equal(t, 11, demo.Channel)
+	assert.False(t, demo.AutoChannel)
+	assert.Equal(t, "wpa2-wpa3", demo.Security)
+	assert.Contains(t, logger.debugMessages, "Hotspot password is stored in plain text")
+
+	_, err = manager.GetHotspotConfig("boat")
+	assert.EqualError(t, err, "hotspot profile 'boat' not found")
+}
+
+func TestValidateConfigFile_HotspotProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+hotspot:
+  car:
+    ssid: Car
+    pasword: secret
+    channel: fast
+    uplink: "usb 0"
+  demo:
+    password: "cmd:"
+    uplink: vpn
+`), 0600))
+
+	errors := ValidateConfigFile(path)
+	require.Len(t, errors, 4)
+	messages := errors.Error()
+	assert.Contains(t, messages, "pasword")
+	assert.Contains(t, messages, `hotspot.car.channel must be a channel number or "auto"`)
+	assert.Contains(t, messages, `hotspot.demo.password: secret reference "cmd:" has an empty cmd target`)
+	assert.Contains(t, messages, `hotspot.car.uplink must be "auto", "vpn" or an interface name`)
+}
+
+func TestValidateConfigFile_ReservedVPNNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+vpn:
+  add:
+    type: tailscale
+  Stop:
+    type: tailscale
+  work:
+    type: tailscale
+`), 0600))
+
+	errors := ValidateConfigFile(path)
+	require.Len(t, errors, 2)
+	messages := errors.Error()
+	assert.Contains(t, messages, "VPN name 'add' is reserved by `net vpn add`")
+	assert.Contains(t, messages, "VPN name 'Stop' is reserved by `net vpn stop`")
+}
+
+// loadConfigInto writes content to a temp config file and loads it with manager.
+func loadConfigInto(t *testing.T, manager *Manager, content string) (*types.Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return manager.LoadConfig(path)
+}
@@ -0,0 +1,357 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/oui"
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/wgconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Finding severities reported by Doctor.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is one problem reported by Doctor.
+type Finding struct {
+	Severity string `json:"severity"` // SeverityError or SeverityWarning
+	Check    string `json:"check"`    // short check id, e.g. "dangling-vpn"
+	Subject  string `json:"subject"`  // what the finding is about, e.g. "network 'office'"
+	Message  string `json:"message"`
+}
+
+// vpnBinaries maps VPN types to the external binary they need. WireGuard is
+// configured natively and needs none.
+var vpnBinaries = map[string]string{
+	"openvpn":   "openvpn",
+	"tailscale": "tailscale",
+	"netbird":   "netbird",
+}
+
+// CheckFile validates a single config file, decrypting it if needed. Unlike
+// ValidateConfigFile, YAML syntax errors are reported as validation errors;
+// err is only set when the file can't be read or decrypted.
+func CheckFile(path string) (ValidationErrors, error) {
+	data, err := readConfigData(path, promptPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return ValidationErrors{{Section: "syntax", Message: err.Error()}}, nil
+	}
+	return validateRawConfig(raw), nil
+}
+
+// Doctor runs semantic checks on the loaded config that go beyond field
+// validation: references, aliases, addressing, WireGuard configs and the
+// binaries each VPN type needs. hasCommand reports whether a binary is
+// installed (types.SystemExecutor.HasCommand). Secret references are
+// resolved, so unreachable secrets are reported too.
+func (m *Manager) Doctor(hasCommand func(string) bool) []Finding {
+	if m.config == nil {
+		return []Finding{{Severity: SeverityError, Check: "load", Subject: "config", Message: "config not loaded"}}
+	}
+
+	var findings []Finding
+	add := func(severity, check, subject, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: severity, Check: check, Subject: subject, Message: fmt.Sprintf(format, args...)})
+	}
+
+	aliases := m.aliases()
+	networks := make([]string, 0, len(m.config.Networks))
+	for name := range m.config.Networks {
+		if _, isAlias := aliases[strings.ToLower(name)]; !isAlias {
+			networks = append(networks, name)
+		}
+	}
+	sort.Strings(networks)
+
+	// Dangling VPN references (also warned about at load time).
+	hasVPN := func(ref string) bool {
+		_, ok := m.config.VPN[ref]
+		if !ok {
+			_, ok = m.config.VPN[strings.ToLower(ref)]
+		}
+		return ok
+	}
+	if ref := m.config.Common.VPN; ref != "" && !hasVPN(ref) {
+		add(SeverityError, "dangling-vpn", "common", "vpn '%s' is not defined under vpn:", ref)
+	}
+
+	// Alias chains: cycles and missing targets.
+	for _, alias := range sortedStringKeys(aliases) {
+		seen := map[string]bool{alias: true}
+		target := aliases[alias]
+		for {
+			next, isAlias := aliases[strings.ToLower(target)]
+			if !isAlias {
+				if _, ok := m.config.Networks[strings.ToLower(target)]; !ok {
+					if _, ok := m.config.Networks[target]; !ok {
+						add(SeverityError, "alias", fmt.Sprintf("alias '%s'", alias), "target '%s' is not a configured network", target)
+					}
+				}
+				break
+			}
+			if seen[strings.ToLower(target)] {
+				add(SeverityError, "alias-cycle", fmt.Sprintf("alias '%s'", alias), "alias chain loops back to '%s'", target)
+				break
+			}
+			seen[strings.ToLower(target)] = true
+			target = next
+		}
+	}
+
+	if err := validateMACSetting(m.config.Common.MAC); err != nil {
+		add(SeverityError, "mac", "common", "%v", err)
+	}
+
+	for _, level := range types.TrustLevels {
+		if _, ok := m.config.Common.Trust[level]; !ok {
+			continue
+		}
+		policy, vpnSet := m.trustPolicy(level)
+		vpn := m.config.Common.VPN
+		if vpnSet {
+			vpn = policy.VPN
+		}
+		subject := "common.trust." + level
+		if ref := policy.VPN; ref != "" && !hasVPN(ref) {
+			add(SeverityError, "dangling-vpn", subject, "vpn '%s' is not defined under vpn:", ref)
+		}
+		if err := validateMACSetting(policy.MAC); err != nil {
+			add(SeverityError, "mac", subject, "%v", err)
+		}
+		if policy.KillSwitch && vpn == "" {
+			add(SeverityWarning, "kill-switch", subject, "kill_switch without a VPN blocks all traffic on %s networks", level)
+		}
+		if vpnType := m.vpnType(vpn); policy.KillSwitch && (vpnType == "tailscale" || vpnType == "netbird") {
+			add(SeverityError, "kill-switch", subject, "kill_switch can't be enforced with %s VPN '%s': connecting to %s networks fails", vpnType, vpn, level)
+		}
+		if policy.EncryptedDNS && hasCommand != nil && !hasCommand("stubby") {
+			add(SeverityError, "missing-binary", subject, "stubby is not installed (needed for encrypted_dns)")
+		}
+	}
+
+	ssids := make(map[string][]string)
+	for _, name := range networks {
+		network := m.config.Networks[name]
+		subject := fmt.Sprintf("network '%s'", name)
+
+		if ref := network.VPN; ref != "" && !hasVPN(ref) {
+			add(SeverityError, "dangling-vpn", subject, "vpn '%s' is not defined under vpn:", ref)
+		}
+		if network.SSID != "" {
+			ssids[network.SSID] = append(ssids[network.SSID], name)
+		}
+		if err := validateMACSetting(network.MAC); err != nil {
+			add(SeverityError, "mac", subject, "%v", err)
+		}
+		if level := normalizeTrust(network.Trust); level != "" {
+			if _, ok := m.config.Common.Trust[level]; !ok {
+				add(SeverityWarning, "trust", subject, "trust level '%s' has no common.trust.%s policy, so it changes nothing", level, level)
+			}
+		}
+		if msg := checkGateway(network.Addr, network.Gateway); msg != "" {
+			add(SeverityWarning, "gateway", subject, "%s", msg)
+		}
+		for _, msg := range checkRoutes(network.Routes) {
+			add(SeverityWarning, "routes", subject, "%s", msg)
+		}
+		if _, err := m.GetNetworkConfig(name); err != nil {
+			add(SeverityError, "secret", subject, "%v", err)
+		}
+	}
+
+	for _, ssid := range sortedStringSliceKeys(ssids) {
+		names := ssids[ssid]
+		if len(names) < 2 {
+			continue
+		}
+		// Shared SSIDs are fine when match: rules tell the sites apart.
+		for _, name := range names {
+			if m.config.Networks[name].Match.IsEmpty() {
+				add(SeverityWarning, "duplicate-ssid", fmt.Sprintf("ssid '%s'", ssid),
+					"used by networks %s; add match: rules to each so `net connect %s` can tell which config to apply", strings.Join(names, ", "), ssid)
+				break
+			}
+		}
+	}
+
+	vpnNames := make([]string, 0, len(m.config.VPN))
+	for name := range m.config.VPN {
+		vpnNames = append(vpnNames, name)
+	}
+	sort.Strings(vpnNames)
+	missing := make(map[string]bool)
+	for _, name := range vpnNames {
+		vpnType := m.config.VPN[name].Type
+		subject := fmt.Sprintf("vpn '%s'", name)
+
+		binary, needsBinary := vpnBinaries[vpnType]
+		if vpnType != "wireguard" && !needsBinary {
+			add(SeverityError, "vpn-type", subject, "unknown type '%s' (expected openvpn, wireguard, tailscale or netbird)", vpnType)
+			continue
+		}
+		if needsBinary && hasCommand != nil && !hasCommand(binary) && !missing[binary] {
+			missing[binary] = true
+			add(SeverityError, "missing-binary", subject, "%s is not installed (needed for %s VPNs)", binary, vpnType)
+		}
+
+		resolved, err := m.GetVPNConfig(name)
+		if err != nil {
+			add(SeverityError, "secret", subject, "%v", err)
+			continue
+		}
+		if vpnType == "wireguard" {
+			config := resolved.Config
+			if resolved.PrivateKey != "" {
+				config = wgconfig.WithPrivateKey(config, resolved.PrivateKey)
+			}
+			if strings.TrimSpace(config) == "" {
+				add(SeverityError, "wireguard", subject, "no WireGuard config")
+			} else if err := wgconfig.Validate(config); err != nil {
+				add(SeverityError, "wireguard", subject, "invalid WireGuard config: %v", err)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity == SeverityError && findings[j].Severity != SeverityError
+	})
+	return findings
+}
+
+// vpnType returns the type of VPN name, or "" when it isn't defined.
+func (m *Manager) vpnType(name string) string {
+	if vpn, ok := m.config.VPN[name]; ok {
+		return vpn.Type
+	}
+	return m.config.VPN[strings.ToLower(name)].Type
+}
+
+// aliases returns the configured network aliases (lowercased name -> target).
+func (m *Manager) aliases() map[string]string {
+	aliases := make(map[string]string)
+	if m.viper == nil {
+		return aliases
+	}
+	for key, value := range m.viper.AllSettings() {
+		if reservedKeys[key] {
+			continue
+		}
+		if target, ok := value.(string); ok && target != "" {
+			aliases[key] = target
+		}
+	}
+	return aliases
+}
+
+// validateMACSetting checks a mac: value: empty, a keyword accepted by
+// SetMAC, a literal address, or a template using "??" for random octets.
+func validateMACSetting(mac string) error {
+	if strings.Contains(mac, "??") {
+		if err := types.ValidateMAC(strings.ReplaceAll(mac, "??", "00")); err != nil {
+			return fmt.Errorf("invalid MAC template '%s': use XX:XX:XX:XX:XX:XX with ?? for random octets", mac)
+		}
+		return nil
+	}
+	if err := types.ValidateMAC(mac); err != nil {
+		return fmt.Errorf("invalid mac '%s': %v", mac, err)
+	}
+	if vendor, ok := strings.CutPrefix(mac, "vendor:"); ok {
+		if _, err := oui.RandomMAC(strings.TrimSpace(vendor)); err != nil {
+			return fmt.Errorf("invalid mac '%s': %v", mac, err)
+		}
+	}
+	return nil
+}
+
+// checkGateway reports a static gateway outside the subnet of addr, which the
+// kernel can't reach without an extra on-link route. addr without a prefix
+// length can't be checked.
+func checkGateway(addr, gateway string) string {
+	if addr == "" || gateway == "" || !strings.Contains(addr, "/") {
+		return ""
+	}
+	_, subnet, err := net.ParseCIDR(addr)
+	if err != nil {
+		return fmt.Sprintf("invalid addr '%s'", addr)
+	}
+	gw := net.ParseIP(gateway)
+	if gw == nil {
+		return fmt.Sprintf("invalid gateway '%s'", gateway)
+	}
+	if !subnet.Contains(gw) {
+		return fmt.Sprintf("gateway %s is not in the addr subnet %s and will be unreachable", gateway, subnet)
+	}
+	return ""
+}
+
+// checkRoutes reports malformed routes ("destination -> gateway") and
+// destinations that overlap, where the more specific route silently wins.
+func checkRoutes(routes []string) []string {
+	var msgs []string
+	type dest struct {
+		route string
+		net   *net.IPNet
+	}
+	var dests []dest
+	for _, route := range routes {
+		if strings.TrimSpace(route) == "default" {
+			continue
+		}
+		parts := strings.Split(route, " -> ")
+		if len(parts) != 2 {
+			msgs = append(msgs, fmt.Sprintf("route '%s' must be 'destination -> gateway'", route))
+			continue
+		}
+		destination := strings.TrimSpace(parts[0])
+		if !strings.Contains(destination, "/") {
+			if ip := net.ParseIP(destination); ip != nil && ip.To4() != nil {
+				destination += "/32"
+			} else {
+				destination += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(destination)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("route '%s' has an invalid destination", route))
+			continue
+		}
+		if net.ParseIP(strings.TrimSpace(parts[1])) == nil {
+			msgs = append(msgs, fmt.Sprintf("route '%s' has an invalid gateway", route))
+		}
+		for _, d := range dests {
+			if d.net.Contains(ipNet.IP) || ipNet.Contains(d.net.IP) {
+				msgs = append(msgs, fmt.Sprintf("routes '%s' and '%s' overlap", d.route, route))
+			}
+		}
+		dests = append(dests, dest{route: route, net: ipNet})
+	}
+	return msgs
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStringSliceKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findingsByCheck indexes findings by check id for assertions.
+func findingsByCheck(findings []Finding) map[string][]Finding {
+	byCheck := make(map[string][]Finding)
+	for _, f := range findings {
+		byCheck[f.Check] = append(byCheck[f.Check], f)
+	}
+	return byCheck
+}
+
+func TestDoctor_CleanConfig(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+common:
+  mac: "00:??:??:??:??:??"
+home:
+  ssid: HomeWiFi
+  addr: 192.168.1.10/24
+  gateway: 192.168.1.1
+  routes:
+    - "10.0.0.0/8 -> 192.168.1.254"
+    - "172.16.0.0/12 -> 192.168.1.254"
+work: home
+vpn:
+  ts:
+    type: tailscale
+`)
+	require.NoError(t, err)
+
+	findings := manager.Doctor(func(string) bool { return true })
+	assert.Empty(t, findings)
+}
+
+func TestDoctor_FindsProblems(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+common:
+  vpn: missing
+home:
+  ssid: Shared
+  mac: "zz:??:00:00:00:00"
+  addr: 192.168.1.10/24
+  gateway: 10.0.0.1
+  routes:
+    - "10.0.0.0/8 -> 192.168.1.1"
+    - "10.1.0.0/16 -> 192.168.1.2"
+    - "nonsense"
+office:
+  ssid: Shared
+  vpn: corp
+a: b
+b: a
+c: nowhere
+vpn:
+  corp:
+    type: openvpn
+  wg:
+    type: wireguard
+    config: |
+      [Interface]
+      PrivateKey = not-a-key
+  weird:
+    type: ipsec
+`)
+	require.NoError(t, err)
+
+	byCheck := findingsByCheck(manager.Doctor(func(cmd string) bool { return cmd != "openvpn" }))
+
+	require.Len(t, byCheck["dangling-vpn"], 1)
+	assert.Equal(t, "common", byCheck["dangling-vpn"][0].Subject)
+
+	require.Len(t, byCheck["alias-cycle"], 2)
+	require.Len(t, byCheck["alias"], 1)
+	assert.Equal(t, "alias 'c'", byCheck["alias"][0].Subject)
+
+	require.Len(t, byCheck["duplicate-ssid"], 1)
+	assert.Contains(t, byCheck["duplicate-ssid"][0].Message, "home, office")
+
+	require.Len(t, byCheck["mac"], 1)
+	require.Len(t, byCheck["gateway"], 1)
+	assert.Contains(t, byCheck["gateway"][0].Message, "not in the addr subnet 192.168.1.0/24")
+
+	require.Len(t, byCheck["routes"], 2)
+	assert.Contains(t, byCheck["routes"][0].Message, "overlap")
+	assert.Contains(t, byCheck["routes"][1].Message, "destination -> gateway")
+
+	require.Len(t, byCheck["wireguard"], 1)
+	assert.Equal(t, "vpn 'wg'", byCheck["wireguard"][0].Subject)
+
+	require.Len(t, byCheck["missing-binary"], 1)
+	assert.Contains(t, byCheck["missing-binary"][0].Message, "openvpn is not installed")
+
+	require.Len(t, byCheck["vpn-type"], 1)
+}
+
+func TestDoctor_SharedSSIDWithMatchRules(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+guest-berlin:
+  ssid: Guest
+  match:
+    bssid: "00:11:22"
+guest-paris:
+  ssid: Guest
+  match:
+    bssid: "00:11:33"
+`)
+	require.NoError(t, err)
+	assert.Empty(t, findingsByCheck(manager.Doctor(nil))["duplicate-ssid"])
+}
+
+func TestDoctor_ErrorsSortedFirst(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+a:
+  ssid: Same
+b:
+  ssid: Same
+  mac: bogus
+`)
+	require.NoError(t, err)
+
+	findings := manager.Doctor(nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, SeverityError, findings[0].Severity)
+	assert.Equal(t, SeverityWarning, findings[1].Severity)
+}
+
+func TestCheckFile_SyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home:\n  ssid: [unclosed\n"), 0600))
+
+	errs, err := CheckFile(path)
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "syntax", errs[0].Section)
+
+	_, err = CheckFile(path + ".missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,464 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a config file parsed for editing. Unlike LoadConfig it keeps
+// the YAML node tree, so comments, key order and untouched entries survive a
+// round trip through Bytes.
+type Document struct {
+	root     *yaml.Node // the document's top-level mapping
+	doc      *yaml.Node
+	original []byte
+}
+
+// ParseDocument parses a config document (or secrets.yaml, another mapping)
+// for editing. Empty data gives an empty document.
+func ParseDocument(data []byte) (*Document, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("document must be a mapping at the top level")
+	}
+	return &Document{root: doc.Content[0], doc: &doc, original: data}, nil
+}
+
+// Bytes returns the edited document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.doc); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return restoreBlankLines(d.original, buf.Bytes()), nil
+}
+
+// restoreBlankLines puts back the blank lines of original that the YAML
+// encoder drops: each line of original is looked up in out, in order, and a
+// blank line that preceded it goes back in front of it. Lines that were
+// edited aren't found and lose theirs; no line of out is ever dropped.
+func restoreBlankLines(original, out []byte) []byte {
+	if len(original) == 0 {
+		return out
+	}
+	dst := strings.Split(string(out), "\n")
+	result := make([]string, 0, len(dst))
+	next, blank := 0, false
+	for _, line := range strings.Split(string(original), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = true
+			continue
+		}
+		for k := next; k < len(dst); k++ {
+			if strings.TrimSpace(dst[k]) != line {
+				continue
+			}
+			result = append(result, dst[next:k]...)
+			if blank && len(result) > 0 && strings.TrimSpace(result[len(result)-1]) != "" {
+				result = append(result, "")
+			}
+			result = append(result, dst[k])
+			next = k + 1
+			break
+		}
+		blank = false
+	}
+	result = append(result, dst[next:]...)
+	return []byte(strings.Join(result, "\n"))
+}
+
+// lookup returns the value node of key in mapping m, or nil.
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Has reports whether the key at path exists, e.g. Has("vpn", "work").
+func (d *Document) Has(path ...string) bool {
+	node := d.root
+	for _, key := range path {
+		if node = lookup(node, key); node == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the scalar at path.
+func (d *Document) Get(path ...string) (string, bool) {
+	node := d.root
+	for _, key := range path {
+		if node = lookup(node, key); node == nil {
+			return "", false
+		}
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return node.Value, true
+}
+
+// Keys returns the keys of the mapping at path, the top level when empty, in
+// file order.
+func (d *Document) Keys(path ...string) []string {
+	node := d.root
+	for _, key := range path {
+		if node = lookup(node, key); node == nil {
+			return nil
+		}
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var keys []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// Networks returns the names of the networks (and aliases) defined in the
+// document: its top-level keys that aren't reserved.
+func (d *Document) Networks() []string {
+	var names []string
+	for _, key := range d.Keys() {
+		if !reservedKeys[key] {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// parent returns the mapping holding the last key of path and the index of
+// that key in its Content, or -1 when it doesn't exist.
+func (d *Document) parent(path []string) (*yaml.Node, int) {
+	node := d.root
+	for _, key := range path[:len(path)-1] {
+		if node = lookup(node, key); node == nil {
+			return nil, -1
+		}
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[len(path)-1] {
+			return node, i
+		}
+	}
+	return node, -1
+}
+
+// Delete removes the key at path with its value and comments.
+func (d *Document) Delete(path ...string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("empty path")
+	}
+	m, i := d.parent(path)
+	if i < 0 {
+		return fmt.Errorf("'%s' not found", strings.Join(path, "."))
+	}
+	m.Content = append(m.Content[:i], m.Content[i+2:]...)
+	return nil
+}
+
+// Rename renames the key at path to name, keeping its place, value and
+// comments.
+func (d *Document) Rename(name string, path ...string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("empty path")
+	}
+	m, i := d.parent(path)
+	if i < 0 {
+		return fmt.Errorf("'%s' not found", strings.Join(path, "."))
+	}
+	if lookup(m, name) != nil {
+		return fmt.Errorf("'%s' already exists", name)
+	}
+	m.Content[i].Value = name
+	return nil
+}
+
+// SetList sets the key at path to a list of strings, written in flow style
+// ([1.1.1.1, 9.9.9.9]).
+func (d *Document) SetList(values []string, path ...string) error {
+	value, err := d.slot(path)
+	if err != nil {
+		return err
+	}
+	seq := yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle, LineComment: value.LineComment}
+	for _, v := range values {
+		seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+	}
+	*value = seq
+	return nil
+}
+
+// SetField sets the config field at path from a command-line value, typed by
+// the config schema: list fields (dns, routes, match rules) take
+// comma-separated values, string fields (ssid, psk, hostname) stay strings
+// whatever they look like, and numbers and booleans are written as such.
+// A path of one key sets an alias ("work: office").
+func (d *Document) SetField(value string, path ...string) error {
+	if len(path) == 1 {
+		if reservedKeys[path[0]] {
+			return fmt.Errorf("'%s' is a section, set one of its fields", path[0])
+		}
+		return d.SetString(value, path...)
+	}
+	t := fieldType(path)
+	switch {
+	case t == nil:
+		// Unknown field: validation reports it.
+		return d.Set(value, path...)
+	case t.Kind() == reflect.String:
+		return d.SetString(value, path...)
+	case t.Kind() == reflect.Slice:
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return d.SetList(values, path...)
+	case t.Kind() == reflect.Struct || t.Kind() == reflect.Map:
+		return fmt.Errorf("'%s' is a section, set one of its fields", strings.Join(path, "."))
+	}
+	return d.Set(value, path...)
+}
+
+// fieldType returns the Go type of the config field at path, or nil when
+// there's no such field.
+func fieldType(path []string) reflect.Type {
+	var t reflect.Type
+	switch path[0] {
+	case "common":
+		t, path = reflect.TypeOf(types.CommonConfig{}), path[1:]
+	case "ignored":
+		t, path = reflect.TypeOf(types.IgnoredConfig{}), path[1:]
+	case "vpn":
+		if len(path) < 2 {
+			return nil
+		}
+		t, path = reflect.TypeOf(types.VPNConfig{}), path[2:]
+	case "hotspot":
+		if len(path) < 2 {
+			return nil
+		}
+		t, path = reflect.TypeOf(types.HotspotConfig{}), path[2:]
+	default:
+		t, path = reflect.TypeOf(types.NetworkConfig{}), path[1:]
+	}
+	for _, key := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			field, ok := structField(t, key)
+			if !ok {
+				return nil
+			}
+			t = field
+		default:
+			return nil
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// structField returns the type of the field of struct t with YAML key key.
+func structField(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); name == key {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the scalar at path to value, creating the mappings on the way;
+// new keys go at the end of their mapping. The value is typed as YAML reads
+// it (10 is a number, true a boolean). A scalar that already exists keeps
+// its comments.
+func (d *Document) Set(value string, path ...string) error {
+	return d.set(value, "", path)
+}
+
+// SetString is Set for values that must stay strings, such as passwords
+// ("12345678") and SSIDs ("yes"): they are quoted when needed.
+func (d *Document) SetString(value string, path ...string) error {
+	return d.set(value, "!!str", path)
+}
+
+func (d *Document) set(value, tag string, path []string) error {
+	node, err := d.slot(path)
+	if err != nil {
+		return err
+	}
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("%s is not a single value", strings.Join(path, "."))
+	}
+	node.Value, node.Tag, node.Style = value, tag, 0
+	return nil
+}
+
+// slot returns the value node of the key at path, creating the key (with a
+// null value) and the mappings on the way; new keys go at the end of their
+// mapping.
+func (d *Document) slot(path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	node := d.root
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a mapping", strings.Join(path[:i], "."))
+		}
+		next := lookup(node, key)
+		last := i == len(path)-1
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				next = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		} else if !last && next.Kind == yaml.ScalarNode && next.Tag == "!!null" {
+			// "vpn:" with nothing under it yet.
+			next.Kind, next.Tag, next.Value = yaml.MappingNode, "!!map", ""
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// networkNameChars are what a derived network name keeps.
+var networkNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// NetworkName derives a config key from an SSID: lowercased, other
+// characters turned into dashes ("Cafe Central!" gives "cafe-central").
+func NetworkName(ssid string) string {
+	name := strings.Trim(networkNameChars.ReplaceAllString(strings.ToLower(ssid), "-"), "-")
+	if name == "" || reservedKeys[name] {
+		name = "wifi-" + name
+	}
+	return strings.TrimSuffix(name, "-")
+}
+
+// CheckNetworkName reports why name can't be a new network of the document:
+// empty, a reserved top-level key, or already in use.
+func (d *Document) CheckNetworkName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("network name is empty")
+	case reservedKeys[name]:
+		return fmt.Errorf("'%s' is a reserved key, choose another network name", name)
+	case d.Has(name):
+		return fmt.Errorf("'%s' already exists in the config", name)
+	}
+	return nil
+}
+
+// AliasesOf returns the aliases ("work: office") pointing at network name.
+func (d *Document) AliasesOf(name string) []string {
+	var aliases []string
+	for _, key := range d.Networks() {
+		if target, ok := d.Get(key); ok && target == name {
+			aliases = append(aliases, key)
+		}
+	}
+	return aliases
+}
+
+// VPNReferences returns the paths of the vpn: keys using VPN name: in
+// networks, common and the common trust policies.
+func (d *Document) VPNReferences(name string) [][]string {
+	var paths [][]string
+	check := func(path ...string) {
+		if value, ok := d.Get(append(path, "vpn")...); ok && value == name {
+			paths = append(paths, append(path, "vpn"))
+		}
+	}
+	check("common")
+	for _, level := range d.Keys("common", "trust") {
+		check("common", "trust", level)
+	}
+	for _, network := range d.Networks() {
+		check(network)
+	}
+	return paths
+}
+
+// RenameNetwork renames network old to name and repoints its aliases.
+func (d *Document) RenameNetwork(old, name string) error {
+	if reservedKeys[old] {
+		return fmt.Errorf("'%s' is not a network", old)
+	}
+	if err := d.CheckNetworkName(name); err != nil {
+		return err
+	}
+	aliases := d.AliasesOf(old)
+	if err := d.Rename(name, old); err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		if err := d.SetString(name, alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameVPN renames VPN old to name and repoints the vpn: keys using it.
+func (d *Document) RenameVPN(old, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("VPN name is empty")
+	}
+	users := d.VPNReferences(old)
+	if err := d.Rename(name, "vpn", old); err != nil {
+		return err
+	}
+	for _, path := range users {
+		if err := d.SetString(name, path...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateConfigData validates a config document (already decrypted) for
+// unknown fields and invalid values, like ValidateConfigFile.
+func ValidateConfigData(data []byte) ValidationErrors {
+	return validateConfigData(data)
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument_KeepsCommentsAndOrder(t *testing.T) {
+	doc, err := ParseDocument([]byte(`# networks
+common:
+  dns: [1.1.1.1] # cloudflare
+vpn:
+home:
+  ssid: Home
+`))
+	require.NoError(t, err)
+
+	require.NoError(t, doc.SetString("Cafe", "cafe", "ssid"))
+	require.NoError(t, doc.SetString("12345678", "cafe", "psk"))
+	require.NoError(t, doc.Set("true", "cafe", "hidden"))
+	require.NoError(t, doc.Set("wg0", "vpn", "work", "interface"))
+	require.NoError(t, doc.SetString("Home 2", "home", "ssid"))
+
+	data, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, `# networks
+common:
+  dns: [1.1.1.1] # cloudflare
+vpn:
+  work:
+    interface: wg0
+home:
+  ssid: Home 2
+cafe:
+  ssid: Cafe
+  psk: "12345678"
+  hidden: true
+`, string(data))
+	assert.True(t, doc.Has("vpn", "work"))
+	assert.False(t, doc.Has("office"))
+}
+
+func TestDocument_Errors(t *testing.T) {
+	_, err := ParseDocument([]byte("- a\n- b\n"))
+	assert.ErrorContains(t, err, "must be a mapping")
+	_, err = ParseDocument([]byte("a: [\n"))
+	assert.ErrorContains(t, err, "failed to parse YAML")
+
+	doc, err := ParseDocument(nil)
+	require.NoError(t, err)
+	require.NoError(t, doc.Set("Home", "home", "ssid"))
+	assert.EqualError(t, doc.Set("x", "home"), "home is not a single value")
+	assert.EqualError(t, doc.Set("x", "home", "ssid", "y"), "home.ssid is not a mapping")
+}
+
+func TestNetworkName(t *testing.T) {
+	assert.Equal(t, "cafe-central", NetworkName("Cafe Central!"))
+	assert.Equal(t, "my_wifi-5g", NetworkName("My_WiFi (5G)"))
+	assert.Equal(t, "wifi-common", NetworkName("common"))
+	assert.Equal(t, "wifi", NetworkName("☕"))
+}
+
+func TestDocument_CheckNetworkName(t *testing.T) {
+	doc, err := ParseDocument([]byte("home:\n  ssid: Home\n"))
+	require.NoError(t, err)
+	assert.NoError(t, doc.CheckNetworkName("cafe"))
+	assert.EqualError(t, doc.CheckNetworkName("home"), "'home' already exists in the config")
+	assert.EqualError(t, doc.CheckNetworkName("vpn"), "'vpn' is a reserved key, choose another network name")
+	assert.EqualError(t, doc.CheckNetworkName(" "), "network name is empty")
+}
+
+func TestDocument_KeepsBlankLines(t *testing.T) {
+	in := "common:\n  dns: [1.1.1.1]\n\n# home\nhome:\n  ssid: Home\n\noffice:\n  ssid: Office\n"
+	doc, err := ParseDocument([]byte(in))
+	require.NoError(t, err)
+	data, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, in, string(data))
+
+	require.NoError(t, doc.Delete("home"))
+	data, err = doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, "common:\n  dns: [1.1.1.1]\n\noffice:\n  ssid: Office\n", string(data))
+}
+
+func TestDocument_SetField(t *testing.T) {
+	doc, err := ParseDocument([]byte("home:\n  ssid: Home\n  dns: [1.1.1.1] # isp\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, doc.SetField("9.9.9.9,149.112.112.112", "home", "dns"))
+	require.NoError(t, doc.SetField("10", "home", "metric"))
+	require.NoError(t, doc.SetField("007", "home", "hostname"))
+	require.NoError(t, doc.SetField("00:11:22", "home", "match", "bssid"))
+	require.NoError(t, doc.SetField("5", "home", "roam", "prefer_band"))
+	require.NoError(t, doc.SetField("true", "vpn", "work", "gateway"))
+	assert.EqualError(t, doc.SetField("x", "common"), "'common' is a section, set one of its fields")
+	assert.EqualError(t, doc.SetField("x", "home", "match"), "'home.match' is a section, set one of its fields")
+
+	data, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, `home:
+  ssid: Home
+  dns: [9.9.9.9, 149.112.112.112] # isp
+  metric: 10
+  hostname: "007"
+  match:
+    bssid: ['00:11:22']
+  roam:
+    prefer_band: "5"
+vpn:
+  work:
+    gateway: true
+`, string(data))
+}
+
+func TestDocument_RenameKeepsReferences(t *testing.T) {
+	doc, err := ParseDocument([]byte(`common:
+  vpn: work
+  trust:
+    public:
+      vpn: work
+vpn:
+  work:
+    type: wireguard
+home:
+  ssid: Home
+  vpn: work
+house: home
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"house"}, doc.AliasesOf("home"))
+	assert.Equal(t, [][]string{{"common", "vpn"}, {"common", "trust", "public", "vpn"}, {"home", "vpn"}}, doc.VPNReferences("work"))
+
+	require.NoError(t, doc.RenameNetwork("home", "flat"))
+	require.NoError(t, doc.RenameVPN("work", "office"))
+	assert.Equal(t, []string{"flat", "house"}, doc.Networks())
+	v, _ := doc.Get("house")
+	assert.Equal(t, "flat", v)
+	assert.Len(t, doc.VPNReferences("office"), 3)
+	assert.Empty(t, doc.VPNReferences("work"))
+	assert.EqualError(t, doc.RenameVPN("play", "x"), "'vpn.play' not found")
+}
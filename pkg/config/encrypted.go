@@ -0,0 +1,241 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/angelfreak/net/pkg/secrets"
+	"github.com/angelfreak/net/pkg/system"
+)
+
+// EncryptedSuffix marks an age-encrypted config file (config.yaml.age).
+const EncryptedSuffix = ".age"
+
+// KeyFileEnv names an age identity file used to decrypt the config. When it
+// is unset, key.txt next to the config file is used if present.
+const KeyFileEnv = "NET_CONFIG_KEY_FILE"
+
+// keyringDescription is the kernel keyring "user" key consulted when there is
+// no identity file. It may hold an age identity or a passphrase.
+const keyringDescription = "net-config"
+
+const (
+	ageHeader   = "age-encryption.org/v1"
+	armorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+// PassphraseFunc prompts for the passphrase of an encrypted config.
+type PassphraseFunc func(prompt string) (string, error)
+
+// promptPassphrase is the default PassphraseFunc: a no-echo terminal prompt.
+func promptPassphrase(prompt string) (string, error) {
+	return system.ReadPassword(prompt)
+}
+
+// ConfigKey is the key an encrypted config was opened with. It is kept so
+// `net config edit` can re-encrypt for the same recipients in the same format.
+type ConfigKey struct {
+	identities []age.Identity
+	recipients []age.Recipient
+	armored    bool
+	// Source describes where the key came from, for messages ("key file
+	// /home/u/.net/key.txt", "kernel keyring", "passphrase").
+	Source string
+}
+
+// IsEncrypted reports whether data is an age-encrypted file (binary or
+// ASCII-armored).
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageHeader)) || bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(armorHeader))
+}
+
+// DecryptConfig decrypts an encrypted config read from path entirely in
+// memory. Key sources are tried in order: the identity file ($NET_CONFIG_KEY_FILE
+// or key.txt next to the config), the "net-config" kernel keyring key, and
+// finally a passphrase prompt (skipped when prompt is nil).
+func DecryptConfig(path string, data []byte, prompt PassphraseFunc) ([]byte, *ConfigKey, error) {
+	armored := !bytes.HasPrefix(data, []byte(ageHeader))
+	var tried []string
+
+	for _, source := range []func() (*ConfigKey, error){
+		func() (*ConfigKey, error) { return keyFromFile(path) },
+		keyFromKeyring,
+	} {
+		key, err := source()
+		if err != nil {
+			return nil, nil, err
+		}
+		if key == nil {
+			continue
+		}
+		plaintext, err := decrypt(data, armored, key.identities)
+		if err == nil {
+			key.armored = armored
+			return plaintext, key, nil
+		}
+		var noMatch *age.NoIdentityMatchError
+		if !errors.As(err, &noMatch) {
+			return nil, nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		tried = append(tried, key.Source)
+	}
+
+	if prompt == nil {
+		return nil, nil, noKeyError(path, tried)
+	}
+	passphrase, err := prompt(fmt.Sprintf("Passphrase for %s: ", path))
+	if errors.Is(err, system.ErrNoTerminal) {
+		return nil, nil, noKeyError(path, tried)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := keyFromPassphrase(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := decrypt(data, armored, key.identities)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, nil, fmt.Errorf("failed to decrypt %s: incorrect passphrase", path)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	key.armored = armored
+	return plaintext, key, nil
+}
+
+// EncryptConfig encrypts plaintext for the recipients of key, in the same
+// (binary or armored) format the config was read in.
+func EncryptConfig(plaintext []byte, key *ConfigKey) ([]byte, error) {
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	var armorWriter io.WriteCloser
+	if key.armored {
+		armorWriter = armor.NewWriter(&buf)
+		out = armorWriter
+	}
+	w, err := age.Encrypt(out, key.recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt config: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt config: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt config: %w", err)
+	}
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt config: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decrypt(data []byte, armored bool, identities []age.Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	if armored {
+		src = armor.NewReader(src)
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func noKeyError(path string, tried []string) error {
+	msg := fmt.Sprintf("%s is encrypted and no key could decrypt it", path)
+	if len(tried) > 0 {
+		msg += " (tried " + strings.Join(tried, ", ") + ")"
+	}
+	return fmt.Errorf("%s; set %s to an age identity file, add a %q key to the keyring, or run from a terminal to enter the passphrase", msg, KeyFileEnv, keyringDescription)
+}
+
+// keyFromFile loads age identities from $NET_CONFIG_KEY_FILE, or key.txt next
+// to the config. Returns nil (no error) when neither is present.
+func keyFromFile(configPath string) (*ConfigKey, error) {
+	path := os.Getenv(KeyFileEnv)
+	explicit := path != ""
+	if !explicit {
+		path = filepath.Join(filepath.Dir(configPath), "key.txt")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open config key file: %w", err)
+	}
+	defer f.Close()
+	identities, err := age.ParseIdentities(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config key file %s: %w", path, err)
+	}
+	return identityKey(identities, "key file "+path)
+}
+
+// keyFromKeyring loads the "net-config" user key from the kernel keyring.
+// Returns nil (no error) when the key is absent or keyrings are unsupported.
+func keyFromKeyring() (*ConfigKey, error) {
+	value, err := (&secrets.Resolver{}).Resolve(secrets.PrefixKeyring + keyringDescription)
+	if err != nil {
+		return nil, nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(value), "AGE-SECRET-KEY-") {
+		identities, err := age.ParseIdentities(strings.NewReader(value))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config key from keyring: %w", err)
+		}
+		return identityKey(identities, "kernel keyring")
+	}
+	key, err := keyFromPassphrase(value)
+	if err != nil {
+		return nil, err
+	}
+	key.Source = "kernel keyring"
+	return key, nil
+}
+
+// identityKey builds a ConfigKey from X25519 identities; each identity's
+// recipient is used for re-encryption.
+func identityKey(identities []age.Identity, source string) (*ConfigKey, error) {
+	key := &ConfigKey{identities: identities, Source: source}
+	for _, id := range identities {
+		x, ok := id.(*age.X25519Identity)
+		if !ok {
+			return nil, fmt.Errorf("unsupported identity type in %s", source)
+		}
+		key.recipients = append(key.recipients, x.Recipient())
+	}
+	return key, nil
+}
+
+func keyFromPassphrase(passphrase string) (*ConfigKey, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigKey{
+		identities: []age.Identity{identity},
+		recipients: []age.Recipient{recipient},
+		Source:     "passphrase",
+	}, nil
+}
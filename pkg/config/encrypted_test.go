@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const encryptedTestConfig = "home:\n  ssid: HomeWiFi\n  psk: hunter2hunter2\n"
+
+// writeEncrypted encrypts content for recipient and writes it to dir/config.yaml.age.
+func writeEncrypted(t *testing.T, dir, content string, recipient age.Recipient) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	path := filepath.Join(dir, "config.yaml.age")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+	return path
+}
+
+func TestLoadConfig_EncryptedWithKeyFile(t *testing.T) {
+	t.Setenv(KeyFileEnv, "")
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.txt"), []byte(identity.String()+"\n"), 0600))
+	writeEncrypted(t, dir, encryptedTestConfig, identity.Recipient())
+
+	manager := NewManager(&mockLogger{})
+	manager.SetPassphrasePrompt(nil)
+	// The plain path falls back to config.yaml.age when it doesn't exist.
+	cfg, err := manager.LoadConfig(filepath.Join(dir, "config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "HomeWiFi", cfg.Networks["home"].SSID)
+}
+
+func TestLoadConfig_EncryptedWithPassphrase(t *testing.T) {
+	t.Setenv(KeyFileEnv, "")
+	dir := t.TempDir()
+	recipient, err := age.NewScryptRecipient("correct horse")
+	require.NoError(t, err)
+	recipient.SetWorkFactor(10) // keep the test fast
+	path := writeEncrypted(t, dir, encryptedTestConfig, recipient)
+
+	var prompts int
+	manager := NewManager(&mockLogger{})
+	manager.SetPassphrasePrompt(func(string) (string, error) {
+		prompts++
+		return "correct horse", nil
+	})
+	cfg, err := manager.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2hunter2", cfg.Networks["home"].PSK)
+	assert.Equal(t, 1, prompts, "config must only be decrypted once per load")
+
+	manager.SetPassphrasePrompt(func(string) (string, error) { return "wrong", nil })
+	_, err = manager.LoadConfig(path)
+	assert.ErrorContains(t, err, "incorrect passphrase")
+}
+
+func TestLoadConfig_EncryptedWithoutKey(t *testing.T) {
+	t.Setenv(KeyFileEnv, "")
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	path := writeEncrypted(t, t.TempDir(), encryptedTestConfig, identity.Recipient())
+
+	manager := NewManager(&mockLogger{})
+	manager.SetPassphrasePrompt(nil)
+	_, err = manager.LoadConfig(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is encrypted")
+	assert.Contains(t, err.Error(), KeyFileEnv)
+}
+
+func TestDecryptConfig_PromptWithoutTerminal(t *testing.T) {
+	t.Setenv(KeyFileEnv, "")
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	path := writeEncrypted(t, t.TempDir(), encryptedTestConfig, identity.Recipient())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, _, err = DecryptConfig(path, data, func(string) (string, error) {
+		return "", errors.New("boom")
+	})
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestEncryptConfig_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "other-key.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0600))
+	t.Setenv(KeyFileEnv, keyFile)
+	path := writeEncrypted(t, dir, encryptedTestConfig, identity.Recipient())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	plaintext, key, err := DecryptConfig(path, data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, encryptedTestConfig, string(plaintext))
+	assert.Equal(t, "key file "+keyFile, key.Source)
+
+	reencrypted, err := EncryptConfig([]byte("edited: true\n"), key)
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(reencrypted))
+	plaintext, _, err = DecryptConfig(path, reencrypted, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "edited: true\n", string(plaintext))
+}
+
+func TestIsEncrypted(t *testing.T) {
+	assert.True(t, IsEncrypted([]byte("age-encryption.org/v1\n-> X25519 abc\n")))
+	assert.True(t, IsEncrypted([]byte("-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n")))
+	assert.False(t, IsEncrypted([]byte("home:\n  ssid: x\n")))
+}
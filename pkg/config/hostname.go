@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// normalizeHostnameMode lowercases a hostname_mode: value.
+func normalizeHostnameMode(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+// validateHostnameMode checks a hostname_mode: value of common or a network.
+func validateHostnameMode(section string, value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	if s, ok := value.(string); ok {
+		mode := normalizeHostnameMode(s)
+		if mode == "" {
+			return nil
+		}
+		for _, m := range types.HostnameModes {
+			if mode == m {
+				return nil
+			}
+		}
+	}
+	return []ValidationError{{Section: section, Field: "hostname_mode",
+		Message: fmt.Sprintf("%s.hostname_mode must be one of %s", section, strings.Join(types.HostnameModes, ", "))}}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeWithCommon_HostnameMode(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+common:
+  hostname_mode: dhcp-only
+cafe:
+  ssid: Cafe
+  hostname_mode: Anonymous
+home:
+  ssid: Home
+`)
+	require.NoError(t, err)
+
+	for name, want := range map[string]string{"cafe": types.HostnameModeAnonymous, "home": types.HostnameModeDHCPOnly} {
+		nc, err := manager.GetNetworkConfig(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, manager.MergeWithCommon(name, nc).HostnameMode, name)
+	}
+}
+
+func TestValidateHostnameMode(t *testing.T) {
+	_, err := loadConfigInto(t, NewManager(&mockLogger{}), "cafe:\n  ssid: Cafe\n  hostname_mode: hidden\n")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hostname_mode must be one of dhcp-only, system, anonymous")
+
+	_, err = loadConfigInto(t, NewManager(&mockLogger{}), "common:\n  hostname_mode: system\n")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// systemConfigDir holds system-wide drop-in configs. A variable so tests can
+// point it at a temp directory.
+var systemConfigDir = "/etc/net/config.d"
+
+// dropInDirName is the per-user drop-in directory, next to the main config
+// (~/.net/config.d for the default config).
+const dropInDirName = "config.d"
+
+// configSource is one file contributing to the merged config.
+type configSource struct {
+	path string
+	raw  map[string]interface{}
+}
+
+// sourcePaths lists the files that make up the config for mainPath, in merge
+// order: system drop-ins (when systemDirs is set), user drop-ins (each sorted
+// by name), then files named by the main config's include: directive, then
+// the main config itself. mainRaw is the already-parsed main config, or nil
+// if it doesn't exist.
+func sourcePaths(mainPath string, mainRaw map[string]interface{}, systemDirs bool) ([]string, error) {
+	var paths []string
+	for _, dir := range dropInDirs(mainPath, systemDirs) {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	if mainRaw != nil {
+		includes, err := includePatterns(mainRaw["include"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", mainPath, err)
+		}
+		for _, pattern := range includes {
+			matches, err := expandInclude(filepath.Dir(mainPath), pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", mainPath, err)
+			}
+			paths = append(paths, matches...)
+		}
+		paths = append(paths, mainPath)
+	}
+
+	// A file reachable twice (e.g. an include pointing into config.d) is
+	// only loaded at its first position.
+	seen := make(map[string]bool)
+	unique := paths[:0]
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			abs = p
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		unique = append(unique, p)
+	}
+	return unique, nil
+}
+
+// dropInDirs returns the drop-in directories of the config at mainPath.
+func dropInDirs(mainPath string, systemDirs bool) []string {
+	user := filepath.Join(filepath.Dir(mainPath), dropInDirName)
+	if !systemDirs {
+		return []string{user}
+	}
+	return []string{systemConfigDir, user}
+}
+
+// samePath reports whether a and b name the same file.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// includePatterns normalises the include: value (a string or a list of
+// strings) into a list of patterns.
+func includePatterns(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		patterns := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include entries must be strings")
+			}
+			patterns = append(patterns, s)
+		}
+		return patterns, nil
+	default:
+		return nil, fmt.Errorf("include must be a path or a list of paths")
+	}
+}
+
+// expandInclude resolves an include pattern relative to baseDir (with ~
+// expansion). Glob patterns may match nothing; a plain path must exist.
+func expandInclude(baseDir, pattern string) ([]string, error) {
+	if strings.HasPrefix(pattern, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		pattern = filepath.Join(home, pattern[1:])
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("include %s: %w", pattern, err)
+		}
+		return []string{pattern}, nil
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// mergeSources merges config files in order. Networks, VPNs and hotspot
+// profiles are keyed by (case-insensitive) name, common settings by field,
+// and ignored interfaces are concatenated. A common field set in several
+// files takes the value from the last one, so later files override shared
+// defaults. Defining the same network, VPN or hotspot profile in two files
+// is a conflict naming both files. Returns the merged document and the file
+// that defined each network ("<name>"), VPN ("vpn.<name>") and hotspot
+// profile ("hotspot.<name>").
+func mergeSources(sources []configSource) (map[string]interface{}, map[string]string, ValidationErrors) {
+	merged := make(map[string]interface{})
+	origin := make(map[string]string) // every claimed key -> defining file
+	provenance := make(map[string]string)
+	var conflicts ValidationErrors
+
+	claim := func(key, what, path string) bool {
+		if prev, ok := origin[key]; ok {
+			conflicts = append(conflicts, ValidationError{
+				File: path, Section: what, Field: key,
+				Message: fmt.Sprintf("%s is defined in both %s and %s", what, prev, path),
+			})
+			return false
+		}
+		origin[key] = path
+		return true
+	}
+
+	for _, src := range sources {
+		for _, key := range sortedKeys(src.raw) {
+			value := src.raw[key]
+			switch key {
+			case "include":
+				// Already expanded into sources.
+			case "common":
+				fields, _ := value.(map[string]interface{})
+				common, _ := merged["common"].(map[string]interface{})
+				if common == nil {
+					common = make(map[string]interface{})
+					merged["common"] = common
+				}
+				for _, field := range sortedKeys(fields) {
+					common[field] = fields[field]
+				}
+			case "ignored":
+				fields, _ := value.(map[string]interface{})
+				ignored, _ := merged["ignored"].(map[string]interface{})
+				if ignored == nil {
+					ignored = make(map[string]interface{})
+					merged["ignored"] = ignored
+				}
+				for field, v := range fields {
+					list, _ := v.([]interface{})
+					existing, _ := ignored[field].([]interface{})
+					ignored[field] = append(existing, list...)
+				}
+			case "vpn":
+				vpns, _ := value.(map[string]interface{})
+				all, _ := merged["vpn"].(map[string]interface{})
+				if all == nil {
+					all = make(map[string]interface{})
+					merged["vpn"] = all
+				}
+				for _, name := range sortedKeys(vpns) {
+					v := vpns[name]
+					if claim("vpn."+strings.ToLower(name), fmt.Sprintf("VPN '%s'", name), src.path) {
+						all[name] = v
+						provenance["vpn."+strings.ToLower(name)] = src.path
+					}
+				}
+			case "hotspot":
+				profiles, _ := value.(map[string]interface{})
+				all, _ := merged["hotspot"].(map[string]interface{})
+				if all == nil {
+					all = make(map[string]interface{})
+					merged["hotspot"] = all
+				}
+				for _, name := range sortedKeys(profiles) {
+					v := profiles[name]
+					if claim("hotspot."+strings.ToLower(name), fmt.Sprintf("hotspot profile '%s'", name), src.path) {
+						all[name] = v
+						provenance["hotspot."+strings.ToLower(name)] = src.path
+					}
+				}
+			default:
+				if claim(strings.ToLower(key), fmt.Sprintf("network '%s'", key), src.path) {
+					merged[key] = value
+					provenance[strings.ToLower(key)] = src.path
+				}
+			}
+		}
+	}
+	return merged, provenance, conflicts
+}
+
+// sortedKeys returns m's keys in order, so merging and conflict reports are
+// deterministic.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readSource reads and parses one config file for merging, validating it on
+// its own so errors name the file they came from. Included files and drop-ins
+// may not include further files.
+func (m *Manager) readSource(path string, isMain bool) (configSource, ValidationErrors, error) {
+	data, err := readConfigData(path, m.passphrasePrompt())
+	if err != nil {
+		return configSource{}, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return parseSource(path, data, isMain)
+}
+
+// parseSource parses and validates data, the content of the config file at
+// path, for merging.
+func parseSource(path string, data []byte, isMain bool) (configSource, ValidationErrors, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return configSource{}, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	errs := validateConfigData(data)
+	if !isMain {
+		for i := range errs {
+			errs[i].File = path
+		}
+		if _, ok := raw["include"]; ok {
+			errs = append(errs, ValidationError{
+				File: path, Section: "include", Field: "include",
+				Message: "include is only allowed in the main config file",
+			})
+		}
+	}
+	return configSource{path: path, raw: raw}, errs, nil
+}
+
+// marshalMerged serialises a merged config for viper.
+func marshalMerged(merged map[string]interface{}) ([]byte, error) {
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge config files: %w", err)
+	}
+	return out, nil
+}
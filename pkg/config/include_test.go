@@ -0,0 +1,218 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// includeFixture creates a config directory layout under a temp HOME, so its
+// config.yaml is the default config, and points the system drop-in
+// directory at a temp dir for the duration of the test.
+type includeFixture struct {
+	t         *testing.T
+	dir       string // directory of the main config
+	systemDir string
+}
+
+func newIncludeFixture(t *testing.T) *includeFixture {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SUDO_USER", "")
+	f := &includeFixture{t: t, dir: filepath.Join(home, ".net"), systemDir: t.TempDir()}
+	require.NoError(t, os.MkdirAll(f.dir, 0700))
+	orig := systemConfigDir
+	systemConfigDir = f.systemDir
+	t.Cleanup(func() { systemConfigDir = orig })
+	return f
+}
+
+func (f *includeFixture) write(path, content string) string {
+	f.t.Helper()
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.dir, path)
+	}
+	require.NoError(f.t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(f.t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func (f *includeFixture) load() (*Manager, error) {
+	manager := NewManager(&mockLogger{})
+	_, err := manager.LoadConfig(filepath.Join(f.dir, "config.yaml"))
+	return manager, err
+}
+
+func TestLoadConfig_DropInsAndIncludes(t *testing.T) {
+	f := newIncludeFixture(t)
+	system := f.write(filepath.Join(f.systemDir, "10-office.yaml"), "office:\n  ssid: OfficeWiFi\n  vpn: corp\nvpn:\n  corp:\n    type: wireguard\n")
+	user := f.write("config.d/personal.yaml", "cafe:\n  ssid: Cafe\nignored:\n  interfaces: [docker0]\n")
+	team := f.write("team/lab.yaml", "lab:\n  ssid: LabWiFi\n")
+	main := f.write("config.yaml", "include: team/*.yaml\ncommon:\n  dns: [1.1.1.1]\nhome:\n  ssid: HomeWiFi\nignored:\n  interfaces: [virbr0]\n")
+
+	manager, err := f.load()
+	require.NoError(t, err)
+	cfg := manager.GetConfig()
+
+	for _, name := range []string{"office", "cafe", "lab", "home"} {
+		_, err := manager.GetNetworkConfig(name)
+		assert.NoError(t, err, name)
+	}
+	_, err = manager.GetVPNConfig("corp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.1.1.1"}, cfg.Common.DNS)
+	assert.Equal(t, []string{"docker0", "virbr0"}, cfg.Ignored.Interfaces, "ignored interfaces concatenate in merge order")
+
+	assert.Equal(t, system, cfg.Sources["office"])
+	assert.Equal(t, system, cfg.Sources["vpn.corp"])
+	assert.Equal(t, user, cfg.Sources["cafe"])
+	assert.Equal(t, team, cfg.Sources["lab"])
+	assert.Equal(t, main, cfg.Sources["home"])
+	assert.Equal(t, []string{system, user, team, main}, manager.SourceFiles())
+}
+
+func TestLoadConfig_ExplicitPathSkipsSystemDropIns(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write(filepath.Join(f.systemDir, "10-office.yaml"), "office:\n  ssid: OfficeWiFi\n")
+	user := f.write("other/config.d/cafe.yaml", "cafe:\n  ssid: Cafe\n")
+	main := f.write("other/config.yaml", "home:\n  ssid: HomeWiFi\n")
+
+	manager := NewManager(&mockLogger{})
+	_, err := manager.LoadConfig(main)
+	require.NoError(t, err)
+	_, err = manager.GetNetworkConfig("office")
+	assert.Error(t, err, "system drop-ins only belong to the default config")
+	assert.Equal(t, []string{user, main}, manager.SourceFiles())
+}
+
+func TestLoadConfigData(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("team/lab.yaml", "lab:\n  ssid: LabWiFi\n")
+	main := f.write("config.yaml", "home:\n  ssid: HomeWiFi\n")
+
+	// The buffer stands in for the file, with includes relative to it
+	manager := NewManager(&mockLogger{})
+	_, err := manager.LoadConfigData(main, []byte("include: team/*.yaml\ncafe:\n  ssid: Cafe\n"))
+	require.NoError(t, err)
+	for _, name := range []string{"lab", "cafe"} {
+		_, err := manager.GetNetworkConfig(name)
+		assert.NoError(t, err, name)
+	}
+	_, err = manager.GetNetworkConfig("home")
+	assert.Error(t, err, "the file on disk is not read")
+
+	_, err = NewManager(&mockLogger{}).LoadConfigData(main, []byte("include: team/lab.yaml\nlab:\n  ssid: Other\n"))
+	assert.ErrorContains(t, err, "network 'lab' is defined in both")
+}
+
+func TestLoadConfig_DropInsWithoutMainConfig(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.d/office.yaml", "office:\n  ssid: OfficeWiFi\n")
+
+	manager, err := f.load()
+	require.NoError(t, err)
+	_, err = manager.GetNetworkConfig("office")
+	assert.NoError(t, err)
+}
+
+func TestLoadConfig_DuplicateNetworkNamesBothFiles(t *testing.T) {
+	f := newIncludeFixture(t)
+	dropIn := f.write("config.d/office.yaml", "Office:\n  ssid: OfficeWiFi\n")
+	main := f.write("config.yaml", "office:\n  ssid: Other\n")
+
+	_, err := f.load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), dropIn)
+	assert.Contains(t, err.Error(), main)
+	assert.Contains(t, err.Error(), "network 'office' is defined in both")
+}
+
+func TestLoadConfig_DuplicateVPN(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.d/a.yaml", "common:\n  dns: [9.9.9.9]\nvpn:\n  corp:\n    type: openvpn\n")
+	f.write("config.yaml", "common:\n  dns: [1.1.1.1]\n  hostname: laptop\nvpn:\n  corp:\n    type: wireguard\n")
+
+	_, err := f.load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VPN 'corp' is defined in both")
+	assert.NotContains(t, err.Error(), "common.")
+}
+
+func TestLoadConfig_CommonOverridesInMergeOrder(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.d/a.yaml", "common:\n  dns: [9.9.9.9]\n  hostname: shared\n  mac: random\n")
+	f.write("config.d/b.yaml", "common:\n  hostname: team\n")
+	f.write("config.yaml", "common:\n  dns: [1.1.1.1]\n")
+
+	manager, err := f.load()
+	require.NoError(t, err)
+	common := manager.GetConfig().Common
+	assert.Equal(t, []string{"1.1.1.1"}, common.DNS, "main config overrides drop-in")
+	assert.Equal(t, "team", common.Hostname, "later drop-in overrides earlier one")
+	assert.Equal(t, "random", common.MAC, "unset fields keep the drop-in default")
+}
+
+func TestLoadConfig_DuplicateHotspotProfile(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.d/a.yaml", "hotspot:\n  car:\n    ssid: Car\n  demo:\n    ssid: Demo\n")
+	f.write("config.yaml", "hotspot:\n  Car:\n    ssid: Other\n")
+
+	_, err := f.load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hotspot profile 'Car' is defined in both")
+
+	f = newIncludeFixture(t)
+	dropIn := f.write("config.d/a.yaml", "hotspot:\n  demo:\n    ssid: Demo\n")
+	f.write("config.yaml", "hotspot:\n  car:\n    ssid: Car\n")
+	manager, err := f.load()
+	require.NoError(t, err)
+	cfg := manager.GetConfig()
+	assert.Len(t, cfg.Hotspot, 2)
+	assert.Equal(t, dropIn, cfg.Sources["hotspot.demo"])
+}
+
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	t.Run("missing plain include", func(t *testing.T) {
+		f := newIncludeFixture(t)
+		f.write("config.yaml", "include: missing.yaml\n")
+		_, err := f.load()
+		assert.ErrorContains(t, err, "missing.yaml")
+	})
+
+	t.Run("unmatched glob is fine", func(t *testing.T) {
+		f := newIncludeFixture(t)
+		f.write("config.yaml", "include: [\"extra/*.yaml\"]\nhome:\n  ssid: Home\n")
+		_, err := f.load()
+		assert.NoError(t, err)
+	})
+
+	t.Run("nested include rejected", func(t *testing.T) {
+		f := newIncludeFixture(t)
+		dropIn := f.write("config.d/a.yaml", "include: b.yaml\n")
+		f.write("config.yaml", "home:\n  ssid: Home\n")
+		_, err := f.load()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), dropIn+": include is only allowed in the main config file")
+	})
+
+	t.Run("bad include type", func(t *testing.T) {
+		f := newIncludeFixture(t)
+		f.write("config.yaml", "include: {a: b}\n")
+		_, err := f.load()
+		assert.ErrorContains(t, err, "include must be a path or a list of paths")
+	})
+}
+
+func TestLoadConfig_DropInValidationErrorNamesFile(t *testing.T) {
+	f := newIncludeFixture(t)
+	dropIn := f.write("config.d/typo.yaml", "office:\n  ssd: OfficeWiFi\n")
+	f.write("config.yaml", "home:\n  ssid: Home\n")
+
+	_, err := f.load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), dropIn+": unknown field 'ssd'")
+}
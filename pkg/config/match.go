@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Match rule kinds, most specific first. When several profiles match, the
+// one matched by the most specific rule wins: a gateway MAC identifies one
+// router, a switch name one wiring closet, a BSSID prefix one vendor's APs
+// at a site, while a DHCP domain is often shared across a whole company.
+var matchRuleRank = map[string]int{
+	"gateway-mac": 4,
+	"switch":      3,
+	"bssid":       2,
+	"domain":      1,
+}
+
+// validateMatch checks a network's match: block: known fields, each a string
+// or list of strings, and well-formed MAC addresses / BSSID prefixes.
+func validateMatch(section string, value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	section += ".match"
+	rules, ok := value.(map[string]interface{})
+	if !ok {
+		return []ValidationError{{Section: section, Field: "match",
+			Message: section + " must be a mapping of bssid, gateway-mac, domain and switch"}}
+	}
+	errs := validateFields(section, rules, validMatchFields)
+	for _, field := range sortedKeys(rules) {
+		if !validMatchFields[field] {
+			continue
+		}
+		values, ok := stringList(rules[field])
+		if !ok {
+			errs = append(errs, ValidationError{Section: section, Field: field,
+				Message: fmt.Sprintf("%s.%s must be a string or a list of strings", section, field)})
+			continue
+		}
+		for _, v := range values {
+			var msg string
+			switch field {
+			case "bssid":
+				if !isMACPrefix(v, 6) {
+					msg = fmt.Sprintf("%s.bssid: '%s' is not a BSSID or BSSID prefix (e.g. 00:11:22)", section, v)
+				}
+			case "gateway-mac":
+				if !isMACPrefix(v, 6) || len(strings.Split(normalizeMAC(v), ":")) != 6 {
+					msg = fmt.Sprintf("%s.gateway-mac: '%s' is not a MAC address", section, v)
+				}
+			}
+			if msg != "" {
+				errs = append(errs, ValidationError{Section: section, Field: field, Message: msg})
+			}
+		}
+	}
+	return errs
+}
+
+// stringList accepts a string or a list of strings.
+func stringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// normalizeMAC lowercases a MAC address or prefix and uses ':' separators.
+func normalizeMAC(mac string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(mac), "-", ":"))
+}
+
+// isMACPrefix reports whether s is 1 to max colon- or dash-separated hex
+// octets.
+func isMACPrefix(s string, max int) bool {
+	octets := strings.Split(normalizeMAC(s), ":")
+	if len(octets) == 0 || len(octets) > max {
+		return false
+	}
+	for _, o := range octets {
+		if len(o) != 2 || strings.Trim(o, "0123456789abcdef") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRule returns the most specific match: rule of network that fits env,
+// as "<kind> <value>", and its rank; rank 0 means no rule matched.
+func matchRule(network types.NetworkConfig, env *types.Environment) (string, int) {
+	best, rank := "", 0
+	try := func(kind, value string, ok bool) {
+		if ok && matchRuleRank[kind] > rank {
+			best, rank = kind+" "+value, matchRuleRank[kind]
+		}
+	}
+	rules := network.Match
+	if mac := normalizeMAC(env.GatewayMAC); mac != "" {
+		for _, v := range rules.GatewayMAC {
+			try("gateway-mac", v, normalizeMAC(v) == mac)
+		}
+	}
+	if env.Switch != "" {
+		for _, v := range rules.Switch {
+			try("switch", v, strings.EqualFold(strings.TrimSpace(v), env.Switch))
+		}
+	}
+	if bssid := normalizeMAC(env.BSSID); bssid != "" {
+		for _, v := range rules.BSSID {
+			try("bssid", v, strings.HasPrefix(bssid, normalizeMAC(v)))
+		}
+	}
+	if domain := strings.ToLower(strings.TrimSuffix(env.Domain, ".")); domain != "" {
+		for _, v := range rules.Domain {
+			want := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(v), "."))
+			try("domain", v, want != "" && (domain == want || strings.HasSuffix(domain, "."+want)))
+		}
+	}
+	return best, rank
+}
+
+// MatchProfile picks the network profile whose match: rules fit env. Only
+// profiles named in candidates are considered, or every network when
+// candidates is nil; profiles without match: rules never match. A WiFi
+// profile must also have env's SSID, and a wired profile (no SSID) only
+// matches a wired link. Returns the profile name and the rule that matched
+// ("gateway-mac 00:11:22:33:44:55"), or "" when nothing matched. Two
+// profiles matched by equally specific rules are an error, since picking
+// either could apply the wrong site's settings.
+func MatchProfile(networks map[string]types.NetworkConfig, env *types.Environment, candidates []string) (string, string, error) {
+	if env == nil {
+		return "", "", nil
+	}
+	if candidates == nil {
+		for name := range networks {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	var bestNames []string
+	bestRule, bestRank := "", 0
+	for _, name := range candidates {
+		network, ok := networks[name]
+		if !ok || network.Match.IsEmpty() || network.SSID != env.SSID {
+			continue
+		}
+		rule, rank := matchRule(network, env)
+		switch {
+		case rank == 0 || rank < bestRank:
+		case rank > bestRank:
+			bestNames, bestRule, bestRank = []string{name}, rule, rank
+		default:
+			bestNames = append(bestNames, name)
+		}
+	}
+	switch len(bestNames) {
+	case 0:
+		return "", "", nil
+	case 1:
+		return bestNames[0], bestRule, nil
+	default:
+		return "", "", fmt.Errorf("networks %s all match this link equally; make their match: rules more specific", strings.Join(bestNames, ", "))
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_MatchRules(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+guest-berlin:
+  ssid: Guest
+  match:
+    bssid: [00:11:22, "00-11-23"]
+    domain: berlin.example.com
+office-wired:
+  match:
+    gateway-mac: AA:BB:CC:DD:EE:FF
+    switch: sw-3f-core
+`)
+	require.NoError(t, err)
+
+	berlin, err := manager.GetNetworkConfig("guest-berlin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"00:11:22", "00-11-23"}, berlin.Match.BSSID)
+	assert.Equal(t, []string{"berlin.example.com"}, berlin.Match.Domain)
+
+	wired, err := manager.GetNetworkConfig("office-wired")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AA:BB:CC:DD:EE:FF"}, wired.Match.GatewayMAC)
+	assert.Equal(t, []string{"sw-3f-core"}, wired.Match.Switch)
+}
+
+func TestValidateMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		match   string
+		wantErr string
+	}{
+		{"valid", "bssid: 00:11:22\n    gateway-mac: aa:bb:cc:dd:ee:ff", ""},
+		{"unknown field", "bsid: 00:11:22", "bsid"},
+		{"bad bssid", "bssid: 00:11:2", "not a BSSID"},
+		{"partial gateway mac", "gateway-mac: aa:bb:cc", "not a MAC address"},
+		{"not a list of strings", "domain: [1, 2]", "must be a string or a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfigInto(t, NewManager(&mockLogger{}), "office:\n  match:\n    "+tt.match+"\n")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestMatchProfile(t *testing.T) {
+	networks := map[string]types.NetworkConfig{
+		"guest-berlin": {SSID: "Guest", Match: types.MatchConfig{BSSID: []string{"00:11:22"}, Domain: []string{"example.com"}}},
+		"guest-paris":  {SSID: "Guest", Match: types.MatchConfig{BSSID: []string{"00-11-33"}}},
+		"guest-hq":     {SSID: "Guest", Match: types.MatchConfig{GatewayMAC: []string{"AA:BB:CC:DD:EE:FF"}}},
+		"guest-plain":  {SSID: "Guest"},
+		"lab":          {Match: types.MatchConfig{Switch: []string{"SW-LAB"}, Domain: []string{"example.com"}}},
+		"office":       {Match: types.MatchConfig{Domain: []string{"office.example.com"}}},
+	}
+
+	tests := []struct {
+		name       string
+		env        types.Environment
+		candidates []string
+		want       string
+		wantRule   string
+		wantErr    bool
+	}{
+		{
+			name: "bssid prefix", env: types.Environment{SSID: "Guest", BSSID: "00:11:33:aa:bb:cc"},
+			want: "guest-paris", wantRule: "bssid 00-11-33",
+		},
+		{
+			name: "gateway mac beats bssid", env: types.Environment{SSID: "Guest", BSSID: "00:11:22:01:02:03", GatewayMAC: "aa:bb:cc:dd:ee:ff"},
+			want: "guest-hq", wantRule: "gateway-mac AA:BB:CC:DD:EE:FF",
+		},
+		{
+			name: "wired profiles never match wifi links", env: types.Environment{SSID: "Other", Domain: "office.example.com"},
+		},
+		{
+			name: "switch beats domain", env: types.Environment{Domain: "office.example.com", Switch: "sw-lab"},
+			want: "lab", wantRule: "switch SW-LAB",
+		},
+		{
+			name: "equally specific matches are ambiguous", env: types.Environment{Domain: "office.example.com"},
+			wantErr: true,
+		},
+		{
+			name: "candidates restrict the choice", env: types.Environment{Domain: "office.example.com"},
+			candidates: []string{"office"}, want: "office", wantRule: "domain office.example.com",
+		},
+		{
+			name: "no rule matches", env: types.Environment{SSID: "Guest", BSSID: "00:99:99:00:00:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			name, rule, err := MatchProfile(networks, &env, tt.candidates)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+			assert.Equal(t, tt.wantRule, rule)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Valid fields of a network's roam: policy
+var validRoamFields = map[string]bool{
+	"prefer_band": true,
+	"band_margin": true,
+	"threshold":   true,
+	"min_gain":    true,
+	"interval":    true,
+}
+
+// validateRoam checks a network's roam: block: known fields, prefer_band 5
+// or 6, a negative threshold in dBm and non-negative margins and interval.
+func validateRoam(section string, value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	section += ".roam"
+	policy, ok := value.(map[string]interface{})
+	if !ok {
+		return []ValidationError{{Section: section, Field: "roam",
+			Message: section + " must be a mapping of prefer_band, band_margin, threshold, min_gain and interval"}}
+	}
+	errs := validateFields(section, policy, validRoamFields)
+	for _, field := range sortedKeys(policy) {
+		var msg string
+		switch field {
+		case "prefer_band":
+			if band := fmt.Sprint(policy[field]); band != "5" && band != "6" {
+				msg = fmt.Sprintf("%s.prefer_band must be 5 or 6 (GHz), got '%s'", section, band)
+			}
+		case "threshold":
+			if n, ok := policy[field].(int); !ok || n >= 0 {
+				msg = fmt.Sprintf("%s.threshold must be a negative signal in dBm (e.g. -70)", section)
+			}
+		case "band_margin", "min_gain", "interval":
+			if n, ok := policy[field].(int); !ok || n < 0 {
+				msg = fmt.Sprintf("%s.%s must be a non-negative number", section, field)
+			}
+		}
+		if msg != "" {
+			errs = append(errs, ValidationError{Section: section, Field: field, Message: msg})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Roam(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+office:
+  ssid: Office
+  roam:
+    prefer_band: 5
+    threshold: -72
+    min_gain: 6
+`)
+	require.NoError(t, err)
+
+	office, err := manager.GetNetworkConfig("office")
+	require.NoError(t, err)
+	require.NotNil(t, office.Roam)
+	assert.Equal(t, "5", office.Roam.PreferBand)
+	assert.Equal(t, -72, office.Roam.GetThreshold())
+	assert.Equal(t, 6, office.Roam.GetMinGain())
+	assert.Equal(t, 10, office.Roam.GetBandMargin())
+
+	_, err = loadConfigInto(t, manager, "office:\n  ssid: Office\n  roam:\n    prefer_band: 5\n    band_margin: 0\n")
+	require.NoError(t, err)
+	office, err = manager.GetNetworkConfig("office")
+	require.NoError(t, err)
+	assert.Equal(t, 0, office.Roam.GetBandMargin(), "an explicit 0 is not the default")
+}
+
+func TestValidateRoam(t *testing.T) {
+	tests := []struct {
+		name    string
+		roam    string
+		wantErr string
+	}{
+		{"valid", "prefer_band: 6\n    band_margin: 12\n    interval: 5", ""},
+		{"unknown field", "prefer: 5", "prefer"},
+		{"bad band", "prefer_band: 2.4", "prefer_band must be 5 or 6"},
+		{"positive threshold", "threshold: 70", "threshold must be a negative signal"},
+		{"negative gain", "min_gain: -3", "min_gain must be a non-negative number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfigInto(t, NewManager(&mockLogger{}), "office:\n  ssid: Office\n  roam:\n    "+tt.roam+"\n")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
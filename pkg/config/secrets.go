@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/angelfreak/net/pkg/secrets"
+	"github.com/angelfreak/net/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Credential fields that accept secret references, per config section.
+var (
+	networkSecretFields = []string{"psk"}
+	vpnSecretFields     = []string{"auth_key", "setup_key", "private_key", "config"}
+	hotspotSecretFields = []string{"password"}
+)
+
+// readConfigData reads a config file, decrypting it in memory if it is
+// age-encrypted (prompting via prompt when no stored key works), and rewrites
+// `!secret <name>` tags into plain "secret:<name>" strings. yaml.v3 silently
+// drops unknown tags (yielding just "<name>"), so without this rewrite the tag
+// would turn into a literal password. Both viper loading and
+// ValidateConfigFile read through here so they always see the same document.
+func readConfigData(path string, prompt PassphraseFunc) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(data) {
+		data, _, err = DecryptConfig(path, data, prompt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rewriteSecretData(data)
+}
+
+// rewriteSecretData applies rewriteSecretTags to a YAML document.
+func rewriteSecretData(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(secrets.SecretTag)) {
+		return data, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// Leave parse errors to the regular loader, which reports them.
+		return data, nil
+	}
+	if !rewriteSecretTags(&doc) {
+		return data, nil
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite secret tags: %w", err)
+	}
+	return out, nil
+}
+
+// rewriteSecretTags walks node and turns every `!secret name` scalar into the
+// string "secret:name". Reports whether anything was rewritten.
+func rewriteSecretTags(node *yaml.Node) bool {
+	changed := false
+	if node.Kind == yaml.ScalarNode && node.Tag == secrets.SecretTag {
+		node.Tag = "!!str"
+		node.Value = secrets.PrefixSecret + node.Value
+		node.Style = yaml.DoubleQuotedStyle
+		changed = true
+	}
+	for _, child := range node.Content {
+		if rewriteSecretTags(child) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// validateSecretRefs checks the syntax of secret references in the given
+// credential fields. Non-string values are left to the unmarshaller.
+func validateSecretRefs(section string, data map[string]interface{}, fields []string) []ValidationError {
+	var errors []ValidationError
+	for _, field := range fields {
+		value, ok := data[field].(string)
+		if !ok {
+			continue
+		}
+		if err := secrets.Validate(value); err != nil {
+			errors = append(errors, ValidationError{
+				Section: section, Field: field,
+				Message: fmt.Sprintf("%s.%s: %v", section, field, err),
+			})
+		}
+	}
+	return errors
+}
+
+// resolver returns the secret resolver, or a default one (no secrets file)
+// for Managers whose config was never loaded from disk. Callers hold m.mu.
+func (m *Manager) resolver() *secrets.Resolver {
+	if m.secrets == nil {
+		return &secrets.Resolver{}
+	}
+	return m.secrets
+}
+
+// resolveNetworkSecrets returns a copy of config with secret references in
+// its credential fields replaced by their values. The cached config keeps the
+// references so resolved secrets don't outlive the caller that needs them.
+func resolveNetworkSecrets(resolver *secrets.Resolver, name string, config *types.NetworkConfig) (*types.NetworkConfig, error) {
+	resolved := *config
+	psk, err := resolver.Resolve(config.PSK)
+	if err != nil {
+		return nil, fmt.Errorf("network '%s' psk: %w", name, err)
+	}
+	resolved.PSK = psk
+	return &resolved, nil
+}
+
+// resolveHotspotSecrets returns a copy of config with a secret reference in
+// its password resolved.
+func resolveHotspotSecrets(resolver *secrets.Resolver, name string, config *types.HotspotConfig) (*types.HotspotConfig, error) {
+	resolved := *config
+	password, err := resolver.Resolve(config.Password)
+	if err != nil {
+		return nil, fmt.Errorf("hotspot '%s' password: %w", name, err)
+	}
+	resolved.Password = password
+	return &resolved, nil
+}
+
+// resolveVPNSecrets returns a copy of config with secret references in its
+// credential fields (auth_key, setup_key, private_key, config) resolved.
+func resolveVPNSecrets(resolver *secrets.Resolver, name string, config *types.VPNConfig) (*types.VPNConfig, error) {
+	resolved := *config
+	for _, f := range []struct {
+		field string
+		value *string
+	}{
+		{"auth_key", &resolved.AuthKey},
+		{"setup_key", &resolved.SetupKey},
+		{"private_key", &resolved.PrivateKey},
+		{"config", &resolved.Config},
+	} {
+		value, err := resolver.Resolve(*f.value)
+		if err != nil {
+			return nil, fmt.Errorf("VPN '%s' %s: %w", name, f.field, err)
+		}
+		*f.value = value
+	}
+	return &resolved, nil
+}
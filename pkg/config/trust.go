@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Valid fields of a common.trust policy
+var validTrustFields = map[string]bool{
+	"vpn":           true,
+	"kill_switch":   true,
+	"firewall":      true,
+	"mac":           true,
+	"hide_hostname": true,
+	"encrypted_dns": true,
+}
+
+// isTrustLevel reports whether level is one of types.TrustLevels.
+func isTrustLevel(level string) bool {
+	for _, l := range types.TrustLevels {
+		if level == l {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTrust lowercases a trust: value.
+func normalizeTrust(level string) string {
+	return strings.ToLower(strings.TrimSpace(level))
+}
+
+// validateNetworkTrust checks a network's trust: value.
+func validateNetworkTrust(section string, value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	if s, ok := value.(string); ok && (s == "" || isTrustLevel(normalizeTrust(s))) {
+		return nil
+	}
+	return []ValidationError{{Section: section, Field: "trust",
+		Message: fmt.Sprintf("%s.trust must be one of %s", section, strings.Join(types.TrustLevels, ", "))}}
+}
+
+// validateTrustPolicies checks common.trust: a mapping from trust level to
+// a policy with known fields.
+func validateTrustPolicies(value interface{}) []ValidationError {
+	if value == nil {
+		return nil
+	}
+	policies, ok := value.(map[string]interface{})
+	if !ok {
+		return []ValidationError{{Section: "common.trust", Field: "trust",
+			Message: "common.trust must be a mapping of trust levels (" + strings.Join(types.TrustLevels, ", ") + ") to policies"}}
+	}
+	var errs []ValidationError
+	for _, level := range sortedKeys(policies) {
+		section := "common.trust." + level
+		if !isTrustLevel(level) {
+			errs = append(errs, ValidationError{Section: "common.trust", Field: level,
+				Message: fmt.Sprintf("common.trust: unknown trust level '%s' (expected %s)", level, strings.Join(types.TrustLevels, ", "))})
+			continue
+		}
+		switch policy := policies[level].(type) {
+		case nil:
+		case map[string]interface{}:
+			errs = append(errs, validateFields(section, policy, validTrustFields)...)
+		default:
+			errs = append(errs, ValidationError{Section: section, Field: level,
+				Message: section + " must be a mapping of policy settings"})
+		}
+	}
+	return errs
+}
+
+// trustPolicy returns the common.trust policy for level and whether it sets
+// vpn: (an empty vpn: turns the common VPN off for that level). Must be
+// called with m.mu held.
+func (m *Manager) trustPolicy(level string) (types.TrustPolicy, bool) {
+	if level == "" || m.config == nil {
+		return types.TrustPolicy{}, false
+	}
+	policy, ok := m.config.Common.Trust[level]
+	if !ok {
+		return types.TrustPolicy{}, false
+	}
+	// viper.IsSet() is false for nil values, so check the raw map.
+	vpnSet := policy.VPN != ""
+	if !vpnSet && m.viper != nil {
+		_, vpnSet = m.viper.GetStringMap("common.trust." + level)["vpn"]
+	}
+	return policy, vpnSet
+}
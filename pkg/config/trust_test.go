@@ -0,0 +1,165 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trustConfig = `
+common:
+  mac: permanent
+  hostname: laptop
+  vpn: home-vpn
+  trust:
+    public:
+      vpn: travel
+      kill_switch: true
+      firewall: true
+      mac: random
+      hide_hostname: true
+      encrypted_dns: true
+    trusted:
+      vpn:
+vpn:
+  home-vpn:
+    type: tailscale
+  travel:
+    type: wireguard
+home:
+  ssid: Home
+  trust: Trusted
+cafe:
+  ssid: Cafe
+  trust: public
+airport:
+  ssid: Airport
+  trust: public
+  vpn: home-vpn
+  hostname: visitor
+office:
+  ssid: Office
+`
+
+func TestMergeWithCommon_TrustPolicies(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	cfg, err := loadConfigInto(t, manager, trustConfig)
+	require.NoError(t, err)
+	assert.True(t, cfg.Common.Trust["public"].KillSwitch)
+	assert.Contains(t, cfg.Common.Trust, "trusted")
+
+	merge := func(name string) *types.NetworkConfig {
+		nc, err := manager.GetNetworkConfig(name)
+		require.NoError(t, err)
+		return manager.MergeWithCommon(name, nc)
+	}
+
+	cafe := merge("cafe")
+	assert.Equal(t, "travel", cafe.VPN)
+	assert.Equal(t, "random", cafe.MAC)
+	assert.Equal(t, types.HostnameModeAnonymous, cafe.HostnameMode)
+
+	// The network's own settings beat the policy.
+	airport := merge("airport")
+	assert.Equal(t, "home-vpn", airport.VPN)
+	assert.Equal(t, "visitor", airport.Hostname)
+	assert.Equal(t, "", airport.HostnameMode)
+
+	// An empty vpn: in the policy turns the common VPN off.
+	home := merge("home")
+	assert.Equal(t, "trusted", home.Trust)
+	assert.Equal(t, "", home.VPN)
+	assert.Equal(t, "permanent", home.MAC)
+	assert.Equal(t, "laptop", home.Hostname)
+
+	// No trust level: common settings only.
+	office := merge("office")
+	assert.Equal(t, "home-vpn", office.VPN)
+	assert.Equal(t, "laptop", office.Hostname)
+
+	// Unknown SSIDs are connected as public.
+	unknown := manager.MergeWithCommon("", &types.NetworkConfig{SSID: "Hotel", Trust: types.TrustPublic})
+	assert.Equal(t, "travel", unknown.VPN)
+}
+
+func TestValidateTrust(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", "common:\n  trust:\n    untrusted:\n      firewall: true\nlab:\n  trust: untrusted\n", ""},
+		{"unknown level", "common:\n  trust:\n    hostile:\n      firewall: true\n", "unknown trust level 'hostile'"},
+		{"unknown policy field", "common:\n  trust:\n    public:\n      killswitch: true\n", "killswitch"},
+		{"policy not a mapping", "common:\n  trust:\n    public: yes\n", "must be a mapping"},
+		{"bad network level", "lab:\n  trust: semi\n", "trust must be one of trusted, untrusted, public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfigInto(t, NewManager(&mockLogger{}), tt.config)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDoctor_TrustPolicies(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+common:
+  trust:
+    public:
+      kill_switch: true
+      encrypted_dns: true
+      vpn: nowhere
+lab:
+  ssid: Lab
+  trust: untrusted
+`)
+	require.NoError(t, err)
+
+	checks := map[string]string{}
+	for _, f := range manager.Doctor(func(string) bool { return false }) {
+		checks[f.Check+" "+f.Subject] = f.Message
+	}
+	assert.Contains(t, checks, "dangling-vpn common.trust.public")
+	assert.Contains(t, checks, "missing-binary common.trust.public")
+	assert.Contains(t, checks["trust network 'lab'"], "no common.trust.untrusted policy")
+}
+
+func TestDoctor_KillSwitchWithTailscale(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := loadConfigInto(t, manager, `
+common:
+  vpn: ts
+  trust:
+    public:
+      kill_switch: true
+    untrusted:
+      kill_switch: true
+      vpn: wg
+vpn:
+  ts:
+    type: tailscale
+  wg:
+    type: wireguard
+`)
+	require.NoError(t, err)
+
+	var killSwitch []Finding
+	for _, f := range manager.Doctor(func(string) bool { return true }) {
+		if f.Check == "kill-switch" {
+			killSwitch = append(killSwitch, f)
+		}
+	}
+	require.Len(t, killSwitch, 1)
+	assert.Equal(t, "common.trust.public", killSwitch[0].Subject)
+	assert.Equal(t, SeverityError, killSwitch[0].Severity)
+	assert.Contains(t, killSwitch[0].Message, "can't be enforced with tailscale VPN 'ts'")
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay debounces bursts of file events (editors typically write a
+// temp file, rename it and chmod it) into a single reload. A variable so
+// tests can shorten it.
+var reloadDelay = 300 * time.Millisecond
+
+// Change describes how a reloaded config differs from the previous one, so
+// long-running components can re-apply only what is affected.
+type Change struct {
+	Networks []string // networks and aliases added, removed or modified (lowercased)
+	VPNs     []string // VPNs added, removed or modified (lowercased)
+	Common   bool     // common: settings changed; applies to every network
+	Ignored  bool     // ignored: interfaces changed
+}
+
+// Empty reports whether nothing changed.
+func (c Change) Empty() bool {
+	return len(c.Networks) == 0 && len(c.VPNs) == 0 && !c.Common && !c.Ignored
+}
+
+// AffectsNetwork reports whether the active network name (connected with
+// vpn, which may be empty) needs its settings re-applied after this change.
+func (c Change) AffectsNetwork(name, vpn string) bool {
+	if c.Common {
+		return true
+	}
+	for _, n := range c.Networks {
+		if n == strings.ToLower(name) {
+			return true
+		}
+	}
+	return vpn != "" && c.AffectsVPN(vpn)
+}
+
+// AffectsVPN reports whether VPN name was added, removed or modified.
+func (c Change) AffectsVPN(name string) bool {
+	for _, v := range c.VPNs {
+		if v == strings.ToLower(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String summarises the change for logs ("networks: home; vpns: work").
+func (c Change) String() string {
+	if c.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	if len(c.Networks) > 0 {
+		parts = append(parts, "networks: "+strings.Join(c.Networks, ", "))
+	}
+	if len(c.VPNs) > 0 {
+		parts = append(parts, "vpns: "+strings.Join(c.VPNs, ", "))
+	}
+	if c.Common {
+		parts = append(parts, "common")
+	}
+	if c.Ignored {
+		parts = append(parts, "ignored")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Diff compares two configs. Networks and VPNs are matched by lowercased
+// name, the way lookups treat them. Source provenance is not compared, so
+// moving a network between files is not a change.
+func Diff(old, new *types.Config) Change {
+	if old == nil {
+		old = &types.Config{}
+	}
+	if new == nil {
+		new = &types.Config{}
+	}
+	return Change{
+		Networks: diffKeys(lowerNetworks(old.Networks, old.Aliases), lowerNetworks(new.Networks, new.Aliases)),
+		VPNs:     diffKeys(lowerVPNs(old.VPN), lowerVPNs(new.VPN)),
+		Common:   !reflect.DeepEqual(old.Common, new.Common),
+		Ignored:  !reflect.DeepEqual(old.Ignored, new.Ignored),
+	}
+}
+
+// lowerNetworks keys networks and aliases by lowercased name. An alias
+// compares as the network it resolves to, so pointing it elsewhere (or
+// changing its target) changes it.
+func lowerNetworks(networks, aliases map[string]types.NetworkConfig) map[string]interface{} {
+	out := make(map[string]interface{}, len(networks)+len(aliases))
+	for _, in := range []map[string]types.NetworkConfig{networks, aliases} {
+		for name, network := range in {
+			out[strings.ToLower(name)] = network
+		}
+	}
+	return out
+}
+
+func lowerVPNs(in map[string]types.VPNConfig) map[string]interface{} {
+	out := make(map[string]interface{}, len(in))
+	for name, vpn := range in {
+		out[strings.ToLower(name)] = vpn
+	}
+	return out
+}
+
+// diffKeys returns the sorted keys present in only one map or whose values
+// differ.
+func diffKeys(old, new map[string]interface{}) []string {
+	var changed []string
+	for name, value := range old {
+		if other, ok := new[name]; !ok || !reflect.DeepEqual(value, other) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Reload re-reads and re-validates the config from the path it was loaded
+// from. The new config only replaces the current one when it loads cleanly;
+// on any error the previous config stays in effect and the error is
+// returned. Reload never prompts for a passphrase.
+func (m *Manager) Reload() (Change, error) {
+	m.mu.RLock()
+	path, old := m.configPath, m.config
+	m.mu.RUnlock()
+	if path == "" {
+		return Change{}, fmt.Errorf("config not loaded from a file")
+	}
+
+	fresh := NewManager(m.logger)
+	fresh.SetPassphrasePrompt(nil)
+	if _, err := fresh.LoadConfig(path); err != nil {
+		return Change{}, err
+	}
+	change := Diff(old, fresh.config)
+
+	m.mu.Lock()
+	m.config = fresh.config
+	m.viper = fresh.viper
+	m.secrets = fresh.secrets
+	m.sourceFiles = fresh.sourceFiles
+	m.mu.Unlock()
+	return change, nil
+}
+
+// Watch watches every file making up the config (the main file, includes
+// and drop-in directories) and reloads on change until ctx is done.
+// onChange is called after each successful reload that changed something;
+// invalid edits are logged and leave the current config in place. Returns
+// when ctx is cancelled, or an error if the files can't be watched.
+func (m *Manager) Watch(ctx context.Context, onChange func(Change)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config: %w", err)
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	relevant := m.watchSet(watcher, watched)
+
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !relevant(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(reloadDelay)
+			} else {
+				timer.Reset(reloadDelay)
+			}
+			fire = timer.C
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if m.logger != nil {
+				m.logger.Warn("Config watch error", "error", err)
+			}
+		case <-fire:
+			fire = nil
+			change, err := m.Reload()
+			if err != nil {
+				if m.logger != nil {
+					m.logger.Warn("Config reload failed, keeping previous config", "error", err)
+				}
+				continue
+			}
+			// include: may now name different files.
+			relevant = m.watchSet(watcher, watched)
+			if change.Empty() {
+				continue
+			}
+			if m.logger != nil {
+				m.logger.Info("Config reloaded", "changed", change.String())
+			}
+			if onChange != nil {
+				onChange(change)
+			}
+		}
+	}
+}
+
+// watchSet adds the directories holding the config's files to watcher
+// (directories, not files, so atomic renames by editors are seen) and
+// returns a filter for the events that matter: a source file, the main
+// config appearing, or a .yaml file in a drop-in directory.
+func (m *Manager) watchSet(watcher *fsnotify.Watcher, watched map[string]bool) func(string) bool {
+	m.mu.RLock()
+	mainPath := m.configPath
+	files := append([]string(nil), m.sourceFiles...)
+	systemDirs := m.systemDirs
+	m.mu.RUnlock()
+
+	dropIns := dropInDirs(mainPath, systemDirs)
+	names := map[string]bool{filepath.Clean(mainPath): true}
+	dirs := append([]string{filepath.Dir(mainPath)}, dropIns...)
+	for _, f := range files {
+		names[filepath.Clean(f)] = true
+		dirs = append(dirs, filepath.Dir(f))
+	}
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if watched[dir] {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			if m.logger != nil {
+				m.logger.Warn("Failed to watch config directory", "dir", dir, "error", err)
+			}
+			continue
+		}
+		watched[dir] = true
+	}
+
+	return func(name string) bool {
+		name = filepath.Clean(name)
+		if names[name] {
+			return true
+		}
+		for _, dir := range dropIns {
+			if filepath.Dir(name) == filepath.Clean(dir) && strings.HasSuffix(name, ".yaml") {
+				return true
+			}
+		}
+		return false
+	}
+}
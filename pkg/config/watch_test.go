@@ -0,0 +1,193 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	old := &types.Config{
+		Common: types.CommonConfig{DNS: []string{"1.1.1.1"}},
+		Networks: map[string]types.NetworkConfig{
+			"home":   {SSID: "Home", DNS: []string{"9.9.9.9"}},
+			"office": {SSID: "Office"},
+			"cafe":   {SSID: "Cafe"},
+		},
+		VPN: map[string]types.VPNConfig{
+			"work": {Type: "wireguard", Config: "a"},
+			"old":  {Type: "openvpn"},
+		},
+	}
+	new := &types.Config{
+		Common: types.CommonConfig{DNS: []string{"1.1.1.1"}},
+		Networks: map[string]types.NetworkConfig{
+			"Home":   {SSID: "Home", DNS: []string{"1.0.0.1"}},
+			"office": {SSID: "Office"},
+			"lab":    {SSID: "Lab"},
+		},
+		VPN: map[string]types.VPNConfig{
+			"Work": {Type: "wireguard", Config: "a"},
+			"new":  {Type: "tailscale"},
+		},
+		Sources: map[string]string{"office": "/elsewhere.yaml"},
+	}
+
+	change := Diff(old, new)
+	assert.Equal(t, []string{"cafe", "home", "lab"}, change.Networks)
+	assert.Equal(t, []string{"new", "old"}, change.VPNs)
+	assert.False(t, change.Common)
+	assert.False(t, change.Ignored)
+
+	assert.True(t, change.AffectsNetwork("HOME", ""))
+	assert.False(t, change.AffectsNetwork("office", "work"))
+	assert.True(t, change.AffectsNetwork("office", "old"))
+	assert.Equal(t, "networks: cafe, home, lab; vpns: new, old", change.String())
+
+	assert.True(t, Diff(old, old).Empty())
+	new.Common.MAC = "random"
+	assert.True(t, Diff(old, new).AffectsNetwork("office", ""))
+}
+
+func TestReload(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.yaml", "home:\n  ssid: Home\n  dns: [1.1.1.1]\noffice:\n  ssid: Office\n")
+	manager, err := f.load()
+	require.NoError(t, err)
+
+	f.write("config.yaml", "home:\n  ssid: Home\n  dns: [9.9.9.9]\noffice:\n  ssid: Office\n")
+	change, err := manager.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home"}, change.Networks)
+	home, err := manager.GetNetworkConfig("home")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"9.9.9.9"}, home.DNS)
+
+	// An invalid edit is rejected and the previous config stays in effect.
+	f.write("config.yaml", "home:\n  ssid: Home\n  dnss: [8.8.8.8]\n")
+	_, err = manager.Reload()
+	require.Error(t, err)
+	home, err = manager.GetNetworkConfig("home")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"9.9.9.9"}, home.DNS)
+	_, err = manager.GetNetworkConfig("office")
+	assert.NoError(t, err)
+}
+
+func TestReload_Aliases(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.yaml", "work: home\nhome:\n  ssid: Home\ncafe:\n  ssid: Cafe\n")
+	manager, err := f.load()
+	require.NoError(t, err)
+
+	// A lookup by alias leaves nothing behind to show up as a change
+	_, err = manager.GetNetworkConfig("work")
+	require.NoError(t, err)
+	change, err := manager.Reload()
+	require.NoError(t, err)
+	assert.True(t, change.Empty(), change.String())
+
+	f.write("config.yaml", "work: cafe\nhome:\n  ssid: Home\ncafe:\n  ssid: Cafe\n")
+	change, err = manager.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work"}, change.Networks, "a retargeted alias changes")
+	work, err := manager.GetNetworkConfig("work")
+	require.NoError(t, err)
+	assert.Equal(t, "Cafe", work.SSID)
+}
+
+// Run with -race: lookups read the config while reloads replace it.
+func TestReload_ConcurrentLookups(t *testing.T) {
+	f := newIncludeFixture(t)
+	f.write("config.yaml", "work: home\nhome:\n  ssid: Home\n")
+	manager, err := f.load()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			_, err := manager.Reload()
+			assert.NoError(t, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			for _, name := range []string{"work", "home", "Home"} {
+				_, err := manager.GetNetworkConfig(name)
+				assert.NoError(t, err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestReload_NotLoadedFromFile(t *testing.T) {
+	manager := NewManager(&mockLogger{})
+	_, err := manager.LoadConfig("-")
+	require.NoError(t, err)
+	_, err = manager.Reload()
+	assert.Error(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	origDelay := reloadDelay
+	reloadDelay = 20 * time.Millisecond
+	t.Cleanup(func() { reloadDelay = origDelay })
+
+	f := newIncludeFixture(t)
+	f.write("config.yaml", "home:\n  ssid: Home\n")
+	f.write("config.d/vpn.yaml", "vpn:\n  work:\n    type: wireguard\n")
+	manager, err := f.load()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan Change, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Watch(ctx, func(c Change) { changes <- c })
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	// Give the watcher time to register its directories.
+	time.Sleep(50 * time.Millisecond)
+
+	f.write("config.d/vpn.yaml", "vpn:\n  work:\n    type: tailscale\n")
+	select {
+	case change := <-changes:
+		assert.Equal(t, []string{"work"}, change.VPNs)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after drop-in change")
+	}
+
+	// Invalid edit: no callback, config unchanged.
+	f.write("config.yaml", "home:\n  sid: Home\n")
+	select {
+	case change := <-changes:
+		t.Fatalf("unexpected reload: %s", change)
+	case <-time.After(200 * time.Millisecond):
+	}
+	vpn, err := manager.GetVPNConfig("work")
+	require.NoError(t, err)
+	assert.Equal(t, "tailscale", vpn.Type)
+
+	// A new drop-in is picked up.
+	f.write("config.yaml", "home:\n  ssid: Home\n")
+	f.write("config.d/lab.yaml", "lab:\n  ssid: Lab\n")
+	select {
+	case change := <-changes:
+		assert.Contains(t, change.Networks, "lab")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after new drop-in")
+	}
+}
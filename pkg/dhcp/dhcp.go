@@ -12,8 +12,11 @@ import (
 
 	"github.com/angelfreak/net/pkg/firewall"
 	"github.com/angelfreak/net/pkg/netlink"
+	"github.com/angelfreak/net/pkg/oui"
+	"github.com/angelfreak/net/pkg/share"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/vpn"
 )
 
 // dhcpManagerImpl implements the DHCPManager interface
@@ -26,6 +29,8 @@ type dhcpManagerImpl struct {
 	stateFile       string // Persists interface and outInterface for crash recovery
 	currentConfig   *types.DHCPServerConfig
 	outInterface    string                // Interface for NAT routing (e.g., wlan0)
+	vpnUplink       bool                  // Clients are routed through the VPN tunnel (uplink: vpn) and restricted to it
+	vpnStateDir     string                // Directory of the VPN manager's state, which names the active tunnel
 	prevIPForward   string                // ip_forward value before we enabled it, for restore ("0"/"1"/"" if unknown)
 	linkMgr         types.LinkManager     // netlink-backed link access (interface up/down)
 	addrMgr         types.AddrManager     // netlink-backed interface address access
@@ -42,6 +47,7 @@ func NewDHCPManager(executor types.SystemExecutor, logger types.Logger) types.DH
 		dnsmasqConfFile: types.RuntimeDir + "/dnsmasq-dhcp.conf",
 		leasesFile:      types.RuntimeDir + "/dnsmasq-dhcp.leases",
 		stateFile:       types.RuntimeDir + "/dhcp-state",
+		vpnStateDir:     types.RuntimeDir,
 		linkMgr:         netlink.NewLinkManager(),
 		addrMgr:         netlink.NewAddrManager(),
 		routeMgr:        netlink.NewRouteManager(),
@@ -77,6 +83,11 @@ func (d *dhcpManagerImpl) Start(config *types.DHCPServerConfig) error {
 		return fmt.Errorf("DHCP server is already running")
 	}
 
+	// Without a tunnel there is nothing to share; fail before touching the interface
+	if config.Uplink == types.UplinkVPN && vpn.ActiveInterface(d.vpnStateDir) == "" {
+		return fmt.Errorf("uplink is vpn, but no VPN is connected")
+	}
+
 	// Bring interface down
 	if err := d.linkMgr.SetDown(config.Interface); err != nil {
 		return fmt.Errorf("failed to bring interface down: %w", err)
@@ -111,7 +122,16 @@ func (d *dhcpManagerImpl) Start(config *types.DHCPServerConfig) error {
 	}
 
 	// Setup NAT/IP forwarding for internet sharing
-	if err := d.setupNAT(config.Interface); err != nil {
+	if err := d.setupNAT(config.Interface, config.Uplink); err != nil {
+		if config.Uplink == types.UplinkVPN {
+			// Half-applied VPN routing could let clients out another way
+			d.cleanupNAT(config.Interface)
+			d.stopDnsmasq()
+			d.addrMgr.Flush(config.Interface)
+			d.outInterface = ""
+			d.vpnUplink = false
+			return fmt.Errorf("failed to route clients through the VPN: %w", err)
+		}
 		d.logger.Warn("Failed to setup NAT", "error", err.Error())
 		// Continue anyway - DHCP will work but without internet sharing
 	}
@@ -174,6 +194,7 @@ func (d *dhcpManagerImpl) Stop() error {
 
 	d.currentConfig = nil
 	d.outInterface = ""
+	d.vpnUplink = false
 	os.Remove(d.stateFile)
 	d.logger.Info("DHCP server stopped successfully")
 	return nil
@@ -226,6 +247,7 @@ func (d *dhcpManagerImpl) GetLeases() ([]types.DHCPLease, error) {
 			MAC:      fields[1],
 			IP:       fields[2],
 			Hostname: hostname,
+			Vendor:   oui.Lookup(fields[1]),
 		})
 	}
 
@@ -257,6 +279,12 @@ func (d *dhcpManagerImpl) validateConfig(config *types.DHCPServerConfig) error {
 			return fmt.Errorf("invalid DNS server: %q", dns)
 		}
 	}
+	if err := types.ValidateUplink(config.Uplink); err != nil {
+		return err
+	}
+	if config.Uplink == config.Interface {
+		return fmt.Errorf("uplink %q is the DHCP server interface", config.Uplink)
+	}
 
 	return nil
 }
@@ -292,14 +320,17 @@ func (d *dhcpManagerImpl) generateDnsmasqConfig(config *types.DHCPServerConfig)
 	sb.WriteString(fmt.Sprintf("dhcp-range=%s,%s\n", config.IPRange, leaseTime))
 
 	// Add DNS servers
-	if len(config.DNS) > 0 {
-		for _, dns := range config.DNS {
-			sb.WriteString(fmt.Sprintf("server=%s\n", dns))
-		}
-	} else {
+	dnsServers := config.DNS
+	if len(dnsServers) == 0 {
 		// Default DNS servers
-		sb.WriteString("server=8.8.8.8\n")
-		sb.WriteString("server=8.8.4.4\n")
+		dnsServers = []string{"8.8.8.8", "8.8.4.4"}
+	}
+	forwarders, err := share.DNSForwarders(config.Uplink, vpn.ActiveInterface(d.vpnStateDir), dnsServers)
+	if err != nil {
+		return err
+	}
+	for _, line := range forwarders {
+		sb.WriteString(line + "\n")
 	}
 
 	sb.WriteString(fmt.Sprintf("dhcp-option=3,%s\n", config.Gateway)) // Gateway
@@ -319,20 +350,26 @@ func (d *dhcpManagerImpl) generateDnsmasqConfig(config *types.DHCPServerConfig)
 }
 
 // setupNAT configures IP forwarding and NAT masquerade for internet sharing
-func (d *dhcpManagerImpl) setupNAT(dhcpIface string) error {
+// out of uplink (see types.DHCPServerConfig.Uplink)
+func (d *dhcpManagerImpl) setupNAT(dhcpIface, uplink string) error {
 	// Record the current forwarding state so teardown can restore it instead
 	// of unconditionally disabling forwarding the host may have had enabled.
 	if prev, err := system.ReadIPForward(); err == nil {
 		d.prevIPForward = prev
 	}
 
+	outIface, err := share.Uplink(uplink, vpn.ActiveInterface(d.vpnStateDir), func() string {
+		return d.detectOutInterface(dhcpIface)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Enable IP forwarding
 	if err := system.WriteIPForward("1"); err != nil {
 		return fmt.Errorf("failed to enable IP forwarding: %w", err)
 	}
 
-	// Find outbound interface (excluding the DHCP server interface)
-	outIface := d.detectOutInterface(dhcpIface)
 	if outIface == "" {
 		d.logger.Warn("No outbound interface detected, skipping NAT setup")
 		return nil
@@ -346,11 +383,16 @@ func (d *dhcpManagerImpl) setupNAT(dhcpIface string) error {
 	if err != nil {
 		return fmt.Errorf("failed to set up NAT: %w", err)
 	}
+	d.outInterface = outIface
+	if uplink == types.UplinkVPN {
+		d.vpnUplink = true
+		if err := share.RouteThroughVPN(fw, d.routeMgr, d.vpnStateDir, dhcpIface, outIface); err != nil {
+			return err
+		}
+	}
 	if err := fw.EnableNAT(dhcpIface, outIface); err != nil {
 		return fmt.Errorf("failed to set up NAT: %w", err)
 	}
-
-	d.outInterface = outIface
 	return nil
 }
 
@@ -379,6 +421,15 @@ func (d *dhcpManagerImpl) cleanupNAT(dhcpIface string) {
 		}
 	}
 
+	// Lift the VPN restriction only after NAT is gone, so clients are never
+	// forwarded out of another interface in between.
+	if d.vpnUplink && dhcpIface != "" {
+		fw, _ := d.firewallMgr()
+		if err := share.Unroute(fw, d.routeMgr, d.vpnStateDir, dhcpIface); err != nil {
+			d.logger.Warn("Failed to stop routing clients through the VPN", "error", err.Error())
+		}
+	}
+
 	// Restore IP forwarding to its pre-server value rather than forcing it off —
 	// the host may have had forwarding enabled. Default to "0" only when we
 	// never recorded the prior value.
@@ -393,7 +444,12 @@ func (d *dhcpManagerImpl) cleanupNAT(dhcpIface string) {
 
 // saveState persists DHCP interface and outInterface to a state file for crash recovery
 func (d *dhcpManagerImpl) saveState(dhcpIface string) {
+	// Format: dhcpInterface|outInterface|prevIPForward, plus |vpn when clients
+	// are routed through the VPN
 	content := dhcpIface + "|" + d.outInterface + "|" + d.prevIPForward
+	if d.vpnUplink {
+		content += "|" + types.UplinkVPN
+	}
 	if err := os.WriteFile(d.stateFile, []byte(content), 0600); err != nil {
 		d.logger.Debug("Failed to save DHCP state", "error", err)
 	}
@@ -405,7 +461,7 @@ func (d *dhcpManagerImpl) loadState() {
 	if err != nil {
 		return
 	}
-	parts := strings.SplitN(strings.TrimSpace(string(data)), "|", 3)
+	parts := strings.SplitN(strings.TrimSpace(string(data)), "|", 4)
 	if len(parts) >= 1 && parts[0] != "" && d.currentConfig == nil {
 		d.currentConfig = &types.DHCPServerConfig{Interface: parts[0]}
 	}
@@ -415,6 +471,9 @@ func (d *dhcpManagerImpl) loadState() {
 	if len(parts) >= 3 && parts[2] != "" {
 		d.prevIPForward = parts[2]
 	}
+	if len(parts) >= 4 {
+		d.vpnUplink = parts[3] == types.UplinkVPN
+	}
 }
 
 // cleanupStaleFiles removes PID, config, and lease files left behind when
@@ -236,6 +236,16 @@ func TestStart_InvalidConfig(t *testing.T) {
 			config: &types.DHCPServerConfig{Interface: "eth0", Gateway: "192.168.1.1"},
 			errMsg: "IP range is required",
 		},
+		{
+			name:   "invalid uplink",
+			config: &types.DHCPServerConfig{Interface: "eth0", Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150", Uplink: "wlan 0"},
+			errMsg: `invalid uplink "wlan 0"`,
+		},
+		{
+			name:   "uplink is the DHCP server interface",
+			config: &types.DHCPServerConfig{Interface: "eth0", Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150", Uplink: "eth0"},
+			errMsg: `uplink "eth0" is the DHCP server interface`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -548,12 +558,13 @@ func TestGetLeases_ValidEntries(t *testing.T) {
 	mgr.leasesFile = tmpFile.Name()
 
 	content := "1709568000 aa:bb:cc:dd:ee:ff 192.168.100.51 laptop 01:aa:bb:cc:dd:ee:ff\n" +
-		"1709571600 11:22:33:44:55:66 192.168.100.52 * 01:11:22:33:44:55:66\n"
+		"1709571600 11:22:33:44:55:66 192.168.100.52 * 01:11:22:33:44:55:66\n" +
+		"1709575200 b8:27:eb:01:02:03 192.168.100.53 pi 01:b8:27:eb:01:02:03\n"
 	os.WriteFile(tmpFile.Name(), []byte(content), 0644)
 
 	leases, err := mgr.GetLeases()
 	assert.NoError(t, err)
-	assert.Len(t, leases, 2)
+	assert.Len(t, leases, 3)
 
 	assert.Equal(t, "aa:bb:cc:dd:ee:ff", leases[0].MAC)
 	assert.Equal(t, "192.168.100.51", leases[0].IP)
@@ -563,6 +574,10 @@ func TestGetLeases_ValidEntries(t *testing.T) {
 	// Hostname "*" should become empty
 	assert.Equal(t, "11:22:33:44:55:66", leases[1].MAC)
 	assert.Equal(t, "", leases[1].Hostname)
+
+	// Vendor comes from the OUI database
+	assert.Equal(t, "", leases[0].Vendor)
+	assert.Equal(t, "Raspberry Pi", leases[2].Vendor)
 }
 
 func TestGetLeases_EmptyFile(t *testing.T) {
@@ -1083,3 +1098,95 @@ func TestStart_WithDifferentNetmasks(t *testing.T) {
 		})
 	}
 }
+
+func TestStart_Uplink(t *testing.T) {
+	ipfPath := filepath.Join(t.TempDir(), "ip_forward")
+	assert.NoError(t, os.WriteFile(ipfPath, []byte("0"), 0644))
+	restore := system.SetIPForwardPathForTest(ipfPath)
+	defer restore()
+
+	config := func(uplink string) *types.DHCPServerConfig {
+		return &types.DHCPServerConfig{
+			Interface: "eth0",
+			Gateway:   "192.168.100.1",
+			IPRange:   "192.168.100.50,192.168.100.150",
+			Uplink:    uplink,
+		}
+	}
+	// connectVPN records wg0 as the active tunnel in a temp VPN state directory.
+	connectVPN := func(t *testing.T, mgr *dhcpManagerImpl) {
+		mgr.vpnStateDir = t.TempDir()
+		os.WriteFile(filepath.Join(mgr.vpnStateDir, "active-vpn"), []byte("travel|wg0|wireguard|192.168.1.1|wlan0|"), 0600)
+	}
+
+	t.Run("interface", func(t *testing.T) {
+		mgr, executor := setupTestManager()
+		defer cleanup(mgr)
+		executor.commands[fmt.Sprintf("dnsmasq -C %s -x %s", mgr.dnsmasqConfFile, mgr.dnsmasqPidFile)] = ""
+
+		assert.NoError(t, mgr.Start(config("usb0")))
+		fw := mgr.firewall.(*fwfake.Manager)
+		assert.Equal(t, []fwfake.NATCall{{Internal: "eth0", Out: "usb0"}}, fw.Enabled)
+		assert.Empty(t, fw.Restricted)
+	})
+
+	t.Run("vpn", func(t *testing.T) {
+		mgr, executor := setupTestManager()
+		defer cleanup(mgr)
+		executor.commands[fmt.Sprintf("dnsmasq -C %s -x %s", mgr.dnsmasqConfFile, mgr.dnsmasqPidFile)] = ""
+		connectVPN(t, mgr)
+		assert.NoError(t, os.WriteFile(ipfPath, []byte("0"), 0644))
+
+		assert.NoError(t, mgr.Start(config("vpn")))
+		fw := mgr.firewall.(*fwfake.Manager)
+		routes := mgr.routeMgr.(*fake.RouteManager)
+		assert.Equal(t, []fwfake.NATCall{{Internal: "eth0", Out: "wg0"}}, fw.Enabled)
+		assert.Equal(t, map[string]string{"eth0": "wg0"}, fw.Restricted)
+		assert.Equal(t, "wg0", routes.TableDefaults[types.VPNRouteTable])
+		assert.Equal(t, map[string]int{"eth0": types.VPNRouteTable}, routes.Rules)
+
+		// dnsmasq's own queries are bound to the tunnel too
+		conf, err := os.ReadFile(mgr.dnsmasqConfFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(conf), "server=8.8.8.8@wg0\nserver=8.8.4.4@wg0\n")
+
+		data, err := os.ReadFile(mgr.stateFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "eth0|wg0|0|vpn", string(data))
+
+		// Stop from a fresh manager, as the CLI does, recovers the uplink
+		// from the state file
+		fresh, _ := setupTestManager()
+		fresh.firewall, fresh.routeMgr, fresh.vpnStateDir = fw, routes, mgr.vpnStateDir
+		assert.NoError(t, fresh.Stop())
+		assert.Contains(t, fw.Disabled, fwfake.NATCall{Internal: "eth0", Out: "wg0"})
+		assert.Empty(t, fw.Restricted)
+		assert.Empty(t, routes.Rules)
+		assert.NoFileExists(t, filepath.Join(mgr.vpnStateDir, "vpn-shares", "eth0"))
+	})
+
+	t.Run("vpn without a tunnel", func(t *testing.T) {
+		mgr, _ := setupTestManager()
+		defer cleanup(mgr)
+		mgr.vpnStateDir = t.TempDir()
+
+		err := mgr.Start(config("vpn"))
+		assert.EqualError(t, err, "uplink is vpn, but no VPN is connected")
+		assert.Empty(t, mgr.linkMgr.(*fake.LinkManager).Downed, "the interface must not be touched")
+	})
+
+	t.Run("vpn routing fails", func(t *testing.T) {
+		mgr, executor := setupTestManager()
+		defer cleanup(mgr)
+		executor.commands[fmt.Sprintf("dnsmasq -C %s -x %s", mgr.dnsmasqConfFile, mgr.dnsmasqPidFile)] = ""
+		connectVPN(t, mgr)
+		mgr.routeMgr.(*fake.RouteManager).RuleErr = fmt.Errorf("operation not permitted")
+
+		err := mgr.Start(config("vpn"))
+		assert.ErrorContains(t, err, "failed to route clients through the VPN")
+		fw := mgr.firewall.(*fwfake.Manager)
+		assert.Empty(t, fw.Enabled, "NAT must not be enabled without the VPN routing")
+		assert.Empty(t, fw.Restricted, "the partial restriction must be lifted again")
+		assert.Nil(t, mgr.GetCurrentConfig())
+	})
+}
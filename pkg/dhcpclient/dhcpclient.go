@@ -54,12 +54,20 @@ const (
 	RetryDelay = 2 * time.Second
 )
 
+// defaultUdhcpcScript is the script BusyBox udhcpc runs to apply a lease
+// (a variable so tests can point it elsewhere).
+var defaultUdhcpcScript = "/usr/share/udhcpc/default.script"
+
 // Manager implements the DHCPClientManager interface
 type Manager struct {
 	executor    types.SystemExecutor
 	logger      types.Logger
 	dhcpTimeout time.Duration // Configurable overall DHCP timeout (0 = use defaults)
 	runtimeDir  string        // overridable for tests; defaults to types.RuntimeDir
+	options     types.DHCPClientOptions
+	// hardwareAddr looks up an interface's current MAC (overridable in
+	// tests; nil uses net.InterfaceByName).
+	hardwareAddr func(iface string) (net.HardwareAddr, error)
 }
 
 // NewManager creates a new DHCP client manager
@@ -88,6 +96,50 @@ func (m *Manager) SetDHCPTimeout(timeout time.Duration) {
 	}
 }
 
+// SetOptions sets the privacy options used by later Acquire/Renew calls.
+func (m *Manager) SetOptions(opts types.DHCPClientOptions) {
+	m.options = opts
+}
+
+// anonymousRequestList is the fixed parameter request list (option 55) of the
+// anonymity profile: only what is needed to configure the link, in the same
+// order on every machine, so it identifies no OS or client (RFC 7844 3.6).
+var anonymousRequestList = []struct {
+	code     int
+	udhcpc   string // BusyBox option name
+	dhclient string // dhclient.conf option name
+}{
+	{1, "subnet", "subnet-mask"},
+	{3, "router", "routers"},
+	{6, "dns", "domain-name-servers"},
+	{15, "domain", "domain-name"},
+	{28, "broadcast", "broadcast-address"},
+}
+
+// clientID returns the client identifier of the anonymity profile for iface:
+// hardware type 1 (Ethernet) followed by the interface's current MAC, so a
+// randomized MAC gives a new identifier too (RFC 7844 3.5).
+func (m *Manager) clientID(iface string) (net.HardwareAddr, error) {
+	lookup := m.hardwareAddr
+	if lookup == nil {
+		lookup = func(name string) (net.HardwareAddr, error) {
+			ifi, err := net.InterfaceByName(name)
+			if err != nil {
+				return nil, err
+			}
+			return ifi.HardwareAddr, nil
+		}
+	}
+	mac, err := lookup(iface)
+	if err != nil {
+		return nil, fmt.Errorf("reading MAC of %s for the client identifier: %w", iface, err)
+	}
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("%s has no Ethernet MAC for the client identifier", iface)
+	}
+	return append(net.HardwareAddr{1}, mac...), nil
+}
+
 // getUdhcpcTimeout returns the configured timeout or the default
 func (m *Manager) getUdhcpcTimeout() time.Duration {
 	if m.dhcpTimeout > 0 {
@@ -217,10 +269,12 @@ func (m *Manager) Release(iface string) error {
 		m.logger.Debug("No dhclient process to kill", "interface", iface)
 	}
 
-	// Clean up lease files
+	// Clean up lease files and the udhcpc wrapper's
 	leaseFiles := []string{
 		"/var/lib/dhcp/dhclient." + iface + ".leases",
-		m.runDir() + "/dhclient." + iface + ".leases",
+		m.dhclientLeaseFile(iface),
+		m.udhcpcScript(iface),
+		m.udhcpcDomainFile(iface),
 	}
 	for _, f := range leaseFiles {
 		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
@@ -253,6 +307,75 @@ func (m *Manager) udhcpcPidFile(iface string) string {
 	return m.runDir() + "/udhcpc." + iface + ".pid"
 }
 
+// udhcpcScript returns the path of the udhcpc script wrapper for iface.
+func (m *Manager) udhcpcScript(iface string) string {
+	return m.runDir() + "/udhcpc." + iface + ".script"
+}
+
+// udhcpcDomainFile returns the file the udhcpc script wrapper records the
+// lease's domain name in.
+func (m *Manager) udhcpcDomainFile(iface string) string {
+	return m.runDir() + "/udhcpc." + iface + ".domain"
+}
+
+// dhclientLeaseFile returns the lease database dhclient keeps for iface.
+func (m *Manager) dhclientLeaseFile(iface string) string {
+	return m.runDir() + "/dhclient." + iface + ".leases"
+}
+
+// writeUdhcpcScript writes a script that records the domain name (option
+// 15) of each lease before handing over to the default script, which applies
+// the lease. Returns "" when there is no default script to wrap.
+func (m *Manager) writeUdhcpcScript(iface string) (string, error) {
+	if _, err := os.Stat(defaultUdhcpcScript); err != nil {
+		return "", nil
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+# Written by net: records the lease's domain name, then applies the lease.
+case "$1" in
+bound|renew) printf '%%s\n' "$domain" > '%s' ;;
+deconfig) rm -f '%s' ;;
+esac
+exec '%s' "$@"
+`, m.udhcpcDomainFile(iface), m.udhcpcDomainFile(iface), defaultUdhcpcScript)
+	path := m.udhcpcScript(iface)
+	if err := system.WriteSecureFile(path, script); err != nil {
+		return "", err
+	}
+	return path, os.Chmod(path, 0700)
+}
+
+// Domain returns the domain name (option 15) of the interface's current
+// lease, or "" when there is none. It comes from the DHCP client itself, not
+// resolv.conf, which net overwrites when it sets DNS.
+func (m *Manager) Domain(iface string) string {
+	if data, err := os.ReadFile(m.udhcpcDomainFile(iface)); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	data, err := os.ReadFile(m.dhclientLeaseFile(iface))
+	if err != nil {
+		return ""
+	}
+	return parseLeaseDomain(string(data))
+}
+
+// parseLeaseDomain returns the domain-name option of the last lease in a
+// dhclient lease database; dhclient appends each new lease.
+func parseLeaseDomain(leases string) string {
+	var domain string
+	for _, line := range strings.Split(leases, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "lease {":
+			domain = ""
+		case strings.HasPrefix(line, "option domain-name "):
+			value := strings.TrimPrefix(line, "option domain-name ")
+			domain = strings.Trim(strings.TrimSuffix(value, ";"), `"`)
+		}
+	}
+	return domain
+}
+
 // acquireUdhcpc uses udhcpc (BusyBox) for DHCP acquisition.
 // udhcpc daemonizes after obtaining the lease and stays alive to handle
 // renewals. The PID is written to /run/net/udhcpc.<iface>.pid so Release
@@ -281,13 +404,28 @@ func (m *Manager) acquireUdhcpc(iface string, hostname string) error {
 		"-T", fmt.Sprintf("%d", UdhcpcDiscoverTimeout),
 		"-A", fmt.Sprintf("%d", UdhcpcTryAgain),
 	}
-	if hostname != "" {
+	// -s: wrap the default script to record the lease's domain name
+	script, err := m.writeUdhcpcScript(iface)
+	if err != nil {
+		return fmt.Errorf("failed to write the udhcpc script: %w", err)
+	}
+	if script != "" {
+		args = append(args, "-s", script)
+	}
+	switch {
+	case m.options.Anonymous:
+		anonArgs, err := m.anonymousUdhcpcArgs(iface)
+		if err != nil {
+			return err
+		}
+		m.logger.Info("Using the DHCP anonymity profile", "interface", iface)
+		args = append(args, anonArgs...)
+	case hostname != "" && !m.options.NoHostname:
 		m.logger.Info("Sending hostname in DHCP request", "hostname", hostname)
 		args = append(args, "-x", "hostname:"+hostname)
 	}
 
-	_, err := m.executor.ExecuteWithTimeout(m.getUdhcpcTimeout(), "udhcpc", args...)
-	if err != nil {
+	if _, err := m.executor.ExecuteWithTimeout(m.getUdhcpcTimeout(), "udhcpc", args...); err != nil {
 		// Clean up any partial state on failure
 		m.Release(iface)
 		return fmt.Errorf("udhcpc failed: %w", err)
@@ -297,6 +435,39 @@ func (m *Manager) acquireUdhcpc(iface string, hostname string) error {
 	return nil
 }
 
+// anonymousUdhcpcArgs returns the udhcpc options of the anonymity profile:
+// -C and -x 0x3d replace BusyBox's default client identifier with ours, an
+// empty -V drops the "udhcp <version>" vendor class, and -o with -O
+// requests exactly anonymousRequestList. BusyBox sends no hostname unless
+// asked to.
+func (m *Manager) anonymousUdhcpcArgs(iface string) ([]string, error) {
+	id, err := m.clientID(iface)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-C", "-x", "0x3d:" + strings.ReplaceAll(id.String(), ":", ""), "-V", "", "-o"}
+	for _, opt := range anonymousRequestList {
+		args = append(args, "-O", opt.udhcpc)
+	}
+	return args, nil
+}
+
+// anonymousDhclientConf returns a dhclient.conf for the anonymity profile.
+// It replaces the system config, so nothing else (hostname, vendor class,
+// extra requests) is sent.
+func (m *Manager) anonymousDhclientConf(iface string) (string, error) {
+	id, err := m.clientID(iface)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(anonymousRequestList))
+	for i, opt := range anonymousRequestList {
+		names[i] = opt.dhclient
+	}
+	return fmt.Sprintf("# RFC 7844 anonymity profile\nsend dhcp-client-identifier %s;\nrequest %s;\n",
+		id.String(), strings.Join(names, ", ")), nil
+}
+
 // acquireDhclient uses dhclient (ISC) as fallback
 func (m *Manager) acquireDhclient(iface string, hostname string) error {
 	// Release any existing clients first
@@ -308,7 +479,18 @@ func (m *Manager) acquireDhclient(iface string, hostname string) error {
 	// renewal daemon alive for lease renewal).
 	dhclientTimeout := m.getDhclientTimeout()
 	args := []string{fmt.Sprintf("%d", int(dhclientTimeout.Seconds())), "dhclient", "-v", "-1"}
-	if hostname != "" {
+	if m.options.Anonymous {
+		confContent, err := m.anonymousDhclientConf(iface)
+		if err != nil {
+			return err
+		}
+		m.logger.Info("Using the DHCP anonymity profile", "interface", iface)
+		dhclientConf := m.runDir() + "/dhclient." + iface + ".conf"
+		if err := system.WriteSecureFile(dhclientConf, confContent); err != nil {
+			return fmt.Errorf("failed to create dhclient config for the anonymity profile: %w", err)
+		}
+		args = append(args, "-cf", dhclientConf)
+	} else if hostname != "" && !m.options.NoHostname {
 		m.logger.Info("Sending hostname in DHCP request", "hostname", hostname)
 		// Create interface-specific dhclient.conf to avoid race conditions
 		// with concurrent DHCP operations on different interfaces
@@ -319,8 +501,17 @@ func (m *Manager) acquireDhclient(iface string, hostname string) error {
 			return fmt.Errorf("failed to create dhclient config for hostname: %w", err)
 		}
 		args = append(args, "-cf", dhclientConf)
+	} else if m.options.NoHostname {
+		// The system dhclient.conf usually sends the system hostname
+		// (send host-name = gethostname()); use a config that sends none.
+		dhclientConf := m.runDir() + "/dhclient." + iface + ".conf"
+		if err := system.WriteSecureFile(dhclientConf, "# no host-name: hostname omitted\n"); err != nil {
+			return fmt.Errorf("failed to create dhclient config without hostname: %w", err)
+		}
+		args = append(args, "-cf", dhclientConf)
 	}
-	args = append(args, iface)
+	// -lf: a lease database of our own, which Domain reads
+	args = append(args, "-lf", m.dhclientLeaseFile(iface), iface)
 
 	// Start dhclient with timeout wrapper. The -1 flag ensures dhclient
 	// exits after the first attempt (success or fail) rather than retrying
@@ -3,12 +3,15 @@ package dhcpclient
 import (
 	"context"
 	"errors"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/angelfreak/net/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -154,7 +157,7 @@ func TestAcquire_ValidatesInterfaceName(t *testing.T) {
 			executor.commands["pkill -9 -f dhclient.*"+tt.iface] = ""
 			executor.commands["rm -f /var/lib/dhcp/dhclient."+tt.iface+".leases /run/net/dhclient."+tt.iface+".leases"] = ""
 			executor.commands["rm -f /run/net/dhclient."+tt.iface+".conf"] = ""
-			executor.commands["timeout 15 dhclient -v -1 "+tt.iface] = ""
+			executor.commands["timeout 15 dhclient -v -1 -lf /run/net/dhclient."+tt.iface+".leases "+tt.iface] = ""
 			executor.commands["ip addr show "+tt.iface] = "inet 192.168.1.50/24"
 			logger := &mockLogger{}
 			manager := NewManager(executor, logger)
@@ -195,8 +198,8 @@ func TestAcquire_ValidatesHostname(t *testing.T) {
 			executor.commands["pkill -9 -f dhclient.*wlan0"] = ""
 			executor.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases /run/net/dhclient.wlan0.leases"] = ""
 			executor.commands["rm -f /run/net/dhclient.wlan0.conf"] = ""
-			executor.commands["timeout 60 dhclient -v -1 wlan0"] = ""
-			executor.commands["timeout 60 dhclient -v -1 -cf /run/net/dhclient.wlan0.conf wlan0"] = ""
+			executor.commands["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"] = ""
+			executor.commands["timeout 60 dhclient -v -1 -cf /run/net/dhclient.wlan0.conf -lf /run/net/dhclient.wlan0.leases wlan0"] = ""
 			executor.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
 			logger := &mockLogger{}
 			manager := NewManager(executor, logger)
@@ -242,14 +245,14 @@ func TestAcquire_UsesDhclientAsFallback(t *testing.T) {
 	executor.commands["pkill -9 -f dhclient.*wlan0"] = ""
 	executor.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases /run/net/dhclient.wlan0.leases"] = ""
 	executor.commands["rm -f /run/net/dhclient.wlan0.conf"] = ""
-	executor.commands["timeout 60 dhclient -v -1 wlan0"] = ""
+	executor.commands["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"] = ""
 	executor.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger)
 
 	err := manager.Acquire("wlan0", "")
 	assert.NoError(t, err)
-	executor.assertCommandExecuted(t, "dhclient -v -1 wlan0")
+	executor.assertCommandExecuted(t, "dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0")
 }
 
 // The executor deadline for the `timeout N dhclient ...` wrapper must exceed
@@ -259,14 +262,14 @@ func TestAcquireDhclient_ExecutorDeadlineExceedsInnerTimeout(t *testing.T) {
 	executor := newMockExecutor()
 	executor.hasCommands["udhcpc"] = false
 	executor.hasCommands["dhclient"] = true
-	executor.commands["timeout 60 dhclient -v -1 wlan0"] = ""
+	executor.commands["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"] = ""
 	executor.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
 	manager := NewManager(executor, &mockLogger{})
 
 	err := manager.Acquire("wlan0", "")
 	assert.NoError(t, err)
 
-	got := executor.timeoutByCmd["timeout 60 dhclient -v -1 wlan0"]
+	got := executor.timeoutByCmd["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"]
 	assert.Greater(t, got, DhclientTimeout,
 		"executor deadline must exceed the inner dhclient timeout so the wrapper isn't SIGKILLed early")
 }
@@ -298,7 +301,7 @@ func TestAcquire_WithHostname_Dhclient(t *testing.T) {
 	executor.commands["pkill -9 -f dhclient.*wlan0"] = ""
 	executor.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases "+tmp+"/dhclient.wlan0.leases"] = ""
 	executor.commands["rm -f "+conf] = ""
-	executor.commands["timeout 60 dhclient -v -1 -cf "+conf+" wlan0"] = ""
+	executor.commands["timeout 60 dhclient -v -1 -cf "+conf+" -lf "+tmp+"/dhclient.wlan0.leases wlan0"] = ""
 	executor.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger)
@@ -314,6 +317,90 @@ func TestAcquire_WithHostname_Dhclient(t *testing.T) {
 	assert.Contains(t, string(data), `send host-name "myhost";`)
 }
 
+func TestAcquire_NoHostname(t *testing.T) {
+	t.Run("udhcpc sends no hostname", func(t *testing.T) {
+		executor := newMockExecutor()
+		executor.hasCommands["udhcpc"] = true
+		manager := NewManager(executor, &mockLogger{})
+		manager.runtimeDir = t.TempDir()
+		manager.SetOptions(types.DHCPClientOptions{NoHostname: true})
+
+		assert.NoError(t, manager.Acquire("wlan0", "myhost"))
+		executor.assertCommandExecuted(t, "udhcpc -i wlan0")
+		executor.assertCommandNotExecuted(t, "hostname:")
+	})
+
+	t.Run("dhclient uses a config without host-name", func(t *testing.T) {
+		tmp := t.TempDir()
+		conf := tmp + "/dhclient.eth0.conf"
+		executor := newMockExecutor()
+		executor.hasCommands["dhclient"] = true
+		manager := NewManager(executor, &mockLogger{})
+		manager.runtimeDir = tmp
+		manager.SetOptions(types.DHCPClientOptions{NoHostname: true})
+
+		assert.NoError(t, manager.Acquire("eth0", ""))
+		// Without -cf, dhclient would read the system config, which
+		// usually sends the system hostname.
+		executor.assertCommandExecuted(t, "-cf "+conf)
+		data, err := os.ReadFile(conf)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "send host-name")
+	})
+}
+
+func TestAcquire_Anonymous(t *testing.T) {
+	mac := func(string) (net.HardwareAddr, error) {
+		return net.HardwareAddr{0x02, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}, nil
+	}
+
+	t.Run("udhcpc sends exactly the RFC 7844 option set", func(t *testing.T) {
+		executor := newMockExecutor()
+		executor.hasCommands["udhcpc"] = true
+		manager := NewManager(executor, &mockLogger{})
+		manager.runtimeDir = t.TempDir()
+		manager.hardwareAddr = mac
+		manager.SetOptions(types.DHCPClientOptions{NoHostname: true, Anonymous: true})
+
+		assert.NoError(t, manager.Acquire("wlan0", "myhost"))
+		want := "udhcpc -i wlan0 -n -p " + manager.udhcpcPidFile("wlan0") + " -R -B -t 6 -T 3 -A 10" +
+			" -C -x 0x3d:01021a2b3c4d5e -V  -o -O subnet -O router -O dns -O domain -O broadcast"
+		assert.Contains(t, executor.executedCmds, want)
+	})
+
+	t.Run("dhclient config sends exactly the RFC 7844 option set", func(t *testing.T) {
+		tmp := t.TempDir()
+		conf := tmp + "/dhclient.eth0.conf"
+		executor := newMockExecutor()
+		executor.hasCommands["dhclient"] = true
+		manager := NewManager(executor, &mockLogger{})
+		manager.runtimeDir = tmp
+		manager.hardwareAddr = mac
+		manager.SetOptions(types.DHCPClientOptions{NoHostname: true, Anonymous: true})
+
+		assert.NoError(t, manager.Acquire("eth0", "myhost"))
+		executor.assertCommandExecuted(t, "timeout 60 dhclient -v -1 -cf "+conf+" -lf "+tmp+"/dhclient.eth0.leases eth0")
+		data, err := os.ReadFile(conf)
+		assert.NoError(t, err)
+		assert.Equal(t, "# RFC 7844 anonymity profile\n"+
+			"send dhcp-client-identifier 01:02:1a:2b:3c:4d:5e;\n"+
+			"request subnet-mask, routers, domain-name-servers, domain-name, broadcast-address;\n", string(data))
+	})
+
+	t.Run("no MAC fails before anything is sent", func(t *testing.T) {
+		executor := newMockExecutor()
+		executor.hasCommands["udhcpc"] = true
+		manager := NewManager(executor, &mockLogger{})
+		manager.runtimeDir = t.TempDir()
+		manager.hardwareAddr = func(string) (net.HardwareAddr, error) { return nil, nil }
+		manager.SetOptions(types.DHCPClientOptions{Anonymous: true})
+
+		err := manager.Acquire("wg0", "")
+		assert.ErrorContains(t, err, "no Ethernet MAC")
+		executor.assertCommandNotExecuted(t, "udhcpc -i")
+	})
+}
+
 func TestAcquire_InterfaceSpecificConfigPath(t *testing.T) {
 	// Verify different interfaces use different config files (no race condition)
 	tmp1 := t.TempDir()
@@ -325,7 +412,7 @@ func TestAcquire_InterfaceSpecificConfigPath(t *testing.T) {
 	executor1.commands["pkill -9 -f dhclient.*eth0"] = ""
 	executor1.commands["rm -f /var/lib/dhcp/dhclient.eth0.leases "+tmp1+"/dhclient.eth0.leases"] = ""
 	executor1.commands["rm -f "+conf1] = ""
-	executor1.commands["timeout 60 dhclient -v -1 -cf "+conf1+" eth0"] = ""
+	executor1.commands["timeout 60 dhclient -v -1 -cf "+conf1+" -lf "+tmp1+"/dhclient.eth0.leases eth0"] = ""
 	executor1.commands["ip addr show eth0"] = "inet 10.0.0.50/24"
 	logger1 := &mockLogger{}
 	manager1 := NewManager(executor1, logger1)
@@ -340,7 +427,7 @@ func TestAcquire_InterfaceSpecificConfigPath(t *testing.T) {
 	executor2.commands["pkill -9 -f dhclient.*wlan0"] = ""
 	executor2.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases "+tmp2+"/dhclient.wlan0.leases"] = ""
 	executor2.commands["rm -f "+conf2] = ""
-	executor2.commands["timeout 15 dhclient -v -1 -cf "+conf2+" wlan0"] = ""
+	executor2.commands["timeout 15 dhclient -v -1 -cf "+conf2+" -lf "+tmp2+"/dhclient.wlan0.leases wlan0"] = ""
 	executor2.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
 	logger2 := &mockLogger{}
 	manager2 := NewManager(executor2, logger2)
@@ -365,7 +452,7 @@ func TestAcquire_DhcpClientFails(t *testing.T) {
 	executor.commands["pkill -9 -f dhclient.*wlan0"] = ""
 	executor.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases /run/net/dhclient.wlan0.leases"] = ""
 	executor.commands["rm -f /run/net/dhclient.wlan0.conf"] = ""
-	executor.errors["timeout 60 dhclient -v -1 wlan0"] = errors.New("dhclient: no lease obtained")
+	executor.errors["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"] = errors.New("dhclient: no lease obtained")
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger)
 
@@ -374,6 +461,63 @@ func TestAcquire_DhcpClientFails(t *testing.T) {
 	assert.Contains(t, err.Error(), "dhclient failed")
 }
 
+// udhcpc runs the default script through a wrapper that records the
+// lease's domain name for Domain.
+func TestAcquire_UdhcpcRecordsDomain(t *testing.T) {
+	tmp := t.TempDir()
+	orig := defaultUdhcpcScript
+	defaultUdhcpcScript = filepath.Join(tmp, "default.script")
+	t.Cleanup(func() { defaultUdhcpcScript = orig })
+	assert.NoError(t, os.WriteFile(defaultUdhcpcScript, []byte("#!/bin/sh\n"), 0o700))
+
+	executor := newMockExecutor()
+	executor.hasCommands["udhcpc"] = true
+	manager := NewManager(executor, &mockLogger{})
+	manager.runtimeDir = tmp
+
+	assert.NoError(t, manager.Acquire("wlan0", ""))
+	script := tmp + "/udhcpc.wlan0.script"
+	executor.assertCommandExecuted(t, "-s "+script)
+	info, err := os.Stat(script)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	}
+
+	// Run the wrapper as udhcpc would after a lease
+	cmd := exec.Command(script, "bound")
+	cmd.Env = append(os.Environ(), "domain=corp.example.com")
+	assert.NoError(t, cmd.Run())
+	assert.Equal(t, "corp.example.com", manager.Domain("wlan0"))
+
+	assert.NoError(t, manager.Release("wlan0"))
+	assert.Empty(t, manager.Domain("wlan0"))
+	assert.NoFileExists(t, script)
+}
+
+func TestDomain_DhclientLease(t *testing.T) {
+	tmp := t.TempDir()
+	manager := NewManager(newMockExecutor(), &mockLogger{})
+	manager.runtimeDir = tmp
+	assert.Empty(t, manager.Domain("eth0"))
+
+	leases := `lease {
+  interface "eth0";
+  option domain-name "old.example.com";
+}
+lease {
+  interface "eth0";
+  fixed-address 10.0.0.5;
+  option domain-name "corp.example.com";
+  option domain-name-servers 10.0.0.1;
+}
+`
+	assert.NoError(t, os.WriteFile(tmp+"/dhclient.eth0.leases", []byte(leases), 0o600))
+	assert.Equal(t, "corp.example.com", manager.Domain("eth0"), "the last lease is the current one")
+
+	assert.NoError(t, os.WriteFile(tmp+"/dhclient.eth0.leases", []byte(leases+"lease {\n  interface \"eth0\";\n}\n"), 0o600))
+	assert.Empty(t, manager.Domain("eth0"), "a lease without the option has no domain")
+}
+
 // Tests for Release
 
 func TestRelease_ValidatesInterfaceName(t *testing.T) {
@@ -473,14 +617,14 @@ func TestRenew_DelegatesToAcquire(t *testing.T) {
 	executor.commands["pkill -9 -f dhclient.*wlan0"] = ""
 	executor.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases /run/net/dhclient.wlan0.leases"] = ""
 	executor.commands["rm -f /run/net/dhclient.wlan0.conf"] = ""
-	executor.commands["timeout 60 dhclient -v -1 wlan0"] = ""
+	executor.commands["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"] = ""
 	executor.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger)
 
 	err := manager.Renew("wlan0", "")
 	assert.NoError(t, err)
-	executor.assertCommandExecuted(t, "dhclient -v -1 wlan0")
+	executor.assertCommandExecuted(t, "dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0")
 }
 
 // Tests for parseIPAddress
@@ -595,7 +739,7 @@ func TestAcquire_CleansUpOnDhclientFailure(t *testing.T) {
 	executor.commands["rm -f /var/lib/dhcp/dhclient.wlan0.leases"] = ""
 	executor.commands["rm -f /run/net/dhclient.wlan0.leases"] = ""
 	executor.commands["rm -f /run/net/dhclient.wlan0.conf"] = ""
-	executor.errors["timeout 60 dhclient -v -1 wlan0"] = errors.New("no lease obtained")
+	executor.errors["timeout 60 dhclient -v -1 -lf /run/net/dhclient.wlan0.leases wlan0"] = errors.New("no lease obtained")
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger)
 
@@ -9,16 +9,35 @@ import (
 var _ types.FirewallManager = (*Manager)(nil)
 
 // Manager is an in-memory fake implementation of types.FirewallManager. It
-// records EnableNAT/DisableNAT calls and tracks which (internal,out) pairs are
-// currently enabled. Set the *Err fields to force a method to fail.
+// records EnableNAT/DisableNAT calls and the kill switch, inbound filter,
+// name-broadcast block and forward restrictions currently in place. Set the
+// *Err fields to force a method to fail.
 type Manager struct {
 	// Enabled records every EnableNAT call in order.
 	Enabled []NATCall
 	// Disabled records every DisableNAT call in order.
 	Disabled []NATCall
 
+	// KillSwitch is the interface the kill switch is enabled on ("" when
+	// off), and KillSwitchAllowed the addresses it lets through.
+	KillSwitch        string
+	KillSwitchAllowed []string
+	// Inbound is the interface inbound connections are blocked on ("" when
+	// off).
+	Inbound string
+	// Names is the interface name broadcasts are blocked on ("" when off).
+	Names string
+	// Restricted maps each internal interface with a forward restriction to
+	// the interface its traffic may leave through.
+	Restricted map[string]string
+
 	EnableErr  error
 	DisableErr error
+	// PolicyErr makes EnableKillSwitch, BlockInbound and
+	// BlockNameBroadcasts fail.
+	PolicyErr error
+	// RestrictErr makes RestrictForward fail.
+	RestrictErr error
 }
 
 // NATCall records the arguments of a single EnableNAT/DisableNAT invocation.
@@ -44,3 +63,66 @@ func (m *Manager) DisableNAT(internalIface, outIface string) error {
 	m.Disabled = append(m.Disabled, NATCall{Internal: internalIface, Out: outIface})
 	return nil
 }
+
+// EnableKillSwitch records the kill switch.
+func (m *Manager) EnableKillSwitch(iface string, allowed []string) error {
+	if m.PolicyErr != nil {
+		return m.PolicyErr
+	}
+	m.KillSwitch, m.KillSwitchAllowed = iface, allowed
+	return nil
+}
+
+// DisableKillSwitch clears the kill switch.
+func (m *Manager) DisableKillSwitch() error {
+	m.KillSwitch, m.KillSwitchAllowed = "", nil
+	return nil
+}
+
+// BlockInbound records the inbound filter.
+func (m *Manager) BlockInbound(iface string) error {
+	if m.PolicyErr != nil {
+		return m.PolicyErr
+	}
+	m.Inbound = iface
+	return nil
+}
+
+// UnblockInbound clears the inbound filter.
+func (m *Manager) UnblockInbound() error {
+	m.Inbound = ""
+	return nil
+}
+
+// BlockNameBroadcasts records the name-broadcast block.
+func (m *Manager) BlockNameBroadcasts(iface string) error {
+	if m.PolicyErr != nil {
+		return m.PolicyErr
+	}
+	m.Names = iface
+	return nil
+}
+
+// UnblockNameBroadcasts clears the name-broadcast block.
+func (m *Manager) UnblockNameBroadcasts() error {
+	m.Names = ""
+	return nil
+}
+
+// RestrictForward records the forward restriction.
+func (m *Manager) RestrictForward(internalIface, outIface string) error {
+	if m.RestrictErr != nil {
+		return m.RestrictErr
+	}
+	if m.Restricted == nil {
+		m.Restricted = make(map[string]string)
+	}
+	m.Restricted[internalIface] = outIface
+	return nil
+}
+
+// UnrestrictForward clears the forward restriction.
+func (m *Manager) UnrestrictForward(internalIface string) error {
+	delete(m.Restricted, internalIface)
+	return nil
+}
@@ -1,6 +1,7 @@
 // Package firewall configures the IPv4 NAT/forwarding rules for internet
-// sharing (hotspot and DHCP server) via github.com/coreos/go-iptables, replacing
-// hand-built `iptables` command lines.
+// sharing (hotspot and DHCP server), and the kill switch and inbound filter of
+// trust policies, via github.com/coreos/go-iptables, replacing hand-built
+// `iptables` command lines.
 package firewall
 
 import (
@@ -17,6 +18,9 @@ var _ types.FirewallManager = (*Manager)(nil)
 // Manager is the go-iptables-backed implementation of types.FirewallManager.
 type Manager struct {
 	ipt *iptables.IPTables
+	// ip6t is nil when ip6tables is unavailable; the kill switch and inbound
+	// filter then cover IPv4 only.
+	ip6t *iptables.IPTables
 }
 
 // New returns a FirewallManager, or an error if iptables is unavailable. The
@@ -27,7 +31,11 @@ func New() (*Manager, error) {
 	if err != nil {
 		return nil, fmt.Errorf("initializing iptables: %w", err)
 	}
-	return &Manager{ipt: ipt}, nil
+	ip6t, err := iptables.NewWithProtocol(iptables.ProtocolIPv6)
+	if err != nil {
+		ip6t = nil
+	}
+	return &Manager{ipt: ipt, ip6t: ip6t}, nil
 }
 
 type natRule struct {
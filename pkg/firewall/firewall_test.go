@@ -55,3 +55,83 @@ func containsPair(s []string, a, b string) bool {
 	}
 	return false
 }
+
+func TestKillSwitchRules(t *testing.T) {
+	allowed := []string{"198.51.100.7", "2001:db8::7", "not-an-ip"}
+
+	v4 := killSwitchRules("wlan0", allowed, false)
+	if !containsPair(v4[0], "-o", "wlan0") || !contains(v4[0], "!") || !contains(v4[0], "RETURN") {
+		t.Errorf("first rule = %v, want traffic not leaving wlan0 to pass", v4[0])
+	}
+	if last := v4[len(v4)-1]; !contains(last, "REJECT") {
+		t.Errorf("last rule = %v, want REJECT", last)
+	}
+	if !hasRule(v4, "--dport", "67") {
+		t.Errorf("rules %v don't let DHCP out", v4)
+	}
+	if !hasRule(v4, "-d", "198.51.100.7") || hasRule(v4, "-d", "2001:db8::7") {
+		t.Errorf("IPv4 rules %v should allow only the IPv4 endpoint", v4)
+	}
+
+	v6 := killSwitchRules("wlan0", allowed, true)
+	if !hasRule(v6, "-d", "2001:db8::7") || hasRule(v6, "-d", "198.51.100.7") {
+		t.Errorf("IPv6 rules %v should allow only the IPv6 endpoint", v6)
+	}
+	if !hasRule(v6, "-p", "ipv6-icmp") {
+		t.Errorf("IPv6 rules %v must allow ICMPv6 for neighbor discovery", v6)
+	}
+}
+
+func TestInboundRules(t *testing.T) {
+	v4 := inboundRules("eth0", false)
+	if !containsPair(v4[0], "-i", "eth0") || !contains(v4[0], "!") {
+		t.Errorf("first rule = %v, want traffic not arriving on eth0 to pass", v4[0])
+	}
+	if !hasRule(v4, "--ctstate", "ESTABLISHED,RELATED") || !hasRule(v4, "--dport", "68") {
+		t.Errorf("rules %v must let replies and DHCP in", v4)
+	}
+	if last := v4[len(v4)-1]; !contains(last, "DROP") {
+		t.Errorf("last rule = %v, want DROP", last)
+	}
+	if v6 := inboundRules("eth0", true); !hasRule(v6, "-p", "ipv6-icmp") || !hasRule(v6, "--dport", "546") {
+		t.Errorf("IPv6 rules %v must let ICMPv6 and DHCPv6 in", v6)
+	}
+}
+
+func TestNameRules(t *testing.T) {
+	v4 := nameRules("wlan0", false)
+	if !containsPair(v4[0], "-o", "wlan0") || !contains(v4[0], "!") {
+		t.Errorf("first rule = %v, want traffic not leaving through wlan0 to pass", v4[0])
+	}
+	if !hasRule(v4, "--ports", "5353,5355,137,138") || !hasRule(v4, "--sport", "5355") {
+		t.Errorf("rules %v must drop mDNS, LLMNR and NetBIOS", v4)
+	}
+	if v6 := nameRules("wlan0", true); !hasRule(v6, "--ports", "5353,5355") {
+		t.Errorf("IPv6 rules %v must drop mDNS and LLMNR", v6)
+	}
+}
+
+func hasRule(rules [][]string, a, b string) bool {
+	for _, r := range rules {
+		if containsPair(r, a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestShareRules(t *testing.T) {
+	rules := shareRules("wlan0", "wg0")
+	if !containsPair(rules[0], "-i", "wlan0") || !contains(rules[0], "!") {
+		t.Errorf("first rule = %v, want traffic not from wlan0 to pass", rules[0])
+	}
+	if !containsPair(rules[1], "-o", "wg0") || !containsPair(rules[1], "-j", "RETURN") {
+		t.Errorf("second rule = %v, want traffic leaving through wg0 to pass", rules[1])
+	}
+	if last := rules[len(rules)-1]; !containsPair(last, "-j", "REJECT") {
+		t.Errorf("last rule = %v, want everything else rejected", last)
+	}
+	if chain := shareChain("abcdefghijklmno"); len(chain) > 28 {
+		t.Errorf("chain %q exceeds iptables' 28 character limit", chain)
+	}
+}
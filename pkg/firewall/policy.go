@@ -0,0 +1,230 @@
+package firewall
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coreos/go-iptables/iptables"
+)
+
+// Chains holding the trust-policy rules. Keeping them in their own chains,
+// jumped to from OUTPUT/INPUT, lets a policy be replaced or removed as a
+// whole without knowing which interface it was installed for.
+const (
+	killSwitchChain = "NETOP-KILLSWITCH"
+	inboundChain    = "NETOP-INBOUND"
+	namesChain      = "NETOP-NAMES"
+)
+
+// shareChainPrefix prefixes the per-interface chains restricting where
+// shared clients' traffic may be forwarded to, so a hotspot and a DHCP server
+// can each have their own.
+const shareChainPrefix = "NETOP-SHARE-"
+
+// shareChain returns the forward restriction chain for internalIface.
+func shareChain(internalIface string) string {
+	return shareChainPrefix + internalIface
+}
+
+// shareRules returns the rules of the forward restriction chain: traffic not
+// arriving on internalIface is left alone, traffic leaving through outIface
+// passes, everything else is rejected.
+func shareRules(internalIface, outIface string) [][]string {
+	return [][]string{
+		{"!", "-i", internalIface, "-j", "RETURN"},
+		{"-o", outIface, "-j", "RETURN"},
+		{"-j", "REJECT"},
+	}
+}
+
+// killSwitchRules returns the rules of the kill switch chain for one address
+// family: traffic not leaving through iface is left alone, DHCP and traffic
+// to the allowed addresses of that family pass, everything else is rejected.
+func killSwitchRules(iface string, allowed []string, ipv6 bool) [][]string {
+	rules := [][]string{
+		{"!", "-o", iface, "-j", "RETURN"},
+	}
+	if ipv6 {
+		rules = append(rules, []string{"-p", "udp", "--dport", "547", "-j", "RETURN"})
+		rules = append(rules, []string{"-p", "ipv6-icmp", "-j", "RETURN"})
+	} else {
+		rules = append(rules, []string{"-p", "udp", "--dport", "67", "-j", "RETURN"})
+	}
+	for _, addr := range allowed {
+		ip := net.ParseIP(addr)
+		if ip == nil || (ip.To4() == nil) != ipv6 {
+			continue
+		}
+		rules = append(rules, []string{"-d", ip.String(), "-j", "RETURN"})
+	}
+	return append(rules, []string{"-j", "REJECT"})
+}
+
+// inboundRules returns the rules of the inbound filter chain for one address
+// family: traffic not arriving on iface is left alone, replies and DHCP pass
+// (and ICMPv6, which IPv6 needs for neighbor discovery), the rest is dropped.
+func inboundRules(iface string, ipv6 bool) [][]string {
+	rules := [][]string{
+		{"!", "-i", iface, "-j", "RETURN"},
+		{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "RETURN"},
+	}
+	if ipv6 {
+		rules = append(rules,
+			[]string{"-p", "udp", "--dport", "546", "-j", "RETURN"},
+			[]string{"-p", "ipv6-icmp", "-j", "RETURN"},
+		)
+	} else {
+		rules = append(rules, []string{"-p", "udp", "--dport", "68", "-j", "RETURN"})
+	}
+	return append(rules, []string{"-j", "DROP"})
+}
+
+// nameRules returns the rules of the name-broadcast chain for one address
+// family: traffic not leaving through iface is left alone, mDNS (5353) and
+// LLMNR (5355) queries and answers are dropped, and for IPv4 NetBIOS name
+// and datagram traffic (137, 138) too.
+func nameRules(iface string, ipv6 bool) [][]string {
+	ports := "5353,5355,137,138"
+	if ipv6 {
+		ports = "5353,5355"
+	}
+	return [][]string{
+		{"!", "-o", iface, "-j", "RETURN"},
+		{"-p", "udp", "-m", "multiport", "--ports", ports, "-j", "DROP"},
+		{"-p", "tcp", "--sport", "5355", "-j", "DROP"},
+	}
+}
+
+// families returns the iptables handles to install policy rules with.
+func (m *Manager) families() []*iptables.IPTables {
+	if m.ip6t == nil {
+		return []*iptables.IPTables{m.ipt}
+	}
+	return []*iptables.IPTables{m.ipt, m.ip6t}
+}
+
+// installChain (re)fills chain with rules and makes parent jump to it.
+func installChain(ipt *iptables.IPTables, parent, chain string, rules [][]string) error {
+	// ClearChain creates the chain when it doesn't exist yet.
+	if err := ipt.ClearChain("filter", chain); err != nil {
+		return fmt.Errorf("preparing %s chain: %w", chain, err)
+	}
+	for _, rule := range rules {
+		if err := ipt.Append("filter", chain, rule...); err != nil {
+			return fmt.Errorf("adding %s rule: %w", chain, err)
+		}
+	}
+	if err := ipt.InsertUnique("filter", parent, 1, "-j", chain); err != nil {
+		return fmt.Errorf("hooking %s into %s: %w", chain, parent, err)
+	}
+	return nil
+}
+
+// removeChain unhooks chain from parent and deletes it, tolerating either
+// being absent.
+func removeChain(ipt *iptables.IPTables, parent, chain string) error {
+	exists, err := ipt.ChainExists("filter", chain)
+	if err != nil {
+		return fmt.Errorf("checking %s chain: %w", chain, err)
+	}
+	if !exists {
+		return nil
+	}
+	if err := ipt.DeleteIfExists("filter", parent, "-j", chain); err != nil {
+		return fmt.Errorf("unhooking %s from %s: %w", chain, parent, err)
+	}
+	if err := ipt.ClearAndDeleteChain("filter", chain); err != nil {
+		return fmt.Errorf("removing %s chain: %w", chain, err)
+	}
+	return nil
+}
+
+// EnableKillSwitch installs the kill switch for iface in both address
+// families. On failure the partially installed kill switch is removed again
+// rather than left half-applied.
+func (m *Manager) EnableKillSwitch(iface string, allowed []string) error {
+	for _, ipt := range m.families() {
+		rules := killSwitchRules(iface, allowed, ipt.Proto() == iptables.ProtocolIPv6)
+		if err := installChain(ipt, "OUTPUT", killSwitchChain, rules); err != nil {
+			_ = m.DisableKillSwitch()
+			return err
+		}
+	}
+	return nil
+}
+
+// DisableKillSwitch removes the kill switch from both address families.
+func (m *Manager) DisableKillSwitch() error {
+	var firstErr error
+	for _, ipt := range m.families() {
+		if err := removeChain(ipt, "OUTPUT", killSwitchChain); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// BlockInbound installs the inbound filter for iface in both address
+// families.
+func (m *Manager) BlockInbound(iface string) error {
+	for _, ipt := range m.families() {
+		rules := inboundRules(iface, ipt.Proto() == iptables.ProtocolIPv6)
+		if err := installChain(ipt, "INPUT", inboundChain, rules); err != nil {
+			_ = m.UnblockInbound()
+			return err
+		}
+	}
+	return nil
+}
+
+// UnblockInbound removes the inbound filter from both address families.
+func (m *Manager) UnblockInbound() error {
+	var firstErr error
+	for _, ipt := range m.families() {
+		if err := removeChain(ipt, "INPUT", inboundChain); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// BlockNameBroadcasts installs the name-broadcast block for iface in both
+// address families.
+func (m *Manager) BlockNameBroadcasts(iface string) error {
+	for _, ipt := range m.families() {
+		rules := nameRules(iface, ipt.Proto() == iptables.ProtocolIPv6)
+		if err := installChain(ipt, "OUTPUT", namesChain, rules); err != nil {
+			_ = m.UnblockNameBroadcasts()
+			return err
+		}
+	}
+	return nil
+}
+
+// UnblockNameBroadcasts removes the name-broadcast block from both address
+// families.
+func (m *Manager) UnblockNameBroadcasts() error {
+	var firstErr error
+	for _, ipt := range m.families() {
+		if err := removeChain(ipt, "OUTPUT", namesChain); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// RestrictForward installs the forward restriction for internalIface. NAT is
+// IPv4-only, so the restriction is too.
+func (m *Manager) RestrictForward(internalIface, outIface string) error {
+	chain := shareChain(internalIface)
+	if err := installChain(m.ipt, "FORWARD", chain, shareRules(internalIface, outIface)); err != nil {
+		_ = removeChain(m.ipt, "FORWARD", chain)
+		return err
+	}
+	return nil
+}
+
+// UnrestrictForward removes the forward restriction for internalIface.
+func (m *Manager) UnrestrictForward(internalIface string) error {
+	return removeChain(m.ipt, "FORWARD", shareChain(internalIface))
+}
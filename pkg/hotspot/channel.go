@@ -0,0 +1,345 @@
+package hotspot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// autoChannels are the channels channel: auto picks from, per band. 2.4 GHz
+// sticks to the three non-overlapping channels; 5 GHz leaves out the DFS
+// channels, where the AP must listen for radar for a minute before it may
+// start.
+var autoChannels = map[string][]int{
+	"2.4": {1, 6, 11},
+	"5":   {36, 40, 44, 48, 149, 153, 157, 161, 165},
+}
+
+// widthBlocks lists the first channels of the 5 GHz channels of each width.
+var widthBlocks = map[int][]int{
+	40:  {36, 44, 52, 60, 100, 108, 116, 124, 132, 140, 149, 157},
+	80:  {36, 52, 100, 116, 132, 149},
+	160: {36, 100},
+}
+
+// channelBand returns the band ("2.4" or "5") of a valid channel.
+func channelBand(channel int) string {
+	if channel >= 36 {
+		return "5"
+	}
+	return "2.4"
+}
+
+// channelFrequency returns the center frequency in MHz of a valid channel.
+func channelFrequency(channel int) int {
+	switch {
+	case channel == 14:
+		return 2484
+	case channel < 14:
+		return 2407 + 5*channel
+	}
+	return 5000 + 5*channel
+}
+
+// widthBlock returns the first channel of the width-MHz 5 GHz channel that
+// contains channel, or 0 when there is none.
+func widthBlock(channel, width int) int {
+	for _, first := range widthBlocks[width] {
+		if channel >= first && channel < first+width/5 {
+			return first
+		}
+	}
+	return 0
+}
+
+// hasWidth reports whether channel can be the primary channel of a width-MHz
+// channel.
+func hasWidth(channel, width int) bool {
+	switch {
+	case width <= 20:
+		return true
+	case width == 40 && channel <= 14:
+		return channel <= 13
+	}
+	return widthBlock(channel, width) != 0
+}
+
+// ht40Above reports whether the secondary channel of a 40 MHz channel lies
+// above channel (HT40+) rather than below it (HT40-).
+func ht40Above(channel int) bool {
+	if channel <= 14 {
+		return channel <= 7
+	}
+	return widthBlock(channel, 40) == channel
+}
+
+// centerChannel returns the channel index of the center of the width-MHz
+// 5 GHz channel containing channel.
+func centerChannel(channel, width int) int {
+	return widthBlock(channel, width) + width/10 - 2
+}
+
+// hotspotBand returns the band the hotspot runs on.
+func hotspotBand(config *types.HotspotConfig) string {
+	switch {
+	case config.Band != "":
+		return config.Band
+	case !config.AutoChannel:
+		return channelBand(config.Channel)
+	case config.IEEE80211AC || config.Width >= 80:
+		return "5"
+	}
+	return "2.4"
+}
+
+// validateRadio checks the channel, band, width, country and 802.11
+// standards of config against each other.
+func validateRadio(config *types.HotspotConfig) error {
+	if config.Band != "" && config.Band != "2.4" && config.Band != "5" {
+		return fmt.Errorf("invalid band %q (valid: 2.4, 5)", config.Band)
+	}
+	if !config.AutoChannel {
+		if !isValidChannel(config.Channel) {
+			return fmt.Errorf("invalid channel %d (valid: 1-14 for 2.4GHz, 36-165 for 5GHz)", config.Channel)
+		}
+		if config.Band != "" && channelBand(config.Channel) != config.Band {
+			return fmt.Errorf("channel %d is not in the %s GHz band", config.Channel, config.Band)
+		}
+	}
+
+	band := hotspotBand(config)
+	if config.IEEE80211AC && band != "5" {
+		return fmt.Errorf("ieee80211ac requires the 5 GHz band")
+	}
+	switch config.Width {
+	case 0, 20:
+	case 40:
+		if !config.IEEE80211N && !config.IEEE80211AC && !config.IEEE80211AX {
+			return fmt.Errorf("width 40 requires ieee80211n, ieee80211ac or ieee80211ax")
+		}
+	case 80, 160:
+		if !config.IEEE80211AC && !config.IEEE80211AX {
+			return fmt.Errorf("width %d requires ieee80211ac or ieee80211ax", config.Width)
+		}
+		if band != "5" {
+			return fmt.Errorf("width %d requires the 5 GHz band", config.Width)
+		}
+	default:
+		return fmt.Errorf("invalid width %d (valid: 20, 40, 80, 160)", config.Width)
+	}
+	if !config.AutoChannel && !hasWidth(config.Channel, config.Width) {
+		return fmt.Errorf("channel %d cannot be used %d MHz wide", config.Channel, config.Width)
+	}
+
+	if config.Country != "" && !isCountryCode(config.Country) {
+		return fmt.Errorf("invalid country %q (expected a two-letter code such as \"DE\")", config.Country)
+	}
+	return nil
+}
+
+// isCountryCode reports whether s looks like an ISO 3166-1 alpha-2 code.
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// radioConfig picks a channel for channel: auto and checks the channel
+// against the frequencies the radio supports, returning a copy of config
+// when the channel changes. The checks are skipped when nl80211 can't say
+// which radio the interface is on.
+func (h *hotspotManagerImpl) radioConfig(config *types.HotspotConfig) (*types.HotspotConfig, error) {
+	phy := -1
+	if ifaces, err := h.wireless.Interfaces(); err == nil {
+		for _, wi := range ifaces {
+			if wi.Name == config.Interface {
+				phy = wi.PHY
+			}
+		}
+	}
+	var freqs []types.RadioFrequency
+	if phy >= 0 {
+		var err error
+		if freqs, err = h.wireless.Frequencies(phy); err != nil {
+			h.logger.Warn("Failed to read the channels of the radio, not checking the channel", "error", err.Error())
+		}
+	}
+
+	if !config.AutoChannel {
+		if len(freqs) == 0 {
+			return config, nil
+		}
+		return config, checkChannel(freqs, config.Channel, config.Country, phy)
+	}
+
+	channel, err := h.pickChannel(config, freqs, phy)
+	if err != nil {
+		return nil, err
+	}
+	h.logger.Info("Picked hotspot channel", "channel", channel, "band", hotspotBand(config))
+	picked := *config
+	picked.Channel = channel
+	return &picked, nil
+}
+
+// checkChannel checks that the radio with frequencies freqs may run an
+// access point on channel. With a country, only hardware support is
+// checked: hostapd applies the country's rules before it starts beaconing,
+// which changes the restrictions the radio reports now.
+func checkChannel(freqs []types.RadioFrequency, channel int, country string, phy int) error {
+	freq := channelFrequency(channel)
+	for _, rf := range freqs {
+		if rf.Frequency != freq {
+			continue
+		}
+		switch {
+		case country != "":
+		case rf.Disabled:
+			return fmt.Errorf("channel %d is disabled in the current regulatory domain; set country to where you are", channel)
+		case rf.NoIR:
+			return fmt.Errorf("channel %d does not allow starting an access point in the current regulatory domain; set country to where you are", channel)
+		}
+		return nil
+	}
+	return fmt.Errorf("phy#%d does not support channel %d", phy, channel)
+}
+
+// pickChannel scans from config.Interface and returns the least congested
+// usable channel of the hotspot's band. Without scan results, the first
+// usable channel wins.
+func (h *hotspotManagerImpl) pickChannel(config *types.HotspotConfig, freqs []types.RadioFrequency, phy int) (int, error) {
+	band := hotspotBand(config)
+	var candidates []int
+	for _, channel := range autoChannels[band] {
+		if !hasWidth(channel, config.Width) {
+			continue
+		}
+		if len(freqs) > 0 && checkChannel(freqs, channel, config.Country, phy) != nil {
+			continue
+		}
+		candidates = append(candidates, channel)
+	}
+	if len(candidates) == 0 {
+		return 0, fmt.Errorf("no usable %s GHz channel for the hotspot on %s; set country to where you are, or pick a channel", band, config.Interface)
+	}
+
+	// Scanning needs the interface up; setupInterface takes it over later.
+	if err := h.linkMgr.SetUp(config.Interface); err != nil {
+		h.logger.Warn("Failed to bring interface up for scanning", "error", err.Error())
+	}
+	if err := h.wireless.TriggerScan(config.Interface, 10*time.Second); err != nil {
+		h.logger.Warn("Scan for a free channel failed, using cached results", "error", err.Error())
+	}
+	bsses, err := h.wireless.ScanResults(config.Interface)
+	if err != nil {
+		h.logger.Warn("Failed to read scan results", "error", err.Error())
+	}
+
+	best, bestScore := 0, -1
+	for _, channel := range candidates {
+		score := congestion(channel, config.Width, bsses)
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = channel, score
+		}
+	}
+	return best, nil
+}
+
+// congestion scores how busy channel would be at the given width: every
+// access point whose primary channel overlaps it counts, louder ones more.
+func congestion(channel, width int, bsses []types.BSS) int {
+	// The span of channel numbers the hotspot occupies
+	lo, hi := channel, channel
+	switch {
+	case channel <= 14:
+		// 2.4 GHz channels are 5 MHz apart but ~20 MHz wide: neighbors
+		// within four channels overlap.
+		lo, hi = channel-4, channel+4
+		if width == 40 {
+			if ht40Above(channel) {
+				hi += 4
+			} else {
+				lo -= 4
+			}
+		}
+	case width >= 40:
+		lo = widthBlock(channel, width)
+		hi = lo + width/5 - 4
+	}
+
+	score := 0
+	for _, bss := range bsses {
+		c := channelFromFrequency(bss.Frequency)
+		if c == 0 || c < lo || c > hi {
+			continue
+		}
+		score += max(1, 100+bss.Signal)
+	}
+	return score
+}
+
+// writeRadioConfig writes the hostapd settings for the band, channel,
+// country and 802.11 standards of config.
+func writeRadioConfig(sb *strings.Builder, config *types.HotspotConfig) {
+	band := channelBand(config.Channel)
+	if band == "5" {
+		sb.WriteString("hw_mode=a\n")
+	} else {
+		sb.WriteString("hw_mode=g\n")
+	}
+	sb.WriteString(fmt.Sprintf("channel=%d\n", config.Channel))
+
+	if config.Country != "" {
+		sb.WriteString(fmt.Sprintf("country_code=%s\n", strings.ToUpper(config.Country)))
+		sb.WriteString("ieee80211d=1\n")
+		if band == "5" {
+			// Spectrum management: DFS channels need it to start at all
+			sb.WriteString("ieee80211h=1\n")
+		}
+	}
+
+	// VHT and HE build on HT, so any of the three enables 802.11n
+	if !config.IEEE80211N && !config.IEEE80211AC && !config.IEEE80211AX {
+		return
+	}
+	sb.WriteString("wmm_enabled=1\n")
+	sb.WriteString("ieee80211n=1\n")
+	if config.Width >= 40 {
+		if ht40Above(config.Channel) {
+			sb.WriteString("ht_capab=[HT40+]\n")
+		} else {
+			sb.WriteString("ht_capab=[HT40-]\n")
+		}
+	}
+
+	// Operating channel width: 0 is 20 or 40 MHz, 1 is 80, 2 is 160
+	chwidth := 0
+	switch config.Width {
+	case 80:
+		chwidth = 1
+	case 160:
+		chwidth = 2
+	}
+	if config.IEEE80211AC {
+		sb.WriteString("ieee80211ac=1\n")
+		sb.WriteString(fmt.Sprintf("vht_oper_chwidth=%d\n", chwidth))
+		if chwidth > 0 {
+			sb.WriteString(fmt.Sprintf("vht_oper_centr_freq_seg0_idx=%d\n", centerChannel(config.Channel, config.Width)))
+		}
+	}
+	if config.IEEE80211AX {
+		sb.WriteString("ieee80211ax=1\n")
+		sb.WriteString(fmt.Sprintf("he_oper_chwidth=%d\n", chwidth))
+		if chwidth > 0 {
+			sb.WriteString(fmt.Sprintf("he_oper_centr_freq_seg0_idx=%d\n", centerChannel(config.Channel, config.Width)))
+		}
+	}
+}
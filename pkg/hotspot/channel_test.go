@@ -0,0 +1,191 @@
+package hotspot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/angelfreak/net/pkg/netlink/fake"
+	"github.com/angelfreak/net/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRadio(t *testing.T) {
+	tests := []struct {
+		name   string
+		config types.HotspotConfig
+		errMsg string
+	}{
+		{name: "defaults", config: types.HotspotConfig{Channel: 6}},
+		{name: "auto", config: types.HotspotConfig{AutoChannel: true, Band: "5"}},
+		{name: "80 MHz", config: types.HotspotConfig{Channel: 44, Width: 80, IEEE80211AC: true, Country: "de"}},
+		{name: "40 MHz on 2.4 GHz", config: types.HotspotConfig{Channel: 11, Width: 40, IEEE80211N: true}},
+		{name: "bad band", config: types.HotspotConfig{Channel: 6, Band: "6"},
+			errMsg: `invalid band "6" (valid: 2.4, 5)`},
+		{name: "channel outside band", config: types.HotspotConfig{Channel: 6, Band: "5"},
+			errMsg: "channel 6 is not in the 5 GHz band"},
+		{name: "ac on 2.4 GHz", config: types.HotspotConfig{Channel: 6, IEEE80211AC: true},
+			errMsg: "ieee80211ac requires the 5 GHz band"},
+		{name: "auto ac defaults to 5 GHz", config: types.HotspotConfig{AutoChannel: true, IEEE80211AC: true}},
+		{name: "40 MHz without HT", config: types.HotspotConfig{Channel: 36, Width: 40},
+			errMsg: "width 40 requires ieee80211n, ieee80211ac or ieee80211ax"},
+		{name: "80 MHz with n only", config: types.HotspotConfig{Channel: 36, Width: 80, IEEE80211N: true},
+			errMsg: "width 80 requires ieee80211ac or ieee80211ax"},
+		{name: "80 MHz on 2.4 GHz", config: types.HotspotConfig{Channel: 6, Width: 80, IEEE80211AX: true},
+			errMsg: "width 80 requires the 5 GHz band"},
+		{name: "bad width", config: types.HotspotConfig{Channel: 6, Width: 30},
+			errMsg: "invalid width 30 (valid: 20, 40, 80, 160)"},
+		{name: "165 has no 40 MHz channel", config: types.HotspotConfig{Channel: 165, Width: 40, IEEE80211N: true},
+			errMsg: "channel 165 cannot be used 40 MHz wide"},
+		{name: "160 MHz outside a block", config: types.HotspotConfig{Channel: 149, Width: 160, IEEE80211AX: true},
+			errMsg: "channel 149 cannot be used 160 MHz wide"},
+		{name: "bad country", config: types.HotspotConfig{Channel: 6, Country: "Germany"},
+			errMsg: `invalid country "Germany" (expected a two-letter code such as "DE")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRadio(&tt.config)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestWriteRadioConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config types.HotspotConfig
+		want   string
+	}{
+		{
+			name:   "plain 2.4 GHz",
+			config: types.HotspotConfig{Channel: 6},
+			want:   "hw_mode=g\nchannel=6\n",
+		},
+		{
+			name:   "40 MHz below",
+			config: types.HotspotConfig{Channel: 11, Width: 40, IEEE80211N: true},
+			want:   "hw_mode=g\nchannel=11\nwmm_enabled=1\nieee80211n=1\nht_capab=[HT40-]\n",
+		},
+		{
+			name:   "80 MHz ac and ax",
+			config: types.HotspotConfig{Channel: 44, Width: 80, Country: "de", IEEE80211AC: true, IEEE80211AX: true},
+			want: "hw_mode=a\nchannel=44\ncountry_code=DE\nieee80211d=1\nieee80211h=1\n" +
+				"wmm_enabled=1\nieee80211n=1\nht_capab=[HT40+]\n" +
+				"ieee80211ac=1\nvht_oper_chwidth=1\nvht_oper_centr_freq_seg0_idx=42\n" +
+				"ieee80211ax=1\nhe_oper_chwidth=1\nhe_oper_centr_freq_seg0_idx=42\n",
+		},
+		{
+			name:   "160 MHz",
+			config: types.HotspotConfig{Channel: 108, Width: 160, IEEE80211AC: true},
+			want: "hw_mode=a\nchannel=108\nwmm_enabled=1\nieee80211n=1\nht_capab=[HT40+]\n" +
+				"ieee80211ac=1\nvht_oper_chwidth=2\nvht_oper_centr_freq_seg0_idx=114\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			writeRadioConfig(&sb, &tt.config)
+			assert.Equal(t, tt.want, sb.String())
+		})
+	}
+}
+
+// radio5GHz returns the non-DFS 5 GHz channels of a radio in a regulatory
+// domain that allows an AP only on the given channels.
+func radio5GHz(allowed ...int) []types.RadioFrequency {
+	var freqs []types.RadioFrequency
+	for _, channel := range autoChannels["5"] {
+		freqs = append(freqs, types.RadioFrequency{Frequency: channelFrequency(channel), NoIR: true})
+	}
+	for i := range freqs {
+		for _, channel := range allowed {
+			if freqs[i].Frequency == channelFrequency(channel) {
+				freqs[i].NoIR = false
+			}
+		}
+	}
+	return freqs
+}
+
+func TestRadioConfig_AutoChannel(t *testing.T) {
+	mgr, _, links, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+	wireless := &fake.WirelessManager{
+		Ifaces: []types.WirelessInterface{{Name: "wlan0", Type: "station", PHY: 1}},
+		Freqs:  map[int][]types.RadioFrequency{1: radio5GHz(36, 40, 44, 48)},
+		FreshScans: map[string][]types.BSS{"wlan0": {
+			fake.BSS("aa:00:00:00:00:01", "Neighbor", 5180, -40), // 36
+			fake.BSS("aa:00:00:00:00:02", "Upstairs", 5200, -85), // 40
+			fake.BSS("aa:00:00:00:00:03", "Office", 5240, -70),   // 48
+		}},
+	}
+	mgr.wireless = wireless
+
+	config := &types.HotspotConfig{Interface: "wlan0", AutoChannel: true, Band: "5"}
+	picked, err := mgr.radioConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, 44, picked.Channel, "the only free allowed channel")
+	assert.Equal(t, 0, config.Channel, "the caller's config is not modified")
+	assert.Equal(t, []string{"wlan0"}, wireless.Triggered)
+	assert.Contains(t, links.Upped, "wlan0", "scanning needs the interface up")
+
+	// 80 MHz: the whole 36-48 block is one channel; its least busy primary
+	// doesn't matter, the block's total does.
+	picked, err = mgr.radioConfig(&types.HotspotConfig{Interface: "wlan0", AutoChannel: true, Width: 80, IEEE80211AC: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 36, picked.Channel)
+
+	// No channel allows an AP in the world regulatory domain...
+	wireless.Freqs[1] = radio5GHz()
+	_, err = mgr.radioConfig(&types.HotspotConfig{Interface: "wlan0", AutoChannel: true, Band: "5"})
+	assert.EqualError(t, err, "no usable 5 GHz channel for the hotspot on wlan0; set country to where you are, or pick a channel")
+
+	// ...but hostapd lifts the restrictions for a country.
+	picked, err = mgr.radioConfig(&types.HotspotConfig{Interface: "wlan0", AutoChannel: true, Band: "5", Country: "US"})
+	assert.NoError(t, err)
+	assert.Equal(t, 44, picked.Channel)
+}
+
+func TestRadioConfig_CheckChannel(t *testing.T) {
+	mgr, _, _, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+	freqs := radio5GHz(36)
+	freqs[1].Disabled = true // 40
+	mgr.wireless = &fake.WirelessManager{
+		Ifaces: []types.WirelessInterface{{Name: "wlan0", Type: "station", PHY: 0}},
+		Freqs:  map[int][]types.RadioFrequency{0: freqs},
+	}
+
+	tests := []struct {
+		channel int
+		country string
+		errMsg  string
+	}{
+		{channel: 36},
+		{channel: 40, errMsg: "channel 40 is disabled in the current regulatory domain; set country to where you are"},
+		{channel: 44, errMsg: "channel 44 does not allow starting an access point in the current regulatory domain; set country to where you are"},
+		{channel: 44, country: "DE"},
+		{channel: 6, errMsg: "phy#0 does not support channel 6"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d%s", tt.channel, tt.country), func(t *testing.T) {
+			config := &types.HotspotConfig{Interface: "wlan0", Channel: tt.channel, Country: tt.country}
+			got, err := mgr.radioConfig(config)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Same(t, config, got)
+			} else {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+
+	// Without nl80211 the channel goes unchecked.
+	mgr.wireless = &fake.WirelessManager{InterfacesErr: fmt.Errorf("nl80211 is not available")}
+	_, err := mgr.radioConfig(&types.HotspotConfig{Interface: "wlan0", Channel: 44})
+	assert.NoError(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -12,8 +13,13 @@ import (
 
 	"github.com/angelfreak/net/pkg/firewall"
 	"github.com/angelfreak/net/pkg/netlink"
+	"github.com/angelfreak/net/pkg/oui"
+	"github.com/angelfreak/net/pkg/share"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/vpn"
+	"github.com/angelfreak/net/pkg/wifi"
+	"github.com/angelfreak/net/pkg/wpa"
 )
 
 // hotspotManagerImpl implements the HotspotManager interface
@@ -24,14 +30,22 @@ type hotspotManagerImpl struct {
 	dnsmasqPidFile  string
 	hostapdConfFile string
 	dnsmasqConfFile string
+	acceptFile      string // hostapd accept_mac_file, written when the allow list is set
+	denyFile        string // hostapd deny_mac_file, written when the deny list is set
+	leasesFile      string // dnsmasq lease file, joined with hostapd's stations by Clients
 	stateFile       string // Persists hotspot interface and outInterface for crash recovery
 	currentConfig   *types.HotspotConfig
 	outInterface    string                // Interface for NAT routing (e.g., eth0)
+	vpnUplink       bool                  // Clients are routed through the VPN tunnel (uplink: vpn) and restricted to it
+	vpnStateDir     string                // Directory of the VPN manager's state, which names the active tunnel
 	prevIPForward   string                // /proc/.../ip_forward value before we enabled it, for restore ("0"/"1"/"" if unknown)
+	station         string                // Connected interface the hotspot runs alongside on a virtual AP interface ("" when it took the interface over)
 	linkMgr         types.LinkManager     // netlink-backed link access (interface up/down)
+	wireless        types.WirelessManager // nl80211-backed radio access (association, interface combinations)
 	addrMgr         types.AddrManager     // netlink-backed interface address access
 	routeMgr        types.RouteManager    // netlink-backed routing table access
 	firewall        types.FirewallManager // go-iptables-backed NAT rules; nil until first use / injected in tests
+	hostapd         types.HostapdClient   // hostapd control socket access (stations, deauthentication)
 }
 
 // NewHotspotManager creates a new hotspot manager
@@ -43,10 +57,16 @@ func NewHotspotManager(executor types.SystemExecutor, logger types.Logger) types
 		dnsmasqPidFile:  types.RuntimeDir + "/dnsmasq-hotspot.pid",
 		hostapdConfFile: types.RuntimeDir + "/hostapd.conf",
 		dnsmasqConfFile: types.RuntimeDir + "/dnsmasq-hotspot.conf",
+		acceptFile:      types.RuntimeDir + "/hostapd.accept",
+		denyFile:        types.RuntimeDir + "/hostapd.deny",
+		leasesFile:      types.RuntimeDir + "/dnsmasq-hotspot.leases",
 		stateFile:       types.RuntimeDir + "/hotspot-state",
+		vpnStateDir:     types.RuntimeDir,
 		linkMgr:         netlink.NewLinkManager(),
+		wireless:        netlink.NewWirelessManager(),
 		addrMgr:         netlink.NewAddrManager(),
 		routeMgr:        netlink.NewRouteManager(),
+		hostapd:         wpa.NewHostapdClient(),
 	}
 }
 
@@ -79,8 +99,30 @@ func (h *hotspotManagerImpl) Start(config *types.HotspotConfig) error {
 		return fmt.Errorf("hotspot is already running")
 	}
 
+	// Without a tunnel there is nothing to share; fail before touching the radio
+	if config.Uplink == types.UplinkVPN && vpn.ActiveInterface(h.vpnStateDir) == "" {
+		return fmt.Errorf("uplink is vpn, but no VPN is connected")
+	}
+
+	// Leave a connected interface alone: run the AP next to it instead
+	config, station, err := h.concurrentConfig(config)
+	if err != nil {
+		return err
+	}
+	if station != "" {
+		if err := h.addAPInterface(station, config.Interface); err != nil {
+			return err
+		}
+		h.station = station
+	} else if config, err = h.radioConfig(config); err != nil {
+		return err
+	}
+
 	// Setup interface (with cleanup on failure)
 	if err := h.setupInterface(config); err != nil {
+		if h.station != "" {
+			h.cleanupInterface(config.Interface)
+		}
 		return err
 	}
 
@@ -120,7 +162,16 @@ func (h *hotspotManagerImpl) Start(config *types.HotspotConfig) error {
 	}
 
 	// Setup NAT/IP forwarding for internet sharing
-	if err := h.setupNAT(config.Interface); err != nil {
+	if err := h.setupNAT(config.Interface, config.Uplink); err != nil {
+		if config.Uplink == types.UplinkVPN {
+			// Half-applied VPN routing could let clients out another way
+			h.cleanupNAT(config.Interface)
+			h.stopDnsmasq()
+			h.stopHostapd()
+			h.cleanupInterface(config.Interface)
+			h.vpnUplink = false
+			return fmt.Errorf("failed to route clients through the VPN: %w", err)
+		}
 		h.logger.Warn("Failed to setup NAT", "error", err.Error())
 		// Continue anyway - hotspot will work but without internet sharing
 	}
@@ -134,6 +185,157 @@ func (h *hotspotManagerImpl) Start(config *types.HotspotConfig) error {
 	return nil
 }
 
+// concurrentConfig checks whether config.Interface is a connected station.
+// If so, taking it over would drop the connection the hotspot is meant to
+// share, so it returns a copy of config for a virtual AP interface on the
+// same radio, on the station's channel (a radio can only be on one channel
+// at a time), along with the station's name. Otherwise config is returned
+// unchanged. It fails when the driver can't run a station and an AP at once.
+func (h *hotspotManagerImpl) concurrentConfig(config *types.HotspotConfig) (*types.HotspotConfig, string, error) {
+	ifaces, err := h.wireless.Interfaces()
+	if err != nil {
+		return config, "", nil // no nl80211: nothing to share
+	}
+	phy := -1
+	for _, wi := range ifaces {
+		if wi.Name == config.Interface && wi.Type == "station" {
+			phy = wi.PHY
+		}
+	}
+	if phy < 0 {
+		return config, "", nil
+	}
+	link, err := h.wireless.LinkInfo(config.Interface)
+	if err != nil || link == nil {
+		return config, "", nil
+	}
+
+	combs, err := h.wireless.InterfaceCombinations(phy)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the interface combinations of phy#%d: %w", phy, err)
+	}
+	if !supportsCombination(combs, "station", "ap") {
+		return nil, "", fmt.Errorf("%s is connected to %q and its driver cannot run an access point at the same time "+
+			"(no valid interface combination of phy#%d has both a station and an AP); disconnect it first or use another radio",
+			config.Interface, link.SSID, phy)
+	}
+	channel := channelFromFrequency(link.Frequency)
+	if !isValidChannel(channel) {
+		return nil, "", fmt.Errorf("%s is connected on %d MHz, which the hotspot cannot share (it supports 2.4GHz and 5GHz channels); disconnect it first or use another radio",
+			config.Interface, link.Frequency)
+	}
+	if config.AutoChannel || config.Channel != channel {
+		h.logger.Info("Moving hotspot to the channel of the connection", "channel", channel, "requested", config.Channel)
+	}
+
+	ap := *config
+	ap.Interface = apInterfaceName(config.Interface)
+	ap.Channel = channel
+	ap.AutoChannel = false
+	if err := validateRadio(&ap); err != nil {
+		return nil, "", fmt.Errorf("%s is connected on channel %d, which the hotspot has to share: %w", config.Interface, channel, err)
+	}
+	return &ap, config.Interface, nil
+}
+
+// supportsCombination reports whether one of combs can run one interface of
+// each of ifTypes at the same time, on one channel.
+func supportsCombination(combs []types.InterfaceCombination, ifTypes ...string) bool {
+	for _, comb := range combs {
+		if comb.Max < len(ifTypes) || comb.Channels < 1 {
+			continue
+		}
+		room := make([]int, len(comb.Limits))
+		for i, limit := range comb.Limits {
+			room[i] = limit.Max
+		}
+		// Limits may share types, so try every way of placing them.
+		var place func(i int) bool
+		place = func(i int) bool {
+			if i == len(ifTypes) {
+				return true
+			}
+			for j, limit := range comb.Limits {
+				if room[j] > 0 && slices.Contains(limit.Types, ifTypes[i]) {
+					room[j]--
+					if place(i + 1) {
+						return true
+					}
+					room[j]++
+				}
+			}
+			return false
+		}
+		if place(0) {
+			return true
+		}
+	}
+	return false
+}
+
+// apInterfaceName names the virtual AP interface of station, within the
+// kernel's 15-character limit on interface names.
+func apInterfaceName(station string) string {
+	const suffix = "_ap"
+	if len(station) > 15-len(suffix) {
+		station = station[:15-len(suffix)]
+	}
+	return station + suffix
+}
+
+// addAPInterface creates the virtual AP interface name on the radio of
+// station, replacing one left behind by a crash. It gets its own MAC: most
+// drivers refuse two interfaces with the same address.
+func (h *hotspotManagerImpl) addAPInterface(station, name string) error {
+	if exists, err := h.linkMgr.Exists(name); err == nil && exists {
+		if err := h.linkMgr.Delete(name); err != nil {
+			return fmt.Errorf("failed to remove stale interface %s: %w", name, err)
+		}
+	}
+	h.logger.Debug("Creating AP interface", "interface", name, "station", station)
+	if err := h.linkMgr.AddWirelessInterface(station, name, "ap"); err != nil {
+		return fmt.Errorf("failed to create AP interface: %w", err)
+	}
+	mac, err := h.linkMgr.GetMAC(station)
+	if err == nil {
+		mac, err = apMAC(mac)
+	}
+	if err == nil {
+		err = h.linkMgr.SetMAC(name, mac)
+	}
+	if err != nil {
+		h.linkMgr.Delete(name)
+		return fmt.Errorf("failed to set the MAC address of %s: %w", name, err)
+	}
+	return nil
+}
+
+// apMAC derives the AP interface's MAC from the station's by setting the
+// locally administered bit, or, when the station's address is already a
+// local one, by flipping the lowest bit of its last octet.
+func apMAC(station string) (string, error) {
+	mac, err := net.ParseMAC(station)
+	if err != nil {
+		return "", err
+	}
+	if mac[0]&0x02 == 0 {
+		mac[0] |= 0x02
+	} else {
+		mac[len(mac)-1] ^= 0x01
+	}
+	return mac.String(), nil
+}
+
+// channelFromFrequency returns the 2.4 or 5 GHz channel number of a center
+// frequency in MHz, or 0 in other bands: the hotspot has no 6 GHz channels,
+// whose numbers would be taken for 2.4 or 5 GHz ones.
+func channelFromFrequency(freq int) int {
+	if band := (types.WiFiNetwork{Frequency: freq}).Band(); band != "2.4" && band != "5" {
+		return 0
+	}
+	return wifi.ChannelFromFrequency(freq)
+}
+
 // setupInterface brings up the interface and configures IP (with cleanup on failure)
 func (h *hotspotManagerImpl) setupInterface(config *types.HotspotConfig) error {
 	// Bring interface down
@@ -141,9 +343,11 @@ func (h *hotspotManagerImpl) setupInterface(config *types.HotspotConfig) error {
 		return fmt.Errorf("failed to bring interface down: %w", err)
 	}
 
-	// Set interface to AP mode
-	if _, err := h.executor.ExecuteWithTimeout(5*time.Second, "iw", config.Interface, "set", "type", "__ap"); err != nil {
-		h.logger.Warn("Failed to set interface to AP mode, continuing anyway", "error", err.Error())
+	// Set interface to AP mode (a virtual AP interface is created as one)
+	if h.station == "" {
+		if _, err := h.executor.ExecuteWithTimeout(5*time.Second, "iw", config.Interface, "set", "type", "__ap"); err != nil {
+			h.logger.Warn("Failed to set interface to AP mode, continuing anyway", "error", err.Error())
+		}
 	}
 
 	// Bring interface up
@@ -164,10 +368,15 @@ func (h *hotspotManagerImpl) setupInterface(config *types.HotspotConfig) error {
 	return nil
 }
 
-// cleanupInterface cleans up interface after a failure
+// cleanupInterface cleans up interface after a failure, removing it when it
+// is a virtual AP interface
 func (h *hotspotManagerImpl) cleanupInterface(iface string) {
 	h.addrMgr.Flush(iface)
 	h.linkMgr.SetDown(iface)
+	if h.station != "" {
+		h.linkMgr.Delete(iface)
+		h.station = ""
+	}
 }
 
 // waitForHostapd waits for hostapd to start (up to 5 seconds)
@@ -181,25 +390,30 @@ func (h *hotspotManagerImpl) waitForHostapd() error {
 	return fmt.Errorf("hostapd failed to start")
 }
 
-// setupNAT configures IP forwarding and NAT masquerade
-func (h *hotspotManagerImpl) setupNAT(hotspotIface string) error {
+// setupNAT configures IP forwarding and NAT masquerade out of uplink (see
+// types.HotspotConfig.Uplink)
+func (h *hotspotManagerImpl) setupNAT(hotspotIface, uplink string) error {
 	// Record the current forwarding state so teardown can restore it instead
 	// of unconditionally disabling forwarding the host may have had enabled.
 	if prev, err := system.ReadIPForward(); err == nil {
 		h.prevIPForward = prev
 	}
 
+	outIface, err := share.Uplink(uplink, vpn.ActiveInterface(h.vpnStateDir), func() string {
+		if h.outInterface != "" {
+			return h.outInterface
+		}
+		return h.detectOutInterface(hotspotIface)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Enable IP forwarding
 	if err := system.WriteIPForward("1"); err != nil {
 		return fmt.Errorf("failed to enable IP forwarding: %w", err)
 	}
 
-	// Find outbound interface if not specified
-	outIface := h.outInterface
-	if outIface == "" {
-		outIface = h.detectOutInterface(hotspotIface)
-	}
-
 	if outIface == "" {
 		h.logger.Warn("No outbound interface detected, skipping NAT setup")
 		return nil
@@ -213,11 +427,16 @@ func (h *hotspotManagerImpl) setupNAT(hotspotIface string) error {
 	if err != nil {
 		return fmt.Errorf("failed to set up NAT: %w", err)
 	}
+	h.outInterface = outIface
+	if uplink == types.UplinkVPN {
+		h.vpnUplink = true
+		if err := share.RouteThroughVPN(fw, h.routeMgr, h.vpnStateDir, hotspotIface, outIface); err != nil {
+			return err
+		}
+	}
 	if err := fw.EnableNAT(hotspotIface, outIface); err != nil {
 		return fmt.Errorf("failed to set up NAT: %w", err)
 	}
-
-	h.outInterface = outIface
 	return nil
 }
 
@@ -245,6 +464,15 @@ func (h *hotspotManagerImpl) cleanupNAT(hotspotIface string) {
 		}
 	}
 
+	// Lift the VPN restriction only after NAT is gone, so clients are never
+	// forwarded out of another interface in between.
+	if h.vpnUplink && hotspotIface != "" {
+		fw, _ := h.firewallMgr()
+		if err := share.Unroute(fw, h.routeMgr, h.vpnStateDir, hotspotIface); err != nil {
+			h.logger.Warn("Failed to stop routing clients through the VPN", "error", err.Error())
+		}
+	}
+
 	// Restore IP forwarding to its pre-hotspot value rather than forcing it
 	// off — the host may have had forwarding enabled before netop ran. Default
 	// to "0" only when we never recorded the prior value.
@@ -296,7 +524,12 @@ func (h *hotspotManagerImpl) Stop() error {
 	}
 
 	// Clean up interface if we have config
-	if h.currentConfig != nil {
+	if h.currentConfig != nil && h.station != "" {
+		// Remove the virtual AP interface; the station keeps its connection
+		if err := h.linkMgr.Delete(h.currentConfig.Interface); err != nil {
+			h.logger.Warn("Failed to remove AP interface", "error", err.Error())
+		}
+	} else if h.currentConfig != nil {
 		// Remove IP address
 		if err := h.addrMgr.Flush(h.currentConfig.Interface); err != nil {
 			h.logger.Warn("Failed to flush IP addresses", "error", err.Error())
@@ -321,9 +554,14 @@ func (h *hotspotManagerImpl) Stop() error {
 	// Clean up configuration files
 	os.Remove(h.hostapdConfFile)
 	os.Remove(h.dnsmasqConfFile)
+	os.Remove(h.acceptFile)
+	os.Remove(h.denyFile)
+	os.Remove(h.leasesFile)
 
 	h.currentConfig = nil
 	h.outInterface = ""
+	h.vpnUplink = false
+	h.station = ""
 	os.Remove(h.stateFile)
 
 	if len(errors) > 0 {
@@ -340,9 +578,14 @@ func (h *hotspotManagerImpl) GetStatus() (*types.HotspotStatus, error) {
 		Running: h.isRunning(),
 	}
 
+	if h.currentConfig == nil && status.Running {
+		h.loadState()
+	}
 	if h.currentConfig != nil {
 		status.Interface = h.currentConfig.Interface
 		status.SSID = h.currentConfig.SSID
+		status.Channel = h.currentConfig.Channel
+		status.Station = h.station
 		if ip := net.ParseIP(h.currentConfig.Gateway); ip != nil {
 			status.Gateway = ip
 		}
@@ -356,9 +599,134 @@ func (h *hotspotManagerImpl) GetStatus() (*types.HotspotStatus, error) {
 		}
 	}
 
+	// The access point can't follow its station to another channel (after
+	// a roam): hostapd stays on the channel it was started on.
+	if status.Running && status.Station != "" {
+		if link, err := h.wireless.LinkInfo(status.Station); err == nil && link != nil {
+			status.StationChannel = channelFromFrequency(link.Frequency)
+			if status.StationChannel != status.Channel {
+				h.logger.Warn("The connection the hotspot shares its channel with has moved to another channel",
+					"station", status.Station, "channel", status.StationChannel, "hotspot_channel", status.Channel)
+			}
+		}
+	}
+
 	return status, nil
 }
 
+// Clients lists the stations connected to the running hotspot, with the IP
+// and hostname of their DHCP lease.
+func (h *hotspotManagerImpl) Clients() ([]types.HotspotClient, error) {
+	if h.currentConfig == nil {
+		h.loadState()
+	}
+	if h.currentConfig == nil || !h.hostapdRunning() {
+		return nil, fmt.Errorf("hotspot is not running")
+	}
+
+	clients, err := h.hostapd.Stations(h.currentConfig.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hotspot clients: %w", err)
+	}
+
+	// A station without a lease yet (or whose lease file is gone) still
+	// shows up, just without an IP.
+	leases, err := GetDnsmasqLeases(h.leasesFile)
+	if err != nil && !os.IsNotExist(err) {
+		h.logger.Warn("Failed to read hotspot leases", "error", err.Error())
+	}
+	for i := range clients {
+		clients[i].Vendor = oui.Lookup(clients[i].MAC)
+		for _, lease := range leases {
+			_, mac, ip, hostname, err := ParseDnsmasqLease(lease)
+			if err != nil || !strings.EqualFold(mac, clients[i].MAC) {
+				continue
+			}
+			clients[i].IP = ip
+			if hostname != "*" {
+				clients[i].Hostname = hostname
+			}
+		}
+	}
+	return clients, nil
+}
+
+// Kick disconnects station mac from the running hotspot and denies it, so it
+// doesn't reconnect straight away.
+func (h *hotspotManagerImpl) Kick(mac string) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("invalid MAC address %q", mac)
+	}
+	clients, err := h.Clients()
+	if err != nil {
+		return err
+	}
+	connected := slices.ContainsFunc(clients, func(c types.HotspotClient) bool {
+		return strings.EqualFold(c.MAC, hw.String())
+	})
+	if !connected {
+		return fmt.Errorf("%s is not connected to the hotspot", hw)
+	}
+
+	h.logger.Info("Disconnecting hotspot client", "mac", hw.String())
+	if err := h.denyMAC(hw.String()); err != nil {
+		return fmt.Errorf("failed to deny %s: %w", hw, err)
+	}
+	if err := h.hostapd.Deauthenticate(h.currentConfig.Interface, hw.String()); err != nil {
+		return fmt.Errorf("failed to disconnect %s: %w", hw, err)
+	}
+	return nil
+}
+
+// denyMAC adds mac to the deny file and takes it out of the accept file,
+// then pushes both lists to the running hostapd, which only reads the files
+// on start.
+func (h *hotspotManagerImpl) denyMAC(mac string) error {
+	deny, err := readMACFile(h.denyFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !slices.Contains(deny, mac) {
+		deny = append(deny, mac)
+	}
+	if err := writeMACFile(h.denyFile, deny); err != nil {
+		return err
+	}
+
+	// Without an accept file hostapd runs with macaddr_acl=0 and the
+	// accept list is unused.
+	accept, err := readMACFile(h.acceptFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		accept = slices.DeleteFunc(accept, func(m string) bool { return m == mac })
+		if err := writeMACFile(h.acceptFile, accept); err != nil {
+			return err
+		}
+	}
+	return h.hostapd.SetACL(h.currentConfig.Interface, accept, deny)
+}
+
+// readMACFile reads a hostapd accept/deny file: one MAC per line.
+func readMACFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeMACs(strings.Fields(string(data))), nil
+}
+
+// writeMACFile writes macs as a hostapd accept/deny file.
+func writeMACFile(path string, macs []string) error {
+	content := strings.Join(macs, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content), 0600)
+}
+
 // validateConfig validates the hotspot configuration
 func (h *hotspotManagerImpl) validateConfig(config *types.HotspotConfig) error {
 	if config.Interface == "" {
@@ -370,13 +738,11 @@ func (h *hotspotManagerImpl) validateConfig(config *types.HotspotConfig) error {
 	if config.SSID == "" {
 		return fmt.Errorf("SSID is required")
 	}
-	if config.Password != "" && (len(config.Password) < 8 || len(config.Password) > 63) {
-		// hostapd requires WPA2 passphrases to be 8..63 characters; anything
-		// outside that range makes it fail to start with a cryptic error.
-		return fmt.Errorf("password must be 8-63 characters")
+	if err := validateSecurity(config); err != nil {
+		return err
 	}
-	if !isValidChannel(config.Channel) {
-		return fmt.Errorf("invalid channel %d (valid: 1-14 for 2.4GHz, 36-165 for 5GHz)", config.Channel)
+	if err := validateRadio(config); err != nil {
+		return err
 	}
 	if config.Gateway == "" {
 		return fmt.Errorf("gateway is required")
@@ -395,7 +761,67 @@ func (h *hotspotManagerImpl) validateConfig(config *types.HotspotConfig) error {
 			return fmt.Errorf("invalid DNS server: %q", dns)
 		}
 	}
+	for _, mac := range append(slices.Clone(config.Deny), config.Allow...) {
+		if _, err := net.ParseMAC(mac); err != nil {
+			return fmt.Errorf("invalid MAC address in deny/allow list: %q", mac)
+		}
+	}
+	if config.MaxClients < 0 || config.MaxClients > maxStations {
+		return fmt.Errorf("invalid max_clients %d (valid: 1-%d, or 0 for no limit)", config.MaxClients, maxStations)
+	}
+	if err := types.ValidateUplink(config.Uplink); err != nil {
+		return err
+	}
+	if config.Uplink == config.Interface {
+		return fmt.Errorf("uplink %q is the hotspot interface", config.Uplink)
+	}
+
+	return nil
+}
+
+// maxStations is hostapd's limit on stations per access point, and the
+// default of max_num_sta.
+const maxStations = 2007
+
+// securityMode returns the effective security mode of config: the
+// configured one, or WPA2 when a password is set and open otherwise.
+func securityMode(config *types.HotspotConfig) string {
+	switch {
+	case config.Security != "":
+		return config.Security
+	case config.Password != "":
+		return types.HotspotSecurityWPA2
+	}
+	return types.HotspotSecurityOpen
+}
 
+// validateSecurity checks the security mode against the password.
+func validateSecurity(config *types.HotspotConfig) error {
+	mode := securityMode(config)
+	switch mode {
+	case types.HotspotSecurityOpen, types.HotspotSecurityOWE:
+		if config.Password != "" {
+			return fmt.Errorf("security %s does not use a password", mode)
+		}
+	case types.HotspotSecurityWPA3:
+		// SAE itself has no length limits (and no 63-character cap); keep
+		// the WPA2 minimum so a weak password isn't accepted just because
+		// the mode changed.
+		if len(config.Password) < 8 {
+			return fmt.Errorf("security wpa3 requires a password of at least 8 characters")
+		}
+	case types.HotspotSecurityWPA2, types.HotspotSecurityWPA2WPA3:
+		// hostapd requires WPA2 passphrases to be 8..63 characters; anything
+		// outside that range makes it fail to start with a cryptic error.
+		if config.Password == "" {
+			return fmt.Errorf("security %s requires a password", mode)
+		}
+		if len(config.Password) < 8 || len(config.Password) > 63 {
+			return fmt.Errorf("password must be 8-63 characters")
+		}
+	default:
+		return fmt.Errorf("invalid security %q (valid: %s)", config.Security, strings.Join(types.HotspotSecurityModes, ", "))
+	}
 	return nil
 }
 
@@ -459,26 +885,49 @@ func (h *hotspotManagerImpl) generateHostapdConfig(config *types.HotspotConfig)
 
 	sb.WriteString(fmt.Sprintf("interface=%s\n", config.Interface))
 	sb.WriteString("driver=nl80211\n")
+	// Control socket for Clients and Kick
+	sb.WriteString(fmt.Sprintf("ctrl_interface=%s\n", wpa.HostapdCtrlDir))
 	sb.WriteString(fmt.Sprintf("ssid=%s\n", escapedSSID))
 
-	// Set hw_mode based on channel (g for 2.4GHz, a for 5GHz)
-	if config.Channel >= 36 {
-		sb.WriteString("hw_mode=a\n")
-	} else {
-		sb.WriteString("hw_mode=g\n")
+	// Band, channel, country and 802.11n/ac/ax
+	writeRadioConfig(&sb, config)
+	if err := h.writeACLConfig(&sb, config); err != nil {
+		return err
+	}
+	if config.MaxClients > 0 {
+		sb.WriteString(fmt.Sprintf("max_num_sta=%d\n", config.MaxClients))
 	}
-
-	sb.WriteString(fmt.Sprintf("channel=%d\n", config.Channel))
-	sb.WriteString("macaddr_acl=0\n")
 	sb.WriteString("ignore_broadcast_ssid=0\n")
 
-	if config.Password != "" {
-		escapedPassword := escapeHostapdString(config.Password)
+	// ieee80211w is management frame protection: 1 optional, 2 required
+	escapedPassword := escapeHostapdString(config.Password)
+	switch securityMode(config) {
+	case types.HotspotSecurityWPA2:
 		sb.WriteString("auth_algs=1\n")
 		sb.WriteString("wpa=2\n")
 		sb.WriteString(fmt.Sprintf("wpa_passphrase=%s\n", escapedPassword))
 		sb.WriteString("wpa_key_mgmt=WPA-PSK\n")
 		sb.WriteString("rsn_pairwise=CCMP\n")
+	case types.HotspotSecurityWPA3:
+		sb.WriteString("auth_algs=1\n")
+		sb.WriteString("wpa=2\n")
+		sb.WriteString(fmt.Sprintf("sae_password=%s\n", escapedPassword))
+		sb.WriteString("wpa_key_mgmt=SAE\n")
+		sb.WriteString("rsn_pairwise=CCMP\n")
+		sb.WriteString("ieee80211w=2\n")
+	case types.HotspotSecurityWPA2WPA3:
+		// Without sae_password, SAE uses wpa_passphrase too
+		sb.WriteString("auth_algs=1\n")
+		sb.WriteString("wpa=2\n")
+		sb.WriteString(fmt.Sprintf("wpa_passphrase=%s\n", escapedPassword))
+		sb.WriteString("wpa_key_mgmt=WPA-PSK SAE\n")
+		sb.WriteString("rsn_pairwise=CCMP\n")
+		sb.WriteString("ieee80211w=1\n")
+	case types.HotspotSecurityOWE:
+		sb.WriteString("wpa=2\n")
+		sb.WriteString("wpa_key_mgmt=OWE\n")
+		sb.WriteString("rsn_pairwise=CCMP\n")
+		sb.WriteString("ieee80211w=2\n")
 	}
 
 	// Write with secure permissions (0600) - config may contain password
@@ -489,6 +938,50 @@ func (h *hotspotManagerImpl) generateHostapdConfig(config *types.HotspotConfig)
 	return nil
 }
 
+// writeACLConfig writes the MAC access control settings of config, and the
+// accept and deny files they point to. hostapd checks the accept file
+// before the deny file, so a MAC on both lists is left out of the accept
+// file: deny wins.
+func (h *hotspotManagerImpl) writeACLConfig(sb *strings.Builder, config *types.HotspotConfig) error {
+	deny := normalizeMACs(config.Deny)
+	if len(deny) > 0 {
+		if err := writeMACFile(h.denyFile, deny); err != nil {
+			return fmt.Errorf("failed to write hostapd deny list: %w", err)
+		}
+		sb.WriteString(fmt.Sprintf("deny_mac_file=%s\n", h.denyFile))
+	}
+	if len(config.Allow) == 0 {
+		sb.WriteString("macaddr_acl=0\n")
+		return nil
+	}
+
+	// Allow-listed, but not denied. An empty accept file locks everyone out,
+	// which is what allow minus deny asks for.
+	var accept []string
+	for _, mac := range normalizeMACs(config.Allow) {
+		if !slices.Contains(deny, mac) {
+			accept = append(accept, mac)
+		}
+	}
+	if err := writeMACFile(h.acceptFile, accept); err != nil {
+		return fmt.Errorf("failed to write hostapd allow list: %w", err)
+	}
+	sb.WriteString("macaddr_acl=1\n")
+	sb.WriteString(fmt.Sprintf("accept_mac_file=%s\n", h.acceptFile))
+	return nil
+}
+
+// normalizeMACs returns the validated MACs in lowercase colon form.
+func normalizeMACs(macs []string) []string {
+	var out []string
+	for _, mac := range macs {
+		if hw, err := net.ParseMAC(mac); err == nil {
+			out = append(out, hw.String())
+		}
+	}
+	return out
+}
+
 // generateDnsmasqConfig generates dnsmasq configuration file
 func (h *hotspotManagerImpl) generateDnsmasqConfig(config *types.HotspotConfig) error {
 	var sb strings.Builder
@@ -496,6 +989,8 @@ func (h *hotspotManagerImpl) generateDnsmasqConfig(config *types.HotspotConfig)
 	sb.WriteString(fmt.Sprintf("interface=%s\n", config.Interface))
 	sb.WriteString("bind-interfaces\n")
 	sb.WriteString(fmt.Sprintf("dhcp-range=%s,12h\n", config.IPRange))
+	// Our own lease file, so Clients can map stations to IPs and hostnames
+	sb.WriteString(fmt.Sprintf("dhcp-leasefile=%s\n", h.leasesFile))
 
 	// Determine DNS servers to use
 	dnsServers := config.DNS
@@ -505,8 +1000,12 @@ func (h *hotspotManagerImpl) generateDnsmasqConfig(config *types.HotspotConfig)
 	}
 
 	// Add DNS servers for forwarding
-	for _, dns := range dnsServers {
-		sb.WriteString(fmt.Sprintf("server=%s\n", dns))
+	forwarders, err := share.DNSForwarders(config.Uplink, vpn.ActiveInterface(h.vpnStateDir), dnsServers)
+	if err != nil {
+		return err
+	}
+	for _, line := range forwarders {
+		sb.WriteString(line + "\n")
 	}
 
 	// Gateway option (option 3)
@@ -627,8 +1126,16 @@ func isValidPID(pid string) bool {
 
 // saveState persists hotspot interface and outInterface to a state file for crash recovery
 func (h *hotspotManagerImpl) saveState(hotspotIface string) {
-	// Format: hotspotInterface|outInterface|prevIPForward
-	content := hotspotIface + "|" + h.outInterface + "|" + h.prevIPForward
+	// Format: hotspotInterface|outInterface|prevIPForward|station|uplink|channel
+	uplink := ""
+	if h.vpnUplink {
+		uplink = types.UplinkVPN
+	}
+	channel := ""
+	if h.currentConfig != nil && h.currentConfig.Channel != 0 {
+		channel = strconv.Itoa(h.currentConfig.Channel)
+	}
+	content := hotspotIface + "|" + h.outInterface + "|" + h.prevIPForward + "|" + h.station + "|" + uplink + "|" + channel
 	if err := os.WriteFile(h.stateFile, []byte(content), 0600); err != nil {
 		h.logger.Debug("Failed to save hotspot state", "error", err)
 	}
@@ -640,7 +1147,7 @@ func (h *hotspotManagerImpl) loadState() {
 	if err != nil {
 		return
 	}
-	parts := strings.SplitN(strings.TrimSpace(string(data)), "|", 3)
+	parts := strings.SplitN(strings.TrimSpace(string(data)), "|", 6)
 	if len(parts) >= 1 && parts[0] != "" {
 		h.currentConfig = &types.HotspotConfig{Interface: parts[0]}
 	}
@@ -650,6 +1157,15 @@ func (h *hotspotManagerImpl) loadState() {
 	if len(parts) >= 3 && parts[2] != "" {
 		h.prevIPForward = parts[2]
 	}
+	if len(parts) >= 4 && parts[3] != "" {
+		h.station = parts[3]
+	}
+	if len(parts) >= 5 {
+		h.vpnUplink = parts[4] == types.UplinkVPN
+	}
+	if len(parts) >= 6 && h.currentConfig != nil {
+		h.currentConfig.Channel, _ = strconv.Atoi(parts[5])
+	}
 }
 
 // killProcess kills a process with SIGTERM, falling back to SIGKILL if needed
@@ -677,28 +1193,17 @@ func (h *hotspotManagerImpl) killProcess(pid string) error {
 	return nil
 }
 
-// getConnectedClients returns the number of connected clients
+// getConnectedClients returns the number of connected clients, from the
+// same hostapd station table as Clients and max_num_sta.
 func (h *hotspotManagerImpl) getConnectedClients() (int, error) {
 	if h.currentConfig == nil {
 		return 0, nil
 	}
-
-	// Get station list from hostapd
-	output, err := h.executor.ExecuteWithTimeout(5*time.Second, "iw", "dev", h.currentConfig.Interface, "station", "dump")
+	stations, err := h.hostapd.Stations(h.currentConfig.Interface)
 	if err != nil {
 		return 0, err
 	}
-
-	// Count "Station" lines
-	count := 0
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(strings.TrimSpace(line), "Station ") {
-			count++
-		}
-	}
-
-	return count, nil
+	return len(stations), nil
 }
 
 // GetDnsmasqLeases reads and returns current DHCP leases
@@ -15,6 +15,7 @@ import (
 	"github.com/angelfreak/net/pkg/netlink/fake"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
+	wpafake "github.com/angelfreak/net/pkg/wpa/fake"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -161,6 +162,13 @@ func setupTestManager() (*hotspotManagerImpl, *mockExecutor, *fake.LinkManager,
 	fw := &fwfake.Manager{}
 	mgr.firewall = fw
 
+	// No radios by default, so Start takes the interface over; concurrent
+	// AP+STA tests fill in mgr.wireless themselves.
+	mgr.wireless = &fake.WirelessManager{}
+
+	// No stations by default; client tests fill in their own.
+	mgr.hostapd = &wpafake.Hostapd{}
+
 	// Use temp files for testing
 	tmpDir := os.TempDir()
 	mgr.hostapdPidFile = filepath.Join(tmpDir, "test_hostapd.pid")
@@ -168,6 +176,9 @@ func setupTestManager() (*hotspotManagerImpl, *mockExecutor, *fake.LinkManager,
 	mgr.hostapdConfFile = filepath.Join(tmpDir, "test_hostapd.conf")
 	mgr.dnsmasqConfFile = filepath.Join(tmpDir, "test_dnsmasq.conf")
 	mgr.stateFile = filepath.Join(tmpDir, "test_hotspot_state")
+	mgr.acceptFile = filepath.Join(tmpDir, "test_hostapd.accept")
+	mgr.denyFile = filepath.Join(tmpDir, "test_hostapd.deny")
+	mgr.leasesFile = filepath.Join(tmpDir, "test_dnsmasq.leases")
 
 	return mgr, executor, links, fw, addrs, routes
 }
@@ -178,6 +189,9 @@ func cleanup(mgr *hotspotManagerImpl) {
 	os.Remove(mgr.hostapdConfFile)
 	os.Remove(mgr.dnsmasqConfFile)
 	os.Remove(mgr.stateFile)
+	os.Remove(mgr.acceptFile)
+	os.Remove(mgr.denyFile)
+	os.Remove(mgr.leasesFile)
 }
 
 // Tests
@@ -263,6 +277,212 @@ func TestStart_Success(t *testing.T) {
 	assert.FileExists(t, mgr.dnsmasqConfFile)
 }
 
+// apStaCombinations is the combination an iwlwifi radio advertises: one
+// station and one AP or P2P interface, on up to two channels.
+var apStaCombinations = []types.InterfaceCombination{{
+	Limits: []types.InterfaceLimit{
+		{Max: 1, Types: []string{"station"}},
+		{Max: 1, Types: []string{"ap", "p2p-client", "p2p-go"}},
+		{Max: 1, Types: []string{"p2p-device"}},
+	},
+	Max:      3,
+	Channels: 2,
+}}
+
+// connectedRadio returns a radio whose wlan0 is associated on 5 GHz channel
+// 44, with the given interface combinations.
+func connectedRadio(combs []types.InterfaceCombination) *fake.WirelessManager {
+	return &fake.WirelessManager{
+		Ifaces:       []types.WirelessInterface{{Name: "wlan0", Type: "station", PHY: 0, SSID: "Cafe"}},
+		Links:        map[string]*types.WirelessLink{"wlan0": {SSID: "Cafe", Frequency: 5220}},
+		Combinations: map[int][]types.InterfaceCombination{0: combs},
+	}
+}
+
+func TestStart_ConcurrentAP(t *testing.T) {
+	mgr, executor, links, fw, addrs, routes := setupTestManager()
+	defer cleanup(mgr)
+	mgr.wireless = connectedRadio(apStaCombinations)
+	links.MACs = map[string]string{"wlan0": "00:11:22:33:44:55"}
+	routes.Routes = []types.Route{{Gw: "10.0.0.1", Iface: "wlan0"}}
+
+	ipfPath := filepath.Join(t.TempDir(), "ip_forward")
+	os.WriteFile(ipfPath, []byte("0"), 0644)
+	restore := system.SetIPForwardPathForTest(ipfPath)
+	defer restore()
+
+	hostapdCmd := fmt.Sprintf("hostapd -B -P %s %s", mgr.hostapdPidFile, mgr.hostapdConfFile)
+	dnsmasqCmd := fmt.Sprintf("dnsmasq -C %s -x %s", mgr.dnsmasqConfFile, mgr.dnsmasqPidFile)
+	hostapdPid, cleanHostapd := startFakeProcess("hostapd")
+	defer cleanHostapd()
+	dnsmasqPid, cleanDnsmasq := startFakeProcess("dnsmasq")
+	defer cleanDnsmasq()
+	executor.callbacks[hostapdCmd] = func() {
+		os.WriteFile(mgr.hostapdPidFile, []byte(hostapdPid), 0644)
+	}
+	executor.callbacks[dnsmasqCmd] = func() {
+		os.WriteFile(mgr.dnsmasqPidFile, []byte(dnsmasqPid), 0644)
+	}
+
+	config := &types.HotspotConfig{
+		Interface: "wlan0",
+		SSID:      "TestAP",
+		Channel:   6,
+		Gateway:   "192.168.50.1",
+		IPRange:   "192.168.50.50,192.168.50.150",
+	}
+	err := mgr.Start(config)
+	assert.NoError(t, err)
+
+	// The AP runs on a virtual interface with its own MAC, on the channel of
+	// the connection; wlan0 itself is left alone.
+	assert.Equal(t, []fake.WirelessCall{{Parent: "wlan0", Name: "wlan0_ap", Type: "ap"}}, links.AddedWireless)
+	assert.Equal(t, "02:11:22:33:44:55", links.MACs["wlan0_ap"])
+	assert.NotContains(t, links.Downed, "wlan0")
+	assert.NotContains(t, executor.called, "iw wlan0 set type __ap")
+	assert.Contains(t, addrs.Added, fake.AddrCall{Iface: "wlan0_ap", CIDR: "192.168.50.1/24"})
+	assert.Contains(t, fw.Enabled, fwfake.NATCall{Internal: "wlan0_ap", Out: "wlan0"})
+	assert.Equal(t, 6, config.Channel, "the caller's config is not modified")
+
+	conf, _ := os.ReadFile(mgr.hostapdConfFile)
+	assert.Contains(t, string(conf), "interface=wlan0_ap\n")
+	assert.Contains(t, string(conf), "hw_mode=a\nchannel=44\n")
+	conf, _ = os.ReadFile(mgr.dnsmasqConfFile)
+	assert.Contains(t, string(conf), "interface=wlan0_ap\n")
+
+	status, err := mgr.GetStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, "wlan0_ap", status.Interface)
+	assert.Equal(t, "wlan0", status.Station)
+	assert.Equal(t, 44, status.Channel)
+	assert.Equal(t, 44, status.StationChannel)
+
+	// Another process reads the channel from the state; after wlan0 roamed
+	// to channel 149 the hotspot is left behind on 44.
+	mgr.currentConfig, mgr.station = nil, ""
+	mgr.wireless.(*fake.WirelessManager).Links["wlan0"].Frequency = 5745
+	status, err = mgr.GetStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, "wlan0", status.Station)
+	assert.Equal(t, 44, status.Channel)
+	assert.Equal(t, 149, status.StationChannel)
+
+	// Stop removes the virtual interface, even after a restart.
+	mgr.currentConfig, mgr.station, mgr.outInterface = nil, "", ""
+	assert.NoError(t, mgr.Stop())
+	assert.Equal(t, []string{"wlan0_ap"}, links.Deleted)
+	assert.NotContains(t, links.Downed, "wlan0")
+	assert.NotContains(t, executor.called, "iw wlan0 set type managed")
+}
+
+func TestStart_ConcurrentAPFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		combs    []types.InterfaceCombination
+		freq     int
+		setup    func(links *fake.LinkManager, wireless *fake.WirelessManager)
+		wantErr  string
+		wantGone bool
+	}{
+		{
+			name:    "no combination",
+			combs:   []types.InterfaceCombination{{Limits: []types.InterfaceLimit{{Max: 1, Types: []string{"station", "ap"}}}, Max: 1, Channels: 1}},
+			wantErr: `wlan0 is connected to "Cafe" and its driver cannot run an access point at the same time (no valid interface combination of phy#0 has both a station and an AP); disconnect it first or use another radio`,
+		},
+		{
+			name:    "6 GHz",
+			combs:   apStaCombinations,
+			freq:    5975,
+			wantErr: "wlan0 is connected on 5975 MHz, which the hotspot cannot share (it supports 2.4GHz and 5GHz channels); disconnect it first or use another radio",
+		},
+		{
+			name:  "combinations unreadable",
+			combs: apStaCombinations,
+			setup: func(links *fake.LinkManager, wireless *fake.WirelessManager) {
+				wireless.CombErr = fmt.Errorf("nl80211 is not available")
+			},
+			wantErr: "failed to read the interface combinations of phy#0: nl80211 is not available",
+		},
+		{
+			name:  "interface not created",
+			combs: apStaCombinations,
+			setup: func(links *fake.LinkManager, wireless *fake.WirelessManager) {
+				links.AddWiErr = fmt.Errorf("operation not supported")
+			},
+			wantErr: "failed to create AP interface: operation not supported",
+		},
+		{
+			name:  "setup fails",
+			combs: apStaCombinations,
+			setup: func(links *fake.LinkManager, wireless *fake.WirelessManager) {
+				links.SetUpErr = fmt.Errorf("device busy")
+			},
+			wantErr:  "failed to bring interface up: device busy",
+			wantGone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, _, links, _, _, _ := setupTestManager()
+			defer cleanup(mgr)
+			wireless := connectedRadio(tt.combs)
+			if tt.freq != 0 {
+				wireless.Links["wlan0"].Frequency = tt.freq
+			}
+			mgr.wireless = wireless
+			links.MACs = map[string]string{"wlan0": "00:11:22:33:44:55"}
+			if tt.setup != nil {
+				tt.setup(links, wireless)
+			}
+
+			err := mgr.Start(&types.HotspotConfig{
+				Interface: "wlan0",
+				SSID:      "TestAP",
+				Channel:   6,
+				Gateway:   "192.168.50.1",
+				IPRange:   "192.168.50.50,192.168.50.150",
+			})
+			assert.EqualError(t, err, tt.wantErr)
+			assert.NotContains(t, links.Downed, "wlan0", "the connection is left alone")
+			if tt.wantGone {
+				assert.Equal(t, []string{"wlan0_ap"}, links.Deleted)
+			}
+			assert.Empty(t, mgr.station)
+		})
+	}
+}
+
+func TestApInterfaceNameAndMAC(t *testing.T) {
+	assert.Equal(t, "wlan0_ap", apInterfaceName("wlan0"))
+	assert.Equal(t, "wlx00c0ca123_ap", apInterfaceName("wlx00c0ca123456"))
+
+	mac, err := apMAC("00:11:22:33:44:55")
+	assert.NoError(t, err)
+	assert.Equal(t, "02:11:22:33:44:55", mac)
+	mac, err = apMAC("3a:11:22:33:44:55")
+	assert.NoError(t, err)
+	assert.Equal(t, "3a:11:22:33:44:54", mac)
+}
+
+func TestSupportsCombination(t *testing.T) {
+	assert.True(t, supportsCombination(apStaCombinations, "station", "ap"))
+	assert.False(t, supportsCombination(apStaCombinations, "station", "station"))
+	assert.False(t, supportsCombination(nil, "station", "ap"))
+
+	// Overlapping limits: the station must not take the only AP slot.
+	shared := []types.InterfaceCombination{{
+		Limits: []types.InterfaceLimit{
+			{Max: 1, Types: []string{"station", "ap"}},
+			{Max: 1, Types: []string{"station"}},
+		},
+		Max:      2,
+		Channels: 1,
+	}}
+	assert.True(t, supportsCombination(shared, "station", "ap"))
+	shared[0].Max = 1
+	assert.False(t, supportsCombination(shared, "station", "ap"))
+}
+
 func TestStart_InvalidConfig(t *testing.T) {
 	mgr, _, _, _, _, _ := setupTestManager()
 	defer cleanup(mgr)
@@ -292,6 +512,31 @@ func TestStart_InvalidConfig(t *testing.T) {
 			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: strings.Repeat("a", 64), Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
 			errMsg: "password must be 8-63 characters",
 		},
+		{
+			name:   "wpa3 without password",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Security: "wpa3", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
+			errMsg: "security wpa3 requires a password of at least 8 characters",
+		},
+		{
+			name:   "wpa3 short password",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Security: "wpa3", Password: "short", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
+			errMsg: "security wpa3 requires a password of at least 8 characters",
+		},
+		{
+			name:   "transition without password",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Security: "wpa2-wpa3", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
+			errMsg: "security wpa2-wpa3 requires a password",
+		},
+		{
+			name:   "owe with password",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Security: "owe", Password: "testpass123", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
+			errMsg: "security owe does not use a password",
+		},
+		{
+			name:   "unknown security",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Security: "wep", Password: "testpass123", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
+			errMsg: `invalid security "wep" (valid: wpa2, wpa3, wpa2-wpa3, open, owe)`,
+		},
 		{
 			name:   "invalid channel low",
 			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 0, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
@@ -302,6 +547,26 @@ func TestStart_InvalidConfig(t *testing.T) {
 			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 30, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150"},
 			errMsg: "invalid channel",
 		},
+		{
+			name:   "invalid deny MAC",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150", Deny: []string{"phone"}},
+			errMsg: `invalid MAC address in deny/allow list: "phone"`,
+		},
+		{
+			name:   "too many clients",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150", MaxClients: 3000},
+			errMsg: "invalid max_clients 3000 (valid: 1-2007, or 0 for no limit)",
+		},
+		{
+			name:   "invalid uplink",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150", Uplink: "eth 0"},
+			errMsg: `invalid uplink "eth 0"`,
+		},
+		{
+			name:   "uplink is the hotspot interface",
+			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 6, Gateway: "192.168.1.1", IPRange: "192.168.1.50,192.168.1.150", Uplink: "wlan0"},
+			errMsg: `uplink "wlan0" is the hotspot interface`,
+		},
 		{
 			name:   "missing gateway",
 			config: &types.HotspotConfig{Interface: "wlan0", SSID: "Test", Password: "testpass123", Channel: 6, IPRange: "192.168.1.50,192.168.1.150"},
@@ -521,6 +786,140 @@ func TestStop_CleansNATWhenDaemonsAlreadyDead(t *testing.T) {
 		"ip_forward must be restored to the recorded prior value (0)")
 }
 
+// mockDaemons makes hostapd and dnsmasq start successfully on wlan0, backed
+// by fake processes that are cleaned up with the test.
+func mockDaemons(t *testing.T, mgr *hotspotManagerImpl, executor *mockExecutor) {
+	t.Helper()
+	executor.commands["iw wlan0 set type __ap"] = ""
+	hostapdCmd := fmt.Sprintf("hostapd -B -P %s %s", mgr.hostapdPidFile, mgr.hostapdConfFile)
+	dnsmasqCmd := fmt.Sprintf("dnsmasq -C %s -x %s", mgr.dnsmasqConfFile, mgr.dnsmasqPidFile)
+	executor.commands[hostapdCmd] = ""
+	executor.commands[dnsmasqCmd] = ""
+	hostapdPid, cleanHostapd := startFakeProcess("hostapd")
+	t.Cleanup(cleanHostapd)
+	dnsmasqPid, cleanDnsmasq := startFakeProcess("dnsmasq")
+	t.Cleanup(cleanDnsmasq)
+	executor.callbacks[hostapdCmd] = func() {
+		os.WriteFile(mgr.hostapdPidFile, []byte(hostapdPid), 0644)
+	}
+	executor.callbacks[dnsmasqCmd] = func() {
+		os.WriteFile(mgr.dnsmasqPidFile, []byte(dnsmasqPid), 0644)
+	}
+}
+
+// connectVPN records wg0 as the active tunnel in a temp VPN state directory.
+func connectVPN(t *testing.T, mgr *hotspotManagerImpl) {
+	t.Helper()
+	mgr.vpnStateDir = t.TempDir()
+	os.WriteFile(filepath.Join(mgr.vpnStateDir, "active-vpn"), []byte("travel|wg0|wireguard|192.168.1.1|eth0|"), 0600)
+}
+
+func TestStart_Uplink(t *testing.T) {
+	ipfPath := filepath.Join(t.TempDir(), "ip_forward")
+	os.WriteFile(ipfPath, []byte("0"), 0644)
+	restore := system.SetIPForwardPathForTest(ipfPath)
+	defer restore()
+
+	config := func(uplink string) *types.HotspotConfig {
+		return &types.HotspotConfig{
+			Interface: "wlan0",
+			SSID:      "TestAP",
+			Password:  "testpass123",
+			Channel:   6,
+			Gateway:   "192.168.50.1",
+			IPRange:   "192.168.50.50,192.168.50.150",
+			Uplink:    uplink,
+		}
+	}
+
+	t.Run("interface", func(t *testing.T) {
+		mgr, executor, _, fw, _, routes := setupTestManager()
+		defer cleanup(mgr)
+		mockDaemons(t, mgr, executor)
+
+		assert.NoError(t, mgr.Start(config("usb0")))
+		assert.Equal(t, []fwfake.NATCall{{Internal: "wlan0", Out: "usb0"}}, fw.Enabled)
+		assert.Empty(t, fw.Restricted)
+		assert.Empty(t, routes.Rules)
+	})
+
+	t.Run("vpn", func(t *testing.T) {
+		mgr, executor, _, fw, _, routes := setupTestManager()
+		defer cleanup(mgr)
+		mockDaemons(t, mgr, executor)
+		connectVPN(t, mgr)
+
+		assert.NoError(t, mgr.Start(config("vpn")))
+		assert.Equal(t, []fwfake.NATCall{{Internal: "wlan0", Out: "wg0"}}, fw.Enabled)
+		assert.Equal(t, map[string]string{"wlan0": "wg0"}, fw.Restricted)
+		assert.Equal(t, "wg0", routes.TableDefaults[types.VPNRouteTable])
+		assert.Equal(t, map[string]int{"wlan0": types.VPNRouteTable}, routes.Rules)
+		assert.FileExists(t, filepath.Join(mgr.vpnStateDir, "vpn-shares", "wlan0"), "a VPN reconnect must find the share")
+
+		// dnsmasq's own queries are bound to the tunnel too
+		conf, err := os.ReadFile(mgr.dnsmasqConfFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(conf), "server=8.8.8.8@wg0\nserver=8.8.4.4@wg0\n")
+
+		// A later Stop (another process) must know to lift the restriction
+		data, err := os.ReadFile(mgr.stateFile)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(string(data), "|vpn|6"), "state %q must record the vpn uplink", data)
+
+		assert.NoError(t, mgr.Stop())
+		assert.Contains(t, fw.Disabled, fwfake.NATCall{Internal: "wlan0", Out: "wg0"})
+		assert.Empty(t, fw.Restricted)
+		assert.Empty(t, routes.Rules)
+		assert.NoFileExists(t, filepath.Join(mgr.vpnStateDir, "vpn-shares", "wlan0"))
+	})
+
+	t.Run("vpn without a tunnel", func(t *testing.T) {
+		mgr, executor, links, fw, _, _ := setupTestManager()
+		defer cleanup(mgr)
+		mockDaemons(t, mgr, executor)
+		mgr.vpnStateDir = t.TempDir()
+
+		err := mgr.Start(config("vpn"))
+		assert.EqualError(t, err, "uplink is vpn, but no VPN is connected")
+		assert.Empty(t, links.Downed, "the radio must not be touched")
+		assert.Empty(t, fw.Enabled)
+	})
+
+	t.Run("vpn routing fails", func(t *testing.T) {
+		mgr, executor, _, fw, _, _ := setupTestManager()
+		defer cleanup(mgr)
+		mockDaemons(t, mgr, executor)
+		connectVPN(t, mgr)
+		fw.RestrictErr = fmt.Errorf("iptables unavailable")
+
+		err := mgr.Start(config("vpn"))
+		assert.ErrorContains(t, err, "failed to route clients through the VPN")
+		assert.Empty(t, fw.Enabled, "NAT must not be enabled without the restriction")
+		assert.False(t, mgr.isRunning(), "the hotspot must not run half set up")
+	})
+}
+
+// Recovering from the state file must lift the VPN restriction too.
+func TestStop_RemovesVPNRoutingFromState(t *testing.T) {
+	mgr, executor, _, fw, _, routes := setupTestManager()
+	defer cleanup(mgr)
+
+	ipfPath := filepath.Join(t.TempDir(), "ip_forward")
+	os.WriteFile(ipfPath, []byte("1"), 0644)
+	restore := system.SetIPForwardPathForTest(ipfPath)
+	defer restore()
+
+	fw.Restricted = map[string]string{"wlan0": "wg0"}
+	routes.Rules = map[string]int{"wlan0": types.VPNRouteTable}
+	os.WriteFile(mgr.stateFile, []byte("wlan0|wg0|0||vpn"), 0600)
+	executor.commands["iw wlan0 set type managed"] = ""
+
+	assert.NoError(t, mgr.Stop())
+	assert.Contains(t, fw.Disabled, fwfake.NATCall{Internal: "wlan0", Out: "wg0"})
+	assert.Empty(t, fw.Restricted)
+	assert.Empty(t, routes.Rules)
+}
+
 func TestStop_PartialFailure(t *testing.T) {
 	mgr, executor, _, _, _, _ := setupTestManager()
 	defer cleanup(mgr)
@@ -554,7 +953,7 @@ func TestStop_PartialFailure(t *testing.T) {
 }
 
 func TestGetStatus(t *testing.T) {
-	mgr, executor, _, _, _, _ := setupTestManager()
+	mgr, _, _, _, _, _ := setupTestManager()
 	defer cleanup(mgr)
 
 	// Test when not running
@@ -577,10 +976,9 @@ func TestGetStatus(t *testing.T) {
 	os.WriteFile(mgr.hostapdPidFile, []byte(hostapdPid), 0644)
 	os.WriteFile(mgr.dnsmasqPidFile, []byte(dnsmasqPid), 0644)
 
-	executor.commands["iw dev wlan0 station dump"] = `Station aa:bb:cc:dd:ee:ff (on wlan0)
-	inactive time:	304 ms
-Station 11:22:33:44:55:66 (on wlan0)
-	inactive time:	104 ms`
+	mgr.hostapd = &wpafake.Hostapd{Clients: map[string][]types.HotspotClient{"wlan0": {
+		{MAC: "aa:bb:cc:dd:ee:ff"}, {MAC: "11:22:33:44:55:66"},
+	}}}
 
 	status, err = mgr.GetStatus()
 	assert.NoError(t, err)
@@ -590,20 +988,112 @@ Station 11:22:33:44:55:66 (on wlan0)
 	assert.Equal(t, 2, status.Clients)
 }
 
+func TestClients(t *testing.T) {
+	mgr, _, _, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+
+	// Not running
+	_, err := mgr.Clients()
+	assert.EqualError(t, err, "hotspot is not running")
+
+	// Running, with the interface recovered from the state file
+	os.WriteFile(mgr.stateFile, []byte("wlan0_ap|eth0|0|wlan0"), 0600)
+	hostapdPid, cleanHostapd := startFakeProcess("hostapd")
+	defer cleanHostapd()
+	os.WriteFile(mgr.hostapdPidFile, []byte(hostapdPid), 0644)
+	mgr.hostapd = &wpafake.Hostapd{Clients: map[string][]types.HotspotClient{"wlan0_ap": {
+		{MAC: "00:1b:63:aa:bb:cc", Signal: -52, ConnectedTime: 10 * time.Minute, RxBytes: 2048, TxBytes: 1 << 20},
+		{MAC: "02:11:22:33:44:55", ConnectedTime: 2 * time.Second},
+		{MAC: "02:66:77:88:99:aa"},
+	}}}
+	os.WriteFile(mgr.leasesFile, []byte("1700000000 00:1b:63:aa:bb:cc 192.168.50.23 macbook 01:00:1b:63:aa:bb:cc\n"+
+		"1700000000 02:11:22:33:44:55 192.168.50.24 * *\n"), 0644)
+
+	clients, err := mgr.Clients()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.HotspotClient{
+		{MAC: "00:1b:63:aa:bb:cc", IP: "192.168.50.23", Hostname: "macbook", Vendor: "Apple",
+			Signal: -52, ConnectedTime: 10 * time.Minute, RxBytes: 2048, TxBytes: 1 << 20},
+		{MAC: "02:11:22:33:44:55", IP: "192.168.50.24", ConnectedTime: 2 * time.Second},
+		{MAC: "02:66:77:88:99:aa"},
+	}, clients)
+
+	// Without a lease file the stations are still listed
+	os.Remove(mgr.leasesFile)
+	clients, err = mgr.Clients()
+	assert.NoError(t, err)
+	assert.Len(t, clients, 3)
+
+	mgr.hostapd = &wpafake.Hostapd{StationsErr: fmt.Errorf("connecting to hostapd on wlan0_ap: no such file or directory")}
+	_, err = mgr.Clients()
+	assert.EqualError(t, err, "failed to list hotspot clients: connecting to hostapd on wlan0_ap: no such file or directory")
+}
+
+func TestKick(t *testing.T) {
+	mgr, _, _, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+
+	mgr.currentConfig = &types.HotspotConfig{Interface: "wlan0"}
+	hostapdPid, cleanHostapd := startFakeProcess("hostapd")
+	defer cleanHostapd()
+	os.WriteFile(mgr.hostapdPidFile, []byte(hostapdPid), 0644)
+	hostapd := &wpafake.Hostapd{Clients: map[string][]types.HotspotClient{"wlan0": {{MAC: "02:11:22:33:44:55"}}}}
+	mgr.hostapd = hostapd
+
+	assert.EqualError(t, mgr.Kick("phone"), `invalid MAC address "phone"`)
+	assert.EqualError(t, mgr.Kick("02:00:00:00:00:01"), "02:00:00:00:00:01 is not connected to the hotspot")
+	assert.Empty(t, hostapd.Deauthed)
+
+	assert.NoError(t, mgr.Kick("02:11:22:33:44:55"))
+	assert.Equal(t, []wpafake.DeauthCall{{Iface: "wlan0", MAC: "02:11:22:33:44:55"}}, hostapd.Deauthed)
+
+	// Denied in the file and in the running hostapd, so it stays out
+	data, err := os.ReadFile(mgr.denyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "02:11:22:33:44:55\n", string(data))
+	assert.Equal(t, []string{"02:11:22:33:44:55"}, hostapd.Deny["wlan0"])
+	assert.Empty(t, hostapd.Accept["wlan0"])
+
+	hostapd.Clients["wlan0"] = []types.HotspotClient{{MAC: "02:11:22:33:44:55"}}
+	hostapd.DeauthErr = fmt.Errorf("DEAUTHENTICATE 02:11:22:33:44:55: FAIL")
+	assert.EqualError(t, mgr.Kick("02:11:22:33:44:55"), "failed to disconnect 02:11:22:33:44:55: DEAUTHENTICATE 02:11:22:33:44:55: FAIL")
+
+	hostapd.ACLErr = fmt.Errorf("DENY_ACL ADD_MAC 02:11:22:33:44:55: FAIL")
+	assert.EqualError(t, mgr.Kick("02:11:22:33:44:55"), "failed to deny 02:11:22:33:44:55: DENY_ACL ADD_MAC 02:11:22:33:44:55: FAIL")
+}
+
+func TestKick_AllowList(t *testing.T) {
+	mgr, _, _, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+
+	mgr.currentConfig = &types.HotspotConfig{Interface: "wlan0"}
+	hostapdPid, cleanHostapd := startFakeProcess("hostapd")
+	defer cleanHostapd()
+	os.WriteFile(mgr.hostapdPidFile, []byte(hostapdPid), 0644)
+	os.WriteFile(mgr.acceptFile, []byte("02:11:22:33:44:55\n02:66:77:88:99:aa\n"), 0600)
+	os.WriteFile(mgr.denyFile, []byte("aa:bb:cc:dd:ee:ff\n"), 0600)
+	hostapd := &wpafake.Hostapd{Clients: map[string][]types.HotspotClient{"wlan0": {{MAC: "02:11:22:33:44:55"}}}}
+	mgr.hostapd = hostapd
+
+	// hostapd checks the accept list first, so a kicked MAC leaves it
+	assert.NoError(t, mgr.Kick("02:11:22:33:44:55"))
+	assert.Equal(t, []string{"02:66:77:88:99:aa"}, hostapd.Accept["wlan0"])
+	assert.Equal(t, []string{"aa:bb:cc:dd:ee:ff", "02:11:22:33:44:55"}, hostapd.Deny["wlan0"])
+	data, _ := os.ReadFile(mgr.acceptFile)
+	assert.Equal(t, "02:66:77:88:99:aa\n", string(data))
+}
+
 func TestGetConnectedClients(t *testing.T) {
-	mgr, executor, _, _, _, _ := setupTestManager()
+	mgr, _, _, _, _, _ := setupTestManager()
 	defer cleanup(mgr)
 
 	mgr.currentConfig = &types.HotspotConfig{
 		Interface: "wlan0",
 	}
 
-	executor.commands["iw dev wlan0 station dump"] = `Station aa:bb:cc:dd:ee:ff (on wlan0)
-	inactive time:	304 ms
-	rx bytes:	12345
-Station 11:22:33:44:55:66 (on wlan0)
-	inactive time:	104 ms
-	rx bytes:	67890`
+	mgr.hostapd = &wpafake.Hostapd{Clients: map[string][]types.HotspotClient{"wlan0": {
+		{MAC: "aa:bb:cc:dd:ee:ff", RxBytes: 12345}, {MAC: "11:22:33:44:55:66", RxBytes: 67890},
+	}}}
 
 	clients, err := mgr.getConnectedClients()
 	assert.NoError(t, err)
@@ -611,14 +1101,14 @@ Station 11:22:33:44:55:66 (on wlan0)
 }
 
 func TestGetConnectedClients_Error(t *testing.T) {
-	mgr, executor, _, _, _, _ := setupTestManager()
+	mgr, _, _, _, _, _ := setupTestManager()
 	defer cleanup(mgr)
 
 	mgr.currentConfig = &types.HotspotConfig{
 		Interface: "wlan0",
 	}
 
-	executor.errors["iw dev wlan0 station dump"] = fmt.Errorf("interface not found")
+	mgr.hostapd = &wpafake.Hostapd{StationsErr: fmt.Errorf("connecting to hostapd on wlan0: no such file or directory")}
 
 	_, err := mgr.getConnectedClients()
 	assert.Error(t, err)
@@ -673,6 +1163,74 @@ func TestGenerateHostapdConfig_NoPassword(t *testing.T) {
 	assert.NotContains(t, content, "wpa=2")
 }
 
+func TestGenerateHostapdConfig_Security(t *testing.T) {
+	mgr, _, _, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+
+	tests := []struct {
+		security string
+		password string
+		want     string
+	}{
+		{"wpa3", strings.Repeat("x", 70),
+			"auth_algs=1\nwpa=2\nsae_password=" + strings.Repeat("x", 70) + "\nwpa_key_mgmt=SAE\nrsn_pairwise=CCMP\nieee80211w=2\n"},
+		{"wpa2-wpa3", "testpass123",
+			"auth_algs=1\nwpa=2\nwpa_passphrase=testpass123\nwpa_key_mgmt=WPA-PSK SAE\nrsn_pairwise=CCMP\nieee80211w=1\n"},
+		{"owe", "",
+			"wpa=2\nwpa_key_mgmt=OWE\nrsn_pairwise=CCMP\nieee80211w=2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.security, func(t *testing.T) {
+			config := &types.HotspotConfig{Interface: "wlan0", SSID: "TestAP", Password: tt.password, Security: tt.security, Channel: 6}
+			assert.NoError(t, validateSecurity(config))
+			assert.NoError(t, mgr.generateHostapdConfig(config))
+
+			data, err := os.ReadFile(mgr.hostapdConfFile)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasSuffix(string(data), "ignore_broadcast_ssid=0\n"+tt.want), string(data))
+		})
+	}
+
+	// An explicit open network gets no RSN settings at all.
+	config := &types.HotspotConfig{Interface: "wlan0", SSID: "OpenAP", Security: "open", Channel: 6}
+	assert.NoError(t, mgr.generateHostapdConfig(config))
+	data, _ := os.ReadFile(mgr.hostapdConfFile)
+	assert.NotContains(t, string(data), "wpa")
+}
+
+func TestGenerateHostapdConfig_AccessControl(t *testing.T) {
+	mgr, _, _, _, _, _ := setupTestManager()
+	defer cleanup(mgr)
+
+	// No lists: everyone may connect
+	config := &types.HotspotConfig{Interface: "wlan0", SSID: "TestAP", Channel: 6}
+	assert.NoError(t, mgr.generateHostapdConfig(config))
+	data, _ := os.ReadFile(mgr.hostapdConfFile)
+	assert.Contains(t, string(data), "macaddr_acl=0\n")
+	assert.Contains(t, string(data), "ctrl_interface=/run/hostapd\n")
+	assert.NotContains(t, string(data), "deny_mac_file")
+	assert.NotContains(t, string(data), "max_num_sta")
+
+	// Deny only
+	config.Deny = []string{"AA:BB:CC:DD:EE:FF"}
+	config.MaxClients = 5
+	assert.NoError(t, mgr.generateHostapdConfig(config))
+	data, _ = os.ReadFile(mgr.hostapdConfFile)
+	assert.Contains(t, string(data), "macaddr_acl=0\n")
+	assert.Contains(t, string(data), "deny_mac_file="+mgr.denyFile+"\n")
+	assert.Contains(t, string(data), "max_num_sta=5\n")
+	deny, _ := os.ReadFile(mgr.denyFile)
+	assert.Equal(t, "aa:bb:cc:dd:ee:ff\n", string(deny))
+
+	// Allow minus deny: hostapd would let a MAC on both lists in
+	config.Allow = []string{"02:11:22:33:44:55", "aa:bb:cc:dd:ee:ff"}
+	assert.NoError(t, mgr.generateHostapdConfig(config))
+	data, _ = os.ReadFile(mgr.hostapdConfFile)
+	assert.Contains(t, string(data), "macaddr_acl=1\naccept_mac_file="+mgr.acceptFile+"\n")
+	accept, _ := os.ReadFile(mgr.acceptFile)
+	assert.Equal(t, "02:11:22:33:44:55\n", string(accept))
+}
+
 func TestGenerateHostapdConfig_5GHz(t *testing.T) {
 	mgr, _, _, _, _, _ := setupTestManager()
 	defer cleanup(mgr)
@@ -11,29 +11,41 @@ var _ types.LinkManager = (*LinkManager)(nil)
 //
 // Existing tracks which interfaces exist (for Exists / Delete / GetMAC).
 // ByType maps a link type to interface names (for ListByType). MACs maps an
-// interface to its current MAC (for GetMAC). All mutating calls are recorded.
+// interface to its current MAC (for GetMAC). AddWirelessInterface marks the
+// new interface existent and gives it its parent's MAC, as the kernel does.
+// All mutating calls are recorded.
 // Set the *Err fields to force a method to fail.
 type LinkManager struct {
 	Existing map[string]bool
 	ByType   map[string][]string
 	MACs     map[string]string
 
-	Upped       []string
-	Downed      []string
-	Deleted     []string
-	AddedWG     []string
-	SetMACCalls []MACCall
+	Upped         []string
+	Downed        []string
+	Deleted       []string
+	AddedWG       []string
+	AddedWireless []WirelessCall
+	SetMACCalls   []MACCall
 
 	SetUpErr   error
 	SetDownErr error
 	DeleteErr  error
 	ExistsErr  error
 	AddWGErr   error
+	AddWiErr   error
 	ListErr    error
 	GetMACErr  error
 	SetMACErr  error
 }
 
+// WirelessCall records the arguments of a single AddWirelessInterface
+// invocation.
+type WirelessCall struct {
+	Parent string
+	Name   string
+	Type   string
+}
+
 // MACCall records the arguments of a single SetMAC invocation.
 type MACCall struct {
 	Iface string
@@ -91,6 +103,22 @@ func (m *LinkManager) AddWireGuard(iface string) error {
 	return nil
 }
 
+// AddWirelessInterface records the call and marks the interface existent.
+func (m *LinkManager) AddWirelessInterface(parent, name, ifType string) error {
+	if m.AddWiErr != nil {
+		return m.AddWiErr
+	}
+	m.AddedWireless = append(m.AddedWireless, WirelessCall{Parent: parent, Name: name, Type: ifType})
+	if m.Existing == nil {
+		m.Existing = map[string]bool{}
+	}
+	m.Existing[name] = true
+	if mac, ok := m.MACs[parent]; ok {
+		m.MACs[name] = mac
+	}
+	return nil
+}
+
 // ListByType returns the configured interface names for the given type.
 func (m *LinkManager) ListByType(linkType string) ([]string, error) {
 	if m.ListErr != nil {
@@ -35,6 +35,11 @@ type RouteManager struct {
 	DeletedRoutes []string
 	// Flushed records the interface of every FlushRoutes call in order.
 	Flushed []string
+	// TableDefaults maps each routing table to the interface of the default
+	// route SetTableDefault installed in it.
+	TableDefaults map[int]string
+	// Rules maps each policy rule's incoming interface to its table.
+	Rules map[string]int
 
 	// Force errors from specific methods when set.
 	GetErr          error
@@ -46,6 +51,8 @@ type RouteManager struct {
 	ReplaceRouteErr error
 	DelRouteErr     error
 	FlushErr        error
+	TableErr        error
+	RuleErr         error
 }
 
 // ReplaceCall records the arguments of a single ReplaceDefault invocation.
@@ -195,3 +202,35 @@ func (m *RouteManager) ListRoutes() ([]types.Route, error) {
 	}
 	return m.Routes, nil
 }
+
+// SetTableDefault records iface as the default route of table.
+func (m *RouteManager) SetTableDefault(table int, iface string) error {
+	if m.TableErr != nil {
+		return m.TableErr
+	}
+	if m.TableDefaults == nil {
+		m.TableDefaults = make(map[int]string)
+	}
+	m.TableDefaults[table] = iface
+	return nil
+}
+
+// AddPolicyRule records the rule.
+func (m *RouteManager) AddPolicyRule(iif string, table int) error {
+	if m.RuleErr != nil {
+		return m.RuleErr
+	}
+	if m.Rules == nil {
+		m.Rules = make(map[string]int)
+	}
+	m.Rules[iif] = table
+	return nil
+}
+
+// DelPolicyRule removes the recorded rule.
+func (m *RouteManager) DelPolicyRule(iif string, table int) error {
+	if m.Rules[iif] == table {
+		delete(m.Rules, iif)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package fake
+
+import (
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Compile-time assertion that the fake satisfies the interface.
+var _ types.WirelessManager = (*WirelessManager)(nil)
+
+// WirelessManager is an in-memory fake implementation of
+// types.WirelessManager.
+//
+// Ifaces is returned by Interfaces and drives InterfaceType. Scans maps an
+// interface to its scan cache (for ScanResults); Links maps an interface to
+// its association (for LinkInfo; missing means not associated). Combinations
+// maps a radio to its interface combinations, Freqs to its channels.
+// TriggerScan calls are recorded, and when FreshScans has an entry for the
+// interface it replaces the scan cache, as a real scan would. Set the *Err
+// fields to force a method to fail.
+type WirelessManager struct {
+	Ifaces       []types.WirelessInterface
+	Scans        map[string][]types.BSS
+	FreshScans   map[string][]types.BSS
+	Links        map[string]*types.WirelessLink
+	Combinations map[int][]types.InterfaceCombination
+	Freqs        map[int][]types.RadioFrequency
+
+	Triggered []string
+
+	InterfacesErr error
+	TriggerErr    error
+	ScanErr       error
+	LinkErr       error
+	CombErr       error
+	FreqErr       error
+}
+
+// Interfaces returns Ifaces.
+func (m *WirelessManager) Interfaces() ([]types.WirelessInterface, error) {
+	if m.InterfacesErr != nil {
+		return nil, m.InterfacesErr
+	}
+	return m.Ifaces, nil
+}
+
+// InterfaceType returns the type of iface in Ifaces, or "" when absent.
+func (m *WirelessManager) InterfaceType(iface string) (string, error) {
+	if m.InterfacesErr != nil {
+		return "", m.InterfacesErr
+	}
+	for _, wi := range m.Ifaces {
+		if wi.Name == iface {
+			return wi.Type, nil
+		}
+	}
+	return "", nil
+}
+
+// TriggerScan records the call and moves FreshScans[iface], if any, into the
+// scan cache.
+func (m *WirelessManager) TriggerScan(iface string, timeout time.Duration) error {
+	m.Triggered = append(m.Triggered, iface)
+	if m.TriggerErr != nil {
+		return m.TriggerErr
+	}
+	if fresh, ok := m.FreshScans[iface]; ok {
+		if m.Scans == nil {
+			m.Scans = map[string][]types.BSS{}
+		}
+		m.Scans[iface] = fresh
+	}
+	return nil
+}
+
+// ScanResults returns the scan cache of iface.
+func (m *WirelessManager) ScanResults(iface string) ([]types.BSS, error) {
+	if m.ScanErr != nil {
+		return nil, m.ScanErr
+	}
+	return m.Scans[iface], nil
+}
+
+// LinkInfo returns the configured association of iface, or nil.
+func (m *WirelessManager) LinkInfo(iface string) (*types.WirelessLink, error) {
+	if m.LinkErr != nil {
+		return nil, m.LinkErr
+	}
+	return m.Links[iface], nil
+}
+
+// InterfaceCombinations returns Combinations[phy].
+func (m *WirelessManager) InterfaceCombinations(phy int) ([]types.InterfaceCombination, error) {
+	if m.CombErr != nil {
+		return nil, m.CombErr
+	}
+	return m.Combinations[phy], nil
+}
+
+// Frequencies returns Freqs[phy].
+func (m *WirelessManager) Frequencies(phy int) ([]types.RadioFrequency, error) {
+	if m.FreqErr != nil {
+		return nil, m.FreqErr
+	}
+	return m.Freqs[phy], nil
+}
+
+// BSS returns a scan entry for an open network, with ssid as its only
+// information element. Append further elements to IEs as needed.
+func BSS(bssid, ssid string, frequency, signal int) types.BSS {
+	return types.BSS{
+		BSSID:     bssid,
+		Frequency: frequency,
+		Signal:    signal,
+		IEs:       append([]byte{0, byte(len(ssid))}, ssid...),
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"net"
 
+	mnl "github.com/mdlayher/netlink"
 	vnl "github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 // LinkManager is the Linux/netlink implementation of types.LinkManager.
@@ -42,7 +44,8 @@ func (m *LinkManager) SetDown(iface string) error {
 	return nil
 }
 
-// Delete removes a virtual interface (e.g. a WireGuard device).
+// Delete removes a virtual interface (e.g. a WireGuard device or a virtual
+// wireless interface).
 func (m *LinkManager) Delete(iface string) error {
 	link, err := vnl.LinkByName(iface)
 	if err != nil {
@@ -51,7 +54,12 @@ func (m *LinkManager) Delete(iface string) error {
 		}
 		return fmt.Errorf("resolving interface %q: %w", iface, err)
 	}
-	if err := vnl.LinkDel(link); err != nil {
+	err = vnl.LinkDel(link)
+	if errors.Is(err, unix.EOPNOTSUPP) {
+		// Wireless interfaces have no rtnetlink link ops; cfg80211 owns them.
+		err = deleteWirelessInterface(uint32(link.Attrs().Index))
+	}
+	if err != nil {
 		return fmt.Errorf("deleting %q: %w", iface, err)
 	}
 	return nil
@@ -78,6 +86,48 @@ func (m *LinkManager) AddWireGuard(iface string) error {
 	return nil
 }
 
+// AddWirelessInterface creates a virtual wireless interface of the given
+// nl80211 type on the radio of parent.
+func (m *LinkManager) AddWirelessInterface(parent, name, ifType string) error {
+	typ, ok := nl80211IfTypeNumber(ifType)
+	if !ok {
+		return fmt.Errorf("unknown wireless interface type %q", ifType)
+	}
+	index, err := ifindex(parent)
+	if err != nil {
+		return err
+	}
+	c, err := dialNL80211()
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+
+	_, err = c.execute(nl80211CmdNewInterface, false, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+		ae.String(nl80211AttrIfname, name)
+		ae.Uint32(nl80211AttrIftype, typ)
+	})
+	if err != nil {
+		return fmt.Errorf("creating %s interface %q on %q: %w", ifType, name, parent, err)
+	}
+	return nil
+}
+
+// deleteWirelessInterface removes a wireless interface through nl80211.
+func deleteWirelessInterface(index uint32) error {
+	c, err := dialNL80211()
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+
+	_, err = c.execute(nl80211CmdDelInterface, false, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+	})
+	return err
+}
+
 // ListByType returns the names of all interfaces of the given link type.
 func (m *LinkManager) ListByType(linkType string) ([]string, error) {
 	links, err := vnl.LinkList()
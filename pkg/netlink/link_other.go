@@ -26,6 +26,9 @@ func (m *LinkManager) Exists(iface string) (bool, error) { return false, ErrUnsu
 // AddWireGuard always returns ErrUnsupported on non-Linux platforms.
 func (m *LinkManager) AddWireGuard(iface string) error { return ErrUnsupported }
 
+// AddWirelessInterface always returns ErrUnsupported on non-Linux platforms.
+func (m *LinkManager) AddWirelessInterface(parent, name, ifType string) error { return ErrUnsupported }
+
 // ListByType always returns ErrUnsupported on non-Linux platforms.
 func (m *LinkManager) ListByType(linkType string) ([]string, error) { return nil, ErrUnsupported }
 
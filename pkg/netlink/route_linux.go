@@ -23,9 +23,10 @@ import (
 // Compile-time assertions that the netlink implementations satisfy the typed
 // manager interfaces.
 var (
-	_ types.RouteManager = (*RouteManager)(nil)
-	_ types.AddrManager  = (*AddrManager)(nil)
-	_ types.LinkManager  = (*LinkManager)(nil)
+	_ types.RouteManager    = (*RouteManager)(nil)
+	_ types.AddrManager     = (*AddrManager)(nil)
+	_ types.LinkManager     = (*LinkManager)(nil)
+	_ types.WirelessManager = (*WirelessManager)(nil)
 )
 
 // RouteManager is the Linux/netlink implementation of types.RouteManager.
@@ -311,6 +312,77 @@ func (m *RouteManager) ListRoutes() ([]types.Route, error) {
 	return out, nil
 }
 
+// tableBackstopMetric is the priority of the unreachable default route
+// SetTableDefault installs behind the device route, high enough that the
+// device route always wins while it exists.
+const tableBackstopMetric = 4096
+
+// policyRulePriority is the priority of the rules AddPolicyRule installs,
+// ahead of the main table's rule (32766).
+const policyRulePriority = 10000
+
+// SetTableDefault installs (or replaces) a device-only IPv4 default route via
+// iface in table, backed by an unreachable default route at
+// tableBackstopMetric. The kernel removes the device route along with iface;
+// the backstop then makes lookups in table fail rather than fall through to
+// the main table's default route.
+func (m *RouteManager) SetTableDefault(table int, iface string) error {
+	link, err := vnl.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("resolving interface %q: %w", iface, err)
+	}
+	backstop := &vnl.Route{
+		Dst:      defaultV4Net(),
+		Family:   vnl.FAMILY_V4,
+		Table:    table,
+		Type:     unix.RTN_UNREACHABLE,
+		Priority: tableBackstopMetric,
+	}
+	if err := vnl.RouteReplace(backstop); err != nil {
+		return fmt.Errorf("adding unreachable default route to table %d: %w", table, err)
+	}
+	route := &vnl.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       defaultV4Net(),
+		Family:    vnl.FAMILY_V4,
+		Table:     table,
+		Scope:     vnl.SCOPE_LINK,
+	}
+	if err := vnl.RouteReplace(route); err != nil {
+		return fmt.Errorf("adding default route dev %q to table %d: %w", iface, table, err)
+	}
+	return nil
+}
+
+// policyRule builds the IPv4 rule routing packets arriving on iif by table.
+func policyRule(iif string, table int) *vnl.Rule {
+	rule := vnl.NewRule()
+	rule.Family = vnl.FAMILY_V4
+	rule.IifName = iif
+	rule.Table = table
+	rule.Priority = policyRulePriority
+	return rule
+}
+
+// AddPolicyRule routes packets arriving on iif by table. An identical rule
+// already in place (EEXIST) is not treated as an error.
+func (m *RouteManager) AddPolicyRule(iif string, table int) error {
+	if err := vnl.RuleAdd(policyRule(iif, table)); err != nil && !errors.Is(err, unix.EEXIST) {
+		return fmt.Errorf("adding rule iif %q lookup %d: %w", iif, table, err)
+	}
+	return nil
+}
+
+// DelPolicyRule removes the rule added by AddPolicyRule. A missing rule is not
+// treated as an error.
+func (m *RouteManager) DelPolicyRule(iif string, table int) error {
+	err := vnl.RuleDel(policyRule(iif, table))
+	if err != nil && !errors.Is(err, unix.ENOENT) && !errors.Is(err, unix.ESRCH) {
+		return fmt.Errorf("deleting rule iif %q lookup %d: %w", iif, table, err)
+	}
+	return nil
+}
+
 // parseDestination parses a route destination that may be either CIDR notation
 // (e.g. "10.0.0.0/8") or a bare IPv4 host address (e.g. "10.0.0.5", treated as
 // /32), matching the flexibility of `ip route add <dest>`.
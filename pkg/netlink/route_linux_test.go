@@ -35,3 +35,11 @@ func TestIsDefaultDst(t *testing.T) {
 		})
 	}
 }
+
+func TestPolicyRule(t *testing.T) {
+	rule := policyRule("wlan0", 100)
+	if rule.IifName != "wlan0" || rule.Table != 100 || rule.Priority != policyRulePriority {
+		t.Errorf("policyRule = iif %q table %d priority %d, want iif wlan0 table 100 priority %d",
+			rule.IifName, rule.Table, rule.Priority, policyRulePriority)
+	}
+}
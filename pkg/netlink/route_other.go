@@ -19,9 +19,10 @@ var ErrUnsupported = errors.New("netlink route operations are only supported on
 // Compile-time assertions that the stub implementations satisfy the typed
 // manager interfaces.
 var (
-	_ types.RouteManager = (*RouteManager)(nil)
-	_ types.AddrManager  = (*AddrManager)(nil)
-	_ types.LinkManager  = (*LinkManager)(nil)
+	_ types.RouteManager    = (*RouteManager)(nil)
+	_ types.AddrManager     = (*AddrManager)(nil)
+	_ types.LinkManager     = (*LinkManager)(nil)
+	_ types.WirelessManager = (*WirelessManager)(nil)
 )
 
 // RouteManager is the non-Linux stub implementation of types.RouteManager.
@@ -77,3 +78,18 @@ func (m *RouteManager) FlushRoutes(iface string) error {
 func (m *RouteManager) ListRoutes() ([]types.Route, error) {
 	return nil, ErrUnsupported
 }
+
+// SetTableDefault always returns ErrUnsupported on non-Linux platforms.
+func (m *RouteManager) SetTableDefault(table int, iface string) error {
+	return ErrUnsupported
+}
+
+// AddPolicyRule always returns ErrUnsupported on non-Linux platforms.
+func (m *RouteManager) AddPolicyRule(iif string, table int) error {
+	return ErrUnsupported
+}
+
+// DelPolicyRule always returns ErrUnsupported on non-Linux platforms.
+func (m *RouteManager) DelPolicyRule(iif string, table int) error {
+	return ErrUnsupported
+}
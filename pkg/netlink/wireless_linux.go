@@ -0,0 +1,669 @@
+//go:build linux
+
+package netlink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/mdlayher/genetlink"
+	mnl "github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// nl80211 commands, attributes and nested attribute types used here, from
+// include/uapi/linux/nl80211.h. golang.org/x/sys/unix only carries a subset.
+const (
+	nl80211FamilyName = "nl80211"
+	nl80211ScanGroup  = "scan"
+
+	nl80211CmdGetWiphy       = 1
+	nl80211CmdGetInterface   = 5
+	nl80211CmdNewInterface   = 7
+	nl80211CmdDelInterface   = 8
+	nl80211CmdGetStation     = 17
+	nl80211CmdGetScan        = 32
+	nl80211CmdTriggerScan    = 33
+	nl80211CmdNewScanResults = 34
+	nl80211CmdScanAborted    = 35
+
+	nl80211AttrWiphy      = 1
+	nl80211AttrIfindex    = 3
+	nl80211AttrIfname     = 4
+	nl80211AttrIftype     = 5
+	nl80211AttrMAC        = 6
+	nl80211AttrStaInfo    = 21
+	nl80211AttrWiphyBands = 22
+	nl80211AttrWiphyFreq  = 38
+	nl80211AttrScanSSIDs  = 45
+	nl80211AttrBSS        = 47
+	nl80211AttrSSID       = 52
+
+	nl80211AttrInterfaceCombinations = 120
+	nl80211AttrSplitWiphyDump        = 174
+
+	nl80211BSSBSSID      = 1
+	nl80211BSSFrequency  = 2
+	nl80211BSSCapability = 5
+	nl80211BSSIEs        = 6
+	nl80211BSSSignalMBM  = 7
+	nl80211BSSStatus     = 9
+
+	nl80211BSSStatusAssociated = 1
+
+	nl80211StaInfoSignal    = 7
+	nl80211StaInfoTxBitrate = 8
+
+	nl80211RateInfoBitrate   = 1
+	nl80211RateInfoBitrate32 = 5
+
+	nl80211IfaceCombLimits      = 1
+	nl80211IfaceCombMaxNum      = 2
+	nl80211IfaceCombNumChannels = 4
+
+	nl80211IfaceLimitMax   = 1
+	nl80211IfaceLimitTypes = 2
+
+	nl80211BandAttrFreqs = 1
+
+	nl80211FrequencyAttrFreq     = 1
+	nl80211FrequencyAttrDisabled = 2
+	nl80211FrequencyAttrNoIR     = 3
+	nl80211FrequencyAttrRadar    = 5
+)
+
+// nl80211IfTypes names the nl80211 interface types (enum nl80211_iftype).
+var nl80211IfTypes = map[uint32]string{
+	1:  "ibss",
+	2:  "station",
+	3:  "ap",
+	4:  "ap-vlan",
+	5:  "wds",
+	6:  "monitor",
+	7:  "mesh",
+	8:  "p2p-client",
+	9:  "p2p-go",
+	10: "p2p-device",
+	11: "ocb",
+	12: "nan",
+}
+
+// nl80211IfTypeNumber returns the enum nl80211_iftype value of a type name.
+func nl80211IfTypeNumber(name string) (uint32, bool) {
+	for n, t := range nl80211IfTypes {
+		if t == name {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// WirelessManager is the Linux/nl80211 implementation of
+// types.WirelessManager. Each call opens its own generic netlink socket, so
+// the manager is safe for concurrent use and holds no resources.
+type WirelessManager struct{}
+
+// NewWirelessManager returns an nl80211-backed WirelessManager.
+func NewWirelessManager() *WirelessManager {
+	return &WirelessManager{}
+}
+
+// nl80211Conn is a generic netlink socket bound to the nl80211 family.
+type nl80211Conn struct {
+	conn   *genetlink.Conn
+	family genetlink.Family
+}
+
+// dialNL80211 opens a generic netlink socket and resolves the nl80211
+// family. A kernel without cfg80211 has no such family.
+func dialNL80211() (*nl80211Conn, error) {
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening generic netlink socket: %w", err)
+	}
+	family, err := conn.GetFamily(nl80211FamilyName)
+	if err != nil {
+		conn.Close()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("nl80211 is not available (no wireless support in the kernel)")
+		}
+		return nil, fmt.Errorf("resolving nl80211 family: %w", err)
+	}
+	return &nl80211Conn{conn: conn, family: family}, nil
+}
+
+// execute sends an nl80211 command with the given attributes and returns the
+// replies. dump requests every object instead of the one addressed.
+func (c *nl80211Conn) execute(cmd uint8, dump bool, attrs func(ae *mnl.AttributeEncoder)) ([]genetlink.Message, error) {
+	ae := mnl.NewAttributeEncoder()
+	if attrs != nil {
+		attrs(ae)
+	}
+	data, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	flags := mnl.Request
+	if dump {
+		flags |= mnl.Dump
+	}
+	return c.conn.Execute(genetlink.Message{
+		Header: genetlink.Header{Command: cmd, Version: c.family.Version},
+		Data:   data,
+	}, c.family.ID, flags)
+}
+
+// ifindex resolves an interface name for nl80211 requests.
+func ifindex(iface string) (uint32, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return 0, fmt.Errorf("resolving interface %q: %w", iface, err)
+	}
+	return uint32(ifi.Index), nil
+}
+
+// Interfaces lists the wireless interfaces of all radios.
+func (m *WirelessManager) Interfaces() ([]types.WirelessInterface, error) {
+	c, err := dialNL80211()
+	if err != nil {
+		return nil, err
+	}
+	defer c.conn.Close()
+
+	msgs, err := c.execute(nl80211CmdGetInterface, true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing wireless interfaces: %w", err)
+	}
+	var out []types.WirelessInterface
+	for _, msg := range msgs {
+		wi, err := parseInterface(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		if wi.Name != "" {
+			out = append(out, wi)
+		}
+	}
+	return out, nil
+}
+
+// InterfaceType returns the nl80211 type of iface, or "" when iface is not a
+// wireless interface.
+func (m *WirelessManager) InterfaceType(iface string) (string, error) {
+	index, err := ifindex(iface)
+	if err != nil {
+		return "", err
+	}
+	c, err := dialNL80211()
+	if err != nil {
+		return "", err
+	}
+	defer c.conn.Close()
+
+	msgs, err := c.execute(nl80211CmdGetInterface, false, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+	})
+	if err != nil {
+		// cfg80211 answers ENODEV for interfaces it doesn't manage.
+		if errors.Is(err, unix.ENODEV) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EOPNOTSUPP) {
+			return "", nil
+		}
+		return "", fmt.Errorf("querying %q: %w", iface, err)
+	}
+	for _, msg := range msgs {
+		wi, err := parseInterface(msg.Data)
+		if err != nil {
+			return "", err
+		}
+		return wi.Type, nil
+	}
+	return "", nil
+}
+
+// parseInterface decodes an NL80211_CMD_NEW_INTERFACE reply.
+func parseInterface(b []byte) (types.WirelessInterface, error) {
+	var wi types.WirelessInterface
+	ad, err := mnl.NewAttributeDecoder(b)
+	if err != nil {
+		return wi, err
+	}
+	for ad.Next() {
+		switch ad.Type() {
+		case nl80211AttrIfname:
+			wi.Name = ad.String()
+		case nl80211AttrIfindex:
+			wi.Index = int(ad.Uint32())
+		case nl80211AttrWiphy:
+			wi.PHY = int(ad.Uint32())
+		case nl80211AttrIftype:
+			if name, ok := nl80211IfTypes[ad.Uint32()]; ok {
+				wi.Type = name
+			} else {
+				wi.Type = "unknown"
+			}
+		case nl80211AttrMAC:
+			wi.MAC = net.HardwareAddr(ad.Bytes()).String()
+		case nl80211AttrSSID:
+			wi.SSID = string(ad.Bytes())
+		}
+	}
+	return wi, ad.Err()
+}
+
+// TriggerScan starts an active (wildcard SSID) scan on iface and waits for
+// the kernel to announce new results on the "scan" multicast group.
+func (m *WirelessManager) TriggerScan(iface string, timeout time.Duration) error {
+	index, err := ifindex(iface)
+	if err != nil {
+		return err
+	}
+	c, err := dialNL80211()
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+
+	// Join the group before triggering so the completion can't be missed.
+	var group uint32
+	for _, g := range c.family.Groups {
+		if g.Name == nl80211ScanGroup {
+			group = g.ID
+		}
+	}
+	if group == 0 {
+		return fmt.Errorf("nl80211 has no %q multicast group", nl80211ScanGroup)
+	}
+	if err := c.conn.JoinGroup(group); err != nil {
+		return fmt.Errorf("subscribing to scan events: %w", err)
+	}
+
+	_, err = c.execute(nl80211CmdTriggerScan, false, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+		// One empty SSID: probe for any network, as `iw scan` does.
+		ae.Nested(nl80211AttrScanSSIDs, func(nae *mnl.AttributeEncoder) error {
+			nae.Bytes(1, []byte{})
+			return nil
+		})
+	})
+	// EBUSY: a scan is already running (wpa_supplicant's, say); its results
+	// will do.
+	if err != nil && !errors.Is(err, unix.EBUSY) {
+		return fmt.Errorf("triggering scan on %q: %w", iface, err)
+	}
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	for {
+		msgs, _, err := c.conn.Receive()
+		if err != nil {
+			return fmt.Errorf("waiting for scan results on %q: %w", iface, err)
+		}
+		for _, msg := range msgs {
+			if msg.Header.Command != nl80211CmdNewScanResults && msg.Header.Command != nl80211CmdScanAborted {
+				continue
+			}
+			wi, err := parseInterface(msg.Data)
+			if err != nil || uint32(wi.Index) != index {
+				continue
+			}
+			if msg.Header.Command == nl80211CmdScanAborted {
+				return fmt.Errorf("scan on %q was aborted", iface)
+			}
+			return nil
+		}
+	}
+}
+
+// ScanResults returns the BSSes in iface's scan cache.
+func (m *WirelessManager) ScanResults(iface string) ([]types.BSS, error) {
+	index, err := ifindex(iface)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialNL80211()
+	if err != nil {
+		return nil, err
+	}
+	defer c.conn.Close()
+
+	msgs, err := c.execute(nl80211CmdGetScan, true, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading scan results of %q: %w", iface, err)
+	}
+	var out []types.BSS
+	for _, msg := range msgs {
+		bss, ok, err := parseScanResult(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, bss)
+		}
+	}
+	return out, nil
+}
+
+// parseScanResult decodes the NL80211_ATTR_BSS of a scan dump entry.
+func parseScanResult(b []byte) (types.BSS, bool, error) {
+	var bss types.BSS
+	found := false
+	ad, err := mnl.NewAttributeDecoder(b)
+	if err != nil {
+		return bss, false, err
+	}
+	for ad.Next() {
+		if ad.Type() != nl80211AttrBSS {
+			continue
+		}
+		found = true
+		ad.Nested(func(nad *mnl.AttributeDecoder) error {
+			for nad.Next() {
+				switch nad.Type() {
+				case nl80211BSSBSSID:
+					bss.BSSID = net.HardwareAddr(nad.Bytes()).String()
+				case nl80211BSSFrequency:
+					bss.Frequency = int(nad.Uint32())
+				case nl80211BSSCapability:
+					bss.Capability = nad.Uint16()
+				case nl80211BSSIEs:
+					bss.IEs = append([]byte(nil), nad.Bytes()...)
+				case nl80211BSSSignalMBM:
+					// mBm: hundredths of a dBm, as a signed 32-bit value.
+					bss.Signal = int(int32(nad.Uint32())) / 100
+				case nl80211BSSStatus:
+					bss.Associated = nad.Uint32() == nl80211BSSStatusAssociated
+				}
+			}
+			return nil
+		})
+	}
+	return bss, found, ad.Err()
+}
+
+// LinkInfo returns iface's current association, or nil when it is not
+// associated.
+func (m *WirelessManager) LinkInfo(iface string) (*types.WirelessLink, error) {
+	bsses, err := m.ScanResults(iface)
+	if err != nil {
+		return nil, err
+	}
+	var link *types.WirelessLink
+	for _, bss := range bsses {
+		if bss.Associated {
+			link = &types.WirelessLink{BSSID: bss.BSSID, Frequency: bss.Frequency, Signal: bss.Signal}
+			break
+		}
+	}
+	if link == nil {
+		return nil, nil
+	}
+
+	index, err := ifindex(iface)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialNL80211()
+	if err != nil {
+		return nil, err
+	}
+	defer c.conn.Close()
+
+	// The SSID comes from the interface: the BSS may be hidden.
+	if msgs, err := c.execute(nl80211CmdGetInterface, false, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+	}); err == nil {
+		for _, msg := range msgs {
+			if wi, err := parseInterface(msg.Data); err == nil {
+				link.SSID = wi.SSID
+			}
+		}
+	}
+
+	// Signal and bitrate come from the station entry of the AP.
+	bssid, err := net.ParseMAC(link.BSSID)
+	if err != nil {
+		return link, nil
+	}
+	msgs, err := c.execute(nl80211CmdGetStation, false, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, index)
+		ae.Bytes(nl80211AttrMAC, bssid)
+	})
+	if err != nil {
+		return link, nil
+	}
+	for _, msg := range msgs {
+		parseStationInfo(msg.Data, link)
+	}
+	return link, nil
+}
+
+// parseStationInfo fills link's signal and tx bitrate from an
+// NL80211_CMD_NEW_STATION reply.
+func parseStationInfo(b []byte, link *types.WirelessLink) {
+	ad, err := mnl.NewAttributeDecoder(b)
+	if err != nil {
+		return
+	}
+	for ad.Next() {
+		if ad.Type() != nl80211AttrStaInfo {
+			continue
+		}
+		ad.Nested(func(nad *mnl.AttributeDecoder) error {
+			for nad.Next() {
+				switch nad.Type() {
+				case nl80211StaInfoSignal:
+					link.Signal = int(int8(nad.Uint8()))
+				case nl80211StaInfoTxBitrate:
+					nad.Nested(func(rad *mnl.AttributeDecoder) error {
+						for rad.Next() {
+							switch rad.Type() {
+							case nl80211RateInfoBitrate32:
+								// Units of 100 kbit/s; the 32-bit form wins.
+								link.TxBitrate = float64(rad.Uint32()) / 10
+							case nl80211RateInfoBitrate:
+								if link.TxBitrate == 0 {
+									link.TxBitrate = float64(rad.Uint16()) / 10
+								}
+							}
+						}
+						return nil
+					})
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// dumpWiphy returns the parts of a split dump of radio phy. Without the
+// split, the kernel leaves out everything that doesn't fit one message.
+func dumpWiphy(phy int) ([]genetlink.Message, error) {
+	c, err := dialNL80211()
+	if err != nil {
+		return nil, err
+	}
+	defer c.conn.Close()
+
+	msgs, err := c.execute(nl80211CmdGetWiphy, true, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrWiphy, uint32(phy))
+		ae.Flag(nl80211AttrSplitWiphyDump, true)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying phy#%d: %w", phy, err)
+	}
+	return msgs, nil
+}
+
+// InterfaceCombinations returns the valid interface combinations of radio
+// phy.
+func (m *WirelessManager) InterfaceCombinations(phy int) ([]types.InterfaceCombination, error) {
+	msgs, err := dumpWiphy(phy)
+	if err != nil {
+		return nil, err
+	}
+	var out []types.InterfaceCombination
+	for _, msg := range msgs {
+		combs, err := parseInterfaceCombinations(msg.Data, phy)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, combs...)
+	}
+	return out, nil
+}
+
+// parseInterfaceCombinations decodes the NL80211_ATTR_INTERFACE_COMBINATIONS
+// of one part of a wiphy dump, skipping parts that belong to another radio.
+func parseInterfaceCombinations(b []byte, phy int) ([]types.InterfaceCombination, error) {
+	ad, err := mnl.NewAttributeDecoder(b)
+	if err != nil {
+		return nil, err
+	}
+	var out []types.InterfaceCombination
+	for ad.Next() {
+		switch ad.Type() {
+		case nl80211AttrWiphy:
+			if int(ad.Uint32()) != phy {
+				return nil, nil
+			}
+		case nl80211AttrInterfaceCombinations:
+			ad.Nested(func(cad *mnl.AttributeDecoder) error {
+				for cad.Next() {
+					var comb types.InterfaceCombination
+					cad.Nested(func(nad *mnl.AttributeDecoder) error {
+						for nad.Next() {
+							switch nad.Type() {
+							case nl80211IfaceCombLimits:
+								nad.Nested(func(lad *mnl.AttributeDecoder) error {
+									for lad.Next() {
+										lad.Nested(func(fad *mnl.AttributeDecoder) error {
+											comb.Limits = append(comb.Limits, parseInterfaceLimit(fad))
+											return nil
+										})
+									}
+									return nil
+								})
+							case nl80211IfaceCombMaxNum:
+								comb.Max = int(nad.Uint32())
+							case nl80211IfaceCombNumChannels:
+								comb.Channels = int(nad.Uint32())
+							}
+						}
+						return nil
+					})
+					out = append(out, comb)
+				}
+				return nil
+			})
+		}
+	}
+	return out, ad.Err()
+}
+
+// parseInterfaceLimit decodes one NL80211_IFACE_COMB_LIMITS entry. Its types
+// are flag attributes numbered after enum nl80211_iftype.
+func parseInterfaceLimit(ad *mnl.AttributeDecoder) types.InterfaceLimit {
+	var limit types.InterfaceLimit
+	for ad.Next() {
+		switch ad.Type() {
+		case nl80211IfaceLimitMax:
+			limit.Max = int(ad.Uint32())
+		case nl80211IfaceLimitTypes:
+			ad.Nested(func(tad *mnl.AttributeDecoder) error {
+				for tad.Next() {
+					if name, ok := nl80211IfTypes[uint32(tad.Type())]; ok {
+						limit.Types = append(limit.Types, name)
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return limit
+}
+
+// Frequencies returns the channels radio phy supports, across all bands.
+func (m *WirelessManager) Frequencies(phy int) ([]types.RadioFrequency, error) {
+	msgs, err := dumpWiphy(phy)
+	if err != nil {
+		return nil, err
+	}
+	var out []types.RadioFrequency
+	for _, msg := range msgs {
+		freqs, err := parseFrequencies(msg.Data, phy)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, freqs...)
+	}
+	return out, nil
+}
+
+// parseFrequencies decodes the frequencies in the NL80211_ATTR_WIPHY_BANDS
+// of one part of a wiphy dump, skipping parts that belong to another radio.
+// A split dump spreads a band's frequencies over several parts.
+func parseFrequencies(b []byte, phy int) ([]types.RadioFrequency, error) {
+	ad, err := mnl.NewAttributeDecoder(b)
+	if err != nil {
+		return nil, err
+	}
+	var out []types.RadioFrequency
+	for ad.Next() {
+		switch ad.Type() {
+		case nl80211AttrWiphy:
+			if int(ad.Uint32()) != phy {
+				return nil, nil
+			}
+		case nl80211AttrWiphyBands:
+			ad.Nested(func(bad *mnl.AttributeDecoder) error {
+				for bad.Next() {
+					bad.Nested(func(nad *mnl.AttributeDecoder) error {
+						for nad.Next() {
+							if nad.Type() != nl80211BandAttrFreqs {
+								continue
+							}
+							nad.Nested(func(fad *mnl.AttributeDecoder) error {
+								for fad.Next() {
+									fad.Nested(func(ead *mnl.AttributeDecoder) error {
+										out = append(out, parseFrequency(ead))
+										return nil
+									})
+								}
+								return nil
+							})
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+		}
+	}
+	return out, ad.Err()
+}
+
+// parseFrequency decodes one NL80211_BAND_ATTR_FREQS entry.
+func parseFrequency(ad *mnl.AttributeDecoder) types.RadioFrequency {
+	var freq types.RadioFrequency
+	for ad.Next() {
+		switch ad.Type() {
+		case nl80211FrequencyAttrFreq:
+			freq.Frequency = int(ad.Uint32())
+		case nl80211FrequencyAttrDisabled:
+			freq.Disabled = true
+		case nl80211FrequencyAttrNoIR:
+			freq.NoIR = true
+		case nl80211FrequencyAttrRadar:
+			freq.Radar = true
+		}
+	}
+	return freq
+}
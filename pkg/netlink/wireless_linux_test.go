@@ -0,0 +1,193 @@
+//go:build linux
+
+package netlink
+
+import (
+	"reflect"
+	"testing"
+
+	mnl "github.com/mdlayher/netlink"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+func encode(t *testing.T, fn func(ae *mnl.AttributeEncoder)) []byte {
+	t.Helper()
+	ae := mnl.NewAttributeEncoder()
+	fn(ae)
+	b, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return b
+}
+
+func TestParseInterface(t *testing.T) {
+	b := encode(t, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrWiphy, 1)
+		ae.Uint32(nl80211AttrIfindex, 4)
+		ae.String(nl80211AttrIfname, "wlan0")
+		ae.Uint32(nl80211AttrIftype, 2)
+		ae.Bytes(nl80211AttrMAC, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55})
+		ae.Bytes(nl80211AttrSSID, []byte("HomeNet"))
+	})
+
+	wi, err := parseInterface(b)
+	if err != nil {
+		t.Fatalf("parseInterface: %v", err)
+	}
+	if wi.Name != "wlan0" || wi.Index != 4 || wi.PHY != 1 || wi.Type != "station" ||
+		wi.MAC != "00:11:22:33:44:55" || wi.SSID != "HomeNet" {
+		t.Errorf("parseInterface = %+v", wi)
+	}
+}
+
+func TestParseScanResult(t *testing.T) {
+	ies := []byte{0, 4, 'C', 'a', 'f', 'e'}
+	b := encode(t, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, 4)
+		ae.Nested(nl80211AttrBSS, func(nae *mnl.AttributeEncoder) error {
+			nae.Bytes(nl80211BSSBSSID, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff})
+			nae.Uint32(nl80211BSSFrequency, 5180)
+			nae.Uint16(nl80211BSSCapability, 0x0011)
+			nae.Bytes(nl80211BSSIEs, ies)
+			nae.Int32(nl80211BSSSignalMBM, -6250)
+			nae.Uint32(nl80211BSSStatus, nl80211BSSStatusAssociated)
+			return nil
+		})
+	})
+
+	bss, found, err := parseScanResult(b)
+	if err != nil || !found {
+		t.Fatalf("parseScanResult: found=%v err=%v", found, err)
+	}
+	if bss.BSSID != "aa:bb:cc:dd:ee:ff" || bss.Frequency != 5180 || bss.Capability != 0x0011 ||
+		bss.Signal != -62 || !bss.Associated || string(bss.IEs) != string(ies) {
+		t.Errorf("parseScanResult = %+v", bss)
+	}
+
+	_, found, err = parseScanResult(encode(t, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrIfindex, 4)
+	}))
+	if err != nil || found {
+		t.Errorf("entry without a BSS: found=%v err=%v", found, err)
+	}
+}
+
+func TestParseStationInfo(t *testing.T) {
+	b := encode(t, func(ae *mnl.AttributeEncoder) {
+		ae.Nested(nl80211AttrStaInfo, func(nae *mnl.AttributeEncoder) error {
+			nae.Uint8(nl80211StaInfoSignal, uint8(0xc6)) // -58 dBm
+			nae.Nested(nl80211StaInfoTxBitrate, func(rae *mnl.AttributeEncoder) error {
+				rae.Uint16(nl80211RateInfoBitrate, 65535)
+				rae.Uint32(nl80211RateInfoBitrate32, 8667)
+				return nil
+			})
+			return nil
+		})
+	})
+
+	var link types.WirelessLink
+	parseStationInfo(b, &link)
+	if link.Signal != -58 || link.TxBitrate != 866.7 {
+		t.Errorf("parseStationInfo = %+v", link)
+	}
+}
+
+func TestParseInterfaceCombinations(t *testing.T) {
+	limit := func(ae *mnl.AttributeEncoder, index uint16, max uint32, iftypes ...uint16) {
+		ae.Nested(index, func(lae *mnl.AttributeEncoder) error {
+			lae.Uint32(nl80211IfaceLimitMax, max)
+			lae.Nested(nl80211IfaceLimitTypes, func(tae *mnl.AttributeEncoder) error {
+				for _, typ := range iftypes {
+					tae.Flag(typ, true)
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+	b := encode(t, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrWiphy, 1)
+		ae.Nested(nl80211AttrInterfaceCombinations, func(cae *mnl.AttributeEncoder) error {
+			cae.Nested(1, func(nae *mnl.AttributeEncoder) error {
+				nae.Nested(nl80211IfaceCombLimits, func(lae *mnl.AttributeEncoder) error {
+					limit(lae, 1, 1, 2)
+					limit(lae, 2, 1, 3, 8, 9)
+					return nil
+				})
+				nae.Uint32(nl80211IfaceCombMaxNum, 2)
+				nae.Uint32(nl80211IfaceCombNumChannels, 1)
+				return nil
+			})
+			return nil
+		})
+	})
+
+	combs, err := parseInterfaceCombinations(b, 1)
+	if err != nil {
+		t.Fatalf("parseInterfaceCombinations: %v", err)
+	}
+	want := []types.InterfaceCombination{{
+		Limits: []types.InterfaceLimit{
+			{Max: 1, Types: []string{"station"}},
+			{Max: 1, Types: []string{"ap", "p2p-client", "p2p-go"}},
+		},
+		Max:      2,
+		Channels: 1,
+	}}
+	if !reflect.DeepEqual(combs, want) {
+		t.Errorf("parseInterfaceCombinations = %+v", combs)
+	}
+
+	if combs, err := parseInterfaceCombinations(b, 0); err != nil || combs != nil {
+		t.Errorf("other radio: combs=%+v err=%v", combs, err)
+	}
+}
+
+func TestParseFrequencies(t *testing.T) {
+	b := encode(t, func(ae *mnl.AttributeEncoder) {
+		ae.Uint32(nl80211AttrWiphy, 0)
+		ae.Nested(nl80211AttrWiphyBands, func(bae *mnl.AttributeEncoder) error {
+			bae.Nested(1, func(nae *mnl.AttributeEncoder) error { // NL80211_BAND_5GHZ
+				nae.Nested(nl80211BandAttrFreqs, func(fae *mnl.AttributeEncoder) error {
+					fae.Nested(0, func(eae *mnl.AttributeEncoder) error {
+						eae.Uint32(nl80211FrequencyAttrFreq, 5180)
+						return nil
+					})
+					fae.Nested(1, func(eae *mnl.AttributeEncoder) error {
+						eae.Uint32(nl80211FrequencyAttrFreq, 5260)
+						eae.Flag(nl80211FrequencyAttrNoIR, true)
+						eae.Flag(nl80211FrequencyAttrRadar, true)
+						return nil
+					})
+					fae.Nested(2, func(eae *mnl.AttributeEncoder) error {
+						eae.Uint32(nl80211FrequencyAttrFreq, 5845)
+						eae.Flag(nl80211FrequencyAttrDisabled, true)
+						return nil
+					})
+					return nil
+				})
+				return nil
+			})
+			return nil
+		})
+	})
+
+	freqs, err := parseFrequencies(b, 0)
+	if err != nil {
+		t.Fatalf("parseFrequencies: %v", err)
+	}
+	want := []types.RadioFrequency{
+		{Frequency: 5180},
+		{Frequency: 5260, NoIR: true, Radar: true},
+		{Frequency: 5845, Disabled: true},
+	}
+	if !reflect.DeepEqual(freqs, want) {
+		t.Errorf("parseFrequencies = %+v", freqs)
+	}
+
+	if freqs, err := parseFrequencies(b, 1); err != nil || freqs != nil {
+		t.Errorf("other radio: freqs=%+v err=%v", freqs, err)
+	}
+}
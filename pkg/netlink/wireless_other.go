@@ -0,0 +1,54 @@
+//go:build !linux
+
+package netlink
+
+import (
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// WirelessManager is the non-Linux stub implementation of
+// types.WirelessManager.
+type WirelessManager struct{}
+
+// NewWirelessManager returns a stub WirelessManager whose operations all fail
+// with ErrUnsupported on non-Linux platforms.
+func NewWirelessManager() *WirelessManager {
+	return &WirelessManager{}
+}
+
+// Interfaces always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) Interfaces() ([]types.WirelessInterface, error) {
+	return nil, ErrUnsupported
+}
+
+// InterfaceType always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) InterfaceType(iface string) (string, error) {
+	return "", ErrUnsupported
+}
+
+// TriggerScan always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) TriggerScan(iface string, timeout time.Duration) error {
+	return ErrUnsupported
+}
+
+// ScanResults always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) ScanResults(iface string) ([]types.BSS, error) {
+	return nil, ErrUnsupported
+}
+
+// LinkInfo always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) LinkInfo(iface string) (*types.WirelessLink, error) {
+	return nil, ErrUnsupported
+}
+
+// InterfaceCombinations always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) InterfaceCombinations(phy int) ([]types.InterfaceCombination, error) {
+	return nil, ErrUnsupported
+}
+
+// Frequencies always returns ErrUnsupported on non-Linux platforms.
+func (m *WirelessManager) Frequencies(phy int) ([]types.RadioFrequency, error) {
+	return nil, ErrUnsupported
+}
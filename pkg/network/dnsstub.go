@@ -0,0 +1,130 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// dnsStubAddr is where the local DNS-over-TLS stub listens. It is a loopback
+// address of its own so it doesn't collide with systemd-resolved (127.0.0.53
+// and 127.0.0.54) or a local dnsmasq.
+const dnsStubAddr = "127.0.2.53"
+
+// defaultDoTServers are the upstreams used when the network's DNS comes from
+// DHCP, whose servers can't be assumed to speak DNS-over-TLS.
+var defaultDoTServers = []string{"1.1.1.1", "9.9.9.9"}
+
+// dotNames maps well-known public resolvers to the name on their TLS
+// certificate, so plain addresses from dns: can be used as upstreams. Other
+// servers are written as "address#name".
+var dotNames = map[string]string{
+	"1.1.1.1":         "cloudflare-dns.com",
+	"1.0.0.1":         "cloudflare-dns.com",
+	"8.8.8.8":         "dns.google",
+	"8.8.4.4":         "dns.google",
+	"9.9.9.9":         "dns.quad9.net",
+	"149.112.112.112": "dns.quad9.net",
+}
+
+// dotServer is a DNS-over-TLS upstream.
+type dotServer struct {
+	addr    string
+	tlsName string
+}
+
+// parseDoTServers turns dns: entries into DNS-over-TLS upstreams. Entries are
+// an IP address of a resolver in dotNames, or "address#tls-name". "dhcp" or
+// no entries selects defaultDoTServers.
+func parseDoTServers(servers []string) ([]dotServer, error) {
+	if len(servers) == 0 || (len(servers) == 1 && servers[0] == "dhcp") {
+		servers = defaultDoTServers
+	}
+	var out []dotServer
+	for _, s := range servers {
+		addr, name, hasName := strings.Cut(strings.TrimSpace(s), "#")
+		if net.ParseIP(addr) == nil {
+			return nil, fmt.Errorf("invalid DNS-over-TLS server %q: not an IP address", s)
+		}
+		if !hasName {
+			name = dotNames[addr]
+		}
+		if name == "" {
+			return nil, fmt.Errorf("no TLS name known for DNS server %s; write it as %s#<name on its certificate>", addr, addr)
+		}
+		out = append(out, dotServer{addr: addr, tlsName: name})
+	}
+	return out, nil
+}
+
+// stubbyConfig renders the stubby configuration for the upstreams. Server
+// certificates are always verified: an unauthenticated encrypted upstream is
+// no protection on a hostile network.
+func stubbyConfig(upstreams []dotServer) string {
+	var b strings.Builder
+	b.WriteString("# Generated by net\n")
+	b.WriteString("resolution_type: GETDNS_RESOLUTION_STUB\n")
+	b.WriteString("dns_transport_list:\n  - GETDNS_TRANSPORT_TLS\n")
+	b.WriteString("tls_authentication: GETDNS_AUTHENTICATION_REQUIRED\n")
+	b.WriteString("tls_query_padding_blocksize: 128\n")
+	b.WriteString("round_robin_upstreams: 1\n")
+	fmt.Fprintf(&b, "listen_addresses:\n  - %s\n", dnsStubAddr)
+	b.WriteString("upstream_recursive_servers:\n")
+	for _, u := range upstreams {
+		fmt.Fprintf(&b, "  - address_data: %s\n    tls_auth_name: %q\n", u.addr, u.tlsName)
+	}
+	return b.String()
+}
+
+// stubConfig returns the stubby config path (overridable in tests).
+func (m *Manager) stubConfig() string {
+	if m.stubConfigPath != "" {
+		return m.stubConfigPath
+	}
+	return types.RuntimeDir + "/stubby.yml"
+}
+
+// SetEncryptedDNS starts stubby as a local DNS-over-TLS resolver forwarding
+// to servers (see parseDoTServers) and points resolv.conf at it.
+func (m *Manager) SetEncryptedDNS(servers []string) error {
+	if !m.executor.HasCommand("stubby") {
+		return fmt.Errorf("encrypted DNS needs stubby, which is not installed")
+	}
+	upstreams, err := parseDoTServers(servers)
+	if err != nil {
+		return err
+	}
+
+	m.stopDNSStub()
+	path := m.stubConfig()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(stubbyConfig(upstreams)), 0644); err != nil {
+		return fmt.Errorf("failed to write stubby config: %w", err)
+	}
+
+	m.logger.Info("Starting encrypted DNS stub", "listen", dnsStubAddr, "upstreams", len(upstreams))
+	// -g daemonizes once the listening socket is open.
+	if _, err := m.executor.ExecuteWithTimeout(5*time.Second, "stubby", "-g", "-C", path); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to start stubby: %w", err)
+	}
+	return m.setDNS([]string{dnsStubAddr})
+}
+
+// stopDNSStub stops the stub started by SetEncryptedDNS, if any.
+func (m *Manager) stopDNSStub() {
+	path := m.stubConfig()
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	m.logger.Debug("Stopping encrypted DNS stub")
+	m.killProcess("stubby -g -C " + path)
+	_ = os.Remove(path)
+}
@@ -0,0 +1,106 @@
+package network
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// DetectEnvironment reports what can be observed on a connected link for
+// match: rules: the SSID and BSSID of the associated AP, the default
+// gateway's MAC address, the domain name of the DHCP lease and the switch name
+// advertised over LLDP. Each observation is best-effort; anything that can't
+// be determined is left empty.
+func (m *Manager) DetectEnvironment(iface string) (*types.Environment, error) {
+	env := &types.Environment{Interface: iface}
+	env.SSID, env.BSSID = m.linkInfo(iface)
+
+	if route, err := m.routeMgr.GetDefaultRouteForIface(iface); err != nil {
+		m.logger.Debug("No default route for gateway MAC lookup", "iface", iface, "error", err)
+	} else if route.Gw != "" {
+		env.GatewayMAC = m.gatewayMAC(iface, route.Gw)
+	}
+
+	// The lease, not resolv.conf: net writes that itself when it sets DNS
+	if m.dhcpClient != nil {
+		env.Domain = m.dhcpClient.Domain(iface)
+	}
+	env.Switch = m.lldpSwitchName(iface)
+
+	m.logger.Debug("Detected link environment", "iface", iface, "ssid", env.SSID, "bssid", env.BSSID,
+		"gatewayMAC", env.GatewayMAC, "domain", env.Domain, "switch", env.Switch)
+	return env, nil
+}
+
+// linkInfo returns the SSID and BSSID the interface is associated with, or
+// empty strings for wired interfaces.
+func (m *Manager) linkInfo(iface string) (ssid, bssid string) {
+	link, err := m.wireless.LinkInfo(iface)
+	if err != nil || link == nil {
+		return "", ""
+	}
+	return link.SSID, strings.ToLower(link.BSSID)
+}
+
+// arpTable returns the kernel ARP table path (overridable in tests).
+func (m *Manager) arpTable() string {
+	if m.arpTablePath != "" {
+		return m.arpTablePath
+	}
+	return "/proc/net/arp"
+}
+
+// gatewayMAC looks up the gateway's MAC address in the ARP table. Right after
+// DHCP the entry may not exist yet, so one ping is sent to populate it.
+func (m *Manager) gatewayMAC(iface, gateway string) string {
+	if mac := m.lookupARP(iface, gateway); mac != "" {
+		return mac
+	}
+	if _, err := m.executor.ExecuteWithTimeout(2*time.Second, "ping", "-c", "1", "-W", "1", "-I", iface, gateway); err != nil {
+		m.logger.Debug("Gateway ping failed", "gateway", gateway, "error", err)
+	}
+	return m.lookupARP(iface, gateway)
+}
+
+// lookupARP returns the MAC address for ip on iface from the ARP table, or
+// "" when there is no complete entry.
+func (m *Manager) lookupARP(iface, ip string) string {
+	data, err := os.ReadFile(m.arpTable())
+	if err != nil {
+		return ""
+	}
+	// IP address  HW type  Flags  HW address  Mask  Device
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[0] != ip || fields[5] != iface {
+			continue
+		}
+		if mac := strings.ToLower(fields[3]); mac != "00:00:00:00:00:00" {
+			return mac
+		}
+	}
+	return ""
+}
+
+// lldpSwitchName returns the system name of the switch on the other end of
+// iface, as learned by lldpd. Empty when lldpd isn't installed or hasn't
+// seen an LLDP frame yet (switches send one every 30 seconds).
+func (m *Manager) lldpSwitchName(iface string) string {
+	if !m.executor.HasCommand("lldpctl") {
+		return ""
+	}
+	output, err := m.executor.ExecuteWithTimeout(2*time.Second, "lldpctl", "-f", "keyvalue", iface)
+	if err != nil {
+		m.logger.Debug("lldpctl failed", "iface", iface, "error", err)
+		return ""
+	}
+	prefix := "lldp." + iface + ".chassis.name="
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		}
+	}
+	return ""
+}
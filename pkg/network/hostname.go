@@ -0,0 +1,106 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// etcFile returns the path of a file in /etc (overridable in tests).
+func (m *Manager) etcFile(name string) string {
+	if m.etcDir != "" {
+		return filepath.Join(m.etcDir, name)
+	}
+	return filepath.Join("/etc", name)
+}
+
+// savedHostname returns where the hostname replaced by hostname_mode: system
+// is kept (overridable in tests). It is under the runtime directory, which
+// is cleared on reboot, as is the temporary hostname.
+func (m *Manager) savedHostname() string {
+	if m.savedHostPath != "" {
+		return m.savedHostPath
+	}
+	return types.RuntimeDir + "/hostname-saved"
+}
+
+// sysHostname sets the kernel hostname via the injected setter (defaults to
+// setHostname), tolerating a nil setter for zero-value Managers.
+func (m *Manager) sysHostname(name string) error {
+	if m.setSysHostname == nil {
+		return setHostname(name)
+	}
+	return m.setSysHostname(name)
+}
+
+// prepareHostname applies config's hostname_mode and dhcp_anonymous before
+// the link comes up and returns the hostname to send in DHCP requests.
+func (m *Manager) prepareHostname(config *types.NetworkConfig) string {
+	mode := config.HostnameMode
+	// The WiFi manager shares this DHCP client, so this covers its requests
+	// too.
+	noHostname := mode == types.HostnameModeAnonymous || config.DHCPAnonymous
+	if m.dhcpClient != nil {
+		m.dhcpClient.SetOptions(types.DHCPClientOptions{NoHostname: noHostname, Anonymous: config.DHCPAnonymous})
+	}
+
+	if mode == types.HostnameModeSystem && config.Hostname != "" {
+		if err := m.setConnectionHostname(config.Hostname); err != nil {
+			m.logger.Warn("Failed to set hostname for the connection", "hostname", config.Hostname, "error", err)
+		}
+	}
+	if noHostname {
+		m.logger.Info("Sending no hostname", "hostname_mode", mode, "dhcp_anonymous", config.DHCPAnonymous)
+		return ""
+	}
+	return config.Hostname
+}
+
+// setConnectionHostname sets the kernel hostname for the duration of a
+// connection. The hostname it replaces is saved only the first time, so
+// RestoreHostname returns to the machine's own name however many networks
+// were joined in between.
+func (m *Manager) setConnectionHostname(name string) error {
+	path := m.savedHostname()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		current, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("reading current hostname: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(current+"\n"), 0644); err != nil {
+			return fmt.Errorf("saving current hostname: %w", err)
+		}
+	}
+	m.logger.Info("Setting hostname for the connection", "hostname", name)
+	return m.applyHostname(name, false)
+}
+
+// RestoreHostname puts back the hostname replaced by setConnectionHostname.
+// Returns whether there was one to restore.
+func (m *Manager) RestoreHostname() (bool, error) {
+	path := m.savedHostname()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if original := strings.TrimSpace(string(data)); original != "" {
+		m.logger.Info("Restoring hostname", "hostname", original)
+		if err := m.applyHostname(original, false); err != nil {
+			return false, err
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		return true, err
+	}
+	return true, nil
+}
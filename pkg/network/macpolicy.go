@@ -0,0 +1,228 @@
+package network
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/oui"
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// timeNow is the clock for MAC rotation (overridable in tests).
+var timeNow = time.Now
+
+// macRecord is a MAC address together with the policy that produced it.
+type macRecord struct {
+	MAC    string `json:"mac"`
+	Policy string `json:"policy"`
+	// Expires is when a rotate: address is replaced.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// stateDirectory returns the persistent state directory (overridable in
+// tests).
+func (m *Manager) stateDirectory() string {
+	if m.stateDir != "" {
+		return m.stateDir
+	}
+	return types.StateDir
+}
+
+// resolveMAC turns a mac: setting into an address for iface and a label
+// naming the policy. network is the SSID being joined, the name of a wired
+// profile, or "" for wired links without one and `net mac`.
+func (m *Manager) resolveMAC(iface, mac, network string) (string, string, error) {
+	switch {
+	case mac == "" || mac == "random":
+		return m.generateRandomMAC(), "random", nil
+	case mac == "default":
+		// Use a default MAC (random MacBook Pro style)
+		return m.generateMacBookProMAC(), "randomized Apple OUI", nil
+	case mac == "permanent":
+		// Restore the factory/permanent MAC address
+		permMAC, err := m.getPermanentMAC(iface)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get permanent MAC: %w", err)
+		}
+		return permMAC, "permanent", nil
+	case mac == "stable":
+		addr, err := m.stableMAC(iface, network)
+		if err != nil {
+			return "", "", err
+		}
+		if network == "" {
+			return addr, "stable for " + iface, nil
+		}
+		return addr, "stable for " + network, nil
+	case strings.HasPrefix(mac, "rotate:"):
+		period, _, err := types.MACRotatePeriod(mac)
+		if err != nil {
+			return "", "", err
+		}
+		return m.rotatingMAC(iface, network, period)
+	case strings.HasPrefix(mac, "vendor:"):
+		vendor := strings.TrimSpace(strings.TrimPrefix(mac, "vendor:"))
+		addr, err := oui.RandomMAC(vendor)
+		if err != nil {
+			return "", "", err
+		}
+		return addr, "randomized " + oui.Lookup(addr) + " OUI", nil
+	case strings.Contains(mac, "??"):
+		// Handle MAC templates like "00:??:??:??:??:??"
+		return m.expandMACTemplate(mac), "randomized from " + mac, nil
+	}
+	return mac, "fixed", nil
+}
+
+// macSecret returns the secret behind stable addresses, creating it on first
+// use. It lives in the persistent state directory: a new secret would give
+// every network a new address.
+func (m *Manager) macSecret() ([]byte, error) {
+	path := filepath.Join(m.stateDirectory(), "mac-secret")
+	secret, err := os.ReadFile(path)
+	if err == nil && len(secret) >= 32 {
+		return secret, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading MAC secret: %w", err)
+	}
+
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating MAC secret: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("creating state directory: %w", err)
+	}
+	if err := atomicWriteMode(path, secret, 0600); err != nil {
+		return nil, fmt.Errorf("writing MAC secret: %w", err)
+	}
+	return secret, nil
+}
+
+// stableMAC derives a locally administered address from the secret, the
+// interface and the network with HMAC-SHA256: the same network always gets
+// the same address, and addresses on different networks can't be linked
+// without the secret.
+func (m *Manager) stableMAC(iface, network string) (string, error) {
+	secret, err := m.macSecret()
+	if err != nil {
+		return "", err
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(iface + "\x00" + network))
+	return formatLocalMAC(h.Sum(nil)[:6]), nil
+}
+
+// rotatingMAC returns the random address in use for iface on network until
+// its period has elapsed, then a new one. The schedule is kept in the state
+// directory so it survives reboots.
+func (m *Manager) rotatingMAC(iface, network string, period time.Duration) (string, string, error) {
+	path := filepath.Join(m.stateDirectory(), "mac-rotation.json")
+	schedule := make(map[string]macRecord)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			m.logger.Warn("Ignoring corrupt MAC rotation state", "path", path, "error", err)
+			schedule = make(map[string]macRecord)
+		}
+	}
+
+	key := iface + "/" + network
+	now := timeNow()
+	rec, ok := schedule[key]
+	if !ok || !now.Before(rec.Expires) || rec.Expires.Sub(now) > period {
+		rec = macRecord{MAC: m.generateRandomMAC(), Expires: now.Add(period)}
+		schedule[key] = rec
+		data, err := json.MarshalIndent(schedule, "", "  ")
+		if err != nil {
+			return "", "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return "", "", fmt.Errorf("creating state directory: %w", err)
+		}
+		if err := atomicWriteMode(path, data, 0600); err != nil {
+			return "", "", fmt.Errorf("writing MAC rotation state: %w", err)
+		}
+	}
+	return rec.MAC, "rotates every " + period.String() + ", next " + rec.Expires.Local().Format("2006-01-02 15:04"), nil
+}
+
+// formatLocalMAC formats b as a unicast, locally administered address.
+func formatLocalMAC(b []byte) string {
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", (b[0]|0x02)&0xfe, b[1], b[2], b[3], b[4], b[5])
+}
+
+// recordMACPolicy remembers which policy produced iface's address, for
+// MACPolicy. A no-op when the Manager has no macPolicyPath, as in a
+// zero-value Manager; NewManager records to types.RuntimeDir.
+func (m *Manager) recordMACPolicy(iface string, rec macRecord) {
+	if m.macPolicyPath == "" {
+		return
+	}
+	records := m.macPolicies()
+	records[iface] = rec
+	data, err := json.Marshal(records)
+	if err != nil {
+		return
+	}
+	_ = os.MkdirAll(filepath.Dir(m.macPolicyPath), 0755)
+	if err := os.WriteFile(m.macPolicyPath, data, 0644); err != nil {
+		m.logger.Debug("Failed to record MAC policy", "error", err)
+	}
+}
+
+// macPolicies reads the recorded MAC policies, keyed by interface.
+func (m *Manager) macPolicies() map[string]macRecord {
+	records := make(map[string]macRecord)
+	if m.macPolicyPath == "" {
+		return records
+	}
+	if data, err := os.ReadFile(m.macPolicyPath); err == nil {
+		_ = json.Unmarshal(data, &records)
+	}
+	return records
+}
+
+// MACPolicy describes the policy that produced iface's current address, or
+// "" when it wasn't set by net (or has been changed since).
+func (m *Manager) MACPolicy(iface string) string {
+	rec, ok := m.macPolicies()[iface]
+	if !ok {
+		return ""
+	}
+	current, err := m.GetMAC(iface)
+	if err != nil || !strings.EqualFold(current, rec.MAC) {
+		return ""
+	}
+	return rec.Policy
+}
+
+// atomicWriteMode writes data to path via a temp file and rename, so a
+// crash never leaves a truncated secret or schedule behind.
+func atomicWriteMode(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
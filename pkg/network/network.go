@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/angelfreak/net/pkg/netlink"
+	"github.com/angelfreak/net/pkg/oui"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
 )
@@ -26,11 +27,21 @@ type Manager struct {
 	executor         types.SystemExecutor
 	logger           types.Logger
 	dhcpClient       types.DHCPClientManager
-	routeMgr         types.RouteManager // netlink-backed routing table access
-	addrMgr          types.AddrManager  // netlink-backed interface address access
-	linkMgr          types.LinkManager  // netlink-backed link access (up/down, MAC)
-	dnsOwnershipPath string             // overridable for tests; defaults to types.RuntimeDir/dns-owned
-	resolvConfPath   string             // overridable for tests; defaults to /etc/resolv.conf
+	routeMgr         types.RouteManager    // netlink-backed routing table access
+	addrMgr          types.AddrManager     // netlink-backed interface address access
+	linkMgr          types.LinkManager     // netlink-backed link access (up/down, MAC)
+	wireless         types.WirelessManager // nl80211-backed wireless interface and association info
+	dnsOwnershipPath string                // overridable for tests; defaults to types.RuntimeDir/dns-owned
+	resolvConfPath   string                // overridable for tests; defaults to /etc/resolv.conf
+	arpTablePath     string                // overridable for tests; defaults to /proc/net/arp
+	stubConfigPath   string                // overridable for tests; defaults to types.RuntimeDir/stubby.yml
+	macPolicyPath    string                // overridable for tests; defaults to types.RuntimeDir/mac-policy.json, empty disables recording
+	stateDir         string                // overridable for tests; defaults to types.StateDir
+	etcDir           string                // overridable for tests; defaults to /etc (hosts, hostname)
+	savedHostPath    string                // overridable for tests; defaults to types.RuntimeDir/hostname-saved
+	// setSysHostname sets the kernel hostname. Defaults to setHostname
+	// (sethostname(2)); overridable in tests, which can't change it.
+	setSysHostname func(name string) error
 	// setImmutable sets/clears the immutable flag on a file. Defaults to
 	// system.SetImmutable (native FS_IOC_SETFLAGS ioctl); overridable in tests
 	// so lock/unlock intent can be observed without CAP_LINUX_IMMUTABLE.
@@ -46,8 +57,12 @@ func NewManager(executor types.SystemExecutor, logger types.Logger, dhcpClient t
 		routeMgr:         netlink.NewRouteManager(),
 		addrMgr:          netlink.NewAddrManager(),
 		linkMgr:          netlink.NewLinkManager(),
+		wireless:         netlink.NewWirelessManager(),
 		dnsOwnershipPath: types.RuntimeDir + "/dns-owned",
 		resolvConfPath:   "/etc/resolv.conf",
+		arpTablePath:     "/proc/net/arp",
+		stubConfigPath:   types.RuntimeDir + "/stubby.yml",
+		macPolicyPath:    types.RuntimeDir + "/mac-policy.json",
 		setImmutable:     system.SetImmutable,
 	}
 }
@@ -110,8 +125,14 @@ func (m *Manager) isDNSOwned() bool {
 	return err == nil
 }
 
-// SetDNS configures DNS servers
+// SetDNS configures DNS servers, replacing an encrypted DNS stub set up by
+// SetEncryptedDNS.
 func (m *Manager) SetDNS(servers []string) error {
+	m.stopDNSStub()
+	return m.setDNS(servers)
+}
+
+func (m *Manager) setDNS(servers []string) error {
 	if len(servers) == 0 || (len(servers) == 1 && servers[0] == "dhcp") {
 		// Remove immutable flag to allow DHCP to update DNS
 		if err := m.unlockResolvConf(); err != nil {
@@ -181,6 +202,7 @@ func (m *Manager) ClearDNSIfOwned() (bool, error) {
 
 func (m *Manager) clearDNS() (bool, error) {
 	m.logger.Debug("Clearing DNS configuration")
+	m.stopDNSStub()
 
 	if !m.isDNSOwned() {
 		return false, nil
@@ -232,6 +254,13 @@ func (m *Manager) resolvConfHasNameserver() bool {
 
 // SetMAC sets the MAC address for an interface
 func (m *Manager) SetMAC(iface, mac string) error {
+	return m.applyMAC(iface, mac, "")
+}
+
+// applyMAC resolves the mac: setting for iface on network (the SSID, a
+// wired profile's name, or "" for a wired link without one) and sets the
+// resulting address.
+func (m *Manager) applyMAC(iface, mac, network string) error {
 	m.logger.Debug("SetMAC using interface", "interface", iface, "mac", mac)
 
 	// Validate interface name
@@ -239,27 +268,9 @@ func (m *Manager) SetMAC(iface, mac string) error {
 		return fmt.Errorf("invalid interface: %w", err)
 	}
 
-	if mac == "" || mac == "random" {
-		mac = m.generateRandomMAC()
-	}
-
-	if mac == "default" {
-		// Use a default MAC (random MacBook Pro style)
-		mac = m.generateMacBookProMAC()
-	}
-
-	if mac == "permanent" {
-		// Restore the factory/permanent MAC address
-		permMAC, err := m.getPermanentMAC(iface)
-		if err != nil {
-			return fmt.Errorf("failed to get permanent MAC: %w", err)
-		}
-		mac = permMAC
-	}
-
-	// Handle MAC templates like "00:??:??:??:??:??"
-	if strings.Contains(mac, "??") {
-		mac = m.expandMACTemplate(mac)
+	mac, policy, err := m.resolveMAC(iface, mac, network)
+	if err != nil {
+		return err
 	}
 
 	// Validate final MAC address format
@@ -284,6 +295,7 @@ func (m *Manager) SetMAC(iface, mac string) error {
 		return fmt.Errorf("failed to bring interface up: %w", err)
 	}
 
+	m.recordMACPolicy(iface, macRecord{MAC: mac, Policy: policy})
 	return nil
 }
 
@@ -368,6 +380,12 @@ func (m *Manager) FlushRoutes(iface string) error {
 
 // SetHostname sets the system hostname
 func (m *Manager) SetHostname(hostname string) error {
+	return m.applyHostname(hostname, true)
+}
+
+// applyHostname sets the kernel hostname and its /etc/hosts entry; persist
+// also writes /etc/hostname so it survives a reboot.
+func (m *Manager) applyHostname(hostname string, persist bool) error {
 	if hostname == "" {
 		m.logger.Debug("No hostname to set")
 		return nil
@@ -378,7 +396,8 @@ func (m *Manager) SetHostname(hostname string) error {
 	// Update /etc/hosts FIRST to include the new hostname (required for sudo to work)
 	// This must happen before the hostname command, otherwise sudo fails with
 	// "unable to resolve host" between the hostname change and hosts update.
-	hostsBytes, err := os.ReadFile("/etc/hosts")
+	hostsPath := m.etcFile("hosts")
+	hostsBytes, err := os.ReadFile(hostsPath)
 	if err != nil {
 		m.logger.Warn("Failed to read /etc/hosts", "error", err)
 	} else {
@@ -415,7 +434,7 @@ func (m *Manager) SetHostname(hostname string) error {
 
 		// Write updated hosts file
 		newHostsContent := strings.Join(newLines, "\n")
-		if err = os.WriteFile("/etc/hosts", []byte(newHostsContent), 0644); err != nil {
+		if err = os.WriteFile(hostsPath, []byte(newHostsContent), 0644); err != nil {
 			m.logger.Warn("Failed to update /etc/hosts", "error", err)
 		} else {
 			m.logger.Debug("Updated /etc/hosts with new hostname")
@@ -423,12 +442,15 @@ func (m *Manager) SetHostname(hostname string) error {
 	}
 
 	// Now set the hostname (after /etc/hosts is updated)
-	if err = setHostname(hostname); err != nil {
+	if err = m.sysHostname(hostname); err != nil {
 		return fmt.Errorf("failed to set hostname: %w", err)
 	}
 
 	// Also update /etc/hostname for persistence
-	if err = os.WriteFile("/etc/hostname", []byte(hostname+"\n"), 0644); err != nil {
+	if !persist {
+		return nil
+	}
+	if err = os.WriteFile(m.etcFile("hostname"), []byte(hostname+"\n"), 0644); err != nil {
 		m.logger.Warn("Failed to update /etc/hostname", "error", err)
 	}
 
@@ -543,20 +565,17 @@ func (m *Manager) detectInterface(config *types.NetworkConfig) string {
 // Helper functions
 
 func (m *Manager) findWirelessInterface() (string, error) {
-	output, err := m.executor.Execute("iw", "dev")
+	ifaces, err := m.wireless.Interfaces()
 	if err != nil {
 		m.logger.Debug("Failed to list wireless devices", "error", err)
 		return "", fmt.Errorf("failed to list wireless devices: %w", err)
 	}
 
-	// Parse output to find interface name
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Interface ") {
-			iface := strings.TrimPrefix(line, "Interface ")
-			m.logger.Debug("Found wireless interface", "interface", iface)
-			return iface, nil
+	// Access points and monitor interfaces can't join a network
+	for _, wi := range ifaces {
+		if wi.Type == "station" {
+			m.logger.Debug("Found wireless interface", "interface", wi.Name)
+			return wi.Name, nil
 		}
 	}
 
@@ -580,14 +599,13 @@ func (m *Manager) generateRandomMAC() string {
 }
 
 func (m *Manager) generateMacBookProMAC() string {
-	// Random MacBook Pro MAC (Apple OUI: AC:BC:32)
-	mac := make([]byte, 3)
-	_, err := rand.Read(mac)
+	// Random MacBook Pro style MAC: a real Apple prefix from the OUI database
+	mac, err := oui.RandomMAC("Apple")
 	if err != nil {
 		m.logger.Warn("Failed to generate random MAC, using fallback", "error", err)
 		return "ac:bc:32:00:00:01"
 	}
-	return fmt.Sprintf("ac:bc:32:%02x:%02x:%02x", mac[0], mac[1], mac[2])
+	return mac
 }
 
 func (m *Manager) expandMACTemplate(template string) string {
@@ -721,7 +739,10 @@ func hasCarrier(iface string) bool {
 
 // ConnectToConfiguredNetwork connects to a network based on the provided configuration
 func (m *Manager) ConnectToConfiguredNetwork(config *types.NetworkConfig, password string, wifiMgr types.WiFiManager) error {
-	// Detect interface if not configured
+	// Detect interface if not configured; WiFi goes to the default radio
+	if config.Interface == "" && config.SSID != "" && wifiMgr != nil {
+		config.Interface = wifiMgr.GetInterface()
+	}
 	if config.Interface == "" {
 		config.Interface = m.detectInterface(config)
 		if config.Interface == "" {
@@ -731,25 +752,41 @@ func (m *Manager) ConnectToConfiguredNetwork(config *types.NetworkConfig, passwo
 
 	m.logger.Debug("Connecting to configured network", "interface", config.Interface, "ssid", config.SSID, "addr", config.Addr)
 
+	// An encrypted DNS stub from the previous network is re-established by
+	// the caller if this network's trust policy wants it.
+	if !config.KeepDNS {
+		m.stopDNSStub()
+	}
+
 	// CRITICAL: Apply MAC address BEFORE bringing interface up or connecting
 	if config.MAC != "" {
 		m.logger.Debug("Setting MAC address from config (before connection)", "mac", config.MAC)
-		err := m.SetMAC(config.Interface, config.MAC)
+		// Per-network policies (stable, rotate) key on the SSID, or for a
+		// wired profile on its name.
+		network := config.SSID
+		if network == "" {
+			network = config.Name
+		}
+		err := m.applyMAC(config.Interface, config.MAC, network)
 		if err != nil {
 			return fmt.Errorf("failed to set MAC: %w", err)
 		}
 	}
 
 	// Note: Hostname is NOT set on the system, but will be sent in DHCP requests
-	// This prevents changing the local system hostname while still identifying to DHCP servers
-	if config.Hostname != "" {
-		m.logger.Debug("Will send hostname in DHCP request", "hostname", config.Hostname)
+	// This prevents changing the local system hostname while still identifying to DHCP servers.
+	// hostname_mode: system sets it for the duration of the connection
+	// instead; anonymous sends none at all.
+	hostname := m.prepareHostname(config)
+	if hostname != "" {
+		m.logger.Debug("Will send hostname in DHCP request", "hostname", hostname)
 	}
 
 	// Check if we should use DHCP for DNS - if so, unlock resolv.conf BEFORE DHCP runs
 	// so the DHCP client can write DNS servers from the DHCP response.
 	// This applies when: dns: dhcp is set, OR no DNS is configured at all (let DHCP handle it)
-	useDHCPForDNS := config.DNS == nil || len(config.DNS) == 0 || (len(config.DNS) == 1 && config.DNS[0] == "dhcp")
+	// Neither applies when another interface's connection keeps the DNS.
+	useDHCPForDNS := !config.KeepDNS && (config.DNS == nil || len(config.DNS) == 0 || (len(config.DNS) == 1 && config.DNS[0] == "dhcp"))
 	if useDHCPForDNS {
 		m.logger.Debug("Clearing resolv.conf for DHCP DNS")
 		if err := m.unlockResolvConf(); err != nil {
@@ -763,8 +800,10 @@ func (m *Manager) ConnectToConfiguredNetwork(config *types.NetworkConfig, passwo
 		}
 	}
 
-	// Connect to WiFi if SSID is specified
+	// Connect to WiFi if SSID is specified, with the manager of the radio
+	// the network's interface selects
 	if config.SSID != "" {
+		wifiMgr = wifiMgr.ForInterface(config.Interface)
 		m.logger.Debug("Connecting to WiFi from config", "ssid", config.SSID, "apAddr", config.ApAddr)
 		if password == "" {
 			password = config.PSK
@@ -775,15 +814,33 @@ func (m *Manager) ConnectToConfiguredNetwork(config *types.NetworkConfig, passwo
 		}
 		m.logger.Info("Connecting to SSID", "ssid", config.SSID)
 
-		// Use BSSID pinning if ap-addr is configured
-		if config.ApAddr != "" {
+		// Use BSSID pinning if ap-addr is configured, or pin the access point
+		// the roam: policy picks from a scan
+		// A hidden network isn't in the scan, so there's nothing to pick from.
+		bssid := config.ApAddr
+		if bssid != "" {
 			m.logger.Info("Using AP address pinning", "bssid", config.ApAddr)
-			err := wifiMgr.ConnectWithBSSID(config.SSID, password, config.ApAddr, config.Hostname)
+		} else if config.Roam != nil && !config.Hidden {
+			best, err := wifiMgr.BestBSSID(config.SSID, *config.Roam)
+			if err != nil {
+				m.logger.Warn("Failed to pick an access point, leaving it to wpa_supplicant", "error", err)
+			} else if best != "" {
+				m.logger.Info("Using the access point the roam policy prefers", "bssid", best)
+				bssid = best
+			}
+		}
+		if config.Hidden {
+			err := wifiMgr.ConnectHidden(config.SSID, password, bssid, hostname)
+			if err != nil {
+				return fmt.Errorf("failed to connect to WiFi: %w", err)
+			}
+		} else if bssid != "" {
+			err := wifiMgr.ConnectWithBSSID(config.SSID, password, bssid, hostname)
 			if err != nil {
 				return fmt.Errorf("failed to connect to WiFi: %w", err)
 			}
 		} else {
-			err := wifiMgr.Connect(config.SSID, password, config.Hostname)
+			err := wifiMgr.Connect(config.SSID, password, hostname)
 			if err != nil {
 				return fmt.Errorf("failed to connect to WiFi: %w", err)
 			}
@@ -833,7 +890,7 @@ func (m *Manager) ConnectToConfiguredNetwork(config *types.NetworkConfig, passwo
 
 			if config.Addr == "" {
 				m.logger.Info("Obtaining DHCP lease on wired interface", "interface", config.Interface)
-				err := m.StartDHCP(config.Interface, config.Hostname)
+				err := m.StartDHCP(config.Interface, hostname)
 				if err != nil {
 					// Surface the failure instead of reporting a successful
 					// connection with no lease. The WiFi path already errors
@@ -878,7 +935,9 @@ func (m *Manager) ConnectToConfiguredNetwork(config *types.NetworkConfig, passwo
 	}
 
 	// Apply DNS AFTER DHCP completes (to override DHCP-provided DNS)
-	if config.DNS != nil && len(config.DNS) > 0 {
+	if config.KeepDNS {
+		m.logger.Debug("Leaving DNS to the connection of another interface")
+	} else if config.DNS != nil && len(config.DNS) > 0 {
 		// Check if DNS is "dhcp" - if so, skip manual DNS setting
 		if len(config.DNS) == 1 && config.DNS[0] == "dhcp" {
 			m.logger.Debug("Using DHCP-provided DNS")
@@ -950,20 +1009,10 @@ func (m *Manager) GetConnectionInfo(iface string) (*types.Connection, error) {
 }
 
 // currentSSID returns the SSID the interface is associated with, or "" for
-// wired interfaces / when not associated. Uses `iw dev <iface> link`, which
-// prints "SSID: <name>" when connected.
+// wired interfaces / when not associated.
 func (m *Manager) currentSSID(iface string) string {
-	output, err := m.executor.ExecuteWithTimeout(2*time.Second, "iw", "dev", iface, "link")
-	if err != nil {
-		return ""
-	}
-	for _, line := range strings.Split(output, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "SSID: ") {
-			return strings.TrimSpace(strings.TrimPrefix(line, "SSID: "))
-		}
-	}
-	return ""
+	ssid, _ := m.linkInfo(iface)
+	return ssid
 }
 
 // applyDefaultRouteMetric finds the DHCP-installed default route on iface and
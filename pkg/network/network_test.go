@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/angelfreak/net/pkg/netlink/fake"
+	"github.com/angelfreak/net/pkg/oui"
 	"github.com/angelfreak/net/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -289,12 +291,24 @@ type mockDHCPClient struct {
 	acquireErr error
 	releaseErr error
 	renewErr   error
+	hostnames  []string // hostname passed to each Acquire
+	options    types.DHCPClientOptions
+	domain     string
 }
 
 func (m *mockDHCPClient) Acquire(iface string, hostname string) error {
+	m.hostnames = append(m.hostnames, hostname)
 	return m.acquireErr
 }
 
+func (m *mockDHCPClient) SetOptions(opts types.DHCPClientOptions) {
+	m.options = opts
+}
+
+func (m *mockDHCPClient) Domain(iface string) string {
+	return m.domain
+}
+
 func (m *mockDHCPClient) Release(iface string) error {
 	return m.releaseErr
 }
@@ -734,18 +748,24 @@ func TestDHCPRenew(t *testing.T) {
 }
 
 func TestFindWirelessInterface(t *testing.T) {
-	t.Run("found - parses iw dev output", func(t *testing.T) {
-		executor := newStrictMockExecutor()
-		// Realistic iw dev output
-		executor.commands["iw dev"] = `phy#0
-	Interface wlan0
-		ifindex 3
-		wdev 0x1
-		addr 00:11:22:33:44:55
-		type managed
-		channel 6 (2437 MHz), width: 20 MHz, center1: 2437 MHz`
+	t.Run("found - first station interface", func(t *testing.T) {
+		wireless := &fake.WirelessManager{Ifaces: []types.WirelessInterface{
+			{Name: "wlan0", Type: "station", PHY: 0, MAC: "00:11:22:33:44:55"},
+		}}
 		logger := &mockLogger{}
-		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: newStrictMockExecutor(), logger: logger}
+
+		result, err := manager.findWirelessInterface()
+		assert.NoError(t, err)
+		assert.Equal(t, "wlan0", result)
+	})
+
+	t.Run("access point is skipped", func(t *testing.T) {
+		wireless := &fake.WirelessManager{Ifaces: []types.WirelessInterface{
+			{Name: "wlan0_ap", Type: "ap"},
+			{Name: "wlan0", Type: "station"},
+		}}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: newStrictMockExecutor(), logger: &mockLogger{}}
 
 		result, err := manager.findWirelessInterface()
 		assert.NoError(t, err)
@@ -753,24 +773,22 @@ func TestFindWirelessInterface(t *testing.T) {
 	})
 
 	t.Run("no wireless interfaces - returns error", func(t *testing.T) {
-		executor := newStrictMockExecutor()
-		executor.commands["iw dev"] = ""
 		logger := &mockLogger{}
-		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: &fake.WirelessManager{}, executor: newStrictMockExecutor(), logger: logger}
 
 		_, err := manager.findWirelessInterface()
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no wireless interface found")
 	})
 
-	t.Run("iw dev fails - returns error", func(t *testing.T) {
-		executor := newStrictMockExecutor()
-		executor.errors["iw dev"] = fmt.Errorf("iw: command not found")
+	t.Run("nl80211 fails - returns error", func(t *testing.T) {
+		wireless := &fake.WirelessManager{InterfacesErr: fmt.Errorf("nl80211 not found")}
 		logger := &mockLogger{}
-		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: newStrictMockExecutor(), logger: logger}
 
 		_, err := manager.findWirelessInterface()
 		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list wireless devices")
 	})
 }
 
@@ -783,7 +801,8 @@ func TestGenerateRandomMAC(t *testing.T) {
 func TestGenerateMacBookProMAC(t *testing.T) {
 	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks()}
 	mac := manager.generateMacBookProMAC()
-	assert.Regexp(t, `^ac:bc:32:[0-9a-f]{2}(:[0-9a-f]{2}){2}$`, mac)
+	assert.Regexp(t, `^[0-9a-f]{2}(:[0-9a-f]{2}){5}$`, mac)
+	assert.Equal(t, "Apple", oui.Lookup(mac))
 }
 
 func TestExpandMACTemplate(t *testing.T) {
@@ -937,6 +956,71 @@ func TestSetHostname(t *testing.T) {
 	})
 }
 
+func TestHostnameModes(t *testing.T) {
+	newManager := func(t *testing.T) (*Manager, *mockDHCPClient, *[]string) {
+		etc := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(etc, "hosts"), []byte("127.0.0.1\tlocalhost\n127.0.1.1\tmylaptop\n"), 0644))
+		var set []string
+		dhcpClient := &mockDHCPClient{}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: newMockExecutor(), logger: &mockLogger{},
+			dhcpClient: dhcpClient, setImmutable: (&immutableRecorder{}).set, resolvConfPath: filepath.Join(etc, "resolv.conf"),
+			etcDir: etc, savedHostPath: filepath.Join(t.TempDir(), "hostname-saved"),
+			setSysHostname: func(name string) error { set = append(set, name); return nil }}
+		return manager, dhcpClient, &set
+	}
+	wired := func(mode string) *types.NetworkConfig {
+		return &types.NetworkConfig{Interface: "eth0", Hostname: "kiosk-7", HostnameMode: mode}
+	}
+
+	t.Run("default sends the hostname in DHCP only", func(t *testing.T) {
+		manager, dhcpClient, set := newManager(t)
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(wired(""), "", nil))
+		assert.Equal(t, []string{"kiosk-7"}, dhcpClient.hostnames)
+		assert.False(t, dhcpClient.options.NoHostname)
+		assert.Empty(t, *set)
+	})
+
+	t.Run("anonymous omits the hostname entirely", func(t *testing.T) {
+		manager, dhcpClient, set := newManager(t)
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(wired(types.HostnameModeAnonymous), "", nil))
+		assert.Equal(t, []string{""}, dhcpClient.hostnames)
+		assert.True(t, dhcpClient.options.NoHostname)
+		assert.Empty(t, *set)
+	})
+
+	t.Run("system sets the hostname until restored", func(t *testing.T) {
+		manager, dhcpClient, set := newManager(t)
+		original, err := os.Hostname()
+		assert.NoError(t, err)
+
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(wired(types.HostnameModeSystem), "", nil))
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(wired(types.HostnameModeSystem), "", nil))
+		assert.Equal(t, []string{"kiosk-7", "kiosk-7"}, dhcpClient.hostnames)
+		hosts, _ := os.ReadFile(manager.etcFile("hosts"))
+		assert.Contains(t, string(hosts), "127.0.1.1\tkiosk-7")
+		_, err = os.Stat(manager.etcFile("hostname"))
+		assert.True(t, os.IsNotExist(err), "a connection's hostname is not persisted")
+
+		restored, err := manager.RestoreHostname()
+		assert.NoError(t, err)
+		assert.True(t, restored)
+		assert.Equal(t, []string{"kiosk-7", "kiosk-7", original}, *set, "the machine's own name comes back, not the previous network's")
+
+		restored, err = manager.RestoreHostname()
+		assert.NoError(t, err)
+		assert.False(t, restored)
+	})
+
+	t.Run("dhcp_anonymous selects the anonymity profile", func(t *testing.T) {
+		manager, dhcpClient, _ := newManager(t)
+		config := wired("")
+		config.DHCPAnonymous = true
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(config, "", nil))
+		assert.Equal(t, []string{""}, dhcpClient.hostnames)
+		assert.Equal(t, types.DHCPClientOptions{NoHostname: true, Anonymous: true}, dhcpClient.options)
+	})
+}
+
 func TestDetectInterface(t *testing.T) {
 	t.Run("configured interface", func(t *testing.T) {
 		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), logger: &mockLogger{}}
@@ -1013,6 +1097,71 @@ func TestConnectToConfiguredNetwork(t *testing.T) {
 
 		err := manager.ConnectToConfiguredNetwork(config, "", wifiManager)
 		assert.NoError(t, err)
+		assert.Equal(t, "00:11:22:33:44:55", wifiManager.pinnedBSSID)
+	})
+
+	t.Run("wireless with roam policy pins the preferred BSSID", func(t *testing.T) {
+		executor := newMockExecutor()
+		logger := &mockLogger{}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger, setImmutable: (&immutableRecorder{}).set}
+
+		config := &types.NetworkConfig{
+			Interface: "wlan0",
+			SSID:      "test-network",
+			PSK:       "password123",
+			Roam:      &types.RoamConfig{PreferBand: "5"},
+		}
+
+		wifiManager := &mockWiFiManagerImpl{
+			executor:  executor,
+			logger:    logger,
+			bestBSSID: "00:11:22:33:44:66",
+		}
+
+		err := manager.ConnectToConfiguredNetwork(config, "", wifiManager)
+		assert.NoError(t, err)
+		assert.Equal(t, "00:11:22:33:44:66", wifiManager.pinnedBSSID)
+	})
+
+	t.Run("interface selects the radio", func(t *testing.T) {
+		executor := newMockExecutor()
+		logger := &mockLogger{}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger, setImmutable: (&immutableRecorder{}).set}
+		wifiManager := &mockWiFiManagerImpl{executor: executor, logger: logger}
+
+		usb := &types.NetworkConfig{Interface: "wlan1", SSID: "mgmt"}
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(usb, "", wifiManager))
+		assert.Equal(t, "wlan1", wifiManager.radio)
+
+		site := &types.NetworkConfig{SSID: "site"}
+		assert.NoError(t, manager.ConnectToConfiguredNetwork(site, "", wifiManager))
+		assert.Equal(t, "wlan0", site.Interface, "WiFi without interface: uses the default radio")
+		assert.Equal(t, "wlan0", wifiManager.radio)
+	})
+
+	t.Run("hidden network skips the roam scan", func(t *testing.T) {
+		executor := newMockExecutor()
+		logger := &mockLogger{}
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger, setImmutable: (&immutableRecorder{}).set}
+
+		config := &types.NetworkConfig{
+			Interface: "wlan0",
+			SSID:      "gateway",
+			PSK:       "password123",
+			Hidden:    true,
+			Roam:      &types.RoamConfig{PreferBand: "5"},
+		}
+
+		wifiManager := &mockWiFiManagerImpl{
+			executor:  executor,
+			logger:    logger,
+			bestBSSID: "00:11:22:33:44:66",
+		}
+
+		err := manager.ConnectToConfiguredNetwork(config, "", wifiManager)
+		assert.NoError(t, err)
+		assert.Equal(t, "gateway", wifiManager.hiddenSSID)
+		assert.Empty(t, wifiManager.pinnedBSSID)
 	})
 
 	t.Run("wired connection with DHCP", func(t *testing.T) {
@@ -1161,6 +1310,41 @@ func TestConnectToConfiguredNetwork(t *testing.T) {
 		assert.False(t, manager.isDNSOwned(), "must not claim DNS ownership when nothing was written")
 	})
 
+	t.Run("KeepDNS leaves resolv.conf to the other connection", func(t *testing.T) {
+		tmp := t.TempDir()
+		resolv := filepath.Join(tmp, "resolv.conf")
+		assert.NoError(t, os.WriteFile(resolv, []byte("nameserver 10.0.0.53\n"), 0644))
+		executor := newMockExecutor()
+		rec := &immutableRecorder{}
+		executor.commands["ip addr flush dev eth1"] = ""
+		executor.commands["ip addr add 192.168.1.100/24 dev eth1"] = ""
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: &mockLogger{}, dnsOwnershipPath: tmp + "/dns-owned", setImmutable: rec.set, resolvConfPath: resolv}
+
+		for _, dns := range [][]string{nil, {"9.9.9.9"}} {
+			config := &types.NetworkConfig{Interface: "eth1", Addr: "192.168.1.100/24", DNS: dns, KeepDNS: true}
+			assert.NoError(t, manager.ConnectToConfiguredNetwork(config, "", nil))
+
+			got, err := os.ReadFile(resolv)
+			assert.NoError(t, err)
+			assert.Equal(t, "nameserver 10.0.0.53\n", string(got))
+			assert.False(t, rec.sawUnlock(resolv) || rec.sawLock(resolv), "must not touch resolv.conf")
+		}
+	})
+
+	t.Run("wired profiles get a stable MAC of their own", func(t *testing.T) {
+		tmp := t.TempDir()
+		executor := newMockExecutor()
+		links := newFakeLinks()
+		manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: links, executor: executor, logger: &mockLogger{}, stateDir: tmp}
+
+		macFor := func(name string) string {
+			config := &types.NetworkConfig{Name: name, Interface: "eth1", Addr: "192.168.1.100/24", MAC: "stable", KeepDNS: true}
+			assert.NoError(t, manager.ConnectToConfiguredNetwork(config, "", nil))
+			return links.SetMACCalls[len(links.SetMACCalls)-1].MAC
+		}
+		assert.NotEqual(t, macFor("office"), macFor("lab"), "wired profiles have no SSID to tell them apart")
+	})
+
 	t.Run("DHCP DNS locks resolv.conf after connection to prevent netbird overwrite", func(t *testing.T) {
 		// When using DHCP for DNS and netbird is still connected, netbird
 		// will overwrite resolv.conf with its own DNS after DHCP writes it.
@@ -1239,6 +1423,14 @@ type mockWiFiManagerImpl struct {
 	// onConnect, if set, is invoked during Connect to simulate side effects a
 	// real DHCP client would perform (e.g. writing nameservers to resolv.conf).
 	onConnect func() error
+	// bestBSSID is what BestBSSID returns; pinnedBSSID records the BSSID
+	// ConnectWithBSSID was called with.
+	bestBSSID   string
+	pinnedBSSID string
+	// hiddenSSID records the SSID ConnectHidden was called with.
+	hiddenSSID string
+	// radio records the interface of the last ForInterface call.
+	radio string
 }
 
 func (m *mockWiFiManagerImpl) Scan() ([]types.WiFiNetwork, error) {
@@ -1253,9 +1445,32 @@ func (m *mockWiFiManagerImpl) Connect(ssid, password, hostname string) error {
 }
 
 func (m *mockWiFiManagerImpl) ConnectWithBSSID(ssid, password, bssid, hostname string) error {
+	m.pinnedBSSID = bssid
+	return nil
+}
+
+func (m *mockWiFiManagerImpl) ConnectHidden(ssid, password, bssid, hostname string) error {
+	m.hiddenSSID = ssid
+	m.pinnedBSSID = bssid
+	return nil
+}
+
+func (m *mockWiFiManagerImpl) ConnectWPS(bssid, pin, hostname string) (*types.WPSCredential, error) {
+	return nil, nil
+}
+
+func (m *mockWiFiManagerImpl) DetectNetwork(ssid string) *types.WiFiNetwork {
 	return nil
 }
 
+func (m *mockWiFiManagerImpl) BestBSSID(ssid string, policy types.RoamConfig) (string, error) {
+	return m.bestBSSID, nil
+}
+
+func (m *mockWiFiManagerImpl) Roam(ssid string, policy types.RoamConfig) (*types.RoamEvent, error) {
+	return nil, nil
+}
+
 func (m *mockWiFiManagerImpl) Disconnect() error {
 	return nil
 }
@@ -1268,6 +1483,11 @@ func (m *mockWiFiManagerImpl) GetInterface() string {
 	return "wlan0"
 }
 
+func (m *mockWiFiManagerImpl) ForInterface(iface string) types.WiFiManager {
+	m.radio = iface
+	return m
+}
+
 // ============================================================================
 // Additional tests for improved coverage
 // ============================================================================
@@ -1377,6 +1597,83 @@ func TestConnectToConfiguredNetwork_WiredDHCPFailureReturnsError(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to obtain DHCP lease")
 }
 
+func TestMACPolicies(t *testing.T) {
+	newManager := func(stateDir string) (*Manager, *fake.LinkManager) {
+		links := newFakeLinks()
+		return &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: links, executor: newStrictMockExecutor(), logger: &mockLogger{}, stateDir: stateDir}, links
+	}
+	setFor := func(m *Manager, links *fake.LinkManager, mac, ssid string) string {
+		assert.NoError(t, m.applyMAC("wlan0", mac, ssid))
+		return links.SetMACCalls[len(links.SetMACCalls)-1].MAC
+	}
+
+	t.Run("stable is per network and survives restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		manager, links := newManager(dir)
+		home := setFor(manager, links, "stable", "Home")
+		cafe := setFor(manager, links, "stable", "Cafe")
+		assert.Equal(t, home, setFor(manager, links, "stable", "Home"))
+		assert.NotEqual(t, home, cafe)
+
+		// Locally administered, unicast.
+		first, err := strconv.ParseUint(home[:2], 16, 8)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0x02), first&0x03)
+
+		restarted, links := newManager(dir)
+		assert.Equal(t, home, setFor(restarted, links, "stable", "Home"))
+
+		info, err := os.Stat(filepath.Join(dir, "mac-secret"))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		other, links := newManager(t.TempDir())
+		assert.NotEqual(t, home, setFor(other, links, "stable", "Home"), "a different secret gives a different address")
+	})
+
+	t.Run("rotate keeps the address until the period elapses", func(t *testing.T) {
+		now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		timeNow = func() time.Time { return now }
+		defer func() { timeNow = time.Now }()
+
+		dir := t.TempDir()
+		manager, links := newManager(dir)
+		first := setFor(manager, links, "rotate:24h", "Home")
+		now = now.Add(23 * time.Hour)
+		restarted, links := newManager(dir)
+		assert.Equal(t, first, setFor(restarted, links, "rotate:24h", "Home"))
+		assert.NotEqual(t, first, setFor(restarted, links, "rotate:24h", "Cafe"), "each network rotates on its own schedule")
+
+		now = now.Add(2 * time.Hour)
+		assert.NotEqual(t, first, setFor(restarted, links, "rotate:24h", "Home"))
+	})
+
+	t.Run("vendor picks a prefix of that vendor", func(t *testing.T) {
+		manager, links := newManager(t.TempDir())
+		mac := setFor(manager, links, "vendor:intel", "")
+		assert.Equal(t, "Intel", oui.Lookup(mac))
+		assert.Error(t, manager.applyMAC("wlan0", "vendor:Acme", ""))
+	})
+
+	t.Run("MACPolicy labels the address it recorded", func(t *testing.T) {
+		manager, links := newManager(t.TempDir())
+		manager.macPolicyPath = filepath.Join(t.TempDir(), "mac-policy.json")
+		links.MACs = map[string]string{}
+
+		mac := setFor(manager, links, "stable", "Home")
+		links.MACs["wlan0"] = strings.ToUpper(mac)
+		assert.Equal(t, "stable for Home", manager.MACPolicy("wlan0"))
+
+		setFor(manager, links, "aa:bb:cc:dd:ee:ff", "")
+		links.MACs["wlan0"] = "aa:bb:cc:dd:ee:ff"
+		assert.Equal(t, "fixed", manager.MACPolicy("wlan0"))
+
+		links.MACs["wlan0"] = "00:11:22:33:44:55"
+		assert.Equal(t, "", manager.MACPolicy("wlan0"), "changed outside net")
+		assert.Equal(t, "", manager.MACPolicy("eth0"))
+	})
+}
+
 func TestSetMAC_ErrorPaths(t *testing.T) {
 	t.Run("interface down fails", func(t *testing.T) {
 		executor := &mockSystemExecutor{}
@@ -1683,13 +1980,12 @@ func TestFlushRoutes_Error(t *testing.T) {
 }
 
 func TestFindWirelessInterface_MultipleInterfaces(t *testing.T) {
-	executor := &mockSystemExecutor{
-		commands: map[string]string{
-			"iw dev": "Interface wlan0\nInterface wlan1",
-		},
-	}
+	wireless := &fake.WirelessManager{Ifaces: []types.WirelessInterface{
+		{Name: "wlan0", Type: "station"},
+		{Name: "wlan1", Type: "station"},
+	}}
 	logger := &mockLogger{}
-	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: logger}
+	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: &mockSystemExecutor{}, logger: logger}
 
 	result, err := manager.findWirelessInterface()
 	assert.NoError(t, err)
@@ -1748,6 +2044,26 @@ func (m *mockWiFiManagerFailing) ConnectWithBSSID(ssid, password, bssid, hostnam
 	return assert.AnError
 }
 
+func (m *mockWiFiManagerFailing) ConnectHidden(ssid, password, bssid, hostname string) error {
+	return assert.AnError
+}
+
+func (m *mockWiFiManagerFailing) ConnectWPS(bssid, pin, hostname string) (*types.WPSCredential, error) {
+	return nil, assert.AnError
+}
+
+func (m *mockWiFiManagerFailing) DetectNetwork(ssid string) *types.WiFiNetwork {
+	return nil
+}
+
+func (m *mockWiFiManagerFailing) BestBSSID(ssid string, policy types.RoamConfig) (string, error) {
+	return "", assert.AnError
+}
+
+func (m *mockWiFiManagerFailing) Roam(ssid string, policy types.RoamConfig) (*types.RoamEvent, error) {
+	return nil, assert.AnError
+}
+
 func (m *mockWiFiManagerFailing) Disconnect() error {
 	return assert.AnError
 }
@@ -1756,6 +2072,10 @@ func (m *mockWiFiManagerFailing) ListConnections() ([]types.Connection, error) {
 	return nil, assert.AnError
 }
 
+func (m *mockWiFiManagerFailing) ForInterface(iface string) types.WiFiManager {
+	return m
+}
+
 func (m *mockWiFiManagerFailing) GetInterface() string {
 	return "wlan0"
 }
@@ -1768,15 +2088,17 @@ func TestGetConnectionInfo_PopulatesSSID(t *testing.T) {
 	// mock has no effect. This test only asserts on SSID, so it's unaffected.
 	executor := newMockExecutor()
 	executor.commands["ip addr show wlan0"] = "inet 192.168.1.50/24"
-	executor.commands["iw dev wlan0 link"] = "Connected to aa:bb:cc:dd:ee:ff\n\tSSID: CoffeeShop\n\tfreq: 2412"
-	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: &mockLogger{}}
+	wireless := &fake.WirelessManager{Links: map[string]*types.WirelessLink{
+		"wlan0": {SSID: "CoffeeShop", BSSID: "aa:bb:cc:dd:ee:ff", Frequency: 2412},
+	}}
+	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: executor, logger: &mockLogger{}}
 
 	conn, err := manager.GetConnectionInfo("wlan0")
 	assert.NoError(t, err)
 	assert.Equal(t, "CoffeeShop", conn.SSID)
 }
 
-// A wired interface (iw returns an error / no link) must leave SSID empty
+// A wired interface (nl80211 returns an error / no link) must leave SSID empty
 // rather than showing garbage.
 func TestGetConnectionInfo_NoSSIDForWired(t *testing.T) {
 	// Note: getDNSServers() now reads the real /etc/resolv.conf via
@@ -1784,8 +2106,8 @@ func TestGetConnectionInfo_NoSSIDForWired(t *testing.T) {
 	// mock has no effect. This test only asserts on SSID, so it's unaffected.
 	executor := newMockExecutor()
 	executor.commands["ip addr show eth0"] = "inet 10.0.0.5/24"
-	executor.errors["iw dev eth0 link"] = fmt.Errorf("nl80211 not found (not a wireless interface)")
-	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), executor: executor, logger: &mockLogger{}}
+	wireless := &fake.WirelessManager{LinkErr: fmt.Errorf("no such device")}
+	manager := &Manager{routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: executor, logger: &mockLogger{}}
 
 	conn, err := manager.GetConnectionInfo("eth0")
 	assert.NoError(t, err)
@@ -1827,3 +2149,108 @@ func TestDisconnect(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to bring interface down")
 	})
 }
+
+func TestDetectEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	arp := filepath.Join(dir, "arp")
+	resolv := filepath.Join(dir, "resolv.conf")
+	os.WriteFile(arp, []byte("IP address       HW type     Flags       HW address            Mask     Device\n"+
+		"192.168.1.1      0x1         0x2         AA:BB:CC:DD:EE:FF     *        eth0\n"+
+		"192.168.1.1      0x1         0x2         11:22:33:44:55:66     *        wlan0\n"), 0644)
+	// What net wrote when it set DNS, not what DHCP handed out
+	os.WriteFile(resolv, []byte("search home.example.net\nnameserver 1.1.1.1\n"), 0644)
+
+	executor := newMockExecutor()
+	executor.hasCommands = map[string]bool{"lldpctl": true}
+	executor.commands["lldpctl -f keyvalue eth0"] = "lldp.eth0.via=LLDP\nlldp.eth0.chassis.name=sw-3f-core\nlldp.eth0.port.descr=Gi1/0/12\n"
+	manager := &Manager{
+		routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(),
+		wireless: &fake.WirelessManager{LinkErr: fmt.Errorf("no such device")},
+		executor: executor, logger: &mockLogger{}, arpTablePath: arp, resolvConfPath: resolv,
+		dhcpClient: &mockDHCPClient{domain: "corp.example.com"},
+	}
+
+	env, err := manager.DetectEnvironment("eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, &types.Environment{
+		Interface:  "eth0",
+		GatewayMAC: "aa:bb:cc:dd:ee:ff",
+		Domain:     "corp.example.com",
+		Switch:     "sw-3f-core",
+	}, env)
+}
+
+func TestDetectEnvironment_WiFi(t *testing.T) {
+	dir := t.TempDir()
+	executor := newMockExecutor()
+	wireless := &fake.WirelessManager{Links: map[string]*types.WirelessLink{
+		"wlan0": {SSID: "Guest", BSSID: "00:11:22:AA:BB:CC", Frequency: 5180},
+	}}
+	manager := &Manager{
+		routeMgr: &fake.RouteManager{Routes: []types.Route{{Gw: "10.1.0.1", Iface: "wlan0"}}},
+		addrMgr:  newFakeAddrs(), linkMgr: newFakeLinks(), wireless: wireless, executor: executor, logger: &mockLogger{},
+		arpTablePath: filepath.Join(dir, "arp"), resolvConfPath: filepath.Join(dir, "resolv.conf"),
+	}
+
+	env, err := manager.DetectEnvironment("wlan0")
+	assert.NoError(t, err)
+	assert.Equal(t, "Guest", env.SSID)
+	assert.Equal(t, "00:11:22:aa:bb:cc", env.BSSID)
+	// No ARP entry: one ping to populate it, still unknown afterwards.
+	assert.Equal(t, "", env.GatewayMAC)
+	executor.assertCommandExecuted(t, "ping -c 1 -W 1 -I wlan0 10.1.0.1")
+	// lldpctl isn't installed.
+	executor.assertCommandNotExecuted(t, "lldpctl -f keyvalue wlan0")
+}
+
+func TestParseDoTServers(t *testing.T) {
+	servers, err := parseDoTServers([]string{"1.1.1.1", "192.0.2.1#dns.example.net"})
+	assert.NoError(t, err)
+	assert.Equal(t, []dotServer{{"1.1.1.1", "cloudflare-dns.com"}, {"192.0.2.1", "dns.example.net"}}, servers)
+
+	servers, err = parseDoTServers([]string{"dhcp"})
+	assert.NoError(t, err)
+	assert.Len(t, servers, len(defaultDoTServers))
+
+	_, err = parseDoTServers([]string{"192.0.2.1"})
+	assert.ErrorContains(t, err, "192.0.2.1#")
+	_, err = parseDoTServers([]string{"dns.example.net"})
+	assert.Error(t, err)
+}
+
+func TestSetEncryptedDNS(t *testing.T) {
+	dir := t.TempDir()
+	stub := filepath.Join(dir, "stubby.yml")
+	resolv := filepath.Join(dir, "resolv.conf")
+	executor := newStrictMockExecutor()
+	executor.hasCommands = map[string]bool{"stubby": true}
+	executor.commands["stubby -g -C "+stub] = ""
+	executor.commands["pkill -9 -f stubby -g -C "+stub] = ""
+	rec := &immutableRecorder{}
+	manager := &Manager{
+		routeMgr: newFakeRoutes(), addrMgr: newFakeAddrs(), linkMgr: newFakeLinks(),
+		executor: executor, logger: &mockLogger{}, setImmutable: rec.set, resolvConfPath: resolv,
+		stubConfigPath: stub, dnsOwnershipPath: filepath.Join(dir, "dns-owned"),
+	}
+
+	assert.NoError(t, manager.SetEncryptedDNS([]string{"9.9.9.9"}))
+	conf, err := os.ReadFile(stub)
+	assert.NoError(t, err)
+	assert.Contains(t, string(conf), "address_data: 9.9.9.9")
+	assert.Contains(t, string(conf), `tls_auth_name: "dns.quad9.net"`)
+	assert.Contains(t, string(conf), "GETDNS_AUTHENTICATION_REQUIRED")
+	got, err := os.ReadFile(resolv)
+	assert.NoError(t, err)
+	assert.Equal(t, "nameserver "+dnsStubAddr+"\n", string(got))
+
+	// Plain DNS replaces the stub.
+	assert.NoError(t, manager.SetDNS([]string{"1.1.1.1"}))
+	executor.assertCommandExecuted(t, "pkill -9 -f stubby -g -C "+stub)
+	_, err = os.Stat(stub)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSetEncryptedDNS_StubbyMissing(t *testing.T) {
+	manager := &Manager{executor: newStrictMockExecutor(), logger: &mockLogger{}, stubConfigPath: filepath.Join(t.TempDir(), "stubby.yml")}
+	assert.ErrorContains(t, manager.SetEncryptedDNS(nil), "stubby")
+}
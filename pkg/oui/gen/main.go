@@ -0,0 +1,197 @@
+// Command gen regenerates the embedded OUI database (oui.txt) from the IEEE
+// MA-L registry, trimmed to one "<prefix> <vendor>" line per assignment with
+// the vendor names shortened ("Apple, Inc." -> "Apple").
+//
+// It runs from the oui package via go generate; -in reads a local copy of
+// oui.csv instead of downloading it.
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const registryURL = "https://standards-oui.ieee.org/oui/oui.csv"
+
+const header = `# Code generated by "go generate" from the IEEE MA-L registry; DO NOT EDIT.
+# Compact OUI database: <prefix> <vendor>, one assignment per line.
+# Vendor names are shortened; see gen/main.go.
+`
+
+// names renames vendors whose shortened registry name isn't what people
+// call them, keyed by the lowercased short name.
+var names = map[string]string{
+	"asustek":                 "ASUS",
+	"hewlett packard":         "HP",
+	"pcs systemtechnik":       "VirtualBox",
+	"raspberry pi foundation": "Raspberry Pi",
+	"raspberry pi trading":    "Raspberry Pi",
+	"tp-link":                 "TP-Link",
+	"vmware":                  "VMware",
+	"xensource":               "Xen",
+}
+
+// extra lists well-known prefixes that aren't MA-L assignments.
+var extra = map[string]string{
+	"525400": "QEMU", // locally administered, used by QEMU/KVM
+}
+
+// skipped are registry entries that don't name a single vendor.
+var skipped = map[string]bool{
+	"ieee registration authority": true,
+	"private":                     true,
+}
+
+// suffixes are trailing words dropped from organization names.
+var suffixes = map[string]bool{
+	"ab": true, "ag": true, "b.v": true, "bv": true, "co": true, "communications": true,
+	"company": true, "computer": true, "corp": true, "corporate": true, "corporation": true,
+	"electronics": true, "gmbh": true, "inc": true, "incorporated": true, "international": true,
+	"kg": true, "limited": true, "llc": true, "ltd": true, "networks": true, "oy": true,
+	"plc": true, "pty": true, "s.a": true, "sa": true, "semiconductor": true, "srl": true,
+	"systems": true, "technologies": true, "technology": true,
+}
+
+var parenthesized = regexp.MustCompile(`\s*\([^)]*\)`)
+
+func main() {
+	in := flag.String("in", "", "read the registry from this oui.csv instead of downloading it")
+	out := flag.String("out", "oui.txt", "database to write")
+	flag.Parse()
+
+	if err := run(*in, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string) error {
+	var src io.ReadCloser
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		src = f
+	} else {
+		client := &http.Client{Timeout: 2 * time.Minute}
+		resp, err := client.Get(registryURL)
+		if err != nil {
+			return fmt.Errorf("failed to download the registry: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("failed to download the registry: %s", resp.Status)
+		}
+		src = resp.Body
+	}
+	defer src.Close()
+
+	var b strings.Builder
+	if err := convert(src, &b); err != nil {
+		return err
+	}
+	return os.WriteFile(out, []byte(b.String()), 0644)
+}
+
+// convert reads the registry CSV from r and writes the database to w,
+// sorted by prefix.
+func convert(r io.Reader, w io.Writer) error {
+	vendors := make(map[string]string)
+	for prefix, vendor := range extra {
+		vendors[prefix] = vendor
+	}
+
+	rows := csv.NewReader(r)
+	rows.FieldsPerRecord = -1
+	for first := true; ; first = false {
+		row, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse the registry: %w", err)
+		}
+		if first || len(row) < 3 || row[0] != "MA-L" {
+			continue // header, or not a 24-bit assignment
+		}
+		prefix := strings.ToUpper(strings.TrimSpace(row[1]))
+		vendor := shorten(row[2])
+		if len(prefix) != 6 || vendor == "" || skipped[strings.ToLower(vendor)] {
+			continue
+		}
+		vendors[prefix] = vendor
+	}
+	if len(vendors) == len(extra) {
+		return errors.New("the registry has no MA-L assignments")
+	}
+
+	prefixes := make([]string, 0, len(vendors))
+	for prefix := range vendors {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		if _, err := fmt.Fprintf(w, "%s %s\n", prefix, vendors[prefix]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shorten turns a registry organization name into the name people know the
+// vendor by: everything after the first comma, parenthesized parts and
+// legal or generic trailing words go, and shouted names are title-cased.
+func shorten(org string) string {
+	name, _, _ := strings.Cut(org, ",")
+	name = parenthesized.ReplaceAllString(name, "")
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	for len(words) > 1 && suffixes[strings.ToLower(strings.TrimRight(words[len(words)-1], "."))] {
+		words = words[:len(words)-1]
+	}
+	for i, word := range words {
+		words[i] = titleCase(strings.TrimRight(word, ".,"))
+	}
+	name = strings.Join(words, " ")
+	if renamed, ok := names[strings.ToLower(name)]; ok {
+		return renamed
+	}
+	return name
+}
+
+// titleCase lowercases all but the first letter of an all-caps word longer
+// than four letters ("NETGEAR" -> "Netgear"); acronyms such as "AVM" stay.
+func titleCase(word string) string {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return word
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters <= 4 {
+		return word
+	}
+	lower := []rune(strings.ToLower(word))
+	lower[0] = unicode.ToUpper(lower[0])
+	return string(lower)
+}
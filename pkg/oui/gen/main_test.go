@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registry is an excerpt of oui.csv in the registry's own format.
+const registry = `Registry,Assignment,Organization Name,Organization Address
+MA-L,50C7BF,"TP-LINK TECHNOLOGIES CO.,LTD.","Building 24 (floors 1,3,4,5) and 28 (floors1-4) Central Science and Technology Park Shennan Road, Nanshan Shenzhen Guangdong CN 518057 "
+MA-L,002119,"Samsung Electronics Co.,Ltd","416, Maetan-3dong, Yeongtong-gu Suwon Gyeonggi-do KR 443742 "
+MA-L,001C4A,AVM GmbH,Alt-Moabit 95 Berlin  DE 10559
+MA-L,001A92,ASUSTek COMPUTER INC.,"No.5 Shin Min Street, Chu Nan Chen, Miao-Li Taiwan TW 350 "
+MA-L,000B86,"Aruba, a Hewlett Packard Enterprise Company",1344 Crossman Ave Sunnyvale CA US 94089
+MA-L,286C07,"XIAOMI Electronics,CO.,LTD","Xiaomi Building, No.68 Qinghe Middle Street Beijing Haidian District CN 100085 "
+MA-L,24A43C,Ubiquiti Networks Inc.,91 E. Tasman Dr. San Jose CA US 95134
+MA-L,A4C138,Telink Semiconductor (Taipei) Co. Ltd.,"9F, No. 150, Jiankang Road, Zhonghe Dist. New Taipei City  TW 23585 "
+MA-L,0050C2,IEEE Registration Authority,445 Hoes Lane Piscataway NJ US 08554
+MA-L,ACBC32,"Apple, Inc.",1 Infinite Loop Cupertino CA US 95014
+MA-L,001B21,Intel Corporate,Lot 8 Jalan Hi-Tech 2/3  Kulim Kedah MY 09000
+MA-L,B827EB,Raspberry Pi Foundation,Mitchell Wood House Caldecote Cambridgeshire GB CB23 7NU
+MA-L,080027,PCS Systemtechnik GmbH,Pfälzer-Wald-Straße 36 München  DE 81539
+MA-M,70B3D5000,"Example Devices, Inc.",1 Example Road Springfield US 00000
+`
+
+func TestConvert(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, convert(strings.NewReader(registry), &out))
+
+	assert.True(t, strings.HasPrefix(out.String(), "# Code generated"), "marked as generated")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var entries []string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	assert.Equal(t, []string{
+		"000B86 Aruba",
+		"001A92 ASUS",
+		"001B21 Intel",
+		"001C4A AVM",
+		"002119 Samsung",
+		"080027 VirtualBox",
+		"24A43C Ubiquiti",
+		"286C07 Xiaomi",
+		"50C7BF TP-Link",
+		"525400 QEMU",
+		"A4C138 Telink",
+		"ACBC32 Apple",
+		"B827EB Raspberry Pi",
+	}, entries, "sorted, names shortened, blocks subdivided by the IEEE and MA-M rows left out")
+}
+
+func TestConvert_EmptyRegistry(t *testing.T) {
+	var out strings.Builder
+	assert.Error(t, convert(strings.NewReader("Registry,Assignment,Organization Name,Organization Address\n"), &out))
+}
+
+func TestShorten(t *testing.T) {
+	tests := []struct {
+		org  string
+		want string
+	}{
+		{"Apple, Inc.", "Apple"},
+		{"Cisco Systems, Inc", "Cisco"},
+		{"Huawei Technologies Co.,Ltd", "Huawei"},
+		{"Realtek Semiconductor Corp.", "Realtek"},
+		{"NETGEAR", "Netgear"},
+		{"Raspberry Pi Trading Ltd", "Raspberry Pi"},
+		{"Xensource, Inc.", "Xen"},
+		{"HUAWEI TECHNOLOGIES CO.,LTD", "Huawei"},
+		{"LG Electronics", "LG"},
+		{"Inc.", "Inc"}, // never shortened to nothing
+	}
+	for _, tt := range tests {
+		t.Run(tt.org, func(t *testing.T) {
+			assert.Equal(t, tt.want, shorten(tt.org))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package oui maps MAC address prefixes (Organizationally Unique
+// Identifiers) to vendors, using a compact database embedded in the binary.
+package oui
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run ./gen
+
+//go:embed oui.txt
+var database string
+
+var (
+	loadOnce sync.Once
+	vendors  map[string]string   // "ACBC32" -> "Apple"
+	prefixes map[string][]string // "apple" -> ["000393", ...]
+	names    []string            // vendor names, sorted
+)
+
+// load parses the embedded database on first use.
+func load() {
+	loadOnce.Do(func() {
+		vendors = make(map[string]string)
+		prefixes = make(map[string][]string)
+		scanner := bufio.NewScanner(strings.NewReader(database))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			prefix, vendor, ok := strings.Cut(line, " ")
+			if !ok || len(prefix) != 6 {
+				continue
+			}
+			prefix, vendor = strings.ToUpper(prefix), strings.TrimSpace(vendor)
+			key := strings.ToLower(vendor)
+			if _, seen := prefixes[key]; !seen {
+				names = append(names, vendor)
+			}
+			vendors[prefix] = vendor
+			prefixes[key] = append(prefixes[key], prefix)
+		}
+		sort.Strings(names)
+	})
+}
+
+// normalize returns the first three octets of mac as six uppercase hex
+// digits, accepting ':', '-' and '.' separators or none. ok is false when
+// mac doesn't start with three octets.
+func normalize(mac string) (string, bool) {
+	var digits strings.Builder
+	for _, r := range mac {
+		switch {
+		case r == ':' || r == '-' || r == '.':
+			continue
+		case strings.ContainsRune("0123456789abcdefABCDEF", r):
+			digits.WriteRune(r)
+		default:
+			return "", false
+		}
+		if digits.Len() == 6 {
+			return strings.ToUpper(digits.String()), true
+		}
+	}
+	return "", false
+}
+
+// Lookup returns the vendor of mac (a full address or just its prefix),
+// or "" when unknown. Randomized addresses are locally administered and
+// have no vendor.
+func Lookup(mac string) string {
+	prefix, ok := normalize(mac)
+	if !ok {
+		return ""
+	}
+	load()
+	return vendors[prefix]
+}
+
+// IsLocal reports whether mac is locally administered, as randomized and
+// most virtual machine addresses are, rather than assigned to a vendor.
+func IsLocal(mac string) bool {
+	prefix, ok := normalize(mac)
+	return ok && hexByte(prefix[:2])&0x02 != 0
+}
+
+// Vendors returns the names of the vendors in the database, sorted.
+func Vendors() []string {
+	load()
+	return append([]string(nil), names...)
+}
+
+// Prefixes returns vendor's prefixes as six uppercase hex digits. Matching
+// is case-insensitive; nil for an unknown vendor.
+func Prefixes(vendor string) []string {
+	load()
+	return append([]string(nil), prefixes[strings.ToLower(strings.TrimSpace(vendor))]...)
+}
+
+// RandomMAC returns an address with a random prefix of vendor and a random
+// device part. Vendor prefixes are universally administered, so the
+// locally-administered bit stays clear: that is what makes the address look
+// like real hardware of that vendor. Prefixes that aren't usable unicast
+// universal assignments (such as QEMU's 52:54:00) are never picked.
+func RandomMAC(vendor string) (string, error) {
+	var usable []string
+	for _, p := range Prefixes(vendor) {
+		if first := hexByte(p[:2]); first&0x03 == 0 {
+			usable = append(usable, p)
+		}
+	}
+	if len(usable) == 0 {
+		if similar := similarVendors(vendor); len(similar) > 0 {
+			return "", fmt.Errorf("unknown vendor %q (did you mean: %s)", vendor, strings.Join(similar, ", "))
+		}
+		return "", fmt.Errorf("unknown vendor %q", vendor)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(usable))))
+	if err != nil {
+		return "", fmt.Errorf("failed to pick a prefix: %w", err)
+	}
+	prefix := usable[n.Int64()]
+	device := make([]byte, 3)
+	if _, err := rand.Read(device); err != nil {
+		return "", fmt.Errorf("failed to generate MAC: %w", err)
+	}
+	return fmt.Sprintf("%s:%s:%s:%02x:%02x:%02x", strings.ToLower(prefix[0:2]), strings.ToLower(prefix[2:4]),
+		strings.ToLower(prefix[4:6]), device[0], device[1], device[2]), nil
+}
+
+// maxSimilar caps the suggestions in RandomMAC's error; the registry has
+// thousands of vendors.
+const maxSimilar = 10
+
+// similarVendors returns up to maxSimilar vendors RandomMAC can generate
+// addresses for whose name contains vendor, case-insensitively.
+func similarVendors(vendor string) []string {
+	needle := strings.ToLower(strings.TrimSpace(vendor))
+	if needle == "" {
+		return nil
+	}
+	var out []string
+	for _, name := range Vendors() {
+		if len(out) == maxSimilar {
+			break
+		}
+		if !strings.Contains(strings.ToLower(name), needle) {
+			continue
+		}
+		for _, p := range Prefixes(name) {
+			if hexByte(p[:2])&0x03 == 0 {
+				out = append(out, name)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// hexByte parses two hex digits; the database is trusted to contain them.
+func hexByte(s string) byte {
+	b, _ := strconv.ParseUint(s, 16, 8)
+	return byte(b)
+}
@@ -0,0 +1,71 @@
+package oui
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"ac:bc:32:01:02:03", "Apple"},
+		{"AC-BC-32-01-02-03", "Apple"},
+		{"acbc.3201.0203", "Apple"},
+		{"00:1b:21", "Intel"},
+		{"b8:27:eb:aa:bb:cc", "Raspberry Pi"},
+		{"02:00:00:00:00:01", ""}, // locally administered
+		{"00:1b", ""},
+		{"not a mac", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mac, func(t *testing.T) {
+			assert.Equal(t, tt.want, Lookup(tt.mac))
+		})
+	}
+}
+
+func TestIsLocal(t *testing.T) {
+	assert.True(t, IsLocal("02:00:00:00:00:01"))
+	assert.True(t, IsLocal("52:54:00:12:34:56"))
+	assert.False(t, IsLocal("ac:bc:32:01:02:03"))
+	assert.False(t, IsLocal("garbage"))
+}
+
+func TestRandomMAC(t *testing.T) {
+	for _, vendor := range []string{"Intel", "apple", "Raspberry Pi"} {
+		t.Run(vendor, func(t *testing.T) {
+			mac, err := RandomMAC(vendor)
+			require.NoError(t, err)
+			assert.Regexp(t, `^[0-9a-f]{2}(:[0-9a-f]{2}){5}$`, mac)
+			assert.True(t, strings.EqualFold(Lookup(mac), vendor), "%s is a %s address", mac, vendor)
+
+			first, err := strconv.ParseUint(mac[:2], 16, 8)
+			require.NoError(t, err)
+			assert.Zero(t, first&0x03, "vendor addresses are universally administered unicast")
+		})
+	}
+
+	t.Run("unknown vendor", func(t *testing.T) {
+		_, err := RandomMAC("Acme")
+		require.Error(t, err)
+		assert.Equal(t, `unknown vendor "Acme"`, err.Error())
+	})
+
+	t.Run("unknown vendor suggests similar names", func(t *testing.T) {
+		_, err := RandomMAC("raspberry")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "did you mean: Raspberry Pi")
+	})
+
+	t.Run("locally administered prefixes are never used", func(t *testing.T) {
+		_, err := RandomMAC("QEMU")
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "did you mean")
+	})
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package secrets
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// fromKeyring reads a "user" key from the kernel keyring, natively via the
+// request_key/keyctl syscalls (replacing `keyctl request user <desc>` +
+// `keyctl pipe`). The search covers the calling process's thread, process and
+// session keyrings — under sudo these are root's, so store the key with
+// `sudo keyctl add user <desc> <secret> @u`.
+func fromKeyring(description string) (string, error) {
+	id, err := unix.RequestKey("user", description, "", 0)
+	if err != nil {
+		return "", fmt.Errorf("key %q not found in kernel keyring: %w", description, err)
+	}
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return "", fmt.Errorf("reading key %q: %w", description, err)
+	}
+	buf := make([]byte, size)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return "", fmt.Errorf("reading key %q: %w", description, err)
+	}
+	if n > len(buf) {
+		n = len(buf)
+	}
+	return trimNewlines(string(buf[:n])), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package secrets
+
+// fromKeyring is unsupported on non-Linux platforms (the kernel keyring is a
+// Linux facility). netop is a Linux tool; this stub exists only so the package
+// cross-compiles for the darwin CI build.
+func fromKeyring(description string) (string, error) {
+	return "", ErrUnsupported
+}
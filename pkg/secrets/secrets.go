@@ -0,0 +1,292 @@
+// Package secrets resolves credential references in config values, so PSKs,
+// VPN auth/setup keys and WireGuard private keys don't have to sit in plain
+// YAML. A reference is a single-line string with a backend prefix:
+//
+//	secret:<name>     entry <name> in secrets.yaml next to the config file
+//	                  (the YAML form `psk: !secret <name>` is rewritten to this)
+//	cmd:<command>     stdout of a shell command, e.g. "cmd:pass show wifi/home"
+//	file:<path>       contents of a file
+//	env:<VAR>         value of an environment variable
+//	creds:<name>      a systemd credential (LoadCredential=/credstore)
+//	keyring:<desc>    a "user" key from the kernel keyring
+//
+// Any other value is a literal and is returned unchanged. Resolved values are
+// never logged; commands are run directly via os/exec rather than through
+// types.SystemExecutor because the executor logs command output in debug mode.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backend prefixes recognised by IsReference and Resolve.
+const (
+	PrefixSecret  = "secret:"
+	PrefixCmd     = "cmd:"
+	PrefixFile    = "file:"
+	PrefixEnv     = "env:"
+	PrefixCreds   = "creds:"
+	PrefixKeyring = "keyring:"
+)
+
+// prefixes lists every backend prefix, used for detection and validation.
+var prefixes = []string{PrefixSecret, PrefixCmd, PrefixFile, PrefixEnv, PrefixCreds, PrefixKeyring}
+
+// SecretTag is the YAML tag accepted as shorthand for a secret: reference
+// (`psk: !secret home-wifi`).
+const SecretTag = "!secret"
+
+// ErrUnsupported is returned by backends that are unavailable on this platform.
+var ErrUnsupported = errors.New("secret backend not supported on this platform")
+
+// defaultCmdTimeout bounds cmd: references so a hung password manager (e.g.
+// waiting on a pinentry that will never appear) can't stall a connect forever.
+const defaultCmdTimeout = 30 * time.Second
+
+// credstoreDirs are the systemd credential store locations searched for
+// creds: references when $CREDENTIALS_DIRECTORY doesn't provide the name.
+var credstoreDirs = []string{"/etc/credstore.encrypted", "/etc/credstore", "/run/credstore.encrypted", "/run/credstore"}
+
+// IsReference reports whether value is a secret reference rather than a
+// literal. Multi-line values (inline WireGuard/OpenVPN configs) are never
+// references.
+func IsReference(value string) bool {
+	if strings.ContainsAny(value, "\r\n") {
+		return false
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(value, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the syntax of a reference without resolving it. Literals
+// are always valid.
+func Validate(value string) error {
+	if !IsReference(value) {
+		return nil
+	}
+	prefix, target := split(value)
+	if strings.TrimSpace(target) == "" {
+		return fmt.Errorf("secret reference %q has an empty %s target", value, strings.TrimSuffix(prefix, ":"))
+	}
+	return nil
+}
+
+// split separates a reference into its prefix and target.
+func split(value string) (prefix, target string) {
+	for _, p := range prefixes {
+		if strings.HasPrefix(value, p) {
+			return p, strings.TrimPrefix(value, p)
+		}
+	}
+	return "", value
+}
+
+// Resolver resolves secret references. The zero value is usable; SecretsFile
+// must be set for secret: references to resolve.
+type Resolver struct {
+	// SecretsFile is the YAML file (name: value) backing secret: references.
+	SecretsFile string
+	// CmdTimeout bounds cmd: and systemd-creds invocations. Zero means 30s.
+	CmdTimeout time.Duration
+
+	// runCommand executes argv and returns stdout. Overridable in tests.
+	runCommand func(ctx context.Context, argv []string) ([]byte, error)
+}
+
+// NewResolver creates a resolver whose secret: references are looked up in
+// secretsFile.
+func NewResolver(secretsFile string) *Resolver {
+	return &Resolver{SecretsFile: secretsFile}
+}
+
+// Resolve returns the secret value for a reference, or value unchanged if it
+// is a literal. Errors name the reference but never include secret material.
+func (r *Resolver) Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	if err := Validate(value); err != nil {
+		return "", err
+	}
+	prefix, target := split(value)
+	target = strings.TrimSpace(target)
+
+	var (
+		secret string
+		err    error
+	)
+	switch prefix {
+	case PrefixSecret:
+		secret, err = r.fromSecretsFile(target)
+	case PrefixCmd:
+		secret, err = r.fromCommand(target)
+	case PrefixFile:
+		secret, err = fromFile(target)
+	case PrefixEnv:
+		secret, err = fromEnv(target)
+	case PrefixCreds:
+		secret, err = r.fromCreds(target)
+	case PrefixKeyring:
+		secret, err = fromKeyring(target)
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", value, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("resolving %s: secret is empty", value)
+	}
+	return secret, nil
+}
+
+func (r *Resolver) cmdTimeout() time.Duration {
+	if r.CmdTimeout > 0 {
+		return r.CmdTimeout
+	}
+	return defaultCmdTimeout
+}
+
+// fromSecretsFile looks name up in the flat name: value secrets file.
+func (r *Resolver) fromSecretsFile(name string) (string, error) {
+	if r.SecretsFile == "" {
+		return "", fmt.Errorf("no secrets file configured")
+	}
+	data, err := os.ReadFile(r.SecretsFile)
+	if err != nil {
+		return "", fmt.Errorf("reading secrets file: %w", err)
+	}
+	var entries map[string]string
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return "", fmt.Errorf("parsing secrets file %s: %w", r.SecretsFile, err)
+	}
+	value, ok := entries[name]
+	if !ok {
+		return "", fmt.Errorf("%q not found in %s", name, r.SecretsFile)
+	}
+	return value, nil
+}
+
+// fromCommand runs command through the shell and returns its stdout with
+// trailing newlines removed. When net was elevated via sudo, the command runs
+// as the invoking user so password managers find that user's store and agent.
+func (r *Resolver) fromCommand(command string) (string, error) {
+	argv := []string{"sh", "-c", command}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != "root" && os.Geteuid() == 0 {
+		argv = append([]string{"sudo", "-u", sudoUser, "-H", "--"}, argv...)
+	}
+	out, err := r.run(argv)
+	if err != nil {
+		return "", err
+	}
+	return trimNewlines(string(out)), nil
+}
+
+// fromCreds reads a systemd credential: first from $CREDENTIALS_DIRECTORY
+// (set for services using LoadCredential=), then from the system credential
+// stores, decrypting *.encrypted stores with systemd-creds.
+func (r *Resolver) fromCreds(name string) (string, error) {
+	if strings.ContainsRune(name, '/') {
+		return "", fmt.Errorf("credential name must not contain '/'")
+	}
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			return trimNewlines(string(data)), nil
+		}
+	}
+	for _, dir := range credstoreDirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if !strings.HasSuffix(dir, ".encrypted") {
+			return fromFile(path)
+		}
+		out, err := r.run([]string{"systemd-creds", "decrypt", "--name=" + name, path, "-"})
+		if err != nil {
+			return "", err
+		}
+		return trimNewlines(string(out)), nil
+	}
+	return "", fmt.Errorf("credential %q not found in $CREDENTIALS_DIRECTORY or %s", name, strings.Join(credstoreDirs, ", "))
+}
+
+// run executes argv with the resolver's timeout, returning stdout. stderr is
+// only surfaced as a short diagnostic in the error.
+func (r *Resolver) run(argv []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cmdTimeout())
+	defer cancel()
+	if r.runCommand != nil {
+		return r.runCommand(ctx, argv)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("command timed out after %s", r.cmdTimeout())
+		}
+		if msg := firstLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("command failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("command failed: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// fromFile reads a secret from path (with ~ expansion), trimming trailing
+// newlines so `echo secret > file` works as expected.
+func fromFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return trimNewlines(string(data)), nil
+}
+
+func fromEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func trimNewlines(s string) string {
+	return strings.TrimRight(s, "\r\n")
+}
+
+// firstLine returns the first non-empty line of s, truncated for error messages.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if len(line) > 200 {
+			line = line[:200] + "..."
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReference(t *testing.T) {
+	for _, v := range []string{"secret:home", "cmd:pass show wifi", "file:/etc/key", "env:PSK", "creds:wifi", "keyring:wifi"} {
+		assert.True(t, IsReference(v), v)
+	}
+	for _, v := range []string{"", "hunter2", "Secret:home", "[Interface]\nPrivateKey = cmd:x", "cmd:echo\nhi"} {
+		assert.False(t, IsReference(v), v)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("plain password"))
+	assert.NoError(t, Validate("env:HOME_PSK"))
+	assert.Error(t, Validate("cmd:"))
+	assert.Error(t, Validate("file:   "))
+}
+
+func TestResolve_Literal(t *testing.T) {
+	value, err := (&Resolver{}).Resolve("not-a-reference")
+	require.NoError(t, err)
+	assert.Equal(t, "not-a-reference", value)
+}
+
+func TestResolve_SecretsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("home: hunter2\nempty: \"\"\n"), 0600))
+	r := NewResolver(path)
+
+	value, err := r.Resolve("secret:home")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	_, err = r.Resolve("secret:missing")
+	assert.ErrorContains(t, err, `"missing" not found`)
+
+	_, err = r.Resolve("secret:empty")
+	assert.ErrorContains(t, err, "secret is empty")
+
+	_, err = (&Resolver{}).Resolve("secret:home")
+	assert.ErrorContains(t, err, "no secrets file configured")
+}
+
+func TestResolve_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "psk")
+	require.NoError(t, os.WriteFile(path, []byte("hunter2\n"), 0600))
+
+	value, err := (&Resolver{}).Resolve("file:" + path)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+}
+
+func TestResolve_Env(t *testing.T) {
+	t.Setenv("NET_SECRETS_TEST", "from-env")
+
+	value, err := (&Resolver{}).Resolve("env:NET_SECRETS_TEST")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	_, err = (&Resolver{}).Resolve("env:NET_SECRETS_TEST_UNSET")
+	assert.ErrorContains(t, err, "is not set")
+}
+
+func TestResolve_Command(t *testing.T) {
+	var gotArgv []string
+	r := &Resolver{runCommand: func(ctx context.Context, argv []string) ([]byte, error) {
+		gotArgv = argv
+		return []byte("hunter2\n"), nil
+	}}
+
+	value, err := r.Resolve("cmd:pass show wifi/home")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+	require.NotEmpty(t, gotArgv)
+	assert.Equal(t, []string{"sh", "-c", "pass show wifi/home"}, gotArgv[len(gotArgv)-3:])
+}
+
+func TestResolve_CommandFailureOmitsOutput(t *testing.T) {
+	r := &Resolver{runCommand: func(ctx context.Context, argv []string) ([]byte, error) {
+		return []byte("partial-secret"), errors.New("exit status 1")
+	}}
+
+	_, err := r.Resolve("cmd:false")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "partial-secret")
+	assert.Contains(t, err.Error(), "cmd:false")
+}
+
+func TestResolve_Creds(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wifi"), []byte("hunter2"), 0600))
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	value, err := (&Resolver{}).Resolve("creds:wifi")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	_, err = (&Resolver{}).Resolve("creds:../wifi")
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+// Package share holds what the hotspot and the DHCP server have in common
+// when they share an uplink with their clients: resolving the uplink to an
+// interface, and with uplink: vpn, routing the clients through the VPN
+// tunnel and following the VPN to whatever tunnel it connects next.
+package share
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// recordDir is the directory, under the VPN state directory, that records
+// the interfaces shared through the VPN: one file per interface, holding the
+// tunnel its clients are routed through.
+const recordDir = "vpn-shares"
+
+// Uplink resolves uplink to the interface to share out of: tunnel (the
+// active VPN's interface) for vpn, the named interface, or for auto the
+// interface auto returns, the default route's ("" when there is none).
+func Uplink(uplink, tunnel string, auto func() string) (string, error) {
+	switch uplink {
+	case types.UplinkVPN:
+		if tunnel == "" {
+			return "", fmt.Errorf("uplink is vpn, but no VPN is connected")
+		}
+		return tunnel, nil
+	case "", types.UplinkAuto:
+		return auto(), nil
+	}
+	return uplink, nil
+}
+
+// DNSForwarders returns the dnsmasq server= lines forwarding client queries
+// to servers. dnsmasq's queries are host traffic, which the clients' policy
+// rule doesn't route: with uplink vpn each server is bound to tunnel
+// (server=<dns>@<tunnel>), and without a tunnel there is nowhere to forward
+// to that doesn't leak.
+func DNSForwarders(uplink, tunnel string, servers []string) ([]string, error) {
+	var lines []string
+	for _, dns := range servers {
+		switch {
+		case uplink != types.UplinkVPN:
+			lines = append(lines, "server="+dns)
+		case tunnel == "":
+			return nil, fmt.Errorf("uplink is vpn, but no VPN is connected")
+		default:
+			lines = append(lines, "server="+dns+"@"+tunnel)
+		}
+	}
+	return lines, nil
+}
+
+// RouteThroughVPN restricts forwarding from internalIface to tunnel and
+// policy-routes its traffic into the VPN routing table, whose unreachable
+// backstop keeps clients offline rather than leaking while the tunnel is
+// down. The share is recorded in stateDir, so Retarget can move it to the
+// next tunnel.
+func RouteThroughVPN(fw types.FirewallManager, routes types.RouteManager, stateDir, internalIface, tunnel string) error {
+	// Restrict first: until then, forwarded traffic could take the default
+	// route out of the physical uplink.
+	if err := fw.RestrictForward(internalIface, tunnel); err != nil {
+		return fmt.Errorf("failed to restrict forwarding to %s: %w", tunnel, err)
+	}
+	if err := routes.SetTableDefault(types.VPNRouteTable, tunnel); err != nil {
+		return fmt.Errorf("failed to route through %s: %w", tunnel, err)
+	}
+	if err := routes.AddPolicyRule(internalIface, types.VPNRouteTable); err != nil {
+		return fmt.Errorf("failed to route through %s: %w", tunnel, err)
+	}
+	if err := writeRecord(stateDir, internalIface, tunnel); err != nil {
+		return fmt.Errorf("failed to record the VPN share of %s: %w", internalIface, err)
+	}
+	return nil
+}
+
+// Unroute undoes RouteThroughVPN for internalIface, including the NAT rule
+// of a tunnel Retarget moved it to. fw is nil when the firewall can't be
+// reached; the routing rule and record are removed anyway. The VPN routing
+// table stays: it is shared, and unused without the rule.
+func Unroute(fw types.FirewallManager, routes types.RouteManager, stateDir, internalIface string) error {
+	var errs []error
+	if fw != nil {
+		if tunnel := readRecord(stateDir, internalIface); tunnel != "" {
+			if err := fw.DisableNAT("", tunnel); err != nil {
+				errs = append(errs, fmt.Errorf("removing NAT through %s: %w", tunnel, err))
+			}
+		}
+		if err := fw.UnrestrictForward(internalIface); err != nil {
+			errs = append(errs, fmt.Errorf("removing forwarding restriction: %w", err))
+		}
+	}
+	if err := routes.DelPolicyRule(internalIface, types.VPNRouteTable); err != nil {
+		errs = append(errs, fmt.Errorf("removing VPN routing rule: %w", err))
+	}
+	if err := os.Remove(recordPath(stateDir, internalIface)); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Retarget moves every interface shared through the VPN to tunnel after a
+// VPN (re)connects: the VPN routing table's default route, and each share's
+// forward restriction and NAT. With nothing shared it does nothing, leaving
+// the routing table alone. fw is only called when a share has to move.
+//
+// dnsmasq keeps forwarding client DNS queries through the tunnel it was
+// started with (server=<dns>@<tunnel>); those clients use the DHCP-provided
+// servers directly, which are forwarded through tunnel like any traffic.
+func Retarget(fw func() (types.FirewallManager, error), routes types.RouteManager, stateDir, tunnel string) error {
+	shares := records(stateDir)
+	if len(shares) == 0 {
+		return nil
+	}
+	if err := routes.SetTableDefault(types.VPNRouteTable, tunnel); err != nil {
+		return fmt.Errorf("failed to route through %s: %w", tunnel, err)
+	}
+
+	var errs []error
+	for iface, previous := range shares {
+		if previous == tunnel {
+			continue
+		}
+		f, err := fw()
+		if err != nil {
+			return err
+		}
+		// Restrict to the new tunnel before NAT moves to it; the old
+		// tunnel's MASQUERADE goes last (an empty internal interface
+		// removes only that rule).
+		if err := f.RestrictForward(iface, tunnel); err != nil {
+			errs = append(errs, fmt.Errorf("restricting %s to %s: %w", iface, tunnel, err))
+			continue
+		}
+		if err := f.EnableNAT(iface, tunnel); err != nil {
+			errs = append(errs, fmt.Errorf("sharing %s through %s: %w", iface, tunnel, err))
+			continue
+		}
+		if err := f.DisableNAT("", previous); err != nil {
+			errs = append(errs, fmt.Errorf("removing NAT through %s: %w", previous, err))
+		}
+		if err := writeRecord(stateDir, iface, tunnel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordPath returns the record file of internalIface.
+func recordPath(stateDir, internalIface string) string {
+	return filepath.Join(stateDir, recordDir, internalIface)
+}
+
+// writeRecord records that internalIface is shared through tunnel.
+func writeRecord(stateDir, internalIface, tunnel string) error {
+	if err := os.MkdirAll(filepath.Join(stateDir, recordDir), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(recordPath(stateDir, internalIface), []byte(tunnel+"\n"), 0600)
+}
+
+// readRecord returns the tunnel internalIface is shared through, or "".
+func readRecord(stateDir, internalIface string) string {
+	data, err := os.ReadFile(recordPath(stateDir, internalIface))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// records returns the recorded shares, keyed by interface.
+func records(stateDir string) map[string]string {
+	entries, err := os.ReadDir(filepath.Join(stateDir, recordDir))
+	if err != nil {
+		return nil
+	}
+	shares := make(map[string]string)
+	for _, e := range entries {
+		if tunnel := readRecord(stateDir, e.Name()); tunnel != "" {
+			shares[e.Name()] = tunnel
+		}
+	}
+	return shares
+}
@@ -0,0 +1,119 @@
+package share
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fwfake "github.com/angelfreak/net/pkg/firewall/fake"
+	"github.com/angelfreak/net/pkg/netlink/fake"
+	"github.com/angelfreak/net/pkg/types"
+)
+
+func TestUplink(t *testing.T) {
+	auto := func() string { return "eth0" }
+
+	for _, uplink := range []string{"", types.UplinkAuto} {
+		iface, err := Uplink(uplink, "wg0", auto)
+		assert.NoError(t, err)
+		assert.Equal(t, "eth0", iface)
+	}
+	iface, err := Uplink("usb0", "wg0", auto)
+	assert.NoError(t, err)
+	assert.Equal(t, "usb0", iface)
+	iface, err = Uplink(types.UplinkVPN, "wg0", auto)
+	assert.NoError(t, err)
+	assert.Equal(t, "wg0", iface)
+
+	_, err = Uplink(types.UplinkVPN, "", auto)
+	assert.EqualError(t, err, "uplink is vpn, but no VPN is connected")
+}
+
+func TestDNSForwarders(t *testing.T) {
+	servers := []string{"1.1.1.1", "9.9.9.9"}
+
+	lines, err := DNSForwarders(types.UplinkAuto, "wg0", servers)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"server=1.1.1.1", "server=9.9.9.9"}, lines)
+
+	// dnsmasq's own queries must not take the physical uplink
+	lines, err = DNSForwarders(types.UplinkVPN, "wg0", servers)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"server=1.1.1.1@wg0", "server=9.9.9.9@wg0"}, lines)
+
+	_, err = DNSForwarders(types.UplinkVPN, "", servers)
+	assert.EqualError(t, err, "uplink is vpn, but no VPN is connected")
+}
+
+func TestRouteThroughVPN(t *testing.T) {
+	dir := t.TempDir()
+	fw := &fwfake.Manager{}
+	routes := &fake.RouteManager{}
+
+	require.NoError(t, RouteThroughVPN(fw, routes, dir, "wlan0_ap", "wg0"))
+	assert.Equal(t, map[string]string{"wlan0_ap": "wg0"}, fw.Restricted)
+	assert.Equal(t, "wg0", routes.TableDefaults[types.VPNRouteTable])
+	assert.Equal(t, map[string]int{"wlan0_ap": types.VPNRouteTable}, routes.Rules)
+	assert.Equal(t, map[string]string{"wlan0_ap": "wg0"}, records(dir))
+
+	require.NoError(t, Unroute(fw, routes, dir, "wlan0_ap"))
+	assert.Empty(t, fw.Restricted)
+	assert.Empty(t, routes.Rules)
+	assert.Equal(t, []fwfake.NATCall{{Out: "wg0"}}, fw.Disabled)
+	assert.Empty(t, records(dir))
+
+	// Without the firewall the rule and record still go
+	require.NoError(t, RouteThroughVPN(fw, routes, dir, "eth1", "wg0"))
+	require.NoError(t, Unroute(nil, routes, dir, "eth1"))
+	assert.Empty(t, routes.Rules)
+	assert.Empty(t, records(dir))
+
+	routes.RuleErr = fmt.Errorf("operation not permitted")
+	err := RouteThroughVPN(fw, routes, dir, "eth1", "wg0")
+	assert.EqualError(t, err, "failed to route through wg0: operation not permitted")
+	assert.Empty(t, records(dir))
+}
+
+func TestRetarget(t *testing.T) {
+	dir := t.TempDir()
+	fw := &fwfake.Manager{}
+	routes := &fake.RouteManager{}
+	firewall := func() (types.FirewallManager, error) { return fw, nil }
+
+	// Nothing shared: the VPN routing table is left alone
+	require.NoError(t, Retarget(firewall, routes, dir, "wg0"))
+	assert.Empty(t, routes.TableDefaults)
+
+	require.NoError(t, RouteThroughVPN(fw, routes, dir, "wlan0_ap", "wg0"))
+	require.NoError(t, fw.EnableNAT("wlan0_ap", "wg0"))
+	fw.Enabled = nil
+
+	// The same tunnel after a reconnect only needs the table's route back
+	routes.TableDefaults = nil
+	require.NoError(t, Retarget(firewall, routes, dir, "wg0"))
+	assert.Equal(t, "wg0", routes.TableDefaults[types.VPNRouteTable])
+	assert.Empty(t, fw.Enabled)
+
+	// Another VPN moves the share to its tunnel
+	require.NoError(t, Retarget(firewall, routes, dir, "tun0"))
+	assert.Equal(t, "tun0", routes.TableDefaults[types.VPNRouteTable])
+	assert.Equal(t, map[string]string{"wlan0_ap": "tun0"}, fw.Restricted)
+	assert.Equal(t, []fwfake.NATCall{{Internal: "wlan0_ap", Out: "tun0"}}, fw.Enabled)
+	assert.Equal(t, []fwfake.NATCall{{Out: "wg0"}}, fw.Disabled)
+	assert.Equal(t, map[string]string{"wlan0_ap": "tun0"}, records(dir))
+
+	// Stopping the share removes the NAT of the tunnel it was moved to
+	fw.Disabled = nil
+	require.NoError(t, Unroute(fw, routes, dir, "wlan0_ap"))
+	assert.Equal(t, []fwfake.NATCall{{Out: "tun0"}}, fw.Disabled)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, recordDir), 0700))
+	require.NoError(t, writeRecord(dir, "eth1", "wg0"))
+	fwErr := fmt.Errorf("iptables not found")
+	err := Retarget(func() (types.FirewallManager, error) { return nil, fwErr }, routes, dir, "tun0")
+	assert.ErrorIs(t, err, fwErr)
+}
@@ -0,0 +1,35 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ErrNoTerminal is returned by ReadPassword when stdin is not a terminal, so
+// callers can fall back to (or suggest) a non-interactive key source.
+var ErrNoTerminal = errors.New("stdin is not a terminal")
+
+// IsTerminal reports whether stdin is an interactive terminal.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ReadPassword prints prompt to stderr and reads a line from the terminal with
+// echo disabled. The prompt goes to stderr so it is visible even when stdout
+// is piped.
+func ReadPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", ErrNoTerminal
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
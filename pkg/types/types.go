@@ -11,12 +11,43 @@ import (
 // Using /run/net/ instead of /tmp/ to avoid symlink attacks
 const RuntimeDir = "/run/net"
 
+// StateDir holds state that must survive reboots, such as the secret behind
+// stable MAC addresses and MAC rotation schedules.
+const StateDir = "/var/lib/net"
+
+// VPNRouteTable is the routing table holding the active VPN tunnel's default
+// route. Shared clients with uplink: vpn are policy-routed into it, so their
+// traffic takes the tunnel regardless of the main table's default route.
+const VPNRouteTable = 51870
+
 // Config represents the main configuration structure
 type Config struct {
 	Common   CommonConfig             `yaml:"common" mapstructure:"common"`
 	Ignored  IgnoredConfig            `yaml:"ignored" mapstructure:"ignored"`
 	VPN      map[string]VPNConfig     `yaml:"vpn" mapstructure:"vpn"`
 	Networks map[string]NetworkConfig `yaml:",inline" mapstructure:",inline"`
+	// Hotspot holds the named hotspot profiles. The config manager decodes
+	// them itself, mapping channel: auto to AutoChannel.
+	Hotspot map[string]HotspotConfig `yaml:"hotspot,omitempty" mapstructure:"-"`
+
+	// Sources records the file that defined each network (keyed by its
+	// lowercased name), VPN ("vpn.<name>") and hotspot profile
+	// ("hotspot.<name>") when the config is assembled from includes and
+	// drop-in files. Not part of the YAML schema.
+	Sources map[string]string `yaml:"-" mapstructure:"-"`
+	// Aliases holds the network each alias ("work: home") resolves to,
+	// keyed by the alias. The config manager fills it at load; aliases whose
+	// target doesn't resolve are left out. Not part of the YAML schema.
+	Aliases map[string]NetworkConfig `yaml:"-" mapstructure:"-"`
+}
+
+// Network returns the network config named name, following an alias.
+func (c *Config) Network(name string) (NetworkConfig, bool) {
+	if network, ok := c.Networks[name]; ok {
+		return network, true
+	}
+	network, ok := c.Aliases[name]
+	return network, ok
 }
 
 // CommonConfig holds default settings applied to all connections
@@ -27,6 +58,53 @@ type CommonConfig struct {
 	VPN      string        `yaml:"vpn" mapstructure:"vpn"`
 	Timeouts TimeoutConfig `yaml:"timeouts" mapstructure:"timeouts"`
 	Portal   PortalConfig  `yaml:"portal" mapstructure:"portal"`
+	// Trust holds the policy applied to networks of each trust level
+	// ("trusted", "untrusted", "public"), keyed by level.
+	Trust map[string]TrustPolicy `yaml:"trust,omitempty" mapstructure:"trust"`
+	// HostnameMode is the default hostname_mode (see HostnameModes).
+	HostnameMode string `yaml:"hostname_mode,omitempty" mapstructure:"hostname_mode"`
+}
+
+// Hostname modes: how much of the machine's name a connection reveals.
+// Without a mode, the configured hostname is sent in DHCP requests and
+// nothing else changes.
+const (
+	// HostnameModeDHCPOnly sends the configured hostname in DHCP requests
+	// only, and keeps mDNS, LLMNR and NetBIOS from announcing the real one
+	// on the link.
+	HostnameModeDHCPOnly = "dhcp-only"
+	// HostnameModeSystem sets the kernel hostname to the configured one for
+	// the duration of the connection, so every service uses it.
+	HostnameModeSystem = "system"
+	// HostnameModeAnonymous sends no hostname at all (no DHCP option 12, as
+	// in the RFC 7844 anonymity profiles) and silences mDNS, LLMNR and
+	// NetBIOS on the link.
+	HostnameModeAnonymous = "anonymous"
+)
+
+// HostnameModes lists the valid hostname_mode values.
+var HostnameModes = []string{HostnameModeDHCPOnly, HostnameModeSystem, HostnameModeAnonymous}
+
+// Trust levels a network can be assigned with its trust: key.
+const (
+	TrustTrusted   = "trusted"
+	TrustUntrusted = "untrusted"
+	TrustPublic    = "public"
+)
+
+// TrustLevels lists the valid trust levels, most trusted first.
+var TrustLevels = []string{TrustTrusted, TrustUntrusted, TrustPublic}
+
+// TrustPolicy is what joining a network of a given trust level turns on.
+// VPN and MAC override the common settings for networks of that level;
+// a network's own vpn:/mac: still take precedence.
+type TrustPolicy struct {
+	VPN          string `yaml:"vpn" mapstructure:"vpn"`                     // VPN to bring up ("" with the key present: none)
+	KillSwitch   bool   `yaml:"kill_switch" mapstructure:"kill_switch"`     // Block traffic outside the VPN tunnel
+	Firewall     bool   `yaml:"firewall" mapstructure:"firewall"`           // Drop unsolicited inbound connections
+	MAC          string `yaml:"mac" mapstructure:"mac"`                     // MAC policy, e.g. "random", "stable" or "rotate:24h"
+	HideHostname bool   `yaml:"hide_hostname" mapstructure:"hide_hostname"` // hostname_mode: anonymous
+	EncryptedDNS bool   `yaml:"encrypted_dns" mapstructure:"encrypted_dns"` // Resolve via a local DNS-over-TLS stub
 }
 
 // TimeoutConfig holds configurable timeout values (in seconds)
@@ -115,6 +193,7 @@ type VPNConfig struct {
 	SetupKey      string `yaml:"setup_key" mapstructure:"setup_key"`           // NetBird setup key
 	ManagementURL string `yaml:"management_url" mapstructure:"management_url"` // NetBird management URL
 	Profile       string `yaml:"profile" mapstructure:"profile"`               // Tailscale/NetBird profile for account switching
+	PrivateKey    string `yaml:"private_key" mapstructure:"private_key"`       // WireGuard private key, overrides PrivateKey in Config
 }
 
 // NetworkConfig represents a network configuration
@@ -135,6 +214,110 @@ type NetworkConfig struct {
 	// built-in default (100 for wired, 600 for WiFi) so wired wins when both
 	// are up simultaneously. Matches NetworkManager's default convention.
 	Metric int `yaml:"metric" mapstructure:"metric"`
+	// Match identifies the site this profile belongs to from what is seen
+	// after link-up, for SSIDs shared between sites and for wired networks.
+	Match MatchConfig `yaml:"match,omitempty" mapstructure:"match"`
+	// Trust is the network's trust level (see TrustLevels); it selects the
+	// common.trust policy applied when connected. Empty applies none.
+	Trust string `yaml:"trust,omitempty" mapstructure:"trust"`
+	// HostnameMode overrides common.hostname_mode (see HostnameModes).
+	HostnameMode string `yaml:"hostname_mode,omitempty" mapstructure:"hostname_mode"`
+	// DHCPAnonymous makes the DHCP client send only the RFC 7844 anonymity
+	// profile's options (see DHCPClientOptions.Anonymous).
+	DHCPAnonymous bool `yaml:"dhcp_anonymous,omitempty" mapstructure:"dhcp_anonymous"`
+	// Roam picks the access point to join from the scan and lets `net roam`
+	// move to a better one. Nil leaves the choice to wpa_supplicant.
+	Roam *RoamConfig `yaml:"roam,omitempty" mapstructure:"roam"`
+	// Hidden marks a network that doesn't broadcast its SSID: it is joined
+	// by probing for it, without looking for it in the scan first.
+	Hidden bool `yaml:"hidden,omitempty" mapstructure:"hidden"`
+	// KeepDNS leaves the system's DNS (resolv.conf, encrypted DNS stub) to
+	// the connection of another interface. Set by net for a second radio's
+	// connection, never read from the config.
+	KeepDNS bool `yaml:"-" mapstructure:"-"`
+	// Name is the config name of the profile being connected. Set by net
+	// (wired profiles have no SSID to tell them apart), never read from the
+	// config.
+	Name string `yaml:"-" mapstructure:"-"`
+}
+
+// RoamConfig is a WiFi network's roam: policy. Which access point (BSS) of
+// the network to join: the strongest, or one on PreferBand when it is at most
+// BandMargin dB weaker. When to move: once the signal drops below Threshold
+// and another BSS is at least MinGain dB stronger.
+type RoamConfig struct {
+	PreferBand string `yaml:"prefer_band" mapstructure:"prefer_band"` // "5" (5 or 6 GHz) or "6"; empty for none
+	BandMargin *int   `yaml:"band_margin" mapstructure:"band_margin"` // dB (default: 10; 0 is kept)
+	Threshold  int    `yaml:"threshold" mapstructure:"threshold"`     // dBm (default: -70)
+	MinGain    int    `yaml:"min_gain" mapstructure:"min_gain"`       // dB (default: 8)
+	Interval   int    `yaml:"interval" mapstructure:"interval"`       // Seconds between signal checks (default: 10s)
+}
+
+// GetBandMargin returns the band preference margin with default fallback.
+// An explicit 0 prefers the band only when it is at least as strong.
+func (r *RoamConfig) GetBandMargin() int {
+	if r.BandMargin != nil && *r.BandMargin >= 0 {
+		return *r.BandMargin
+	}
+	return 10
+}
+
+// GetThreshold returns the roaming signal threshold with default fallback
+func (r *RoamConfig) GetThreshold() int {
+	if r.Threshold < 0 {
+		return r.Threshold
+	}
+	return -70
+}
+
+// GetMinGain returns the minimum signal gain for a roam with default fallback
+func (r *RoamConfig) GetMinGain() int {
+	if r.MinGain > 0 {
+		return r.MinGain
+	}
+	return 8
+}
+
+// GetInterval returns the signal check interval with default fallback
+func (r *RoamConfig) GetInterval() time.Duration {
+	if r.Interval > 0 {
+		return time.Duration(r.Interval) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// RoamEvent describes a move between two access points of a network.
+type RoamEvent struct {
+	SSID       string
+	From       string // BSSID
+	FromSignal int    // dBm
+	To         string // BSSID
+	ToSignal   int    // dBm
+}
+
+// MatchConfig holds the match: rules of a network profile. Each field lists
+// accepted values; the profile matches when any value of any field matches.
+type MatchConfig struct {
+	BSSID      []string `yaml:"bssid" mapstructure:"bssid"`             // BSSID prefixes, e.g. "00:11:22" or a full address
+	GatewayMAC []string `yaml:"gateway-mac" mapstructure:"gateway-mac"` // MAC address of the default gateway
+	Domain     []string `yaml:"domain" mapstructure:"domain"`           // DHCP-provided domain name (subdomains match too)
+	Switch     []string `yaml:"switch" mapstructure:"switch"`           // LLDP-advertised switch (system) name
+}
+
+// IsEmpty reports whether no match rules are configured.
+func (m MatchConfig) IsEmpty() bool {
+	return len(m.BSSID) == 0 && len(m.GatewayMAC) == 0 && len(m.Domain) == 0 && len(m.Switch) == 0
+}
+
+// Environment is what was observed on a link after it came up, compared
+// against match: rules. Empty fields were not observed.
+type Environment struct {
+	Interface  string
+	SSID       string
+	BSSID      string
+	GatewayMAC string
+	Domain     string
+	Switch     string
 }
 
 // DefaultRouteMetric returns the default-route metric for this network,
@@ -149,13 +332,69 @@ func (n *NetworkConfig) DefaultRouteMetric() int {
 	return 100
 }
 
-// WiFiNetwork represents a discovered WiFi network
+// WiFiNetwork represents a discovered WiFi network: one BSS (access point)
+// of a scan, with the details parsed from its information elements.
 type WiFiNetwork struct {
-	SSID      string
-	BSSID     string
-	Signal    int
+	SSID   string
+	BSSID  string
+	Signal int
+	// Security summarises the authentication: "Open", "OWE", "WEP", "WPA",
+	// "WPA2", "WPA3", "WPA2/WPA3", "WPA2-Enterprise" or "WPA3-Enterprise".
 	Security  string
 	Frequency int
+
+	// AKMs lists the advertised key management suites in wpa_supplicant's
+	// naming ("WPA-PSK", "WPA-PSK-SHA256", "SAE", "WPA-EAP", "OWE", ...).
+	AKMs []string
+	// Pairwise and Group are the advertised cipher suites ("CCMP", "TKIP",
+	// "GCMP-256", ...).
+	Pairwise    []string
+	Group       string
+	PMFRequired bool // management frame protection required (MFPR)
+	PMFCapable  bool // management frame protection supported (MFPC)
+
+	Channel    int
+	Width      int    // channel width in MHz (20, 40, 80, 160, 320)
+	Generation string // highest 802.11 amendment: "n", "ac", "ax", "be"; "" for legacy
+	WPS        bool   // Wi-Fi Protected Setup is advertised
+	Hidden     bool   // the SSID is not broadcast (SSID is empty)
+}
+
+// Band returns the frequency band of the network: "2.4", "5" or "6" (GHz),
+// or "" when the frequency is unknown.
+func (n WiFiNetwork) Band() string {
+	switch {
+	case n.Frequency >= 2400 && n.Frequency < 2500:
+		return "2.4"
+	case n.Frequency >= 5000 && n.Frequency < 5925:
+		return "5"
+	case n.Frequency >= 5925 && n.Frequency < 7125:
+		return "6"
+	}
+	return ""
+}
+
+// PassphraseAKMs are the key management suites a password is used for, in
+// the order offered to wpa_supplicant.
+var PassphraseAKMs = []string{"WPA-PSK", "WPA-PSK-SHA256", "FT-PSK", "SAE", "SAE-EXT-KEY", "FT-SAE", "FT-SAE-EXT-KEY"}
+
+// NeedsPassphrase reports whether joining the network takes a password
+// (WPA-Personal or WPA3-SAE).
+func (n WiFiNetwork) NeedsPassphrase() bool {
+	return n.HasAKM(PassphraseAKMs...)
+}
+
+// HasAKM reports whether the network advertises any of the given key
+// management suites.
+func (n WiFiNetwork) HasAKM(akms ...string) bool {
+	for _, have := range n.AKMs {
+		for _, want := range akms {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Connection represents an active network connection
@@ -166,6 +405,8 @@ type Connection struct {
 	IP        net.IP
 	Gateway   net.IP
 	DNS       []net.IP
+	Radio     string // "phy#N" for a wireless interface, "" otherwise
+	BSSID     string // the access point a station is associated with
 }
 
 // VPNStatus represents VPN connection status
@@ -179,16 +420,49 @@ type VPNStatus struct {
 
 // HotspotConfig represents hotspot configuration
 type HotspotConfig struct {
-	Interface string   `yaml:"interface" mapstructure:"interface"`
-	SSID      string   `yaml:"ssid" mapstructure:"ssid"`
-	Password  string   `yaml:"password" mapstructure:"password"`
-	Channel   int      `yaml:"channel" mapstructure:"channel"`
-	IPRange   string   `yaml:"ip_range" mapstructure:"ip_range"` // DHCP range, e.g., "192.168.50.50,192.168.50.150"
-	Gateway   string   `yaml:"gateway" mapstructure:"gateway"`   // e.g., "192.168.50.1"
-	Netmask   string   `yaml:"netmask" mapstructure:"netmask"`   // CIDR prefix length, e.g., "24" (default: "24")
-	DNS       []string `yaml:"dns" mapstructure:"dns"`
+	Interface   string   `yaml:"interface" mapstructure:"interface"`
+	SSID        string   `yaml:"ssid" mapstructure:"ssid"`
+	Password    string   `yaml:"password" mapstructure:"password"`
+	Security    string   `yaml:"security" mapstructure:"security"` // see HotspotSecurityModes (default: wpa2 with a password, open without)
+	Channel     int      `yaml:"channel" mapstructure:"channel"`
+	AutoChannel bool     `yaml:"-" mapstructure:"-"`                     // channel: auto — pick the least congested channel of the band at start
+	Band        string   `yaml:"band" mapstructure:"band"`               // "2.4" or "5" (default: the channel's; for auto, "5" with ieee80211ac or width 80+, else "2.4")
+	Width       int      `yaml:"width" mapstructure:"width"`             // channel width in MHz: 20 (default), 40, 80 or 160
+	Country     string   `yaml:"country" mapstructure:"country"`         // regulatory domain, ISO 3166-1 alpha-2 (e.g. "DE")
+	IEEE80211N  bool     `yaml:"ieee80211n" mapstructure:"ieee80211n"`   // 802.11n (HT, WiFi 4)
+	IEEE80211AC bool     `yaml:"ieee80211ac" mapstructure:"ieee80211ac"` // 802.11ac (VHT, WiFi 5; 5 GHz only)
+	IEEE80211AX bool     `yaml:"ieee80211ax" mapstructure:"ieee80211ax"` // 802.11ax (HE, WiFi 6)
+	IPRange     string   `yaml:"ip_range" mapstructure:"ip_range"`       // DHCP range, e.g., "192.168.50.50,192.168.50.150"
+	Gateway     string   `yaml:"gateway" mapstructure:"gateway"`         // e.g., "192.168.50.1"
+	Netmask     string   `yaml:"netmask" mapstructure:"netmask"`         // CIDR prefix length, e.g., "24" (default: "24")
+	DNS         []string `yaml:"dns" mapstructure:"dns"`
+	Deny        []string `yaml:"deny" mapstructure:"deny"`               // MACs that may not connect
+	Allow       []string `yaml:"allow" mapstructure:"allow"`             // when set, only these MACs may connect
+	MaxClients  int      `yaml:"max_clients" mapstructure:"max_clients"` // 0 for hostapd's limit
+	Uplink      string   `yaml:"uplink" mapstructure:"uplink"`           // see Uplink* (default: auto)
 }
 
+// Hotspot security modes: how clients authenticate to the access point.
+const (
+	// HotspotSecurityWPA2 is WPA2-Personal (PSK), which every client supports.
+	HotspotSecurityWPA2 = "wpa2"
+	// HotspotSecurityWPA3 is WPA3-Personal (SAE) with management frame
+	// protection required; older clients can't join.
+	HotspotSecurityWPA3 = "wpa3"
+	// HotspotSecurityWPA2WPA3 is transition mode: SAE for clients that
+	// support it, PSK for the others, with management frame protection
+	// optional.
+	HotspotSecurityWPA2WPA3 = "wpa2-wpa3"
+	// HotspotSecurityOpen has no authentication or encryption.
+	HotspotSecurityOpen = "open"
+	// HotspotSecurityOWE is Enhanced Open: no password, but each client's
+	// traffic is encrypted (Opportunistic Wireless Encryption).
+	HotspotSecurityOWE = "owe"
+)
+
+// HotspotSecurityModes lists the valid hotspot security values.
+var HotspotSecurityModes = []string{HotspotSecurityWPA2, HotspotSecurityWPA3, HotspotSecurityWPA2WPA3, HotspotSecurityOpen, HotspotSecurityOWE}
+
 // HotspotStatus represents hotspot status
 type HotspotStatus struct {
 	Interface string
@@ -196,6 +470,14 @@ type HotspotStatus struct {
 	Running   bool
 	Clients   int
 	Gateway   net.IP
+	// Station is set when the hotspot runs on a virtual interface next to
+	// this station interface's connection, on its channel.
+	Station string
+	Channel int
+	// StationChannel is the channel of Station's connection now. It differs
+	// from Channel once the station has roamed to another channel, which
+	// the access point can't follow.
+	StationChannel int
 }
 
 // DHCPServerConfig represents DHCP server configuration
@@ -206,8 +488,20 @@ type DHCPServerConfig struct {
 	Netmask   string   `yaml:"netmask" mapstructure:"netmask"`   // CIDR bits, e.g., "24" for /24. Defaults to "24"
 	DNS       []string `yaml:"dns" mapstructure:"dns"`
 	LeaseTime string   `yaml:"lease_time" mapstructure:"lease_time"` // e.g., "12h"
+	Uplink    string   `yaml:"uplink" mapstructure:"uplink"`         // see Uplink* (default: auto)
 }
 
+// Uplinks that shared clients (hotspot, DHCP server) reach the internet
+// through. Any other value names the interface to share out of.
+const (
+	// UplinkAuto shares out of the interface holding the default route.
+	UplinkAuto = "auto"
+	// UplinkVPN shares only through the active VPN tunnel: clients are
+	// policy-routed into it, and their traffic is rejected rather than sent
+	// out of another interface while the tunnel is down.
+	UplinkVPN = "vpn"
+)
+
 // Interfaces for dependency injection and testing
 
 // SystemExecutor handles system command execution
@@ -277,9 +571,39 @@ type WiFiManager interface {
 	Scan() ([]WiFiNetwork, error)
 	Connect(ssid, password, hostname string) error
 	ConnectWithBSSID(ssid, password, bssid, hostname string) error
+	// ConnectHidden connects to a network that doesn't broadcast its SSID,
+	// probing for it instead of looking it up in the scan. bssid may be "".
+	ConnectHidden(ssid, password, bssid, hostname string) error
+	// ConnectWPS joins a network with WPS, push-button when pin is empty,
+	// with the access point bssid ("" for any), and returns the credentials
+	// the access point provisioned.
+	ConnectWPS(bssid, pin, hostname string) (*WPSCredential, error)
+	// DetectNetwork returns the strongest scanned BSS of ssid, or nil when it
+	// isn't heard.
+	DetectNetwork(ssid string) *WiFiNetwork
+	// BestBSSID scans and returns the BSSID of ssid that policy prefers, or
+	// "" when ssid isn't heard.
+	BestBSSID(ssid string, policy RoamConfig) (string, error)
+	// Roam moves the association with ssid to a better access point when the
+	// signal is below policy's threshold and one is heard. It returns the
+	// roam performed, or nil when staying.
+	Roam(ssid string, policy RoamConfig) (*RoamEvent, error)
 	Disconnect() error
+	// ListConnections returns the manager's interface and every other
+	// wireless interface (station or access point) with its association.
 	ListConnections() ([]Connection, error)
 	GetInterface() string
+	// ForInterface returns the manager of the wireless interface iface,
+	// which runs its own wpa_supplicant, so radios can be connected at the
+	// same time. It returns the manager itself for its own interface or "".
+	ForInterface(iface string) WiFiManager
+}
+
+// WPSCredential is the network a WPS exchange provisioned.
+type WPSCredential struct {
+	SSID  string
+	PSK   string // empty for an open network
+	BSSID string // the access point's address, when it sent one
 }
 
 // VPNManager handles VPN operations
@@ -306,9 +630,21 @@ type NetworkManager interface {
 	DHCPRenew(iface string, hostname string) error
 	ConnectToConfiguredNetwork(config *NetworkConfig, password string, wifiMgr WiFiManager) error
 	GetConnectionInfo(iface string) (*Connection, error)
+	// MACPolicy describes the mac: policy that produced iface's current
+	// address (e.g. "stable for Home"), or "" when net didn't set it.
+	MACPolicy(iface string) string
+	// SetEncryptedDNS runs a local DNS-over-TLS stub resolver forwarding to
+	// servers and points resolv.conf at it. ClearDNS stops it.
+	SetEncryptedDNS(servers []string) error
+	// DetectEnvironment reports what can be observed on a connected link
+	// (BSSID, gateway MAC, DHCP domain, LLDP switch name) for match: rules.
+	DetectEnvironment(iface string) (*Environment, error)
 	// Disconnect releases DHCP, flushes addresses/routes, and brings the link down
 	// for a single interface. Safe to call on an already-down interface.
 	Disconnect(iface string) error
+	// RestoreHostname puts back the kernel hostname replaced for a
+	// connection with hostname_mode: system. Returns whether one was.
+	RestoreHostname() (bool, error)
 	// DisconnectAll tears down every non-loopback/non-virtual interface that has
 	// an IPv4 address assigned. Used by `net stop` to clean up both wired and WiFi.
 	// Returns the list of interfaces that were torn down.
@@ -320,6 +656,9 @@ type ConfigManager interface {
 	LoadConfig(path string) (*Config, error)
 	GetNetworkConfig(name string) (*NetworkConfig, error)
 	GetVPNConfig(name string) (*VPNConfig, error)
+	// GetHotspotConfig returns the named hotspot profile with its password
+	// resolved.
+	GetHotspotConfig(name string) (*HotspotConfig, error)
 	MergeWithCommon(networkName string, config *NetworkConfig) *NetworkConfig
 	GetConfig() *Config
 }
@@ -329,6 +668,41 @@ type HotspotManager interface {
 	Start(config *HotspotConfig) error
 	Stop() error
 	GetStatus() (*HotspotStatus, error)
+	// Clients lists the stations connected to the running hotspot.
+	Clients() ([]HotspotClient, error)
+	// Kick disconnects station mac from the running hotspot and adds it to
+	// the deny list until the hotspot stops.
+	Kick(mac string) error
+}
+
+// HotspotClient is a station connected to the hotspot: what hostapd knows
+// about it, joined with its DHCP lease.
+type HotspotClient struct {
+	MAC           string
+	IP            string // empty until the station has a lease
+	Hostname      string
+	Vendor        string // from the MAC's OUI; empty when unknown or randomized
+	Signal        int    // dBm; 0 when hostapd doesn't report it
+	ConnectedTime time.Duration
+	RxBytes       uint64 // received from the station
+	TxBytes       uint64 // sent to the station
+}
+
+// HostapdClient talks to a running hostapd through the control interface
+// UNIX socket of its interface (ctrl_interface=/run/hostapd), replacing
+// `hostapd_cli`.
+type HostapdClient interface {
+	// Stations returns the stations associated with iface, with their MAC,
+	// signal, connected time and byte counters. Replaces
+	// `hostapd_cli all_sta`.
+	Stations(iface string) ([]HotspotClient, error)
+	// Deauthenticate disconnects station mac from iface. Replaces
+	// `hostapd_cli deauthenticate`.
+	Deauthenticate(iface, mac string) error
+	// SetACL replaces the accept and deny MAC lists of iface, which hostapd
+	// checks under the macaddr_acl it was started with. Replaces
+	// `hostapd_cli accept_acl` and `hostapd_cli deny_acl`.
+	SetACL(iface string, accept, deny []string) error
 }
 
 // DHCPLease represents a single DHCP lease from the dnsmasq lease file
@@ -337,6 +711,7 @@ type DHCPLease struct {
 	MAC      string
 	IP       string
 	Hostname string
+	Vendor   string // from the MAC's OUI; empty when unknown or randomized
 }
 
 // DHCPManager handles DHCP server operations (running dnsmasq for hotspot)
@@ -354,6 +729,22 @@ type DHCPClientManager interface {
 	Acquire(iface string, hostname string) error
 	Release(iface string) error
 	Renew(iface string, hostname string) error
+	// SetOptions controls what later requests reveal about the machine.
+	SetOptions(opts DHCPClientOptions)
+	// Domain returns the domain name (option 15) of iface's current lease,
+	// or "" when there is none.
+	Domain(iface string) string
+}
+
+// DHCPClientOptions are the privacy settings of the DHCP client.
+type DHCPClientOptions struct {
+	// NoHostname omits option 12 entirely, including the system hostname a
+	// client's own defaults would send (dhclient.conf usually does).
+	NoHostname bool
+	// Anonymous sends only the minimal option set of the RFC 7844
+	// anonymity profile: a client identifier derived from the current MAC
+	// and a fixed parameter request list. No hostname, no vendor class.
+	Anonymous bool
 }
 
 // Route describes a single routing table entry in structured form. It replaces
@@ -421,6 +812,17 @@ type RouteManager interface {
 	FlushRoutes(iface string) error
 	// ListRoutes returns all IPv4 routes in the main table.
 	ListRoutes() ([]Route, error)
+	// SetTableDefault installs (or replaces) a device-only IPv4 default route
+	// via iface in table, backed by an unreachable default at a lower
+	// priority: once iface goes away, lookups in table fail instead of
+	// falling through to the main table.
+	SetTableDefault(table int, iface string) error
+	// AddPolicyRule routes packets arriving on iif by table. Re-adding an
+	// existing rule is a no-op.
+	AddPolicyRule(iif string, table int) error
+	// DelPolicyRule removes the rule added by AddPolicyRule. A missing rule is
+	// not treated as an error.
+	DelPolicyRule(iif string, table int) error
 }
 
 // AddrManager provides structured access to interface IPv4 addresses via
@@ -452,12 +854,17 @@ type LinkManager interface {
 	SetUp(iface string) error
 	// SetDown brings the interface administratively down.
 	SetDown(iface string) error
-	// Delete removes a virtual interface (e.g. a WireGuard device).
+	// Delete removes a virtual interface (e.g. a WireGuard device or a
+	// virtual wireless interface).
 	Delete(iface string) error
 	// Exists reports whether an interface with the given name exists.
 	Exists(iface string) (bool, error)
 	// AddWireGuard creates a WireGuard interface with the given name.
 	AddWireGuard(iface string) error
+	// AddWirelessInterface creates a virtual wireless interface of the
+	// given nl80211 type ("ap", "station", ...) on the radio of parent.
+	// Replaces `iw dev <parent> interface add <name> type <type>`.
+	AddWirelessInterface(parent, name, ifType string) error
 	// ListByType returns the names of all interfaces of the given link type
 	// (e.g. "wireguard"), in kernel order.
 	ListByType(linkType string) ([]string, error)
@@ -468,9 +875,143 @@ type LinkManager interface {
 	SetMAC(iface, mac string) error
 }
 
+// WirelessManager provides structured access to wireless interfaces via
+// nl80211 generic netlink, replacing text-parsing of `iw`. Read operations
+// are unprivileged; TriggerScan requires CAP_NET_ADMIN. Implementations must
+// return a clear error (never panic) when nl80211 is unavailable.
+type WirelessManager interface {
+	// Interfaces lists the wireless interfaces of all radios, in kernel
+	// order. Replaces parsing `iw dev`.
+	Interfaces() ([]WirelessInterface, error)
+	// InterfaceType returns the nl80211 type of iface ("station", "ap",
+	// "monitor", ...), or "" when iface is not a wireless interface.
+	InterfaceType(iface string) (string, error)
+	// TriggerScan starts a scan on iface and waits until the results are in
+	// (or the scan is aborted, or timeout passes). Replaces `iw <iface> scan`.
+	TriggerScan(iface string, timeout time.Duration) error
+	// ScanResults returns the BSSes in iface's scan cache. Replaces
+	// `iw <iface> scan dump`.
+	ScanResults(iface string) ([]BSS, error)
+	// LinkInfo returns iface's current association, or nil when it is not
+	// associated. Replaces `iw <iface> link`.
+	LinkInfo(iface string) (*WirelessLink, error)
+	// InterfaceCombinations returns the valid interface combinations of
+	// radio phy: which interface types it can run at the same time.
+	// Replaces the "valid interface combinations" section of `iw phy`.
+	InterfaceCombinations(phy int) ([]InterfaceCombination, error)
+	// Frequencies returns the channels radio phy supports, with their
+	// regulatory restrictions. Replaces the "Frequencies" lists of `iw phy`.
+	Frequencies(phy int) ([]RadioFrequency, error)
+}
+
+// RadioFrequency is a channel a radio supports, as reported by nl80211.
+type RadioFrequency struct {
+	Frequency int  // MHz
+	Disabled  bool // not allowed in the current regulatory domain
+	NoIR      bool // no initiating radiation: the radio may not beacon here
+	Radar     bool // DFS: radar detection is required before use
+}
+
+// InterfaceCombination is one valid interface combination of a radio: at
+// most Max interfaces in total on at most Channels different channels, with
+// each limit capping the number of interfaces of its types.
+type InterfaceCombination struct {
+	Limits   []InterfaceLimit
+	Max      int
+	Channels int
+}
+
+// InterfaceLimit caps the number of interfaces of some types within an
+// InterfaceCombination.
+type InterfaceLimit struct {
+	Max   int
+	Types []string // "station", "ap", ...
+}
+
+// WirelessInterface is a wireless interface as reported by nl80211.
+type WirelessInterface struct {
+	Name  string
+	Type  string // "station", "ap", "monitor", ...
+	PHY   int    // index of the radio (phy#N)
+	SSID  string // set while associated (station) or beaconing (AP)
+	MAC   string
+	Index int
+}
+
+// BSS is one entry of a scan: an access point as heard by the radio.
+type BSS struct {
+	BSSID      string
+	Frequency  int    // MHz
+	Signal     int    // dBm
+	Capability uint16 // 802.11 capability information field
+	// IEs holds the raw information elements (SSID, RSN, vendor, ...) of
+	// the latest probe response or beacon.
+	IEs []byte
+	// Associated is set for the BSS the interface is associated with.
+	Associated bool
+}
+
+// WirelessLink describes the association of a station interface.
+type WirelessLink struct {
+	SSID      string
+	BSSID     string
+	Frequency int     // MHz
+	Signal    int     // dBm, 0 when unknown
+	TxBitrate float64 // Mbit/s, 0 when unknown
+}
+
+// SupplicantClient talks to a running wpa_supplicant through the control
+// interface UNIX socket of an interface (ctrl_interface=/run/wpa_supplicant),
+// replacing `wpa_cli`. Implementations must return a clear error (never
+// panic) when no wpa_supplicant serves the interface.
+type SupplicantClient interface {
+	// Status returns the STATUS fields of iface (wpa_state, ssid, bssid,
+	// ...). Replaces `wpa_cli status`.
+	Status(iface string) (map[string]string, error)
+	// SignalPoll returns the SIGNAL_POLL fields of iface (RSSI, LINKSPEED,
+	// NOISE, FREQUENCY).
+	SignalPoll(iface string) (map[string]string, error)
+	// AddNetwork adds an empty network block and returns its id.
+	AddNetwork(iface string) (int, error)
+	// SetNetwork sets a variable of network block id. value is sent as is:
+	// string values need their double quotes (`"MySSID"`).
+	SetNetwork(iface string, id int, name, value string) error
+	// Reconfigure makes wpa_supplicant reread its configuration file.
+	Reconfigure(iface string) error
+	// Reassociate reconnects with the current configuration.
+	Reassociate(iface string) error
+	// Terminate stops wpa_supplicant. Replaces `wpa_cli terminate`.
+	Terminate(iface string) error
+	// StartWPS starts WPS with the access point bssid ("" for any):
+	// push-button when pin is empty, otherwise the PIN method with pin.
+	StartWPS(iface, bssid, pin string) error
+	// Attach subscribes to the events of iface. Events that happen before
+	// Attach returns are not delivered.
+	Attach(iface string) (SupplicantMonitor, error)
+}
+
+// SupplicantMonitor receives the events of an attached control connection.
+type SupplicantMonitor interface {
+	// Next returns the next event, waiting at most timeout. A timeout is
+	// reported as an error wrapping os.ErrDeadlineExceeded.
+	Next(timeout time.Duration) (SupplicantEvent, error)
+	// Close detaches and closes the connection.
+	Close() error
+}
+
+// SupplicantEvent is an unsolicited wpa_supplicant control message, such as
+// "CTRL-EVENT-SSID-TEMP-DISABLED id=0 ssid=\"Cafe\" auth_failures=1
+// duration=10 reason=WRONG_KEY".
+type SupplicantEvent struct {
+	Name   string            // first word, e.g. "CTRL-EVENT-CONNECTED"
+	Fields map[string]string // key=value arguments, unquoted
+	Text   string            // the whole message without its priority
+}
+
 // FirewallManager configures the IPv4 NAT/forwarding rules that let clients on
 // an internal interface (hotspot or DHCP-served) reach the internet through an
-// outbound interface. It wraps iptables (via github.com/coreos/go-iptables),
+// outbound interface, and the kill switch and inbound filter that trust
+// policies turn on for untrusted networks. It wraps iptables (via github.com/coreos/go-iptables),
 // which reduces duplicate-rule and rule-listing bugs versus building iptables
 // command lines by hand. Implementations must return a clear error (never
 // panic) when iptables is unavailable.
@@ -483,6 +1024,31 @@ type FirewallManager interface {
 	// DisableNAT removes the rules installed by EnableNAT. Missing rules are not
 	// treated as errors.
 	DisableNAT(internalIface, outIface string) error
+	// EnableKillSwitch rejects traffic leaving through iface except DHCP and
+	// traffic to the allowed addresses (the VPN servers), so nothing leaks
+	// outside the tunnel. Replaces any previous kill switch.
+	EnableKillSwitch(iface string, allowed []string) error
+	// DisableKillSwitch removes the kill switch. Safe to call when none is set.
+	DisableKillSwitch() error
+	// BlockInbound drops connections initiated from the network on iface;
+	// replies to outgoing traffic, DHCP and (IPv6) neighbor discovery still
+	// pass. Replaces any previous inbound filter.
+	BlockInbound(iface string) error
+	// UnblockInbound removes the inbound filter. Safe to call when none is set.
+	UnblockInbound() error
+	// BlockNameBroadcasts drops mDNS, LLMNR and NetBIOS name traffic leaving
+	// through iface, which would announce the machine's real hostname.
+	// Replaces any previous block.
+	BlockNameBroadcasts(iface string) error
+	// UnblockNameBroadcasts removes the block. Safe to call when none is set.
+	UnblockNameBroadcasts() error
+	// RestrictForward rejects traffic forwarded from internalIface unless it
+	// leaves through outIface, so shared clients can't reach the internet
+	// any other way. Replaces any previous restriction on internalIface.
+	RestrictForward(internalIface, outIface string) error
+	// UnrestrictForward removes the restriction on internalIface. Safe to
+	// call when none is set.
+	UnrestrictForward(internalIface string) error
 }
 
 // WireGuardConfigurator applies and inspects WireGuard interface configuration
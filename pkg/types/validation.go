@@ -5,7 +5,9 @@ import (
 	"net"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Validation regexes - compiled once at package init
@@ -43,7 +45,16 @@ func ValidateMAC(mac string) error {
 		return nil // Empty is allowed (means don't change)
 	}
 	// Special values accepted by SetMAC
-	if mac == "random" || mac == "default" || mac == "permanent" {
+	if mac == "random" || mac == "default" || mac == "permanent" || mac == "stable" {
+		return nil
+	}
+	if _, ok, err := MACRotatePeriod(mac); ok {
+		return err
+	}
+	if vendor, ok := strings.CutPrefix(mac, "vendor:"); ok {
+		if strings.TrimSpace(vendor) == "" {
+			return fmt.Errorf("missing vendor name: use e.g. vendor:Intel")
+		}
 		return nil
 	}
 	if !macRegex.MatchString(mac) {
@@ -52,6 +63,29 @@ func ValidateMAC(mac string) error {
 	return nil
 }
 
+// MACRotatePeriod parses a "rotate:<period>" MAC policy, where period is a
+// Go duration ("12h") or a number of days ("7d"). ok reports whether mac is
+// a rotate policy at all; err whether its period is unusable.
+func MACRotatePeriod(mac string) (period time.Duration, ok bool, err error) {
+	value, ok := strings.CutPrefix(mac, "rotate:")
+	if !ok {
+		return 0, false, nil
+	}
+	if days, isDays := strings.CutSuffix(value, "d"); isDays {
+		n, convErr := strconv.Atoi(days)
+		if convErr != nil {
+			return 0, true, fmt.Errorf("invalid rotation period %q: use e.g. 24h or 7d", value)
+		}
+		period = time.Duration(n) * 24 * time.Hour
+	} else if period, err = time.ParseDuration(value); err != nil {
+		return 0, true, fmt.Errorf("invalid rotation period %q: use e.g. 24h or 7d", value)
+	}
+	if period < time.Minute {
+		return 0, true, fmt.Errorf("rotation period %q is too short (minimum 1m)", value)
+	}
+	return period, true, nil
+}
+
 // ValidateSSID validates a WiFi SSID
 func ValidateSSID(ssid string) error {
 	if ssid == "" {
@@ -137,6 +171,19 @@ func ValidateDNSServer(server string) error {
 	return nil
 }
 
+// ValidateUplink validates a sharing uplink: empty, UplinkAuto, UplinkVPN or
+// an interface name.
+func ValidateUplink(uplink string) error {
+	switch uplink {
+	case "", UplinkAuto, UplinkVPN:
+		return nil
+	}
+	if err := ValidateInterfaceName(uplink); err != nil {
+		return fmt.Errorf("invalid uplink %q (valid: %s, %s or an interface name)", uplink, UplinkAuto, UplinkVPN)
+	}
+	return nil
+}
+
 // ValidatePortalProbeURL reports whether raw is acceptable as a captive-portal
 // probe endpoint: printable ASCII only in the RAW string (the CLI prints the
 // configured URL verbatim — this rules out control bytes, bidi/format runes,
@@ -52,6 +52,13 @@ func TestValidateMAC(t *testing.T) {
 		{"empty", "", false},
 		{"random keyword", "random", false},
 		{"permanent keyword", "permanent", false},
+		{"stable keyword", "stable", false},
+		{"rotate hours", "rotate:24h", false},
+		{"rotate days", "rotate:7d", false},
+		{"rotate bad period", "rotate:daily", true},
+		{"rotate too short", "rotate:10s", true},
+		{"vendor", "vendor:Intel", false},
+		{"vendor without name", "vendor:", true},
 		{"too short", "aa:bb:cc:dd:ee", true},
 		{"too long", "aa:bb:cc:dd:ee:ff:00", true},
 		{"wrong separator", "aa-bb-cc-dd-ee-ff", true},
@@ -234,6 +241,32 @@ func TestValidateDNSServer(t *testing.T) {
 	}
 }
 
+func TestValidateUplink(t *testing.T) {
+	tests := []struct {
+		name    string
+		uplink  string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"auto", "auto", false},
+		{"vpn", "vpn", false},
+		{"interface", "eth0", false},
+		{"invalid interface", "eth 0", true},
+		{"too long", "abcdefghijklmnop", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUplink(tt.uplink)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestTimeoutConfigGetDHCPTimeout(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,71 @@
+package vpn
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// lookupHost resolves VPN server hostnames (overridable in tests).
+var lookupHost = net.LookupHost
+
+// ServerAddresses returns the IP addresses of the servers a WireGuard or
+// OpenVPN config connects to: every peer Endpoint, or every "remote" line.
+// A kill switch must let these through for the tunnel to come up, so
+// hostnames are resolved now, while DNS still works outside the tunnel.
+// Tailscale and NetBird pick relays and peers at run time and have no fixed
+// servers, which is an error.
+func ServerAddresses(config *types.VPNConfig) ([]string, error) {
+	var hosts []string
+	switch config.Type {
+	case "wireguard":
+		for _, line := range strings.Split(config.Config, "\n") {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "endpoint") {
+				hosts = append(hosts, endpointHost(strings.TrimSpace(value)))
+			}
+		}
+	case "openvpn":
+		for _, line := range strings.Split(config.Config, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "remote" {
+				hosts = append(hosts, fields[1])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s VPNs have no fixed server addresses", config.Type)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no server address in the %s config", config.Type)
+	}
+
+	var addrs []string
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		resolved := []string{host}
+		if net.ParseIP(host) == nil {
+			var err error
+			if resolved, err = lookupHost(host); err != nil {
+				return nil, fmt.Errorf("resolving VPN server %s: %w", host, err)
+			}
+		}
+		for _, addr := range resolved {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// endpointHost strips the port from a WireGuard Endpoint ("host:port",
+// "[v6]:port").
+func endpointHost(endpoint string) string {
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+	return strings.Trim(endpoint, "[]")
+}
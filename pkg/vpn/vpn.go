@@ -12,7 +12,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/angelfreak/net/pkg/firewall"
 	"github.com/angelfreak/net/pkg/netlink"
+	"github.com/angelfreak/net/pkg/share"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
 	"github.com/angelfreak/net/pkg/wgconfig"
@@ -31,6 +33,7 @@ type Manager struct {
 	addrMgr       types.AddrManager           // netlink-backed interface address access (WireGuard iface IP)
 	linkMgr       types.LinkManager           // netlink-backed link access (WireGuard iface create/delete/enumerate)
 	wgConfig      types.WireGuardConfigurator // wgctrl-backed WireGuard config; nil until first use / injected in tests
+	firewall      types.FirewallManager       // go-iptables-backed rules of clients shared through the VPN; nil until first use / injected in tests
 	endpointRoute string                      // Stores the VPN endpoint IP for cleanup on disconnect
 	runtimeDir    string                      // Directory for runtime files (active-vpn state file)
 	mu            sync.Mutex                  // Protects endpointRoute and serializes Connect/Disconnect/state file operations
@@ -179,9 +182,30 @@ func (m *Manager) Connect(name string) error {
 		// Non-fatal: connection succeeded, just status tracking won't work perfectly
 	}
 
+	// Move hotspot and DHCP clients shared with uplink: vpn to the new
+	// tunnel, so they resume through it after a reconnect or a switch to
+	// another VPN. Without such a share the VPN routing table is left alone.
+	if err := share.Retarget(m.firewallMgr, m.routeMgr, m.runtimeDir, vpnIface); err != nil {
+		m.logger.Warn("Failed to route shared clients through the VPN", "interface", vpnIface, "error", err)
+	}
+
 	return nil
 }
 
+// firewallMgr returns the injected FirewallManager or lazily constructs the
+// go-iptables-backed one.
+func (m *Manager) firewallMgr() (types.FirewallManager, error) {
+	if m.firewall != nil {
+		return m.firewall, nil
+	}
+	fw, err := firewall.New()
+	if err != nil {
+		return nil, err
+	}
+	m.firewall = fw
+	return m.firewall, nil
+}
+
 // Disconnect disconnects from a VPN
 func (m *Manager) Disconnect(name string) error {
 	m.mu.Lock()
@@ -910,8 +934,15 @@ func (m *Manager) connectWireGuard(config *types.VPNConfig, origGW, origIface st
 		}
 	}
 
+	// A separately stored private key (usually a secret reference, already
+	// resolved by the config manager) takes precedence over one in the INI.
+	wgConfig := config.Config
+	if config.PrivateKey != "" {
+		wgConfig = wgconfig.WithPrivateKey(wgConfig, config.PrivateKey)
+	}
+
 	// Set config natively via wgctrl (equivalent to `wg setconf`).
-	if err := wg.Configure(iface, config.Config); err != nil {
+	if err := wg.Configure(iface, wgConfig); err != nil {
 		// Clean up interface on failure.
 		m.linkMgr.Delete(iface)
 		return fmt.Errorf("failed to set WireGuard config: %w", err)
@@ -1053,9 +1084,13 @@ func (m *Manager) writeSecretFile(name, secret string) (path string, cleanup fun
 	return path, func() { m.removeFile(path) }, nil
 }
 
+// activeVPNFileName is the name of the active VPN state file in the runtime
+// directory.
+const activeVPNFileName = "active-vpn"
+
 // activeVPNFilePath returns the path to the active VPN state file
 func (m *Manager) activeVPNFilePath() string {
-	return filepath.Join(m.runtimeDir, "active-vpn")
+	return filepath.Join(m.runtimeDir, activeVPNFileName)
 }
 
 // setActiveVPNState records the full VPN state to the state file
@@ -1077,7 +1112,23 @@ func (m *Manager) setActiveVPNState(state vpnState) error {
 
 // getActiveVPNState reads the full VPN state from the state file
 func (m *Manager) getActiveVPNState() *vpnState {
-	data, err := os.ReadFile(m.activeVPNFilePath())
+	return readVPNState(m.activeVPNFilePath())
+}
+
+// ActiveInterface returns the tunnel interface of the VPN connected via net,
+// as recorded in runtimeDir, or "" when none is connected.
+func ActiveInterface(runtimeDir string) string {
+	state := readVPNState(filepath.Join(runtimeDir, activeVPNFileName))
+	if state == nil {
+		return ""
+	}
+	return state.Interface
+}
+
+// readVPNState parses the active VPN state file at path, returning nil when
+// it is missing or empty.
+func readVPNState(path string) *vpnState {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil
 	}
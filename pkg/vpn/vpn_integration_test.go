@@ -298,6 +298,10 @@ func (c *testConfigManager) GetVPNConfig(name string) (*types.VPNConfig, error)
 	return c.vpnConfig, nil
 }
 
+func (c *testConfigManager) GetHotspotConfig(name string) (*types.HotspotConfig, error) {
+	return nil, nil
+}
+
 func (c *testConfigManager) GetIgnoredInterfaces() []string {
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	fwfake "github.com/angelfreak/net/pkg/firewall/fake"
 	"github.com/angelfreak/net/pkg/netlink/fake"
 	"github.com/angelfreak/net/pkg/types"
 	wgfake "github.com/angelfreak/net/pkg/wgconfig/fake"
@@ -265,6 +266,10 @@ func (m *mockConfigManager) GetVPNConfig(name string) (*types.VPNConfig, error)
 	return nil, fmt.Errorf("VPN config '%s' not found", name)
 }
 
+func (m *mockConfigManager) GetHotspotConfig(name string) (*types.HotspotConfig, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockConfigManager) MergeWithCommon(networkName string, config *types.NetworkConfig) *types.NetworkConfig {
 	return config
 }
@@ -1210,6 +1215,43 @@ func TestConnect_SetsActiveVPNStateFile(t *testing.T) {
 	assert.Equal(t, "wireguard", state.Type)
 	assert.Equal(t, "192.168.1.1", state.OriginalGateway)
 	assert.Equal(t, "eth0", state.OriginalInterface)
+
+	// The tunnel is what other packages find via ActiveInterface. Nothing is
+	// shared through the VPN, so its routing table is left alone.
+	routes := manager.routeMgr.(*fake.RouteManager)
+	assert.Empty(t, routes.TableDefaults)
+	assert.Equal(t, "wg0", ActiveInterface(tempDir))
+	assert.Equal(t, "", ActiveInterface(t.TempDir()))
+}
+
+func TestConnect_MovesSharedClients(t *testing.T) {
+	tempDir := t.TempDir()
+	configMgr := &mockConfigManager{
+		vpnConfigs: map[string]*types.VPNConfig{
+			"test-vpn": {Type: "wireguard", Config: "wireguard config", Interface: "wg0", Address: "10.0.0.1/24"},
+		},
+	}
+	manager := NewManagerWithDir(&mockSystemExecutor{commands: map[string]string{}}, &mockLogger{}, configMgr, tempDir)
+	manager.routeMgr = &fake.RouteManager{Routes: []types.Route{{Gw: "192.168.1.1", Iface: "eth0"}}}
+	manager.addrMgr = newFakeAddrs()
+	manager.linkMgr = newFakeLinks()
+	manager.wgConfig = wgfake.New()
+	fw := &fwfake.Manager{}
+	manager.firewall = fw
+
+	// A hotspot shared with uplink: vpn while the previous VPN was on tun0
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "vpn-shares"), 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "vpn-shares", "wlan0_ap"), []byte("tun0\n"), 0600))
+
+	assert.NoError(t, manager.Connect("test-vpn"))
+	routes := manager.routeMgr.(*fake.RouteManager)
+	assert.Equal(t, "wg0", routes.TableDefaults[types.VPNRouteTable])
+	assert.Equal(t, map[string]string{"wlan0_ap": "wg0"}, fw.Restricted)
+	assert.Equal(t, []fwfake.NATCall{{Internal: "wlan0_ap", Out: "wg0"}}, fw.Enabled)
+	assert.Equal(t, []fwfake.NATCall{{Internal: "", Out: "tun0"}}, fw.Disabled)
+	data, err := os.ReadFile(filepath.Join(tempDir, "vpn-shares", "wlan0_ap"))
+	assert.NoError(t, err)
+	assert.Equal(t, "wg0\n", string(data))
 }
 
 func TestExtractEndpoint(t *testing.T) {
@@ -1699,3 +1741,37 @@ func TestListVPNs_AmbiguousSameTypeNotBothConnected(t *testing.T) {
 		assert.False(t, v.Connected, "ambiguous same-type VPN %q must not be flagged connected", v.Name)
 	}
 }
+
+func TestServerAddresses(t *testing.T) {
+	origLookup := lookupHost
+	lookupHost = func(host string) ([]string, error) {
+		if host == "vpn.example.com" {
+			return []string{"203.0.113.5", "2001:db8::5"}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+	t.Cleanup(func() { lookupHost = origLookup })
+
+	addrs, err := ServerAddresses(&types.VPNConfig{Type: "wireguard", Config: `[Interface]
+PrivateKey = x
+[Peer]
+Endpoint = vpn.example.com:51820
+[Peer]
+Endpoint = [2001:db8::9]:51820
+[Peer]
+endpoint=198.51.100.1:51820
+`})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.5", "2001:db8::5", "2001:db8::9", "198.51.100.1"}, addrs)
+
+	addrs, err = ServerAddresses(&types.VPNConfig{Type: "openvpn", Config: "client\nremote 192.0.2.10 1194 udp\nremote vpn.example.com 443\n"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.10", "203.0.113.5", "2001:db8::5"}, addrs)
+
+	_, err = ServerAddresses(&types.VPNConfig{Type: "openvpn", Config: "remote unknown.example.com\n"})
+	assert.ErrorContains(t, err, "unknown.example.com")
+	_, err = ServerAddresses(&types.VPNConfig{Type: "wireguard", Config: "[Interface]\n"})
+	assert.Error(t, err)
+	_, err = ServerAddresses(&types.VPNConfig{Type: "tailscale"})
+	assert.Error(t, err)
+}
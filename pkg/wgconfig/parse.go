@@ -239,3 +239,63 @@ func parseAllowedIPs(value string) ([]net.IPNet, error) {
 	}
 	return nets, nil
 }
+
+// WithPrivateKey returns config with its [Interface] PrivateKey set to key,
+// replacing any existing PrivateKey line. It lets the private key be kept in
+// a secret store (VPNConfig.PrivateKey) while the rest of the INI stays in
+// the config file. An [Interface] section is prepended if config has none.
+func WithPrivateKey(config, key string) string {
+	lines := strings.Split(config, "\n")
+	out := make([]string, 0, len(lines)+2)
+	section := ""
+	inserted := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.ToLower(strings.Trim(trimmed, "[]"))
+			out = append(out, line)
+			if section == "interface" && !inserted {
+				out = append(out, "PrivateKey = "+key)
+				inserted = true
+			}
+			continue
+		}
+		if section == "interface" {
+			if k, _, ok := strings.Cut(trimmed, "="); ok && strings.EqualFold(strings.TrimSpace(k), "PrivateKey") {
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	if !inserted {
+		out = append([]string{"[Interface]", "PrivateKey = " + key, ""}, out...)
+	}
+	return strings.Join(out, "\n")
+}
+
+// Address returns the first wg-quick Address of config's [Interface]
+// section ("10.0.0.2/32" of "Address = 10.0.0.2/32, fd00::2/128"), or "".
+func Address(config string) string {
+	section := ""
+	for _, line := range strings.Split(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.ToLower(strings.Trim(trimmed, "[]"))
+			continue
+		}
+		k, v, ok := strings.Cut(trimmed, "=")
+		if section == "interface" && ok && strings.EqualFold(strings.TrimSpace(k), "Address") {
+			first, _, _ := strings.Cut(v, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	return ""
+}
+
+// Validate reports whether config is a WireGuard configuration that Configure
+// would accept. Endpoint hostnames are resolved, so validation of a config
+// with a hostname endpoint needs working DNS.
+func Validate(config string) error {
+	_, err := parseConfig(config)
+	return err
+}
@@ -1,6 +1,7 @@
 package wgconfig
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -174,3 +175,38 @@ func TestParseConfig_EndpointFormats(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestWithPrivateKey(t *testing.T) {
+	const otherKey = "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+
+	t.Run("replaces existing key", func(t *testing.T) {
+		out := WithPrivateKey("[Interface]\nPrivateKey = "+zeroKey+"\nListenPort = 51820\n\n[Peer]\nPublicKey = "+zeroKey+"\n", otherKey)
+		cfg, err := parseConfig(out)
+		require.NoError(t, err)
+		require.NotNil(t, cfg.PrivateKey)
+		assert.Equal(t, otherKey, cfg.PrivateKey.String())
+		assert.Equal(t, 1, strings.Count(out, "PrivateKey"))
+	})
+
+	t.Run("adds key to interface without one", func(t *testing.T) {
+		out := WithPrivateKey("[Interface]\nAddress = 10.0.0.2/32\n\n[Peer]\nPublicKey = "+zeroKey+"\n", otherKey)
+		cfg, err := parseConfig(out)
+		require.NoError(t, err)
+		require.NotNil(t, cfg.PrivateKey)
+		assert.Equal(t, otherKey, cfg.PrivateKey.String())
+	})
+
+	t.Run("prepends interface section", func(t *testing.T) {
+		out := WithPrivateKey("[Peer]\nPublicKey = "+zeroKey+"\n", otherKey)
+		cfg, err := parseConfig(out)
+		require.NoError(t, err)
+		require.NotNil(t, cfg.PrivateKey)
+		assert.Equal(t, otherKey, cfg.PrivateKey.String())
+		require.Len(t, cfg.Peers, 1)
+	})
+}
+
+func TestAddress(t *testing.T) {
+	assert.Equal(t, "10.0.0.2/32", Address("[Interface]\nPrivateKey = x\nAddress = 10.0.0.2/32, fd00::2/128\n\n[Peer]\nAddress = 1.2.3.4\n"))
+	assert.Equal(t, "", Address("[Peer]\nAddress = 1.2.3.4\n"))
+}
@@ -0,0 +1,381 @@
+package wifi
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Information element IDs (IEEE 802.11-2020, 9.4.2).
+const (
+	ieSSID         = 0
+	ieHTCapability = 45
+	ieRSN          = 48
+	ieHTOperation  = 61
+	ieVHTCapab     = 191
+	ieVHTOperation = 192
+	ieVendor       = 221
+	ieExtension    = 255
+)
+
+// Element ID extensions carried in ieExtension.
+const (
+	extHECapability  = 35
+	extHEOperation   = 36
+	extEHTOperation  = 106
+	extEHTCapability = 108
+)
+
+// capPrivacy is the Privacy bit of the capability information field: the
+// BSS requires encryption. Without an RSN or WPA element, that means WEP.
+const capPrivacy = 0x0010
+
+// AKM suite types under the IEEE 802.11 OUI 00-0F-AC.
+const (
+	akmPSK       = 2
+	akmPSKSHA256 = 6
+	akmSAE       = 8
+	akmOWE       = 18
+	akmSAEExtKey = 24
+)
+
+// rsnAKMNames maps AKM suite types (00-0F-AC:n) to wpa_supplicant key_mgmt
+// names.
+var rsnAKMNames = map[byte]string{
+	1:            "WPA-EAP",
+	akmPSK:       "WPA-PSK",
+	3:            "FT-EAP",
+	4:            "FT-PSK",
+	5:            "WPA-EAP-SHA256",
+	akmPSKSHA256: "WPA-PSK-SHA256",
+	akmSAE:       "SAE",
+	9:            "FT-SAE",
+	11:           "WPA-EAP-SUITE-B",
+	12:           "WPA-EAP-SUITE-B-192",
+	13:           "FT-EAP-SHA384",
+	akmOWE:       "OWE",
+	akmSAEExtKey: "SAE-EXT-KEY",
+	25:           "FT-SAE-EXT-KEY",
+}
+
+// wpaAKMNames maps the suite types of the pre-RSN WPA element (00-50-F2:n).
+var wpaAKMNames = map[byte]string{
+	1: "WPA-EAP",
+	2: "WPA-PSK",
+}
+
+// cipherNames maps cipher suite types to wpa_supplicant names. RSN (00-0F-AC)
+// and WPA (00-50-F2) share the numbering for the suites they have in common.
+var cipherNames = map[byte]string{
+	1:  "WEP40",
+	2:  "TKIP",
+	4:  "CCMP",
+	5:  "WEP104",
+	8:  "GCMP",
+	9:  "GCMP-256",
+	10: "CCMP-256",
+}
+
+// RSN capabilities bits for management frame protection.
+const (
+	rsnCapMFPR = 1 << 6
+	rsnCapMFPC = 1 << 7
+)
+
+// elements walks the information elements in b, calling fn with each
+// element's ID and body. A truncated trailing element is ignored.
+func elements(b []byte, fn func(id byte, body []byte)) {
+	for len(b) >= 2 {
+		id, n := b[0], int(b[1])
+		if len(b) < 2+n {
+			return
+		}
+		fn(id, b[2:2+n])
+		b = b[2+n:]
+	}
+}
+
+// suiteParams is what an RSN or WPA element advertises.
+type suiteParams struct {
+	group    string
+	pairwise []string
+	akms     []string
+	caps     uint16
+}
+
+// parseSuites decodes the body of an RSN element, or of a WPA element with
+// its OUI and type stripped: version, group cipher, pairwise cipher list,
+// AKM list and (RSN only) capabilities. Later fields may be omitted, in which
+// case the defaults stand; suites under other OUIs are skipped.
+func parseSuites(b []byte, oui [3]byte, akmNames map[byte]string) suiteParams {
+	var p suiteParams
+	suite := func(s []byte) (byte, bool) {
+		return s[3], s[0] == oui[0] && s[1] == oui[1] && s[2] == oui[2]
+	}
+	if len(b) < 6 {
+		return p
+	}
+	if t, ok := suite(b[2:6]); ok {
+		p.group = cipherNames[t]
+	}
+	rest := b[6:]
+	for _, list := range []func(t byte){
+		func(t byte) {
+			if name, ok := cipherNames[t]; ok {
+				p.pairwise = append(p.pairwise, name)
+			}
+		},
+		func(t byte) {
+			if name, ok := akmNames[t]; ok {
+				p.akms = append(p.akms, name)
+			}
+		},
+	} {
+		if len(rest) < 2 {
+			return p
+		}
+		count := int(binary.LittleEndian.Uint16(rest))
+		rest = rest[2:]
+		for i := 0; i < count && len(rest) >= 4; i++ {
+			if t, ok := suite(rest[:4]); ok {
+				list(t)
+			}
+			rest = rest[4:]
+		}
+	}
+	if len(rest) >= 2 {
+		p.caps = binary.LittleEndian.Uint16(rest)
+	}
+	return p
+}
+
+var (
+	ouiIEEE = [3]byte{0x00, 0x0f, 0xac}
+	ouiMS   = [3]byte{0x00, 0x50, 0xf2}
+)
+
+// isMSElement reports whether a vendor element body is the Microsoft
+// (00-50-F2) element of the given type: 1 is WPA, 4 is WPS.
+func isMSElement(body []byte, typ byte) bool {
+	return len(body) >= 4 && body[0] == ouiMS[0] && body[1] == ouiMS[1] && body[2] == ouiMS[2] && body[3] == typ
+}
+
+// ChannelFromFrequency returns the channel number of a center frequency in
+// MHz, or 0 when it is outside the 2.4, 5 and 6 GHz bands. Channel numbers
+// repeat across bands: 6 GHz channel 1 is not 2.4 GHz channel 1.
+func ChannelFromFrequency(freq int) int {
+	switch {
+	case freq == 2484:
+		return 14
+	case freq >= 2412 && freq < 2484:
+		return (freq - 2407) / 5
+	case freq == 5935:
+		return 2
+	case freq >= 5955 && freq <= 7115:
+		return (freq - 5950) / 5
+	case freq >= 5000 && freq < 5925:
+		return (freq - 5000) / 5
+	}
+	return 0
+}
+
+// vhtWidth decodes the channel width of VHT operation information: width
+// code, CCFS0 and CCFS1 (802.11-2020, Table 9-274).
+func vhtWidth(code, ccfs0, ccfs1 byte) int {
+	switch code {
+	case 1:
+		if ccfs1 != 0 {
+			return 160 // 160 or 80+80 signalled through CCFS1
+		}
+		return 80
+	case 2, 3:
+		return 160
+	}
+	return 0
+}
+
+// heOperationWidth returns the channel width from the 6 GHz operation
+// information of an HE operation element, or 0 when absent.
+func heOperationWidth(body []byte) int {
+	// HE operation parameters (3), BSS color (1), basic HE-MCS and NSS (2).
+	if len(body) < 6 {
+		return 0
+	}
+	params := uint32(body[0]) | uint32(body[1])<<8 | uint32(body[2])<<16
+	off := 6
+	if params&(1<<14) != 0 { // VHT operation information present
+		off += 3
+	}
+	if params&(1<<15) != 0 { // co-hosted BSS
+		off++
+	}
+	if params&(1<<17) == 0 || len(body) < off+5 {
+		return 0
+	}
+	// Primary channel, control, CCFS0, CCFS1, minimum rate.
+	switch body[off+1] & 0x03 {
+	case 1:
+		return 40
+	case 2:
+		return 80
+	case 3:
+		return 160
+	}
+	return 20
+}
+
+// ehtOperationWidth returns the channel width from an EHT operation
+// element, or 0 when it carries no operation information.
+func ehtOperationWidth(body []byte) int {
+	// EHT operation parameters (1), basic EHT-MCS and NSS (4), then the
+	// operation information (control, CCFS0, CCFS1) when bit 0 is set.
+	if len(body) < 8 || body[0]&0x01 == 0 {
+		return 0
+	}
+	switch body[5] & 0x07 {
+	case 1:
+		return 40
+	case 2:
+		return 80
+	case 3:
+		return 160
+	case 4:
+		return 320
+	}
+	return 20
+}
+
+// networkFromBSS turns a scan entry into a WiFiNetwork, decoding security,
+// channel, width, generation, WPS and hidden SSIDs from its elements.
+func networkFromBSS(bss types.BSS) types.WiFiNetwork {
+	network := types.WiFiNetwork{
+		BSSID:     bss.BSSID,
+		Signal:    bss.Signal,
+		Frequency: bss.Frequency,
+		Channel:   ChannelFromFrequency(bss.Frequency),
+		Security:  "Open",
+		Width:     20,
+	}
+	if bss.Capability&capPrivacy != 0 {
+		network.Security = "WEP"
+	}
+
+	var rsn, wpa *suiteParams
+	widen := func(mhz int) {
+		if mhz > network.Width {
+			network.Width = mhz
+		}
+	}
+	hidden := true
+	elements(bss.IEs, func(id byte, body []byte) {
+		switch id {
+		case ieSSID:
+			network.SSID = string(body)
+			for _, c := range body {
+				if c != 0 {
+					hidden = false
+				}
+			}
+		case ieRSN:
+			p := parseSuites(body, ouiIEEE, rsnAKMNames)
+			rsn = &p
+		case ieVendor:
+			switch {
+			case isMSElement(body, 1):
+				p := parseSuites(body[4:], ouiMS, wpaAKMNames)
+				wpa = &p
+			case isMSElement(body, 4):
+				network.WPS = true
+			}
+		case ieHTCapability:
+			if network.Generation == "" {
+				network.Generation = "n"
+			}
+		case ieHTOperation:
+			// Secondary channel offset (bits 0-1) with the any-width bit.
+			if len(body) >= 2 && body[1]&0x03 != 0 && body[1]&0x04 != 0 {
+				widen(40)
+			}
+		case ieVHTCapab:
+			if network.Generation == "" || network.Generation == "n" {
+				network.Generation = "ac"
+			}
+		case ieVHTOperation:
+			if len(body) >= 3 {
+				widen(vhtWidth(body[0], body[1], body[2]))
+			}
+		case ieExtension:
+			if len(body) < 1 {
+				return
+			}
+			switch body[0] {
+			case extHECapability:
+				if network.Generation != "be" {
+					network.Generation = "ax"
+				}
+			case extEHTCapability:
+				network.Generation = "be"
+			case extHEOperation:
+				widen(heOperationWidth(body[1:]))
+			case extEHTOperation:
+				widen(ehtOperationWidth(body[1:]))
+			}
+		}
+	})
+	network.Hidden = hidden
+	if hidden {
+		network.SSID = ""
+	}
+
+	switch {
+	case rsn != nil:
+		network.AKMs = rsn.akms
+		network.Pairwise = rsn.pairwise
+		network.Group = rsn.group
+		network.PMFRequired = rsn.caps&rsnCapMFPR != 0
+		network.PMFCapable = rsn.caps&rsnCapMFPC != 0
+		network.Security = rsnSecurity(network)
+	case wpa != nil:
+		network.AKMs = wpa.akms
+		network.Pairwise = wpa.pairwise
+		network.Group = wpa.group
+		network.Security = "WPA"
+	}
+	return network
+}
+
+// rsnSecurity summarises the AKMs of an RSN network.
+func rsnSecurity(n types.WiFiNetwork) string {
+	sae := n.HasAKM("SAE", "SAE-EXT-KEY", "FT-SAE", "FT-SAE-EXT-KEY")
+	psk := n.HasAKM("WPA-PSK", "WPA-PSK-SHA256", "FT-PSK")
+	switch {
+	case sae && psk:
+		return "WPA2/WPA3"
+	case sae:
+		return "WPA3"
+	case psk:
+		return "WPA2"
+	case n.HasAKM("WPA-EAP-SUITE-B-192", "WPA-EAP-SUITE-B", "FT-EAP-SHA384"):
+		return "WPA3-Enterprise"
+	case n.HasAKM("WPA-EAP", "WPA-EAP-SHA256", "FT-EAP"):
+		return "WPA2-Enterprise"
+	case n.HasAKM("OWE"):
+		return "OWE"
+	}
+	return "WPA2"
+}
+
+// networksFromScan turns scan entries into WiFiNetworks, one per BSS,
+// strongest first.
+func networksFromScan(bsses []types.BSS) []types.WiFiNetwork {
+	networks := make([]types.WiFiNetwork, 0, len(bsses))
+	for _, bss := range bsses {
+		networks = append(networks, networkFromBSS(bss))
+	}
+	// dBm values are negative: closer to 0 is stronger.
+	sort.SliceStable(networks, func(i, j int) bool {
+		return networks[i].Signal > networks[j].Signal
+	})
+	return networks
+}
@@ -0,0 +1,114 @@
+package wifi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// bandRank orders the bands for prefer_band: a BSS is on the preferred band
+// when its band ranks at least as high ("5" also accepts 6 GHz).
+var bandRank = map[string]int{"2.4": 1, "5": 2, "6": 3}
+
+// SelectBSS returns the access point of ssid that policy prefers among
+// networks: the strongest, unless a BSS on the preferred band is at most
+// the band margin weaker, in which case the strongest of those.
+func SelectBSS(networks []types.WiFiNetwork, ssid string, policy types.RoamConfig) (types.WiFiNetwork, bool) {
+	var strongest, preferred *types.WiFiNetwork
+	for i := range networks {
+		n := &networks[i]
+		if n.SSID != ssid || n.Hidden {
+			continue
+		}
+		if strongest == nil || n.Signal > strongest.Signal {
+			strongest = n
+		}
+		if policy.PreferBand != "" && bandRank[n.Band()] >= bandRank[policy.PreferBand] &&
+			(preferred == nil || n.Signal > preferred.Signal) {
+			preferred = n
+		}
+	}
+	if strongest == nil {
+		return types.WiFiNetwork{}, false
+	}
+	if preferred != nil && preferred.Signal >= strongest.Signal-policy.GetBandMargin() {
+		return *preferred, true
+	}
+	return *strongest, true
+}
+
+// BestBSSID scans and returns the BSSID of ssid that policy prefers, or ""
+// when ssid isn't heard.
+func (m *Manager) BestBSSID(ssid string, policy types.RoamConfig) (string, error) {
+	networks, err := m.Scan()
+	if err != nil {
+		return "", err
+	}
+	best, ok := SelectBSS(networks, ssid, policy)
+	if !ok {
+		return "", nil
+	}
+	m.logger.Debug("Selected access point", "ssid", ssid, "bssid", best.BSSID, "signal", best.Signal, "band", best.Band())
+	return best.BSSID, nil
+}
+
+// Roam checks the association with ssid and, when its signal is below the
+// policy threshold, scans for an access point at least MinGain dB stronger
+// and reassociates to it through wpa_supplicant. The network block is
+// re-pinned to the new BSSID so wpa_supplicant stays there.
+func (m *Manager) Roam(ssid string, policy types.RoamConfig) (*types.RoamEvent, error) {
+	link, err := m.wireless.LinkInfo(m.iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read link info: %w", err)
+	}
+	if link == nil || link.SSID != ssid {
+		return nil, fmt.Errorf("not connected to %s", ssid)
+	}
+	if link.Signal == 0 || link.Signal >= policy.GetThreshold() {
+		return nil, nil
+	}
+
+	networks, err := m.Scan()
+	if err != nil {
+		return nil, err
+	}
+	// Only access points clearly better than the current one are candidates,
+	// so a weak link doesn't flap between two similar ones.
+	var candidates []types.WiFiNetwork
+	for _, n := range networks {
+		if !strings.EqualFold(n.BSSID, link.BSSID) && n.Signal >= link.Signal+policy.GetMinGain() {
+			candidates = append(candidates, n)
+		}
+	}
+	best, ok := SelectBSS(candidates, ssid, policy)
+	if !ok {
+		m.logger.Debug("No better access point", "ssid", ssid, "bssid", link.BSSID, "signal", link.Signal)
+		return nil, nil
+	}
+
+	bssid := strings.ToLower(best.BSSID)
+	if !isValidBSSID(bssid) {
+		return nil, fmt.Errorf("invalid BSSID in scan: %q", best.BSSID)
+	}
+	// Pin the network block wpa_supplicant is using now, whatever its id.
+	status, err := m.supplicant.Status(m.iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wpa_supplicant status: %w", err)
+	}
+	id, err := strconv.Atoi(status["id"])
+	if err != nil {
+		return nil, fmt.Errorf("wpa_supplicant reports no current network for %s", ssid)
+	}
+	if err := m.supplicant.SetNetwork(m.iface, id, "bssid", bssid); err != nil {
+		return nil, fmt.Errorf("failed to pin new access point: %w", err)
+	}
+	if err := m.supplicant.Reassociate(m.iface); err != nil {
+		return nil, fmt.Errorf("failed to reassociate: %w", err)
+	}
+	event := &types.RoamEvent{SSID: ssid, From: link.BSSID, FromSignal: link.Signal, To: bssid, ToSignal: best.Signal}
+	m.logger.Info("Roaming to a better access point", "ssid", ssid, "from", event.From, "fromSignal", event.FromSignal,
+		"to", event.To, "toSignal", event.ToSignal)
+	return event, nil
+}
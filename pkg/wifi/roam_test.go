@@ -0,0 +1,121 @@
+package wifi
+
+import (
+	"testing"
+
+	"github.com/angelfreak/net/pkg/netlink/fake"
+	"github.com/angelfreak/net/pkg/types"
+	wpafake "github.com/angelfreak/net/pkg/wpa/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+// margin returns a band_margin setting.
+func margin(db int) *int { return &db }
+
+func TestSelectBSS(t *testing.T) {
+	networks := []types.WiFiNetwork{
+		{SSID: "Office", BSSID: "aa:00:00:00:00:01", Signal: -50, Frequency: 2412},
+		{SSID: "Office", BSSID: "aa:00:00:00:00:02", Signal: -58, Frequency: 5180},
+		{SSID: "Office", BSSID: "aa:00:00:00:00:03", Signal: -65, Frequency: 5975},
+		{SSID: "Other", BSSID: "bb:00:00:00:00:01", Signal: -30, Frequency: 5180},
+	}
+	tests := []struct {
+		name   string
+		policy types.RoamConfig
+		want   string
+	}{
+		{"strongest without preference", types.RoamConfig{}, "aa:00:00:00:00:01"},
+		{"5 GHz within margin", types.RoamConfig{PreferBand: "5"}, "aa:00:00:00:00:02"},
+		{"5 GHz outside margin", types.RoamConfig{PreferBand: "5", BandMargin: margin(5)}, "aa:00:00:00:00:01"},
+		{"6 GHz within margin", types.RoamConfig{PreferBand: "6", BandMargin: margin(15)}, "aa:00:00:00:00:03"},
+		{"6 GHz outside default margin", types.RoamConfig{PreferBand: "6"}, "aa:00:00:00:00:01"},
+		{"zero margin", types.RoamConfig{PreferBand: "5", BandMargin: margin(0)}, "aa:00:00:00:00:01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, ok := SelectBSS(networks, "Office", tt.policy)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, best.BSSID)
+		})
+	}
+
+	_, ok := SelectBSS(networks, "Missing", types.RoamConfig{})
+	assert.False(t, ok)
+}
+
+func TestBestBSSID(t *testing.T) {
+	manager := NewManager(&mockSystemExecutor{}, &mockLogger{}, "wlan0", &mockDHCPClient{})
+	manager.linkMgr = &fake.LinkManager{}
+	manager.wireless = &fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {
+		fake.BSS("aa:00:00:00:00:01", "Office", 2412, -50),
+		fake.BSS("aa:00:00:00:00:02", "Office", 5180, -55),
+	}}}
+
+	bssid, err := manager.BestBSSID("Office", types.RoamConfig{PreferBand: "5"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aa:00:00:00:00:02", bssid)
+
+	bssid, err = manager.BestBSSID("Missing", types.RoamConfig{})
+	assert.NoError(t, err)
+	assert.Empty(t, bssid)
+}
+
+func TestRoam(t *testing.T) {
+	scan := []types.BSS{
+		fake.BSS("aa:00:00:00:00:01", "Office", 2412, -78),
+		fake.BSS("AA:00:00:00:00:02", "Office", 5180, -74),
+		fake.BSS("aa:00:00:00:00:03", "Office", 5200, -60),
+	}
+	newManager := func(signal int) (*Manager, *wpafake.Supplicant) {
+		supplicant := &wpafake.Supplicant{Statuses: []map[string]string{{"wpa_state": "COMPLETED", "id": "1"}}}
+		manager := NewManager(&mockSystemExecutor{}, &mockLogger{}, "wlan0", &mockDHCPClient{})
+		manager.supplicant = supplicant
+		manager.linkMgr = &fake.LinkManager{}
+		manager.wireless = &fake.WirelessManager{
+			Scans: map[string][]types.BSS{"wlan0": scan},
+			Links: map[string]*types.WirelessLink{"wlan0": {SSID: "Office", BSSID: "aa:00:00:00:00:01", Signal: signal}},
+		}
+		return manager, supplicant
+	}
+
+	t.Run("roams below threshold", func(t *testing.T) {
+		manager, supplicant := newManager(-78)
+		event, err := manager.Roam("Office", types.RoamConfig{})
+		assert.NoError(t, err)
+		assert.Equal(t, &types.RoamEvent{SSID: "Office", From: "aa:00:00:00:00:01", FromSignal: -78,
+			To: "aa:00:00:00:00:03", ToSignal: -60}, event)
+		assert.Equal(t, []wpafake.SetCall{{Iface: "wlan0", ID: 1, Name: "bssid", Value: "aa:00:00:00:00:03"}}, supplicant.Set)
+		assert.Equal(t, []string{"wlan0"}, supplicant.Reassociated)
+	})
+
+	t.Run("stays above threshold", func(t *testing.T) {
+		manager, supplicant := newManager(-65)
+		event, err := manager.Roam("Office", types.RoamConfig{})
+		assert.NoError(t, err)
+		assert.Nil(t, event)
+		assert.Empty(t, supplicant.Reassociated)
+	})
+
+	t.Run("stays without enough gain", func(t *testing.T) {
+		manager, supplicant := newManager(-78)
+		event, err := manager.Roam("Office", types.RoamConfig{MinGain: 20})
+		assert.NoError(t, err)
+		assert.Nil(t, event)
+		assert.Empty(t, supplicant.Reassociated)
+	})
+
+	t.Run("no current network block", func(t *testing.T) {
+		manager, supplicant := newManager(-78)
+		supplicant.Statuses = []map[string]string{{"wpa_state": "SCANNING"}}
+		_, err := manager.Roam("Office", types.RoamConfig{})
+		assert.ErrorContains(t, err, "no current network")
+		assert.Empty(t, supplicant.Set)
+		assert.Empty(t, supplicant.Reassociated)
+	})
+
+	t.Run("not connected to the network", func(t *testing.T) {
+		manager, _ := newManager(-78)
+		_, err := manager.Roam("Home", types.RoamConfig{})
+		assert.ErrorContains(t, err, "not connected to Home")
+	})
+}
@@ -1,43 +1,35 @@
 package wifi
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"regexp"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/angelfreak/net/pkg/netlink"
 	"github.com/angelfreak/net/pkg/system"
 	"github.com/angelfreak/net/pkg/types"
+	"github.com/angelfreak/net/pkg/wpa"
 )
 
 // Compiled regexes for parsing - initialized once at package load
 var (
-	// WiFi scan result parsing
-	ssidRegex   = regexp.MustCompile(`SSID: (.+)`)
-	bssidRegex  = regexp.MustCompile(`BSS ([0-9a-f:]+)`)
-	signalRegex = regexp.MustCompile(`signal: ([-\d.]+)`)
-	freqRegex   = regexp.MustCompile(`freq: (.+)`)
-
-	// SSID hex escape decoding
-	hexEscapeRegex = regexp.MustCompile(`\\x([0-9a-fA-F]{2})`)
-
-	// wpa_cli status parsing
-	wpaSSIDRegex  = regexp.MustCompile(`(?m)^ssid=(.+)$`)
-	wpaStateRegex = regexp.MustCompile(`(?m)^wpa_state=(.+)$`)
-	wpaBSSIDRegex = regexp.MustCompile(`(?m)^bssid=(.+)$`)
-
-	// IP address parsing
-	inetRegex = regexp.MustCompile(`inet (\d+\.\d+\.\d+\.\d+)`)
-
 	// BSSID validation - exactly 6 pairs of hex digits separated by colons
 	validBSSIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}$`)
 )
 
+// Association failures reported by wpa_supplicant. Connect errors wrap them,
+// so callers can tell them apart with errors.Is.
+var (
+	ErrWrongKey        = errors.New("wrong password")
+	ErrAuthTimeout     = errors.New("authentication timed out")
+	ErrNetworkNotFound = errors.New("access point not found")
+)
+
 // Manager implements the WiFiManager interface
 type Manager struct {
 	executor           types.SystemExecutor
@@ -45,11 +37,13 @@ type Manager struct {
 	iface              string
 	associationTimeout time.Duration // Configurable for testing, defaults to 30s
 	dhcpClient         types.DHCPClientManager
-	linkMgr            types.LinkManager  // netlink-backed link access (interface up/down)
-	addrMgr            types.AddrManager  // netlink-backed interface address access
-	routeMgr           types.RouteManager // netlink-backed routing table access
-	runtimeDir         string             // overridable for tests; defaults to types.RuntimeDir
-	resolvConfPath     string             // overridable for tests; defaults to /etc/resolv.conf
+	linkMgr            types.LinkManager      // netlink-backed link access (interface up/down)
+	addrMgr            types.AddrManager      // netlink-backed interface address access
+	routeMgr           types.RouteManager     // netlink-backed routing table access
+	wireless           types.WirelessManager  // nl80211-backed scanning and link info
+	supplicant         types.SupplicantClient // wpa_supplicant control socket
+	runtimeDir         string                 // overridable for tests; defaults to types.RuntimeDir
+	resolvConfPath     string                 // overridable for tests; defaults to /etc/resolv.conf
 }
 
 // NewManager creates a new WiFi manager
@@ -63,6 +57,8 @@ func NewManager(executor types.SystemExecutor, logger types.Logger, iface string
 		linkMgr:            netlink.NewLinkManager(),
 		addrMgr:            netlink.NewAddrManager(),
 		routeMgr:           netlink.NewRouteManager(),
+		wireless:           netlink.NewWirelessManager(),
+		supplicant:         wpa.NewClient(),
 		runtimeDir:         types.RuntimeDir,
 		resolvConfPath:     "/etc/resolv.conf",
 	}
@@ -77,14 +73,14 @@ func (m *Manager) resolvConf() string {
 	return m.resolvConfPath
 }
 
-// wpaConfigPath returns the path for the wpa_supplicant config file. The
-// runtime dir is overridable in tests.
+// wpaConfigPath returns the path for the wpa_supplicant config file of the
+// interface; each radio has its own. The runtime dir is overridable in tests.
 func (m *Manager) wpaConfigPath() string {
 	dir := m.runtimeDir
 	if dir == "" {
 		dir = types.RuntimeDir
 	}
-	return dir + "/wpa_supplicant.conf"
+	return dir + "/wpa_supplicant-" + m.iface + ".conf"
 }
 
 // SetAssociationTimeout configures the WiFi association timeout from user config.
@@ -104,20 +100,21 @@ func (m *Manager) Scan() ([]types.WiFiNetwork, error) {
 		m.logger.Warn("Failed to bring interface up", "error", err)
 	}
 
-	// Always trigger a fresh scan — cached results from scan dump may only
-	// contain the currently connected AP when connected to a network
-	_, err = m.executor.ExecuteWithTimeout(10*time.Second, "iw", m.iface, "scan")
-	if err != nil {
+	// Always trigger a fresh scan — the cache may only contain the currently
+	// connected AP when connected to a network
+	if err := m.wireless.TriggerScan(m.iface, 10*time.Second); err != nil {
 		m.logger.Warn("Fresh scan failed, falling back to cached results", "error", err)
 	}
 
 	// Read scan results (includes results from fresh scan above)
-	output, err := m.executor.ExecuteWithTimeout(5*time.Second, "iw", m.iface, "scan", "dump")
+	bsses, err := m.wireless.ScanResults(m.iface)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get scan results: %w", err)
 	}
 
-	return m.parseScanResults(output)
+	networks := networksFromScan(bsses)
+	m.logger.Debug("Parsed scan results", "bsses", len(bsses), "networks", len(networks))
+	return networks, nil
 }
 
 // Connect connects to a WiFi network without BSSID pinning
@@ -128,6 +125,18 @@ func (m *Manager) Connect(ssid, password, hostname string) error {
 // ConnectWithBSSID connects to a WiFi network with optional BSSID pinning
 // hostname is optional - if provided, it will be sent in DHCP requests without changing system hostname
 func (m *Manager) ConnectWithBSSID(ssid, password, bssid, hostname string) error {
+	return m.connect(ssid, password, bssid, hostname, false)
+}
+
+// ConnectHidden connects to a network that doesn't broadcast its SSID. It
+// isn't in the scan, so there's no security to detect: wpa_supplicant
+// probes for it (scan_ssid=1) with the universal network block, and "not
+// found" scans don't end the wait early.
+func (m *Manager) ConnectHidden(ssid, password, bssid, hostname string) error {
+	return m.connect(ssid, password, bssid, hostname, true)
+}
+
+func (m *Manager) connect(ssid, password, bssid, hostname string, hidden bool) error {
 	// Validate inputs
 	if err := types.ValidateSSID(ssid); err != nil {
 		return fmt.Errorf("invalid SSID: %w", err)
@@ -143,9 +152,9 @@ func (m *Manager) ConnectWithBSSID(ssid, password, bssid, hostname string) error
 
 	var err error
 	if bssid != "" {
-		m.logger.Info("Connecting to WiFi network with BSSID pinning", "ssid", ssid, "bssid", bssid, "interface", m.iface)
+		m.logger.Info("Connecting to WiFi network with BSSID pinning", "ssid", ssid, "bssid", bssid, "interface", m.iface, "hidden", hidden)
 	} else {
-		m.logger.Info("Connecting to WiFi network", "ssid", ssid, "interface", m.iface)
+		m.logger.Info("Connecting to WiFi network", "ssid", ssid, "interface", m.iface, "hidden", hidden)
 	}
 
 	// Only disconnect if connected to a different network
@@ -156,11 +165,17 @@ func (m *Manager) ConnectWithBSSID(ssid, password, bssid, hostname string) error
 		_ = m.Disconnect()
 	}
 
-	// Detect AP security type from cached scan results to generate the correct
+	// Detect AP security from cached scan results to generate the correct
 	// wpa_supplicant config (WPA3 needs SAE key_mgmt and required PMF)
-	security := m.detectNetworkSecurity(ssid)
-	if security != "" {
-		m.logger.Debug("Detected AP security type", "ssid", ssid, "security", security)
+	var scanned *types.WiFiNetwork
+	if !hidden {
+		scanned = m.detectNetworkSecurity(ssid, bssid)
+	}
+	security := ""
+	if scanned != nil {
+		security = scanned.Security
+		m.logger.Debug("Detected AP security type", "ssid", ssid, "security", security,
+			"akms", scanned.AKMs, "pmfRequired", scanned.PMFRequired)
 	}
 
 	// Reject WEP networks - insecure and not supported
@@ -168,30 +183,64 @@ func (m *Manager) ConnectWithBSSID(ssid, password, bssid, hostname string) error
 		return fmt.Errorf("WEP networks are not supported: WEP encryption is broken and insecure, use WPA2 or WPA3 instead")
 	}
 
-	// Warn if AP requires encryption but no password was provided
-	if password == "" && (security == "WPA3" || security == "WPA2/WPA3" || security == "WPA2") {
+	// Warn if AP requires a passphrase but none was provided
+	if password == "" && scanned != nil && scanned.NeedsPassphrase() {
 		m.logger.Warn("Network requires encryption but no password provided - check config file (YAML '#' starts a comment, quote passwords containing '#')", "ssid", ssid, "security", security)
 	}
 
 	// Create wpa_supplicant config with optional BSSID pinning
-	config := m.generateWPAConfig(ssid, password, bssid, security)
+	config := m.generateWPAConfig(ssid, password, bssid, scanned)
 	// Don't log config - it contains credentials
 	m.logger.Debug("Generated WPA config", "ssid", ssid, "hasBSSID", bssid != "", "security", security)
 
+	cleanup, err := m.startSupplicant(config)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Wait for association with the access point
+	err = m.waitForAssociation(ssid, hidden)
+	if err != nil {
+		// Clean up wpa_supplicant on failure (interface-specific)
+		m.terminateWpaSupplicant()
+		if security == "WPA3" && strings.Contains(err.Error(), "crashed") {
+			return fmt.Errorf("WPA3 (SAE) connection failed — wpa_supplicant crashed. Your wpa_supplicant may not support SAE. Check with: wpa_supplicant -h 2>&1 | grep SAE")
+		}
+		return fmt.Errorf("failed to associate with access point: %w", err)
+	}
+
+	// Get DHCP lease with optional hostname
+	err = m.obtainDHCP(hostname)
+	if err != nil {
+		// Clean up wpa_supplicant on failure (interface-specific)
+		m.terminateWpaSupplicant()
+		return fmt.Errorf("failed to obtain DHCP lease: %w", err)
+	}
+
+	// Skip captive portal check - it adds unnecessary delay
+	// Users can manually check if they suspect a captive portal
+
+	m.logger.Debug("Successfully connected to WiFi network", "ssid", ssid)
+	return nil
+}
+
+// startSupplicant writes config and (re)starts wpa_supplicant with it on a
+// clean interface. The returned cleanup removes the config file, which
+// holds credentials, once wpa_supplicant has read it.
+func (m *Manager) startSupplicant(config string) (func(), error) {
 	// Write config to temp file in secure runtime directory
 	tempConfig := m.wpaConfigPath()
 	// Remove any existing file to avoid permission issues
-	if err = os.Remove(tempConfig); err != nil && !os.IsNotExist(err) {
+	if err := os.Remove(tempConfig); err != nil && !os.IsNotExist(err) {
 		m.logger.Warn("Failed to remove old config file", "error", err)
 	}
-	err = m.writeFile(tempConfig, config)
-	if err != nil {
-		return fmt.Errorf("failed to write WPA config: %w", err)
+	if err := m.writeFile(tempConfig, config); err != nil {
+		return nil, fmt.Errorf("failed to write WPA config: %w", err)
 	}
-	// Clean up credentials file after wpa_supplicant reads it
-	defer func() {
+	cleanup := func() {
 		_ = os.Remove(tempConfig)
-	}()
+	}
 
 	// Terminate existing wpa_supplicant for this interface only
 	m.terminateWpaSupplicant()
@@ -206,50 +255,26 @@ func (m *Manager) ConnectWithBSSID(ssid, password, bssid, hostname string) error
 	m.routeMgr.FlushRoutes(m.iface)
 
 	// Bring interface up before starting wpa_supplicant
-	err = m.linkMgr.SetUp(m.iface)
-	if err != nil {
-		return fmt.Errorf("failed to bring interface up: %w", err)
+	if err := m.linkMgr.SetUp(m.iface); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to bring interface up: %w", err)
 	}
 
 	// Ensure wpa_supplicant control directory exists
-	_ = os.MkdirAll("/run/wpa_supplicant", 0755)
+	_ = os.MkdirAll(wpa.CtrlDir, 0755)
 
 	// Start wpa_supplicant — ctrl_interface is set in the config file,
 	// don't also pass -C which can conflict and cause crashes with SAE.
-	_, err = m.executor.Execute("wpa_supplicant", "-B", "-i", m.iface, "-c", tempConfig)
-	if err != nil {
-		return fmt.Errorf("failed to start wpa_supplicant: %w", err)
+	if _, err := m.executor.Execute("wpa_supplicant", "-B", "-i", m.iface, "-c", tempConfig); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to start wpa_supplicant: %w", err)
 	}
 
 	// Wait for wpa_supplicant to be ready (polls up to 1 second, usually ready in <100ms)
 	if !m.waitForWpaSupplicantReady(1 * time.Second) {
 		m.logger.Warn("wpa_supplicant may not be fully ready, proceeding anyway")
 	}
-
-	// Wait for association with the access point
-	err = m.waitForAssociation(ssid)
-	if err != nil {
-		// Clean up wpa_supplicant on failure (interface-specific)
-		m.terminateWpaSupplicant()
-		if security == "WPA3" && strings.Contains(err.Error(), "crashed") {
-			return fmt.Errorf("WPA3 (SAE) connection failed — wpa_supplicant crashed. Your wpa_supplicant may not support SAE. Check with: wpa_supplicant -h 2>&1 | grep SAE")
-		}
-		return fmt.Errorf("failed to associate with access point: %w", err)
-	}
-
-	// Get DHCP lease with optional hostname
-	err = m.obtainDHCP(hostname)
-	if err != nil {
-		// Clean up wpa_supplicant on failure (interface-specific)
-		m.terminateWpaSupplicant()
-		return fmt.Errorf("failed to obtain DHCP lease: %w", err)
-	}
-
-	// Skip captive portal check - it adds unnecessary delay
-	// Users can manually check if they suspect a captive portal
-
-	m.logger.Debug("Successfully connected to WiFi network", "ssid", ssid)
-	return nil
+	return cleanup, nil
 }
 
 // Disconnect disconnects from the current WiFi network
@@ -280,16 +305,51 @@ func (m *Manager) Disconnect() error {
 	return nil
 }
 
-// ListConnections lists current network connections
+// ListConnections lists the managed interface first, then the other station
+// and access point interfaces of all radios, each with its association.
 func (m *Manager) ListConnections() ([]types.Connection, error) {
 	m.logger.Debug("Listing network connections")
 
-	var connections []types.Connection
+	own, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+	connections := []types.Connection{own}
+
+	radios, err := m.wireless.Interfaces()
+	if err != nil {
+		m.logger.Debug("Failed to list wireless interfaces", "error", err)
+		return connections, nil
+	}
+	for _, wi := range radios {
+		radio := fmt.Sprintf("phy#%d", wi.PHY)
+		if wi.Name == m.iface {
+			connections[0].Radio = radio
+			continue
+		}
+		if wi.Type != "station" && wi.Type != "ap" {
+			continue
+		}
+		conn, err := m.forInterface(wi.Name).connection()
+		if err != nil {
+			m.logger.Debug("Failed to get connection", "interface", wi.Name, "error", err)
+			continue
+		}
+		conn.Radio = radio
+		if wi.Type == "ap" {
+			conn.SSID, conn.State = wi.SSID, "access point"
+		}
+		connections = append(connections, conn)
+	}
+	return connections, nil
+}
 
+// connection returns the state of the managed interface.
+func (m *Manager) connection() (types.Connection, error) {
 	// Get IP address (netlink)
 	ip, err := m.addrMgr.GetFirstIPv4(m.iface)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get IP addresses: %w", err)
+		return types.Connection{}, fmt.Errorf("failed to get IP addresses: %w", err)
 	}
 
 	// Get the interface's default-route gateway (netlink)
@@ -300,10 +360,12 @@ func (m *Manager) ListConnections() ([]types.Connection, error) {
 		gateway = net.ParseIP(route.Gw)
 	}
 
-	// Get current SSID
-	ssid, err := m.getCurrentSSID()
-	if err != nil {
+	// Get the current association
+	var ssid, bssid string
+	if link, err := m.wireless.LinkInfo(m.iface); err != nil {
 		m.logger.Debug("Failed to get current SSID", "error", err)
+	} else if link != nil {
+		ssid, bssid = link.SSID, link.BSSID
 	}
 
 	// Get DNS servers
@@ -317,17 +379,15 @@ func (m *Manager) ListConnections() ([]types.Connection, error) {
 		state = "connected"
 	}
 
-	connection := types.Connection{
+	return types.Connection{
 		Interface: m.iface,
 		SSID:      ssid,
 		State:     state,
 		IP:        ip,
 		Gateway:   gateway,
 		DNS:       dns,
-	}
-
-	connections = append(connections, connection)
-	return connections, nil
+		BSSID:     bssid,
+	}, nil
 }
 
 // GetInterface returns the managed interface name
@@ -335,115 +395,24 @@ func (m *Manager) GetInterface() string {
 	return m.iface
 }
 
-// Helper functions
-
-func (m *Manager) parseScanResults(output string) ([]types.WiFiNetwork, error) {
-	if m.logger != nil {
-		m.logger.Debug("Parsing scan results", "output", output)
-	}
-	networksMap := make(map[string]*types.WiFiNetwork)
-	lines := strings.Split(output, "\n")
-
-	var currentNetwork *types.WiFiNetwork
-	var currentSecurity string
-	var inRSN bool
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "BSS ") {
-			// Save previous network
-			if currentNetwork != nil && currentNetwork.SSID != "" {
-				currentNetwork.Security = currentSecurity
-				if existing, ok := networksMap[currentNetwork.SSID]; !ok || currentNetwork.Signal > existing.Signal {
-					networksMap[currentNetwork.SSID] = currentNetwork
-				}
-				if m.logger != nil {
-					m.logger.Debug("Parsed network", "ssid", currentNetwork.SSID, "bssid", currentNetwork.BSSID, "signal", currentNetwork.Signal, "freq", currentNetwork.Frequency, "security", currentNetwork.Security)
-				}
-			}
-			currentNetwork = &types.WiFiNetwork{}
-			currentSecurity = "Open"
-			inRSN = false
-			if match := bssidRegex.FindStringSubmatch(line); len(match) > 1 {
-				currentNetwork.BSSID = match[1]
-				if m.logger != nil {
-					m.logger.Debug("Found BSSID", "bssid", match[1])
-				}
-			}
-		} else if strings.HasPrefix(line, "SSID: ") {
-			if match := ssidRegex.FindStringSubmatch(line); len(match) > 1 {
-				currentNetwork.SSID = strings.TrimSpace(match[1])
-				currentNetwork.SSID = m.decodeSSID(currentNetwork.SSID)
-				if m.logger != nil {
-					m.logger.Debug("Found SSID", "ssid", match[1])
-				}
-			}
-		} else if strings.HasPrefix(line, "signal: ") {
-			if match := signalRegex.FindStringSubmatch(line); len(match) > 1 {
-				if m.logger != nil {
-					m.logger.Debug("Signal match", "raw", match[1])
-				}
-				if signal, err := strconv.ParseFloat(match[1], 64); err == nil {
-					currentNetwork.Signal = int(signal)
-					if m.logger != nil {
-						m.logger.Debug("Parsed signal", "signal", signal)
-					}
-				} else {
-					if m.logger != nil {
-						m.logger.Warn("Failed to parse signal", "raw", match[1], "error", err)
-					}
-				}
-			}
-		} else if strings.HasPrefix(line, "freq: ") {
-			if match := freqRegex.FindStringSubmatch(line); len(match) > 1 {
-				if freq, err := strconv.Atoi(match[1]); err == nil {
-					currentNetwork.Frequency = freq
-					if m.logger != nil {
-						m.logger.Debug("Parsed freq", "freq", freq)
-					}
-				}
-			}
-		} else if strings.Contains(line, "RSN:") {
-			inRSN = true
-			currentSecurity = "WPA2" // default RSN = WPA2, may upgrade to WPA3
-		} else if strings.Contains(line, "WPA:") {
-			inRSN = false
-			currentSecurity = "WPA"
-		} else if strings.Contains(line, "WEP:") {
-			inRSN = false
-			currentSecurity = "WEP"
-		} else if inRSN && strings.Contains(line, "Authentication suites:") {
-			if strings.Contains(line, "SAE") && strings.Contains(line, "PSK") {
-				currentSecurity = "WPA2/WPA3"
-			} else if strings.Contains(line, "SAE") {
-				currentSecurity = "WPA3"
-			}
-			// PSK-only stays as "WPA2" (set when RSN: was seen)
-		}
-	}
-
-	if currentNetwork != nil && currentNetwork.SSID != "" {
-		currentNetwork.Security = currentSecurity
-		if existing, ok := networksMap[currentNetwork.SSID]; !ok || currentNetwork.Signal > existing.Signal {
-			networksMap[currentNetwork.SSID] = currentNetwork
-		}
-	}
+// ForInterface returns the manager of radio iface. It shares m's executor,
+// DHCP client, timeouts and netlink handles; wpa_supplicant, its config file
+// and control socket are per interface, so both can be connected at once.
+func (m *Manager) ForInterface(iface string) types.WiFiManager {
+	return m.forInterface(iface)
+}
 
-	var networks []types.WiFiNetwork
-	for _, net := range networksMap {
-		networks = append(networks, *net)
+func (m *Manager) forInterface(iface string) *Manager {
+	if iface == "" || iface == m.iface {
+		return m
 	}
-
-	// Sort networks by signal strength (strongest first)
-	// Since dBm values are negative, higher values (closer to 0) are stronger
-	sort.Slice(networks, func(i, j int) bool {
-		return networks[i].Signal > networks[j].Signal
-	})
-
-	return networks, nil
+	other := *m
+	other.iface = iface
+	return &other
 }
 
+// Helper functions
+
 // escapeWPAString escapes special characters for wpa_supplicant config values
 // This prevents injection attacks via specially crafted SSIDs/passwords
 func escapeWPAString(s string) string {
@@ -463,14 +432,24 @@ func isValidBSSID(bssid string) bool {
 	return validBSSIDRegex.MatchString(bssid)
 }
 
-// detectNetworkSecurity determines the security type of a given SSID.
-// Tries cached scan results first (fast), falls back to a fresh scan if
+// isRawPSK reports whether key is a 256-bit PSK in hex rather than a
+// passphrase (which is at most 63 characters).
+func isRawPSK(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// detectNetworkSecurity returns the scanned BSS of ssid — the pinned bssid
+// when given and heard, otherwise the strongest — or nil if it isn't in the
+// scan. Tries cached scan results first (fast), falls back to a fresh scan if
 // the cache is empty (e.g. after interface was cycled for MAC change).
-// Returns "WPA3", "WPA2/WPA3", "WPA2", or "" if not found.
-func (m *Manager) detectNetworkSecurity(ssid string) string {
+func (m *Manager) detectNetworkSecurity(ssid, bssid string) *types.WiFiNetwork {
 	// Try cached results first (instant)
-	if sec := m.findSecurityInScan(ssid, "iw", m.iface, "scan", "dump"); sec != "" {
-		return sec
+	if network := m.findNetworkInScan(ssid, bssid); network != nil {
+		return network
 	}
 
 	// Cache was empty or SSID not found — do a fresh scan.
@@ -480,31 +459,51 @@ func (m *Manager) detectNetworkSecurity(ssid string) string {
 	// Brief delay for the driver to initialize after interface up
 	time.Sleep(200 * time.Millisecond)
 	m.logger.Debug("Scan cache empty, running fresh scan for security detection")
-	return m.findSecurityInScan(ssid, "iw", m.iface, "scan")
-}
-
-// findSecurityInScan runs the given iw scan command and returns the security
-// type for the given SSID, or "" if not found.
-func (m *Manager) findSecurityInScan(ssid string, cmd ...string) string {
-	output, err := m.executor.ExecuteWithTimeout(10*time.Second, cmd[0], cmd[1:]...)
-	if err != nil {
+	if err := m.wireless.TriggerScan(m.iface, 10*time.Second); err != nil {
 		m.logger.Debug("Scan failed during security detection", "error", err)
-		return ""
+		return nil
 	}
-	networks, err := m.parseScanResults(output)
+	return m.findNetworkInScan(ssid, bssid)
+}
+
+// DetectNetwork returns the strongest scanned BSS of ssid, or nil when it
+// isn't heard.
+func (m *Manager) DetectNetwork(ssid string) *types.WiFiNetwork {
+	return m.detectNetworkSecurity(ssid, "")
+}
+
+// findNetworkInScan returns the BSS of ssid in the scan cache (see
+// detectNetworkSecurity), or nil if not found.
+func (m *Manager) findNetworkInScan(ssid, bssid string) *types.WiFiNetwork {
+	bsses, err := m.wireless.ScanResults(m.iface)
 	if err != nil {
-		m.logger.Debug("Failed to parse scan results for security detection", "error", err)
-		return ""
+		m.logger.Debug("Failed to read scan results for security detection", "error", err)
+		return nil
 	}
-	for _, net := range networks {
-		if net.SSID == ssid {
-			return net.Security
+	var found *types.WiFiNetwork
+	for _, net := range networksFromScan(bsses) {
+		if net.SSID != ssid {
+			continue
+		}
+		if strings.EqualFold(net.BSSID, bssid) {
+			return &net
+		}
+		if found == nil {
+			found = &net // strongest first
 		}
 	}
-	return ""
+	return found
 }
 
-func (m *Manager) generateWPAConfig(ssid, password string, bssid string, security ...string) string {
+// supplicantCiphers are the cipher suites wpa_supplicant accepts for
+// pairwise= and group=.
+var supplicantCiphers = map[string]bool{"CCMP": true, "TKIP": true, "GCMP": true, "GCMP-256": true, "CCMP-256": true}
+
+// generateWPAConfig returns the wpa_supplicant config for ssid. The key
+// management, ciphers and PMF setting follow the AKMs and RSN capabilities
+// the AP advertised in scanned; with no scan (nil) or no usable AKM, a
+// universal WPA/WPA2/WPA3 network block is written instead.
+func (m *Manager) generateWPAConfig(ssid, password string, bssid string, scanned *types.WiFiNetwork) string {
 	// Escape SSID and password to prevent injection
 	escapedSSID := escapeWPAString(ssid)
 
@@ -517,12 +516,18 @@ func (m *Manager) generateWPAConfig(ssid, password string, bssid string, securit
 		m.logger.Warn("Invalid BSSID format, ignoring", "bssid", bssid)
 	}
 
-	// ctrl_interface is required for wpa_cli communication
+	// ctrl_interface is required for the control socket we talk to
 	header := "ctrl_interface=/run/wpa_supplicant\n"
 
 	if password == "" {
-		// Open network — include scan_ssid=1 so hidden networks are probed
-		config := header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\tkey_mgmt=NONE\n\tscan_ssid=1", escapedSSID)
+		// Open network — include scan_ssid=1 so hidden networks are probed.
+		// Opportunistic Wireless Encryption is open but encrypted, and
+		// requires PMF.
+		keyMgmt := "key_mgmt=NONE"
+		if scanned != nil && scanned.HasAKM("OWE") {
+			keyMgmt = "key_mgmt=OWE\n\tproto=RSN\n\tieee80211w=2"
+		}
+		config := header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\t%s\n\tscan_ssid=1", escapedSSID, keyMgmt)
 		if validatedBSSID != "" {
 			config += fmt.Sprintf("\n\tbssid=%s", validatedBSSID)
 		}
@@ -530,10 +535,12 @@ func (m *Manager) generateWPAConfig(ssid, password string, bssid string, securit
 		return config
 	}
 
-	escapedPassword := escapeWPAString(password)
-	sec := ""
-	if len(security) > 0 {
-		sec = security[0]
+	// A 64-hex-digit key is the raw PSK (as WPS may provision it), written
+	// unquoted; SAE has no equivalent, so it's WPA-PSK only.
+	pskValue := fmt.Sprintf("\"%s\"", escapeWPAString(password))
+	rawPSK := isRawPSK(password)
+	if rawPSK {
+		pskValue = strings.ToLower(password)
 	}
 
 	// SAE (WPA3) needs sae_pwe in the global section for driver compatibility.
@@ -544,28 +551,72 @@ func (m *Manager) generateWPAConfig(ssid, password string, bssid string, securit
 	// offers SAE when security type is unknown.
 	header += "sae_pwe=2\n"
 
+	// The passphrase AKMs the AP advertises, in our preference order.
+	var keyMgmt []string
+	psk, sae := false, false
+	if scanned != nil {
+		for _, akm := range types.PassphraseAKMs {
+			if scanned.HasAKM(akm) && !(rawPSK && strings.Contains(akm, "SAE")) {
+				keyMgmt = append(keyMgmt, akm)
+				if strings.Contains(akm, "SAE") {
+					sae = true
+				} else {
+					psk = true
+				}
+			}
+		}
+		if len(keyMgmt) == 0 && len(scanned.AKMs) > 0 {
+			m.logger.Warn("Network advertises no passphrase authentication, trying WPA-PSK/SAE anyway", "ssid", ssid, "akms", scanned.AKMs)
+		}
+	}
+
 	var config string
-	switch sec {
-	case "WPA3":
-		// WPA3-only: SAE authentication with required PMF.
-		// scan_ssid=1 triggers active probing — needed for iPhone hotspots
-		// which can behave like hidden networks when the hotspot screen isn't open.
-		// proto=RSN and pairwise=CCMP are required for SAE.
-		config = header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\tscan_ssid=1\n\tsae_password=\"%s\"\n\tkey_mgmt=SAE\n\tproto=RSN\n\tpairwise=CCMP\n\tgroup=CCMP\n\tieee80211w=2",
-			escapedSSID, escapedPassword)
-	case "WPA2/WPA3":
-		// Transition mode: WPA-PSK-SHA256 works better than WPA-PSK for
-		// mixed-mode APs that require management frame protection.
-		config = header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\tscan_ssid=1\n\tpsk=\"%s\"\n\tsae_password=\"%s\"\n\tkey_mgmt=WPA-PSK-SHA256 SAE\n\tproto=RSN\n\tpairwise=CCMP\n\tgroup=CCMP\n\tieee80211w=1",
-			escapedSSID, escapedPassword, escapedPassword)
-	default:
-		// WPA2 or unknown: offer WPA-PSK first (most compatible), then
+	if len(keyMgmt) == 0 && rawPSK {
+		config = header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\tscan_ssid=1\n\tpsk=%s\n\tkey_mgmt=WPA-PSK WPA-PSK-SHA256\n\tproto=RSN WPA\n\tpairwise=CCMP TKIP\n\tgroup=CCMP TKIP\n\tieee80211w=1",
+			escapedSSID, pskValue)
+	} else if len(keyMgmt) == 0 {
+		// Unknown: offer WPA-PSK first (most compatible), then
 		// WPA-PSK-SHA256 and SAE for transition/WPA3 APs.
 		// WPA-PSK must be listed so pure WPA2 APs (which only advertise PSK)
 		// can negotiate successfully.
 		// ieee80211w=1 (optional PMF) allows both PMF and non-PMF APs.
+		escapedPassword := escapeWPAString(password)
 		config = header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\tscan_ssid=1\n\tpsk=\"%s\"\n\tsae_password=\"%s\"\n\tkey_mgmt=WPA-PSK WPA-PSK-SHA256 SAE\n\tproto=RSN WPA\n\tpairwise=CCMP TKIP\n\tgroup=CCMP TKIP\n\tieee80211w=1",
 			escapedSSID, escapedPassword, escapedPassword)
+	} else {
+		// scan_ssid=1 triggers active probing — needed for iPhone hotspots
+		// which can behave like hidden networks when the hotspot screen isn't open.
+		config = header + fmt.Sprintf("\nnetwork={\n\tssid=\"%s\"\n\tscan_ssid=1", escapedSSID)
+		if psk {
+			config += "\n\tpsk=" + pskValue
+		}
+		if sae {
+			config += fmt.Sprintf("\n\tsae_password=\"%s\"", escapeWPAString(password))
+		}
+		proto := "RSN"
+		if scanned.Security == "WPA" {
+			proto = "WPA"
+		}
+		config += fmt.Sprintf("\n\tkey_mgmt=%s\n\tproto=%s", strings.Join(keyMgmt, " "), proto)
+		var pairwise []string
+		for _, cipher := range scanned.Pairwise {
+			if supplicantCiphers[cipher] {
+				pairwise = append(pairwise, cipher)
+			}
+		}
+		if len(pairwise) > 0 {
+			config += "\n\tpairwise=" + strings.Join(pairwise, " ")
+		}
+		if supplicantCiphers[scanned.Group] {
+			config += "\n\tgroup=" + scanned.Group
+		}
+		// PMF: required when the AP requires it (always for WPA3-only),
+		// otherwise optional so non-PMF clients of a mixed AP still work.
+		pmf := 1
+		if scanned.PMFRequired {
+			pmf = 2
+		}
+		config += fmt.Sprintf("\n\tieee80211w=%d", pmf)
 	}
 
 	if validatedBSSID != "" {
@@ -580,21 +631,14 @@ func (m *Manager) obtainDHCP(hostname string) error {
 }
 
 func (m *Manager) getCurrentSSID() (string, error) {
-	// Use 2s timeout - this is a simple query that completes in <100ms
-	output, err := m.executor.ExecuteWithTimeout(2*time.Second, "iw", m.iface, "link")
+	link, err := m.wireless.LinkInfo(m.iface)
 	if err != nil {
 		return "", err
 	}
-
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "SSID: ") {
-			ssid := strings.TrimPrefix(line, "SSID: ")
-			return m.decodeSSID(ssid), nil
-		}
+	if link == nil || link.SSID == "" {
+		return "", fmt.Errorf("SSID not found")
 	}
-	return "", fmt.Errorf("SSID not found")
+	return link.SSID, nil
 }
 
 // otherInterfaceAssociated reports whether any wireless interface OTHER than
@@ -602,19 +646,13 @@ func (m *Manager) getCurrentSSID() (string, error) {
 // global wpa_supplicant kill so we don't drop another interface's connection.
 // On any error enumerating interfaces it returns true (fail safe: don't kill).
 func (m *Manager) otherInterfaceAssociated() bool {
-	output, err := m.executor.ExecuteWithTimeout(2*time.Second, "iw", "dev")
+	ifaces, err := m.wireless.Interfaces()
 	if err != nil {
 		return true
 	}
-	var iface string
-	for _, line := range strings.Split(output, "\n") {
-		fields := strings.Fields(line)
-		if len(fields) >= 2 && fields[0] == "Interface" {
-			iface = fields[1]
-			continue
-		}
-		// "ssid <name>" appears under an Interface stanza only when associated.
-		if iface != "" && iface != m.iface && len(fields) >= 1 && fields[0] == "ssid" {
+	for _, wi := range ifaces {
+		// nl80211 reports an SSID for a station only while associated.
+		if wi.Name != m.iface && wi.Type == "station" && wi.SSID != "" {
 			return true
 		}
 	}
@@ -628,18 +666,6 @@ func (m *Manager) getDNSServers() ([]net.IP, error) {
 	}
 	return system.ParseDNSFromResolvConf(output), nil
 }
-func (m *Manager) decodeSSID(ssid string) string {
-	// Use package-level compiled regex for better performance
-	result := hexEscapeRegex.ReplaceAllStringFunc(ssid, func(match string) string {
-		hex := match[2:] // remove \x
-		b, err := strconv.ParseUint(hex, 16, 8)
-		if err != nil {
-			return match // if invalid, keep as is
-		}
-		return string(byte(b))
-	})
-	return result
-}
 
 // writeFile writes content to a file with secure permissions (0600)
 // Uses install command to atomically create file with correct permissions
@@ -663,20 +689,20 @@ func (m *Manager) killProcess(pattern string) {
 }
 
 // terminateWpaSupplicant terminates wpa_supplicant for this interface only
-// Uses wpa_cli terminate for graceful shutdown, with pkill fallback
+// Uses TERMINATE on the control socket for graceful shutdown, with pkill fallback
 func (m *Manager) terminateWpaSupplicant() {
 	// Tell NetworkManager to stop managing this interface.
 	// This prevents NM from restarting wpa_supplicant after we kill it.
 	// Fails silently if nmcli is not installed (non-NM systems).
 	m.executor.ExecuteWithTimeout(2*time.Second, "nmcli", "device", "set", m.iface, "managed", "no")
 
-	// Try graceful termination via wpa_cli (interface-specific).
+	// Try graceful termination via the control socket (interface-specific).
 	// This reaches both standalone and system (D-Bus) wpa_supplicant instances.
-	_, err := m.executor.ExecuteWithTimeout(2*time.Second, "wpa_cli", "-i", m.iface, "terminate")
+	err := m.supplicant.Terminate(m.iface)
 	if err != nil {
 		// Fallback: try interface-specific kill first by matching the -i flag
 		_, err2 := m.executor.ExecuteWithTimeout(500*time.Millisecond,
-			"pkill", "-9", "-f", fmt.Sprintf("wpa_supplicant.*-i.*%s", m.iface))
+			"pkill", "-9", "-f", wpaSupplicantPattern(m.iface))
 		if err2 != nil {
 			// Last resort: kill the system wpa_supplicant (started via -u -s for
 			// D-Bus) which doesn't use -i flag. Required to avoid nl80211 "Match
@@ -699,7 +725,13 @@ func (m *Manager) terminateWpaSupplicant() {
 	// Remove stale control socket — after suspend/resume the old wpa_supplicant
 	// process is gone but its socket file remains, causing the new instance to
 	// fail with exit code 255
-	_ = os.Remove(fmt.Sprintf("/run/wpa_supplicant/%s", m.iface))
+	_ = os.Remove(fmt.Sprintf("%s/%s", wpa.CtrlDir, m.iface))
+}
+
+// wpaSupplicantPattern is the pkill pattern of the wpa_supplicant started
+// with -i iface: anchored after the name, so wlan1 doesn't match wlan10.
+func wpaSupplicantPattern(iface string) string {
+	return fmt.Sprintf("wpa_supplicant.*-i ?%s( |$)", regexp.QuoteMeta(iface))
 }
 
 // terminateDhcpClients terminates all DHCP clients (dhclient and udhcpc) for this interface
@@ -707,17 +739,14 @@ func (m *Manager) terminateDhcpClients() {
 	m.dhcpClient.Release(m.iface)
 }
 
-// waitForWpaSupplicantReady polls until wpa_supplicant responds to wpa_cli
-// Returns true if ready within timeout, false otherwise
+// waitForWpaSupplicantReady polls until wpa_supplicant answers STATUS on its
+// control socket. Returns true if ready within timeout, false otherwise
 func (m *Manager) waitForWpaSupplicantReady(timeout time.Duration) bool {
 	deadline := time.Now().Add(timeout)
 	pollInterval := 50 * time.Millisecond // Fast polling since wpa_supplicant is usually quick
 
 	for time.Now().Before(deadline) {
-		// wpa_cli status returns 0 when wpa_supplicant is responsive
-		// Use 2s timeout - wpa_cli typically responds in <100ms
-		_, err := m.executor.ExecuteWithTimeout(2*time.Second, "wpa_cli", "-i", m.iface, "status")
-		if err == nil {
+		if _, err := m.supplicant.Status(m.iface); err == nil {
 			return true
 		}
 		time.Sleep(pollInterval)
@@ -725,62 +754,106 @@ func (m *Manager) waitForWpaSupplicantReady(timeout time.Duration) bool {
 	return false
 }
 
-func (m *Manager) waitForAssociation(expectedSSID string) error {
+// waitForAssociation waits until wpa_supplicant is associated with
+// expectedSSID. For a hidden network, scans that don't find it are expected
+// and don't end the wait.
+func (m *Manager) waitForAssociation(expectedSSID string, hidden bool) error {
 	timeout := m.associationTimeout
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
-	// Try event-based waiting first (faster)
+	// Wait for events (no polling delay); fall back to polling STATUS when
+	// the event connection can't be set up or breaks.
 	start := time.Now()
-	err := m.waitForAssociationEvents(expectedSSID, timeout)
+	monitor, err := m.supplicant.Attach(m.iface)
 	if err == nil {
-		return nil
+		err = m.waitForAssociationEvents(monitor, expectedSSID, timeout, hidden)
+		monitor.Close()
+		if !errors.Is(err, errEventsLost) {
+			return err
+		}
 	}
 
-	// Event-based failed (likely wpa_cli doesn't support wait_event), fall back to polling.
 	// Subtract elapsed time so total wait doesn't exceed the configured timeout.
 	remaining := timeout - time.Since(start)
 	if remaining <= 0 {
-		return fmt.Errorf("timeout waiting for association with %q", expectedSSID)
+		return m.associationTimeoutError(expectedSSID, false)
 	}
 	m.logger.Debug("Event-based association wait failed, using polling", "error", err, "remaining", remaining)
 	return m.waitForAssociationPolling(expectedSSID, remaining)
 }
 
-// waitForAssociationEvents uses wpa_cli wait_event for instant notification
-func (m *Manager) waitForAssociationEvents(expectedSSID string, timeout time.Duration) error {
-	// wpa_cli wait_event blocks until one of the specified events occurs
-	// This is much faster than polling as we get notified immediately
-	output, err := m.executor.ExecuteWithTimeout(timeout, "wpa_cli", "-i", m.iface,
-		"wait_event", "CTRL-EVENT-CONNECTED", "CTRL-EVENT-ASSOC-REJECT",
-		"CTRL-EVENT-DISCONNECTED", "CTRL-EVENT-TEMP-DISABLED", "CTRL-EVENT-AUTH-REJECT")
+// errEventsLost means the event connection broke before association
+// finished; the wait continues by polling.
+var errEventsLost = errors.New("lost wpa_supplicant event connection")
 
-	if err != nil {
-		return fmt.Errorf("wait_event failed: %w", err)
-	}
+// maxNetworkNotFound is how many scans may miss the network before the wait
+// gives up with ErrNetworkNotFound.
+const maxNetworkNotFound = 3
 
-	// Check which event we received
-	if strings.Contains(output, "CTRL-EVENT-CONNECTED") {
-		m.logger.Debug("Successfully associated with access point (event)", "ssid", expectedSSID)
+// waitForAssociationEvents waits for the wpa_supplicant event that settles
+// the association: connected, or the reason it failed.
+func (m *Manager) waitForAssociationEvents(monitor types.SupplicantMonitor, expectedSSID string, timeout time.Duration, hidden bool) error {
+	deadline := time.Now().Add(timeout)
+	// Association may have completed before we attached.
+	if associated, _ := m.checkAssociationStatus(expectedSSID); associated {
+		m.logger.Debug("Successfully associated with access point", "ssid", expectedSSID)
 		return nil
 	}
 
-	// Any other event is a failure
-	if strings.Contains(output, "CTRL-EVENT-ASSOC-REJECT") {
-		return fmt.Errorf("association rejected")
-	}
-	if strings.Contains(output, "CTRL-EVENT-AUTH-REJECT") {
-		return fmt.Errorf("authentication rejected")
+	notFound := 0
+	for {
+		event, err := monitor.Next(time.Until(deadline))
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return m.associationTimeoutError(expectedSSID, notFound > 0 && !hidden)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", errEventsLost, err)
+		}
+		m.logger.Debug("wpa_supplicant event", "event", event.Text)
+
+		switch event.Name {
+		case "CTRL-EVENT-CONNECTED":
+			m.logger.Debug("Successfully associated with access point (event)", "ssid", expectedSSID)
+			return nil
+		case "CTRL-EVENT-SSID-TEMP-DISABLED":
+			// wpa_supplicant gave up on the network for a while.
+			if event.Fields["reason"] == "WRONG_KEY" {
+				return fmt.Errorf("%q: %w", expectedSSID, ErrWrongKey)
+			}
+			return fmt.Errorf("network temporarily disabled (%s)", event.Fields["reason"])
+		case "CTRL-EVENT-ASSOC-REJECT":
+			// Status 16: timeout waiting for the next authentication frame.
+			if strings.Contains(event.Text, " timeout") || event.Fields["status_code"] == "16" {
+				return fmt.Errorf("%q: %w", expectedSSID, ErrAuthTimeout)
+			}
+			return fmt.Errorf("association rejected (status %s)", event.Fields["status_code"])
+		case "CTRL-EVENT-AUTH-REJECT":
+			return fmt.Errorf("authentication rejected (status %s)", event.Fields["status_code"])
+		case "CTRL-EVENT-NETWORK-NOT-FOUND":
+			notFound++
+			if notFound >= maxNetworkNotFound && !hidden {
+				return fmt.Errorf("%q: %w", expectedSSID, ErrNetworkNotFound)
+			}
+		}
 	}
-	if strings.Contains(output, "CTRL-EVENT-TEMP-DISABLED") {
-		return fmt.Errorf("network temporarily disabled (wrong password?)")
+}
+
+// associationTimeoutError explains a wait that ran out of time: an access
+// point that stopped answering mid-authentication, a network that was never
+// found, or a plain timeout.
+func (m *Manager) associationTimeoutError(expectedSSID string, notFound bool) error {
+	if status, err := m.supplicant.Status(m.iface); err == nil {
+		switch status["wpa_state"] {
+		case "AUTHENTICATING", "ASSOCIATING", "ASSOCIATED", "4WAY_HANDSHAKE", "GROUP_HANDSHAKE":
+			return fmt.Errorf("%q: %w", expectedSSID, ErrAuthTimeout)
+		}
 	}
-	if strings.Contains(output, "CTRL-EVENT-DISCONNECTED") {
-		return fmt.Errorf("disconnected during association")
+	if notFound {
+		return fmt.Errorf("%q: %w", expectedSSID, ErrNetworkNotFound)
 	}
-
-	return fmt.Errorf("unexpected event: %s", output)
+	return fmt.Errorf("timeout waiting for association with %q", expectedSSID)
 }
 
 // waitForAssociationPolling uses polling as a fallback
@@ -796,7 +869,7 @@ func (m *Manager) waitForAssociationPolling(expectedSSID string, timeout time.Du
 	for {
 		select {
 		case <-timeoutCh:
-			return fmt.Errorf("timeout waiting for association to %s", expectedSSID)
+			return m.associationTimeoutError(expectedSSID, false)
 		case <-ticker.C:
 			associated, reachable := m.checkAssociationStatus(expectedSSID)
 			if associated {
@@ -817,24 +890,11 @@ func (m *Manager) waitForAssociationPolling(expectedSSID string, timeout time.Du
 
 // checkAssociationStatus returns (associated, wpaSupplicantReachable).
 // associated is true when the SSID matches and wpa_state=COMPLETED.
-// wpaSupplicantReachable is true when wpa_cli can communicate with wpa_supplicant.
+// wpaSupplicantReachable is true when wpa_supplicant answered STATUS.
 func (m *Manager) checkAssociationStatus(expectedSSID string) (bool, bool) {
-	output, err := m.executor.ExecuteWithTimeout(2*time.Second, "wpa_cli", "-i", m.iface, "status")
+	status, err := m.supplicant.Status(m.iface)
 	if err != nil {
 		return false, false
 	}
-
-	var ssidMatch, stateCompleted bool
-	for _, line := range strings.Split(output, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "ssid=") {
-			ssid := strings.TrimPrefix(line, "ssid=")
-			ssidMatch = (ssid == expectedSSID)
-		}
-		if line == "wpa_state=COMPLETED" {
-			stateCompleted = true
-		}
-	}
-
-	return ssidMatch && stateCompleted, true
+	return status["ssid"] == expectedSSID && status["wpa_state"] == "COMPLETED", true
 }
@@ -6,12 +6,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/angelfreak/net/pkg/netlink/fake"
 	"github.com/angelfreak/net/pkg/types"
+	wpafake "github.com/angelfreak/net/pkg/wpa/fake"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,7 +21,6 @@ import (
 type mockSystemExecutor struct {
 	commands    map[string]string
 	errors      map[string]error
-	callCount   map[string]int
 	hasCommands map[string]bool // which commands are "installed"
 }
 
@@ -29,17 +30,6 @@ func (m *mockSystemExecutor) Execute(cmd string, args ...string) (string, error)
 		fullCmd += " " + arg
 	}
 
-	// Special handling for wpa_cli status when callCount is set (for association simulation)
-	if fullCmd == "wpa_cli -i wlan0 status" && m.callCount != nil {
-		count := m.callCount[fullCmd]
-		m.callCount[fullCmd] = count + 1
-		if count == 0 {
-			return "wpa_state=SCANNING", nil
-		} else {
-			return "wpa_state=COMPLETED\nssid=TestSSID", nil
-		}
-	}
-
 	// Check for errors first
 	if err, hasErr := m.errors[fullCmd]; hasErr {
 		output := ""
@@ -138,6 +128,10 @@ func (m *mockDHCPClient) Renew(iface string, hostname string) error {
 	return m.renewErr
 }
 
+func (m *mockDHCPClient) SetOptions(opts types.DHCPClientOptions) {}
+
+func (m *mockDHCPClient) Domain(iface string) string { return "" }
+
 func TestNewManager(t *testing.T) {
 	executor := &mockSystemExecutor{}
 	logger := &mockLogger{}
@@ -150,24 +144,14 @@ func TestNewManager(t *testing.T) {
 
 func TestScan(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan": "",
-				"iw wlan0 scan dump": `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: TestNetwork
-signal: -50.00
-freq: 2412
-
-BSS 11:22:33:44:55:66(on wlan0)
-SSID: AnotherNetwork
-signal: -60.00
-freq: 2437
-`,
-			},
-		}
+		wireless := &fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {
+			fake.BSS("aa:bb:cc:dd:ee:ff", "TestNetwork", 2412, -50),
+			fake.BSS("11:22:33:44:55:66", "AnotherNetwork", 2437, -60),
+		}}}
 		logger := &mockLogger{}
-		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+		manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
 		manager.linkMgr = &fake.LinkManager{}
+		manager.wireless = wireless
 
 		networks, err := manager.Scan()
 		assert.NoError(t, err)
@@ -186,23 +170,16 @@ freq: 2437
 		assert.Equal(t, 2437, networks[1].Frequency)
 	})
 
-	t.Run("fresh scan fails but dump succeeds", func(t *testing.T) {
-		// When iw scan fails (e.g. permission error), we fall back to cached results
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan dump": `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: CachedNetwork
-signal: -55.00
-freq: 2412
-`,
-			},
-			errors: map[string]error{
-				"iw wlan0 scan": assert.AnError,
-			},
+	t.Run("fresh scan fails but cached results are returned", func(t *testing.T) {
+		// When triggering a scan fails (e.g. permission error), we fall back to cached results
+		wireless := &fake.WirelessManager{
+			Scans:      map[string][]types.BSS{"wlan0": {fake.BSS("aa:bb:cc:dd:ee:ff", "CachedNetwork", 2412, -55)}},
+			TriggerErr: assert.AnError,
 		}
 		logger := &mockLogger{}
-		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+		manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
 		manager.linkMgr = &fake.LinkManager{}
+		manager.wireless = wireless
 
 		networks, err := manager.Scan()
 		assert.NoError(t, err)
@@ -210,18 +187,13 @@ freq: 2412
 		assert.Equal(t, "CachedNetwork", networks[0].SSID)
 	})
 
-	t.Run("both scan and dump fail", func(t *testing.T) {
-		// When both iw scan and iw scan dump fail, Scan() returns an error
-		executor := &mockSystemExecutor{
-			commands: map[string]string{},
-			errors: map[string]error{
-				"iw wlan0 scan":      assert.AnError,
-				"iw wlan0 scan dump": assert.AnError,
-			},
-		}
+	t.Run("both scan and results fail", func(t *testing.T) {
+		// When both the scan and reading the results fail, Scan() returns an error
+		wireless := &fake.WirelessManager{TriggerErr: assert.AnError, ScanErr: assert.AnError}
 		logger := &mockLogger{}
-		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+		manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
 		manager.linkMgr = &fake.LinkManager{}
+		manager.wireless = wireless
 
 		_, err := manager.Scan()
 		assert.Error(t, err)
@@ -232,16 +204,13 @@ func TestConnect(t *testing.T) {
 	t.Run("reconnects even if already connected to different network", func(t *testing.T) {
 		tmp := t.TempDir()
 		// When connected to a different network, disconnect first
+		links := map[string]*types.WirelessLink{"wlan0": {SSID: "OtherSSID"}}
 		executor := &mockSystemExecutor{
 			commands: map[string]string{
-				"iw wlan0 link": `Connected to aa:bb:cc:dd:ee:ff (on wlan0)
-SSID: OtherSSID`,
 				// Disconnect commands (interface-specific termination)
-				"wpa_cli -i wlan0 terminate":  "",
 				"pkill -9 -f dhclient.*wlan0": "",
 				// Reconnect commands
-				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant.conf": "",
-				"wpa_cli -i wlan0 status": "wpa_state=COMPLETED\nssid=TestSSID",
+				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant-wlan0.conf": "",
 				// DHCP flow
 				"pkill -9 -f udhcpc.*wlan0": "",
 				"rm -f /var/lib/dhcp/dhclient.wlan0.leases /run/net/dhclient.wlan0.leases": "",
@@ -253,6 +222,8 @@ SSID: OtherSSID`,
 		manager.linkMgr = &fake.LinkManager{}
 		manager.addrMgr = &fake.AddrManager{}
 		manager.routeMgr = &fake.RouteManager{}
+		manager.wireless = &fake.WirelessManager{Links: links}
+		manager.supplicant = &wpafake.Supplicant{Statuses: []map[string]string{{"wpa_state": "COMPLETED", "ssid": "TestSSID"}}}
 		manager.runtimeDir = tmp
 
 		err := manager.Connect("TestSSID", "password", "")
@@ -263,23 +234,24 @@ SSID: OtherSSID`,
 		tmp := t.TempDir()
 		executor := &mockSystemExecutor{
 			commands: map[string]string{
-				"iw wlan0 link": "Not connected",
-				// Interface-specific wpa_supplicant termination
-				"wpa_cli -i wlan0 terminate":                                    "",
-				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant.conf": "",
+				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant-wlan0.conf": "",
 				// DHCP flow
 				"pkill -9 -f udhcpc.*wlan0":   "",
 				"pkill -9 -f dhclient.*wlan0": "",
 				"rm -f /var/lib/dhcp/dhclient.wlan0.leases /run/net/dhclient.wlan0.leases": "",
 				"timeout 15 dhclient -v wlan0":                                             "",
 			},
-			callCount: make(map[string]int),
 		}
 		logger := &mockLogger{}
 		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
 		manager.linkMgr = &fake.LinkManager{}
 		manager.addrMgr = &fake.AddrManager{}
 		manager.routeMgr = &fake.RouteManager{}
+		manager.wireless = &fake.WirelessManager{}
+		manager.supplicant = &wpafake.Supplicant{
+			Statuses: []map[string]string{{"wpa_state": "SCANNING"}},
+			Events:   []types.SupplicantEvent{wpafake.Event("CTRL-EVENT-CONNECTED")},
+		}
 		manager.runtimeDir = tmp
 
 		err := manager.Connect("TestSSID", "password", "")
@@ -291,11 +263,7 @@ SSID: OtherSSID`,
 		// Test that timeout is properly handled when network is unavailable
 		executor := &mockSystemExecutor{
 			commands: map[string]string{
-				"iw wlan0 link": "Not connected",
-				// Interface-specific wpa_supplicant termination
-				"wpa_cli -i wlan0 terminate":                                    "",
-				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant.conf": "",
-				"wpa_cli -i wlan0 status":                                       "wpa_state=SCANNING", // Never completes
+				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant-wlan0.conf": "",
 			},
 		}
 		logger := &mockLogger{}
@@ -303,6 +271,8 @@ SSID: OtherSSID`,
 		manager.linkMgr = &fake.LinkManager{}
 		manager.addrMgr = &fake.AddrManager{}
 		manager.routeMgr = &fake.RouteManager{}
+		manager.wireless = &fake.WirelessManager{}
+		manager.supplicant = &wpafake.Supplicant{Statuses: []map[string]string{{"wpa_state": "SCANNING"}}} // Never completes
 		manager.runtimeDir = tmp
 		manager.associationTimeout = 1 * time.Second // Short timeout for test
 
@@ -313,26 +283,43 @@ SSID: OtherSSID`,
 }
 
 func TestConnectRejectsWEP(t *testing.T) {
-	executor := &mockSystemExecutor{
-		commands: map[string]string{
-			"iw wlan0 link": "Not connected",
-			"iw wlan0 scan dump": `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: WEPNetwork
-signal: -50.00
-freq: 2412
-	WEP:
-`,
-		},
-	}
+	wep := fake.BSS("aa:bb:cc:dd:ee:ff", "WEPNetwork", 2412, -50)
+	wep.Capability = capPrivacy
 	logger := &mockLogger{}
-	manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+	manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
 	manager.linkMgr = &fake.LinkManager{}
+	manager.wireless = &fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {wep}}}
 
 	err := manager.Connect("WEPNetwork", "password", "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "WEP networks are not supported")
 }
 
+func TestConnectHidden(t *testing.T) {
+	tmp := t.TempDir()
+	executor := &mockSystemExecutor{
+		commands: map[string]string{
+			"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant-wlan0.conf": "",
+		},
+	}
+	manager := NewManager(executor, &mockLogger{}, "wlan0", &mockDHCPClient{})
+	manager.linkMgr = &fake.LinkManager{}
+	manager.addrMgr = &fake.AddrManager{}
+	manager.routeMgr = &fake.RouteManager{}
+	wireless := &fake.WirelessManager{}
+	manager.wireless = wireless
+	// Probing takes a few scans; they don't count as "not found".
+	notFound := wpafake.Event("CTRL-EVENT-NETWORK-NOT-FOUND")
+	manager.supplicant = &wpafake.Supplicant{
+		Statuses: []map[string]string{{"wpa_state": "SCANNING"}},
+		Events:   []types.SupplicantEvent{notFound, notFound, notFound, notFound, wpafake.Event("CTRL-EVENT-CONNECTED")},
+	}
+	manager.runtimeDir = tmp
+
+	assert.NoError(t, manager.ConnectHidden("Gateway", "password", "", ""))
+	assert.Empty(t, wireless.Triggered, "a hidden network isn't looked up in a scan")
+}
+
 func TestConnectFlushesStaleStateBeforeConnect(t *testing.T) {
 	// After suspend/resume, wpa_supplicant is dead so getCurrentSSID() returns empty.
 	// The full Disconnect() cleanup is skipped, but stale IPs and routes remain
@@ -341,10 +328,7 @@ func TestConnectFlushesStaleStateBeforeConnect(t *testing.T) {
 	executor := &recordingExecutor{
 		mockSystemExecutor: mockSystemExecutor{
 			commands: map[string]string{
-				"iw wlan0 link":              "Not connected", // post-hibernation: no current SSID
-				"wpa_cli -i wlan0 terminate": "",
-				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant.conf": "",
-				"wpa_cli -i wlan0 status": "wpa_state=COMPLETED\nssid=TestSSID",
+				"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant-wlan0.conf": "",
 			},
 		},
 	}
@@ -356,6 +340,12 @@ func TestConnectFlushesStaleStateBeforeConnect(t *testing.T) {
 	manager.addrMgr = addrs
 	routes := &fake.RouteManager{}
 	manager.routeMgr = routes
+	manager.wireless = &fake.WirelessManager{}
+	supplicant := &terminateRecorder{
+		Supplicant: wpafake.Supplicant{Statuses: []map[string]string{{"wpa_state": "COMPLETED", "ssid": "TestSSID"}}},
+		flushed:    func() int { return len(addrs.Flushed) },
+	}
+	manager.supplicant = supplicant
 	manager.runtimeDir = tmp
 
 	err := manager.Connect("TestSSID", "password", "")
@@ -367,15 +357,12 @@ func TestConnectFlushesStaleStateBeforeConnect(t *testing.T) {
 	assert.Contains(t, routes.Flushed, "wlan0",
 		"should flush stale routes before connecting")
 
-	// Verify flush happens after terminateWpaSupplicant. The flush is recorded
-	// by the netlink fakes, terminate by the executor, so compare against the
-	// executor call that starts wpa_supplicant (the flush + mkdir happen natively
-	// via os.MkdirAll in between, and are no longer visible to the executor).
-	terminateIdx := indexOf(executor.calledCommands, "wpa_cli -i wlan0 terminate")
-	wpaSupplicantStartIdx := indexOf(executor.calledCommands, "wpa_supplicant -B -i wlan0 -c "+tmp+"/wpa_supplicant.conf")
-	assert.True(t, terminateIdx >= 0, "wpa_cli terminate should have been called")
-	assert.True(t, terminateIdx < wpaSupplicantStartIdx,
-		"terminate should come before wpa_supplicant setup (flush runs between them)")
+	// Verify flush happens after terminateWpaSupplicant: nothing was flushed
+	// yet when the control socket got TERMINATE.
+	assert.Equal(t, []string{"wlan0"}, supplicant.Terminated, "wpa_supplicant should have been terminated")
+	assert.Equal(t, []int{0}, supplicant.flushedAtTerminate,
+		"terminate should come before the flush")
+	assert.True(t, indexOf(executor.calledCommands, "wpa_supplicant -B -i wlan0 -c "+tmp+"/wpa_supplicant-wlan0.conf") >= 0)
 
 	// The interface is brought up via the netlink LinkManager (not the executor).
 	// Verify the pre-wpa_supplicant interface-up happened.
@@ -383,14 +370,11 @@ func TestConnectFlushesStaleStateBeforeConnect(t *testing.T) {
 }
 
 func TestDisconnect(t *testing.T) {
-	executor := &mockSystemExecutor{
-		commands: map[string]string{
-			// Interface-specific termination commands
-			"wpa_cli -i wlan0 terminate": "",
-		},
-	}
+	executor := &mockSystemExecutor{}
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+	supplicant := &wpafake.Supplicant{}
+	manager.supplicant = supplicant
 	links := &fake.LinkManager{}
 	manager.linkMgr = links
 	addrs := &fake.AddrManager{}
@@ -400,20 +384,19 @@ func TestDisconnect(t *testing.T) {
 
 	err := manager.Disconnect()
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"wlan0"}, supplicant.Terminated)
 	assert.Contains(t, links.Downed, "wlan0")
 	assert.Contains(t, addrs.Flushed, "wlan0")
 	assert.Contains(t, routes.Flushed, "wlan0")
 }
 
 func TestListConnections(t *testing.T) {
-	executor := &mockSystemExecutor{
-		commands: map[string]string{
-			"iw wlan0 link": `Connected to aa:bb:cc:dd:ee:ff (on wlan0)
-SSID: TestNetwork`,
-		},
-	}
+	executor := &mockSystemExecutor{}
 	logger := &mockLogger{}
 	manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+	manager.wireless = &fake.WirelessManager{
+		Links: map[string]*types.WirelessLink{"wlan0": {SSID: "TestNetwork", BSSID: "aa:bb:cc:dd:ee:ff"}},
+	}
 	manager.addrMgr = &fake.AddrManager{FirstIPv4: "192.168.1.100"}
 	manager.routeMgr = &fake.RouteManager{
 		Routes: []types.Route{{Gw: "192.168.1.1", Iface: "wlan0"}},
@@ -446,22 +429,30 @@ func TestGetInterface(t *testing.T) {
 	assert.Equal(t, "wlan0", manager.GetInterface())
 }
 
-func TestParseScanResults(t *testing.T) {
-	manager := &Manager{}
-
-	output := `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: TestNetwork
-signal: -50.00
-freq: 2412
+// rsnElement returns an RSN information element with CCMP ciphers that
+// advertises the given AKM suite types (00-0F-AC:n).
+func rsnElement(akms ...byte) []byte {
+	body := []byte{1, 0, 0x00, 0x0f, 0xac, 4, 1, 0, 0x00, 0x0f, 0xac, 4, byte(len(akms)), 0}
+	for _, akm := range akms {
+		body = append(body, 0x00, 0x0f, 0xac, akm)
+	}
+	body = append(body, 0, 0) // RSN capabilities
+	return append([]byte{ieRSN, byte(len(body))}, body...)
+}
 
-BSS 11:22:33:44:55:66(on wlan0)
-SSID: AnotherNetwork
-signal: -60.00
-freq: 2437
-`
+// withIEs returns bss with the given information elements appended.
+func withIEs(bss types.BSS, ies ...[]byte) types.BSS {
+	for _, ie := range ies {
+		bss.IEs = append(bss.IEs, ie...)
+	}
+	return bss
+}
 
-	networks, err := manager.parseScanResults(output)
-	assert.NoError(t, err)
+func TestNetworksFromScan(t *testing.T) {
+	networks := networksFromScan([]types.BSS{
+		fake.BSS("aa:bb:cc:dd:ee:ff", "TestNetwork", 2412, -50),
+		fake.BSS("11:22:33:44:55:66", "AnotherNetwork", 2437, -60),
+	})
 	assert.Len(t, networks, 2)
 
 	// Networks should be sorted by signal strength (strongest first)
@@ -469,6 +460,7 @@ freq: 2437
 	assert.Equal(t, "aa:bb:cc:dd:ee:ff", networks[0].BSSID)
 	assert.Equal(t, -50, networks[0].Signal)
 	assert.Equal(t, 2412, networks[0].Frequency)
+	assert.Equal(t, "Open", networks[0].Security)
 
 	assert.Equal(t, "AnotherNetwork", networks[1].SSID)
 	assert.Equal(t, "11:22:33:44:55:66", networks[1].BSSID)
@@ -476,33 +468,14 @@ freq: 2437
 	assert.Equal(t, 2437, networks[1].Frequency)
 }
 
-func TestParseScanResultsSignalSorting(t *testing.T) {
-	manager := &Manager{}
-
+func TestNetworksFromScanSignalSorting(t *testing.T) {
 	// Test with multiple networks having different signal strengths
-	output := `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: WeakNetwork
-signal: -85.00
-freq: 2412
-
-BSS 11:22:33:44:55:66(on wlan0)
-SSID: StrongNetwork
-signal: -30.00
-freq: 2437
-
-BSS 77:88:99:aa:bb:cc(on wlan0)
-SSID: MediumNetwork
-signal: -55.00
-freq: 2462
-
-BSS dd:ee:ff:11:22:33(on wlan0)
-SSID: VeryWeakNetwork
-signal: -95.00
-freq: 5180
-`
-
-	networks, err := manager.parseScanResults(output)
-	assert.NoError(t, err)
+	networks := networksFromScan([]types.BSS{
+		fake.BSS("aa:bb:cc:dd:ee:ff", "WeakNetwork", 2412, -85),
+		fake.BSS("11:22:33:44:55:66", "StrongNetwork", 2437, -30),
+		fake.BSS("77:88:99:aa:bb:cc", "MediumNetwork", 2462, -55),
+		fake.BSS("dd:ee:ff:11:22:33", "VeryWeakNetwork", 5180, -95),
+	})
 	assert.Len(t, networks, 4)
 
 	// Networks should be sorted by signal strength (strongest first)
@@ -520,185 +493,198 @@ freq: 5180
 	assert.Equal(t, -95, networks[3].Signal)
 }
 
-func TestParseScanResultsWPA3Detection(t *testing.T) {
-	manager := &Manager{}
-
-	t.Run("detects WPA3-SAE from RSN block", func(t *testing.T) {
-		output := `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: WPA3Network
-signal: -45.00
-freq: 5180
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: SAE
-		 * Capabilities: MFPReq (0x00ac)
-`
-		networks, err := manager.parseScanResults(output)
-		assert.NoError(t, err)
-		assert.Len(t, networks, 1)
-		assert.Equal(t, "WPA3", networks[0].Security)
+func TestNetworksFromScanReturnsEveryBSS(t *testing.T) {
+	networks := networksFromScan([]types.BSS{
+		fake.BSS("aa:bb:cc:00:00:01", "Office", 2412, -70),
+		fake.BSS("aa:bb:cc:00:00:02", "Office", 5180, -48),
+		fake.BSS("aa:bb:cc:00:00:03", "", 5180, -40),
+		fake.BSS("aa:bb:cc:00:00:04", "\x00\x00\x00", 2437, -80),
 	})
+	assert.Len(t, networks, 4)
+	assert.Equal(t, "aa:bb:cc:00:00:03", networks[0].BSSID)
+	assert.True(t, networks[0].Hidden)
+	assert.Equal(t, "aa:bb:cc:00:00:02", networks[1].BSSID)
+	assert.False(t, networks[1].Hidden)
+	assert.Equal(t, "aa:bb:cc:00:00:01", networks[2].BSSID)
+	// An SSID of NUL bytes is a hidden network too.
+	assert.True(t, networks[3].Hidden)
+	assert.Equal(t, "", networks[3].SSID)
+}
 
-	t.Run("detects WPA2 from RSN block without SAE", func(t *testing.T) {
-		output := `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: WPA2Network
-signal: -50.00
-freq: 2412
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: PSK
-`
-		networks, err := manager.parseScanResults(output)
-		assert.NoError(t, err)
-		assert.Len(t, networks, 1)
-		assert.Equal(t, "WPA2", networks[0].Security)
+func TestNetworkFromBSSDetails(t *testing.T) {
+	t.Run("RSN suites and PMF", func(t *testing.T) {
+		// RSN with CCMP group, CCMP + GCMP-256 pairwise, PSK + SAE, MFPC.
+		rsn := []byte{ieRSN, 28, 1, 0, 0x00, 0x0f, 0xac, 4,
+			2, 0, 0x00, 0x0f, 0xac, 4, 0x00, 0x0f, 0xac, 9,
+			2, 0, 0x00, 0x0f, 0xac, akmPSK, 0x00, 0x0f, 0xac, akmSAE,
+			0x80, 0x00}
+		n := networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Net", 2437, -50), rsn))
+		assert.Equal(t, []string{"WPA-PSK", "SAE"}, n.AKMs)
+		assert.Equal(t, []string{"CCMP", "GCMP-256"}, n.Pairwise)
+		assert.Equal(t, "CCMP", n.Group)
+		assert.True(t, n.PMFCapable)
+		assert.False(t, n.PMFRequired)
+		assert.Equal(t, 6, n.Channel)
+		assert.Equal(t, 20, n.Width)
+		assert.Equal(t, "", n.Generation)
 	})
 
-	t.Run("detects WPA2/WPA3 transition mode", func(t *testing.T) {
-		output := `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: TransitionNetwork
-signal: -50.00
-freq: 2412
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: PSK SAE
-`
-		networks, err := manager.parseScanResults(output)
-		assert.NoError(t, err)
-		assert.Len(t, networks, 1)
-		assert.Equal(t, "WPA2/WPA3", networks[0].Security)
+	t.Run("enterprise and OWE", func(t *testing.T) {
+		n := networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Corp", 5180, -50), rsnElement(1)))
+		assert.Equal(t, "WPA2-Enterprise", n.Security)
+		assert.Equal(t, []string{"WPA-EAP"}, n.AKMs)
+
+		n = networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Cafe", 5180, -50), rsnElement(akmOWE)))
+		assert.Equal(t, "OWE", n.Security)
+	})
+
+	t.Run("legacy WPA element suites", func(t *testing.T) {
+		wpa := []byte{ieVendor, 22, 0x00, 0x50, 0xf2, 0x01, 1, 0, 0x00, 0x50, 0xf2, 2,
+			1, 0, 0x00, 0x50, 0xf2, 2, 1, 0, 0x00, 0x50, 0xf2, 2}
+		n := networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Old", 2412, -50), wpa))
+		assert.Equal(t, "WPA", n.Security)
+		assert.Equal(t, []string{"WPA-PSK"}, n.AKMs)
+		assert.Equal(t, []string{"TKIP"}, n.Pairwise)
+		assert.Equal(t, "TKIP", n.Group)
+	})
+
+	t.Run("generation and width", func(t *testing.T) {
+		ht := []byte{ieHTCapability, 2, 0, 0}
+		htOp := []byte{ieHTOperation, 2, 36, 0x05} // secondary above, 40 MHz
+		vht := []byte{ieVHTCapab, 1, 0}
+		vhtOp := []byte{ieVHTOperation, 3, 1, 42, 0} // 80 MHz
+		he := []byte{ieExtension, 1, extHECapability}
+		n := networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Fast", 5180, -50), ht, htOp, vht, vhtOp, he))
+		assert.Equal(t, 36, n.Channel)
+		assert.Equal(t, 80, n.Width)
+		assert.Equal(t, "ax", n.Generation)
+		assert.Equal(t, "5", n.Band())
+
+		vhtOp160 := []byte{ieVHTOperation, 3, 1, 42, 50}
+		n = networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Wide", 5180, -50), vht, vhtOp160))
+		assert.Equal(t, 160, n.Width)
+		assert.Equal(t, "ac", n.Generation)
+
+		// 6 GHz: HE operation with 6 GHz operation information (160 MHz)
+		// and EHT operation with 320 MHz.
+		heOp := []byte{ieExtension, 12, extHEOperation, 0, 0, 0x02, 0, 0, 0, 37, 0x03, 47, 31, 0}
+		eht := []byte{ieExtension, 1, extEHTCapability}
+		ehtOp := []byte{ieExtension, 9, extEHTOperation, 0x01, 0, 0, 0, 0, 0x04, 63, 31}
+		n = networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "WiFi7", 6135, -50), he, heOp, eht, ehtOp))
+		assert.Equal(t, 37, n.Channel)
+		assert.Equal(t, "6", n.Band())
+		assert.Equal(t, 320, n.Width)
+		assert.Equal(t, "be", n.Generation)
+	})
+
+	t.Run("WPS", func(t *testing.T) {
+		wps := []byte{ieVendor, 4, 0x00, 0x50, 0xf2, 0x04}
+		n := networkFromBSS(withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Printer", 2412, -50), wps))
+		assert.True(t, n.WPS)
+		assert.Equal(t, "Open", n.Security)
 	})
 }
 
+func TestChannelFromFrequency(t *testing.T) {
+	for freq, want := range map[int]int{2412: 1, 2472: 13, 2484: 14, 5180: 36, 5825: 165, 5955: 1, 6135: 37, 7115: 233, 900: 0} {
+		assert.Equal(t, want, ChannelFromFrequency(freq), "frequency %d", freq)
+	}
+}
+
+func TestNetworksFromScanSecurityDetection(t *testing.T) {
+	tests := []struct {
+		name string
+		bss  types.BSS
+		want string
+	}{
+		{"WPA3-SAE from RSN element", withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Net", 5180, -45), rsnElement(akmSAE)), "WPA3"},
+		{"WPA2 from RSN element without SAE", withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Net", 2412, -50), rsnElement(akmPSK)), "WPA2"},
+		{"WPA2/WPA3 transition mode", withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Net", 2412, -50), rsnElement(akmPSK, akmSAE)), "WPA2/WPA3"},
+		{"WPA from vendor element", withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Net", 2412, -50), []byte{ieVendor, 4, 0x00, 0x50, 0xf2, 0x01}), "WPA"},
+		{"WEP from privacy bit", types.BSS{BSSID: "aa:bb:cc:dd:ee:ff", Capability: capPrivacy, IEs: []byte{ieSSID, 3, 'N', 'e', 't'}}, "WEP"},
+		{"truncated RSN element is still RSN", withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "Net", 2412, -50), []byte{ieRSN, 2, 1, 0}), "WPA2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			networks := networksFromScan([]types.BSS{tt.bss})
+			assert.Len(t, networks, 1)
+			assert.Equal(t, tt.want, networks[0].Security)
+		})
+	}
+}
+
 func TestDetectNetworkSecurity(t *testing.T) {
-	t.Run("detects WPA3 for SAE-only network", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan dump": `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: MyWPA3AP
-signal: -45.00
-freq: 5180
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: SAE
-		 * Capabilities: MFPReq (0x00ac)
-`,
-			},
-		}
-		manager := &Manager{
+	newManager := func(wireless *fake.WirelessManager) *Manager {
+		return &Manager{
 			iface:    "wlan0",
-			executor: executor,
+			executor: &mockSystemExecutor{},
 			logger:   &mockLogger{},
 			linkMgr:  &fake.LinkManager{},
+			wireless: wireless,
 		}
-		security := manager.detectNetworkSecurity("MyWPA3AP")
-		assert.Equal(t, "WPA3", security)
+	}
+
+	t.Run("detects WPA3 for SAE-only network", func(t *testing.T) {
+		manager := newManager(&fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {
+			withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "MyWPA3AP", 5180, -45), rsnElement(akmSAE)),
+		}}})
+		network := manager.detectNetworkSecurity("MyWPA3AP", "")
+		assert.Equal(t, "WPA3", network.Security)
 	})
 
 	t.Run("detects WPA2/WPA3 for transition mode", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan dump": `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: TransitionAP
-signal: -50.00
-freq: 2412
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: PSK SAE
-`,
-			},
-		}
-		manager := &Manager{
-			iface:    "wlan0",
-			executor: executor,
-			logger:   &mockLogger{},
-			linkMgr:  &fake.LinkManager{},
-		}
-		security := manager.detectNetworkSecurity("TransitionAP")
-		assert.Equal(t, "WPA2/WPA3", security)
+		manager := newManager(&fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {
+			withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "TransitionAP", 2412, -50), rsnElement(akmPSK, akmSAE)),
+		}}})
+		network := manager.detectNetworkSecurity("TransitionAP", "")
+		assert.Equal(t, "WPA2/WPA3", network.Security)
 	})
 
 	t.Run("returns empty for unknown SSID", func(t *testing.T) {
-		scanData := `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: OtherNetwork
-signal: -50.00
-freq: 2412
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: PSK
-`
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan dump": scanData,
-				"iw wlan0 scan":      scanData,
-			},
-		}
-		manager := &Manager{
-			iface:    "wlan0",
-			executor: executor,
-			logger:   &mockLogger{},
-			linkMgr:  &fake.LinkManager{},
-		}
-		security := manager.detectNetworkSecurity("NotFound")
-		assert.Equal(t, "", security)
+		other := []types.BSS{withIEs(fake.BSS("aa:bb:cc:dd:ee:ff", "OtherNetwork", 2412, -50), rsnElement(akmPSK))}
+		manager := newManager(&fake.WirelessManager{
+			Scans:      map[string][]types.BSS{"wlan0": other},
+			FreshScans: map[string][]types.BSS{"wlan0": other},
+		})
+		network := manager.detectNetworkSecurity("NotFound", "")
+		assert.Nil(t, network)
 	})
 
 	t.Run("returns empty on scan failure", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{},
-			errors: map[string]error{
-				"iw wlan0 scan dump": fmt.Errorf("scan failed"),
-				"iw wlan0 scan":      fmt.Errorf("scan failed"),
-			},
-		}
-		manager := &Manager{
-			iface:    "wlan0",
-			executor: executor,
-			logger:   &mockLogger{},
-			linkMgr:  &fake.LinkManager{},
-		}
-		security := manager.detectNetworkSecurity("AnyNetwork")
-		assert.Equal(t, "", security)
+		manager := newManager(&fake.WirelessManager{TriggerErr: fmt.Errorf("scan failed"), ScanErr: fmt.Errorf("scan failed")})
+		network := manager.detectNetworkSecurity("AnyNetwork", "")
+		assert.Nil(t, network)
 	})
 
-	t.Run("falls back to fresh scan when dump is empty", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan dump": "",
-				"iw wlan0 scan": `BSS 7c:7b:ec:1a:75:7a(on wlan0)
-SSID: Lanso iPhone
-signal: -56.00
-freq: 5180
-	RSN:	 * Version: 1
-		 * Group cipher: CCMP
-		 * Pairwise ciphers: CCMP
-		 * Authentication suites: SAE
-		 * Capabilities: MFP-required MFP-capable (0x00cc)
-`,
-			},
-		}
-		manager := &Manager{
-			iface:    "wlan0",
-			executor: executor,
-			logger:   &mockLogger{},
-			linkMgr:  &fake.LinkManager{},
-		}
-		security := manager.detectNetworkSecurity("Lanso iPhone")
-		assert.Equal(t, "WPA3", security)
+	t.Run("prefers the pinned BSSID over the strongest BSS", func(t *testing.T) {
+		manager := newManager(&fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {
+			withIEs(fake.BSS("aa:bb:cc:00:00:01", "Office", 5180, -40), rsnElement(akmSAE)),
+			withIEs(fake.BSS("aa:bb:cc:00:00:02", "Office", 2412, -70), rsnElement(akmPSK)),
+		}}})
+		network := manager.detectNetworkSecurity("Office", "AA:BB:CC:00:00:02")
+		assert.Equal(t, "aa:bb:cc:00:00:02", network.BSSID)
+		assert.Equal(t, "WPA2", network.Security)
+
+		network = manager.detectNetworkSecurity("Office", "")
+		assert.Equal(t, "aa:bb:cc:00:00:01", network.BSSID)
+	})
+
+	t.Run("falls back to fresh scan when cache is empty", func(t *testing.T) {
+		wireless := &fake.WirelessManager{FreshScans: map[string][]types.BSS{"wlan0": {
+			withIEs(fake.BSS("7c:7b:ec:1a:75:7a", "Lanso iPhone", 5180, -56), rsnElement(akmSAE)),
+		}}}
+		manager := newManager(wireless)
+		network := manager.detectNetworkSecurity("Lanso iPhone", "")
+		assert.Equal(t, "WPA3", network.Security)
+		assert.Equal(t, []string{"wlan0"}, wireless.Triggered)
 	})
 }
 
 func TestGenerateWPAConfig(t *testing.T) {
 	manager := &Manager{logger: &mockLogger{}}
 
-	t.Run("with password defaults to transition mode", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", "password", "")
+	t.Run("with password and no scan defaults to universal mode", func(t *testing.T) {
+		config := manager.generateWPAConfig("TestSSID", "password", "", nil)
 		// ctrl_interface is REQUIRED for wpa_cli to communicate with wpa_supplicant
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant", "ctrl_interface is required for wpa_cli communication")
 		assert.Contains(t, config, `ssid="TestSSID"`)
@@ -711,15 +697,15 @@ func TestGenerateWPAConfig(t *testing.T) {
 		assert.Contains(t, config, "pairwise=CCMP TKIP")
 	})
 
-	t.Run("config with BSSID defaults to transition mode", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", "password", "aa:bb:cc:dd:ee:ff")
+	t.Run("config with BSSID and no scan defaults to universal mode", func(t *testing.T) {
+		config := manager.generateWPAConfig("TestSSID", "password", "aa:bb:cc:dd:ee:ff", nil)
 		assert.Contains(t, config, "key_mgmt=WPA-PSK WPA-PSK-SHA256 SAE")
 		assert.Contains(t, config, "ieee80211w=1")
 		assert.Contains(t, config, "bssid=aa:bb:cc:dd:ee:ff")
 	})
 
 	t.Run("open network", func(t *testing.T) {
-		config := manager.generateWPAConfig("OpenSSID", "", "")
+		config := manager.generateWPAConfig("OpenSSID", "", "", nil)
 		// ctrl_interface is REQUIRED for wpa_cli to communicate with wpa_supplicant
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant", "ctrl_interface is required for wpa_cli communication")
 		assert.Contains(t, config, `ssid="OpenSSID"`)
@@ -727,7 +713,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 	})
 
 	t.Run("with BSSID pinning", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", "password", "00:11:22:33:44:55")
+		config := manager.generateWPAConfig("TestSSID", "password", "00:11:22:33:44:55", nil)
 		// ctrl_interface is REQUIRED for wpa_cli to communicate with wpa_supplicant
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant", "ctrl_interface is required for wpa_cli communication")
 		assert.Contains(t, config, `ssid="TestSSID"`)
@@ -737,7 +723,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("escapes special characters in SSID", func(t *testing.T) {
 		// Test SSID with quotes and backslashes
-		config := manager.generateWPAConfig(`Test"SSID\with\special`, "password", "")
+		config := manager.generateWPAConfig(`Test"SSID\with\special`, "password", "", nil)
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant", "ctrl_interface is required for wpa_cli communication")
 		assert.Contains(t, config, `ssid="Test\"SSID\\with\\special"`)
 		assert.Contains(t, config, `psk="password"`)
@@ -745,7 +731,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("escapes special characters in password", func(t *testing.T) {
 		// Test password with quotes and backslashes
-		config := manager.generateWPAConfig("TestSSID", `pass"word\with\quotes`, "")
+		config := manager.generateWPAConfig("TestSSID", `pass"word\with\quotes`, "", nil)
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant", "ctrl_interface is required for wpa_cli communication")
 		assert.Contains(t, config, `ssid="TestSSID"`)
 		assert.Contains(t, config, `psk="pass\"word\\with\\quotes"`)
@@ -753,7 +739,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("escapes special characters in open network", func(t *testing.T) {
 		// Test open network with special characters in SSID
-		config := manager.generateWPAConfig(`Evil"Network`, "", "")
+		config := manager.generateWPAConfig(`Evil"Network`, "", "", nil)
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant", "ctrl_interface is required for wpa_cli communication")
 		assert.Contains(t, config, `ssid="Evil\"Network"`)
 		assert.Contains(t, config, `key_mgmt=NONE`)
@@ -761,7 +747,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("escapes newlines in SSID to prevent injection", func(t *testing.T) {
 		// Test SSID with newline that could inject additional config
-		config := manager.generateWPAConfig("Evil\nnetwork={\nssid=\"injected\"", "password", "")
+		config := manager.generateWPAConfig("Evil\nnetwork={\nssid=\"injected\"", "password", "", nil)
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant")
 		// Newlines should be escaped as literal \n (backslash followed by 'n'), not actual newlines
 		assert.Contains(t, config, `ssid="Evil\nnetwork={\nssid=\"injected\""`)
@@ -781,7 +767,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("escapes newlines in password to prevent injection", func(t *testing.T) {
 		// Test password with newline that could inject additional config
-		config := manager.generateWPAConfig("TestSSID", "pass\nnetwork={\nssid=\"injected\"", "")
+		config := manager.generateWPAConfig("TestSSID", "pass\nnetwork={\nssid=\"injected\"", "", nil)
 		assert.Contains(t, config, "ctrl_interface=/run/wpa_supplicant")
 		assert.Contains(t, config, `ssid="TestSSID"`)
 		// Newlines should be escaped as literal \n
@@ -790,13 +776,13 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("escapes carriage returns in SSID", func(t *testing.T) {
 		// Test SSID with carriage return
-		config := manager.generateWPAConfig("Evil\rNetwork", "password", "")
+		config := manager.generateWPAConfig("Evil\rNetwork", "password", "", nil)
 		assert.Contains(t, config, `ssid="Evil\rNetwork"`)
 	})
 
 	t.Run("rejects invalid BSSID to prevent injection", func(t *testing.T) {
 		// Test with malicious BSSID containing config injection attempt
-		config := manager.generateWPAConfig("TestSSID", "password", "00:11:22:33:44:55\nnetwork={\nssid=\"injected\"")
+		config := manager.generateWPAConfig("TestSSID", "password", "00:11:22:33:44:55\nnetwork={\nssid=\"injected\"", nil)
 		assert.Contains(t, config, `ssid="TestSSID"`)
 		assert.Contains(t, config, `psk="password"`)
 		// Invalid BSSID should be silently ignored (not included in config)
@@ -806,15 +792,15 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 	t.Run("accepts valid BSSID formats", func(t *testing.T) {
 		// Valid lowercase
-		config := manager.generateWPAConfig("TestSSID", "password", "aa:bb:cc:dd:ee:ff")
+		config := manager.generateWPAConfig("TestSSID", "password", "aa:bb:cc:dd:ee:ff", nil)
 		assert.Contains(t, config, "bssid=aa:bb:cc:dd:ee:ff")
 
 		// Valid uppercase (should be normalized to lowercase)
-		config = manager.generateWPAConfig("TestSSID", "password", "AA:BB:CC:DD:EE:FF")
+		config = manager.generateWPAConfig("TestSSID", "password", "AA:BB:CC:DD:EE:FF", nil)
 		assert.Contains(t, config, "bssid=aa:bb:cc:dd:ee:ff")
 
 		// Valid mixed case
-		config = manager.generateWPAConfig("TestSSID", "password", "Aa:Bb:Cc:Dd:Ee:Ff")
+		config = manager.generateWPAConfig("TestSSID", "password", "Aa:Bb:Cc:Dd:Ee:Ff", nil)
 		assert.Contains(t, config, "bssid=aa:bb:cc:dd:ee:ff")
 	})
 
@@ -832,7 +818,7 @@ func TestGenerateWPAConfig(t *testing.T) {
 		}
 
 		for _, invalidBSSID := range invalidBSSIDs {
-			config := manager.generateWPAConfig("TestSSID", "password", invalidBSSID)
+			config := manager.generateWPAConfig("TestSSID", "password", invalidBSSID, nil)
 			assert.NotContains(t, config, "bssid=", "invalid BSSID %q should be rejected", invalidBSSID)
 		}
 	})
@@ -840,9 +826,11 @@ func TestGenerateWPAConfig(t *testing.T) {
 
 func TestGenerateWPAConfigSecurityAware(t *testing.T) {
 	manager := &Manager{logger: &mockLogger{}}
+	wpa3 := &types.WiFiNetwork{Security: "WPA3", AKMs: []string{"SAE"}, Pairwise: []string{"CCMP"}, Group: "CCMP", PMFRequired: true, PMFCapable: true}
+	transition := &types.WiFiNetwork{Security: "WPA2/WPA3", AKMs: []string{"WPA-PSK", "SAE"}, Pairwise: []string{"CCMP"}, Group: "CCMP", PMFCapable: true}
 
 	t.Run("WPA3-only uses SAE key_mgmt and required PMF", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", "password", "", "WPA3")
+		config := manager.generateWPAConfig("TestSSID", "password", "", wpa3)
 		assert.Contains(t, config, "key_mgmt=SAE")
 		assert.NotContains(t, config, "WPA-PSK")
 		assert.Contains(t, config, "ieee80211w=2")
@@ -855,21 +843,40 @@ func TestGenerateWPAConfigSecurityAware(t *testing.T) {
 		assert.Contains(t, config, "group=CCMP")
 	})
 
-	t.Run("WPA2/WPA3 transition uses SHA256 key_mgmt and sae_password", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", "password", "", "WPA2/WPA3")
-		assert.Contains(t, config, "key_mgmt=WPA-PSK-SHA256 SAE")
+	t.Run("WPA2/WPA3 transition offers the advertised AKMs with optional PMF", func(t *testing.T) {
+		config := manager.generateWPAConfig("TestSSID", "password", "", transition)
+		assert.Contains(t, config, "key_mgmt=WPA-PSK SAE\n")
 		assert.Contains(t, config, "ieee80211w=1")
 		assert.Contains(t, config, `psk="password"`)
 		assert.Contains(t, config, `sae_password="password"`)
 		assert.Contains(t, config, "sae_pwe=2")
 		assert.Contains(t, config, "scan_ssid=1")
-		assert.Contains(t, config, "proto=RSN")
+		assert.Contains(t, config, "proto=RSN\n")
 		assert.Contains(t, config, "pairwise=CCMP")
 	})
 
-	t.Run("WPA2 or unknown defaults to universal mode for compatibility", func(t *testing.T) {
-		for _, sec := range []string{"WPA2", ""} {
-			config := manager.generateWPAConfig("TestSSID", "password", "", sec)
+	t.Run("WPA2 with PSK-SHA256 and required PMF", func(t *testing.T) {
+		network := &types.WiFiNetwork{Security: "WPA2", AKMs: []string{"WPA-PSK-SHA256"}, Pairwise: []string{"CCMP"}, Group: "CCMP", PMFRequired: true}
+		config := manager.generateWPAConfig("TestSSID", "password", "", network)
+		assert.Contains(t, config, "key_mgmt=WPA-PSK-SHA256\n")
+		assert.Contains(t, config, "ieee80211w=2")
+		assert.Contains(t, config, `psk="password"`)
+		assert.NotContains(t, config, "sae_password")
+	})
+
+	t.Run("legacy WPA uses proto WPA and TKIP", func(t *testing.T) {
+		network := &types.WiFiNetwork{Security: "WPA", AKMs: []string{"WPA-PSK"}, Pairwise: []string{"TKIP"}, Group: "TKIP"}
+		config := manager.generateWPAConfig("TestSSID", "password", "", network)
+		assert.Contains(t, config, "key_mgmt=WPA-PSK\n")
+		assert.Contains(t, config, "proto=WPA\n")
+		assert.Contains(t, config, "pairwise=TKIP")
+		assert.Contains(t, config, "group=TKIP")
+	})
+
+	t.Run("unknown or non-passphrase AKMs default to universal mode", func(t *testing.T) {
+		enterprise := &types.WiFiNetwork{Security: "WPA2-Enterprise", AKMs: []string{"WPA-EAP"}}
+		for _, network := range []*types.WiFiNetwork{nil, {Security: "WPA2"}, enterprise} {
+			config := manager.generateWPAConfig("TestSSID", "password", "", network)
 			assert.Contains(t, config, "key_mgmt=WPA-PSK WPA-PSK-SHA256 SAE")
 			assert.Contains(t, config, "ieee80211w=1")
 			assert.Contains(t, config, `psk="password"`)
@@ -880,8 +887,26 @@ func TestGenerateWPAConfigSecurityAware(t *testing.T) {
 		}
 	})
 
+	t.Run("raw PSK is written unquoted and without SAE", func(t *testing.T) {
+		key := strings.Repeat("0123456789ABCDEF", 4)
+		for _, network := range []*types.WiFiNetwork{nil, transition} {
+			config := manager.generateWPAConfig("TestSSID", key, "", network)
+			assert.Contains(t, config, "psk="+strings.ToLower(key)+"\n")
+			assert.NotContains(t, config, "SAE")
+			assert.NotContains(t, config, "sae_password")
+		}
+	})
+
+	t.Run("OWE network without password", func(t *testing.T) {
+		network := &types.WiFiNetwork{Security: "OWE", AKMs: []string{"OWE"}, PMFRequired: true}
+		config := manager.generateWPAConfig("CafeSecure", "", "", network)
+		assert.Contains(t, config, "key_mgmt=OWE")
+		assert.Contains(t, config, "ieee80211w=2")
+		assert.NotContains(t, config, "key_mgmt=NONE")
+	})
+
 	t.Run("WPA3 with BSSID pinning", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", "password", "aa:bb:cc:dd:ee:ff", "WPA3")
+		config := manager.generateWPAConfig("TestSSID", "password", "aa:bb:cc:dd:ee:ff", wpa3)
 		assert.Contains(t, config, "key_mgmt=SAE")
 		assert.Contains(t, config, "ieee80211w=2")
 		assert.Contains(t, config, `sae_password="password"`)
@@ -889,7 +914,7 @@ func TestGenerateWPAConfigSecurityAware(t *testing.T) {
 	})
 
 	t.Run("WPA3 escapes special characters in sae_password", func(t *testing.T) {
-		config := manager.generateWPAConfig("TestSSID", `pass"word\special`, "", "WPA3")
+		config := manager.generateWPAConfig("TestSSID", `pass"word\special`, "", wpa3)
 		assert.Contains(t, config, `sae_password="pass\"word\\special"`)
 	})
 }
@@ -946,18 +971,18 @@ func TestObtainDHCP(t *testing.T) {
 }
 
 func TestGetCurrentSSID(t *testing.T) {
-	executor := &mockSystemExecutor{
-		commands: map[string]string{
-			"iw wlan0 link": `Connected to aa:bb:cc:dd:ee:ff (on wlan0)
-SSID: TestNetwork`,
-		},
-	}
 	logger := &mockLogger{}
-	manager := &Manager{executor: executor, logger: logger, iface: "wlan0"}
+	manager := &Manager{logger: logger, iface: "wlan0", wireless: &fake.WirelessManager{
+		Links: map[string]*types.WirelessLink{"wlan0": {SSID: "TestNetwork", BSSID: "aa:bb:cc:dd:ee:ff"}},
+	}}
 
 	ssid, err := manager.getCurrentSSID()
 	assert.NoError(t, err)
 	assert.Equal(t, "TestNetwork", ssid)
+
+	manager.wireless = &fake.WirelessManager{}
+	_, err = manager.getCurrentSSID()
+	assert.Error(t, err, "not associated")
 }
 
 func TestGetDNSServers(t *testing.T) {
@@ -1002,30 +1027,10 @@ func TestReadFile(t *testing.T) {
 	assert.Equal(t, "nameserver 8.8.8.8", content)
 }
 
-func TestDecodeSSID(t *testing.T) {
-	manager := &Manager{}
-
-	t.Run("ASCII SSID", func(t *testing.T) {
-		ssid := manager.decodeSSID("TestNetwork")
-		assert.Equal(t, "TestNetwork", ssid)
-	})
-
-	t.Run("Invalid escape", func(t *testing.T) {
-		// Invalid hex should be left as is
-		ssid := manager.decodeSSID("Test\\xZZ")
-		assert.Equal(t, "Test\\xZZ", ssid)
-	})
-
-	t.Run("Hex encoded SSID", func(t *testing.T) {
-		// Test with actual hex encoded chars
-		ssid := manager.decodeSSID("Test\\x20Network") // \x20 is space
-		assert.Equal(t, "Test Network", ssid)
-	})
-
-	t.Run("Multiple hex escapes", func(t *testing.T) {
-		ssid := manager.decodeSSID("\\x48\\x65\\x6c\\x6c\\x6f") // "Hello"
-		assert.Equal(t, "Hello", ssid)
-	})
+func TestNetworkFromBSSKeepsRawSSID(t *testing.T) {
+	// nl80211 hands over the SSID bytes as-is: no escapes to decode.
+	network := networkFromBSS(fake.BSS("aa:bb:cc:dd:ee:ff", "Café \\x20", 2412, -50))
+	assert.Equal(t, "Café \\x20", network.SSID)
 }
 
 func TestDisconnect_AdditionalCases(t *testing.T) {
@@ -1038,7 +1043,7 @@ func TestDisconnect_AdditionalCases(t *testing.T) {
 		}
 		logger := &mockLogger{}
 		links := &fake.LinkManager{}
-		manager := &Manager{executor: executor, logger: logger, iface: "wlan0", dhcpClient: &mockDHCPClient{}, linkMgr: links, addrMgr: &fake.AddrManager{}, routeMgr: &fake.RouteManager{}}
+		manager := &Manager{executor: executor, logger: logger, iface: "wlan0", dhcpClient: &mockDHCPClient{}, linkMgr: links, addrMgr: &fake.AddrManager{}, routeMgr: &fake.RouteManager{}, supplicant: &wpafake.Supplicant{}}
 
 		err := manager.Disconnect()
 		assert.NoError(t, err)
@@ -1053,7 +1058,7 @@ func TestDisconnect_AdditionalCases(t *testing.T) {
 		}
 		logger := &mockLogger{}
 		links := &fake.LinkManager{SetDownErr: assert.AnError}
-		manager := &Manager{executor: executor, logger: logger, iface: "wlan0", dhcpClient: &mockDHCPClient{}, linkMgr: links, addrMgr: &fake.AddrManager{}, routeMgr: &fake.RouteManager{}}
+		manager := &Manager{executor: executor, logger: logger, iface: "wlan0", dhcpClient: &mockDHCPClient{}, linkMgr: links, addrMgr: &fake.AddrManager{}, routeMgr: &fake.RouteManager{}, supplicant: &wpafake.Supplicant{}}
 
 		err := manager.Disconnect()
 		// Should return error if interface down fails
@@ -1064,10 +1069,9 @@ func TestDisconnect_AdditionalCases(t *testing.T) {
 
 func TestListConnections_AdditionalCases(t *testing.T) {
 	t.Run("connection without DNS", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 link": "Connected to 00:11:22:33:44:55 (on wlan0)\nSSID: TestNetwork",
-			},
+		executor := &mockSystemExecutor{}
+		wireless := &fake.WirelessManager{
+			Links: map[string]*types.WirelessLink{"wlan0": {SSID: "TestNetwork"}},
 		}
 		logger := &mockLogger{}
 		// Empty resolv.conf → no DNS servers. Inject a real empty temp file so
@@ -1080,6 +1084,7 @@ func TestListConnections_AdditionalCases(t *testing.T) {
 			iface:          "wlan0",
 			addrMgr:        &fake.AddrManager{FirstIPv4: "192.168.1.100"},
 			routeMgr:       &fake.RouteManager{Routes: []types.Route{{Gw: "192.168.1.1", Iface: "wlan0"}}},
+			wireless:       wireless,
 			resolvConfPath: resolvPath,
 		}
 
@@ -1090,10 +1095,9 @@ func TestListConnections_AdditionalCases(t *testing.T) {
 	})
 
 	t.Run("connection without gateway", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 link": "Connected to 00:11:22:33:44:55 (on wlan0)\nSSID: TestNetwork",
-			},
+		executor := &mockSystemExecutor{}
+		wireless := &fake.WirelessManager{
+			Links: map[string]*types.WirelessLink{"wlan0": {SSID: "TestNetwork"}},
 		}
 		logger := &mockLogger{}
 		manager := &Manager{
@@ -1102,6 +1106,7 @@ func TestListConnections_AdditionalCases(t *testing.T) {
 			iface:    "wlan0",
 			addrMgr:  &fake.AddrManager{FirstIPv4: "192.168.1.100"},
 			routeMgr: &fake.RouteManager{}, // No default route on interface
+			wireless: wireless,
 		}
 
 		connections, err := manager.ListConnections()
@@ -1111,52 +1116,80 @@ func TestListConnections_AdditionalCases(t *testing.T) {
 	})
 }
 
-func TestScan_AlwaysTriggersFreshScan(t *testing.T) {
-	// Even when scan dump returns cached results, a fresh scan should be triggered
-	executor := &recordingExecutor{
-		mockSystemExecutor: mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan": "",
-				"iw wlan0 scan dump": `BSS aa:bb:cc:dd:ee:ff(on wlan0)
-SSID: FreshNetwork
-signal: -50.00
-freq: 2412
-`,
-			},
+func TestListConnections_AllRadios(t *testing.T) {
+	manager := NewManager(&mockSystemExecutor{}, &mockLogger{}, "wlan0", &mockDHCPClient{})
+	manager.wireless = &fake.WirelessManager{
+		Ifaces: []types.WirelessInterface{
+			{Name: "wlan1", Type: "station", PHY: 1, SSID: "Mgmt"},
+			{Name: "wlan0", Type: "station", PHY: 0, SSID: "Site"},
+			{Name: "wlan0_ap", Type: "ap", PHY: 0, SSID: "Field Hotspot"},
+			{Name: "mon0", Type: "monitor", PHY: 1},
 		},
+		Links: map[string]*types.WirelessLink{
+			"wlan0": {SSID: "Site", BSSID: "aa:00:00:00:00:01"},
+			"wlan1": {SSID: "Mgmt", BSSID: "bb:00:00:00:00:01"},
+		},
+	}
+	manager.addrMgr = &fake.AddrManager{FirstIPv4: "192.168.1.100"}
+	manager.routeMgr = &fake.RouteManager{}
+	manager.resolvConfPath = filepath.Join(t.TempDir(), "resolv.conf")
+
+	connections, err := manager.ListConnections()
+	assert.NoError(t, err)
+	if !assert.Len(t, connections, 3) {
+		return
+	}
+	assert.Equal(t, types.Connection{Interface: "wlan0", SSID: "Site", State: "connected", IP: net.ParseIP("192.168.1.100"),
+		Radio: "phy#0", BSSID: "aa:00:00:00:00:01"}, connections[0])
+	assert.Equal(t, "wlan1", connections[1].Interface)
+	assert.Equal(t, "phy#1", connections[1].Radio)
+	assert.Equal(t, "Mgmt", connections[1].SSID)
+	assert.Equal(t, "bb:00:00:00:00:01", connections[1].BSSID)
+	assert.Equal(t, "wlan0_ap", connections[2].Interface)
+	assert.Equal(t, "access point", connections[2].State)
+	assert.Equal(t, "Field Hotspot", connections[2].SSID)
+}
+
+func TestForInterface(t *testing.T) {
+	tmp := t.TempDir()
+	manager := NewManager(&mockSystemExecutor{}, &mockLogger{}, "wlan0", &mockDHCPClient{})
+	manager.runtimeDir = tmp
+
+	assert.Same(t, manager, manager.ForInterface(""))
+	assert.Same(t, manager, manager.ForInterface("wlan0"))
+	usb := manager.ForInterface("wlan1").(*Manager)
+	assert.Equal(t, "wlan1", usb.GetInterface())
+	assert.Equal(t, "wlan0", manager.GetInterface(), "the default radio is unchanged")
+	assert.Equal(t, tmp+"/wpa_supplicant-wlan1.conf", usb.wpaConfigPath())
+	assert.Equal(t, tmp+"/wpa_supplicant-wlan0.conf", manager.wpaConfigPath())
+}
+
+func TestScan_AlwaysTriggersFreshScan(t *testing.T) {
+	// Even when the cache has results, a fresh scan should be triggered and
+	// its results returned
+	wireless := &fake.WirelessManager{
+		Scans:      map[string][]types.BSS{"wlan0": {fake.BSS("aa:bb:cc:dd:ee:ff", "StaleNetwork", 2412, -50)}},
+		FreshScans: map[string][]types.BSS{"wlan0": {fake.BSS("aa:bb:cc:dd:ee:ff", "FreshNetwork", 2412, -50)}},
 	}
 	logger := &mockLogger{}
-	manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+	manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
 	manager.linkMgr = &fake.LinkManager{}
+	manager.wireless = wireless
 
 	networks, err := manager.Scan()
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"wlan0"}, wireless.Triggered, "should always trigger a fresh scan")
 	assert.Len(t, networks, 1)
-
-	// Verify iw scan was called (fresh scan triggered)
-	assert.Contains(t, executor.calledCommands, "iw wlan0 scan",
-		"should always trigger a fresh scan")
-
-	// Verify fresh scan happens before scan dump
-	scanIdx := indexOf(executor.calledCommands, "iw wlan0 scan")
-	dumpIdx := indexOf(executor.calledCommands, "iw wlan0 scan dump")
-	assert.True(t, scanIdx >= 0, "iw scan should have been called")
-	assert.True(t, dumpIdx >= 0, "iw scan dump should have been called")
-	assert.True(t, scanIdx < dumpIdx, "iw scan should be called before iw scan dump")
+	assert.Equal(t, "FreshNetwork", networks[0].SSID)
 }
 
 func TestScan_AdditionalCases(t *testing.T) {
 	t.Run("scan with interface up failure", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"iw wlan0 scan":      "",
-				"iw wlan0 scan dump": "BSS 00:11:22:33:44:55\nSSID: TestNetwork\nsignal: -50.00 dBm",
-			},
-		}
 		logger := &mockLogger{}
 		// Bringing the interface up fails, but Scan only logs a warning and continues.
 		links := &fake.LinkManager{SetUpErr: assert.AnError}
-		manager := &Manager{executor: executor, logger: logger, iface: "wlan0", linkMgr: links}
+		wireless := &fake.WirelessManager{Scans: map[string][]types.BSS{"wlan0": {fake.BSS("00:11:22:33:44:55", "TestNetwork", 2412, -50)}}}
+		manager := &Manager{executor: &mockSystemExecutor{}, logger: logger, iface: "wlan0", linkMgr: links, wireless: wireless}
 
 		networks, err := manager.Scan()
 		assert.NoError(t, err)
@@ -1165,62 +1198,54 @@ func TestScan_AdditionalCases(t *testing.T) {
 }
 
 // Tests for interface-specific process termination (Issue 2 fix)
-
 func TestTerminateWpaSupplicant(t *testing.T) {
-	t.Run("graceful termination via wpa_cli succeeds", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"wpa_cli -i wlan0 terminate": "OK",
-			},
-		}
-		logger := &mockLogger{}
-		manager := &Manager{executor: executor, logger: logger, iface: "wlan0"}
+	t.Run("graceful termination via the control socket succeeds", func(t *testing.T) {
+		executor := &recordingExecutor{}
+		supplicant := &wpafake.Supplicant{}
+		manager := &Manager{executor: executor, logger: &mockLogger{}, iface: "wlan0", supplicant: supplicant}
 
-		// Should not panic
 		manager.terminateWpaSupplicant()
+		assert.Equal(t, []string{"wlan0"}, supplicant.Terminated)
+		assert.Equal(t, -1, indexOf(executor.calledCommands, "pkill -9 wpa_supplicant"))
 	})
 
-	t.Run("fallback to pkill when wpa_cli fails", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"pkill -9 wpa_supplicant": "",
-			},
+	t.Run("fallback to pkill when the control socket fails", func(t *testing.T) {
+		executor := &recordingExecutor{mockSystemExecutor: mockSystemExecutor{
 			errors: map[string]error{
-				"wpa_cli -i wlan0 terminate": assert.AnError,
+				"pkill -9 -f wpa_supplicant.*-i ?wlan0( |$)": assert.AnError,
 			},
-		}
-		logger := &mockLogger{}
-		manager := &Manager{executor: executor, logger: logger, iface: "wlan0"}
+		}}
+		manager := &Manager{executor: executor, logger: &mockLogger{}, iface: "wlan0",
+			supplicant: &wpafake.Supplicant{TerminateErr: assert.AnError}, wireless: &fake.WirelessManager{}}
 
-		// Should not panic, falls back to killing all wpa_supplicant processes
+		// Falls back to killing all wpa_supplicant processes
 		manager.terminateWpaSupplicant()
+		assert.True(t, indexOf(executor.calledCommands, "pkill -9 wpa_supplicant") >= 0)
 	})
 
-	t.Run("uses correct interface in wpa_cli", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"wpa_cli -i eth0 terminate": "OK",
-			},
-		}
-		logger := &mockLogger{}
-		manager := &Manager{executor: executor, logger: logger, iface: "eth0"}
+	t.Run("uses correct interface", func(t *testing.T) {
+		supplicant := &wpafake.Supplicant{}
+		manager := &Manager{executor: &mockSystemExecutor{}, logger: &mockLogger{}, iface: "eth0", supplicant: supplicant}
 
 		manager.terminateWpaSupplicant()
+		assert.Equal(t, []string{"eth0"}, supplicant.Terminated)
 	})
 
-	t.Run("kills all wpa_supplicant in pkill fallback", func(t *testing.T) {
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				"pkill -9 wpa_supplicant": "",
-			},
-			errors: map[string]error{
-				"wpa_cli -i wlp2s0 terminate": assert.AnError,
-			},
-		}
-		logger := &mockLogger{}
-		manager := &Manager{executor: executor, logger: logger, iface: "wlp2s0"}
+	t.Run("interface-specific pkill fallback", func(t *testing.T) {
+		executor := &recordingExecutor{}
+		manager := &Manager{executor: executor, logger: &mockLogger{}, iface: "wlp2s0",
+			supplicant: &wpafake.Supplicant{TerminateErr: assert.AnError}}
 
 		manager.terminateWpaSupplicant()
+		assert.True(t, indexOf(executor.calledCommands, "pkill -9 -f wpa_supplicant.*-i ?wlp2s0( |$)") >= 0)
+		assert.Equal(t, -1, indexOf(executor.calledCommands, "pkill -9 wpa_supplicant"))
+	})
+
+	t.Run("pkill pattern only matches its interface", func(t *testing.T) {
+		pattern := regexp.MustCompile(wpaSupplicantPattern("wlan1"))
+		assert.True(t, pattern.MatchString("wpa_supplicant -B -i wlan1 -c /run/net/wpa_supplicant-wlan1.conf"))
+		assert.True(t, pattern.MatchString("wpa_supplicant -B -iwlan1"))
+		assert.False(t, pattern.MatchString("wpa_supplicant -B -i wlan10 -c /run/net/wpa_supplicant-wlan10.conf"))
 	})
 }
 
@@ -1248,20 +1273,19 @@ func TestDisconnectInterfaceIsolation(t *testing.T) {
 	t.Run("does not kill wpa_supplicant on other interfaces", func(t *testing.T) {
 		// This test verifies that disconnect only affects the managed interface
 		// It uses interface-specific commands rather than global pkill
-		executor := &mockSystemExecutor{
-			commands: map[string]string{
-				// Interface-specific commands for wlan0 only
-				"wpa_cli -i wlan0 terminate": "OK",
-			},
-		}
+		executor := &recordingExecutor{}
 		logger := &mockLogger{}
 		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
 		manager.linkMgr = &fake.LinkManager{}
 		manager.addrMgr = &fake.AddrManager{}
 		manager.routeMgr = &fake.RouteManager{}
+		supplicant := &wpafake.Supplicant{}
+		manager.supplicant = supplicant
 
 		err := manager.Disconnect()
 		assert.NoError(t, err)
+		assert.Equal(t, []string{"wlan0"}, supplicant.Terminated)
+		assert.Equal(t, -1, indexOf(executor.calledCommands, "pkill -9 wpa_supplicant"))
 
 		// DHCP client cleanup is now delegated to dhcpClient.Release()
 		// which kills both udhcpc and dhclient for the specific interface
@@ -1269,20 +1293,16 @@ func TestDisconnectInterfaceIsolation(t *testing.T) {
 }
 
 func TestWaitForAssociationPollingDetectsCrash(t *testing.T) {
-	t.Run("returns error after consecutive wpa_cli failures", func(t *testing.T) {
-		// Simulate wpa_supplicant crash: all wpa_cli calls fail
-		executor := &mockSystemExecutor{
-			commands: map[string]string{},
-			errors: map[string]error{
-				"wpa_cli -i wlan0 status": fmt.Errorf("Failed to connect to non-global ctrl_ifname: wlan0"),
-				"wpa_cli -i wlan0 wait_event CTRL-EVENT-CONNECTED CTRL-EVENT-ASSOC-REJECT CTRL-EVENT-DISCONNECTED CTRL-EVENT-TEMP-DISABLED CTRL-EVENT-AUTH-REJECT": fmt.Errorf("Failed to connect to non-global ctrl_ifname: wlan0"),
-			},
-		}
+	socketGone := fmt.Errorf("connecting to wpa_supplicant on wlan0: no such file or directory")
+
+	t.Run("returns error after consecutive status failures", func(t *testing.T) {
+		// Simulate wpa_supplicant crash: the control socket is gone
 		logger := &mockLogger{}
-		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+		manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
+		manager.supplicant = &wpafake.Supplicant{AttachErr: socketGone, StatusErr: socketGone}
 		manager.associationTimeout = 5 * time.Second
 
-		err := manager.waitForAssociation("TestSSID")
+		err := manager.waitForAssociation("TestSSID", false)
 
 		assert.Error(t, err)
 		// Should detect crash quickly, not wait the full 5s timeout
@@ -1291,90 +1311,116 @@ func TestWaitForAssociationPollingDetectsCrash(t *testing.T) {
 		assert.NotContains(t, err.Error(), "timeout")
 	})
 
-	t.Run("does not false-positive on transient wpa_cli failure", func(t *testing.T) {
-		// First few wpa_cli calls fail, then succeeds — should not report crash
-		callNum := 0
-		executor := &countingExecutor{
-			mockSystemExecutor: mockSystemExecutor{
-				commands: map[string]string{},
-			},
-			statusFunc: func(n int) (string, error) {
-				if n < 2 {
-					return "", fmt.Errorf("temporarily unavailable")
-				}
-				return "wpa_state=COMPLETED\nssid=TestSSID", nil
-			},
-		}
-		_ = callNum
+	t.Run("does not false-positive on transient status failure", func(t *testing.T) {
+		// First few STATUS requests fail, then succeed — should not report crash
 		logger := &mockLogger{}
-		manager := NewManager(executor, logger, "wlan0", &mockDHCPClient{})
+		manager := NewManager(&mockSystemExecutor{}, logger, "wlan0", &mockDHCPClient{})
+		manager.supplicant = &wpafake.Supplicant{
+			AttachErr:  socketGone,
+			StatusErrs: []error{fmt.Errorf("temporarily unavailable"), fmt.Errorf("temporarily unavailable")},
+			Statuses:   []map[string]string{{"wpa_state": "COMPLETED", "ssid": "TestSSID"}},
+		}
 		manager.associationTimeout = 5 * time.Second
 
-		err := manager.waitForAssociation("TestSSID")
+		err := manager.waitForAssociation("TestSSID", false)
 
 		assert.NoError(t, err)
 	})
 }
 
-// countingExecutor tracks call counts per command for fine-grained control
-type countingExecutor struct {
-	mockSystemExecutor
-	statusFunc  func(callNum int) (string, error)
-	statusCalls int
-}
-
-func (c *countingExecutor) Execute(cmd string, args ...string) (string, error) {
-	fullCmd := cmd
-	for _, arg := range args {
-		fullCmd += " " + arg
+func TestWaitForAssociationEvents(t *testing.T) {
+	scanning := []map[string]string{{"wpa_state": "SCANNING"}}
+	tests := []struct {
+		name     string
+		events   []types.SupplicantEvent
+		statuses []map[string]string
+		wantErr  error
+		wantMsg  string
+	}{
+		{name: "connected", events: []types.SupplicantEvent{
+			wpafake.Event("CTRL-EVENT-SCAN-RESULTS"), wpafake.Event("CTRL-EVENT-DISCONNECTED", "reason", "15"), wpafake.Event("CTRL-EVENT-CONNECTED"),
+		}, statuses: scanning},
+		{name: "associated before attach", statuses: []map[string]string{{"wpa_state": "COMPLETED", "ssid": "Cafe"}}},
+		{name: "wrong key", events: []types.SupplicantEvent{
+			wpafake.Event("CTRL-EVENT-DISCONNECTED", "reason", "15"),
+			wpafake.Event("CTRL-EVENT-SSID-TEMP-DISABLED", "id", "0", "ssid", "Cafe", "auth_failures", "1", "reason", "WRONG_KEY"),
+		}, statuses: scanning, wantErr: ErrWrongKey, wantMsg: `"Cafe": wrong password`},
+		{name: "association timed out", events: []types.SupplicantEvent{
+			{Name: "CTRL-EVENT-ASSOC-REJECT", Fields: map[string]string{"status_code": "1"}, Text: "CTRL-EVENT-ASSOC-REJECT bssid=00:11:22:33:44:55 status_code=1 timeout"},
+		}, statuses: scanning, wantErr: ErrAuthTimeout},
+		{name: "stuck authenticating", statuses: []map[string]string{{"wpa_state": "SCANNING"}, {"wpa_state": "AUTHENTICATING"}},
+			wantErr: ErrAuthTimeout},
+		{name: "network not found", events: []types.SupplicantEvent{
+			wpafake.Event("CTRL-EVENT-NETWORK-NOT-FOUND"), wpafake.Event("CTRL-EVENT-NETWORK-NOT-FOUND"), wpafake.Event("CTRL-EVENT-NETWORK-NOT-FOUND"),
+		}, statuses: scanning, wantErr: ErrNetworkNotFound, wantMsg: `"Cafe": access point not found`},
+		{name: "not found before timeout", events: []types.SupplicantEvent{wpafake.Event("CTRL-EVENT-NETWORK-NOT-FOUND")},
+			statuses: scanning, wantErr: ErrNetworkNotFound},
+		{name: "association rejected", events: []types.SupplicantEvent{wpafake.Event("CTRL-EVENT-ASSOC-REJECT", "status_code", "17")},
+			statuses: scanning, wantMsg: "association rejected (status 17)"},
+		{name: "plain timeout", statuses: scanning, wantMsg: `timeout waiting for association with "Cafe"`},
 	}
-	if fullCmd == "wpa_cli -i wlan0 status" && c.statusFunc != nil {
-		n := c.statusCalls
-		c.statusCalls++
-		return c.statusFunc(n)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supplicant := &wpafake.Supplicant{Events: tt.events, Statuses: tt.statuses}
+			manager := NewManager(&mockSystemExecutor{}, &mockLogger{}, "wlan0", &mockDHCPClient{})
+			manager.supplicant = supplicant
+
+			err := manager.waitForAssociation("Cafe", false)
+			assert.Equal(t, []string{"wlan0"}, supplicant.Attached)
+			if tt.wantErr == nil && tt.wantMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			if tt.wantMsg != "" {
+				assert.EqualError(t, err, tt.wantMsg)
+			}
+		})
 	}
-	return c.mockSystemExecutor.Execute(cmd, args...)
 }
 
-func (c *countingExecutor) ExecuteWithTimeout(timeout time.Duration, cmd string, args ...string) (string, error) {
-	fullCmd := cmd
-	for _, arg := range args {
-		fullCmd += " " + arg
-	}
-	if fullCmd == "wpa_cli -i wlan0 status" && c.statusFunc != nil {
-		n := c.statusCalls
-		c.statusCalls++
-		return c.statusFunc(n)
-	}
-	// For wait_event, use the error map
-	return c.mockSystemExecutor.Execute(cmd, args...)
+// terminateRecorder records how many interfaces had been flushed at each
+// Terminate, to check the order of the two.
+type terminateRecorder struct {
+	wpafake.Supplicant
+	flushed            func() int
+	flushedAtTerminate []int
+}
+
+func (r *terminateRecorder) Terminate(iface string) error {
+	r.flushedAtTerminate = append(r.flushedAtTerminate, r.flushed())
+	return r.Supplicant.Terminate(iface)
 }
 
 func TestOtherInterfaceAssociated(t *testing.T) {
-	iwDev := `phy#0
-	Interface wlan0
-		type managed
-		ssid HomeNet
-	Interface wlan1
-		type managed`
+	ifaces := []types.WirelessInterface{
+		{Name: "wlan0", Type: "station", SSID: "HomeNet"},
+		{Name: "wlan1", Type: "station"},
+	}
 
 	t.Run("another interface associated", func(t *testing.T) {
-		// From wlan1's view, wlan0 has an ssid line -> associated.
-		executor := &mockSystemExecutor{commands: map[string]string{"iw dev": iwDev}}
-		m := &Manager{iface: "wlan1", executor: executor, logger: &mockLogger{}}
+		// From wlan1's view, wlan0 has an SSID -> associated.
+		m := &Manager{iface: "wlan1", logger: &mockLogger{}, wireless: &fake.WirelessManager{Ifaces: ifaces}}
 		assert.True(t, m.otherInterfaceAssociated())
 	})
 
 	t.Run("only our interface associated", func(t *testing.T) {
-		// From wlan0's view, only wlan0 has an ssid -> no OTHER interface up.
-		executor := &mockSystemExecutor{commands: map[string]string{"iw dev": iwDev}}
-		m := &Manager{iface: "wlan0", executor: executor, logger: &mockLogger{}}
+		// From wlan0's view, only wlan0 has an SSID -> no OTHER interface up.
+		m := &Manager{iface: "wlan0", logger: &mockLogger{}, wireless: &fake.WirelessManager{Ifaces: ifaces}}
+		assert.False(t, m.otherInterfaceAssociated())
+	})
+
+	t.Run("an access point on another interface is not an association", func(t *testing.T) {
+		m := &Manager{iface: "wlan1", logger: &mockLogger{}, wireless: &fake.WirelessManager{Ifaces: []types.WirelessInterface{
+			{Name: "wlan0", Type: "ap", SSID: "Hotspot"},
+		}}}
 		assert.False(t, m.otherInterfaceAssociated())
 	})
 
-	t.Run("iw error fails safe to true", func(t *testing.T) {
-		executor := &mockSystemExecutor{errors: map[string]error{"iw dev": assert.AnError}}
-		m := &Manager{iface: "wlan0", executor: executor, logger: &mockLogger{}}
+	t.Run("nl80211 error fails safe to true", func(t *testing.T) {
+		m := &Manager{iface: "wlan0", logger: &mockLogger{}, wireless: &fake.WirelessManager{InterfacesErr: assert.AnError}}
 		assert.True(t, m.otherInterfaceAssociated())
 	})
 }
@@ -0,0 +1,275 @@
+package wifi
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// WPS failures reported by wpa_supplicant.
+var (
+	ErrWPSTimeout = errors.New("no access point answered WPS within 2 minutes")
+	ErrWPSOverlap = errors.New("more than one access point is in WPS push-button mode")
+)
+
+// wpsWalkTime is how long an access point stays in WPS mode; wpa_supplicant
+// reports WPS-TIMEOUT once it has passed, the margin covers a late event.
+const wpsWalkTime = 2*time.Minute + 10*time.Second
+
+// WPS attribute types (Wi-Fi Simple Configuration, section 12).
+const (
+	wpsAttrAuthType   = 0x1003
+	wpsAttrCredential = 0x100e
+	wpsAttrEncrType   = 0x100f
+	wpsAttrMAC        = 0x1020
+	wpsAttrNetworkKey = 0x1027
+	wpsAttrSSID       = 0x1045
+)
+
+// WPS authentication and encryption type flags.
+const (
+	wpsAuthWPAPSK  = 0x0002
+	wpsAuthWPAEAP  = 0x0008
+	wpsAuthWPA2EAP = 0x0010
+	wpsAuthWPA2PSK = 0x0020
+	wpsEncrWEP     = 0x0002
+)
+
+// wpsConfig runs wpa_supplicant without networks; WPS adds the one it
+// learns. wps_cred_processing=2 both uses the credential and reports it
+// (WPS-CRED-RECEIVED) so it can be saved.
+const wpsConfig = "ctrl_interface=/run/wpa_supplicant\nwps_cred_processing=2\n"
+
+// ConnectWPS joins a network with WPS: push-button when pin is empty,
+// otherwise the PIN entered on the access point. bssid limits WPS to one
+// access point ("" for any). On success the interface is associated and
+// configured, and the provisioned credential is returned.
+func (m *Manager) ConnectWPS(bssid, pin, hostname string) (*types.WPSCredential, error) {
+	if bssid != "" && !isValidBSSID(bssid) {
+		return nil, fmt.Errorf("invalid BSSID %q", bssid)
+	}
+	if pin != "" {
+		if err := ValidateWPSPIN(pin); err != nil {
+			return nil, err
+		}
+	}
+	if hostname != "" {
+		if err := types.ValidateHostname(hostname); err != nil {
+			return nil, fmt.Errorf("invalid hostname: %w", err)
+		}
+	}
+	m.logger.Info("Connecting with WPS", "interface", m.iface, "bssid", bssid, "pin", pin != "")
+
+	if currentSSID, _ := m.getCurrentSSID(); currentSSID != "" {
+		m.logger.Debug("Disconnecting from current network", "currentSSID", currentSSID)
+		_ = m.Disconnect()
+	}
+
+	cleanup, err := m.startSupplicant(wpsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	cred, err := m.runWPS(bssid, pin)
+	if err != nil {
+		m.terminateWpaSupplicant()
+		return nil, err
+	}
+	m.logger.Info("WPS provisioned network", "ssid", cred.SSID, "bssid", cred.BSSID)
+
+	if err := m.obtainDHCP(hostname); err != nil {
+		m.terminateWpaSupplicant()
+		return nil, fmt.Errorf("failed to obtain DHCP lease: %w", err)
+	}
+	return cred, nil
+}
+
+// runWPS starts WPS on the running wpa_supplicant and waits for the
+// credential and the association with the network it describes.
+func (m *Manager) runWPS(bssid, pin string) (*types.WPSCredential, error) {
+	monitor, err := m.supplicant.Attach(m.iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch wpa_supplicant: %w", err)
+	}
+	defer monitor.Close()
+
+	if err := m.supplicant.StartWPS(m.iface, bssid, pin); err != nil {
+		return nil, fmt.Errorf("failed to start WPS: %w", err)
+	}
+	cred, err := m.waitForWPS(monitor, pin != "")
+	if err != nil {
+		return nil, err
+	}
+
+	// wpa_supplicant reconnects with the new credential by itself.
+	timeout := m.associationTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	start := time.Now()
+	err = m.waitForAssociationEvents(monitor, cred.SSID, timeout, false)
+	if errors.Is(err, errEventsLost) {
+		err = m.waitForAssociationPolling(cred.SSID, timeout-time.Since(start))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to associate with access point: %w", err)
+	}
+	return cred, nil
+}
+
+// waitForWPS waits for the WPS exchange to finish and returns the
+// credential the access point sent.
+func (m *Manager) waitForWPS(monitor types.SupplicantMonitor, withPIN bool) (*types.WPSCredential, error) {
+	deadline := time.Now().Add(wpsWalkTime)
+	var cred *types.WPSCredential
+	for {
+		event, err := monitor.Next(time.Until(deadline))
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, ErrWPSTimeout
+		}
+		if err != nil {
+			return nil, fmt.Errorf("lost wpa_supplicant event connection during WPS: %v", err)
+		}
+		m.logger.Debug("wpa_supplicant event", "event", event.Name)
+
+		switch event.Name {
+		case "WPS-CRED-RECEIVED":
+			words := strings.Fields(event.Text)
+			if len(words) < 2 {
+				return nil, fmt.Errorf("WPS credential event without data")
+			}
+			if cred, err = parseWPSCredential(words[1]); err != nil {
+				return nil, fmt.Errorf("failed to read WPS credential: %w", err)
+			}
+		case "WPS-SUCCESS":
+			if cred == nil {
+				return nil, fmt.Errorf("WPS succeeded but the access point sent no credential")
+			}
+			return cred, nil
+		case "WPS-FAIL":
+			// config_error 18: the device password (PIN) didn't match.
+			if withPIN && event.Fields["config_error"] == "18" {
+				return nil, fmt.Errorf("WPS failed: the access point rejected the PIN")
+			}
+			return nil, fmt.Errorf("WPS failed (msg %s, config_error %s)", event.Fields["msg"], event.Fields["config_error"])
+		case "WPS-TIMEOUT":
+			return nil, ErrWPSTimeout
+		case "WPS-OVERLAP-DETECTED":
+			return nil, fmt.Errorf("%w, give the BSSID of yours", ErrWPSOverlap)
+		}
+	}
+}
+
+// parseWPSCredential decodes the hex Credential attribute of a
+// WPS-CRED-RECEIVED event.
+func parseWPSCredential(data string) (*types.WPSCredential, error) {
+	raw, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %w", err)
+	}
+	attrs, err := parseWPSAttributes(raw)
+	if err != nil {
+		return nil, err
+	}
+	// The event carries the attribute with its header; unwrap it.
+	if inner, ok := attrs[wpsAttrCredential]; ok {
+		if attrs, err = parseWPSAttributes(inner); err != nil {
+			return nil, err
+		}
+	}
+
+	cred := &types.WPSCredential{
+		SSID: string(attrs[wpsAttrSSID]),
+		PSK:  strings.TrimRight(string(attrs[wpsAttrNetworkKey]), "\x00"),
+	}
+	if cred.SSID == "" {
+		return nil, fmt.Errorf("credential has no SSID")
+	}
+	if mac := attrs[wpsAttrMAC]; len(mac) == 6 {
+		cred.BSSID = net.HardwareAddr(mac).String()
+	}
+	if auth, ok := attrs[wpsAttrAuthType]; ok && len(auth) == 2 {
+		flags := binary.BigEndian.Uint16(auth)
+		if flags&(wpsAuthWPAEAP|wpsAuthWPA2EAP) != 0 && flags&(wpsAuthWPAPSK|wpsAuthWPA2PSK) == 0 {
+			return nil, fmt.Errorf("%q uses 802.1X authentication, which is not supported", cred.SSID)
+		}
+	}
+	if encr, ok := attrs[wpsAttrEncrType]; ok && len(encr) == 2 && binary.BigEndian.Uint16(encr) == wpsEncrWEP {
+		return nil, fmt.Errorf("%q uses WEP, which is not supported", cred.SSID)
+	}
+	if err := types.ValidatePSK(cred.PSK); err != nil {
+		return nil, fmt.Errorf("invalid network key: %w", err)
+	}
+	return cred, nil
+}
+
+// parseWPSAttributes splits data into its type-length-value attributes,
+// keeping the first of repeated types.
+func parseWPSAttributes(data []byte) (map[uint16][]byte, error) {
+	attrs := make(map[uint16][]byte)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated attribute header")
+		}
+		typ, length := binary.BigEndian.Uint16(data), int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < 4+length {
+			return nil, fmt.Errorf("attribute 0x%04x is truncated", typ)
+		}
+		if _, seen := attrs[typ]; !seen {
+			attrs[typ] = data[4 : 4+length]
+		}
+		data = data[4+length:]
+	}
+	return attrs, nil
+}
+
+// GenerateWPSPIN returns a random 8-digit WPS PIN with a valid checksum.
+func GenerateWPSPIN() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(10_000_000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate PIN: %w", err)
+	}
+	pin := int(n.Int64())
+	return fmt.Sprintf("%07d%d", pin, wpsPINChecksum(pin)), nil
+}
+
+// ValidateWPSPIN checks that pin is 4 digits, or 8 digits whose last one
+// is the checksum of the first seven.
+func ValidateWPSPIN(pin string) error {
+	if len(pin) != 4 && len(pin) != 8 {
+		return fmt.Errorf("WPS PIN must have 4 or 8 digits")
+	}
+	value := 0
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("WPS PIN must have 4 or 8 digits")
+		}
+		value = value*10 + int(c-'0')
+	}
+	if len(pin) == 8 && wpsPINChecksum(value/10) != value%10 {
+		return fmt.Errorf("WPS PIN %s has a wrong checksum digit", pin)
+	}
+	return nil
+}
+
+// wpsPINChecksum returns the checksum digit of the 7-digit pin.
+func wpsPINChecksum(pin int) int {
+	accum := 0
+	for pin > 0 {
+		accum += 3 * (pin % 10)
+		pin /= 10
+		accum += pin % 10
+		pin /= 10
+	}
+	return (10 - accum%10) % 10
+}
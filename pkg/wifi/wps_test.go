@@ -0,0 +1,159 @@
+package wifi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/angelfreak/net/pkg/netlink/fake"
+	"github.com/angelfreak/net/pkg/types"
+	wpafake "github.com/angelfreak/net/pkg/wpa/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wpsAttr encodes one WPS type-length-value attribute.
+func wpsAttr(typ uint16, value []byte) []byte {
+	b := make([]byte, 4, 4+len(value))
+	binary.BigEndian.PutUint16(b, typ)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(value)))
+	return append(b, value...)
+}
+
+// wpsCredential returns the hex Credential attribute of a WPS-CRED-RECEIVED
+// event for a WPA2-PSK network.
+func wpsCredential(ssid, key string) string {
+	var inner []byte
+	inner = append(inner, wpsAttr(0x1026, []byte{1})...)
+	inner = append(inner, wpsAttr(wpsAttrSSID, []byte(ssid))...)
+	inner = append(inner, wpsAttr(wpsAttrAuthType, []byte{0, wpsAuthWPA2PSK})...)
+	inner = append(inner, wpsAttr(wpsAttrEncrType, []byte{0, 0x08})...)
+	inner = append(inner, wpsAttr(wpsAttrNetworkKey, []byte(key))...)
+	inner = append(inner, wpsAttr(wpsAttrMAC, []byte{0xaa, 0xbb, 0xcc, 0, 0, 1})...)
+	return hex.EncodeToString(wpsAttr(wpsAttrCredential, inner))
+}
+
+func credReceived(data string) types.SupplicantEvent {
+	return types.SupplicantEvent{Name: "WPS-CRED-RECEIVED", Fields: map[string]string{}, Text: "WPS-CRED-RECEIVED " + data}
+}
+
+func TestParseWPSCredential(t *testing.T) {
+	cred, err := parseWPSCredential(wpsCredential("Printer Net", "s3cret-key\x00"))
+	require.NoError(t, err)
+	assert.Equal(t, &types.WPSCredential{SSID: "Printer Net", PSK: "s3cret-key", BSSID: "aa:bb:cc:00:00:01"}, cred)
+
+	open := hex.EncodeToString(wpsAttr(wpsAttrSSID, []byte("Lobby")))
+	cred, err = parseWPSCredential(open)
+	require.NoError(t, err)
+	assert.Equal(t, &types.WPSCredential{SSID: "Lobby"}, cred)
+
+	eap := hex.EncodeToString(append(wpsAttr(wpsAttrSSID, []byte("Corp")), wpsAttr(wpsAttrAuthType, []byte{0, wpsAuthWPA2EAP})...))
+	_, err = parseWPSCredential(eap)
+	assert.EqualError(t, err, `"Corp" uses 802.1X authentication, which is not supported`)
+
+	_, err = parseWPSCredential("100e00ff")
+	assert.EqualError(t, err, "attribute 0x100e is truncated")
+	_, err = parseWPSCredential("zz")
+	assert.ErrorContains(t, err, "invalid hex")
+	_, err = parseWPSCredential(hex.EncodeToString(wpsAttr(wpsAttrNetworkKey, []byte("12345678"))))
+	assert.EqualError(t, err, "credential has no SSID")
+}
+
+func TestWPSPIN(t *testing.T) {
+	assert.NoError(t, ValidateWPSPIN("12345670"))
+	assert.NoError(t, ValidateWPSPIN("1234"))
+	assert.EqualError(t, ValidateWPSPIN("12345678"), "WPS PIN 12345678 has a wrong checksum digit")
+	assert.EqualError(t, ValidateWPSPIN("12a4"), "WPS PIN must have 4 or 8 digits")
+	assert.EqualError(t, ValidateWPSPIN("123456"), "WPS PIN must have 4 or 8 digits")
+
+	for i := 0; i < 20; i++ {
+		pin, err := GenerateWPSPIN()
+		require.NoError(t, err)
+		assert.NoError(t, ValidateWPSPIN(pin), pin)
+	}
+}
+
+func newWPSTestManager(t *testing.T, supplicant *wpafake.Supplicant) (*Manager, string) {
+	t.Helper()
+	tmp := t.TempDir()
+	executor := &mockSystemExecutor{
+		commands: map[string]string{
+			"wpa_supplicant -B -i wlan0 -c " + tmp + "/wpa_supplicant-wlan0.conf": "",
+		},
+	}
+	manager := NewManager(executor, &mockLogger{}, "wlan0", &mockDHCPClient{})
+	manager.linkMgr = &fake.LinkManager{}
+	manager.addrMgr = &fake.AddrManager{}
+	manager.routeMgr = &fake.RouteManager{}
+	manager.wireless = &fake.WirelessManager{}
+	manager.supplicant = supplicant
+	manager.runtimeDir = tmp
+	return manager, tmp
+}
+
+func TestConnectWPS(t *testing.T) {
+	supplicant := &wpafake.Supplicant{
+		Statuses: []map[string]string{{"wpa_state": "SCANNING"}},
+		Events: []types.SupplicantEvent{
+			wpafake.Event("WPS-PBC-ACTIVE"),
+			credReceived(wpsCredential("Printer Net", "s3cret-key")),
+			wpafake.Event("WPS-SUCCESS"),
+			wpafake.Event("CTRL-EVENT-DISCONNECTED", "reason", "3"),
+			wpafake.Event("CTRL-EVENT-CONNECTED"),
+		},
+	}
+	manager, tmp := newWPSTestManager(t, supplicant)
+
+	cred, err := manager.ConnectWPS("AA:BB:CC:00:00:01", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Printer Net", cred.SSID)
+	assert.Equal(t, "s3cret-key", cred.PSK)
+	assert.Equal(t, []wpafake.WPSCall{{Iface: "wlan0", BSSID: "AA:BB:CC:00:00:01"}}, supplicant.WPS)
+	_, err = os.Stat(tmp + "/wpa_supplicant-wlan0.conf")
+	assert.True(t, os.IsNotExist(err), "config file is removed")
+}
+
+func TestConnectWPS_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		pin     string
+		events  []types.SupplicantEvent
+		wantErr error
+		wantMsg string
+	}{
+		{name: "timeout", events: []types.SupplicantEvent{wpafake.Event("WPS-TIMEOUT")}, wantErr: ErrWPSTimeout},
+		{name: "no events", wantErr: ErrWPSTimeout},
+		{name: "overlap", events: []types.SupplicantEvent{wpafake.Event("WPS-OVERLAP-DETECTED")}, wantErr: ErrWPSOverlap},
+		{name: "wrong PIN", pin: "12345670", events: []types.SupplicantEvent{wpafake.Event("WPS-FAIL", "msg", "8", "config_error", "18")},
+			wantMsg: "WPS failed: the access point rejected the PIN"},
+		{name: "fail", events: []types.SupplicantEvent{wpafake.Event("WPS-FAIL", "msg", "4", "config_error", "0")},
+			wantMsg: "WPS failed (msg 4, config_error 0)"},
+		{name: "no credential", events: []types.SupplicantEvent{wpafake.Event("WPS-SUCCESS")},
+			wantMsg: "WPS succeeded but the access point sent no credential"},
+		{name: "not associated", events: []types.SupplicantEvent{
+			credReceived(wpsCredential("Printer Net", "s3cret-key")), wpafake.Event("WPS-SUCCESS"),
+		}, wantMsg: `failed to associate with access point: timeout waiting for association with "Printer Net"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supplicant := &wpafake.Supplicant{Statuses: []map[string]string{{"wpa_state": "SCANNING"}}, Events: tt.events}
+			manager, _ := newWPSTestManager(t, supplicant)
+
+			_, err := manager.ConnectWPS("", tt.pin, "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			if tt.wantMsg != "" {
+				assert.EqualError(t, err, tt.wantMsg)
+			}
+			assert.NotEmpty(t, supplicant.Terminated, "wpa_supplicant is stopped on failure")
+		})
+	}
+
+	manager, _ := newWPSTestManager(t, &wpafake.Supplicant{})
+	_, err := manager.ConnectWPS("", "1234567", "")
+	assert.EqualError(t, err, "WPS PIN must have 4 or 8 digits")
+	_, err = manager.ConnectWPS("printer", "", "")
+	assert.EqualError(t, err, `invalid BSSID "printer"`)
+}
@@ -0,0 +1,75 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Compile-time assertion that the fake satisfies the interface.
+var _ types.HostapdClient = (*Hostapd)(nil)
+
+// DeauthCall records one Deauthenticate call.
+type DeauthCall struct {
+	Iface string
+	MAC   string
+}
+
+// Hostapd is an in-memory fake implementation of types.HostapdClient.
+//
+// Clients maps an interface to its associated stations; Deauthenticate
+// records the call and drops the station. Accept and Deny hold the lists of
+// the last SetACL per interface. Set the *Err fields to force a method to
+// fail.
+type Hostapd struct {
+	Clients map[string][]types.HotspotClient
+
+	Deauthed []DeauthCall
+	Accept   map[string][]string
+	Deny     map[string][]string
+
+	StationsErr error
+	DeauthErr   error
+	ACLErr      error
+}
+
+// Stations returns a copy of the stations of iface.
+func (h *Hostapd) Stations(iface string) ([]types.HotspotClient, error) {
+	if h.StationsErr != nil {
+		return nil, h.StationsErr
+	}
+	return append([]types.HotspotClient(nil), h.Clients[iface]...), nil
+}
+
+// Deauthenticate records the call and removes mac from iface's stations.
+func (h *Hostapd) Deauthenticate(iface, mac string) error {
+	if h.DeauthErr != nil {
+		return h.DeauthErr
+	}
+	h.Deauthed = append(h.Deauthed, DeauthCall{Iface: iface, MAC: mac})
+	stations := h.Clients[iface]
+	for i, sta := range stations {
+		if strings.EqualFold(sta.MAC, mac) {
+			h.Clients[iface] = append(stations[:i:i], stations[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("DEAUTHENTICATE %s: FAIL", mac)
+}
+
+// SetACL records accept and deny as the lists of iface.
+func (h *Hostapd) SetACL(iface string, accept, deny []string) error {
+	if h.ACLErr != nil {
+		return h.ACLErr
+	}
+	if h.Accept == nil {
+		h.Accept = map[string][]string{}
+	}
+	if h.Deny == nil {
+		h.Deny = map[string][]string{}
+	}
+	h.Accept[iface] = append([]string(nil), accept...)
+	h.Deny[iface] = append([]string(nil), deny...)
+	return nil
+}
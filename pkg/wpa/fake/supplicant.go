@@ -0,0 +1,180 @@
+// Package fake provides an in-memory test double for types.SupplicantClient.
+package fake
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Compile-time assertion that the fake satisfies the interface.
+var _ types.SupplicantClient = (*Supplicant)(nil)
+
+// Supplicant is an in-memory fake implementation of types.SupplicantClient.
+//
+// Statuses are returned by successive Status calls, the last one repeating
+// (none: an empty status); a non-nil StatusErrs entry fails the call of the
+// same index instead. Events are delivered, in order, by the monitor Attach
+// returns; once they run out Next reports a timeout. Mutating calls are
+// recorded. Set the *Err fields to force a method to fail.
+type Supplicant struct {
+	Statuses   []map[string]string
+	StatusErrs []error
+	Signal     map[string]string
+	Events     []types.SupplicantEvent
+
+	StatusCalls  int
+	Added        int
+	Set          []SetCall
+	Reconfigured []string
+	Reassociated []string
+	Terminated   []string
+	Attached     []string
+	WPS          []WPSCall
+
+	StatusErr      error
+	AddErr         error
+	SetErr         error
+	ReconfigureErr error
+	ReassociateErr error
+	TerminateErr   error
+	AttachErr      error
+	WPSErr         error
+}
+
+// SetCall records the arguments of a single SetNetwork invocation.
+type SetCall struct {
+	Iface string
+	ID    int
+	Name  string
+	Value string
+}
+
+// WPSCall records the arguments of a single StartWPS invocation.
+type WPSCall struct {
+	Iface string
+	BSSID string
+	PIN   string
+}
+
+// Status returns the next of Statuses.
+func (s *Supplicant) Status(iface string) (map[string]string, error) {
+	n := s.StatusCalls
+	s.StatusCalls++
+	if s.StatusErr != nil {
+		return nil, s.StatusErr
+	}
+	if n < len(s.StatusErrs) && s.StatusErrs[n] != nil {
+		return nil, s.StatusErrs[n]
+	}
+	if len(s.Statuses) == 0 {
+		return map[string]string{}, nil
+	}
+	if n >= len(s.Statuses) {
+		n = len(s.Statuses) - 1
+	}
+	return s.Statuses[n], nil
+}
+
+// SignalPoll returns Signal.
+func (s *Supplicant) SignalPoll(iface string) (map[string]string, error) {
+	if s.StatusErr != nil {
+		return nil, s.StatusErr
+	}
+	return s.Signal, nil
+}
+
+// AddNetwork returns the next network id.
+func (s *Supplicant) AddNetwork(iface string) (int, error) {
+	if s.AddErr != nil {
+		return -1, s.AddErr
+	}
+	s.Added++
+	return s.Added - 1, nil
+}
+
+// SetNetwork records the call.
+func (s *Supplicant) SetNetwork(iface string, id int, name, value string) error {
+	if s.SetErr != nil {
+		return s.SetErr
+	}
+	s.Set = append(s.Set, SetCall{Iface: iface, ID: id, Name: name, Value: value})
+	return nil
+}
+
+// Reconfigure records the call.
+func (s *Supplicant) Reconfigure(iface string) error {
+	if s.ReconfigureErr != nil {
+		return s.ReconfigureErr
+	}
+	s.Reconfigured = append(s.Reconfigured, iface)
+	return nil
+}
+
+// Reassociate records the call.
+func (s *Supplicant) Reassociate(iface string) error {
+	if s.ReassociateErr != nil {
+		return s.ReassociateErr
+	}
+	s.Reassociated = append(s.Reassociated, iface)
+	return nil
+}
+
+// Terminate records the call.
+func (s *Supplicant) Terminate(iface string) error {
+	if s.TerminateErr != nil {
+		return s.TerminateErr
+	}
+	s.Terminated = append(s.Terminated, iface)
+	return nil
+}
+
+// StartWPS records the call.
+func (s *Supplicant) StartWPS(iface, bssid, pin string) error {
+	if s.WPSErr != nil {
+		return s.WPSErr
+	}
+	s.WPS = append(s.WPS, WPSCall{Iface: iface, BSSID: bssid, PIN: pin})
+	return nil
+}
+
+// Attach records the call and returns a monitor delivering Events.
+func (s *Supplicant) Attach(iface string) (types.SupplicantMonitor, error) {
+	if s.AttachErr != nil {
+		return nil, s.AttachErr
+	}
+	s.Attached = append(s.Attached, iface)
+	return &monitor{s: s}, nil
+}
+
+type monitor struct {
+	s    *Supplicant
+	next int
+}
+
+// Next returns the next of Events, or a timeout once they run out.
+func (m *monitor) Next(timeout time.Duration) (types.SupplicantEvent, error) {
+	if m.next >= len(m.s.Events) {
+		return types.SupplicantEvent{}, fmt.Errorf("waiting for wpa_supplicant events: %w", os.ErrDeadlineExceeded)
+	}
+	m.next++
+	return m.s.Events[m.next-1], nil
+}
+
+// Close is a no-op.
+func (m *monitor) Close() error {
+	return nil
+}
+
+// Event returns an event with the given name and key=value fields, e.g.
+// Event("CTRL-EVENT-SSID-TEMP-DISABLED", "reason", "WRONG_KEY").
+func Event(name string, kv ...string) types.SupplicantEvent {
+	event := types.SupplicantEvent{Name: name, Fields: make(map[string]string), Text: name}
+	for i := 0; i+1 < len(kv); i += 2 {
+		event.Fields[kv[i]] = kv[i+1]
+		event.Text += fmt.Sprintf(" %s=%s", kv[i], kv[i+1])
+	}
+	return event
+}
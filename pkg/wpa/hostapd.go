@@ -0,0 +1,132 @@
+package wpa
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Compile-time assertion that the impl satisfies the interface.
+var _ types.HostapdClient = (*HostapdClient)(nil)
+
+// HostapdCtrlDir is the ctrl_interface directory of the hostapd configs we
+// generate.
+const HostapdCtrlDir = "/run/hostapd"
+
+// maxStations bounds the STA-NEXT walk; hostapd allows at most 2007
+// stations per BSS.
+const maxStations = 2007
+
+// HostapdClient is the control-socket implementation of
+// types.HostapdClient. Like Client, it holds no connection.
+type HostapdClient struct {
+	dir string // ctrl_interface directory, overridable for tests
+}
+
+// NewHostapdClient returns a client for hostapd instances under
+// HostapdCtrlDir.
+func NewHostapdClient() *HostapdClient {
+	return &HostapdClient{dir: HostapdCtrlDir}
+}
+
+// do opens the socket of iface, runs fn on it and closes it.
+func (cl *HostapdClient) do(iface string, fn func(c *conn) error) error {
+	dir := cl.dir
+	if dir == "" {
+		dir = HostapdCtrlDir
+	}
+	c, err := dial(dir, iface, "hostapd")
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	return fn(c)
+}
+
+// Stations walks the station table of iface with STA-FIRST and STA-NEXT.
+func (cl *HostapdClient) Stations(iface string) ([]types.HotspotClient, error) {
+	var stations []types.HotspotClient
+	err := cl.do(iface, func(c *conn) error {
+		cmd := "STA-FIRST"
+		for len(stations) < maxStations {
+			reply, err := c.request(cmd)
+			if err != nil {
+				return err
+			}
+			sta, ok := parseStation(reply)
+			if !ok {
+				return nil // end of the table
+			}
+			stations = append(stations, sta)
+			cmd = "STA-NEXT " + sta.MAC
+		}
+		return nil
+	})
+	return stations, err
+}
+
+// parseStation parses a STA-FIRST/STA-NEXT reply: the station's MAC on the
+// first line, then key=value lines. An empty or FAIL reply ends the table.
+func parseStation(reply string) (types.HotspotClient, bool) {
+	var sta types.HotspotClient
+	first, rest, _ := strings.Cut(reply, "\n")
+	mac, err := net.ParseMAC(strings.TrimSpace(first))
+	if err != nil {
+		return sta, false
+	}
+	sta.MAC = mac.String()
+	fields := parseFields(rest)
+	if v, err := strconv.Atoi(fields["signal"]); err == nil {
+		sta.Signal = v
+	}
+	if v, err := strconv.Atoi(fields["connected_time"]); err == nil {
+		sta.ConnectedTime = time.Duration(v) * time.Second
+	}
+	sta.RxBytes, _ = strconv.ParseUint(fields["rx_bytes"], 10, 64)
+	sta.TxBytes, _ = strconv.ParseUint(fields["tx_bytes"], 10, 64)
+	return sta, true
+}
+
+// Deauthenticate sends DEAUTHENTICATE for mac.
+func (cl *HostapdClient) Deauthenticate(iface, mac string) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("invalid MAC address %q", mac)
+	}
+	return cl.do(iface, func(c *conn) error {
+		return c.expectOK("DEAUTHENTICATE " + hw.String())
+	})
+}
+
+// SetACL clears the ACCEPT_ACL and DENY_ACL lists and adds accept and deny
+// back with ADD_MAC. hostapd disconnects stations the new lists exclude.
+func (cl *HostapdClient) SetACL(iface string, accept, deny []string) error {
+	cmds := []string{"ACCEPT_ACL CLEAR"}
+	for _, mac := range accept {
+		hw, err := net.ParseMAC(mac)
+		if err != nil {
+			return fmt.Errorf("invalid MAC address %q", mac)
+		}
+		cmds = append(cmds, "ACCEPT_ACL ADD_MAC "+hw.String())
+	}
+	cmds = append(cmds, "DENY_ACL CLEAR")
+	for _, mac := range deny {
+		hw, err := net.ParseMAC(mac)
+		if err != nil {
+			return fmt.Errorf("invalid MAC address %q", mac)
+		}
+		cmds = append(cmds, "DENY_ACL ADD_MAC "+hw.String())
+	}
+	return cl.do(iface, func(c *conn) error {
+		for _, cmd := range cmds {
+			if err := c.expectOK(cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package wpa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+func TestHostapdClient(t *testing.T) {
+	supplicant, s := startSupplicant(t, map[string]string{
+		"STA-FIRST": "02:11:22:33:44:55\nflags=[AUTH][ASSOC][AUTHORIZED]\nrx_bytes=1200\ntx_bytes=34000\n" +
+			"connected_time=95\nsignal=-48\n",
+		"STA-NEXT 02:11:22:33:44:55":       "aa:bb:cc:00:00:01\nflags=[AUTH][ASSOC]\nconnected_time=3\n",
+		"STA-NEXT aa:bb:cc:00:00:01":       "",
+		"DEAUTHENTICATE 02:11:22:33:44:55": "OK\n",
+	})
+	client := &HostapdClient{dir: supplicant.dir}
+
+	stations, err := client.Stations("wlan0")
+	require.NoError(t, err)
+	assert.Equal(t, []types.HotspotClient{
+		{MAC: "02:11:22:33:44:55", Signal: -48, ConnectedTime: 95 * time.Second, RxBytes: 1200, TxBytes: 34000},
+		{MAC: "aa:bb:cc:00:00:01", ConnectedTime: 3 * time.Second},
+	}, stations)
+	assert.Equal(t, "STA-FIRST", <-s.requests)
+
+	assert.NoError(t, client.Deauthenticate("wlan0", "02:11:22:33:44:55"))
+	assert.EqualError(t, client.Deauthenticate("wlan0", "aa:bb:cc:00:00:01"), "DEAUTHENTICATE aa:bb:cc:00:00:01: UNKNOWN COMMAND")
+	assert.EqualError(t, client.Deauthenticate("wlan0", "phone"), `invalid MAC address "phone"`)
+
+	_, err = (&HostapdClient{dir: t.TempDir()}).Stations("wlan0")
+	assert.ErrorContains(t, err, "connecting to hostapd on wlan0")
+}
+
+func TestHostapdClient_SetACL(t *testing.T) {
+	supplicant, s := startSupplicant(t, map[string]string{
+		"ACCEPT_ACL CLEAR":                     "OK\n",
+		"ACCEPT_ACL ADD_MAC 02:11:22:33:44:55": "OK\n",
+		"DENY_ACL CLEAR":                       "OK\n",
+		"DENY_ACL ADD_MAC aa:bb:cc:00:00:01":   "OK\n",
+	})
+	client := &HostapdClient{dir: supplicant.dir}
+
+	assert.NoError(t, client.SetACL("wlan0", []string{"02:11:22:33:44:55"}, []string{"AA:BB:CC:00:00:01"}))
+	assert.Equal(t, "ACCEPT_ACL CLEAR", <-s.requests)
+	assert.Equal(t, "ACCEPT_ACL ADD_MAC 02:11:22:33:44:55", <-s.requests)
+	assert.Equal(t, "DENY_ACL CLEAR", <-s.requests)
+	assert.Equal(t, "DENY_ACL ADD_MAC aa:bb:cc:00:00:01", <-s.requests)
+
+	assert.EqualError(t, client.SetACL("wlan0", nil, []string{"02:00:00:00:00:02"}), "DENY_ACL ADD_MAC 02:00:00:00:00:02: UNKNOWN COMMAND")
+	assert.EqualError(t, client.SetACL("wlan0", nil, []string{"phone"}), `invalid MAC address "phone"`)
+}
@@ -0,0 +1,345 @@
+// Package wpa is a client for the wpa_supplicant control interface: the
+// per-interface UNIX datagram socket under ctrl_interface, the same one
+// `wpa_cli` talks to. Requests get a single reply datagram; after ATTACH,
+// the socket also receives unsolicited events ("<3>CTRL-EVENT-CONNECTED ...").
+// hostapd serves the same protocol, which HostapdClient speaks.
+package wpa
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/angelfreak/net/pkg/types"
+)
+
+// Compile-time assertion that the impl satisfies the interface.
+var _ types.SupplicantClient = (*Client)(nil)
+
+// CtrlDir is the ctrl_interface directory of the configs we generate.
+const CtrlDir = "/run/wpa_supplicant"
+
+// requestTimeout bounds the wait for a reply; wpa_supplicant usually answers
+// in well under 100ms.
+const requestTimeout = 2 * time.Second
+
+// Client is the control-socket implementation of types.SupplicantClient.
+// It holds no connection: every call opens the socket of its interface.
+type Client struct {
+	dir string // ctrl_interface directory, overridable for tests
+}
+
+// NewClient returns a client for wpa_supplicant instances under CtrlDir.
+func NewClient() *Client {
+	return &Client{dir: CtrlDir}
+}
+
+// localSeq numbers the client-side sockets of this process.
+var localSeq atomic.Uint32
+
+// conn is an open control connection. wpa_supplicant replies to the
+// sender's address, so the client binds a socket of its own, like wpa_cli's
+// /tmp/wpa_ctrl_<pid>-<n>.
+type conn struct {
+	c     *net.UnixConn
+	local string
+	// pending holds events that arrived while waiting for a reply.
+	pending []string
+}
+
+func (cl *Client) dial(iface string) (*conn, error) {
+	dir := cl.dir
+	if dir == "" {
+		dir = CtrlDir
+	}
+	return dial(dir, iface, "wpa_supplicant")
+}
+
+// dial connects to the control socket of iface under dir, served by daemon.
+func dial(dir, iface, daemon string) (*conn, error) {
+	local := filepath.Join(os.TempDir(), fmt.Sprintf("net_wpa_ctrl_%d-%d", os.Getpid(), localSeq.Add(1)))
+	_ = os.Remove(local)
+	c, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: local, Net: "unixgram"},
+		&net.UnixAddr{Name: filepath.Join(dir, iface), Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s on %s: %w", daemon, iface, err)
+	}
+	return &conn{c: c, local: local}, nil
+}
+
+func (c *conn) close() error {
+	err := c.c.Close()
+	_ = os.Remove(c.local)
+	return err
+}
+
+// read returns the next datagram, waiting until deadline.
+func (c *conn) read(deadline time.Time) (string, error) {
+	if err := c.c.SetReadDeadline(deadline); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 4096)
+	n, err := c.c.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
+
+// request sends cmd and returns its reply. Events received meanwhile are
+// kept for the monitor.
+func (c *conn) request(cmd string) (string, error) {
+	if _, err := c.c.Write([]byte(cmd)); err != nil {
+		return "", fmt.Errorf("%s: %w", cmd, err)
+	}
+	deadline := time.Now().Add(requestTimeout)
+	for {
+		msg, err := c.read(deadline)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", cmd, err)
+		}
+		if strings.HasPrefix(msg, "<") {
+			c.pending = append(c.pending, msg)
+			continue
+		}
+		return msg, nil
+	}
+}
+
+// expectOK sends cmd and fails unless the reply is OK.
+func (c *conn) expectOK(cmd string) error {
+	reply, err := c.request(cmd)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reply) != "OK" {
+		return fmt.Errorf("%s: %s", cmd, strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// do opens the socket of iface, runs fn on it and closes it.
+func (cl *Client) do(iface string, fn func(c *conn) error) error {
+	c, err := cl.dial(iface)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	return fn(c)
+}
+
+// parseFields parses a key=value per line reply (STATUS, SIGNAL_POLL).
+func parseFields(reply string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(reply, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// fields runs a request answered with key=value lines.
+func (cl *Client) fields(iface, cmd string) (map[string]string, error) {
+	var fields map[string]string
+	err := cl.do(iface, func(c *conn) error {
+		reply, err := c.request(cmd)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(reply) == "FAIL" {
+			return fmt.Errorf("%s: FAIL", cmd)
+		}
+		fields = parseFields(reply)
+		return nil
+	})
+	return fields, err
+}
+
+// Status returns the STATUS fields of iface.
+func (cl *Client) Status(iface string) (map[string]string, error) {
+	return cl.fields(iface, "STATUS")
+}
+
+// SignalPoll returns the SIGNAL_POLL fields of iface.
+func (cl *Client) SignalPoll(iface string) (map[string]string, error) {
+	return cl.fields(iface, "SIGNAL_POLL")
+}
+
+// AddNetwork adds an empty network block and returns its id.
+func (cl *Client) AddNetwork(iface string) (int, error) {
+	id := -1
+	err := cl.do(iface, func(c *conn) error {
+		reply, err := c.request("ADD_NETWORK")
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(reply))
+		if err != nil {
+			return fmt.Errorf("ADD_NETWORK: %s", strings.TrimSpace(reply))
+		}
+		id = n
+		return nil
+	})
+	return id, err
+}
+
+// SetNetwork sets a variable of network block id.
+func (cl *Client) SetNetwork(iface string, id int, name, value string) error {
+	if strings.ContainsAny(name+value, "\n") {
+		return fmt.Errorf("SET_NETWORK: value of %s contains a newline", name)
+	}
+	return cl.do(iface, func(c *conn) error {
+		return c.expectOK(fmt.Sprintf("SET_NETWORK %d %s %s", id, name, value))
+	})
+}
+
+// Reconfigure makes wpa_supplicant reread its configuration file.
+func (cl *Client) Reconfigure(iface string) error {
+	return cl.do(iface, func(c *conn) error { return c.expectOK("RECONFIGURE") })
+}
+
+// Reassociate reconnects with the current configuration.
+func (cl *Client) Reassociate(iface string) error {
+	return cl.do(iface, func(c *conn) error { return c.expectOK("REASSOCIATE") })
+}
+
+// Terminate stops wpa_supplicant.
+func (cl *Client) Terminate(iface string) error {
+	return cl.do(iface, func(c *conn) error { return c.expectOK("TERMINATE") })
+}
+
+// StartWPS starts WPS: WPS_PBC [bssid], or WPS_PIN <bssid|any> <pin>, which
+// wpa_supplicant answers with the PIN.
+func (cl *Client) StartWPS(iface, bssid, pin string) error {
+	if strings.ContainsAny(bssid+pin, " \n") {
+		return fmt.Errorf("WPS: invalid BSSID or PIN")
+	}
+	return cl.do(iface, func(c *conn) error {
+		if pin == "" {
+			cmd := "WPS_PBC"
+			if bssid != "" {
+				cmd += " " + bssid
+			}
+			return c.expectOK(cmd)
+		}
+		target := bssid
+		if target == "" {
+			target = "any"
+		}
+		reply, err := c.request(fmt.Sprintf("WPS_PIN %s %s", target, pin))
+		if err != nil {
+			return err
+		}
+		if reply = strings.TrimSpace(reply); strings.HasPrefix(reply, "FAIL") {
+			return fmt.Errorf("WPS_PIN: %s", reply)
+		}
+		return nil
+	})
+}
+
+// Attach subscribes to the events of iface.
+func (cl *Client) Attach(iface string) (types.SupplicantMonitor, error) {
+	c, err := cl.dial(iface)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.expectOK("ATTACH"); err != nil {
+		c.close()
+		return nil, err
+	}
+	return &monitor{conn: c}, nil
+}
+
+// monitor is an attached connection.
+type monitor struct {
+	*conn
+}
+
+// Next returns the next event, waiting at most timeout.
+func (m *monitor) Next(timeout time.Duration) (types.SupplicantEvent, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var msg string
+		if len(m.pending) > 0 {
+			msg, m.pending = m.pending[0], m.pending[1:]
+		} else {
+			var err error
+			if msg, err = m.read(deadline); err != nil {
+				return types.SupplicantEvent{}, fmt.Errorf("waiting for wpa_supplicant events: %w", err)
+			}
+		}
+		// Stray replies (a late answer to a timed-out request) aren't events.
+		if event, ok := ParseEvent(msg); ok {
+			return event, nil
+		}
+	}
+}
+
+// Close detaches and closes the connection.
+func (m *monitor) Close() error {
+	_ = m.expectOK("DETACH")
+	return m.close()
+}
+
+// ParseEvent parses an event message: an optional "IFNAME=<iface> " prefix
+// (global control interface), the "<level>" priority, then the event name
+// and its arguments. Quoted values may contain spaces.
+func ParseEvent(msg string) (types.SupplicantEvent, bool) {
+	msg = strings.TrimSpace(msg)
+	if strings.HasPrefix(msg, "IFNAME=") {
+		if _, rest, ok := strings.Cut(msg, " "); ok {
+			msg = rest
+		}
+	}
+	if !strings.HasPrefix(msg, "<") {
+		return types.SupplicantEvent{}, false
+	}
+	end := strings.IndexByte(msg, '>')
+	if end < 0 {
+		return types.SupplicantEvent{}, false
+	}
+	text := msg[end+1:]
+	words := splitQuoted(text)
+	if len(words) == 0 {
+		return types.SupplicantEvent{}, false
+	}
+	event := types.SupplicantEvent{Name: words[0], Fields: make(map[string]string), Text: text}
+	for _, w := range words[1:] {
+		if k, v, ok := strings.Cut(w, "="); ok {
+			event.Fields[k] = strings.Trim(v, `"`)
+		}
+	}
+	return event, true
+}
+
+// splitQuoted splits s at spaces outside double quotes.
+func splitQuoted(s string) []string {
+	var words []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case r == ' ' && !quoted:
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+	return words
+}
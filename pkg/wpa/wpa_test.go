@@ -0,0 +1,140 @@
+package wpa
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSupplicant serves a control socket for iface in a temp directory,
+// answering requests from replies and sending events after ATTACH.
+type fakeSupplicant struct {
+	conn     *net.UnixConn
+	replies  map[string]string
+	events   []string
+	requests chan string
+}
+
+func startSupplicant(t *testing.T, replies map[string]string, events ...string) (*Client, *fakeSupplicant) {
+	t.Helper()
+	dir := t.TempDir()
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "wlan0"), Net: "unixgram"})
+	require.NoError(t, err)
+	s := &fakeSupplicant{conn: conn, replies: replies, events: events, requests: make(chan string, 16)}
+	go s.serve()
+	t.Cleanup(func() { conn.Close() })
+	return &Client{dir: dir}, s
+}
+
+func (s *fakeSupplicant) serve() {
+	buf := make([]byte, 4096)
+	for {
+		n, from, err := s.conn.ReadFromUnix(buf)
+		if err != nil {
+			return
+		}
+		cmd := string(buf[:n])
+		s.requests <- cmd
+		reply, ok := s.replies[cmd]
+		if !ok {
+			reply = "UNKNOWN COMMAND\n"
+		}
+		if cmd == "ATTACH" {
+			reply = "OK\n"
+		}
+		_, _ = s.conn.WriteToUnix([]byte(reply), from)
+		if cmd == "ATTACH" {
+			for _, e := range s.events {
+				_, _ = s.conn.WriteToUnix([]byte(e), from)
+			}
+		}
+	}
+}
+
+func TestClientRequests(t *testing.T) {
+	client, s := startSupplicant(t, map[string]string{
+		"STATUS":                      "bssid=00:11:22:33:44:55\nfreq=5180\nssid=Cafe\nwpa_state=COMPLETED\n",
+		"SIGNAL_POLL":                 "RSSI=-61\nLINKSPEED=866\nNOISE=9999\nFREQUENCY=5180\n",
+		"ADD_NETWORK":                 "1\n",
+		"SET_NETWORK 1 ssid \"Cafe\"": "OK\n",
+		"SET_NETWORK 1 psk \"x\"":     "FAIL\n",
+		"RECONFIGURE":                 "OK\n",
+		"TERMINATE":                   "OK\n",
+		"WPS_PBC":                     "OK\n",
+		"WPS_PIN any 12345670":        "12345670\n",
+		"WPS_PIN any 1234":            "FAIL\n",
+	})
+
+	status, err := client.Status("wlan0")
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETED", status["wpa_state"])
+	assert.Equal(t, "Cafe", status["ssid"])
+	assert.Equal(t, "STATUS", <-s.requests)
+
+	signal, err := client.SignalPoll("wlan0")
+	require.NoError(t, err)
+	assert.Equal(t, "-61", signal["RSSI"])
+
+	id, err := client.AddNetwork("wlan0")
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	assert.NoError(t, client.SetNetwork("wlan0", 1, "ssid", `"Cafe"`))
+	assert.EqualError(t, client.SetNetwork("wlan0", 1, "psk", `"x"`), `SET_NETWORK 1 psk "x": FAIL`)
+	assert.Error(t, client.SetNetwork("wlan0", 1, "psk", "\"x\"\nTERMINATE"))
+	assert.NoError(t, client.Reconfigure("wlan0"))
+	assert.NoError(t, client.Terminate("wlan0"))
+	assert.Error(t, client.Reassociate("wlan0"), "UNKNOWN COMMAND is not OK")
+	assert.NoError(t, client.StartWPS("wlan0", "", ""))
+	assert.NoError(t, client.StartWPS("wlan0", "", "12345670"))
+	assert.EqualError(t, client.StartWPS("wlan0", "", "1234"), "WPS_PIN: FAIL")
+}
+
+func TestClientNotRunning(t *testing.T) {
+	client := &Client{dir: t.TempDir()}
+	_, err := client.Status("wlan0")
+	assert.ErrorContains(t, err, "connecting to wpa_supplicant on wlan0")
+	_, err = client.Attach("wlan0")
+	assert.Error(t, err)
+}
+
+func TestMonitor(t *testing.T) {
+	client, _ := startSupplicant(t, map[string]string{"DETACH": "OK\n"},
+		"<3>CTRL-EVENT-SCAN-RESULTS ",
+		`<3>CTRL-EVENT-SSID-TEMP-DISABLED id=0 ssid="My Cafe" auth_failures=1 duration=10 reason=WRONG_KEY`,
+	)
+
+	monitor, err := client.Attach("wlan0")
+	require.NoError(t, err)
+	defer monitor.Close()
+
+	event, err := monitor.Next(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "CTRL-EVENT-SCAN-RESULTS", event.Name)
+
+	event, err = monitor.Next(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "CTRL-EVENT-SSID-TEMP-DISABLED", event.Name)
+	assert.Equal(t, "My Cafe", event.Fields["ssid"])
+	assert.Equal(t, "WRONG_KEY", event.Fields["reason"])
+
+	_, err = monitor.Next(20 * time.Millisecond)
+	assert.True(t, errors.Is(err, os.ErrDeadlineExceeded), "got %v", err)
+}
+
+func TestParseEvent(t *testing.T) {
+	event, ok := ParseEvent("IFNAME=wlan0 <3>CTRL-EVENT-ASSOC-REJECT bssid=00:11:22:33:44:55 status_code=1 timeout")
+	require.True(t, ok)
+	assert.Equal(t, "CTRL-EVENT-ASSOC-REJECT", event.Name)
+	assert.Equal(t, "1", event.Fields["status_code"])
+	assert.Equal(t, "CTRL-EVENT-ASSOC-REJECT bssid=00:11:22:33:44:55 status_code=1 timeout", event.Text)
+
+	_, ok = ParseEvent("OK\n")
+	assert.False(t, ok, "replies are not events")
+}